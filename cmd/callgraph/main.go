@@ -14,35 +14,42 @@ package main // import "golang.org/x/tools/cmd/callgraph"
 //   - functions reachable from root (use digraph tool?)
 //   - unreachable functions (use digraph tool?)
 //   - dynamic (runtime) types
-//   - indexed output (numbered nodes)
-//   - JSON output
 //   - additional template fields:
 //     callee file/line/col
 
 import (
 	"bytes"
+	_ "embed"
+	"encoding/json"
 	"flag"
 	"fmt"
 	"go/token"
+	htmltemplate "html/template"
 	"io"
 	"os"
+	"path/filepath"
 	"runtime"
+	"slices"
+	"sort"
+	"strings"
 	"text/template"
 
 	"golang.org/x/tools/go/callgraph"
 	"golang.org/x/tools/go/callgraph/cha"
+	"golang.org/x/tools/go/callgraph/pta"
 	"golang.org/x/tools/go/callgraph/rta"
 	"golang.org/x/tools/go/callgraph/static"
 	"golang.org/x/tools/go/callgraph/vta"
 	"golang.org/x/tools/go/packages"
 	"golang.org/x/tools/go/ssa"
 	"golang.org/x/tools/go/ssa/ssautil"
+	"golang.org/x/tools/internal/typesinternal"
 )
 
 // flags
 var (
 	algoFlag = flag.String("algo", "rta",
-		`Call graph construction algorithm (static, cha, rta, vta)`)
+		`Call graph construction algorithm (static, cha, rta, vta, pta)`)
 
 	testFlag = flag.Bool("test", false,
 		"Loads test code (*_test.go) for imported packages")
@@ -52,13 +59,20 @@ var (
 		"A template expression specifying how to format an edge")
 
 	tagsFlag = flag.String("tags", "", "comma-separated list of extra build tags (see: go help buildconstraint)")
+
+	fromFlag = flag.String("from", "", "comma-separated list of functions; show only the graph reachable from them")
+
+	toFlag = flag.String("to", "", "comma-separated list of functions; show only the graph that reaches them")
+
+	depthFlag = flag.Int("depth", 0, "maximum number of calls on the paths from -from functions and to -to functions (0 means unlimited)")
 )
 
 const Usage = `callgraph: display the call graph of a Go program.
 
 Usage:
 
-  callgraph [-algo=static|cha|rta|vta] [-test] [-format=...] package...
+  callgraph [-algo=static|cha|rta|vta|pta] [-test] [-format=...]
+            [-from=func,...] [-to=func,...] [-depth=n] package...
 
 Flags:
 
@@ -68,20 +82,68 @@ Flags:
             cha         Class Hierarchy Analysis
             rta         Rapid Type Analysis
             vta         Variable Type Analysis
+            pta         inclusion-based Points-To Analysis
 
            The algorithms are ordered by increasing precision in their
            treatment of dynamic calls (and thus also computational cost).
-           RTA requires a whole program (main or test), and
+           RTA and PTA require a whole program (main or test), and
            include only functions reachable from main.
 
 -test      Include the package's tests in the analysis.
 
+-from      Restricts the output to the subgraph of functions reachable
+           from any of the named functions. Functions are named as
+           in the deadcode command's -whylive flag, for example
+           "example.com/pkg.Func" or "example.com/pkg.Type.Method".
+           Multiple names may be separated by commas.
+
+-to        Restricts the output to the subgraph of functions from which
+           any of the named functions is reachable. If both -from and
+           -to are specified, the output contains only functions on
+           call paths from a -from function to a -to function.
+
+-depth     Limits the -from and -to subgraphs to call paths of at most this
+           many calls: from a -from function, to a -to function, or, if
+           both are specified, from one to the other. Zero means no limit.
+
 -format    Specifies the format in which each call graph edge is displayed.
            One of:
 
             digraph     output suitable for input to
                         golang.org/x/tools/cmd/digraph.
             graphviz    output in AT&T GraphViz (.dot) format.
+            json        a single JSON object describing the whole graph.
+            html        a self-contained HTML page for interactively
+                        browsing the callers and callees of each function.
+
+           The json format prints an object of this form:
+
+                   type Graph struct {
+                           Nodes []Node
+                           Edges []Edge
+                   }
+
+                   type Node struct {
+                           ID       int      // index of this node in Nodes
+                           Func     string   // e.g. "(*sync.Mutex).Lock", or "<root>"
+                           Package  string   // import path of the function's package, if any
+                           Position Position // declaration of the function, if any
+                   }
+
+                   type Edge struct {
+                           Caller, Callee int      // IDs of the caller and callee nodes
+                           Position       Position // call site, if any
+                           Dynamic        bool     // call is dynamically dispatched
+                           Description    string   // e.g. "static method call"
+                   }
+
+                   type Position struct {
+                           File      string
+                           Line, Col int
+                   }
+
+           The json and html formats are not templates, and are
+           available for all algorithms.
 
            All other values are interpreted using text/template syntax.
            The default value is:
@@ -138,6 +200,15 @@ Examples:
       sed -ne 's/-dynamic-/--/p' |
       sed -ne 's/-->.*fmt_test.*$//p' | sort | uniq
 
+  Save an interactive viewer of the call graph computed by VTA:
+
+    callgraph -algo=vta -format=html golang.org/x/tools/cmd/callgraph > callgraph.html
+
+  Show all call paths of at most three calls from a handler to os.Exit:
+
+    callgraph -algo=vta -from=example.com/server.handle -to=os.Exit -depth=3 \
+      example.com/server
+
   Show all functions directly called by the callgraph tool's main function:
 
     callgraph -format=digraph golang.org/x/tools/cmd/callgraph |
@@ -158,7 +229,7 @@ func init() {
 
 func main() {
 	flag.Parse()
-	if err := doCallgraph("", "", *algoFlag, *formatFlag, *testFlag, flag.Args()); err != nil {
+	if err := doCallgraph("", "", *algoFlag, *formatFlag, *testFlag, *fromFlag, *toFlag, *depthFlag, flag.Args()); err != nil {
 		fmt.Fprintf(os.Stderr, "callgraph: %s\n", err)
 		os.Exit(1)
 	}
@@ -166,11 +237,14 @@ func main() {
 
 var stdout io.Writer = os.Stdout
 
-func doCallgraph(dir, gopath, algo, format string, tests bool, args []string) error {
+func doCallgraph(dir, gopath, algo, format string, tests bool, from, to string, depth int, args []string) error {
 	if len(args) == 0 {
 		fmt.Fprint(os.Stderr, Usage)
 		return nil
 	}
+	if depth < 0 {
+		return fmt.Errorf("invalid -depth %d: must not be negative", depth)
+	}
 
 	cfg := &packages.Config{
 		Mode:       packages.LoadAllSyntax,
@@ -206,7 +280,15 @@ func doCallgraph(dir, gopath, algo, format string, tests bool, args []string) er
 		cg = cha.CallGraph(prog)
 
 	case "pta":
-		return fmt.Errorf("pointer analysis is no longer supported (see Go issue #59676)")
+		mains, err := mainPackages(pkgs)
+		if err != nil {
+			return err
+		}
+		ptares, err := pta.Analyze(&pta.Config{Mains: mains})
+		if err != nil {
+			return err
+		}
+		cg = ptares.CallGraph
 
 	case "rta":
 		mains, err := mainPackages(pkgs)
@@ -231,12 +313,29 @@ func doCallgraph(dir, gopath, algo, format string, tests bool, args []string) er
 
 	cg.DeleteSyntheticNodes()
 
+	if from != "" || to != "" {
+		if err := restrict(cg, from, to, depth); err != nil {
+			return err
+		}
+	}
+
 	// -- output------------------------------------------------------------
 
 	var before, after string
 
 	// Pre-canned formats.
 	switch format {
+	case "json":
+		data, err := json.MarshalIndent(toJSONGraph(prog.Fset, cg), "", "\t")
+		if err != nil {
+			return fmt.Errorf("internal error: %v", err)
+		}
+		_, err = fmt.Fprintf(stdout, "%s\n", data)
+		return err
+
+	case "html":
+		return htmlTemplate.Execute(stdout, toJSONGraph(prog.Fset, cg))
+
 	case "digraph":
 		format = `{{printf "%q %q" .Caller .Callee}}`
 
@@ -283,6 +382,288 @@ func doCallgraph(dir, gopath, algo, format string, tests bool, args []string) er
 	return nil
 }
 
+// restrict removes from call graph cg all nodes and edges that are
+// not part of the subgraph selected by the -from, -to and -depth flags:
+// those on call paths of at most depth calls (any number if depth is
+// zero) from a -from function, to a -to function, or both.
+//
+// from and to are comma-separated lists of function names (as
+// printed by prettyName); either may be empty.
+func restrict(cg *callgraph.Graph, from, to string, depth int) error {
+	// The distances of the nodes from the -from functions and to the
+	// -to functions; nil if the flag is not set.
+	var fromDist, toDist map[*callgraph.Node]int
+	for _, dir := range []struct {
+		names   string
+		forward bool
+		dist    *map[*callgraph.Node]int
+	}{{from, true, &fromDist}, {to, false, &toDist}} {
+		if dir.names == "" {
+			continue
+		}
+		roots, err := lookupNodes(cg, dir.names)
+		if err != nil {
+			return err
+		}
+		*dir.dist = distances(roots, dir.forward)
+	}
+
+	// onPath reports whether there is a path of at most depth calls
+	// through the call from caller to callee (through node caller, if
+	// callee is nil).
+	onPath := func(caller, callee *callgraph.Node) bool {
+		length := 0
+		if callee != nil {
+			length++
+		} else {
+			callee = caller
+		}
+		if fromDist != nil {
+			d, ok := fromDist[caller]
+			if !ok {
+				return false
+			}
+			length += d
+		}
+		if toDist != nil {
+			d, ok := toDist[callee]
+			if !ok {
+				return false
+			}
+			length += d
+		}
+		return depth == 0 || length <= depth
+	}
+
+	// Delete the nodes and edges in a single pass,
+	// as Graph.DeleteNode is slow for large batches.
+	filter := func(edges []*callgraph.Edge) []*callgraph.Edge {
+		out := edges[:0]
+		for _, e := range edges {
+			if onPath(e.Caller, e.Callee) {
+				out = append(out, e)
+			}
+		}
+		return out
+	}
+	for fn, n := range cg.Nodes {
+		if onPath(n, nil) {
+			n.In = filter(n.In)
+			n.Out = filter(n.Out)
+		} else {
+			delete(cg.Nodes, fn)
+		}
+	}
+	return nil
+}
+
+// lookupNodes returns the call graph nodes for the functions named by
+// the comma-separated list names. It is an error if any name does
+// not denote a function in the graph.
+func lookupNodes(cg *callgraph.Graph, names string) ([]*callgraph.Node, error) {
+	byName := make(map[string][]*callgraph.Node)
+	for fn, n := range cg.Nodes {
+		if fn != nil {
+			name := prettyName(fn)
+			byName[name] = append(byName[name], n)
+		}
+	}
+	var nodes []*callgraph.Node
+	for _, name := range strings.Split(names, ",") {
+		name = strings.TrimSpace(name)
+		if byName[name] == nil {
+			return nil, fmt.Errorf("function %q not found in call graph", name)
+		}
+		nodes = append(nodes, byName[name]...)
+	}
+	return nodes, nil
+}
+
+// distances returns the length of the shortest path from roots to each
+// node reachable from them, following edges forward (caller to callee)
+// or in reverse.
+func distances(roots []*callgraph.Node, forward bool) map[*callgraph.Node]int {
+	dist := make(map[*callgraph.Node]int)
+	for _, n := range roots {
+		dist[n] = 0
+	}
+	// Breadth-first search, so that each node is first seen at its
+	// minimum distance from the roots.
+	queue := roots
+	for d := 1; len(queue) > 0; d++ {
+		var next []*callgraph.Node
+		for _, n := range queue {
+			edges := n.Out
+			if !forward {
+				edges = n.In
+			}
+			for _, e := range edges {
+				succ := e.Callee
+				if !forward {
+					succ = e.Caller
+				}
+				if _, ok := dist[succ]; !ok {
+					dist[succ] = d
+					next = append(next, succ)
+				}
+			}
+		}
+		queue = next
+	}
+	return dist
+}
+
+// prettyName is a fork of the deadcode function of the same name,
+// so that -from and -to accept the same names as deadcode -whylive.
+//
+// It returns the package-qualified name of fn, for example
+// "example.com/pkg.Type.Method", or "example.com/pkg.Func$1" for an
+// anonymous function.
+func prettyName(fn *ssa.Function) string {
+	var buf strings.Builder
+
+	// package qualifier
+	if fn.Pkg != nil {
+		fmt.Fprintf(&buf, "%s.", fn.Pkg.Pkg.Path())
+	}
+
+	var format func(*ssa.Function)
+	format = func(fn *ssa.Function) {
+		// anonymous?
+		if fn.Parent() != nil {
+			format(fn.Parent())
+			i := slices.Index(fn.Parent().AnonFuncs, fn)
+			fmt.Fprintf(&buf, "$%d", i+1)
+			return
+		}
+
+		// method receiver?
+		if recv := fn.Signature.Recv(); recv != nil {
+			if _, named := typesinternal.ReceiverNamed(recv); named != nil {
+				buf.WriteString(named.Obj().Name())
+				buf.WriteByte('.')
+			}
+		}
+
+		// function/method name
+		buf.WriteString(fn.Name())
+	}
+	format(fn)
+
+	return buf.String()
+}
+
+// toJSONGraph returns the JSON form of the call graph cg.
+// Nodes are numbered in order of their function names, and edges are
+// sorted by caller, then call site, then callee, so that the output
+// is deterministic.
+func toJSONGraph(fset *token.FileSet, cg *callgraph.Graph) *jsonGraph {
+	nodes := make([]*callgraph.Node, 0, len(cg.Nodes))
+	for _, n := range cg.Nodes {
+		nodes = append(nodes, n)
+	}
+	sort.Slice(nodes, func(i, j int) bool {
+		x, y := nodes[i], nodes[j]
+		if x.Func == nil || y.Func == nil {
+			return x.Func == nil && y.Func != nil // root first
+		}
+		if x, y := x.Func.String(), y.Func.String(); x != y {
+			return x < y
+		}
+		return x.Func.Pos() < y.Func.Pos()
+	})
+
+	var g jsonGraph
+	ids := make(map[*callgraph.Node]int, len(nodes))
+	for i, n := range nodes {
+		ids[n] = i
+		node := jsonNode{ID: i, Func: "<root>"}
+		if fn := n.Func; fn != nil {
+			node.Func = fn.String()
+			if fn.Pkg != nil {
+				node.Package = fn.Pkg.Pkg.Path()
+			}
+			node.Position = toJSONPosition(fset.Position(fn.Pos()))
+		}
+		g.Nodes = append(g.Nodes, node)
+	}
+
+	for _, n := range nodes {
+		start := len(g.Edges)
+		for _, e := range n.Out {
+			g.Edges = append(g.Edges, jsonEdge{
+				Caller:      ids[e.Caller],
+				Callee:      ids[e.Callee],
+				Position:    toJSONPosition(fset.Position(e.Pos())),
+				Dynamic:     e.Site != nil && e.Site.Common().StaticCallee() == nil,
+				Description: e.Description(),
+				pos:         e.Pos(),
+			})
+		}
+		out := g.Edges[start:]
+		sort.Slice(out, func(i, j int) bool {
+			if x, y := out[i].pos, out[j].pos; x != y {
+				return x < y
+			}
+			return out[i].Callee < out[j].Callee
+		})
+	}
+	return &g
+}
+
+var cwd, _ = os.Getwd()
+
+func toJSONPosition(posn token.Position) jsonPosition {
+	// Use cwd-relative filename if possible.
+	filename := posn.Filename
+	if rel, err := filepath.Rel(cwd, filename); err == nil && !strings.HasPrefix(rel, "..") {
+		filename = rel
+	}
+
+	return jsonPosition{filename, posn.Line, posn.Column}
+}
+
+// -- output protocol for -format=json and -format=html --
+
+// Keep in sync with Usage!
+
+type jsonGraph struct {
+	Nodes []jsonNode
+	Edges []jsonEdge
+}
+
+type jsonNode struct {
+	ID       int
+	Func     string
+	Package  string `json:",omitempty"`
+	Position jsonPosition
+}
+
+type jsonEdge struct {
+	Caller, Callee int
+	Position       jsonPosition
+	Dynamic        bool
+	Description    string
+
+	pos token.Pos // for sorting
+}
+
+type jsonPosition struct {
+	File      string `json:",omitempty"`
+	Line, Col int    `json:",omitempty"`
+}
+
+func (p jsonPosition) String() string {
+	return fmt.Sprintf("%s:%d:%d", p.File, p.Line, p.Col)
+}
+
+//go:embed callgraph.html
+var htmlSource string
+
+// htmlTemplate renders the interactive viewer for -format=html.
+// The graph is embedded in the page as a JSON literal.
+var htmlTemplate = htmltemplate.Must(htmltemplate.New("html").Parse(htmlSource))
+
 // mainPackages returns the main packages to analyze.
 // Each resulting package is named "main" and has a main function.
 func mainPackages(pkgs []*ssa.Package) ([]*ssa.Package, error) {
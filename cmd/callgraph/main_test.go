@@ -11,10 +11,13 @@ package main
 
 import (
 	"bytes"
+	"encoding/json"
 	"fmt"
 	"log"
 	"os"
 	"path/filepath"
+	"reflect"
+	"regexp"
 	"strings"
 	"testing"
 
@@ -60,6 +63,12 @@ func TestCallgraph(t *testing.T) {
 			"pkg.main --> pkg.main2",
 			"pkg.main2 --> (pkg.D).f",
 		}},
+		{"pta", false, []string{
+			// pta distinguishes main->C, main2->D.
+			"pkg.main --> (pkg.C).f",
+			"pkg.main --> pkg.main2",
+			"pkg.main2 --> (pkg.D).f",
+		}},
 		// tests: both the package's main and the test's main are called.
 		// The callgraph includes all the guts of the "testing" package.
 		{"rta", true, []string{
@@ -77,7 +86,7 @@ func TestCallgraph(t *testing.T) {
 	} {
 		const format = "{{.Caller}} --> {{.Callee}}"
 		stdout = new(bytes.Buffer)
-		if err := doCallgraph("testdata/src", gopath, test.algo, format, test.tests, []string{"pkg"}); err != nil {
+		if err := doCallgraph("testdata/src", gopath, test.algo, format, test.tests, "", "", 0, []string{"pkg"}); err != nil {
 			t.Error(err)
 			continue
 		}
@@ -99,3 +108,153 @@ func TestCallgraph(t *testing.T) {
 		}
 	}
 }
+
+func TestCallgraphJSON(t *testing.T) {
+	testenv.NeedsTool(t, "go")
+
+	gopath, err := filepath.Abs("testdata")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, algo := range []string{"static", "cha", "rta", "vta", "pta"} {
+		stdout = new(bytes.Buffer)
+		if err := doCallgraph("testdata/src", gopath, algo, "json", false, "", "", 0, []string{"pkg"}); err != nil {
+			t.Error(err)
+			continue
+		}
+
+		var graph jsonGraph
+		if err := json.Unmarshal(stdout.(*bytes.Buffer).Bytes(), &graph); err != nil {
+			t.Errorf("callgraph(%q): invalid JSON: %v", algo, err)
+			continue
+		}
+		got := make(map[string]bool)
+		for _, edge := range graph.Edges {
+			got[fmt.Sprintf("%s --%t--> %s",
+				graph.Nodes[edge.Caller].Func, edge.Dynamic, graph.Nodes[edge.Callee].Func)] = true
+		}
+		// All algorithms report the static call; the others
+		// report the dynamic call from main to (C).f.
+		want := []string{"pkg.main --false--> pkg.main2"}
+		if algo != "static" {
+			want = append(want, "pkg.main --true--> (pkg.C).f")
+		}
+		for _, edge := range want {
+			if !got[edge] {
+				t.Errorf("callgraph(%q): missing edge: %s\ngot:\n%s", algo, edge, stdout)
+			}
+		}
+	}
+}
+
+// TestCallgraphHTML checks that -format=html renders the embedded
+// viewer, with the same graph as -format=json embedded in its script.
+func TestCallgraphHTML(t *testing.T) {
+	testenv.NeedsTool(t, "go")
+
+	gopath, err := filepath.Abs("testdata")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var graphs [2]jsonGraph
+	for i, format := range []string{"json", "html"} {
+		stdout = new(bytes.Buffer)
+		if err := doCallgraph("testdata/src", gopath, "rta", format, false, "", "", 0, []string{"pkg"}); err != nil {
+			t.Fatal(err)
+		}
+		out := stdout.(*bytes.Buffer).String()
+		if format == "html" {
+			if !strings.HasPrefix(out, "<!DOCTYPE html>") {
+				t.Errorf("-format=html: output does not start with <!DOCTYPE html>:\n%s", out)
+			}
+			m := regexp.MustCompile(`(?m)^const graph = (.*);$`).FindStringSubmatch(out)
+			if m == nil {
+				t.Fatalf("-format=html: no graph in output:\n%s", out)
+			}
+			out = m[1]
+		}
+		if err := json.Unmarshal([]byte(out), &graphs[i]); err != nil {
+			t.Fatalf("-format=%s: invalid graph: %v\n%s", format, err, out)
+		}
+	}
+	if len(graphs[0].Edges) == 0 {
+		t.Errorf("-format=json: no edges")
+	}
+	if !reflect.DeepEqual(graphs[0], graphs[1]) {
+		t.Errorf("-format=html graph differs from -format=json:\nhtml: %+v\njson: %+v", graphs[1], graphs[0])
+	}
+}
+
+func TestCallgraphRestrict(t *testing.T) {
+	testenv.NeedsTool(t, "go")
+
+	gopath, err := filepath.Abs("testdata")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, test := range []struct {
+		algo     string
+		from, to string
+		depth    int
+		want     []string
+	}{
+		{"vta", "pkg.main2", "", 0, []string{
+			"pkg.main2 --> (pkg.D).f",
+		}},
+		{"rta", "", "pkg.C.f", 0, []string{
+			"pkg.main --> (pkg.C).f",
+			"pkg.main --> pkg.main2",
+			"pkg.main2 --> (pkg.C).f",
+		}},
+		{"vta", "", "pkg.D.f", 1, []string{
+			"pkg.main2 --> (pkg.D).f",
+		}},
+		{"rta", "pkg.main", "pkg.D.f", 0, []string{
+			"pkg.main --> (pkg.D).f",
+			"pkg.main --> pkg.main2",
+			"pkg.main2 --> (pkg.D).f",
+		}},
+		// depth is measured from the -to function.
+		{"rta", "", "pkg.main2", 1, []string{
+			"pkg.main --> pkg.main2",
+		}},
+		// With both -from and -to, depth limits the whole path:
+		// main --> main2 --> D.f has two calls.
+		{"rta", "pkg.main", "pkg.D.f", 1, []string{
+			"pkg.main --> (pkg.D).f",
+		}},
+		{"rta", "pkg.main", "pkg.D.f", 2, []string{
+			"pkg.main --> (pkg.D).f",
+			"pkg.main --> pkg.main2",
+			"pkg.main2 --> (pkg.D).f",
+		}},
+	} {
+		stdout = new(bytes.Buffer)
+		if err := doCallgraph("testdata/src", gopath, test.algo, "json", false, test.from, test.to, test.depth, []string{"pkg"}); err != nil {
+			t.Error(err)
+			continue
+		}
+		var graph jsonGraph
+		if err := json.Unmarshal(stdout.(*bytes.Buffer).Bytes(), &graph); err != nil {
+			t.Fatal(err)
+		}
+		var got []string
+		for _, edge := range graph.Edges {
+			got = append(got, fmt.Sprintf("%s --> %s",
+				graph.Nodes[edge.Caller].Func, graph.Nodes[edge.Callee].Func))
+		}
+		if !reflect.DeepEqual(got, test.want) {
+			t.Errorf("callgraph(%q, from=%q, to=%q, depth=%d) = %q, want %q",
+				test.algo, test.from, test.to, test.depth, got, test.want)
+		}
+	}
+}
+
+func TestCallgraphNegativeDepth(t *testing.T) {
+	err := doCallgraph("testdata/src", "", "rta", "json", false, "", "pkg.main2", -1, []string{"pkg"})
+	if err == nil || !strings.Contains(err.Error(), "-depth") {
+		t.Errorf("doCallgraph with -depth=-1 returned %v, want -depth error", err)
+	}
+}
@@ -8,6 +8,8 @@
 package main // import "golang.org/x/tools/cmd/eg"
 
 import (
+	"bytes"
+	"encoding/json"
 	"flag"
 	"fmt"
 	"go/ast"
@@ -21,6 +23,7 @@ import (
 	"strings"
 
 	"golang.org/x/tools/go/packages"
+	"golang.org/x/tools/internal/diff"
 	"golang.org/x/tools/refactor/eg"
 )
 
@@ -30,16 +33,20 @@ var (
 	templateFlag   = flag.String("t", "", "template.go file specifying the refactoring")
 	transitiveFlag = flag.Bool("transitive", false, "apply refactoring to all dependencies too")
 	writeFlag      = flag.Bool("w", false, "rewrite input files in place (by default, the results are printed to standard output)")
+	diffFlag       = flag.Bool("d", false, "display diffs instead of rewriting files")
+	jsonFlag       = flag.Bool("json", false, "print the matches in JSON format instead of rewriting files")
 	verboseFlag    = flag.Bool("v", false, "show verbose matcher diagnostics")
 )
 
 const usage = `eg: an example-based refactoring tool.
 
-Usage: eg -t template.go [-w] [-transitive] <packages>
+Usage: eg -t template.go [-w | -d | -json] [-transitive] <packages>
 
 -help            show detailed help message
 -t template.go	 specifies the template file (use -help to see explanation)
 -w          	 causes files to be re-written in place.
+-d               display diffs instead of rewriting files.
+-json            print the matches in JSON format instead of rewriting files.
 -transitive 	 causes all dependencies to be refactored too.
 -v               show verbose matcher diagnostics
 -beforeedit cmd  a command to exec before each file is modified.
@@ -71,6 +78,9 @@ func doMain() error {
 	if *templateFlag == "" {
 		return fmt.Errorf("no -t template.go file specified")
 	}
+	if btoi(*writeFlag)+btoi(*diffFlag)+btoi(*jsonFlag) > 1 {
+		return fmt.Errorf("at most one of -w, -d and -json may be specified")
+	}
 
 	tAbs, err := filepath.Abs(*templateFlag)
 	if err != nil {
@@ -128,19 +138,58 @@ func doMain() error {
 		all = pkgs
 	}
 	var hadErrors bool
+	enc := json.NewEncoder(os.Stdout)
+	enc.SetIndent("", "\t")
+	seen := make(map[string]bool) // a file may belong to a package and its test variant
 	for _, pkg := range pkgs {
+		var nmatches, nfiles int
 		for i, filename := range pkg.CompiledGoFiles {
 			if filename == tAbs {
 				// Don't rewrite the template file.
 				continue
 			}
+			if seen[filename] {
+				continue
+			}
+			seen[filename] = true
 			file := pkg.Syntax[i]
 			n := xform.Transform(pkg.TypesInfo, pkg.Types, file)
 			if n == 0 {
 				continue
 			}
+			nmatches += n
+			nfiles++
 			fmt.Fprintf(os.Stderr, "=== %s (%d matches)\n", filename, n)
-			if *writeFlag {
+			if *jsonFlag {
+				for _, m := range xform.Matches() {
+					start, end := cfg.Fset.Position(m.Pos), cfg.Fset.Position(m.End)
+					enc.Encode(jsonMatch{
+						Package:   pkg.PkgPath,
+						File:      filename,
+						Line:      start.Line,
+						Column:    start.Column,
+						EndLine:   end.Line,
+						EndColumn: end.Column,
+						Rule:      m.Rule,
+						Old:       m.Old,
+						New:       m.New,
+					})
+				}
+			} else if *diffFlag {
+				old, err := os.ReadFile(filename)
+				if err != nil {
+					fmt.Fprintf(os.Stderr, "eg: %s\n", err)
+					hadErrors = true
+					continue
+				}
+				var new bytes.Buffer
+				if err := format.Node(&new, cfg.Fset, file); err != nil {
+					fmt.Fprintf(os.Stderr, "eg: %s\n", err)
+					hadErrors = true
+					continue
+				}
+				fmt.Print(diff.Unified(filename+".orig", filename, string(old), new.String()))
+			} else if *writeFlag {
 				// Run the before-edit command (e.g. "chmod +w",  "checkout") if any.
 				if *beforeeditFlag != "" {
 					args := strings.Fields(*beforeeditFlag)
@@ -166,6 +215,9 @@ func doMain() error {
 				format.Node(os.Stdout, cfg.Fset, file)
 			}
 		}
+		if nmatches > 0 {
+			fmt.Fprintf(os.Stderr, "=== package %s: %d matches in %d files\n", pkg.ID, nmatches, nfiles)
+		}
 	}
 	if hadErrors {
 		os.Exit(1)
@@ -174,6 +226,23 @@ func doMain() error {
 	return nil
 }
 
+// A jsonMatch is the JSON form of a replacement, printed by -json.
+type jsonMatch struct {
+	Package            string // package path
+	File               string
+	Line, Column       int    // start of the replaced syntax
+	EndLine, EndColumn int    // end of the replaced syntax
+	Rule               string // name of the before function
+	Old, New           string // replaced and replacement syntax
+}
+
+func btoi(b bool) int {
+	if b {
+		return 1
+	}
+	return 0
+}
+
 type pkgsImporter []*packages.Package
 
 func (p pkgsImporter) Import(path string) (tpkg *types.Package, err error) {
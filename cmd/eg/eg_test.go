@@ -0,0 +1,165 @@
+// Copyright 2024 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"golang.org/x/tools/internal/testenv"
+	"golang.org/x/tools/txtar"
+)
+
+func TestMain(m *testing.M) {
+	if os.Getenv("EG_TEST_IS_EG") != "" {
+		main()
+		os.Exit(0)
+	}
+	os.Exit(m.Run())
+}
+
+const egTestdata = `
+-- go.mod --
+module example.com
+
+go 1.18
+
+-- a/a.go --
+package a
+
+import "strings"
+
+func F(s string) bool {
+	return strings.Index(s, "x") >= 0
+}
+
+-- template.txt --
+package template
+
+import "strings"
+
+func before(s, sub string) bool { return strings.Index(s, sub) >= 0 }
+func after(s, sub string) bool  { return strings.Contains(s, sub) }
+`
+
+// runEg runs the eg command with the given arguments in a new copy of
+// egTestdata, and returns its directory, standard output and error.
+func runEg(t *testing.T, args ...string) (dir, stdout, stderr string, ok bool) {
+	testenv.NeedsExec(t)
+	testenv.NeedsTool(t, "go")
+
+	dir = t.TempDir()
+	for _, f := range txtar.Parse([]byte(egTestdata)).Files {
+		filename := filepath.Join(dir, f.Name)
+		if err := os.MkdirAll(filepath.Dir(filename), 0777); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(filename, f.Data, 0666); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	exe, err := os.Executable()
+	if err != nil {
+		t.Fatal(err)
+	}
+	cmd := exec.Command(exe, append([]string{"-t", "template.txt"}, args...)...)
+	cmd.Dir = dir
+	cmd.Env = append(os.Environ(), "EG_TEST_IS_EG=1")
+	var outbuf, errbuf bytes.Buffer
+	cmd.Stdout = &outbuf
+	cmd.Stderr = &errbuf
+	err = cmd.Run()
+	if _, isExit := err.(*exec.ExitError); err != nil && !isExit {
+		t.Fatal(err)
+	}
+	return dir, outbuf.String(), errbuf.String(), err == nil
+}
+
+// checkUnchanged reports an error if eg modified the input file.
+func checkUnchanged(t *testing.T, dir string) {
+	t.Helper()
+	data, err := os.ReadFile(filepath.Join(dir, "a", "a.go"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Contains(data, []byte(`strings.Index(s, "x") >= 0`)) {
+		t.Errorf("a.go was modified:\n%s", data)
+	}
+}
+
+func TestDiff(t *testing.T) {
+	dir, stdout, stderr, ok := runEg(t, "-d", "./a")
+	if !ok {
+		t.Fatalf("eg -d failed: %s", stderr)
+	}
+	checkUnchanged(t, dir)
+
+	filename := filepath.Join(dir, "a", "a.go")
+	for _, want := range []string{
+		"--- " + filename + ".orig\n",
+		"+++ " + filename + "\n",
+		"-\treturn strings.Index(s, \"x\") >= 0\n",
+		"+\treturn strings.Contains(s, \"x\")\n",
+	} {
+		if !strings.Contains(stdout, want) {
+			t.Errorf("eg -d output does not contain %q:\n%s", want, stdout)
+		}
+	}
+	if want := "=== " + filename + " (1 matches)"; !strings.Contains(stderr, want) {
+		t.Errorf("eg -d standard error does not contain %q:\n%s", want, stderr)
+	}
+}
+
+func TestJSON(t *testing.T) {
+	dir, stdout, stderr, ok := runEg(t, "-json", "./a")
+	if !ok {
+		t.Fatalf("eg -json failed: %s", stderr)
+	}
+	checkUnchanged(t, dir)
+
+	var matches []jsonMatch
+	dec := json.NewDecoder(strings.NewReader(stdout))
+	for {
+		var m jsonMatch
+		if err := dec.Decode(&m); err == io.EOF {
+			break
+		} else if err != nil {
+			t.Fatalf("invalid JSON output: %v\n%s", err, stdout)
+		}
+		matches = append(matches, m)
+	}
+	want := jsonMatch{
+		Package:   "example.com/a",
+		File:      filepath.Join(dir, "a", "a.go"),
+		Line:      6,
+		Column:    9,
+		EndLine:   6,
+		EndColumn: 35,
+		Rule:      "before",
+		Old:       `strings.Index(s, "x") >= 0`,
+		New:       `strings.Contains(s, "x")`,
+	}
+	if len(matches) != 1 || matches[0] != want {
+		t.Errorf("eg -json matches = %+v, want [%+v]", matches, want)
+	}
+}
+
+func TestExclusiveFlags(t *testing.T) {
+	dir, _, stderr, ok := runEg(t, "-d", "-json", "./a")
+	if ok {
+		t.Fatal("eg -d -json succeeded")
+	}
+	checkUnchanged(t, dir)
+	if want := "at most one of -w, -d and -json may be specified"; !strings.Contains(stderr, want) {
+		t.Errorf("eg -d -json standard error does not contain %q:\n%s", want, stderr)
+	}
+}
@@ -41,6 +41,34 @@ the configuration file to ignore when scanning. No globbing or regex
 patterns are allowed. Use the "-v" verbose flag to verify it's
 working and see what goimports is doing.
 
+The -local flag places imports beginning with the given prefixes in a
+separate group after third-party imports. For finer control, the
+-grouping flag names a file of rules, one per line, that define the
+groups in order of appearance and the names required for some imports:
+
+	std                           # standard library imports
+	default                       # imports that belong to no other group
+	prefix example.com/           # imports beginning with one of the prefixes
+	regexp /gen/                  # imports matching the regular expression
+	blank                         # blank imports
+	dot                           # dot imports
+	alias example.com/gen/v1 genpb # import path must be named genpb
+
+An import belongs to the most specific group that matches it: blank
+and dot imports to their own groups, if present; then the first
+matching regexp group; then the prefix group with the longest matching
+prefix; then the std group for the standard library; then the default
+group.
+
+In module mode, goimports finds missing packages by scanning the
+dependencies of the main module and the module cache. With the
+-modindex flag, it instead consults an index of the exported symbols
+of the module cache, which it creates or brings up to date as needed.
+The index is much faster than a scan of a large module cache. When
+several packages in the index could satisfy a reference, goimports
+prefers those whose functions accept the number of arguments of the
+calls in the file.
+
 File bugs or feature requests at:
 
 	https://golang.org/issues/new?title=x/tools/cmd/goimports:+
@@ -26,10 +26,11 @@ import (
 
 var (
 	// main operation modes
-	list   = flag.Bool("l", false, "list files whose formatting differs from goimport's")
-	write  = flag.Bool("w", false, "write result to (source) file instead of stdout")
-	doDiff = flag.Bool("d", false, "display diffs instead of rewriting files")
-	srcdir = flag.String("srcdir", "", "choose imports as if source code is from `dir`. When operating on a single file, dir may instead be the complete file name.")
+	list     = flag.Bool("l", false, "list files whose formatting differs from goimport's")
+	write    = flag.Bool("w", false, "write result to (source) file instead of stdout")
+	doDiff   = flag.Bool("d", false, "display diffs instead of rewriting files")
+	srcdir   = flag.String("srcdir", "", "choose imports as if source code is from `dir`. When operating on a single file, dir may instead be the complete file name.")
+	grouping = flag.String("grouping", "", "read import grouping rules from `file`, overriding -local")
 
 	verbose bool // verbose logging
 
@@ -52,6 +53,7 @@ var (
 func init() {
 	flag.BoolVar(&options.AllErrors, "e", false, "report all errors (not just the first 10 on different lines)")
 	flag.StringVar(&options.LocalPrefix, "local", "", "put imports beginning with this string after 3rd-party packages; comma-separated list")
+	flag.BoolVar(&options.Env.UseModIndex, "modindex", false, "find packages in the module cache using its index of exported symbols, instead of scanning it")
 	flag.BoolVar(&options.FormatOnly, "format-only", false, "if true, don't fix imports and only format. In this mode, goimports is effectively gofmt, with the addition that imports are grouped into sections.")
 }
 
@@ -262,6 +264,17 @@ func gofmtMain() {
 		log.SetFlags(log.LstdFlags | log.Lmicroseconds)
 		options.Env.Logf = log.Printf
 	}
+	if *grouping != "" {
+		data, err := os.ReadFile(*grouping)
+		if err == nil {
+			options.Grouping, err = imports.ParseGrouping(strings.Split(string(data), "\n"))
+		}
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "reading grouping rules: %v\n", err)
+			exitCode = 2
+			return
+		}
+	}
 	if options.TabWidth < 0 {
 		fmt.Fprintf(os.Stderr, "negative tabwidth %d\n", options.TabWidth)
 		exitCode = 2
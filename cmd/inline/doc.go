@@ -0,0 +1,50 @@
+// Copyright 2024 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+/*
+The inline command inlines all calls to selected functions.
+
+Usage: inline [flags] package...
+
+The inline command loads the specified packages from source, and
+replaces each static call within them to a selected function or method
+by the body of the callee, using the same inliner as gopls' "Inline
+call" code action. It is typically used to migrate all the callers of a
+deprecated function to its replacement, once the deprecated function
+has been reimplemented in terms of the replacement.
+
+The functions to inline are selected by the -func flag, a
+comma-separated list of names in the form printed by
+types.Func.FullName, such as "example.com/pkg.F" or
+"(*example.com/pkg.T).M"; or by the -annotated flag, which selects
+every function whose declaration is preceded by a "//go:fix inline"
+directive, or whose doc comment has a line consisting of the word
+"inlineme". The
+callees may be declared in any package imported by the specified
+packages, directly or indirectly.
+
+Calls that cannot be inlined, for example because a callee refers to
+unexported symbols of another package, are reported and left unchanged.
+Calls that result from inlining are themselves inlined, up to a limit.
+
+Unless -remove=false, the declaration of each selected function (not
+method) that, once the calls are inlined, is no longer referenced by
+any loaded package, is removed, along with imports that thereby become
+unused. Beware that an exported function may still be used by
+packages that were not loaded.
+
+By default, the updated files are printed to the standard output.
+The -d flag causes the changes to be displayed as unified diffs, and
+the -w flag causes the files to be updated in place.
+
+Example: inline all calls to the annotated functions within the
+current module, and update the files:
+
+	$ inline -annotated -w ./...
+
+Example: preview the inlining of calls to ioutil.ReadFile:
+
+	$ inline -d -func=io/ioutil.ReadFile ./...
+*/
+package main
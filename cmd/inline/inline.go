@@ -0,0 +1,573 @@
+// Copyright 2024 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"bytes"
+	_ "embed"
+	"flag"
+	"fmt"
+	"go/ast"
+	"go/format"
+	"go/parser"
+	"go/token"
+	"go/types"
+	"io"
+	"log"
+	"os"
+	"sort"
+	"strings"
+
+	"golang.org/x/tools/go/ast/astutil"
+	"golang.org/x/tools/go/packages"
+	"golang.org/x/tools/internal/diff"
+	"golang.org/x/tools/internal/refactor/inline"
+)
+
+//go:embed doc.go
+var doc string
+
+// flags
+var (
+	funcFlag      = flag.String("func", "", "comma-separated list of functions to inline, such as example.com/pkg.F")
+	annotatedFlag = flag.Bool("annotated", false, "inline calls to functions annotated with //go:fix inline")
+	removeFlag    = flag.Bool("remove", true, "remove the declarations of inlined functions that are no longer referenced")
+	testFlag      = flag.Bool("test", true, "include test files")
+	tagsFlag      = flag.String("tags", "", "comma-separated list of extra build tags (see: go help buildconstraint)")
+	writeFlag     = flag.Bool("w", false, "write the updated files in place")
+	diffFlag      = flag.Bool("d", false, "display diffs instead of the updated files")
+	verboseFlag   = flag.Bool("v", false, "log the decisions of the inliner")
+)
+
+// maxInlines is the maximum number of calls inlined in a single file,
+// which bounds the inlining of recursive functions.
+const maxInlines = 1000
+
+func usage() {
+	// Extract the content of the /* ... */ comment in doc.go.
+	_, after, _ := strings.Cut(doc, "/*\n")
+	doc, _, _ := strings.Cut(after, "*/")
+	io.WriteString(flag.CommandLine.Output(), doc+`
+Flags:
+
+`)
+	flag.PrintDefaults()
+}
+
+func main() {
+	log.SetPrefix("inline: ")
+	log.SetFlags(0) // no time prefix
+
+	flag.Usage = usage
+	flag.Parse()
+	if len(flag.Args()) == 0 {
+		usage()
+		os.Exit(2)
+	}
+	if *funcFlag == "" && !*annotatedFlag {
+		log.Fatalf("no functions selected: use -func or -annotated")
+	}
+	if *writeFlag && *diffFlag {
+		log.Fatalf("you cannot specify both -w and -d")
+	}
+
+	// Load, parse, and type-check the packages and their dependencies.
+	cfg := &packages.Config{
+		BuildFlags: []string{"-tags=" + *tagsFlag},
+		Mode:       packages.LoadAllSyntax | packages.NeedModule,
+		Tests:      *testFlag,
+	}
+	initial, err := packages.Load(cfg, flag.Args()...)
+	if err != nil {
+		log.Fatalf("Load: %v", err)
+	}
+	if len(initial) == 0 {
+		log.Fatalf("no packages")
+	}
+	if packages.PrintErrors(initial) > 0 {
+		log.Fatalf("packages contain errors")
+	}
+
+	in := newInliner(initial)
+	names := make(map[string]bool)
+	if *funcFlag != "" {
+		for _, name := range strings.Split(*funcFlag, ",") {
+			names[strings.TrimSpace(name)] = true
+		}
+	}
+	in.findCallees(names, *annotatedFlag)
+	for name := range names {
+		if _, ok := in.callees[name]; !ok {
+			log.Printf("function %s not found", name)
+		}
+	}
+
+	// Inline the calls in each file of the initial packages.
+	var ncalls, nfiles int
+	for _, pkg := range initial {
+		if pkg.Name == "main" && strings.HasSuffix(pkg.ID, ".test") {
+			continue // generated test main package
+		}
+		for i, filename := range pkg.CompiledGoFiles {
+			if in.files[filename] != nil || !strings.HasSuffix(filename, ".go") {
+				continue // seen in another variant, or generated by cgo
+			}
+			n := in.inlineFile(pkg, i)
+			if n > 0 {
+				ncalls += n
+				nfiles++
+			}
+		}
+	}
+	if *removeFlag {
+		in.removeUnused()
+	}
+
+	// Print or write the updated files.
+	var filenames []string
+	for filename, f := range in.files {
+		if f.changed {
+			filenames = append(filenames, filename)
+		}
+	}
+	sort.Strings(filenames)
+	exitCode := 0
+	for _, filename := range filenames {
+		f := in.files[filename]
+		var buf bytes.Buffer
+		if err := format.Node(&buf, f.fset, f.file); err != nil {
+			log.Printf("formatting %s: %v", filename, err)
+			exitCode = 1
+			continue
+		}
+		switch {
+		case *writeFlag:
+			if err := os.WriteFile(filename, buf.Bytes(), 0666); err != nil {
+				log.Print(err)
+				exitCode = 1
+			}
+		case *diffFlag:
+			fmt.Print(diff.Unified(filename+".orig", filename, string(f.orig), buf.String()))
+		default:
+			fmt.Fprintf(os.Stderr, "=== %s\n", filename)
+			os.Stdout.Write(buf.Bytes())
+		}
+	}
+	fmt.Fprintf(os.Stderr, "inlined %d calls in %d files\n", ncalls, nfiles)
+	if in.errors {
+		exitCode = 1
+	}
+	os.Exit(exitCode)
+}
+
+// An inliner holds the state of the inlining of calls in a set of
+// packages.
+type inliner struct {
+	initial []*packages.Package
+	roots   map[*types.Package]bool   // types of the initial packages
+	byPath  map[string]*types.Package // types of all loaded packages, by path
+	callees map[string]*callee        // functions selected for inlining, by full name; nil if not inlinable
+	files   map[string]*fileState     // state of files of the initial packages, by name
+	content map[string][]byte         // memoized file contents
+	errors  bool                      // whether some calls could not be inlined
+	logf    func(format string, args ...any)
+}
+
+// A callee is a function selected for inlining.
+type callee struct {
+	name     string         // full name, as by types.Func.FullName
+	filename string         // name of the file of its declaration
+	method   bool           // whether it is a method
+	root     bool           // whether it is declared in an initial package
+	inline   *inline.Callee // summary for the inliner
+	ncalls   int            // number of calls inlined
+}
+
+// A fileState holds the current syntax and type information of a file
+// of the initial packages.
+type fileState struct {
+	fset    *token.FileSet
+	orig    []byte // original content
+	file    *ast.File
+	info    *types.Info
+	changed bool
+}
+
+func newInliner(initial []*packages.Package) *inliner {
+	in := &inliner{
+		initial: initial,
+		roots:   make(map[*types.Package]bool),
+		byPath:  make(map[string]*types.Package),
+		callees: make(map[string]*callee),
+		files:   make(map[string]*fileState),
+		content: make(map[string][]byte),
+		logf:    func(string, ...any) {},
+	}
+	if *verboseFlag {
+		in.logf = log.Printf
+	}
+	for _, pkg := range initial {
+		in.roots[pkg.Types] = true
+	}
+	packages.Visit(initial, nil, func(pkg *packages.Package) {
+		// Prefer the package to its test variants.
+		if _, ok := in.byPath[pkg.PkgPath]; !ok || pkg.ID == pkg.PkgPath {
+			in.byPath[pkg.PkgPath] = pkg.Types
+		}
+	})
+	return in
+}
+
+// readFile returns the content of the named file.
+func (in *inliner) readFile(filename string) ([]byte, error) {
+	content, ok := in.content[filename]
+	if !ok {
+		var err error
+		content, err = os.ReadFile(filename)
+		if err != nil {
+			return nil, err
+		}
+		in.content[filename] = content
+	}
+	return content, nil
+}
+
+// findCallees records the functions declared in the loaded packages
+// that are named in names or, if annotated, that are annotated for
+// inlining.
+func (in *inliner) findCallees(names map[string]bool, annotated bool) {
+	packages.Visit(in.initial, nil, func(pkg *packages.Package) {
+		for _, file := range pkg.Syntax {
+			for _, decl := range file.Decls {
+				decl, ok := decl.(*ast.FuncDecl)
+				if !ok || decl.Body == nil {
+					continue
+				}
+				fn, ok := pkg.TypesInfo.Defs[decl.Name].(*types.Func)
+				if !ok {
+					continue
+				}
+				name := fn.FullName()
+				if _, seen := in.callees[name]; seen || !(names[name] || annotated && isAnnotated(decl)) {
+					continue
+				}
+				filename := pkg.Fset.File(decl.Pos()).Name()
+				content, err := in.readFile(filename)
+				if err != nil {
+					log.Print(err)
+					continue
+				}
+				c, err := inline.AnalyzeCallee(in.logf, pkg.Fset, pkg.Types, pkg.TypesInfo, decl, content)
+				if err == nil && isRecursive(pkg.TypesInfo, decl, fn) {
+					err = fmt.Errorf("function is recursive")
+				}
+				if err != nil {
+					log.Printf("%s: cannot inline %s: %v", pkg.Fset.Position(decl.Name.Pos()), name, err)
+					in.errors = true
+					in.callees[name] = nil
+					continue
+				}
+				in.callees[name] = &callee{
+					name:     name,
+					filename: filename,
+					method:   decl.Recv != nil,
+					root:     in.roots[pkg.Types],
+					inline:   c,
+				}
+			}
+		}
+	})
+}
+
+// isRecursive reports whether the body of the declaration of fn
+// refers to fn.
+func isRecursive(info *types.Info, decl *ast.FuncDecl, fn *types.Func) bool {
+	found := false
+	ast.Inspect(decl.Body, func(n ast.Node) bool {
+		if id, ok := n.(*ast.Ident); ok && info.Uses[id] != nil {
+			if f, ok := info.Uses[id].(*types.Func); ok && f.Origin() == fn {
+				found = true
+			}
+		}
+		return !found
+	})
+	return found
+}
+
+// isAnnotated reports whether the function declaration is annotated
+// for inlining by a "//go:fix inline" directive, or by a doc comment
+// line consisting of the word "inlineme". Unlike the inline analyzer,
+// it ignores doc comments that merely mention the word, as the
+// selected functions may then be removed.
+func isAnnotated(decl *ast.FuncDecl) bool {
+	if decl.Doc == nil {
+		return false
+	}
+	for _, c := range decl.Doc.List {
+		if strings.TrimSpace(c.Text) == "//go:fix inline" {
+			return true
+		}
+	}
+	for _, line := range strings.Split(decl.Doc.Text(), "\n") {
+		if strings.TrimSpace(line) == "inlineme" {
+			return true
+		}
+	}
+	return false
+}
+
+// inlineFile inlines the calls to the callees in the ith file of pkg,
+// and returns the number of calls inlined.
+func (in *inliner) inlineFile(pkg *packages.Package, i int) int {
+	filename := pkg.CompiledGoFiles[i]
+	content, err := in.readFile(filename)
+	if err != nil {
+		log.Print(err)
+		in.errors = true
+		return 0
+	}
+	state := &fileState{
+		fset: pkg.Fset,
+		orig: content,
+		file: pkg.Syntax[i],
+		info: pkg.TypesInfo,
+	}
+	in.files[filename] = state
+
+	var (
+		file    = pkg.Syntax[i]
+		tpkg    = pkg.Types
+		info    = pkg.TypesInfo
+		inlined = make(map[*callee]int)
+		n, skip int // numbers of calls inlined and not
+	)
+	for {
+		calls := in.calls(info, file)
+		if skip >= len(calls) {
+			break
+		}
+		if n == maxInlines {
+			log.Printf("%s: too many calls inlined; is a callee recursive?", filename)
+			in.errors = true
+			break
+		}
+		call := calls[skip]
+		c := in.callees[calleeName(info, call)]
+		caller := &inline.Caller{
+			Fset:    pkg.Fset,
+			Types:   tpkg,
+			Info:    info,
+			File:    file,
+			Call:    call,
+			Content: content,
+		}
+		res, err := inline.Inline(caller, c.inline, &inline.Options{Logf: in.logf})
+		if err != nil {
+			log.Printf("%s: cannot inline call to %s: %v", pkg.Fset.Position(call.Lparen), c.name, err)
+			in.errors = true
+			skip++
+			continue
+		}
+
+		// Parse and type-check the package again, for the next call.
+		file2, err := parser.ParseFile(pkg.Fset, filename, res.Content, parser.ParseComments|parser.SkipObjectResolution)
+		if err == nil {
+			tpkg, info, err = in.check(pkg, i, file2)
+		}
+		if err != nil {
+			// Abandon all the changes to the file.
+			log.Printf("%s: inlining call to %s produced invalid code: %v", pkg.Fset.Position(call.Lparen), c.name, err)
+			in.errors = true
+			return 0
+		}
+		file, content = file2, res.Content
+		inlined[c]++
+		n++
+	}
+
+	if n > 0 {
+		state.file, state.info, state.changed = file, info, true
+		for c, k := range inlined {
+			c.ncalls += k
+		}
+		fmt.Fprintf(os.Stderr, "%s: inlined %d calls\n", filename, n)
+	}
+	return n
+}
+
+// calls returns the calls in file, in order, to functions selected
+// for inlining, except recursive calls.
+func (in *inliner) calls(info *types.Info, file *ast.File) []*ast.CallExpr {
+	var calls []*ast.CallExpr
+	for _, decl := range file.Decls {
+		var self string // full name of the enclosing function
+		if decl, ok := decl.(*ast.FuncDecl); ok {
+			if fn, ok := info.Defs[decl.Name].(*types.Func); ok {
+				self = fn.FullName()
+			}
+		}
+		ast.Inspect(decl, func(n ast.Node) bool {
+			if call, ok := n.(*ast.CallExpr); ok {
+				if name := calleeName(info, call); name != self && in.callees[name] != nil {
+					calls = append(calls, call)
+				}
+			}
+			return true
+		})
+	}
+	return calls
+}
+
+// calleeName returns the full name of the function or concrete method
+// called by call, or "" if it is not statically known.
+func calleeName(info *types.Info, call *ast.CallExpr) string {
+	if fn := inline.ConcreteCallee(info, call); fn != nil {
+		return fn.Origin().FullName()
+	}
+	return ""
+}
+
+// check type-checks pkg again, with file as its ith file.
+func (in *inliner) check(pkg *packages.Package, i int, file *ast.File) (*types.Package, *types.Info, error) {
+	files := append([]*ast.File(nil), pkg.Syntax...)
+	files[i] = file
+	info := &types.Info{
+		Types:        make(map[ast.Expr]types.TypeAndValue),
+		Defs:         make(map[*ast.Ident]types.Object),
+		Uses:         make(map[*ast.Ident]types.Object),
+		Implicits:    make(map[ast.Node]types.Object),
+		Selections:   make(map[*ast.SelectorExpr]*types.Selection),
+		Scopes:       make(map[ast.Node]*types.Scope),
+		Instances:    make(map[*ast.Ident]types.Instance),
+		FileVersions: make(map[*ast.File]string),
+	}
+	cfg := &types.Config{
+		Importer: importerFunc(func(path string) (*types.Package, error) {
+			if imp, ok := pkg.Imports[path]; ok {
+				return imp.Types, nil
+			}
+			// An import added by the inliner.
+			if tpkg, ok := in.byPath[path]; ok {
+				return tpkg, nil
+			}
+			return nil, fmt.Errorf("package %q was not loaded", path)
+		}),
+		Sizes: pkg.TypesSizes,
+	}
+	if pkg.Module != nil && pkg.Module.GoVersion != "" {
+		cfg.GoVersion = "go" + pkg.Module.GoVersion
+	}
+	tpkg, err := cfg.Check(pkg.PkgPath, pkg.Fset, files, info)
+	return tpkg, info, err
+}
+
+type importerFunc func(path string) (*types.Package, error)
+
+func (f importerFunc) Import(path string) (*types.Package, error) { return f(path) }
+
+// removeUnused removes the declarations of the functions, declared in
+// the initial packages, some of whose calls were inlined, that are no
+// longer referenced, along with the imports that thereby become
+// unused.
+func (in *inliner) removeUnused() {
+	// Count the remaining references to each callee, except those
+	// within its own declaration.
+	refs := make(map[string]int)
+	for _, f := range in.files {
+		ast.Inspect(f.file, func(n ast.Node) bool {
+			if decl, ok := n.(*ast.FuncDecl); ok {
+				if fn, ok := f.info.Defs[decl.Name].(*types.Func); ok {
+					self := fn.FullName()
+					ast.Inspect(decl, func(n ast.Node) bool {
+						if id, ok := n.(*ast.Ident); ok {
+							if name := funcName(f.info.Uses[id]); name != "" && name != self {
+								refs[name]++
+							}
+						}
+						return true
+					})
+					return false
+				}
+			}
+			if id, ok := n.(*ast.Ident); ok {
+				if name := funcName(f.info.Uses[id]); name != "" {
+					refs[name]++
+				}
+			}
+			return true
+		})
+	}
+
+	for _, c := range in.callees {
+		if c == nil || !c.root || c.method || c.ncalls == 0 || refs[c.name] > 0 {
+			continue
+		}
+		f := in.files[c.filename]
+		if f == nil {
+			continue // file not loaded, e.g. excluded by build tags
+		}
+		for i, decl := range f.file.Decls {
+			if decl, ok := decl.(*ast.FuncDecl); ok && funcName(f.info.Defs[decl.Name]) == c.name {
+				removeDecl(f, i)
+				fmt.Fprintf(os.Stderr, "removed unused function %s\n", c.name)
+				break
+			}
+		}
+	}
+}
+
+// funcName returns the full name of obj if it is a function, or "".
+func funcName(obj types.Object) string {
+	if fn, ok := obj.(*types.Func); ok {
+		return fn.Origin().FullName()
+	}
+	return ""
+}
+
+// removeDecl removes the ith declaration of the file, its comments,
+// and the imports used only by it.
+func removeDecl(f *fileState, i int) {
+	decl := f.file.Decls[i].(*ast.FuncDecl)
+	start, end := decl.Pos(), decl.End()
+	if decl.Doc != nil {
+		start = decl.Doc.Pos()
+	}
+	within := func(n ast.Node) bool { return start <= n.Pos() && n.End() <= end }
+
+	f.file.Decls = append(f.file.Decls[:i:i], f.file.Decls[i+1:]...)
+	var comments []*ast.CommentGroup
+	for _, cg := range f.file.Comments {
+		if !within(cg) {
+			comments = append(comments, cg)
+		}
+	}
+	f.file.Comments = comments
+	f.changed = true
+
+	// Remove the imports that are no longer used.
+	used := make(map[*types.PkgName]bool)
+	for id, obj := range f.info.Uses {
+		if pkgname, ok := obj.(*types.PkgName); ok && !within(id) {
+			used[pkgname] = true
+		}
+	}
+	for _, spec := range f.file.Imports {
+		var obj types.Object
+		if spec.Name != nil {
+			obj = f.info.Defs[spec.Name]
+		} else {
+			obj = f.info.Implicits[spec]
+		}
+		pkgname, ok := obj.(*types.PkgName)
+		if !ok || used[pkgname] {
+			continue // blank or dot import, or used
+		}
+		name := ""
+		if spec.Name != nil {
+			name = spec.Name.Name
+		}
+		astutil.DeleteNamedImport(f.fset, f.file, name, pkgname.Imported().Path())
+	}
+}
@@ -9,14 +9,21 @@ import (
 	"flag"
 	"fmt"
 	"go/build"
+	"go/token"
 	"go/types"
+	"io"
 	"os"
+	"path/filepath"
 	"runtime"
 	"runtime/pprof"
+	"strconv"
+	"strings"
 
+	"golang.org/x/tools/go/callgraph/cha"
 	"golang.org/x/tools/go/packages"
 	"golang.org/x/tools/go/ssa"
 	"golang.org/x/tools/go/ssa/interp"
+	"golang.org/x/tools/go/ssa/slicing"
 	"golang.org/x/tools/go/ssa/ssautil"
 )
 
@@ -32,8 +39,17 @@ var (
 The value is a sequence of zero or more more of these letters:
 R	disable [R]ecover() from panic; show interpreter crash instead.
 T	[T]race execution of the program.  Best for single-threaded programs!
+D	[D]etect data races between goroutines.
 `)
 
+	seedFlag = flag.Int64("seed", 0, "if nonzero, run goroutines under the randomized schedule with this seed")
+
+	exploreFlag = flag.Int("explore", 0, "run the program under `N` randomized schedules (from -seed, or 1), with data race\ndetection, and report the first that panics, deadlocks or races")
+
+	sliceFlag = flag.String("slice", "", "print the backward slice of the instructions and variables at `FILE:LINE`; incompatible with -run")
+
+	forwardFlag = flag.Bool("forward", false, "with -slice, print the forward slice instead")
+
 	cpuprofile = flag.String("cpuprofile", "", "write cpu profile to file")
 
 	args stringListValue
@@ -47,17 +63,24 @@ func init() {
 }
 
 const usage = `SSA builder and interpreter.
-Usage: ssadump [-build=[DBCSNFLG]] [-test] [-run] [-interp=[TR]] [-arg=...] package...
+Usage: ssadump [-build=[DBCSNFLG]] [-test] [-run] [-interp=[TRD]] [-seed=N] [-explore=N] [-arg=...]
+               [-slice=FILE:LINE [-forward]] package...
 Use -help flag to display options.
 
 Examples:
-% ssadump -build=F hello.go              # dump SSA form of a single package
-% ssadump -build=F -test fmt             # dump SSA form of a package and its tests
-% ssadump -run -interp=T hello.go        # interpret a program, with tracing
+% ssadump -build=F hello.go                # dump SSA form of a single package
+% ssadump -build=F -test fmt               # dump SSA form of a package and its tests
+% ssadump -run -interp=T hello.go          # interpret a program, with tracing
+% ssadump -run -explore=100 prog.go        # look for a schedule that fails
+% ssadump -run -interp=D -seed=42 prog.go  # replay the failing schedule with seed 42
+% ssadump -slice=prog.go:42 prog.go        # what affects line 42 of prog.go?
 
 The -run flag causes ssadump to build the code in a runnable form and run the first
 package named main.
 
+The -explore flag enables data race detection (-interp=D), so a failing schedule
+is replayed with both -interp=D and its -seed.
+
 Interpretation of the standard "testing" package is no longer supported.
 `
 
@@ -75,6 +98,10 @@ func doMain() error {
 		os.Exit(1)
 	}
 
+	if *runFlag && *sliceFlag != "" {
+		return fmt.Errorf("-slice cannot be used with -run")
+	}
+
 	cfg := &packages.Config{
 		BuildFlags: []string{"-tags=" + *tagsFlag},
 		Mode:       packages.LoadSyntax,
@@ -100,6 +127,8 @@ func doMain() error {
 			interpMode |= interp.EnableTracing
 		case 'R':
 			interpMode |= interp.DisableRecover
+		case 'D':
+			interpMode |= interp.DetectRaces
 		default:
 			return fmt.Errorf("unknown -interp option: '%c'", c)
 		}
@@ -137,6 +166,11 @@ func doMain() error {
 		mode |= ssa.InstantiateGenerics
 	}
 
+	// Slicing needs the source-level variables.
+	if *sliceFlag != "" {
+		mode |= ssa.GlobalDebug
+	}
+
 	// Create SSA-form program representation.
 	prog, pkgs := ssautil.AllPackages(initial, mode)
 
@@ -153,6 +187,10 @@ func doMain() error {
 			p.Build()
 		}
 
+		if *sliceFlag != "" {
+			return slice(prog)
+		}
+
 	} else {
 		// Run the interpreter.
 		// Build SSA for all packages.
@@ -176,14 +214,128 @@ func doMain() error {
 
 		// Run first main package.
 		for _, main := range ssautil.MainPackages(pkgs) {
+			if *exploreFlag > 0 {
+				return explore(main, interpMode|interp.DetectRaces, sizes)
+			}
 			fmt.Fprintf(os.Stderr, "Running: %s\n", main.Pkg.Path())
-			os.Exit(interp.Interpret(main, interpMode, sizes, main.Pkg.Path(), args))
+			os.Exit(interp.InterpretWithSeed(main, interpMode, sizes, main.Pkg.Path(), args, *seedFlag))
 		}
 		return fmt.Errorf("no main package")
 	}
 	return nil
 }
 
+// slice prints the slice whose criteria are the instructions and
+// variables of the line specified by -slice, using the CHA call graph.
+func slice(prog *ssa.Program) error {
+	i := strings.LastIndex(*sliceFlag, ":")
+	if i < 0 {
+		return fmt.Errorf("-slice: want FILE:LINE, got %q", *sliceFlag)
+	}
+	line, err := strconv.Atoi((*sliceFlag)[i+1:])
+	if err != nil {
+		return fmt.Errorf("-slice: bad line number: %v", err)
+	}
+	name, err := filepath.Abs((*sliceFlag)[:i])
+	if err != nil {
+		return err
+	}
+	var file *token.File
+	prog.Fset.Iterate(func(f *token.File) bool {
+		if abs, err := filepath.Abs(f.Name()); err == nil && abs == name {
+			file = f
+			return false
+		}
+		return true
+	})
+	if file == nil {
+		return fmt.Errorf("-slice: file %s is not among the packages", (*sliceFlag)[:i])
+	}
+	if line < 1 || line > file.LineCount() {
+		return fmt.Errorf("-slice: file %s has no line %d", file.Name(), line)
+	}
+	start, end := file.LineStart(line), token.Pos(file.Base()+file.Size())
+	if line < file.LineCount() {
+		end = file.LineStart(line + 1)
+	}
+
+	var funcs []*ssa.Function
+	for fn := range ssautil.AllFunctions(prog) {
+		if pos := fn.Pos(); pos.IsValid() && prog.Fset.File(pos) == file {
+			funcs = append(funcs, fn)
+		}
+	}
+	criteria := slicing.At(funcs, start, end)
+	if len(criteria) == 0 {
+		return fmt.Errorf("-slice: no instructions at %s", *sliceFlag)
+	}
+
+	cg := cha.CallGraph(prog)
+	var s slicing.Slice
+	if *forwardFlag {
+		s = slicing.Forward(criteria, cg)
+	} else {
+		s = slicing.Backward(criteria, cg)
+	}
+	for _, n := range s.Nodes() {
+		var fn string
+		switch n := n.(type) {
+		case ssa.Instruction:
+			fn = n.Parent().String()
+		case *ssa.Parameter:
+			fn = n.Parent().String()
+		case *ssa.FreeVar:
+			fn = n.Parent().String()
+		}
+		text := n.String()
+		if v, ok := n.(ssa.Value); ok {
+			if _, ok := n.(ssa.Instruction); ok {
+				text = v.Name() + " = " + text
+			} else {
+				text = v.Name()
+			}
+		}
+		fmt.Printf("%s:\t%s\t%s\n", prog.Fset.Position(n.Pos()), fn, text)
+	}
+	return nil
+}
+
+// explore runs the main package under -explore randomized schedules,
+// discarding the output of each, until one exits with a nonzero code.
+// It then shows the output of the failing run and exits with its code.
+func explore(main *ssa.Package, interpMode interp.Mode, sizes types.Sizes) error {
+	seed := *seedFlag
+	if seed == 0 {
+		seed = 1
+	}
+	for i := 0; i < *exploreFlag; i, seed = i+1, seed+1 {
+		out, err := os.CreateTemp("", "ssadump")
+		if err != nil {
+			return err
+		}
+		stdout, stderr := os.Stdout, os.Stderr
+		os.Stdout, os.Stderr = out, out
+		code := interp.InterpretWithSeed(main, interpMode, sizes, main.Pkg.Path(), args, seed)
+		os.Stdout, os.Stderr = stdout, stderr
+
+		if code != 0 {
+			out.Seek(0, io.SeekStart)
+			io.Copy(os.Stderr, out)
+		}
+		out.Close()
+		os.Remove(out.Name())
+		if code != 0 {
+			// The schedule is replayed only under the same -interp flags,
+			// which include D as explore enables race detection.
+			flags := fmt.Sprintf("-interp=%sD -seed=%d", strings.ReplaceAll(*interpFlag, "D", ""), seed)
+			fmt.Fprintf(os.Stderr, "ssadump: schedule %d failed with exit code %d; replay it with %s\n", seed, code, flags)
+			os.Exit(code)
+		}
+	}
+	fmt.Fprintf(os.Stderr, "ssadump: %d schedules explored; none failed\n", *exploreFlag)
+	return nil
+}
+
 // stringListValue is a flag.Value that accumulates strings.
 // e.g. --flag=one --flag=two would produce []string{"one", "two"}.
 type stringListValue []string
@@ -0,0 +1,263 @@
+// Copyright 2024 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package pta
+
+import (
+	"bytes"
+	"fmt"
+	"go/token"
+	"go/types"
+	"sort"
+
+	"golang.org/x/tools/container/intsets"
+	"golang.org/x/tools/go/callgraph"
+	"golang.org/x/tools/go/ssa"
+	"golang.org/x/tools/go/types/typeutil"
+)
+
+// A Config formulates a pointer analysis problem for [Analyze].
+type Config struct {
+	// Mains contains the set of 'main' packages to analyze.
+	// The init and main functions of each are roots of the analysis.
+	Mains []*ssa.Package
+
+	// Roots contains additional functions to treat as roots of the
+	// analysis, for example the test functions of a package, or
+	// the exported functions of a library. The parameters of a
+	// root function point to nothing.
+	Roots []*ssa.Function
+}
+
+// A Result contains the results of a pointer analysis.
+type Result struct {
+	// CallGraph is the call graph of the program, containing all
+	// functions reachable from the roots. Its root node calls each
+	// root function.
+	CallGraph *callgraph.Graph
+
+	a *analysis
+}
+
+// Analyze runs the pointer analysis with the specified configuration.
+// It returns an error if the configuration has no roots.
+func Analyze(config *Config) (*Result, error) {
+	var roots []*ssa.Function
+	for _, mainpkg := range config.Mains {
+		if init := mainpkg.Func("init"); init != nil {
+			roots = append(roots, init)
+		}
+		if main := mainpkg.Func("main"); main != nil {
+			roots = append(roots, main)
+		} else {
+			return nil, fmt.Errorf("%s is not a main package", mainpkg.Pkg.Path())
+		}
+	}
+	roots = append(roots, config.Roots...)
+	if len(roots) == 0 {
+		return nil, fmt.Errorf("no root functions")
+	}
+
+	a := newAnalysis(roots[0].Prog)
+	for _, fn := range roots {
+		callgraph.AddEdge(a.cg.Root, nil, a.reach(fn))
+	}
+	a.solve()
+	return &Result{CallGraph: a.cg, a: a}, nil
+}
+
+// Reachable reports whether fn was found to be reachable from the roots.
+// Values of unreachable functions point to nothing.
+func (r *Result) Reachable(fn *ssa.Function) bool {
+	_, ok := r.a.cg.Nodes[fn]
+	return ok
+}
+
+// PointsTo returns the set of locations to which v may point.
+// v must have a pointer-like type: a pointer, slice, map, channel,
+// function, interface or unsafe.Pointer type. For values of other
+// types, PointsTo returns an empty set.
+func (r *Result) PointsTo(v ssa.Value) PointsToSet {
+	if !isPointerLike(v.Type()) {
+		return PointsToSet{r.a, nil}
+	}
+	id, ok := r.a.values[v]
+	if !ok {
+		switch v := v.(type) {
+		case *ssa.Global:
+			return r.singleton(r.a.globalObject(v))
+		case *ssa.Function:
+			return r.singleton(r.a.funcObject(v))
+		}
+		return PointsToSet{r.a, nil} // value of an unreachable function
+	}
+	return PointsToSet{r.a, &r.a.nodes[id].pts}
+}
+
+// PointsToIndirect returns the set of locations to which the variable
+// addressed by v may point; v must be a pointer to a pointer-like
+// type, such as the address of a struct field of interface type.
+func (r *Result) PointsToIndirect(v ssa.Value) PointsToSet {
+	ptr, ok := v.Type().Underlying().(*types.Pointer)
+	if !ok || !isPointerLike(ptr.Elem()) {
+		return PointsToSet{r.a, nil}
+	}
+	var pts intsets.Sparse
+	for _, loc := range r.PointsTo(v).locs() {
+		pts.UnionWith(&r.a.nodes[loc].pts)
+	}
+	return PointsToSet{r.a, &pts}
+}
+
+// MayAlias reports whether the pointer-like values x and y may point
+// to the same location.
+func (r *Result) MayAlias(x, y ssa.Value) bool {
+	return r.PointsTo(x).Intersects(r.PointsTo(y))
+}
+
+func (r *Result) singleton(obj *object) PointsToSet {
+	var pts intsets.Sparse
+	pts.Insert(int(obj.start))
+	return PointsToSet{r.a, &pts}
+}
+
+// A PointsToSet is a set of labels, each representing an abstract
+// location that a pointer-like value may point to.
+type PointsToSet struct {
+	a   *analysis
+	pts *intsets.Sparse // nil => empty
+}
+
+func (s PointsToSet) locs() []int {
+	if s.pts == nil {
+		return nil
+	}
+	return s.pts.AppendTo(nil)
+}
+
+// Labels returns the set of labels, in an arbitrary but deterministic order.
+func (s PointsToSet) Labels() []*Label {
+	var labels []*Label
+	for _, loc := range s.locs() {
+		if obj := s.a.nodes[loc].obj; obj != nil {
+			labels = append(labels, &Label{obj: obj, offset: nodeid(loc) - obj.start})
+		}
+	}
+	return labels
+}
+
+// Intersects reports whether this points-to set and the argument
+// points-to set contain at least one common label.
+func (s PointsToSet) Intersects(y PointsToSet) bool {
+	return s.pts != nil && y.pts != nil && s.pts.Intersects(y.pts)
+}
+
+// DynamicTypes returns the set of dynamic types that may be contained
+// in an interface value, along with the set of labels to which a
+// value of that dynamic type may point. If the dynamic type is not
+// pointer-like, its points-to set is empty.
+//
+// The result is meaningful only for points-to sets of interface values.
+func (s PointsToSet) DynamicTypes() *typeutil.Map {
+	var tmap typeutil.Map
+	tmap.SetHasher(s.a.hasher)
+	for _, loc := range s.locs() {
+		obj := s.a.nodes[loc].obj
+		if obj == nil || obj.tag == nil || nodeid(loc) != obj.start {
+			continue
+		}
+		pts, _ := tmap.At(obj.tag).(PointsToSet)
+		if pts.pts == nil {
+			pts = PointsToSet{s.a, new(intsets.Sparse)}
+			tmap.Set(obj.tag, pts)
+		}
+		if isPointerLike(obj.tag) {
+			pts.pts.UnionWith(&s.a.nodes[obj.start].pts)
+		}
+	}
+	return &tmap
+}
+
+func (s PointsToSet) String() string {
+	var labels []string
+	for _, l := range s.Labels() {
+		labels = append(labels, l.String())
+	}
+	sort.Strings(labels)
+	var buf bytes.Buffer
+	buf.WriteByte('[')
+	for i, l := range labels {
+		if i > 0 {
+			buf.WriteString(", ")
+		}
+		buf.WriteString(l)
+	}
+	buf.WriteByte(']')
+	return buf.String()
+}
+
+// A Label denotes an abstract location: a cell of an object allocated
+// at a particular allocation site.
+type Label struct {
+	obj    *object
+	offset nodeid // offset of the cell within obj
+}
+
+// Value returns the allocation site of the object containing the
+// labelled location: one of *ssa.Alloc, *ssa.MakeSlice,
+// *ssa.MakeMap, *ssa.MakeChan, *ssa.MakeInterface, *ssa.Call (for
+// calls to append), *ssa.Global or *ssa.Function.
+func (l *Label) Value() ssa.Value {
+	return l.obj.site
+}
+
+// Path returns the access path of the labelled location within its
+// object, for example ".f.g" for a field or "[*]" for an element of
+// an array. It is empty for the first cell of an object.
+func (l *Label) Path() string {
+	if l.offset == 0 {
+		return ""
+	}
+	return l.obj.layout[l.offset].path
+}
+
+// Pos returns the position of the allocation site.
+func (l *Label) Pos() token.Pos {
+	return l.obj.site.Pos()
+}
+
+// String returns a description of the label, such as "p.x.f" for a
+// field of global variable p.x, or "new@file.go:12:3" for the object
+// allocated by a new(T) expression.
+func (l *Label) String() string {
+	var s string
+	switch site := l.obj.site.(type) {
+	case *ssa.Global:
+		s = site.String()
+	case *ssa.Function:
+		s = site.String()
+	default:
+		var kind string
+		switch site := site.(type) {
+		case *ssa.Alloc:
+			kind = "new"
+			if !site.Heap {
+				kind = "local"
+			}
+		case *ssa.MakeSlice:
+			kind = "makeslice"
+		case *ssa.MakeMap:
+			kind = "makemap"
+		case *ssa.MakeChan:
+			kind = "makechan"
+		case *ssa.MakeInterface:
+			kind = "makeinterface:" + types.TypeString(site.X.Type(), (*types.Package).Name)
+		case *ssa.Call:
+			kind = "append"
+		}
+		prog := site.Parent().Prog
+		s = fmt.Sprintf("%s@%s", kind, prog.Fset.Position(site.Pos()))
+	}
+	return s + l.Path()
+}
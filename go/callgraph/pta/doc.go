@@ -0,0 +1,73 @@
+// Copyright 2024 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+/*
+Package pta implements an inclusion-based ("Andersen-style")
+points-to analysis for Go programs in SSA form. It computes, for each
+pointer-like value of the program, the set of abstract memory locations
+to which it may point, and builds a call graph of the program on the fly.
+
+The analysis is:
+
+  - inclusion-based: assignments x = y give rise to the constraint
+    pts(x) ⊇ pts(y), rather than unifying the two sets;
+  - field-sensitive: each field of a struct is a separate location,
+    so that p.f and p.g do not alias; all elements of an array,
+    slice, map or channel are represented by a single location;
+  - flow-insensitive: the order of instructions is ignored;
+  - context-insensitive: each function is analyzed once, and the
+    values of its parameters and results are merged across all
+    calls to it.
+
+# Abstract objects
+
+Each allocation site of the program gives rise to one abstract object:
+new(T) and &T{} (ssa.Alloc), make of slices, maps and channels, calls
+to append, conversions to interface (ssa.MakeInterface), each global
+variable, and each function. An object of struct type is divided into
+cells, one per field (recursively), and a points-to set contains cells
+rather than whole objects, so that &x.f and &x.g are distinct. A
+[Label] describes one such cell.
+
+Interface values point to the object created by the conversion to
+interface, which records the dynamic type of the value. This allows the
+analysis to resolve dynamic method calls and type assertions, and is
+reported by [PointsToSet.DynamicTypes].
+
+# Call graph
+
+The analysis begins at the root functions given by the [Config] and
+generates constraints only for functions found to be reachable from
+them. Dynamic calls through function values and interface method calls
+are resolved using the points-to sets computed so far, so the resulting
+call graph is usually considerably more precise than CHA or RTA.
+
+# Generics
+
+Programs should be built with the [ssa.InstantiateGenerics] mode, so that
+each instantiation of a generic function has its own body in terms of
+concrete types. The bodies of generic functions are analyzed too, if
+they are reachable, but values whose type is a type parameter are then
+treated as opaque.
+
+# Limitations
+
+The analysis is not sound in general: its results are only as
+complete as the function bodies it sees. A call to a function without
+a body, such as an assembly routine, a cgo function, a function
+implemented by the runtime via linkname, or any function of a package
+whose SSA form was created from export data rather than syntax, is
+treated as having no effect. Its result points to nothing, and the
+pointers passed to it are not assumed to escape or to be called, so
+points-to sets may be missing objects, and the call graph may be
+missing edges, wherever the program's data flows through such a
+function. The builtins append, copy and recover are modeled; other
+builtins are assumed to have no pointer effects.
+
+The analysis also does not model the reflect package, pointer
+arithmetic through unsafe.Pointer (conversions to unsafe.Pointer and
+back are tracked), or calls made by the runtime, such as those of
+finalizers set by runtime.SetFinalizer.
+*/
+package pta // import "golang.org/x/tools/go/callgraph/pta"
@@ -0,0 +1,362 @@
+// Copyright 2024 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package pta
+
+// This file defines the generation of constraints from SSA code.
+
+import (
+	"fmt"
+	"go/token"
+	"go/types"
+
+	"golang.org/x/tools/go/callgraph"
+	"golang.org/x/tools/go/ssa"
+	"golang.org/x/tools/internal/typeparams"
+)
+
+// valueNode returns the first node of the block for value v,
+// creating it if necessary.
+func (a *analysis) valueNode(v ssa.Value) nodeid {
+	id, ok := a.values[v]
+	if !ok {
+		id = a.addNodes(v.Type())
+		a.values[v] = id
+
+		// Globals and functions are constant pointers to their objects.
+		switch v := v.(type) {
+		case *ssa.Global:
+			a.addr(id, a.globalObject(v).start)
+		case *ssa.Function:
+			a.addr(id, a.funcObject(v).start)
+		}
+	}
+	return id
+}
+
+// resultNode returns the first node of the block for the results of fn.
+func (a *analysis) resultNode(fn *ssa.Function) nodeid {
+	id, ok := a.results[fn]
+	if !ok {
+		id = a.addNodes(fn.Signature.Results())
+		a.results[fn] = id
+	}
+	return id
+}
+
+// genFunc generates constraints for the body of function fn.
+func (a *analysis) genFunc(fn *ssa.Function) {
+	// Functions without a body (such as assembly routines)
+	// are not modeled.
+	for _, b := range fn.Blocks {
+		for _, instr := range b.Instrs {
+			a.genInstr(fn, instr)
+		}
+	}
+}
+
+func (a *analysis) genInstr(fn *ssa.Function, instr ssa.Instruction) {
+	switch instr := instr.(type) {
+	case *ssa.Alloc:
+		obj := a.addObject(instr, a.layout(deref(instr.Type())), nil)
+		a.addr(a.valueNode(instr), obj.start)
+
+	case *ssa.MakeSlice:
+		elem := typeparams.CoreType(instr.Type()).(*types.Slice).Elem()
+		obj := a.addObject(instr, a.elemLayout(elem), nil)
+		a.addr(a.valueNode(instr), obj.start)
+
+	case *ssa.MakeMap:
+		obj := a.addObject(instr, a.mapLayout(typeparams.CoreType(instr.Type()).(*types.Map)), nil)
+		a.addr(a.valueNode(instr), obj.start)
+
+	case *ssa.MakeChan:
+		elem := typeparams.CoreType(instr.Type()).(*types.Chan).Elem()
+		obj := a.addObject(instr, a.elemLayout(elem), nil)
+		a.addr(a.valueNode(instr), obj.start)
+
+	case *ssa.MakeInterface:
+		T := instr.X.Type()
+		obj := a.addObject(instr, a.layout(T), T)
+		a.copy(obj.start, a.valueNode(instr.X), a.sizeof(T))
+		a.addr(a.valueNode(instr), obj.start)
+
+	case *ssa.MakeClosure:
+		// Analysis is context-insensitive, so the bindings of
+		// all closures of a function flow to its free variables.
+		callee := instr.Fn.(*ssa.Function)
+		for i, b := range instr.Bindings {
+			fv := callee.FreeVars[i]
+			a.copy(a.valueNode(fv), a.valueNode(b), a.sizeof(fv.Type()))
+		}
+		a.copy1(a.valueNode(instr), a.valueNode(callee))
+
+	case *ssa.Store:
+		a.addComplex(a.valueNode(instr.Addr), &storeConstraint{
+			src:  a.valueNode(instr.Val),
+			size: a.sizeof(instr.Val.Type()),
+		})
+
+	case *ssa.UnOp:
+		switch instr.Op {
+		case token.MUL: // *x
+			a.load(instr, instr.X, 0)
+		case token.ARROW: // <-x
+			a.load(instr, instr.X, 0)
+		}
+
+	case *ssa.FieldAddr:
+		a.addComplex(a.valueNode(instr.X), &offsetAddrConstraint{
+			dst:    a.valueNode(instr),
+			offset: a.offsetOf(deref(instr.X.Type()), instr.Field),
+		})
+
+	case *ssa.Field:
+		offset := a.offsetOf(instr.X.Type(), instr.Field)
+		a.copy(a.valueNode(instr), a.valueNode(instr.X)+offset, a.sizeof(instr.Type()))
+
+	case *ssa.IndexAddr:
+		// Elements of arrays are represented by a single cell
+		// at offset zero.
+		a.addComplex(a.valueNode(instr.X), &offsetAddrConstraint{dst: a.valueNode(instr)})
+
+	case *ssa.Index:
+		if _, ok := typeparams.CoreType(instr.X.Type()).(*types.Array); ok {
+			a.copy(a.valueNode(instr), a.valueNode(instr.X), a.sizeof(instr.Type()))
+		}
+
+	case *ssa.Lookup:
+		if m, ok := typeparams.CoreType(instr.X.Type()).(*types.Map); ok {
+			a.load(instr, instr.X, nodeid(a.sizeof(m.Key())))
+		}
+
+	case *ssa.MapUpdate:
+		m := typeparams.CoreType(instr.Map.Type()).(*types.Map)
+		mapnode := a.valueNode(instr.Map)
+		a.addComplex(mapnode, &storeConstraint{
+			src:  a.valueNode(instr.Key),
+			size: a.sizeof(m.Key()),
+		})
+		a.addComplex(mapnode, &storeConstraint{
+			src:    a.valueNode(instr.Value),
+			offset: nodeid(a.sizeof(m.Key())),
+			size:   a.sizeof(m.Elem()),
+		})
+
+	case *ssa.Range:
+		if _, ok := typeparams.CoreType(instr.X.Type()).(*types.Map); ok {
+			a.copy1(a.valueNode(instr), a.valueNode(instr.X))
+		}
+
+	case *ssa.Next:
+		if !instr.IsString {
+			// The result is (ok, key, value); the map object
+			// is laid out as (key, value).
+			tuple := instr.Type().(*types.Tuple)
+			a.addComplex(a.valueNode(instr.Iter), &loadConstraint{
+				dst:  a.valueNode(instr) + a.tupleOffset(tuple, 1),
+				size: a.sizeof(tuple.At(1).Type()) + a.sizeof(tuple.At(2).Type()),
+			})
+		}
+
+	case *ssa.Send:
+		a.addComplex(a.valueNode(instr.Chan), &storeConstraint{
+			src:  a.valueNode(instr.X),
+			size: a.sizeof(instr.X.Type()),
+		})
+
+	case *ssa.Select:
+		// The result is (index, recvOk, r_0, ..., r_n-1).
+		tuple := instr.Type().(*types.Tuple)
+		recv := 2
+		for _, st := range instr.States {
+			elem := typeparams.CoreType(st.Chan.Type()).(*types.Chan).Elem()
+			if st.Dir == types.SendOnly {
+				a.addComplex(a.valueNode(st.Chan), &storeConstraint{
+					src:  a.valueNode(st.Send),
+					size: a.sizeof(elem),
+				})
+			} else {
+				a.addComplex(a.valueNode(st.Chan), &loadConstraint{
+					dst:  a.valueNode(instr) + a.tupleOffset(tuple, recv),
+					size: a.sizeof(elem),
+				})
+				recv++
+			}
+		}
+
+	case *ssa.Phi:
+		size := a.sizeof(instr.Type())
+		for _, edge := range instr.Edges {
+			a.copy(a.valueNode(instr), a.valueNode(edge), size)
+		}
+
+	case *ssa.Extract:
+		tuple := instr.Tuple.Type().(*types.Tuple)
+		a.copy(a.valueNode(instr), a.valueNode(instr.Tuple)+a.tupleOffset(tuple, instr.Index), a.sizeof(instr.Type()))
+
+	case *ssa.ChangeType:
+		a.copy(a.valueNode(instr), a.valueNode(instr.X), a.sizeof(instr.Type()))
+
+	case *ssa.ChangeInterface:
+		a.copy1(a.valueNode(instr), a.valueNode(instr.X))
+
+	case *ssa.SliceToArrayPointer:
+		a.copy1(a.valueNode(instr), a.valueNode(instr.X))
+
+	case *ssa.Slice:
+		if _, ok := typeparams.CoreType(instr.X.Type()).(*types.Basic); !ok { // not a string
+			a.copy1(a.valueNode(instr), a.valueNode(instr.X))
+		}
+
+	case *ssa.Convert:
+		// Conversions between pointer-like types (via unsafe.Pointer)
+		// preserve the points-to set.
+		if isPointerLike(instr.Type()) && isPointerLike(instr.X.Type()) {
+			a.copy1(a.valueNode(instr), a.valueNode(instr.X))
+		}
+
+	case *ssa.MultiConvert:
+		if size := a.sizeof(instr.Type()); size == a.sizeof(instr.X.Type()) {
+			a.copy(a.valueNode(instr), a.valueNode(instr.X), size)
+		}
+
+	case *ssa.TypeAssert:
+		dst := a.valueNode(instr) // (the first element, if CommaOk)
+		if iface, ok := instr.AssertedType.Underlying().(*types.Interface); ok {
+			a.addComplex(a.valueNode(instr.X), &typeFilterConstraint{iface: iface, dst: dst})
+		} else {
+			a.addComplex(a.valueNode(instr.X), &untagConstraint{
+				typ:  instr.AssertedType,
+				dst:  dst,
+				size: a.sizeof(instr.AssertedType),
+			})
+		}
+
+	case *ssa.Return:
+		results := fn.Signature.Results()
+		for i, r := range instr.Results {
+			a.copy(a.resultNode(fn)+a.tupleOffset(results, i), a.valueNode(r), a.sizeof(r.Type()))
+		}
+
+	case *ssa.Panic:
+		a.copy1(a.panic, a.valueNode(instr.X))
+
+	case ssa.CallInstruction:
+		a.genCall(fn, instr)
+
+	case *ssa.BinOp, *ssa.DebugRef, *ssa.Jump, *ssa.If, *ssa.RunDefers:
+		// No pointer flow.
+
+	default:
+		panic(fmt.Sprintf("unexpected instruction: %T", instr))
+	}
+}
+
+// load adds the constraint dst = *(x + offset), where the size of the
+// loaded block is that of dst (or of its first element, if it is a
+// tuple, as for comma-ok forms).
+func (a *analysis) load(dst ssa.Value, x ssa.Value, offset nodeid) {
+	t := dst.Type()
+	if tuple, ok := t.(*types.Tuple); ok {
+		t = tuple.At(0).Type()
+	}
+	a.addComplex(a.valueNode(x), &loadConstraint{
+		dst:    a.valueNode(dst),
+		offset: offset,
+		size:   a.sizeof(t),
+	})
+}
+
+// genCall generates constraints for a function call, go, or defer.
+func (a *analysis) genCall(caller *ssa.Function, site ssa.CallInstruction) {
+	common := site.Common()
+	switch {
+	case common.IsInvoke():
+		a.addComplex(a.valueNode(common.Value), &invokeConstraint{caller: caller, site: site})
+
+	case common.StaticCallee() != nil:
+		a.call(caller, site, common.StaticCallee(), nil)
+
+	default:
+		if b, ok := common.Value.(*ssa.Builtin); ok {
+			a.genBuiltin(site, b)
+		} else {
+			a.addComplex(a.valueNode(common.Value), &dynCallConstraint{caller: caller, site: site})
+		}
+	}
+}
+
+// call adds the call graph edge from site to callee, and the flows of
+// the arguments and results of the call. For an interface method
+// call, recv is the interface object whose value is the receiver.
+func (a *analysis) call(caller *ssa.Function, site ssa.CallInstruction, callee *ssa.Function, recv *object) {
+	key := callEdge{site, callee}
+	if a.edges[key] {
+		return
+	}
+	a.edges[key] = true
+	callgraph.AddEdge(a.cg.CreateNode(caller), site, a.reach(callee))
+
+	if callee.Blocks == nil {
+		return // no body, hence no parameters
+	}
+	common := site.Common()
+	params := callee.Params
+	if recv != nil {
+		a.copy(a.valueNode(params[0]), recv.start, a.sizeof(params[0].Type()))
+		params = params[1:]
+	}
+	for i, arg := range common.Args {
+		if i < len(params) {
+			a.copy(a.valueNode(params[i]), a.valueNode(arg), a.sizeof(params[i].Type()))
+		}
+	}
+	if v, ok := site.(ssa.Value); ok && callee.Signature.Results().Len() > 0 {
+		a.copy(a.valueNode(v), a.resultNode(callee), a.sizeof(v.Type()))
+	}
+}
+
+// genBuiltin generates constraints for a call to a built-in function.
+func (a *analysis) genBuiltin(site ssa.CallInstruction, b *ssa.Builtin) {
+	args := site.Common().Args
+	v, _ := site.(ssa.Value) // nil for go/defer
+	switch b.Name() {
+	case "append":
+		// append(s, x...) returns either s, or a new array
+		// containing the elements of s and x.
+		if v == nil {
+			return
+		}
+		elem := typeparams.CoreType(args[0].Type()).(*types.Slice).Elem()
+		obj := a.addObject(v.(*ssa.Call), a.elemLayout(elem), nil)
+		dst := a.valueNode(v)
+		a.addr(dst, obj.start)
+		a.copy1(dst, a.valueNode(args[0]))
+		for _, arg := range args {
+			if _, ok := typeparams.CoreType(arg.Type()).(*types.Slice); ok {
+				a.addComplex(a.valueNode(arg), &loadConstraint{dst: obj.start, size: len(obj.layout)})
+			}
+		}
+
+	case "copy":
+		// copy(dst, src) copies elements of src to dst.
+		if _, ok := typeparams.CoreType(args[1].Type()).(*types.Slice); ok {
+			elem := typeparams.CoreType(args[0].Type()).(*types.Slice).Elem()
+			tmp := a.addNodes(elem)
+			a.addComplex(a.valueNode(args[1]), &loadConstraint{dst: tmp, size: a.sizeof(elem)})
+			a.addComplex(a.valueNode(args[0]), &storeConstraint{src: tmp, size: a.sizeof(elem)})
+		}
+
+	case "recover":
+		if v != nil {
+			a.copy1(a.valueNode(v), a.panic)
+		}
+
+	case "ssa:wrapnilchk":
+		if v != nil {
+			a.copy1(a.valueNode(v), a.valueNode(args[0]))
+		}
+	}
+}
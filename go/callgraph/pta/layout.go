@@ -0,0 +1,139 @@
+// Copyright 2024 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package pta
+
+import (
+	"fmt"
+	"go/types"
+
+	"golang.org/x/tools/internal/typeparams"
+)
+
+// A leaf is an element of the layout of a type: a variable of
+// non-aggregate type within a value of that type.
+type leaf struct {
+	typ  types.Type // type of the leaf
+	path string     // access path of the leaf, e.g. ".f[*].g"
+}
+
+// layout returns the flattened layout of type t: the sequence of its
+// leaves, in which structs are replaced by their fields, tuples by
+// their elements, and arrays by a single element. Every type has at
+// least one leaf; a struct without fields has one leaf of its own type.
+//
+// A value of type t is represented by a block of len(layout(t)) nodes.
+func (a *analysis) layout(t types.Type) []leaf {
+	if !isGoType(t) {
+		// Types internal to the SSA builder, such as the type of
+		// a range iterator, have a single leaf.
+		return []leaf{{typ: t}}
+	}
+	if l, ok := a.layouts.At(t).([]leaf); ok {
+		return l
+	}
+
+	var l []leaf
+	switch u := typeparams.CoreType(t).(type) {
+	case *types.Struct:
+		for i := 0; i < u.NumFields(); i++ {
+			f := u.Field(i)
+			for _, fl := range a.layout(f.Type()) {
+				l = append(l, leaf{fl.typ, "." + f.Name() + fl.path})
+			}
+		}
+	case *types.Array:
+		for _, el := range a.layout(u.Elem()) {
+			l = append(l, leaf{el.typ, "[*]" + el.path})
+		}
+	case *types.Tuple:
+		for i := 0; i < u.Len(); i++ {
+			for _, el := range a.layout(u.At(i).Type()) {
+				l = append(l, leaf{el.typ, fmt.Sprintf("#%d%s", i, el.path)})
+			}
+		}
+	}
+	if len(l) == 0 {
+		l = []leaf{{typ: t}}
+	}
+
+	a.layouts.Set(t, l)
+	return l
+}
+
+// sizeof returns the number of nodes of a value of type t.
+func (a *analysis) sizeof(t types.Type) int {
+	return len(a.layout(t))
+}
+
+// offsetOf returns the offset of field i within a value of struct type t.
+func (a *analysis) offsetOf(t types.Type, i int) nodeid {
+	s := typeparams.CoreType(t).(*types.Struct)
+	var offset int
+	for j := 0; j < i; j++ {
+		offset += a.sizeof(s.Field(j).Type())
+	}
+	return nodeid(offset)
+}
+
+// tupleOffset returns the offset of element i within a value of tuple type t.
+func (a *analysis) tupleOffset(t *types.Tuple, i int) nodeid {
+	var offset int
+	for j := 0; j < i; j++ {
+		offset += a.sizeof(t.At(j).Type())
+	}
+	return nodeid(offset)
+}
+
+// elemLayout returns the layout of the elements of an array, slice or
+// channel object with elements of type elem.
+func (a *analysis) elemLayout(elem types.Type) []leaf {
+	var l []leaf
+	for _, el := range a.layout(elem) {
+		l = append(l, leaf{el.typ, "[*]" + el.path})
+	}
+	return l
+}
+
+// mapLayout returns the layout of a map object: its keys followed by its values.
+func (a *analysis) mapLayout(m *types.Map) []leaf {
+	var l []leaf
+	for _, el := range a.layout(m.Key()) {
+		l = append(l, leaf{el.typ, "[key]" + el.path})
+	}
+	for _, el := range a.layout(m.Elem()) {
+		l = append(l, leaf{el.typ, "[value]" + el.path})
+	}
+	return l
+}
+
+// isGoType reports whether t is one of the types defined by go/types.
+func isGoType(t types.Type) bool {
+	switch t.(type) {
+	case *types.Basic, *types.Pointer, *types.Array, *types.Slice,
+		*types.Map, *types.Chan, *types.Struct, *types.Tuple,
+		*types.Signature, *types.Interface, *types.Named,
+		*types.Alias, *types.TypeParam, *types.Union:
+		return true
+	}
+	return false
+}
+
+// isPointerLike reports whether a value of type t is represented by
+// a single node that may point to objects.
+func isPointerLike(t types.Type) bool {
+	switch u := t.Underlying().(type) {
+	case *types.Pointer, *types.Slice, *types.Map, *types.Chan,
+		*types.Signature, *types.Interface:
+		return true
+	case *types.Basic:
+		return u.Kind() == types.UnsafePointer
+	}
+	return false
+}
+
+// deref returns the element type of pointer type t.
+func deref(t types.Type) types.Type {
+	return typeparams.CoreType(t).(*types.Pointer).Elem()
+}
@@ -0,0 +1,125 @@
+// Copyright 2024 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package pta_test
+
+import (
+	"go/ast"
+	"go/importer"
+	"go/parser"
+	"go/token"
+	"go/types"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"testing"
+
+	"golang.org/x/tools/go/callgraph/pta"
+	"golang.org/x/tools/go/ssa"
+	"golang.org/x/tools/go/ssa/ssautil"
+)
+
+// TestPointer analyzes each program in testdata and checks the
+// assertions in its comments, which are of the form
+//
+//	print(x) // @pointsto label | ... | label
+//	f()      // @calls function | ... | function
+//
+// A @pointsto assertion lists the labels of the points-to set of the
+// argument of the print call on the same line, with positions of
+// allocation sites reduced to their line number. A @calls assertion
+// lists the callees of the call on the same line.
+func TestPointer(t *testing.T) {
+	files, err := filepath.Glob("testdata/*.go")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, file := range files {
+		t.Run(filepath.Base(file), func(t *testing.T) {
+			fset := token.NewFileSet()
+			f, err := parser.ParseFile(fset, file, nil, parser.ParseComments)
+			if err != nil {
+				t.Fatal(err)
+			}
+			pkg := types.NewPackage("main", "")
+			ssapkg, _, err := ssautil.BuildPackage(&types.Config{Importer: importer.Default()}, fset, pkg, []*ast.File{f}, ssa.InstantiateGenerics)
+			if err != nil {
+				t.Fatal(err)
+			}
+			res, err := pta.Analyze(&pta.Config{Mains: []*ssa.Package{ssapkg}})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			// Index the call instructions of reachable functions by line.
+			calls := make(map[int][]ssa.CallInstruction)
+			for fn := range res.CallGraph.Nodes {
+				if fn == nil {
+					continue
+				}
+				for _, b := range fn.Blocks {
+					for _, instr := range b.Instrs {
+						if call, ok := instr.(ssa.CallInstruction); ok {
+							line := fset.Position(call.Pos()).Line
+							calls[line] = append(calls[line], call)
+						}
+					}
+				}
+			}
+
+			for _, group := range f.Comments {
+				for _, c := range group.List {
+					text := strings.TrimPrefix(c.Text, "//")
+					kind, want, ok := strings.Cut(strings.TrimSpace(text), " ")
+					if !ok {
+						kind = strings.TrimSpace(text)
+					}
+					if kind != "@pointsto" && kind != "@calls" {
+						continue
+					}
+					line := fset.Position(c.Pos()).Line
+					var got []string
+					found := false
+					for _, call := range calls[line] {
+						b, isBuiltin := call.Common().Value.(*ssa.Builtin)
+						switch {
+						case kind == "@pointsto" && isBuiltin && b.Name() == "print":
+							found = true
+							for _, l := range res.PointsTo(call.Common().Args[0]).Labels() {
+								got = append(got, posnRx.ReplaceAllString(l.String(), "@$1"))
+							}
+						case kind == "@calls" && !isBuiltin:
+							found = true
+							for _, e := range res.CallGraph.Nodes[call.Parent()].Out {
+								if e.Site == call {
+									got = append(got, e.Callee.Func.String())
+								}
+							}
+						}
+					}
+					if !found {
+						t.Errorf("%s:%d: no call for %s assertion", file, line, kind)
+						continue
+					}
+					sort.Strings(got)
+					var wants []string
+					for _, w := range strings.Split(want, "|") {
+						if w = strings.TrimSpace(w); w != "" {
+							wants = append(wants, w)
+						}
+					}
+					sort.Strings(wants)
+					if strings.Join(got, " | ") != strings.Join(wants, " | ") {
+						t.Errorf("%s:%d: %s: got [%s], want [%s]", file, line, kind,
+							strings.Join(got, " | "), strings.Join(wants, " | "))
+					}
+				}
+			}
+		})
+	}
+}
+
+// posnRx matches the position of an allocation site in a label.
+var posnRx = regexp.MustCompile(`@[^@]*:(\d+):\d+`)
@@ -0,0 +1,353 @@
+// Copyright 2024 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package pta
+
+// This file defines the constraint graph and its solver.
+
+import (
+	"go/types"
+
+	"golang.org/x/tools/container/intsets"
+	"golang.org/x/tools/go/callgraph"
+	"golang.org/x/tools/go/ssa"
+	"golang.org/x/tools/go/types/typeutil"
+)
+
+// A nodeid is the index of a node in analysis.nodes.
+//
+// Each pointer-like variable or memory cell of the program is
+// represented by a node. The value of a variable of struct, array or
+// tuple type is represented by a block of consecutive nodes, one per
+// leaf of its layout; so is each object.
+type nodeid uint32
+
+// A node is a variable of the constraint system.
+type node struct {
+	obj *object // object of which this node is a cell, or nil
+
+	pts     intsets.Sparse // points-to set: nodeids of object cells
+	prevPts intsets.Sparse // part of pts already processed by the solver
+	copyTo  intsets.Sparse // successors: pts(copyTo) ⊇ pts(this)
+	complex []constraint   // constraints whose operand is this node
+	queued  bool           // node is in the solver's worklist
+}
+
+// An object is an abstract memory object, allocated at a single site.
+type object struct {
+	start  nodeid     // nodeid of the first cell
+	site   ssa.Value  // allocation site (see Label.Value)
+	layout []leaf     // layout of the cells
+	tag    types.Type // dynamic type, for objects created by MakeInterface
+}
+
+// contains reports whether id is a cell of obj.
+func (obj *object) contains(id nodeid) bool {
+	return obj.start <= id && id < obj.start+nodeid(len(obj.layout))
+}
+
+// analysis holds the state of a pointer analysis.
+type analysis struct {
+	prog    *ssa.Program
+	hasher  typeutil.Hasher
+	layouts typeutil.Map // memoizes layout
+
+	nodes   []*node
+	values  map[ssa.Value]nodeid      // first node of each value
+	results map[*ssa.Function]nodeid  // first node of result block of each function
+	globals map[*ssa.Global]*object   // object of each global variable
+	funcs   map[*ssa.Function]*object // object of each function
+	panic   nodeid                    // all values passed to panic
+	edges   map[callEdge]bool         // call graph edges, for de-duplication
+	cg      *callgraph.Graph          // call graph
+	genq    []*ssa.Function           // reachable functions awaiting constraint generation
+	work    []nodeid                  // solver worklist
+}
+
+type callEdge struct {
+	site   ssa.CallInstruction
+	callee *ssa.Function
+}
+
+func newAnalysis(prog *ssa.Program) *analysis {
+	a := &analysis{
+		prog:    prog,
+		hasher:  typeutil.MakeHasher(),
+		values:  make(map[ssa.Value]nodeid),
+		results: make(map[*ssa.Function]nodeid),
+		globals: make(map[*ssa.Global]*object),
+		funcs:   make(map[*ssa.Function]*object),
+		edges:   make(map[callEdge]bool),
+		cg:      callgraph.New(nil),
+	}
+	a.layouts.SetHasher(a.hasher)
+	a.panic = a.addNodes(tEface)
+	return a
+}
+
+var tEface = types.NewInterfaceType(nil, nil).Complete()
+
+// addNodes adds a block of nodes for a variable of type t,
+// and returns the id of the first one.
+func (a *analysis) addNodes(t types.Type) nodeid {
+	id := nodeid(len(a.nodes))
+	for range a.layout(t) {
+		a.nodes = append(a.nodes, new(node))
+	}
+	return id
+}
+
+// addObject adds a new object with the specified layout.
+func (a *analysis) addObject(site ssa.Value, layout []leaf, tag types.Type) *object {
+	obj := &object{start: nodeid(len(a.nodes)), site: site, layout: layout, tag: tag}
+	for range layout {
+		a.nodes = append(a.nodes, &node{obj: obj})
+	}
+	return obj
+}
+
+// globalObject returns the object of global variable g.
+func (a *analysis) globalObject(g *ssa.Global) *object {
+	obj, ok := a.globals[g]
+	if !ok {
+		obj = a.addObject(g, a.layout(deref(g.Type())), nil)
+		a.globals[g] = obj
+	}
+	return obj
+}
+
+// funcObject returns the object of function fn, which is the target
+// of each value of fn.
+func (a *analysis) funcObject(fn *ssa.Function) *object {
+	obj, ok := a.funcs[fn]
+	if !ok {
+		obj = a.addObject(fn, []leaf{{typ: fn.Signature}}, nil)
+		a.funcs[fn] = obj
+	}
+	return obj
+}
+
+// reach marks fn as reachable, queuing it for constraint generation,
+// and returns its call graph node.
+func (a *analysis) reach(fn *ssa.Function) *callgraph.Node {
+	n, ok := a.cg.Nodes[fn]
+	if !ok {
+		n = a.cg.CreateNode(fn)
+		a.genq = append(a.genq, fn)
+	}
+	return n
+}
+
+// -- constraints --
+
+// addr adds the constraint pts(dst) ∋ loc.
+func (a *analysis) addr(dst, loc nodeid) {
+	if a.nodes[dst].pts.Insert(int(loc)) {
+		a.enqueue(dst)
+	}
+}
+
+// copy adds the constraints pts(dst+i) ⊇ pts(src+i) for i < size.
+func (a *analysis) copy(dst, src nodeid, size int) {
+	for i := nodeid(0); i < nodeid(size); i++ {
+		a.copy1(dst+i, src+i)
+	}
+}
+
+func (a *analysis) copy1(dst, src nodeid) {
+	if dst == src {
+		return
+	}
+	s := a.nodes[src]
+	if s.copyTo.Insert(int(dst)) {
+		if a.nodes[dst].pts.UnionWith(&s.pts) {
+			a.enqueue(dst)
+		}
+	}
+}
+
+// addComplex attaches constraint c to node id. The part of the
+// points-to set of id already processed by the solver is applied
+// to c immediately; the rest is applied when the node is solved.
+func (a *analysis) addComplex(id nodeid, c constraint) {
+	n := a.nodes[id]
+	n.complex = append(n.complex, c)
+	if !n.prevPts.IsEmpty() {
+		c.solve(a, n.prevPts.AppendTo(nil))
+	}
+}
+
+func (a *analysis) enqueue(id nodeid) {
+	if n := a.nodes[id]; !n.queued {
+		n.queued = true
+		a.work = append(a.work, id)
+	}
+}
+
+// A constraint is a "complex" constraint, whose effect depends on the
+// points-to set of its operand node.
+type constraint interface {
+	// solve applies the constraint for each new location in delta.
+	solve(a *analysis, delta []int)
+}
+
+// cell returns the nodeid of the cell at the specified offset from
+// location loc, and reports whether that cell exists. (Ill-typed
+// programs, and unsafe conversions, may cause the offset to fall
+// outside the object.)
+func (a *analysis) cell(loc int, offset nodeid, size int) (nodeid, bool) {
+	id := nodeid(loc) + offset
+	obj := a.nodes[loc].obj
+	return id, obj != nil && obj.contains(id) && (size == 0 || obj.contains(id+nodeid(size-1)))
+}
+
+// dst = *(src + offset), for a block of size nodes.
+type loadConstraint struct {
+	dst    nodeid
+	offset nodeid
+	size   int
+}
+
+func (c *loadConstraint) solve(a *analysis, delta []int) {
+	for _, loc := range delta {
+		if id, ok := a.cell(loc, c.offset, c.size); ok {
+			a.copy(c.dst, id, c.size)
+		}
+	}
+}
+
+// *(dst + offset) = src, for a block of size nodes.
+type storeConstraint struct {
+	src    nodeid
+	offset nodeid
+	size   int
+}
+
+func (c *storeConstraint) solve(a *analysis, delta []int) {
+	for _, loc := range delta {
+		if id, ok := a.cell(loc, c.offset, c.size); ok {
+			a.copy(id, c.src, c.size)
+		}
+	}
+}
+
+// dst = src + offset: the address of a field or element.
+type offsetAddrConstraint struct {
+	dst    nodeid
+	offset nodeid
+}
+
+func (c *offsetAddrConstraint) solve(a *analysis, delta []int) {
+	for _, loc := range delta {
+		if id, ok := a.cell(loc, c.offset, 1); ok {
+			a.addr(c.dst, id)
+		}
+	}
+}
+
+// dst = src.(iface): the interface objects whose dynamic type
+// implements the interface type.
+type typeFilterConstraint struct {
+	iface *types.Interface
+	dst   nodeid
+}
+
+func (c *typeFilterConstraint) solve(a *analysis, delta []int) {
+	for _, loc := range delta {
+		if obj := a.nodes[loc].obj; obj != nil && obj.tag != nil && types.Implements(obj.tag, c.iface) {
+			a.addr(c.dst, nodeid(loc))
+		}
+	}
+}
+
+// dst = src.(T), for a concrete type T: the value contained in
+// interface objects whose dynamic type is T.
+type untagConstraint struct {
+	typ  types.Type
+	dst  nodeid
+	size int
+}
+
+func (c *untagConstraint) solve(a *analysis, delta []int) {
+	for _, loc := range delta {
+		if obj := a.nodes[loc].obj; obj != nil && obj.tag != nil && types.Identical(obj.tag, c.typ) {
+			a.copy(c.dst, obj.start, c.size)
+		}
+	}
+}
+
+// A dynamic call through a function value.
+type dynCallConstraint struct {
+	caller *ssa.Function
+	site   ssa.CallInstruction
+}
+
+func (c *dynCallConstraint) solve(a *analysis, delta []int) {
+	for _, loc := range delta {
+		if fn, ok := a.nodes[loc].obj.site.(*ssa.Function); ok {
+			a.call(c.caller, c.site, fn, nil)
+		}
+	}
+}
+
+// A dynamic call of an interface method.
+type invokeConstraint struct {
+	caller *ssa.Function
+	site   ssa.CallInstruction
+}
+
+func (c *invokeConstraint) solve(a *analysis, delta []int) {
+	method := c.site.Common().Method
+	for _, loc := range delta {
+		obj := a.nodes[loc].obj
+		if obj == nil || obj.tag == nil {
+			continue
+		}
+		sel := a.prog.MethodSets.MethodSet(obj.tag).Lookup(method.Pkg(), method.Name())
+		if sel == nil {
+			continue // ill-typed
+		}
+		if fn := a.prog.MethodValue(sel); fn != nil {
+			a.call(c.caller, c.site, fn, obj)
+		}
+	}
+}
+
+// -- solver --
+
+// solve generates constraints for reachable functions and solves them,
+// until a fixed point is reached.
+func (a *analysis) solve() {
+	for {
+		for len(a.genq) > 0 {
+			fn := a.genq[0]
+			a.genq = a.genq[1:]
+			a.genFunc(fn)
+		}
+		if len(a.work) == 0 {
+			break
+		}
+		id := a.work[0]
+		a.work = a.work[1:]
+		n := a.nodes[id]
+		n.queued = false
+
+		var delta intsets.Sparse
+		delta.Difference(&n.pts, &n.prevPts)
+		if delta.IsEmpty() {
+			continue
+		}
+		n.prevPts.Copy(&n.pts)
+
+		locs := delta.AppendTo(nil)
+		for _, c := range n.complex {
+			c.solve(a, locs)
+		}
+		for _, succ := range n.copyTo.AppendTo(nil) {
+			if a.nodes[succ].pts.UnionWith(&delta) {
+				a.enqueue(nodeid(succ))
+			}
+		}
+	}
+}
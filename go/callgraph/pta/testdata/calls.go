@@ -0,0 +1,44 @@
+package main
+
+// Dynamic calls through interfaces and function values.
+
+type I interface{ f() *int }
+
+type A struct{ p *int }
+
+func (a A) f() *int { return a.p }
+
+type B struct{}
+
+func (*B) f() *int { return &y }
+
+var x, y, z int
+
+func call(i I) *int {
+	return i.f() // @calls (main.A).f | (*main.B).f
+}
+
+func apply(fn func() *int) *int {
+	return fn() // @calls main.main$1
+}
+
+func main() {
+	print(call(A{&x}))  // @pointsto main.x | main.y
+	print(call(new(B))) // @pointsto main.x | main.y
+
+	i := I(A{&z})
+	if a, ok := i.(A); ok {
+		print(a.p) // @pointsto main.z
+	}
+	if j, ok := i.(interface{ f() *int }); ok {
+		print(j) // @pointsto makeinterface:main.A@29
+	}
+
+	w := &x
+	print(apply(func() *int { return w })) // @pointsto main.x
+
+	defer func() {
+		print(recover()) // @pointsto makeinterface:*int@43
+	}()
+	panic(any(&z))
+}
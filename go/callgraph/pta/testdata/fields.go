@@ -0,0 +1,46 @@
+package main
+
+// Field sensitivity, pointers, slices, maps and channels.
+
+var a, b, c int
+
+type T struct {
+	x, y *int
+	next *T
+}
+
+func main() {
+	t := &T{x: &a, y: &b}
+	print(t.x) // @pointsto main.a
+	print(t.y) // @pointsto main.b
+
+	var s S
+	s.inner.p = &c
+	q := s.inner
+	print(q.p) // @pointsto main.c
+
+	arr := []*int{&a}
+	arr = append(arr, &b)
+	print(arr[1]) // @pointsto main.a | main.b
+
+	m := make(map[*int]*T)
+	m[&c] = t
+	for k, v := range m {
+		print(k) // @pointsto main.c
+		print(v) // @pointsto new@13
+	}
+	print(m[nil]) // @pointsto new@13
+
+	ch := make(chan *int, 1)
+	ch <- &a
+	print(<-ch) // @pointsto main.a
+
+	t.next = new(T)
+	print(t.next)      // @pointsto new@38
+	print(t.next.next) // @pointsto
+}
+
+type S struct {
+	n     int
+	inner struct{ p *int }
+}
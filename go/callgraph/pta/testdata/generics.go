@@ -0,0 +1,26 @@
+package main
+
+// Instantiated generic functions and types. Each instantiation is
+// analyzed separately.
+
+type Box[T any] struct{ v T }
+
+func (b *Box[T]) Get() T { return b.v }
+
+func wrap[T any](v T) *Box[T] { return &Box[T]{v: v} }
+
+var (
+	x int
+	y string
+)
+
+func main() {
+	b := wrap(&x)
+	print(b.Get()) // @pointsto main.x
+
+	c := wrap(&y)
+	print(c.Get()) // @pointsto main.y
+
+	var g interface{ Get() *string } = c
+	print(g.Get()) // @pointsto main.y
+}
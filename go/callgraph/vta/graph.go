@@ -321,6 +321,11 @@ type builder struct {
 	graph   vtaGraph
 	callees calleesFunc // initial call graph for creating flows at unresolved call sites.
 
+	// summarized holds the calls of the functions whose
+	// intraprocedural flows have been added from a Summary.
+	// Only these calls are visited.
+	summarized map[*ssa.Function][]ssa.CallInstruction
+
 	// Specialized type map for canonicalization of types.Type.
 	// Semantically equivalent types can have different implementations,
 	// i.e., they are different pointer values. The map allows us to
@@ -343,6 +348,12 @@ func (b *builder) visit(funcs map[*ssa.Function]bool) {
 }
 
 func (b *builder) fun(f *ssa.Function) {
+	if calls, ok := b.summarized[f]; ok {
+		for _, c := range calls {
+			b.call(c)
+		}
+		return
+	}
 	for _, bl := range f.Blocks {
 		for _, instr := range bl.Instrs {
 			b.instr(instr)
@@ -842,7 +853,7 @@ func (b *builder) representative(n node) node {
 		return field{StructType: canonicalize(i.StructType, &b.canon), index: i.index}
 	case indexedLocal:
 		return indexedLocal{typ: t, val: i.val, index: i.index}
-	case local, global, panicArg, recoverReturn, function, resultVar:
+	case local, global, panicArg, recoverReturn, function, resultVar, anonymous:
 		return n
 	default:
 		panic(fmt.Errorf("canonicalizing unrecognized node %v", n))
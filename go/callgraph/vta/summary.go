@@ -0,0 +1,1113 @@
+// Copyright 2024 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package vta
+
+// This file defines per-package summaries of the type propagation
+// graph, which allow a whole-program call graph to be assembled
+// without revisiting the instructions of packages that have not
+// changed since their summaries were computed.
+
+import (
+	"bytes"
+	"encoding/gob"
+	"fmt"
+	"go/token"
+	"go/types"
+	"sort"
+
+	"golang.org/x/tools/go/callgraph"
+	"golang.org/x/tools/go/ssa"
+	"golang.org/x/tools/go/types/typeutil"
+	"golang.org/x/tools/internal/typeparams"
+)
+
+// A Summary records the type flow induced by the functions of a
+// single package.
+//
+// A summary consists of the edges of the type propagation graph
+// contributed by the instructions of the package other than calls.
+// The local variables that take part in calls (parameters, free
+// variables, call operands and results) are identified by their
+// position within their function, so that a summary can be decoded
+// without visiting function bodies. All other local variables are
+// replaced by anonymous nodes of the same type. Interprocedural
+// flows at call sites are not part of a summary: they depend on the
+// initial call graph of the whole program, and are recomputed by
+// [CallGraphWithSummaries] each time the call graph is assembled.
+//
+// A summary remains valid as long as neither the package nor the
+// types of its dependencies change, so a client that caches summaries
+// should key them by the same information it uses to cache analysis
+// facts or export data. Summaries are not valid across ssa builder
+// modes.
+type Summary struct {
+	pkg   *ssa.Package
+	funcs map[*ssa.Function]int // summarized functions, with their number of calls
+	edges [][2]node             // flow edges
+	refs  map[ssa.Value]siteRef // positions of local variables in calls (nil if decoded)
+}
+
+// Package returns the package summarized by s.
+func (s *Summary) Package() *ssa.Package { return s.pkg }
+
+// A siteRef identifies a local variable that takes part in calls by
+// its position within its function f: a parameter or free variable,
+// or the callee value, an argument or the result of the call
+// instruction with the given index in calls(f). Free variables are
+// also referred to when f is not summarized but its closures are
+// created by summarized functions.
+//
+// In decoded summaries, references to call operands and results take
+// the place of local nodes until [CallGraphWithSummaries] resolves
+// them against the calls of f.
+type siteRef struct {
+	f     *ssa.Function
+	kind  byte // kParam, kFreeVar, kSiteValue, or kSiteResult
+	site  int  // index of the call (kSiteValue and kSiteResult)
+	index int  // parameter, free variable or argument index, or tuple index of a result; -1 for the callee value or an untupled result
+}
+
+func (r siteRef) Type() types.Type {
+	return nil
+}
+
+func (r siteRef) String() string {
+	return fmt.Sprintf("Site(%s, %d, %d, %d)", r.f, r.kind, r.site, r.index)
+}
+
+// resolve returns the node referred to by r, given the calls of r.f.
+// It reports false if r does not match the calls.
+func (r siteRef) resolve(calls []ssa.CallInstruction) (node, bool) {
+	if r.site >= len(calls) {
+		return nil, false
+	}
+	c := calls[r.site]
+	switch r.kind {
+	case kSiteValue:
+		cc := c.Common()
+		v := cc.Value
+		if r.index >= 0 {
+			if r.index >= len(cc.Args) {
+				return nil, false
+			}
+			v = cc.Args[r.index]
+		}
+		if _, ok := v.(ssa.Instruction); !ok {
+			return nil, false
+		}
+		return local{val: v}, true
+	case kSiteResult:
+		v, ok := c.(ssa.Value)
+		if !ok {
+			return nil, false
+		}
+		if r.index < 0 {
+			return local{val: v}, true
+		}
+		tup, ok := v.Type().(*types.Tuple)
+		if !ok || r.index >= tup.Len() {
+			return nil, false
+		}
+		return indexedLocal{val: v, index: r.index, typ: tup.At(r.index).Type()}, true
+	}
+	return nil, false
+}
+
+// An anonymous node stands for a local variable of a summarized
+// function that takes no part in calls, and so need not be identified
+// across programs.
+type anonymous struct {
+	s     *Summary
+	index int
+	typ   types.Type
+}
+
+func (a anonymous) Type() types.Type {
+	return a.typ
+}
+
+func (a anonymous) String() string {
+	return fmt.Sprintf("Anonymous(%s, %d)", a.s.pkg.Pkg.Path(), a.index)
+}
+
+// Summarize computes the summary of the functions in funcs that
+// belong to pkg. Synthetic functions that belong to no package, such
+// as wrappers and instantiations of generic functions, are not
+// summarized; CallGraphWithSummaries always visits them directly.
+func Summarize(pkg *ssa.Package, funcs map[*ssa.Function]bool) *Summary {
+	s := &Summary{
+		pkg:   pkg,
+		funcs: make(map[*ssa.Function]int),
+		refs:  make(map[ssa.Value]siteRef),
+	}
+	for f, in := range funcs {
+		if in && f.Pkg == pkg {
+			s.funcs[f] = 0
+		}
+	}
+
+	// Build the intraprocedural part of the type propagation
+	// graph, and record the positions of the local variables that
+	// take part in calls.
+	var b builder
+	ref := func(v ssa.Value, r siteRef) {
+		if _, ok := s.refs[v]; !ok {
+			s.refs[v] = r
+		}
+	}
+	for f := range s.funcs {
+		for i, p := range f.Params {
+			ref(p, siteRef{f: f, kind: kParam, index: i})
+		}
+		for i, fv := range f.FreeVars {
+			ref(fv, siteRef{f: f, kind: kFreeVar, index: i})
+		}
+		site := 0
+		for _, bl := range f.Blocks {
+			for _, instr := range bl.Instrs {
+				c, ok := instr.(ssa.CallInstruction)
+				if !ok {
+					// The free variables of a closure that is not
+					// summarized, such as a bound method wrapper,
+					// receive flow from the bindings here.
+					if mc, ok := instr.(*ssa.MakeClosure); ok {
+						fn := mc.Fn.(*ssa.Function)
+						if _, ok := s.funcs[fn]; !ok {
+							for i, fv := range fn.FreeVars {
+								ref(fv, siteRef{f: fn, kind: kFreeVar, index: i})
+							}
+						}
+					}
+					b.instr(instr)
+					continue
+				}
+				cc := c.Common()
+				if _, ok := cc.Value.(ssa.Instruction); ok {
+					ref(cc.Value, siteRef{f: f, kind: kSiteValue, site: site, index: -1})
+				}
+				for i, arg := range cc.Args {
+					if _, ok := arg.(ssa.Instruction); ok {
+						ref(arg, siteRef{f: f, kind: kSiteValue, site: site, index: i})
+					}
+				}
+				if v, ok := c.(ssa.Value); ok {
+					ref(v, siteRef{f: f, kind: kSiteResult, site: site, index: -1})
+				}
+				site++
+			}
+		}
+		s.funcs[f] = site
+	}
+
+	// Copy the edges of the graph, replacing the other local
+	// variables by anonymous nodes.
+	anon := make(map[node]node)
+	rename := func(n node) node {
+		switch n := n.(type) {
+		case local:
+			if _, ok := s.refs[n.val]; ok {
+				return n
+			}
+		case indexedLocal:
+			if r, ok := s.refs[n.val]; ok && r.kind == kSiteResult {
+				return n
+			}
+		default:
+			return n
+		}
+		a, ok := anon[n]
+		if !ok {
+			a = anonymous{s: s, index: len(anon), typ: n.Type()}
+			anon[n] = a
+		}
+		return a
+	}
+	g := &b.graph
+	for x := range g.node {
+		src := rename(g.node[x])
+		g.successors(idx(x))(func(y idx) bool {
+			s.edges = append(s.edges, [2]node{src, rename(g.node[y])})
+			return true
+		})
+	}
+	return s
+}
+
+// CallGraphWithSummaries is like [CallGraph], but takes the type flow
+// of the functions covered by summaries from the summaries, instead of
+// visiting their instructions. Only the call instructions of these
+// functions are visited, to add the interprocedural flows implied by
+// initial. The functions of a summary whose calls do not match the
+// program are visited in full, as if it had not been given.
+//
+// Each summary must have been computed from, or decoded against, the
+// same program as funcs. Given summaries of the current versions of
+// the packages, the result is the same as that of CallGraph.
+func CallGraphWithSummaries(funcs map[*ssa.Function]bool, initial *callgraph.Graph, summaries []*Summary) *callgraph.Graph {
+	callees := makeCalleesFunc(funcs, initial)
+
+	b := builder{callees: callees, summarized: make(map[*ssa.Function][]ssa.CallInstruction)}
+	for _, s := range summaries {
+		s.add(&b)
+	}
+	b.visit(funcs)
+	b.callees = nil // ensure callees is not pinned by pointers to other fields of b.
+
+	types := propagate(&b.graph, &b.canon)
+	c := &constructor{types: types, callees: callees, cache: make(methodCache)}
+	return c.construct(funcs)
+}
+
+// add adds the edges of s to the graph of b, and records the calls of
+// the summarized functions in b.summarized. If the calls do not match
+// the summary, add does nothing.
+func (s *Summary) add(b *builder) {
+	sites := make(map[*ssa.Function][]ssa.CallInstruction, len(s.funcs))
+	for f, n := range s.funcs {
+		cs := calls(f)
+		if len(cs) != n {
+			return
+		}
+		sites[f] = cs
+	}
+	resolve := func(n node) (node, bool) {
+		if r, ok := n.(siteRef); ok {
+			return r.resolve(sites[r.f])
+		}
+		return n, true
+	}
+	edges := make([][2]node, 0, len(s.edges))
+	for _, e := range s.edges {
+		x, ok := resolve(e[0])
+		if !ok {
+			return
+		}
+		y, ok := resolve(e[1])
+		if !ok {
+			return
+		}
+		edges = append(edges, [2]node{x, y})
+	}
+
+	for f, cs := range sites {
+		b.summarized[f] = cs
+	}
+	for _, e := range edges {
+		b.addInFlowEdge(e[0], e[1])
+	}
+}
+
+// -- serialization --
+
+// summaryVersion is the version of the encoding of summaries.
+// It must be incremented whenever the encoding, or the construction
+// of the type propagation graph, changes.
+const summaryVersion = 3
+
+// The encoded form of a Summary. Functions and globals are referred
+// to by name, local variables by their position within their
+// function (see siteRef), and types by their structure, with named
+// types referred to by package and name.
+type encSummary struct {
+	Version int
+	Package string   // package path
+	Funcs   []string // names of summarized functions
+	Calls   []int    // numbers of calls of summarized functions
+	Names   []string // table of function and global names
+	Types   []encType
+	Nodes   []encNode
+	Edges   []int // pairs of indices into Nodes
+}
+
+type encNode struct {
+	Kind  byte
+	Name  int // index into Names of a function or global (or -1)
+	Type  int // index into Types (or -1)
+	Site  int // index of a call within function Name
+	Index int // field, result, parameter, argument, tuple, or anonymous node index
+}
+
+// Node kinds.
+const (
+	kConstant byte = iota
+	kPointer
+	kMapKey
+	kMapValue
+	kSliceElem
+	kChannelElem
+	kField
+	kGlobal
+	kParam
+	kFreeVar
+	kSiteValue
+	kSiteResult
+	kAnonymous
+	kFunction
+	kResultVar
+	kNestedPtrInterface
+	kNestedPtrFunction
+	kPanicArg
+	kRecoverReturn
+)
+
+// The encoded form of a type. Types are encoded after their
+// components, so Elems refer to earlier entries of the table.
+type encType struct {
+	Kind     byte
+	Path     string   // package path of a named type or type parameter
+	Name     string   // name of a named type or type parameter
+	Int      int64    // basic kind, array length, channel direction, number of parameters or methods, or index of a local type
+	Elems    []int    // component types
+	Vars     []encVar // fields of a struct, or methods of an interface
+	Variadic bool
+}
+
+// The encoded form of a struct field or interface method.
+type encVar struct {
+	Path     string // package path of an unexported name
+	Name     string
+	Embedded bool
+	Tag      string
+}
+
+// Type kinds.
+const (
+	tBasic byte = iota
+	tPointer
+	tSlice
+	tArray
+	tChan
+	tMap
+	tTuple
+	tSignature
+	tStruct
+	tInterface
+	tNamed
+	tTypeParam
+)
+
+// Encode returns the encoded form of s, suitable for caching.
+// The result may be decoded by a [SummaryDecoder] for a later
+// program that contains the same version of the package.
+func (s *Summary) Encode() ([]byte, error) {
+	if s.refs == nil {
+		return nil, fmt.Errorf("cannot encode a decoded summary of %s", s.pkg.Pkg.Path())
+	}
+	enc := encSummary{Version: summaryVersion, Package: s.pkg.Pkg.Path()}
+	names := make(map[string]int)
+	name := func(x string) int {
+		i, ok := names[x]
+		if !ok {
+			i = len(enc.Names)
+			names[x] = i
+			enc.Names = append(enc.Names, x)
+		}
+		return i
+	}
+	te := typeEncoder{enc: &enc}
+	typ := func(t types.Type) (int, error) { return te.encode(t) }
+
+	nodes := make(map[node]int)
+	encode := func(n node) (int, error) {
+		if i, ok := nodes[n]; ok {
+			return i, nil
+		}
+		e := encNode{Name: -1, Type: -1}
+		var err error
+		switch n := n.(type) {
+		case constant:
+			e.Kind = kConstant
+			e.Type, err = typ(n.typ)
+		case pointer:
+			e.Kind = kPointer
+			e.Type, err = typ(n.typ)
+		case mapKey:
+			e.Kind = kMapKey
+			e.Type, err = typ(n.typ)
+		case mapValue:
+			e.Kind = kMapValue
+			e.Type, err = typ(n.typ)
+		case sliceElem:
+			e.Kind = kSliceElem
+			e.Type, err = typ(n.typ)
+		case channelElem:
+			e.Kind = kChannelElem
+			e.Type, err = typ(n.typ)
+		case nestedPtrInterface:
+			e.Kind = kNestedPtrInterface
+			e.Type, err = typ(n.typ)
+		case nestedPtrFunction:
+			e.Kind = kNestedPtrFunction
+			e.Type, err = typ(n.typ)
+		case field:
+			e.Kind, e.Index = kField, n.index
+			e.Type, err = typ(n.StructType)
+		case anonymous:
+			e.Kind, e.Index = kAnonymous, n.index
+			e.Type, err = typ(n.typ)
+		case global:
+			e.Kind, e.Name = kGlobal, name(n.val.String())
+		case local:
+			r, ok := s.refs[n.val]
+			if !ok {
+				return 0, fmt.Errorf("cannot encode local variable %v", n.val)
+			}
+			e.Kind, e.Name, e.Site, e.Index = r.kind, name(r.f.String()), r.site, r.index
+		case indexedLocal:
+			r, ok := s.refs[n.val]
+			if !ok || r.kind != kSiteResult {
+				return 0, fmt.Errorf("cannot encode local variable %v", n.val)
+			}
+			e.Kind, e.Name, e.Site, e.Index = kSiteResult, name(r.f.String()), r.site, n.index
+		case function:
+			e.Kind, e.Name = kFunction, name(n.f.String())
+		case resultVar:
+			e.Kind, e.Name, e.Index = kResultVar, name(n.f.String()), n.index
+		case panicArg:
+			e.Kind = kPanicArg
+		case recoverReturn:
+			e.Kind = kRecoverReturn
+		default:
+			return 0, fmt.Errorf("cannot encode node %v", n)
+		}
+		if err != nil {
+			return 0, err
+		}
+		i := len(enc.Nodes)
+		nodes[n] = i
+		enc.Nodes = append(enc.Nodes, e)
+		return i, nil
+	}
+
+	funcs := make([]*ssa.Function, 0, len(s.funcs))
+	for f := range s.funcs {
+		funcs = append(funcs, f)
+	}
+	sort.Slice(funcs, func(i, j int) bool { return funcs[i].String() < funcs[j].String() })
+	for _, f := range funcs {
+		enc.Funcs = append(enc.Funcs, f.String())
+		enc.Calls = append(enc.Calls, s.funcs[f])
+	}
+	for _, e := range s.edges {
+		x, err := encode(e[0])
+		if err != nil {
+			return nil, fmt.Errorf("encoding summary of %s: %v", s.pkg.Pkg.Path(), err)
+		}
+		y, err := encode(e[1])
+		if err != nil {
+			return nil, fmt.Errorf("encoding summary of %s: %v", s.pkg.Pkg.Path(), err)
+		}
+		enc.Edges = append(enc.Edges, x, y)
+	}
+
+	var buf bytes.Buffer
+	if err := gob.NewEncoder(&buf).Encode(&enc); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// A typeEncoder appends the encoded forms of types to the type table
+// of a summary.
+type typeEncoder struct {
+	enc    *encSummary
+	index  typeutil.Map                               // maps each encoded type to its index
+	locals map[*types.Package]map[*types.TypeName]int // indices of local type names, by package
+}
+
+// encode returns the index of t in the type table.
+func (e *typeEncoder) encode(t types.Type) (int, error) {
+	t = types.Unalias(t)
+	if i := e.index.At(t); i != nil {
+		return i.(int), nil
+	}
+
+	var (
+		et  encType
+		err error
+	)
+	elems := func(ts ...types.Type) {
+		for _, t := range ts {
+			if err != nil {
+				return
+			}
+			var i int
+			i, err = e.encode(t)
+			et.Elems = append(et.Elems, i)
+		}
+	}
+	switch t := t.(type) {
+	case *types.Basic:
+		et.Kind, et.Int = tBasic, int64(t.Kind())
+	case *types.Pointer:
+		et.Kind = tPointer
+		elems(t.Elem())
+	case *types.Slice:
+		et.Kind = tSlice
+		elems(t.Elem())
+	case *types.Array:
+		et.Kind, et.Int = tArray, t.Len()
+		elems(t.Elem())
+	case *types.Chan:
+		et.Kind, et.Int = tChan, int64(t.Dir())
+		elems(t.Elem())
+	case *types.Map:
+		et.Kind = tMap
+		elems(t.Key(), t.Elem())
+	case *types.Tuple:
+		et.Kind = tTuple
+		for i := 0; i < t.Len(); i++ {
+			elems(t.At(i).Type())
+		}
+	case *types.Signature:
+		if t.TypeParams().Len() > 0 {
+			return 0, fmt.Errorf("cannot encode generic signature %v", t)
+		}
+		et.Kind, et.Int, et.Variadic = tSignature, int64(t.Params().Len()), t.Variadic()
+		for i := 0; i < t.Params().Len(); i++ {
+			elems(t.Params().At(i).Type())
+		}
+		for i := 0; i < t.Results().Len(); i++ {
+			elems(t.Results().At(i).Type())
+		}
+	case *types.Struct:
+		et.Kind = tStruct
+		for i := 0; i < t.NumFields(); i++ {
+			f := t.Field(i)
+			et.Vars = append(et.Vars, encVar{Path: unexportedPath(f), Name: f.Name(), Embedded: f.Embedded(), Tag: t.Tag(i)})
+			elems(f.Type())
+		}
+	case *types.Interface:
+		et.Kind, et.Int = tInterface, int64(t.NumExplicitMethods())
+		for i := 0; i < t.NumExplicitMethods(); i++ {
+			m := t.ExplicitMethod(i)
+			et.Vars = append(et.Vars, encVar{Path: unexportedPath(m), Name: m.Name()})
+			elems(m.Type())
+		}
+		for i := 0; i < t.NumEmbeddeds(); i++ {
+			elems(t.EmbeddedType(i))
+		}
+	case *types.Named:
+		obj := t.Obj()
+		et.Kind, et.Name, et.Int = tNamed, obj.Name(), -1
+		if pkg := obj.Pkg(); pkg != nil {
+			et.Path = pkg.Path()
+			if obj.Parent() != pkg.Scope() {
+				et.Int, err = e.local(obj)
+			}
+		}
+		for i := 0; i < t.TypeArgs().Len(); i++ {
+			elems(t.TypeArgs().At(i))
+		}
+	case *types.TypeParam:
+		obj := t.Obj()
+		et.Kind, et.Path, et.Name = tTypeParam, obj.Pkg().Path(), obj.Name()
+		et.Int, err = e.local(obj)
+	default:
+		return 0, fmt.Errorf("cannot encode type %v", t)
+	}
+	if err != nil {
+		return 0, err
+	}
+
+	i := len(e.enc.Types)
+	e.enc.Types = append(e.enc.Types, et)
+	e.index.Set(t, i)
+	return i, nil
+}
+
+// local returns the index of type name obj among the local type
+// names of its package.
+func (e *typeEncoder) local(obj *types.TypeName) (int64, error) {
+	if e.locals == nil {
+		e.locals = make(map[*types.Package]map[*types.TypeName]int)
+	}
+	index, ok := e.locals[obj.Pkg()]
+	if !ok {
+		index = make(map[*types.TypeName]int)
+		for i, tn := range localTypeNames(obj.Pkg()) {
+			index[tn] = i
+		}
+		e.locals[obj.Pkg()] = index
+	}
+	i, ok := index[obj]
+	if !ok {
+		return 0, fmt.Errorf("cannot encode type %s declared in an unknown scope", obj.Name())
+	}
+	return int64(i), nil
+}
+
+// localTypeNames returns the type names, including type parameters,
+// declared in the local scopes of pkg, in a deterministic order.
+func localTypeNames(pkg *types.Package) []*types.TypeName {
+	var names []*types.TypeName
+	var visit func(s *types.Scope)
+	visit = func(s *types.Scope) {
+		if s != pkg.Scope() {
+			for _, name := range s.Names() {
+				if tn, ok := s.Lookup(name).(*types.TypeName); ok {
+					names = append(names, tn)
+				}
+			}
+		}
+		for i := 0; i < s.NumChildren(); i++ {
+			visit(s.Child(i))
+		}
+	}
+	visit(pkg.Scope())
+	return names
+}
+
+// unexportedPath returns the package path that qualifies the name of
+// obj, or "" if the name is exported.
+func unexportedPath(obj types.Object) string {
+	if obj.Exported() || obj.Pkg() == nil {
+		return ""
+	}
+	return obj.Pkg().Path()
+}
+
+// A SummaryDecoder decodes summaries against a program.
+// A single decoder should be used for all summaries of a program,
+// as it indexes the functions, globals and packages of the program.
+type SummaryDecoder struct {
+	funcs    map[*ssa.Function]bool
+	byName   map[string]*ssa.Function             // nil => ambiguous
+	globals  map[string]*ssa.Global               // nil => ambiguous
+	packages map[string]*types.Package            // by path
+	locals   map[*types.Package][]*types.TypeName // local type names, computed on demand
+}
+
+// NewSummaryDecoder returns a decoder for summaries of packages whose
+// functions are among funcs, typically the same set of functions that
+// will be passed to CallGraphWithSummaries.
+func NewSummaryDecoder(funcs map[*ssa.Function]bool) *SummaryDecoder {
+	d := &SummaryDecoder{
+		funcs:    funcs,
+		byName:   make(map[string]*ssa.Function),
+		globals:  make(map[string]*ssa.Global),
+		packages: make(map[string]*types.Package),
+		locals:   make(map[*types.Package][]*types.TypeName),
+	}
+	var prog *ssa.Program
+	for f := range funcs {
+		name := f.String()
+		if _, ok := d.byName[name]; ok {
+			d.byName[name] = nil // ambiguous
+		} else {
+			d.byName[name] = f
+		}
+		prog = f.Prog
+	}
+	if prog != nil {
+		var addPackage func(pkg *types.Package)
+		addPackage = func(pkg *types.Package) {
+			if _, ok := d.packages[pkg.Path()]; ok {
+				return
+			}
+			d.packages[pkg.Path()] = pkg
+			for _, imp := range pkg.Imports() {
+				addPackage(imp)
+			}
+		}
+		for _, pkg := range prog.AllPackages() {
+			addPackage(pkg.Pkg)
+			for _, mem := range pkg.Members {
+				if g, ok := mem.(*ssa.Global); ok {
+					name := g.String()
+					if _, ok := d.globals[name]; ok {
+						d.globals[name] = nil // ambiguous
+					} else {
+						d.globals[name] = g
+					}
+				}
+			}
+		}
+	}
+	return d
+}
+
+// Decode decodes the summary of pkg from data, which must have been
+// produced by [Summary.Encode] for the same version of the package.
+// It returns an error if data does not match the program.
+//
+// Decode does not visit the instructions of the functions of pkg.
+func (d *SummaryDecoder) Decode(pkg *ssa.Package, data []byte) (*Summary, error) {
+	var enc encSummary
+	if err := gob.NewDecoder(bytes.NewReader(data)).Decode(&enc); err != nil {
+		return nil, fmt.Errorf("decoding summary of %s: %v", pkg.Pkg.Path(), err)
+	}
+	if enc.Version != summaryVersion {
+		return nil, fmt.Errorf("summary of %s has version %d, want %d", pkg.Pkg.Path(), enc.Version, summaryVersion)
+	}
+	if enc.Package != pkg.Pkg.Path() {
+		return nil, fmt.Errorf("summary is for package %s, not %s", enc.Package, pkg.Pkg.Path())
+	}
+	s, err := d.decode(pkg, &enc)
+	if err != nil {
+		return nil, fmt.Errorf("in summary of %s: %v", pkg.Pkg.Path(), err)
+	}
+	return s, nil
+}
+
+func (d *SummaryDecoder) decode(pkg *ssa.Package, enc *encSummary) (*Summary, error) {
+	s := &Summary{pkg: pkg, funcs: make(map[*ssa.Function]int)}
+	lookupFunc := func(name string) (*ssa.Function, error) {
+		f := d.byName[name]
+		if f == nil {
+			if _, ok := d.byName[name]; ok {
+				return nil, fmt.Errorf("ambiguous function %s", name)
+			}
+			return nil, fmt.Errorf("no function %s", name)
+		}
+		return f, nil
+	}
+	if len(enc.Calls) != len(enc.Funcs) {
+		return nil, fmt.Errorf("%d call counts for %d functions", len(enc.Calls), len(enc.Funcs))
+	}
+	for i, name := range enc.Funcs {
+		f, err := lookupFunc(name)
+		if err != nil {
+			return nil, err
+		}
+		if f.Pkg != pkg {
+			return nil, fmt.Errorf("function %s belongs to another package", name)
+		}
+		s.funcs[f] = enc.Calls[i]
+	}
+	// All functions of pkg must be covered by the summary (and
+	// none must have been added), or the summary is stale.
+	for f, in := range d.funcs {
+		if in && f.Pkg == pkg {
+			if _, ok := s.funcs[f]; !ok {
+				return nil, fmt.Errorf("summary does not cover function %s", f)
+			}
+		}
+	}
+
+	typs, err := d.decodeTypes(enc.Types)
+	if err != nil {
+		return nil, err
+	}
+
+	decode := func(e encNode) (node, error) {
+		var name string
+		if e.Name >= 0 {
+			if e.Name >= len(enc.Names) {
+				return nil, fmt.Errorf("invalid name index %d", e.Name)
+			}
+			name = enc.Names[e.Name]
+		}
+		var t types.Type
+		if e.Type >= 0 {
+			if e.Type >= len(typs) {
+				return nil, fmt.Errorf("invalid type index %d", e.Type)
+			}
+			t = typs[e.Type]
+		}
+		switch e.Kind {
+		case kConstant, kPointer, kMapKey, kMapValue, kSliceElem, kChannelElem,
+			kNestedPtrInterface, kNestedPtrFunction, kField, kAnonymous:
+			if t == nil {
+				return nil, fmt.Errorf("node of kind %d has no type", e.Kind)
+			}
+			switch e.Kind {
+			case kConstant:
+				return constant{typ: t}, nil
+			case kPointer:
+				if p, ok := t.(*types.Pointer); ok {
+					return pointer{typ: p}, nil
+				}
+				return nil, fmt.Errorf("type %s of pointer node is not a pointer", t)
+			case kMapKey:
+				return mapKey{typ: t}, nil
+			case kMapValue:
+				return mapValue{typ: t}, nil
+			case kSliceElem:
+				return sliceElem{typ: t}, nil
+			case kChannelElem:
+				return channelElem{typ: t}, nil
+			case kNestedPtrInterface:
+				return nestedPtrInterface{typ: t}, nil
+			case kNestedPtrFunction:
+				return nestedPtrFunction{typ: t}, nil
+			case kAnonymous:
+				return anonymous{s: s, index: e.Index, typ: t}, nil
+			default: // kField
+				st, ok := typeparams.CoreType(t).(*types.Struct)
+				if !ok || e.Index < 0 || e.Index >= st.NumFields() {
+					return nil, fmt.Errorf("invalid field %d of type %s", e.Index, t)
+				}
+				return field{StructType: t, index: e.Index}, nil
+			}
+		case kGlobal:
+			g := d.globals[name]
+			if g == nil {
+				return nil, fmt.Errorf("no unique global %s", name)
+			}
+			return global{val: g}, nil
+		case kParam, kFreeVar, kSiteValue, kSiteResult:
+			f, err := lookupFunc(name)
+			if err != nil {
+				return nil, err
+			}
+			calls, ok := s.funcs[f]
+			if !ok && e.Kind != kFreeVar {
+				return nil, fmt.Errorf("local variable of function %s, which is not summarized", f)
+			}
+			switch e.Kind {
+			case kParam:
+				if e.Index < 0 || e.Index >= len(f.Params) {
+					return nil, fmt.Errorf("invalid parameter index %d of %s", e.Index, f)
+				}
+				return local{val: f.Params[e.Index]}, nil
+			case kFreeVar:
+				if e.Index < 0 || e.Index >= len(f.FreeVars) {
+					return nil, fmt.Errorf("invalid free variable index %d of %s", e.Index, f)
+				}
+				return local{val: f.FreeVars[e.Index]}, nil
+			}
+			if e.Site < 0 || e.Site >= calls || e.Index < -1 {
+				return nil, fmt.Errorf("invalid call %d, operand %d of %s", e.Site, e.Index, f)
+			}
+			return siteRef{f: f, kind: e.Kind, site: e.Site, index: e.Index}, nil
+		case kFunction, kResultVar:
+			f, err := lookupFunc(name)
+			if err != nil {
+				return nil, err
+			}
+			if e.Kind == kFunction {
+				return function{f: f}, nil
+			}
+			if e.Index < 0 || e.Index >= f.Signature.Results().Len() {
+				return nil, fmt.Errorf("invalid result index %d of %s", e.Index, f)
+			}
+			return resultVar{f: f, index: e.Index}, nil
+		case kPanicArg:
+			return panicArg{}, nil
+		case kRecoverReturn:
+			return recoverReturn{}, nil
+		}
+		return nil, fmt.Errorf("invalid node kind %d", e.Kind)
+	}
+
+	nodes := make([]node, len(enc.Nodes))
+	for i, e := range enc.Nodes {
+		n, err := decode(e)
+		if err != nil {
+			return nil, err
+		}
+		nodes[i] = n
+	}
+	if len(enc.Edges)%2 != 0 {
+		return nil, fmt.Errorf("odd number of edge endpoints")
+	}
+	for i := 0; i < len(enc.Edges); i += 2 {
+		x, y := enc.Edges[i], enc.Edges[i+1]
+		if x < 0 || x >= len(nodes) || y < 0 || y >= len(nodes) {
+			return nil, fmt.Errorf("invalid edge %d -> %d", x, y)
+		}
+		s.edges = append(s.edges, [2]node{nodes[x], nodes[y]})
+	}
+	return s, nil
+}
+
+// decodeTypes decodes a table of types.
+func (d *SummaryDecoder) decodeTypes(encs []encType) ([]types.Type, error) {
+	typs := make([]types.Type, len(encs))
+	for i, et := range encs {
+		elems := make([]types.Type, len(et.Elems))
+		for j, x := range et.Elems {
+			if x < 0 || x >= i {
+				return nil, fmt.Errorf("invalid type index %d", x)
+			}
+			elems[j] = typs[x]
+		}
+		t, err := d.decodeType(et, elems)
+		if err != nil {
+			return nil, err
+		}
+		typs[i] = t
+	}
+	return typs, nil
+}
+
+// decodeType decodes a single type, given its decoded components.
+func (d *SummaryDecoder) decodeType(et encType, elems []types.Type) (types.Type, error) {
+	invalid := func() (types.Type, error) {
+		return nil, fmt.Errorf("invalid encoding of type of kind %d", et.Kind)
+	}
+	want := func(n int) bool { return len(elems) == n }
+
+	switch et.Kind {
+	case tBasic:
+		if et.Int < 0 || et.Int >= int64(len(types.Typ)) || types.Typ[et.Int] == nil {
+			return invalid()
+		}
+		return types.Typ[et.Int], nil
+	case tPointer:
+		if !want(1) {
+			return invalid()
+		}
+		return types.NewPointer(elems[0]), nil
+	case tSlice:
+		if !want(1) {
+			return invalid()
+		}
+		return types.NewSlice(elems[0]), nil
+	case tArray:
+		if !want(1) || et.Int < 0 {
+			return invalid()
+		}
+		return types.NewArray(elems[0], et.Int), nil
+	case tChan:
+		if !want(1) || et.Int < int64(types.SendRecv) || et.Int > int64(types.RecvOnly) {
+			return invalid()
+		}
+		return types.NewChan(types.ChanDir(et.Int), elems[0]), nil
+	case tMap:
+		if !want(2) {
+			return invalid()
+		}
+		return types.NewMap(elems[0], elems[1]), nil
+	case tTuple:
+		return tuple(elems), nil
+	case tSignature:
+		n := int(et.Int)
+		if n < 0 || n > len(elems) || et.Variadic && (n == 0 || !isSlice(elems[n-1])) {
+			return invalid()
+		}
+		return types.NewSignatureType(nil, nil, nil, tuple(elems[:n]), tuple(elems[n:]), et.Variadic), nil
+	case tStruct:
+		if !want(len(et.Vars)) {
+			return invalid()
+		}
+		fields := make([]*types.Var, len(elems))
+		tags := make([]string, len(elems))
+		for i, v := range et.Vars {
+			pkg, err := d.varPackage(v)
+			if err != nil {
+				return nil, err
+			}
+			fields[i] = types.NewField(token.NoPos, pkg, v.Name, elems[i], v.Embedded)
+			tags[i] = v.Tag
+		}
+		return types.NewStruct(fields, tags), nil
+	case tInterface:
+		n := int(et.Int)
+		if n < 0 || n > len(elems) || len(et.Vars) != n {
+			return invalid()
+		}
+		methods := make([]*types.Func, n)
+		for i, v := range et.Vars {
+			sig, ok := elems[i].(*types.Signature)
+			if !ok {
+				return invalid()
+			}
+			pkg, err := d.varPackage(v)
+			if err != nil {
+				return nil, err
+			}
+			methods[i] = types.NewFunc(token.NoPos, pkg, v.Name, sig)
+		}
+		return types.NewInterfaceType(methods, elems[n:]).Complete(), nil
+	case tNamed:
+		var obj types.Object
+		switch {
+		case et.Path == "":
+			obj = types.Universe.Lookup(et.Name)
+		case et.Int < 0:
+			pkg, err := d.pkg(et.Path)
+			if err != nil {
+				return nil, err
+			}
+			obj = pkg.Scope().Lookup(et.Name)
+		default:
+			tn, err := d.local(et)
+			if err != nil {
+				return nil, err
+			}
+			obj = tn
+		}
+		tn, ok := obj.(*types.TypeName)
+		if !ok {
+			return nil, fmt.Errorf("no type %s.%s", et.Path, et.Name)
+		}
+		named, ok := types.Unalias(tn.Type()).(*types.Named)
+		if !ok {
+			return nil, fmt.Errorf("type %s.%s is not a named type", et.Path, et.Name)
+		}
+		if len(elems) == 0 {
+			if named.TypeParams().Len() > 0 {
+				return nil, fmt.Errorf("uninstantiated generic type %s.%s", et.Path, et.Name)
+			}
+			return named, nil
+		}
+		return types.Instantiate(nil, named, elems, true)
+	case tTypeParam:
+		tn, err := d.local(et)
+		if err != nil {
+			return nil, err
+		}
+		tp, ok := tn.Type().(*types.TypeParam)
+		if !ok {
+			return nil, fmt.Errorf("type %s.%s is not a type parameter", et.Path, et.Name)
+		}
+		return tp, nil
+	}
+	return invalid()
+}
+
+// pkg returns the package of the program with the given path.
+func (d *SummaryDecoder) pkg(path string) (*types.Package, error) {
+	pkg := d.packages[path]
+	if pkg == nil {
+		return nil, fmt.Errorf("no package %s", path)
+	}
+	return pkg, nil
+}
+
+// varPackage returns the package that qualifies the name of an
+// encoded field or method, or nil if the name is exported.
+func (d *SummaryDecoder) varPackage(v encVar) (*types.Package, error) {
+	if v.Path == "" {
+		return nil, nil
+	}
+	return d.pkg(v.Path)
+}
+
+// local returns the local type name denoted by a named type or type
+// parameter encoding.
+func (d *SummaryDecoder) local(et encType) (*types.TypeName, error) {
+	pkg, err := d.pkg(et.Path)
+	if err != nil {
+		return nil, err
+	}
+	names, ok := d.locals[pkg]
+	if !ok {
+		names = localTypeNames(pkg)
+		d.locals[pkg] = names
+	}
+	if et.Int < 0 || et.Int >= int64(len(names)) || names[et.Int].Name() != et.Name {
+		return nil, fmt.Errorf("no local type %s in %s", et.Name, et.Path)
+	}
+	return names[et.Int], nil
+}
+
+// tuple returns a tuple of unnamed variables of types ts.
+func tuple(ts []types.Type) *types.Tuple {
+	vars := make([]*types.Var, len(ts))
+	for i, t := range ts {
+		vars[i] = types.NewParam(token.NoPos, nil, "", t)
+	}
+	return types.NewTuple(vars...)
+}
+
+// isSlice reports whether t is a slice type, as required of the last
+// parameter of a variadic signature.
+func isSlice(t types.Type) bool {
+	_, ok := t.Underlying().(*types.Slice)
+	return ok
+}
@@ -0,0 +1,241 @@
+// Copyright 2024 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package vta
+
+import (
+	"fmt"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"golang.org/x/tools/go/callgraph"
+	"golang.org/x/tools/go/callgraph/cha"
+	"golang.org/x/tools/go/packages"
+	"golang.org/x/tools/go/ssa"
+	"golang.org/x/tools/go/ssa/ssautil"
+	"golang.org/x/tools/internal/testenv"
+)
+
+func TestSummaries(t *testing.T) {
+	files := []string{
+		"testdata/src/callgraph_static.go",
+		"testdata/src/callgraph_ho.go",
+		"testdata/src/callgraph_interfaces.go",
+		"testdata/src/callgraph_pointers.go",
+		"testdata/src/callgraph_collections.go",
+		"testdata/src/callgraph_fields.go",
+		"testdata/src/callgraph_field_funcs.go",
+		"testdata/src/callgraph_recursive_types.go",
+		"testdata/src/callgraph_issue_57756.go",
+		"testdata/src/callgraph_comma_maps.go",
+		"testdata/src/callgraph_nested_ptr.go",
+		"testdata/src/callgraph_generics.go",
+		"testdata/src/panic.go",
+	}
+	for _, file := range files {
+		t.Run(file, func(t *testing.T) {
+			_, ssapkg := loadFile(t, file, ssa.InstantiateGenerics)
+			prog := ssapkg.Prog
+			funcs := ssautil.AllFunctions(prog)
+			initial := cha.CallGraph(prog)
+			want := sortedCallGraphStr(CallGraph(funcs, initial))
+
+			// Summarize, encode and decode each package.
+			// Decoding must not depend on the function bodies,
+			// so hide them while decoding.
+			dec := NewSummaryDecoder(funcs)
+			var summaries []*Summary
+			for _, pkg := range prog.AllPackages() {
+				data, err := Summarize(pkg, funcs).Encode()
+				if err != nil {
+					t.Fatalf("encoding summary of %s: %v", pkg.Pkg.Path(), err)
+				}
+				blocks := make(map[*ssa.Function][]*ssa.BasicBlock)
+				for f := range funcs {
+					blocks[f], f.Blocks = f.Blocks, nil
+				}
+				s, err := dec.Decode(pkg, data)
+				for f, b := range blocks {
+					f.Blocks = b
+				}
+				if err != nil {
+					t.Fatalf("decoding summary of %s: %v", pkg.Pkg.Path(), err)
+				}
+				summaries = append(summaries, s)
+			}
+
+			got := sortedCallGraphStr(CallGraphWithSummaries(funcs, initial, summaries))
+			if diff := cmp.Diff(want, got); diff != "" {
+				t.Errorf("call graph with summaries differs from CallGraph (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+// TestSummariesIncremental checks that summaries encoded for one
+// program may be decoded for another program, loaded separately from
+// the same source, and combined there with the summaries of the other
+// packages to compute the same call graph as CallGraph.
+func TestSummariesIncremental(t *testing.T) {
+	testenv.NeedsGoPackages(t)
+
+	const lib = `package lib
+
+type I interface{ F() }
+
+type A struct{}
+
+func (A) F() {}
+
+type B struct{ next I }
+
+func (b B) F() { b.next.F() }
+
+// Wrap returns an I that calls i.
+func Wrap(i I) I { return B{i} }
+
+// Apply calls each of fs.
+func Apply(fs ...func()) {
+	for _, f := range fs {
+		f()
+	}
+}
+
+// Store is a global through which values of type I flow.
+var Store I
+`
+	const main = `package main
+
+import "x.io/lib"
+
+type C struct{}
+
+func (C) F() {}
+
+func main() {
+	var i lib.I = C{}
+	lib.Store = lib.Wrap(i)
+	lib.Apply(lib.Store.F, func() { lib.A{}.F() })
+}
+`
+	// load loads and builds a new program from the module.
+	load := func() (*ssa.Program, map[string]*ssa.Package) {
+		dir := t.TempDir()
+		cfg := &packages.Config{
+			Mode: packages.LoadAllSyntax,
+			Dir:  dir,
+			Overlay: map[string][]byte{
+				filepath.Join(dir, "go.mod"):          fmt.Appendf(nil, "module x.io\ngo 1.%d", testenv.Go1Point()),
+				filepath.Join(dir, "lib", "lib.go"):   []byte(lib),
+				filepath.Join(dir, "main", "main.go"): []byte(main),
+			},
+		}
+		pkgs, err := packages.Load(cfg, "./main")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if packages.PrintErrors(pkgs) > 0 {
+			t.Fatal("packages contain errors")
+		}
+		prog, _ := ssautil.AllPackages(pkgs, ssa.InstantiateGenerics)
+		prog.Build()
+		byPath := make(map[string]*ssa.Package)
+		for _, pkg := range prog.AllPackages() {
+			byPath[pkg.Pkg.Path()] = pkg
+		}
+		return prog, byPath
+	}
+
+	// Encode the summaries of all packages of the first program.
+	prog1, pkgs1 := load()
+	funcs1 := ssautil.AllFunctions(prog1)
+	encoded := make(map[string][]byte)
+	for path, pkg := range pkgs1 {
+		data, err := Summarize(pkg, funcs1).Encode()
+		if err != nil {
+			t.Fatalf("encoding summary of %s: %v", path, err)
+		}
+		encoded[path] = data
+	}
+
+	// In the second program, decode the summaries of all packages but
+	// those in fresh, which are summarized again.
+	prog2, pkgs2 := load()
+	funcs2 := ssautil.AllFunctions(prog2)
+	initial := cha.CallGraph(prog2)
+	want := sortedCallGraphStr(CallGraph(funcs2, initial))
+	for _, fresh := range []string{"", "x.io/main", "x.io/lib"} {
+		dec := NewSummaryDecoder(funcs2)
+		var summaries []*Summary
+		for path, pkg := range pkgs2 {
+			if path == fresh {
+				summaries = append(summaries, Summarize(pkg, funcs2))
+				continue
+			}
+			s, err := dec.Decode(pkg, encoded[path])
+			if err != nil {
+				t.Fatalf("decoding summary of %s: %v", path, err)
+			}
+			summaries = append(summaries, s)
+		}
+		got := sortedCallGraphStr(CallGraphWithSummaries(funcs2, initial, summaries))
+		if diff := cmp.Diff(want, got); diff != "" {
+			t.Errorf("call graph with summaries (fresh %q) differs from CallGraph (-want +got):\n%s", fresh, diff)
+		}
+	}
+	// The calls through I in lib depend on the flow of types from
+	// main, through the global Store and the bound method lib.Store.F.
+	for _, call := range []string{
+		"B.F: invoke t2.F() -> C.F",
+		"F$bound: invoke recv.F() -> B.F",
+	} {
+		if !slices.Contains(want, call) {
+			t.Errorf("CallGraph lacks call %q; got:\n%s", call, strings.Join(want, "\n"))
+		}
+	}
+}
+
+// TestSummaryStale checks that decoding the summary of a package
+// against a different version of it fails.
+func TestSummaryStale(t *testing.T) {
+	_, old := loadFile(t, "testdata/src/callgraph_static.go", ssa.BuilderMode(0))
+	data, err := Summarize(old, ssautil.AllFunctions(old.Prog)).Encode()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, new := loadFile(t, "testdata/src/callgraph_interfaces.go", ssa.BuilderMode(0))
+	if _, err := NewSummaryDecoder(ssautil.AllFunctions(new.Prog)).Decode(new, data); err == nil {
+		t.Errorf("decoding a stale summary succeeded, want error")
+	}
+}
+
+// TestSummaryCallsMismatch checks that the functions of a summary
+// whose calls do not match the program are visited directly.
+func TestSummaryCallsMismatch(t *testing.T) {
+	_, pkg := loadFile(t, "testdata/src/callgraph_interfaces.go", ssa.BuilderMode(0))
+	funcs := ssautil.AllFunctions(pkg.Prog)
+	initial := cha.CallGraph(pkg.Prog)
+	want := sortedCallGraphStr(CallGraph(funcs, initial))
+
+	s := Summarize(pkg, funcs)
+	s.edges = nil // a summary of nothing, claiming one extra call per function
+	for f := range s.funcs {
+		s.funcs[f]++
+	}
+	got := sortedCallGraphStr(CallGraphWithSummaries(funcs, initial, []*Summary{s}))
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("call graph with mismatched summary differs from CallGraph (-want +got):\n%s", diff)
+	}
+}
+
+func sortedCallGraphStr(g *callgraph.Graph) []string {
+	gs := callGraphStr(g)
+	sort.Strings(gs)
+	return gs
+}
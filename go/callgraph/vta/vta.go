@@ -52,6 +52,11 @@
 // it may have. This information is then used to construct the call graph.
 // For each unresolved call site, vta uses the set of types and functions
 // reaching the node representing the call site to create a set of callees.
+//
+// The part of the type propagation graph contributed by a single package
+// can be computed ahead of time as a [Summary] and cached in encoded form.
+// [CallGraphWithSummaries] assembles the whole-program call graph from the
+// summaries of unchanged packages and the instructions of the others.
 package vta
 
 // TODO(zpavlinovic): update VTA for how it handles generic function bodies and instantiation wrappers.
@@ -170,6 +170,88 @@ func TestDeadCode(t *testing.T) {
 	}
 }
 
+func TestLoops(t *testing.T) {
+	const src = `package p
+
+func nested(n int) {
+	for i := 0; i < n; i++ {
+		for range n {
+			if i > 2 {
+				continue
+			}
+			f()
+		}
+	}
+}
+
+func exits(n int) {
+	for {
+		if n == 0 {
+			break
+		}
+		if n < 0 {
+			return
+		}
+		n--
+	}
+}
+
+func goto_(n int) {
+loop:
+	n--
+	if n > 0 {
+		goto loop
+	}
+}
+
+func none(x int) {
+	if x > 0 {
+		f()
+	}
+}
+`
+	fset := token.NewFileSet()
+	f, err := parser.ParseFile(fset, "p.go", src, parser.Mode(0))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]string{
+		"nested": "loop@ForLoop depth=1 blocks=[3 1 4 5 6 7 8 9] latches=[4] exits=[2]\n" +
+			"loop@RangeLoop depth=2 blocks=[5 6 8 9] latches=[8 9] exits=[7]",
+		"exits": "loop@ForBody depth=1 blocks=[1 4 7] latches=[7] exits=[3 6]",
+		"goto_": "loop@Label depth=1 blocks=[1 2] latches=[2] exits=[3]",
+		"none":  "",
+	}
+	indices := func(blocks []*cfg.Block) []int32 {
+		var indices []int32
+		for _, b := range blocks {
+			indices = append(indices, b.Index)
+		}
+		return indices
+	}
+	for _, decl := range f.Decls {
+		decl := decl.(*ast.FuncDecl)
+		g := cfg.New(decl.Body, mayReturn)
+		lf := g.Loops()
+		var buf bytes.Buffer
+		for _, l := range lf.All() {
+			if buf.Len() > 0 {
+				buf.WriteByte('\n')
+			}
+			fmt.Fprintf(&buf, "loop@%s depth=%d blocks=%v latches=%v exits=%v",
+				l.Header.Kind, l.Depth, indices(l.Blocks), indices(l.Latches), indices(l.Exits))
+			for _, b := range l.Blocks {
+				if !l.Contains(b) || lf.Depth(b) < l.Depth {
+					t.Errorf("%s: inconsistent membership of block %d", decl.Name, b.Index)
+				}
+			}
+		}
+		if got := buf.String(); got != want[decl.Name.Name] {
+			t.Errorf("%s: got loops\n%s\nwant\n%s\n%s", decl.Name, got, want[decl.Name.Name], g.Format(fset))
+		}
+	}
+}
+
 // TestSmoke runs the CFG builder on every FuncDecl in the standard
 // library and x/tools. (This is all well-typed code, but it gives
 // some coverage.)
@@ -0,0 +1,257 @@
+// Copyright 2024 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package cfg
+
+// This file defines the loop forest of a CFG.
+//
+// A back edge is an edge b->h such that h dominates b; h is the header
+// of a loop and b one of its latches. The natural loop of a header is
+// the set of blocks from which a latch can be reached without passing
+// through the header, plus the header itself. Natural loops with
+// distinct headers are either disjoint or nested, so they form a
+// forest. An irreducible cycle, such as may be created by goto, has no
+// back edge and thus no loop.
+//
+// Only live blocks belong to loops.
+
+import "sort"
+
+// A Loop is a natural loop of a CFG.
+//
+// The Stmt of the header block is the loop statement (ForStmt or
+// RangeStmt) that gave rise to the loop, if any; loops formed by goto
+// have headers of kind KindLabel.
+type Loop struct {
+	Header   *Block   // the unique entry block, which dominates the loop
+	Blocks   []*Block // blocks of the loop, header first, then in Index order
+	Latches  []*Block // blocks with a back edge to the header, in Index order
+	Exits    []*Block // blocks outside the loop with a predecessor inside, in Index order
+	Parent   *Loop    // innermost enclosing loop, or nil
+	Children []*Loop  // loops immediately nested within this one, by header Index
+	Depth    int      // nesting depth: 1 for an outermost loop
+
+	in []bool // in[b.Index] reports whether b is in the loop
+}
+
+// Contains reports whether block b belongs to the loop (or one of the
+// loops nested within it).
+func (l *Loop) Contains(b *Block) bool {
+	return int(b.Index) < len(l.in) && l.in[b.Index]
+}
+
+// A LoopForest holds the natural loops of a CFG.
+type LoopForest struct {
+	Loops []*Loop // outermost loops, by header Index
+
+	innermost []*Loop // innermost loop containing each block, by Index
+}
+
+// All returns a new slice containing all the loops of the forest,
+// outer loops before the loops they contain.
+func (lf *LoopForest) All() []*Loop {
+	var all []*Loop
+	var visit func(loops []*Loop)
+	visit = func(loops []*Loop) {
+		for _, l := range loops {
+			all = append(all, l)
+			visit(l.Children)
+		}
+	}
+	visit(lf.Loops)
+	return all
+}
+
+// LoopOf returns the innermost loop containing block b, or nil if b is
+// not in a loop.
+func (lf *LoopForest) LoopOf(b *Block) *Loop {
+	if int(b.Index) < len(lf.innermost) {
+		return lf.innermost[b.Index]
+	}
+	return nil
+}
+
+// Depth returns the loop nesting depth of block b: zero if b is not
+// in a loop.
+func (lf *LoopForest) Depth(b *Block) int {
+	if l := lf.LoopOf(b); l != nil {
+		return l.Depth
+	}
+	return 0
+}
+
+// Loops returns a new loop forest for the natural loops of g.
+func (g *CFG) Loops() *LoopForest {
+	n := len(g.Blocks)
+	lf := &LoopForest{innermost: make([]*Loop, n)}
+	if n == 0 {
+		return lf
+	}
+	preds := make([][]*Block, n)
+	for _, b := range g.Blocks {
+		if b.Live {
+			for _, s := range b.Succs {
+				preds[s.Index] = append(preds[s.Index], b)
+			}
+		}
+	}
+	idom := dominators(g, preds)
+	dominates := func(x, y *Block) bool {
+		for ; y != nil; y = idom[y.Index] {
+			if y == x {
+				return true
+			}
+		}
+		return false
+	}
+
+	// Find the back edges, and the loop of each header.
+	var loops []*Loop
+	for _, h := range g.Blocks {
+		if !h.Live {
+			continue
+		}
+		var l *Loop
+		for _, b := range preds[h.Index] {
+			if dominates(h, b) {
+				if l == nil {
+					l = &Loop{Header: h, in: make([]bool, n)}
+					loops = append(loops, l)
+				}
+				l.Latches = append(l.Latches, b)
+			}
+		}
+		if l == nil {
+			continue
+		}
+
+		// Walk backwards from the latches to the header.
+		l.in[h.Index] = true
+		stack := append([]*Block(nil), l.Latches...)
+		for len(stack) > 0 {
+			b := stack[len(stack)-1]
+			stack = stack[:len(stack)-1]
+			if !l.in[b.Index] {
+				l.in[b.Index] = true
+				stack = append(stack, preds[b.Index]...)
+			}
+		}
+
+		l.Blocks = append(l.Blocks, h)
+		for _, b := range g.Blocks {
+			if l.in[b.Index] && b != h {
+				l.Blocks = append(l.Blocks, b)
+			}
+		}
+		seen := make(map[*Block]bool)
+		for _, b := range l.Blocks {
+			for _, s := range b.Succs {
+				if !l.in[s.Index] && !seen[s] {
+					seen[s] = true
+					l.Exits = append(l.Exits, s)
+				}
+			}
+		}
+		sortBlocks(l.Latches)
+		sortBlocks(l.Exits)
+	}
+
+	// Nest the loops. A loop is strictly larger than the loops
+	// nested within it, so visiting loops in decreasing order of
+	// size finds each loop's parent before the loop itself.
+	sorted := append([]*Loop(nil), loops...)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return len(sorted[i].Blocks) > len(sorted[j].Blocks)
+	})
+	for _, l := range sorted {
+		l.Parent = lf.innermost[l.Header.Index]
+		for _, b := range l.Blocks {
+			lf.innermost[b.Index] = l
+		}
+	}
+	for _, l := range loops { // in header order
+		if l.Parent == nil {
+			lf.Loops = append(lf.Loops, l)
+		} else {
+			l.Parent.Children = append(l.Parent.Children, l)
+		}
+	}
+	for _, l := range lf.All() {
+		l.Depth = 1
+		if l.Parent != nil {
+			l.Depth = l.Parent.Depth + 1
+		}
+	}
+	return lf
+}
+
+func sortBlocks(blocks []*Block) {
+	sort.Slice(blocks, func(i, j int) bool { return blocks[i].Index < blocks[j].Index })
+}
+
+// dominators returns the immediate dominator of each live block of g
+// (nil for the entry block and dead blocks), using the iterative
+// algorithm of Cooper, Harvey and Kennedy, A Simple, Fast Dominance
+// Algorithm, 2001.
+func dominators(g *CFG, preds [][]*Block) []*Block {
+	n := len(g.Blocks)
+	order := make([]int, n) // postorder number, or -1 if unvisited
+	for i := range order {
+		order[i] = -1
+	}
+	var post []*Block
+	var visit func(b *Block)
+	visit = func(b *Block) {
+		order[b.Index] = 0
+		for _, s := range b.Succs {
+			if order[s.Index] < 0 {
+				visit(s)
+			}
+		}
+		order[b.Index] = len(post)
+		post = append(post, b)
+	}
+	entry := g.Blocks[0]
+	visit(entry)
+	var rpo []*Block
+	for i := len(post) - 1; i >= 0; i-- {
+		rpo = append(rpo, post[i])
+	}
+
+	idom := make([]*Block, n)
+	idom[entry.Index] = entry
+	intersect := func(x, y *Block) *Block {
+		for x != y {
+			for order[x.Index] < order[y.Index] {
+				x = idom[x.Index]
+			}
+			for order[y.Index] < order[x.Index] {
+				y = idom[y.Index]
+			}
+		}
+		return x
+	}
+	for changed := true; changed; {
+		changed = false
+		for _, b := range rpo[1:] {
+			var d *Block
+			for _, p := range preds[b.Index] {
+				if idom[p.Index] == nil {
+					continue // not yet processed
+				}
+				if d == nil {
+					d = p
+				} else {
+					d = intersect(p, d)
+				}
+			}
+			if idom[b.Index] != d {
+				idom[b.Index] = d
+				changed = true
+			}
+		}
+	}
+	idom[entry.Index] = nil
+	return idom
+}
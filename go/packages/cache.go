@@ -0,0 +1,213 @@
+// Copyright 2024 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package packages
+
+// This file defines the persistent cache of type information.
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"go/ast"
+	"go/token"
+	"os"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"sync"
+
+	"golang.org/x/tools/go/gcexportdata"
+)
+
+// A Cache records the type information of packages type-checked from
+// source, so that later calls to [Load], even in other processes, may
+// reuse it. See [Config.Cache].
+//
+// The type information of a package is recorded as export data, keyed
+// by a hash of the contents of its files, the configuration of the
+// type checker, and the keys of its dependencies. When the syntax of a
+// package is not needed, typically because it is an indirect
+// dependency of the requested packages, Load reads its types from the
+// cache, if present, instead of parsing and type-checking it. Only
+// packages without errors are recorded.
+//
+// A Cache also retains in memory, for its lifetime, the syntax trees of
+// the files parsed by Load. Later calls to Load with the same Cache and
+// the same [Config.Fset] reuse the tree of a file whose contents are
+// unchanged. Consequently, clients must not modify the syntax trees of
+// such calls, nor supply a [Config.ParseFile] function whose result
+// depends on anything but the file name and contents.
+//
+// A Cache may be used by concurrent calls to Load, and its directory by
+// concurrent processes.
+type Cache struct {
+	dir string
+
+	mu     sync.Mutex
+	syntax map[syntaxKey]*ast.File
+}
+
+type syntaxKey struct {
+	fset     *token.FileSet
+	filename string
+	hash     [sha256.Size]byte
+}
+
+// cacheVersion is mixed into every key. Increment it when the format
+// of the cache changes.
+const cacheVersion = 1
+
+// NewCache returns a Cache that stores type information in directory
+// dir, which is created if necessary. If dir is empty, NewCache uses a
+// subdirectory of [os.UserCacheDir].
+func NewCache(dir string) (*Cache, error) {
+	if dir == "" {
+		base, err := os.UserCacheDir()
+		if err != nil {
+			return nil, err
+		}
+		dir = filepath.Join(base, "go", "packages")
+	}
+	if err := os.MkdirAll(dir, 0o777); err != nil {
+		return nil, err
+	}
+	return &Cache{dir: dir, syntax: make(map[syntaxKey]*ast.File)}, nil
+}
+
+// filename returns the name of the file holding the data for key.
+func (c *Cache) filename(key *[sha256.Size]byte) string {
+	x := hex.EncodeToString(key[:])
+	return filepath.Join(c.dir, x[:2], x)
+}
+
+// get returns the data recorded for key.
+func (c *Cache) get(key *[sha256.Size]byte) ([]byte, error) {
+	ioLimit <- unit{} // acquire a token
+	defer func() { <-ioLimit }()
+	return os.ReadFile(c.filename(key))
+}
+
+// set records data for key, replacing the file atomically so that
+// concurrent readers never observe partial data.
+func (c *Cache) set(key *[sha256.Size]byte, data []byte) error {
+	ioLimit <- unit{} // acquire a token
+	defer func() { <-ioLimit }()
+
+	filename := c.filename(key)
+	if err := os.MkdirAll(filepath.Dir(filename), 0o777); err != nil {
+		return err
+	}
+	f, err := os.CreateTemp(filepath.Dir(filename), filepath.Base(filename)+".tmp*")
+	if err != nil {
+		return err
+	}
+	_, err = f.Write(data)
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err == nil {
+		err = os.Rename(f.Name(), filename)
+	}
+	if err != nil {
+		os.Remove(f.Name())
+	}
+	return err
+}
+
+// lookupSyntax returns the syntax tree previously parsed, using fset,
+// from the named file with the given content hash, or nil.
+func (c *Cache) lookupSyntax(fset *token.FileSet, filename string, hash [sha256.Size]byte) *ast.File {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.syntax[syntaxKey{fset, filename, hash}]
+}
+
+// storeSyntax records the syntax tree f parsed using fset from the
+// named file with the given content hash.
+func (c *Cache) storeSyntax(fset *token.FileSet, filename string, hash [sha256.Size]byte, f *ast.File) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.syntax[syntaxKey{fset, filename, hash}] = f
+}
+
+// cacheKey returns the key of lpkg in ld.Cache, or nil if the package
+// cannot be cached because one of its files or dependencies has none.
+// It must be called after the dependencies of lpkg are loaded.
+func (ld *loader) cacheKey(lpkg *loaderPackage) *[sha256.Size]byte {
+	if len(lpkg.importErrors) > 0 {
+		return nil
+	}
+	h := sha256.New()
+	fmt.Fprintf(h, "version %d %s\n", cacheVersion, runtime.Version())
+	fmt.Fprintf(h, "target %s\n", ld.target)
+	fmt.Fprintf(h, "package %s %s %s\n", lpkg.ID, lpkg.PkgPath, lpkg.Name)
+	if lpkg.Module != nil {
+		fmt.Fprintf(h, "go %s\n", lpkg.Module.GoVersion)
+	}
+	fmt.Fprintf(h, "cgo %t\n", ld.Mode&typecheckCgo != 0)
+	fmt.Fprintf(h, "bodies %t\n", ld.Mode&NeedDeps != 0 || lpkg.initial)
+	for _, filename := range lpkg.CompiledGoFiles {
+		hash, err := ld.fileHash(filename)
+		if err != nil {
+			return nil
+		}
+		fmt.Fprintf(h, "file %s %x\n", filename, hash)
+	}
+	paths := make([]string, 0, len(lpkg.Imports))
+	for path := range lpkg.Imports {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+	for _, path := range paths {
+		dep := ld.pkgs[lpkg.Imports[path].ID]
+		if dep.cacheKey == nil {
+			return nil
+		}
+		fmt.Fprintf(h, "import %s %x\n", path, *dep.cacheKey)
+	}
+	var key [sha256.Size]byte
+	h.Sum(key[:0])
+	return &key
+}
+
+// exportFileKey returns the key of a package loaded from the export
+// data file produced by the build system.
+func exportFileKey(lpkg *loaderPackage) *[sha256.Size]byte {
+	key := sha256.Sum256([]byte(fmt.Sprintf("export %s %s", lpkg.ID, lpkg.ExportFile)))
+	return &key
+}
+
+// readCache attempts to load the types of lpkg from ld.Cache,
+// and reports whether it succeeded.
+func (ld *loader) readCache(lpkg *loaderPackage) bool {
+	data, err := ld.Cache.get(lpkg.cacheKey)
+	if err != nil {
+		return false // cache miss
+	}
+
+	ld.exportMu.Lock()
+	defer ld.exportMu.Unlock()
+	if err := ld.readExportData(lpkg, bytes.NewReader(data), ld.Cache.filename(lpkg.cacheKey)); err != nil {
+		ld.Logf("%s: %v", lpkg.ID, err)
+		return false
+	}
+	ld.Logf("%s: types loaded from cache", lpkg.ID)
+	return true
+}
+
+// writeCache records the types of lpkg, which has no errors, in ld.Cache.
+func (ld *loader) writeCache(lpkg *loaderPackage) {
+	var buf bytes.Buffer
+	ld.exportMu.Lock() // (the export data of lpkg may mention incomplete packages)
+	err := gcexportdata.Write(&buf, ld.Fset, lpkg.Types)
+	ld.exportMu.Unlock()
+	if err == nil {
+		err = ld.Cache.set(lpkg.cacheKey, buf.Bytes())
+	}
+	if err != nil {
+		ld.Logf("%s: writing cache: %v", lpkg.ID, err)
+	}
+}
@@ -8,6 +8,7 @@ package packages
 
 import (
 	"context"
+	"crypto/sha256"
 	"encoding/json"
 	"errors"
 	"fmt"
@@ -16,6 +17,7 @@ import (
 	"go/scanner"
 	"go/token"
 	"go/types"
+	"io"
 	"log"
 	"os"
 	"path/filepath"
@@ -240,6 +242,23 @@ type Config struct {
 	// drivers may vary in their level of support for overlays.
 	Overlay map[string][]byte
 
+	// Cache, if non-nil, records the type information of packages
+	// type-checked from source, so that later calls to [Load], even
+	// in other processes, can avoid type-checking packages whose
+	// syntax is not needed. See [Cache] for details.
+	Cache *Cache
+
+	// OnPackage, if non-nil, is called for each package as soon as
+	// the information requested by Mode is available, so that
+	// clients may start processing some packages while others are
+	// still being loaded. Calls are made one at a time, and each
+	// package is delivered after the packages it imports.
+	//
+	// During the call, fields not requested by Mode may be set;
+	// they are cleared before Load returns. The package must not
+	// be modified.
+	OnPackage func(*Package)
+
 	// goListOverlayFile is the JSON file that encodes the Overlay
 	// mapping, used by 'go list -overlay=...'
 	goListOverlayFile string
@@ -280,6 +299,7 @@ func Load(cfg *Config, patterns ...string) ([]*Package, error) {
 	}
 
 	ld.sizes = types.SizesFor(response.Compiler, response.Arch)
+	ld.target = response.Compiler + "/" + response.Arch
 	if ld.sizes == nil && ld.Config.Mode&(NeedTypes|NeedTypesSizes|NeedTypesInfo) != 0 {
 		// Type size information is needed but unavailable.
 		if external {
@@ -691,14 +711,15 @@ func (p *Package) String() string { return p.ID }
 // loaderPackage augments Package with state used during the loading phase
 type loaderPackage struct {
 	*Package
-	importErrors    map[string]error // maps each bad import to its error
-	preds           []*loaderPackage // packages that import this one
-	unfinishedSuccs atomic.Int32     // number of direct imports not yet loaded
-	color           uint8            // for cycle detection
-	needsrc         bool             // load from source (Mode >= LoadTypes)
-	needtypes       bool             // type information is either requested or depended on
-	initial         bool             // package was matched by a pattern
-	goVersion       int              // minor version number of go command on PATH
+	importErrors    map[string]error   // maps each bad import to its error
+	preds           []*loaderPackage   // packages that import this one
+	unfinishedSuccs atomic.Int32       // number of direct imports not yet loaded
+	color           uint8              // for cycle detection
+	needsrc         bool               // load from source (Mode >= LoadTypes)
+	needtypes       bool               // type information is either requested or depended on
+	initial         bool               // package was matched by a pattern
+	goVersion       int                // minor version number of go command on PATH
+	cacheKey        *[sha256.Size]byte // key in Config.Cache, or nil
 }
 
 // loader holds the working state of a single call to load.
@@ -706,9 +727,11 @@ type loader struct {
 	pkgs map[string]*loaderPackage // keyed by Package.ID
 	Config
 	sizes        types.Sizes // non-nil if needed by mode
+	target       string      // compiler and architecture, for cache keys
 	parseCache   map[string]*parseValue
 	parseCacheMu sync.Mutex
 	exportMu     sync.Mutex // enforces mutual exclusion of exportdata operations
+	deliverMu    sync.Mutex // serializes calls to OnPackage
 
 	// Config.Mode contains the implied mode (see impliedLoadMode).
 	// Implied mode contains all the fields we need the data for.
@@ -720,9 +743,11 @@ type loader struct {
 }
 
 type parseValue struct {
-	f     *ast.File
-	err   error
-	ready chan struct{}
+	f      *ast.File
+	err    error
+	hash   [sha256.Size]byte // hash of file contents, if hashed
+	hashed bool
+	ready  chan struct{}
 }
 
 func newLoader(cfg *Config) *loader {
@@ -834,6 +859,7 @@ func (ld *loader) refine(response *DriverResponse) ([]*Package, error) {
 	// Materialize the import graph if it is needed (NeedImports),
 	// or if we'll be using loadPackages (Need{Syntax|Types|TypesInfo}).
 	var leaves []*loaderPackage // packages with no unfinished successors
+	postorder := initial        // packages in dependency order
 	if ld.Mode&(NeedImports|NeedSyntax|NeedTypes|NeedTypesInfo) != 0 {
 		const (
 			white = 0 // new
@@ -906,6 +932,7 @@ func (ld *loader) refine(response *DriverResponse) ([]*Package, error) {
 				if len(lpkg.Imports) == 0 {
 					leaves = append(leaves, lpkg)
 				}
+				postorder = append(postorder, lpkg)
 
 				stack = stack[:len(stack)-1] // pop
 				lpkg.color = black
@@ -921,6 +948,7 @@ func (ld *loader) refine(response *DriverResponse) ([]*Package, error) {
 		}
 
 		// For each initial package, create its import DAG.
+		postorder = nil
 		for _, lpkg := range initial {
 			visit(nil, lpkg)
 		}
@@ -952,6 +980,7 @@ func (ld *loader) refine(response *DriverResponse) ([]*Package, error) {
 			g.Go(func() error {
 				// Parse and type-check.
 				ld.loadPackage(lpkg)
+				ld.deliver(lpkg)
 
 				// Notify each waiting predecessor,
 				// and enqueue it when it becomes a leaf.
@@ -974,6 +1003,10 @@ func (ld *loader) refine(response *DriverResponse) ([]*Package, error) {
 		if err := g.Wait(); err != nil {
 			return nil, err // cancelled
 		}
+	} else {
+		for _, lpkg := range postorder {
+			ld.deliver(lpkg)
+		}
 	}
 
 	// If the context is done, return its error and
@@ -1037,6 +1070,16 @@ func (ld *loader) refine(response *DriverResponse) ([]*Package, error) {
 	return result, nil
 }
 
+// deliver passes lpkg to the OnPackage hook, if any,
+// unless the context is done.
+func (ld *loader) deliver(lpkg *loaderPackage) {
+	if ld.OnPackage != nil && ld.Context.Err() == nil {
+		ld.deliverMu.Lock()
+		defer ld.deliverMu.Unlock()
+		ld.OnPackage(lpkg.Package)
+	}
+}
+
 // loadPackage loads/parses/typechecks the specified package.
 // It must be called only once per Package,
 // after immediate dependencies are loaded.
@@ -1049,6 +1092,7 @@ func (ld *loader) loadPackage(lpkg *loaderPackage) {
 		lpkg.Syntax = []*ast.File{}
 		lpkg.TypesInfo = new(types.Info)
 		lpkg.TypesSizes = ld.sizes
+		lpkg.cacheKey = &[sha256.Size]byte{} // (any constant will do)
 		return
 	}
 
@@ -1087,6 +1131,8 @@ func (ld *loader) loadPackage(lpkg *loaderPackage) {
 				Msg:  err.Error(),
 				Kind: UnknownError, // e.g. can't find/open/parse export data
 			})
+		} else if ld.Cache != nil {
+			lpkg.cacheKey = exportFileKey(lpkg)
 		}
 		return // not a source package, don't get syntax trees
 	}
@@ -1179,6 +1225,17 @@ func (ld *loader) loadPackage(lpkg *loaderPackage) {
 		return // can't get syntax trees for this package
 	}
 
+	// If only the types of the package are needed,
+	// they may be read from the cache.
+	if ld.Cache != nil && ld.Mode&NeedTypes != 0 {
+		lpkg.cacheKey = ld.cacheKey(lpkg)
+		needsyntax := ld.Mode&(NeedSyntax|NeedTypesInfo) != 0 && (lpkg.initial || ld.Mode&NeedDeps != 0)
+		if lpkg.cacheKey != nil && !needsyntax && ld.readCache(lpkg) {
+			lpkg.TypesSizes = ld.sizes
+			return
+		}
+	}
+
 	files, errs := ld.parseFiles(lpkg.CompiledGoFiles)
 	for _, err := range errs {
 		appendError(err)
@@ -1321,6 +1378,10 @@ func (ld *loader) loadPackage(lpkg *loaderPackage) {
 		}
 	}
 	lpkg.IllTyped = illTyped
+
+	if ld.Cache != nil && lpkg.cacheKey != nil && !illTyped {
+		ld.writeCache(lpkg)
+	}
 }
 
 // An importFunc is an implementation of the single-method
@@ -1349,30 +1410,24 @@ func (ld *loader) parseFile(filename string) (*ast.File, error) {
 		ld.parseCache[filename] = v
 		ld.parseCacheMu.Unlock()
 
-		var src []byte
-		for f, contents := range ld.Config.Overlay {
-			// TODO(adonovan): Inefficient for large overlays.
-			// Do an exact name-based map lookup
-			// (for nonexistent files) followed by a
-			// FileID-based map lookup (for existing ones).
-			if sameFile(f, filename) {
-				src = contents
-				break
-			}
-		}
-		var err error
-		if src == nil {
-			ioLimit <- unit{} // acquire a token
-			src, err = os.ReadFile(filename)
-			<-ioLimit // release a token
-		}
+		src, err := ld.readFile(filename)
 		if err != nil {
 			v.err = err
 		} else {
-			// Parsing is CPU intensive.
-			cpuLimit <- unit{} // acquire a token
-			v.f, v.err = ld.ParseFile(ld.Fset, filename, src)
-			<-cpuLimit // release a token
+			v.hash, v.hashed = sha256.Sum256(src), true
+			if ld.Cache != nil {
+				v.f = ld.Cache.lookupSyntax(ld.Fset, filename, v.hash)
+			}
+			if v.f == nil {
+				// Parsing is CPU intensive.
+				cpuLimit <- unit{} // acquire a token
+				v.f, v.err = ld.ParseFile(ld.Fset, filename, src)
+				<-cpuLimit // release a token
+
+				if ld.Cache != nil && v.err == nil {
+					ld.Cache.storeSyntax(ld.Fset, filename, v.hash, v.f)
+				}
+			}
 		}
 
 		close(v.ready)
@@ -1380,6 +1435,42 @@ func (ld *loader) parseFile(filename string) (*ast.File, error) {
 	return v.f, v.err
 }
 
+// readFile returns the contents of the named file,
+// from the overlay if present.
+func (ld *loader) readFile(filename string) ([]byte, error) {
+	for f, contents := range ld.Config.Overlay {
+		// TODO(adonovan): Inefficient for large overlays.
+		// Do an exact name-based map lookup
+		// (for nonexistent files) followed by a
+		// FileID-based map lookup (for existing ones).
+		if sameFile(f, filename) {
+			return contents, nil
+		}
+	}
+	ioLimit <- unit{} // acquire a token
+	defer func() { <-ioLimit }()
+	return os.ReadFile(filename)
+}
+
+// fileHash returns the hash of the contents of the named file,
+// reusing the hash computed by parseFile if any.
+func (ld *loader) fileHash(filename string) ([sha256.Size]byte, error) {
+	ld.parseCacheMu.Lock()
+	v, ok := ld.parseCache[filename]
+	ld.parseCacheMu.Unlock()
+	if ok {
+		<-v.ready
+		if v.hashed {
+			return v.hash, nil
+		}
+	}
+	src, err := ld.readFile(filename)
+	if err != nil {
+		return [sha256.Size]byte{}, err
+	}
+	return sha256.Sum256(src), nil
+}
+
 // parseFiles reads and parses the Go source files and returns the ASTs
 // of the ones that could be at least partially parsed, along with a
 // list of I/O and parse errors encountered.
@@ -1496,7 +1587,13 @@ func (ld *loader) loadFromExportData(lpkg *loaderPackage) error {
 	if err != nil {
 		return fmt.Errorf("reading %s: %v", lpkg.ExportFile, err)
 	}
+	return ld.readExportData(lpkg, r, lpkg.ExportFile)
+}
 
+// readExportData sets lpkg.Types to the package decoded from the export
+// data r, read from the named file.
+// The caller must hold ld.exportMu.
+func (ld *loader) readExportData(lpkg *loaderPackage, r io.Reader, filename string) error {
 	// Build the view.
 	//
 	// The gcexportdata machinery has no concept of package ID.
@@ -1538,7 +1635,7 @@ func (ld *loader) loadFromExportData(lpkg *loaderPackage) error {
 	// (May modify incomplete packages in view but not create new ones.)
 	tpkg, err := gcexportdata.Read(r, ld.Fset, view, lpkg.PkgPath)
 	if err != nil {
-		return fmt.Errorf("reading %s: %v", lpkg.ExportFile, err)
+		return fmt.Errorf("reading %s: %v", filename, err)
 	}
 	if _, ok := view["go.shape"]; ok {
 		// Account for the pseudopackage "go.shape" that gets
@@ -22,6 +22,7 @@ import (
 	"runtime"
 	"sort"
 	"strings"
+	"sync"
 	"testing"
 	"testing/fstest"
 	"time"
@@ -845,6 +846,117 @@ func testLoadDiamondTypes(t *testing.T, exporter packagestest.Exporter) {
 	}
 }
 
+func TestCache(t *testing.T) { testAllOrModulesParallel(t, testCache) }
+func testCache(t *testing.T, exporter packagestest.Exporter) {
+	exported := packagestest.Export(t, exporter, []packagestest.Module{{
+		Name: "golang.org/fake",
+		Files: map[string]interface{}{
+			"a/a.go": `package a; import ("golang.org/fake/b"; "golang.org/fake/c"); const A = "a" + b.B + c.C`,
+			"b/b.go": `package b; const B = "b"`,
+			"c/c.go": `package c; const C = "c"`,
+		}}})
+	defer exported.Cleanup()
+
+	cache, err := packages.NewCache(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	var (
+		mu   sync.Mutex
+		hits []string
+	)
+	exported.Config.Mode = packages.NeedName | packages.NeedImports | packages.NeedTypes | packages.NeedDeps
+	exported.Config.Cache = cache
+	exported.Config.Logf = func(format string, args ...interface{}) {
+		if msg := fmt.Sprintf(format, args...); strings.HasSuffix(msg, ": types loaded from cache") {
+			mu.Lock()
+			hits = append(hits, strings.TrimSuffix(msg, ": types loaded from cache"))
+			mu.Unlock()
+		}
+	}
+
+	// load loads a and checks the value of a.A.
+	load := func(wantA string, wantHits ...string) {
+		t.Helper()
+		hits = nil
+		initial, err := packages.Load(exported.Config, "golang.org/fake/a")
+		if err != nil {
+			t.Fatal(err)
+		}
+		packages.Visit(initial, nil, func(pkg *packages.Package) {
+			for _, err := range pkg.Errors {
+				t.Errorf("package %s: %v", pkg.ID, err)
+			}
+		})
+		if c, ok := initial[0].Types.Scope().Lookup("A").(*types.Const); !ok || c.Val().ExactString() != wantA {
+			t.Errorf("a.A = %v, want %s", initial[0].Types.Scope().Lookup("A"), wantA)
+		}
+		sort.Strings(hits)
+		if !reflect.DeepEqual(hits, wantHits) {
+			t.Errorf("cache hits = %v, want %v", hits, wantHits)
+		}
+	}
+	load(`"abc"`)
+	load(`"abc"`, "golang.org/fake/a", "golang.org/fake/b", "golang.org/fake/c")
+
+	// A change to c invalidates c and a, but not b.
+	if err := os.WriteFile(exported.File("golang.org/fake", "c/c.go"), []byte(`package c; const C = "C"`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	load(`"abC"`, "golang.org/fake/b")
+	load(`"abC"`, "golang.org/fake/a", "golang.org/fake/b", "golang.org/fake/c")
+
+	// So does an overlay.
+	exported.Config.Overlay = map[string][]byte{
+		exported.File("golang.org/fake", "c/c.go"): []byte(`package c; const C = "x"`),
+	}
+	load(`"abx"`, "golang.org/fake/b")
+}
+
+func TestOnPackage(t *testing.T) { testAllOrModulesParallel(t, testOnPackage) }
+func testOnPackage(t *testing.T, exporter packagestest.Exporter) {
+	exported := packagestest.Export(t, exporter, []packagestest.Module{{
+		Name: "golang.org/fake",
+		Files: map[string]interface{}{
+			"a/a.go": `package a; import ("golang.org/fake/b"; "golang.org/fake/c"); var _ = b.B == c.C`,
+			"b/b.go": `package b; import "golang.org/fake/d"; var B d.D`,
+			"c/c.go": `package c; import "golang.org/fake/d"; var C d.D`,
+			"d/d.go": `package d; type D int`,
+		}}})
+	defer exported.Cleanup()
+
+	for _, mode := range []packages.LoadMode{
+		packages.NeedName | packages.NeedImports | packages.NeedDeps,
+		packages.NeedName | packages.NeedImports | packages.NeedTypes | packages.NeedSyntax | packages.NeedDeps,
+	} {
+		delivered := make(map[*packages.Package]bool)
+		exported.Config.Mode = mode
+		exported.Config.OnPackage = func(pkg *packages.Package) {
+			if delivered[pkg] {
+				t.Errorf("%v: package %s delivered twice", mode, pkg.ID)
+			}
+			for _, imp := range pkg.Imports {
+				if !delivered[imp] {
+					t.Errorf("%v: package %s delivered before its import %s", mode, pkg.ID, imp.ID)
+				}
+			}
+			if mode&packages.NeedTypes != 0 && !pkg.Types.Complete() {
+				t.Errorf("%v: package %s delivered without complete types", mode, pkg.ID)
+			}
+			delivered[pkg] = true
+		}
+		initial, err := packages.Load(exported.Config, "golang.org/fake/a")
+		if err != nil {
+			t.Fatal(err)
+		}
+		packages.Visit(initial, nil, func(pkg *packages.Package) {
+			if !delivered[pkg] {
+				t.Errorf("%v: package %s not delivered", mode, pkg.ID)
+			}
+		})
+	}
+}
+
 func TestLoadSyntaxError(t *testing.T) { testAllOrModulesParallel(t, testLoadSyntaxError) }
 func testLoadSyntaxError(t *testing.T, exporter packagestest.Exporter) {
 	// A type error in a lower-level package (e) prevents go list
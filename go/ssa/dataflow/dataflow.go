@@ -0,0 +1,157 @@
+// Copyright 2024 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package dataflow provides generic solvers for monotone dataflow
+// problems over programs in SSA form.
+//
+// A [BlockProblem] is a classical dataflow problem over the control-flow
+// graph of a single function: a fact is associated with the entry and
+// exit of each basic block, and a transfer function describes the
+// effect of each block. It may be solved in the [Forward] direction,
+// like reaching definitions, or [Backward], like liveness.
+//
+// A [ValueProblem] is a sparse problem over the values of one or more
+// functions: a fact is associated with each [ssa.Value], and a transfer
+// function computes it from the facts of other values, such as its
+// operands or, for an interprocedural problem, the results of the
+// functions it calls. Because SSA values are defined exactly once, no
+// block-level facts are needed.
+//
+// In both cases facts form a join semilattice of finite height,
+// described by a [Lattice], and the solvers compute the least fixed
+// point by iteration from a worklist.
+//
+// The [golang.org/x/tools/go/ssa/dataflow/taint] package uses this
+// framework to implement an interprocedural taint tracker. The
+// [golang.org/x/tools/go/ssa/dataflow/ranges] package, which needs
+// widening and the reachability of blocks, has its own solver, as does
+// the [golang.org/x/tools/go/ssa/dataflow/escape] package, whose facts
+// are the numbers of dereferences along paths of a flow graph.
+package dataflow // import "golang.org/x/tools/go/ssa/dataflow"
+
+import "golang.org/x/tools/go/ssa"
+
+// A Lattice describes a join semilattice of facts of type F.
+// It must have finite height for the solvers to terminate.
+type Lattice[F any] interface {
+	// Bottom returns the least element, representing no information.
+	Bottom() F
+	// Join returns the least upper bound of x and y.
+	// It must not modify its operands.
+	Join(x, y F) F
+	// Equal reports whether x and y are the same element.
+	Equal(x, y F) bool
+}
+
+// A Direction is the direction in which facts flow through the
+// control-flow graph.
+type Direction int
+
+const (
+	Forward  Direction = iota // facts flow from predecessors to successors
+	Backward                  // facts flow from successors to predecessors
+)
+
+// A BlockProblem describes a dataflow problem over the basic blocks of
+// a function.
+type BlockProblem[F any] struct {
+	Lattice   Lattice[F]
+	Direction Direction
+
+	// Boundary is the fact at the start of the entry and recover
+	// blocks of a Forward problem, or at the end of blocks without
+	// successors (those ending in Return or Panic) of a Backward problem.
+	Boundary F
+
+	// Transfer computes the effect of block b. For a Forward problem
+	// it maps the fact at the start of b to the fact at its end; for
+	// a Backward problem, the fact at the end to the fact at the start.
+	// It must be monotone, and must not modify its operand.
+	Transfer func(b *ssa.BasicBlock, fact F) F
+}
+
+// A BlockResult holds the solution of a [BlockProblem].
+// Its slices are indexed by [ssa.BasicBlock.Index].
+type BlockResult[F any] struct {
+	In  []F // fact at the start of each block
+	Out []F // fact at the end of each block
+}
+
+// SolveBlocks solves the dataflow problem p over the blocks of function fn.
+// Functions without a body have no blocks, and an empty result.
+func SolveBlocks[F any](fn *ssa.Function, p *BlockProblem[F]) *BlockResult[F] {
+	n := len(fn.Blocks)
+	res := &BlockResult[F]{In: make([]F, n), Out: make([]F, n)}
+	bottom := p.Lattice.Bottom()
+	for i := range fn.Blocks {
+		res.In[i] = bottom
+		res.Out[i] = bottom
+	}
+
+	// For a Forward problem, the "before" fact of a block is its In
+	// fact, which is the join of the "after" facts of its
+	// predecessors; and conversely for Backward.
+	before, after := res.In, res.Out
+	if p.Direction == Backward {
+		before, after = res.Out, res.In
+	}
+	preds := func(b *ssa.BasicBlock) []*ssa.BasicBlock {
+		if p.Direction == Backward {
+			return b.Succs
+		}
+		return b.Preds
+	}
+	succs := func(b *ssa.BasicBlock) []*ssa.BasicBlock {
+		if p.Direction == Backward {
+			return b.Preds
+		}
+		return b.Succs
+	}
+	isBoundary := func(b *ssa.BasicBlock) bool {
+		if p.Direction == Backward {
+			return len(b.Succs) == 0
+		}
+		return b.Index == 0 || b == fn.Recover
+	}
+
+	// The initial worklist contains all blocks, in an order that
+	// follows the flow of facts where possible.
+	work := make([]*ssa.BasicBlock, 0, n)
+	queued := make([]bool, n)
+	for i := range fn.Blocks {
+		b := fn.Blocks[i]
+		if p.Direction == Backward {
+			b = fn.Blocks[n-1-i]
+		}
+		work = append(work, b)
+		queued[b.Index] = true
+	}
+	for len(work) > 0 {
+		b := work[0]
+		work = work[1:]
+		queued[b.Index] = false
+
+		fact := bottom
+		if isBoundary(b) {
+			fact = p.Boundary
+		}
+		for _, pred := range preds(b) {
+			fact = p.Lattice.Join(fact, after[pred.Index])
+		}
+		before[b.Index] = fact
+
+		out := p.Transfer(b, fact)
+		if p.Lattice.Equal(out, after[b.Index]) {
+			continue
+		}
+		after[b.Index] = out
+		for _, succ := range succs(b) {
+			if !queued[succ.Index] {
+				queued[succ.Index] = true
+				work = append(work, succ)
+			}
+		}
+	}
+	return res
+}
@@ -0,0 +1,216 @@
+// Copyright 2024 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package dataflow_test
+
+import (
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"go/types"
+	"reflect"
+	"sort"
+	"testing"
+
+	"golang.org/x/tools/go/ssa"
+	"golang.org/x/tools/go/ssa/dataflow"
+	"golang.org/x/tools/go/ssa/ssautil"
+)
+
+const src = `package p
+
+func f(x, y, z int) int {
+	if x > 0 {
+		return y
+	}
+	for z > 0 {
+		z--
+	}
+	return g(x)
+}
+
+func g(x int) int { return x }
+`
+
+func build(t *testing.T) *ssa.Package {
+	fset := token.NewFileSet()
+	f, err := parser.ParseFile(fset, "p.go", src, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pkg, _, err := ssautil.BuildPackage(&types.Config{}, fset, types.NewPackage("p", ""), []*ast.File{f}, ssa.SanityCheckFunctions)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return pkg
+}
+
+// set is a lattice of sets of names, ordered by inclusion.
+type set map[string]bool
+
+type setLattice struct{}
+
+func (setLattice) Bottom() set { return nil }
+
+func (setLattice) Join(x, y set) set {
+	z := make(set)
+	for k := range x {
+		z[k] = true
+	}
+	for k := range y {
+		z[k] = true
+	}
+	return z
+}
+
+func (setLattice) Equal(x, y set) bool { return reflect.DeepEqual(x, y) || len(x)+len(y) == 0 }
+
+func (s set) sorted() []string {
+	var keys []string
+	for k := range s {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// TestBackward computes the live variables of f.
+func TestBackward(t *testing.T) {
+	fn := build(t).Func("f")
+	res := dataflow.SolveBlocks(fn, &dataflow.BlockProblem[set]{
+		Lattice:   setLattice{},
+		Direction: dataflow.Backward,
+		Transfer: func(b *ssa.BasicBlock, out set) set {
+			live := setLattice{}.Join(out, nil)
+			// The operands of φ-nodes are used at the end of the
+			// corresponding predecessor.
+			for _, succ := range b.Succs {
+				for _, instr := range succ.Instrs {
+					if phi, ok := instr.(*ssa.Phi); ok {
+						for i, pred := range succ.Preds {
+							if pred == b {
+								addUse(live, phi.Edges[i])
+							}
+						}
+					}
+				}
+			}
+			for i := len(b.Instrs) - 1; i >= 0; i-- {
+				instr := b.Instrs[i]
+				if v, ok := instr.(ssa.Value); ok {
+					delete(live, v.Name())
+				}
+				if _, ok := instr.(*ssa.Phi); ok {
+					continue // uses of φ-nodes belong to predecessors
+				}
+				for _, op := range instr.Operands(nil) {
+					addUse(live, *op)
+				}
+			}
+			return live
+		},
+	})
+	if got, want := set(res.In[0]).sorted(), []string{"x", "y", "z"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("live at entry = %v, want %v", got, want)
+	}
+	for _, b := range fn.Blocks {
+		if len(b.Succs) == 0 && len(res.Out[b.Index]) > 0 {
+			t.Errorf("live at exit of %s = %v, want none", b, res.Out[b.Index].sorted())
+		}
+	}
+}
+
+func addUse(live set, v ssa.Value) {
+	switch v.(type) {
+	case *ssa.Parameter, ssa.Instruction:
+		live[v.Name()] = true
+	}
+}
+
+// TestForward computes the functions that may have been called on
+// some path to each block of f.
+func TestForward(t *testing.T) {
+	fn := build(t).Func("f")
+	res := dataflow.SolveBlocks(fn, &dataflow.BlockProblem[set]{
+		Lattice:   setLattice{},
+		Direction: dataflow.Forward,
+		Boundary:  set{"entry": true},
+		Transfer: func(b *ssa.BasicBlock, in set) set {
+			out := setLattice{}.Join(in, nil)
+			for _, instr := range b.Instrs {
+				if call, ok := instr.(*ssa.Call); ok {
+					out[call.Call.StaticCallee().Name()] = true
+				}
+			}
+			return out
+		},
+	})
+	for _, b := range fn.Blocks {
+		want := []string{"entry"}
+		if _, ok := b.Instrs[len(b.Instrs)-1].(*ssa.Return); ok && len(b.Preds) > 0 && b.Preds[0] != fn.Blocks[0] {
+			want = []string{"entry", "g"} // return g(x)
+		}
+		if got := res.Out[b.Index].sorted(); !reflect.DeepEqual(got, want) {
+			t.Errorf("calls at exit of %s = %v, want %v", b, got, want)
+		}
+	}
+}
+
+// TestValues computes the parameters on which each value depends,
+// interprocedurally.
+func TestValues(t *testing.T) {
+	pkg := build(t)
+	f := pkg.Func("f")
+	res := dataflow.SolveValues(dataflow.Values(f), &dataflow.ValueProblem[set]{
+		Lattice: setLattice{},
+		Transfer: func(v ssa.Value, fact func(ssa.Value) set) set {
+			deps := make(set)
+			switch v := v.(type) {
+			case *ssa.Parameter:
+				deps[v.Parent().Name()+"."+v.Name()] = true
+			case *ssa.Call:
+				// Depend on the results of the callee.
+				for _, b := range v.Call.StaticCallee().Blocks {
+					if ret, ok := b.Instrs[len(b.Instrs)-1].(*ssa.Return); ok {
+						for _, r := range ret.Results {
+							deps = setLattice{}.Join(deps, fact(r))
+						}
+					}
+				}
+			case ssa.Instruction:
+				for _, op := range v.Operands(nil) {
+					if *op != nil {
+						deps = setLattice{}.Join(deps, fact(*op))
+					}
+				}
+			}
+			return deps
+		},
+	})
+	for _, b := range f.Blocks {
+		if ret, ok := b.Instrs[len(b.Instrs)-1].(*ssa.Return); ok {
+			got := res.Fact(ret.Results[0]).sorted()
+			var want []string
+			switch ret.Results[0].(type) {
+			case *ssa.Parameter:
+				want = []string{"f.y"}
+			case *ssa.Call:
+				want = []string{"g.x"}
+			}
+			if !reflect.DeepEqual(got, want) {
+				t.Errorf("%s: deps = %v, want %v", ret.Results[0].Name(), got, want)
+			}
+		}
+	}
+	// The loop variable depends only on z.
+	for _, b := range f.Blocks {
+		for _, instr := range b.Instrs {
+			if phi, ok := instr.(*ssa.Phi); ok {
+				if got, want := res.Fact(phi).sorted(), []string{"f.z"}; !reflect.DeepEqual(got, want) {
+					t.Errorf("%s: deps = %v, want %v", phi.Name(), got, want)
+				}
+			}
+		}
+	}
+}
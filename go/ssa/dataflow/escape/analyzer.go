@@ -0,0 +1,52 @@
+// Copyright 2024 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package escape
+
+import (
+	"go/types"
+	"reflect"
+
+	"golang.org/x/tools/go/analysis"
+	"golang.org/x/tools/go/analysis/passes/buildssa"
+	"golang.org/x/tools/go/ssa"
+)
+
+// Analyzer computes the escape information of the functions of a
+// package. It exports the [Summary] of each function as a fact, so
+// that the leaks of the parameters of functions in other packages are
+// known. It reports no diagnostics; its result, of type *Result, is
+// for use by other analyzers.
+var Analyzer = &analysis.Analyzer{
+	Name:       "escape",
+	Doc:        "predict the escape of allocations to the heap",
+	URL:        "https://pkg.go.dev/golang.org/x/tools/go/ssa/dataflow/escape",
+	Requires:   []*analysis.Analyzer{buildssa.Analyzer},
+	FactTypes:  []analysis.Fact{new(Summary)},
+	ResultType: reflect.TypeOf(new(Result)),
+	Run:        run,
+}
+
+func run(pass *analysis.Pass) (any, error) {
+	ssainput := pass.ResultOf[buildssa.Analyzer].(*buildssa.SSA)
+
+	imported := func(fn *ssa.Function) *Summary {
+		obj, ok := fn.Object().(*types.Func)
+		if !ok || obj.Pkg() == pass.Pkg {
+			return nil
+		}
+		s := new(Summary)
+		if !pass.ImportObjectFact(obj, s) {
+			return nil
+		}
+		return s
+	}
+	res := Analyze(ssainput.SrcFuncs, pass.TypesSizes, imported)
+	for fn, s := range res.Summaries {
+		if obj := fn.Object(); obj != nil {
+			pass.ExportObjectFact(obj, s)
+		}
+	}
+	return res, nil
+}
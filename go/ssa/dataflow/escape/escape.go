@@ -0,0 +1,822 @@
+// Copyright 2024 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package escape implements an escape analysis for programs in SSA
+// form that approximates the decisions of the gc compiler, and an
+// [analysis.Analyzer] based on it.
+//
+// For each allocation site, such as an [ssa.Alloc] for a variable
+// whose address is taken, or a MakeSlice, MakeMap, MakeClosure or
+// MakeInterface instruction, the analysis predicts whether the
+// compiler allocates the object on the heap, because it may outlive
+// the call that allocates it; and for each parameter of a function,
+// whether it leaks: whether (some dereference of) its value may flow
+// to the heap, or to the results of the function. The predictions are
+// meant to match those reported by the compiler's -m flag, so that
+// tools can report allocations, for example in hot loops, without
+// compiling the program.
+//
+// As in the compiler, the analysis builds a graph of the flows of
+// values between locations (variables, SSA values, and the heap), in
+// which each edge records the number of times the value is
+// dereferenced (or -1 if its address is taken) along the way. An
+// object escapes if its address flows to a location that outlives it:
+// the heap; the results of its function; or, if it is allocated
+// within a loop, a variable or φ-node of an enclosing loop iteration.
+// Values stored through pointers other than the addresses of local
+// variables, stored in maps, sent on channels or passed to unknown
+// functions flow to the heap.
+//
+// Each function is described by a [Summary] of the leaks of its
+// parameters, which is used at each static call of the function. An
+// anonymous function is analyzed together with the function that
+// encloses it. Differences from the compiler include the following:
+// functions are not inlined (so results are comparable with those of
+// -gcflags='-m -l'), mutually recursive functions are treated as
+// unknown, the variables captured by closures are always captured by
+// reference, and allocations by the runtime that are not explicit in
+// the SSA form, such as those of append, are not modeled.
+package escape // import "golang.org/x/tools/go/ssa/dataflow/escape"
+
+import (
+	"fmt"
+	"go/token"
+	"go/types"
+	"strings"
+
+	"golang.org/x/tools/go/ssa"
+)
+
+// NoLeak is the number of dereferences recorded in a [Leak] for a flow
+// that does not occur.
+const NoLeak = -1
+
+// A Leak describes how the value of a parameter flows out of its
+// function.
+type Leak struct {
+	// Heap is the least number of dereferences of the parameter
+	// whose value flows to the heap: 0 if the parameter's value
+	// itself escapes, 1 if the value it points to does, and so on.
+	// It is NoLeak if no part of the parameter escapes.
+	Heap int
+
+	// Results holds, for each result of the function, the least
+	// number of dereferences of the parameter whose value flows to
+	// that result, or NoLeak.
+	Results []int
+}
+
+// describe returns the descriptions of the leaks of parameter name in
+// the style of the compiler's -m flag.
+func (l Leak) describe(name string, results *types.Tuple) []string {
+	var descs []string
+	switch {
+	case l.Heap == 0:
+		return []string{"leaking param: " + name}
+	case l.Heap > 0:
+		descs = append(descs, "leaking param content: "+name)
+	}
+	for i, d := range l.Results {
+		if d != NoLeak {
+			res := results.At(i).Name()
+			if res == "" || res == "_" {
+				res = fmt.Sprintf("~r%d", i)
+			}
+			descs = append(descs, fmt.Sprintf("leaking param: %s to result %s level=%d", name, res, d))
+		}
+	}
+	if descs == nil {
+		return []string{name + " does not escape"}
+	}
+	return descs
+}
+
+// A Summary describes the leaks of the parameters of a function,
+// including its receiver, if any.
+// The Analyzer exports it as a fact for each function.
+type Summary struct {
+	Params []Leak
+}
+
+func (*Summary) AFact() {}
+
+func (s *Summary) String() string {
+	var leaks []string
+	for i, l := range s.Params {
+		leaks = append(leaks, fmt.Sprintf("%d:%d%v", i, l.Heap, l.Results))
+	}
+	return fmt.Sprintf("escape(%s)", strings.Join(leaks, " "))
+}
+
+// Describe returns the descriptions of the leaks of parameter i of
+// fn, which must be described by s, in the style of the compiler's -m
+// flag.
+func (s *Summary) Describe(fn *ssa.Function, i int) []string {
+	return s.Params[i].describe(fn.Params[i].Name(), fn.Signature.Results())
+}
+
+// A Result holds the results of [Analyze].
+type Result struct {
+	// Summaries holds the summary of each analyzed function,
+	// other than anonymous functions.
+	Summaries map[*ssa.Function]*Summary
+
+	// Allocs maps each allocation site in the analyzed functions
+	// to whether the object it allocates escapes to the heap.
+	Allocs map[ssa.Value]bool
+}
+
+// Escapes reports whether the object allocated by v escapes to the
+// heap. It returns false if v is not an allocation site.
+func (r *Result) Escapes(v ssa.Value) bool {
+	return r.Allocs[v]
+}
+
+// IsAlloc reports whether the value of v is the address of (or
+// another reference to) an object that it allocates.
+//
+// The allocation sites are: Alloc; MakeSlice, MakeMap and MakeChan;
+// MakeClosure; MakeInterface of a value that is neither a constant nor
+// pointer-shaped; conversions between strings and byte or rune
+// slices; and the concatenation of strings.
+func IsAlloc(v ssa.Value) bool {
+	switch v := v.(type) {
+	case *ssa.Alloc, *ssa.MakeSlice, *ssa.MakeMap, *ssa.MakeChan, *ssa.MakeClosure:
+		return true
+	case *ssa.MakeInterface:
+		_, isConst := v.X.(*ssa.Const)
+		return !isConst && !isPointerShaped(v.X.Type())
+	case *ssa.Convert:
+		return isString(v.Type()) != isString(v.X.Type()) &&
+			(isSlice(v.Type()) || isSlice(v.X.Type()))
+	case *ssa.BinOp:
+		return v.Op == token.ADD && isString(v.Type())
+	}
+	return false
+}
+
+// Pos returns the source position of allocation site v, for use in
+// reports: that of its function literal for a MakeClosure, or, if v
+// has no position, such as an implicit conversion to an interface,
+// that of the first instruction that uses it and has one.
+func Pos(v ssa.Value) token.Pos {
+	if mc, ok := v.(*ssa.MakeClosure); ok {
+		return mc.Fn.Pos()
+	}
+	if pos := v.Pos(); pos.IsValid() || v.Referrers() == nil {
+		return pos
+	}
+	for _, instr := range *v.Referrers() {
+		if pos := instr.Pos(); pos.IsValid() {
+			return pos
+		}
+	}
+	return token.NoPos
+}
+
+// Analyze computes the escape information of the specified functions,
+// which are typically those of one package, and their anonymous
+// functions. Functions are identified with their generic origin, if
+// any; instances need not be included. sizes determines the sizes of
+// objects, as objects too large for the stack are allocated on the
+// heap.
+//
+// The imported function, if non-nil, returns the summary of a function
+// that is not among funcs, or nil if it is unknown. The arguments of
+// calls to unknown functions escape.
+func Analyze(funcs []*ssa.Function, sizes types.Sizes, imported func(*ssa.Function) *Summary) *Result {
+	a := &analyzer{
+		sizes:    sizes,
+		imported: imported,
+		funcs:    make(map[*ssa.Function]bool),
+		active:   make(map[*ssa.Function]bool),
+		res: &Result{
+			Summaries: make(map[*ssa.Function]*Summary),
+			Allocs:    make(map[ssa.Value]bool),
+		},
+	}
+	var roots []*ssa.Function
+	for _, fn := range funcs {
+		fn = origin(fn)
+		for fn.Parent() != nil {
+			fn = fn.Parent()
+		}
+		if !a.funcs[fn] {
+			a.funcs[fn] = true
+			roots = append(roots, fn)
+		}
+	}
+	for _, fn := range roots {
+		a.summary(fn)
+	}
+	return a.res
+}
+
+type analyzer struct {
+	sizes    types.Sizes
+	imported func(*ssa.Function) *Summary
+	funcs    map[*ssa.Function]bool // analyzed functions (outermost origins)
+	active   map[*ssa.Function]bool // functions being analyzed
+	res      *Result
+}
+
+// summary returns the summary of fn, analyzing it if necessary, or nil
+// if it is unknown.
+func (a *analyzer) summary(fn *ssa.Function) *Summary {
+	fn = origin(fn)
+	if s, ok := a.res.Summaries[fn]; ok {
+		return s
+	}
+	if !a.funcs[fn] {
+		if a.imported != nil {
+			return a.imported(fn)
+		}
+		return nil
+	}
+	if a.active[fn] || len(fn.Blocks) == 0 {
+		return nil // recursive, or no body
+	}
+	a.active[fn] = true
+	s := newBatch(a, fn).analyze()
+	delete(a.active, fn)
+	a.res.Summaries[fn] = s
+	return s
+}
+
+// A location is a node of the flow graph: the heap, an SSA value, an
+// object allocated by an allocation site, or a result of a function.
+type location struct {
+	fn     *ssa.Function // function of the location; nil for the heap
+	value  ssa.Value     // SSA value, for a value location
+	site   ssa.Value     // allocation site, for an object
+	result int           // index of the result, for a result location, or -1
+	param  int           // index of the parameter, for a parameter of the batch root, or -1
+	block  *ssa.BasicBlock
+
+	edges   []edge // incoming flows
+	escapes bool   // whether an object escapes
+
+	walkgen int // walk that last visited the location
+	derefs  int // least dereferences from the walk's root to the location
+}
+
+// An edge records that the value of src, dereferenced derefs times
+// (or -1 if the address of src is taken), flows to the location that
+// has the edge.
+type edge struct {
+	src    *location
+	derefs int
+}
+
+// A batch is the flow graph of a function and its anonymous functions.
+type batch struct {
+	a    *analyzer
+	root *ssa.Function
+
+	heap     *location
+	values   map[ssa.Value]*location
+	objects  map[ssa.Value]*location // by allocation site
+	results  map[*ssa.Function][]*location
+	bindings map[*ssa.FreeVar]ssa.Value // values bound to free variables
+	funcs    map[*ssa.Function]bool     // functions of the batch
+	loops    map[*ssa.Function]*ssa.LoopForest
+	all      []*location // all objects, φ-nodes and results, in order
+
+	walkgen int
+}
+
+func newBatch(a *analyzer, root *ssa.Function) *batch {
+	return &batch{
+		a:        a,
+		root:     root,
+		heap:     &location{result: -1, param: -1},
+		values:   make(map[ssa.Value]*location),
+		objects:  make(map[ssa.Value]*location),
+		results:  make(map[*ssa.Function][]*location),
+		bindings: make(map[*ssa.FreeVar]ssa.Value),
+		funcs:    make(map[*ssa.Function]bool),
+		loops:    make(map[*ssa.Function]*ssa.LoopForest),
+	}
+}
+
+// analyze builds and solves the flow graph of the batch, records the
+// decisions for its allocation sites, and returns the summary of its
+// root function.
+func (b *batch) analyze() *Summary {
+	var fns []*ssa.Function
+	var add func(fn *ssa.Function)
+	add = func(fn *ssa.Function) {
+		b.funcs[fn] = true
+		fns = append(fns, fn)
+		b.loops[fn] = fn.Loops()
+		var res []*location
+		for i := 0; i < fn.Signature.Results().Len(); i++ {
+			l := &location{fn: fn, result: i, param: -1}
+			res = append(res, l)
+			b.all = append(b.all, l)
+		}
+		b.results[fn] = res
+		for _, anon := range fn.AnonFuncs {
+			add(anon)
+		}
+	}
+	add(b.root)
+
+	// Bind free variables before building the flows that use them.
+	for _, fn := range fns {
+		forEachInstr(fn, func(instr ssa.Instruction) {
+			if mc, ok := instr.(*ssa.MakeClosure); ok {
+				anon := mc.Fn.(*ssa.Function)
+				for i, v := range mc.Bindings {
+					b.bindings[anon.FreeVars[i]] = v
+				}
+			}
+		})
+	}
+
+	s := &Summary{Params: make([]Leak, len(b.root.Params))}
+	for i, p := range b.root.Params {
+		b.value(p).param = i
+		l := Leak{Heap: NoLeak, Results: make([]int, b.root.Signature.Results().Len())}
+		for j := range l.Results {
+			l.Results[j] = NoLeak
+		}
+		s.Params[i] = l
+	}
+	for _, fn := range fns {
+		forEachInstr(fn, b.instr)
+	}
+	b.solve(s)
+	return s
+}
+
+// solve walks the flow graph from each location that may outlive
+// others, marking the objects that escape and recording the leaks of
+// the parameters of the root in s.
+func (b *batch) solve(s *Summary) {
+	todo := []*location{b.heap}
+	for _, l := range b.all {
+		if l.escapes {
+			todo = append(todo, l)
+		}
+	}
+	todo = append(todo, b.all...)
+	for len(todo) > 0 {
+		root := todo[0]
+		todo = todo[1:]
+		b.walk(root, s, func(l *location) { todo = append(todo, l) })
+	}
+	for site, obj := range b.objects {
+		b.a.res.Allocs[site] = obj.escapes
+	}
+}
+
+// walk visits the locations whose values flow to root, computing the
+// least number of dereferences along the way, and calls escape for each
+// object that newly escapes as a result.
+func (b *batch) walk(root *location, s *Summary, escape func(*location)) {
+	b.walkgen++
+	root.walkgen = b.walkgen
+	root.derefs = 0
+	todo := []*location{root}
+	for len(todo) > 0 {
+		l := todo[len(todo)-1]
+		todo = todo[:len(todo)-1]
+
+		derefs := l.derefs
+		if derefs < 0 {
+			// The address of l flows to root, but for a flow
+			// like "root = &l; l = x", that of x does not.
+			derefs = 0
+			if l.site != nil && !l.escapes && b.outlives(root, l) {
+				l.escapes = true
+				escape(l)
+			}
+		}
+		if l.param >= 0 {
+			leak := &s.Params[l.param]
+			switch {
+			case root == b.heap || root.escapes:
+				leak.Heap = least(leak.Heap, derefs)
+			case root.result >= 0 && root.fn == b.root:
+				leak.Results[root.result] = least(leak.Results[root.result], derefs)
+			}
+		}
+		for _, e := range l.edges {
+			d := derefs + e.derefs
+			if e.src.walkgen != b.walkgen || e.src.derefs > d {
+				e.src.walkgen = b.walkgen
+				e.src.derefs = d
+				todo = append(todo, e.src)
+			}
+		}
+	}
+}
+
+func least(x, y int) int {
+	if x == NoLeak || y < x {
+		return y
+	}
+	return x
+}
+
+// outlives reports whether values stored at root may outlive the
+// object l.
+func (b *batch) outlives(root, l *location) bool {
+	if root == b.heap || root.escapes {
+		return true
+	}
+	if root.result >= 0 {
+		// The results of a function outlive the objects it and
+		// its anonymous functions allocate.
+		for fn := l.fn; fn != nil; fn = fn.Parent() {
+			if fn == root.fn {
+				return true
+			}
+		}
+		return false
+	}
+	if root.fn != l.fn {
+		return false
+	}
+	// A variable or φ-node declared outside a loop outlives the
+	// objects allocated in each iteration, as does a φ-node of the
+	// loop header, which carries values from one iteration to the
+	// next.
+	forest := b.loops[l.fn]
+	inner := forest.LoopOf(l.block)
+	if inner == nil {
+		return false
+	}
+	if _, ok := root.value.(*ssa.Phi); ok {
+		for loop := inner; loop != nil; loop = loop.Parent {
+			if loop.Header == root.block {
+				return true
+			}
+		}
+	}
+	return forest.Depth(root.block) < inner.Depth
+}
+
+// value returns the location of value v, or nil if v refers to no
+// object of the batch.
+func (b *batch) value(v ssa.Value) *location {
+	switch v.(type) {
+	case *ssa.Const, *ssa.Function, *ssa.Global, *ssa.Builtin:
+		return nil
+	}
+	l, ok := b.values[v]
+	if !ok {
+		l = &location{value: v, result: -1, param: -1}
+		switch v := v.(type) {
+		case ssa.Instruction:
+			l.fn, l.block = v.Parent(), v.Block()
+			if _, ok := v.(*ssa.Phi); ok {
+				b.all = append(b.all, l)
+			}
+		case *ssa.Parameter:
+			l.fn = v.Parent()
+		case *ssa.FreeVar:
+			l.fn = v.Parent()
+		}
+		b.values[v] = l
+	}
+	return l
+}
+
+// object returns the location of the object allocated by site, whose
+// address (or reference) flows to the value of site.
+func (b *batch) object(site ssa.Value) *location {
+	instr := site.(ssa.Instruction)
+	obj := &location{fn: instr.Parent(), site: site, block: instr.Block(), result: -1, param: -1}
+	obj.escapes = b.tooLarge(site)
+	b.objects[site] = obj
+	b.all = append(b.all, obj)
+	b.value(site).edges = append(b.value(site).edges, edge{obj, -1})
+	return obj
+}
+
+// Objects larger than these limits, in bytes, are allocated on the
+// heap: the first for new(T), composite literals and so on, the
+// second for declared variables.
+const (
+	maxImplicitStackSize = 64 << 10
+	maxStackSize         = 128 << 10
+)
+
+// tooLarge reports whether the object allocated by site is too large
+// for the stack, or of unknown size, or is always allocated on the heap.
+func (b *batch) tooLarge(site ssa.Value) bool {
+	switch site := site.(type) {
+	case *ssa.Alloc:
+		size := b.a.sizes.Sizeof(site.Type().Underlying().(*types.Pointer).Elem())
+		switch site.Comment {
+		case "new", "complit", "slicelit", "makeslice", "varargs":
+			return size > maxImplicitStackSize
+		}
+		return size > maxStackSize
+	case *ssa.MakeSlice, *ssa.MakeChan:
+		// The capacity of a MakeSlice is not constant; the SSA
+		// form of other calls to make([]T) is an Alloc of an array.
+		return true
+	}
+	return false
+}
+
+// flow records that the value of src, dereferenced derefs times, flows
+// to location dst.
+func (b *batch) flow(dst *location, src ssa.Value, derefs int) {
+	if s := b.value(src); s != nil && dst != nil {
+		dst.edges = append(dst.edges, edge{s, derefs})
+	}
+}
+
+// instr adds the flows of instruction instr.
+func (b *batch) instr(instr ssa.Instruction) {
+	fn := instr.Parent()
+	switch instr := instr.(type) {
+	case *ssa.Alloc, *ssa.MakeSlice, *ssa.MakeMap, *ssa.MakeChan:
+		b.object(instr.(ssa.Value))
+
+	case *ssa.MakeClosure:
+		obj := b.object(instr)
+		anon := instr.Fn.(*ssa.Function)
+		for i, v := range instr.Bindings {
+			b.flow(obj, v, 0)
+			b.flow(b.value(anon.FreeVars[i]), v, 0)
+		}
+		// The results of a closure flow to its unknown callers.
+		for _, r := range b.results[anon] {
+			obj.edges = append(obj.edges, edge{r, 0})
+		}
+
+	case *ssa.MakeInterface:
+		if IsAlloc(instr) {
+			b.flow(b.object(instr), instr.X, 0)
+		} else {
+			b.flow(b.value(instr), instr.X, 0)
+		}
+
+	case *ssa.Convert:
+		if IsAlloc(instr) {
+			b.object(instr)
+		} else {
+			b.flow(b.value(instr), instr.X, 0)
+		}
+
+	case *ssa.BinOp:
+		if IsAlloc(instr) {
+			b.object(instr)
+		}
+
+	case *ssa.FieldAddr:
+		b.flow(b.value(instr), instr.X, 0)
+	case *ssa.IndexAddr:
+		b.flow(b.value(instr), instr.X, 0)
+	case *ssa.Field:
+		b.flow(b.value(instr), instr.X, 0)
+	case *ssa.Index:
+		b.flow(b.value(instr), instr.X, 0)
+	case *ssa.Slice:
+		b.flow(b.value(instr), instr.X, 0)
+	case *ssa.ChangeType:
+		b.flow(b.value(instr), instr.X, 0)
+	case *ssa.ChangeInterface:
+		b.flow(b.value(instr), instr.X, 0)
+	case *ssa.MultiConvert:
+		b.flow(b.value(instr), instr.X, 0)
+	case *ssa.SliceToArrayPointer:
+		b.flow(b.value(instr), instr.X, 0)
+	case *ssa.TypeAssert:
+		b.flow(b.value(instr), instr.X, 0)
+	case *ssa.Extract:
+		b.flow(b.value(instr), instr.Tuple, 0)
+
+	case *ssa.Phi:
+		for _, v := range instr.Edges {
+			b.flow(b.value(instr), v, 0)
+		}
+
+	case *ssa.UnOp:
+		if instr.Op == token.MUL {
+			b.flow(b.value(instr), instr.X, 1) // load
+		}
+
+	case *ssa.Lookup:
+		if _, ok := instr.X.Type().Underlying().(*types.Map); ok {
+			b.flow(b.value(instr), instr.X, 1)
+		}
+
+	case *ssa.Store:
+		if obj := b.variable(instr.Addr); obj != nil {
+			b.flow(obj, instr.Val, 0)
+		} else {
+			b.flow(b.heap, instr.Val, 0)
+		}
+
+	case *ssa.MapUpdate:
+		b.flow(b.heap, instr.Key, 0)
+		b.flow(b.heap, instr.Value, 0)
+
+	case *ssa.Send:
+		b.flow(b.heap, instr.X, 0)
+
+	case *ssa.Select:
+		for _, st := range instr.States {
+			if st.Send != nil {
+				b.flow(b.heap, st.Send, 0)
+			}
+		}
+
+	case *ssa.Panic:
+		b.flow(b.heap, instr.X, 0)
+
+	case *ssa.Return:
+		for i, v := range instr.Results {
+			if hasPointers(v.Type()) {
+				b.flow(b.results[fn][i], v, 0)
+			}
+		}
+
+	case *ssa.Call:
+		b.call(instr.Common(), b.value(instr))
+
+	case *ssa.Defer:
+		if b.loops[fn].LoopOf(instr.Block()) != nil {
+			b.unknown(instr.Common(), true) // heap-allocated defer record
+		} else {
+			b.call(instr.Common(), nil)
+		}
+
+	case *ssa.Go:
+		b.unknown(instr.Common(), true)
+	}
+}
+
+// variable returns the object of the local variable from which address
+// addr was derived by field and array element selections, or nil if
+// there is none.
+func (b *batch) variable(addr ssa.Value) *location {
+	for {
+		switch x := addr.(type) {
+		case *ssa.FieldAddr:
+			addr = x.X
+		case *ssa.IndexAddr:
+			if _, ok := x.X.Type().Underlying().(*types.Slice); ok {
+				return nil
+			}
+			addr = x.X
+		case *ssa.FreeVar:
+			v, ok := b.bindings[x]
+			if !ok {
+				return nil
+			}
+			addr = v
+		case *ssa.Alloc:
+			return b.objects[x]
+		default:
+			return nil
+		}
+	}
+}
+
+// call adds the flows of a call whose result flows to res.
+func (b *batch) call(call *ssa.CallCommon, res *location) {
+	if builtin, ok := call.Value.(*ssa.Builtin); ok {
+		b.builtin(builtin.Name(), call.Args, res)
+		return
+	}
+	callee := call.StaticCallee()
+	if callee != nil && b.funcs[callee] {
+		// Anonymous function of the batch.
+		for i, arg := range call.Args {
+			b.flow(b.value(callee.Params[i]), arg, 0)
+		}
+		for _, r := range b.results[callee] {
+			if res != nil {
+				res.edges = append(res.edges, edge{r, 0})
+			}
+		}
+		return
+	}
+	if callee != nil && len(callee.FreeVars) == 0 {
+		if s := b.a.summary(callee); s != nil && len(s.Params) == len(call.Args) {
+			for i, arg := range call.Args {
+				leak := s.Params[i]
+				if leak.Heap != NoLeak {
+					b.flow(b.heap, arg, leak.Heap)
+				}
+				for _, d := range leak.Results {
+					if d != NoLeak {
+						b.flow(res, arg, d)
+					}
+				}
+			}
+			return
+		}
+	}
+	b.unknown(call, false)
+}
+
+// unknown adds the flows of a call to an unknown function: its
+// arguments, including the receiver of an interface method, escape,
+// as does the function value itself if the call outlives the caller's
+// frame.
+func (b *batch) unknown(call *ssa.CallCommon, async bool) {
+	if async || call.IsInvoke() {
+		b.flow(b.heap, call.Value, 0)
+	}
+	for _, arg := range call.Args {
+		b.flow(b.heap, arg, 0)
+	}
+}
+
+// builtin adds the flows of a call to the named built-in function.
+func (b *batch) builtin(name string, args []ssa.Value, res *location) {
+	switch name {
+	case "append":
+		// The appended slice may flow to the result, and, if it is
+		// reallocated, its elements to the heap, as do those of
+		// the slice of appended values.
+		b.flow(res, args[0], 0)
+		if hasPointers(args[0].Type()) {
+			b.flow(b.heap, args[0], 1)
+		}
+		if len(args) > 1 {
+			b.flow(b.heap, args[1], 1)
+		}
+	case "copy":
+		b.flow(b.heap, args[1], 1)
+	case "panic":
+		b.flow(b.heap, args[0], 0)
+	case "ssa:wrapnilchk":
+		b.flow(res, args[0], 0)
+	}
+}
+
+func forEachInstr(fn *ssa.Function, f func(ssa.Instruction)) {
+	for _, b := range fn.Blocks {
+		for _, instr := range b.Instrs {
+			f(instr)
+		}
+	}
+}
+
+func origin(fn *ssa.Function) *ssa.Function {
+	if o := fn.Origin(); o != nil {
+		return o
+	}
+	return fn
+}
+
+// isPointerShaped reports whether values of type t are represented by
+// a single pointer, and so are stored in an interface without boxing.
+func isPointerShaped(t types.Type) bool {
+	switch t := t.Underlying().(type) {
+	case *types.Pointer, *types.Map, *types.Chan, *types.Signature:
+		return true
+	case *types.Basic:
+		return t.Kind() == types.UnsafePointer
+	case *types.Struct:
+		return t.NumFields() == 1 && isPointerShaped(t.Field(0).Type())
+	case *types.Array:
+		return t.Len() == 1 && isPointerShaped(t.Elem())
+	}
+	return false
+}
+
+// hasPointers reports whether values of type t contain pointers.
+func hasPointers(t types.Type) bool {
+	switch t := t.Underlying().(type) {
+	case *types.Basic:
+		return t.Info()&types.IsString != 0 || t.Kind() == types.UnsafePointer
+	case *types.Struct:
+		for i := 0; i < t.NumFields(); i++ {
+			if hasPointers(t.Field(i).Type()) {
+				return true
+			}
+		}
+		return false
+	case *types.Array:
+		return t.Len() > 0 && hasPointers(t.Elem())
+	case *types.Tuple:
+		for i := 0; i < t.Len(); i++ {
+			if hasPointers(t.At(i).Type()) {
+				return true
+			}
+		}
+		return false
+	}
+	return true
+}
+
+func isString(t types.Type) bool {
+	b, ok := t.Underlying().(*types.Basic)
+	return ok && b.Info()&types.IsString != 0
+}
+
+func isSlice(t types.Type) bool {
+	_, ok := t.Underlying().(*types.Slice)
+	return ok
+}
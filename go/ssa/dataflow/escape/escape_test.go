@@ -0,0 +1,167 @@
+// Copyright 2024 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package escape_test
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"go/types"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"reflect"
+	"regexp"
+	"runtime"
+	"strconv"
+	"strings"
+	"testing"
+
+	"golang.org/x/tools/go/analysis"
+	"golang.org/x/tools/go/analysis/analysistest"
+	"golang.org/x/tools/go/ssa"
+	"golang.org/x/tools/go/ssa/dataflow/escape"
+	"golang.org/x/tools/go/ssa/ssautil"
+	"golang.org/x/tools/internal/testenv"
+)
+
+// TestCompiler compares the predictions of the analysis for
+// testdata/src/compare with the decisions of the compiler, as
+// reported by its -m flag (with inlining disabled).
+func TestCompiler(t *testing.T) {
+	testenv.NeedsGoBuild(t)
+
+	dir := filepath.Join(analysistest.TestData(), "src", "compare")
+	cmd := exec.Command("go", "build", "-gcflags=-m -l", "-o", os.DevNull, "compare.go")
+	cmd.Dir = dir
+	// The analysis below uses the sizes of the host architecture.
+	cmd.Env = append(os.Environ(), "GOARCH="+runtime.GOARCH)
+	out, err := cmd.CombinedOutput()
+	if err != nil {
+		t.Fatalf("go build failed: %v\n%s", err, out)
+	}
+
+	// The compiler's decisions, by position.
+	type decision struct {
+		line, col int
+		msg       string
+	}
+	var decisions []decision
+	re := regexp.MustCompile(`^\./compare\.go:(\d+):(\d+): (.*)$`)
+	sc := bufio.NewScanner(bytes.NewReader(out))
+	for sc.Scan() {
+		m := re.FindStringSubmatch(sc.Text())
+		if m == nil {
+			continue
+		}
+		line, _ := strconv.Atoi(m[1])
+		col, _ := strconv.Atoi(m[2])
+		decisions = append(decisions, decision{line, col, m[3]})
+	}
+
+	fset := token.NewFileSet()
+	f, err := parser.ParseFile(fset, filepath.Join(dir, "compare.go"), nil, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pkg, _, err := ssautil.BuildPackage(&types.Config{}, fset, types.NewPackage("compare", ""), []*ast.File{f}, ssa.SanityCheckFunctions)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var funcs []*ssa.Function
+	for _, mem := range pkg.Members {
+		if fn, ok := mem.(*ssa.Function); ok && fn.Synthetic == "" {
+			funcs = append(funcs, fn)
+		}
+	}
+	res := escape.Analyze(funcs, types.SizesFor("gc", runtime.GOARCH), nil)
+
+	// Compare the leaks of parameters, by position.
+	params := make(map[[2]int]bool)
+	for fn, s := range res.Summaries {
+		for i, p := range fn.Params {
+			posn := fset.Position(p.Pos())
+			var want []string
+			for _, d := range decisions {
+				if d.line == posn.Line && d.col == posn.Column {
+					params[[2]int{d.line, d.col}] = true
+					want = append(want, d.msg)
+				}
+			}
+			if want == nil {
+				continue // no pointers
+			}
+			if got := s.Describe(fn, i); !reflect.DeepEqual(got, want) {
+				t.Errorf("%s: param %s: got %q, want %q", posn, p.Name(), got, want)
+			}
+		}
+	}
+
+	// Compare the allocation sites, by line: the compiler reports
+	// the escaping variables and the decisions for other objects.
+	compiler := make(map[int]bool) // line -> escapes
+	for _, d := range decisions {
+		// The reallocations of append and the optimization of
+		// some conversions are not modeled.
+		if params[[2]int{d.line, d.col}] || strings.HasPrefix(d.msg, "append ") || strings.Contains(d.msg, "zero-copy") {
+			continue
+		}
+		compiler[d.line] = compiler[d.line] || !strings.HasSuffix(d.msg, "does not escape")
+	}
+	ours := make(map[int]bool)
+	for site, escapes := range res.Allocs {
+		if line := fset.Position(escape.Pos(site)).Line; line > 0 {
+			ours[line] = ours[line] || escapes
+		}
+	}
+	for line, escapes := range ours {
+		if want := compiler[line]; escapes != want {
+			t.Errorf("compare.go:%d: escapes = %t, compiler says %t", line, escapes, want)
+		}
+	}
+	for line := range compiler {
+		if _, ok := ours[line]; !ok {
+			t.Errorf("compare.go:%d: no allocation site, compiler says %t", line, compiler[line])
+		}
+	}
+	if t.Failed() {
+		t.Logf("compiler output:\n%s", out)
+	}
+}
+
+// checker reports heap allocations within loops, as a client of
+// escape.Analyzer.
+var checker = &analysis.Analyzer{
+	Name:     "checker",
+	Doc:      "report heap allocations in loops",
+	Requires: []*analysis.Analyzer{escape.Analyzer},
+	Run: func(pass *analysis.Pass) (any, error) {
+		res := pass.ResultOf[escape.Analyzer].(*escape.Result)
+		for site, escapes := range res.Allocs {
+			instr := site.(ssa.Instruction)
+			if escapes && instr.Parent().Loops().LoopOf(instr.Block()) != nil {
+				pass.Reportf(escape.Pos(site), "heap allocation in loop: %s", describe(site))
+			}
+		}
+		return nil, nil
+	},
+}
+
+func describe(site ssa.Value) string {
+	switch site := site.(type) {
+	case *ssa.Alloc:
+		return site.Comment
+	case *ssa.MakeInterface:
+		return fmt.Sprintf("conversion of %s to interface", site.X.Type())
+	}
+	return strings.ToLower(strings.TrimPrefix(fmt.Sprintf("%T", site), "*ssa."))
+}
+
+func TestAnalyzer(t *testing.T) {
+	analysistest.Run(t, analysistest.TestData(), checker, "a", "b")
+}
@@ -0,0 +1,28 @@
+package a
+
+import "b"
+
+func loops(n int) (sum int) {
+	for i := 0; i < n; i++ {
+		x := i // want "heap allocation in loop: x"
+		b.Keep(&x)
+	}
+	for i := 0; i < n; i++ {
+		y := i
+		sum += b.Use(&y)
+	}
+	for i := 0; i < n; i++ {
+		s := make([]int, n) // want "heap allocation in loop: makeslice"
+		sum += len(s)
+	}
+	var ps []*int
+	for i := 0; i < n; i++ {
+		ps = append(ps, new(int)) // want "heap allocation in loop: new"
+	}
+	return sum + len(ps)
+}
+
+func noloop() {
+	x := 0
+	b.Keep(&x)
+}
@@ -0,0 +1,7 @@
+package b
+
+var global *int
+
+func Keep(p *int) { global = p }
+
+func Use(p *int) int { return *p }
@@ -0,0 +1,272 @@
+package compare
+
+type T struct {
+	p *int
+	n int
+}
+
+var global *int
+
+func local() int {
+	x := 0
+	p := &x
+	return *p
+}
+
+func returned() *int {
+	x := 0
+	return &x
+}
+
+func stored() {
+	x := 0
+	global = &x
+}
+
+func newLocal() int {
+	p := new(int)
+	return *p
+}
+
+func newReturned() *T {
+	return &T{n: 1}
+}
+
+func param(p *int) int {
+	return *p
+}
+
+func paramLeak(p *int) {
+	global = p
+}
+
+func paramContent(pp **int) {
+	global = *pp
+}
+
+func paramResult(p *int) *int {
+	return p
+}
+
+func paramStore(pp **int, p *int) {
+	*pp = p
+}
+
+func callee() {
+	x := 0
+	paramLeak(&x)
+}
+
+func callee2() int {
+	x := 0
+	return param(&x)
+}
+
+func callee3() *int {
+	x := 0
+	return paramResult(&x)
+}
+
+func slice() int {
+	s := make([]int, 10)
+	return s[0]
+}
+
+func sliceReturned() []int {
+	s := make([]int, 10)
+	return s
+}
+
+func sliceVar(n int) []int {
+	s := make([]int, n)
+	return s
+}
+
+func sliceLit() int {
+	s := []int{1, 2, 3}
+	return len(s)
+}
+
+func mapLocal() int {
+	m := make(map[int]int)
+	m[1] = 2
+	return m[1]
+}
+
+func mapReturned() map[int]int {
+	m := make(map[int]int)
+	return m
+}
+
+func closure() int {
+	x := 0
+	f := func() { x++ }
+	f()
+	return x
+}
+
+func closureEscapes() func() int {
+	x := 0
+	return func() int { x++; return x }
+}
+
+func iface(n int) any {
+	return n
+}
+
+func ifaceLocal(n int) bool {
+	x := any(n)
+	_, ok := x.(string)
+	return ok
+}
+
+func loop() *int {
+	var p *int
+	for i := 0; i < 10; i++ {
+		x := i
+		p = &x
+	}
+	return p
+}
+
+func loopLocal() int {
+	sum := 0
+	for i := 0; i < 10; i++ {
+		x := i
+		p := &x
+		sum += *p
+	}
+	return sum
+}
+
+func loopCarried() int {
+	var p *int
+	for i := 0; i < 10; i++ {
+		if p != nil {
+			i += *p
+		}
+		x := i
+		p = &x
+	}
+	return 0
+}
+
+func concat(a, b string) string {
+	return a + b
+}
+
+func concatLocal(a, b string) int {
+	s := a + b
+	return len(s)
+}
+
+func bytes(s string) []byte {
+	return []byte(s)
+}
+
+func bytesLocal(s string) int {
+	b := []byte(s)
+	return len(b)
+}
+
+func large() int {
+	var a [1 << 20]int
+	p := &a
+	return p[0]
+}
+
+func method(t *T) *int {
+	return t.p
+}
+
+func methodCall() *int {
+	x := T{}
+	return method(&x)
+}
+
+func field() int {
+	var t T
+	x := 0
+	t.p = &x
+	return *t.p
+}
+
+func fieldEscapes() T {
+	var t T
+	x := 0
+	t.p = &x
+	return t
+}
+
+func array() int {
+	var a [4]int
+	s := a[:]
+	return s[1]
+}
+
+func arrayReturned() []int {
+	var a [4]int
+	return a[:]
+}
+
+func variadic(xs ...*int) int {
+	return len(xs)
+}
+
+func variadicCall() int {
+	x := 0
+	return variadic(&x, nil)
+}
+
+func goroutine() {
+	x := 0
+	go func() { x++ }()
+}
+
+func deferred() int {
+	x := 0
+	defer func() { x++ }()
+	return x
+}
+
+func appended(s []*int) []*int {
+	x := 0
+	return append(s, &x)
+}
+
+func sent(c chan *int) {
+	x := 0
+	c <- &x
+}
+
+func mapped(m map[int]*int) {
+	x := 0
+	m[0] = &x
+}
+
+func unknown(f func(*int)) {
+	x := 0
+	f(&x)
+}
+
+func invoked(s interface{ M(*int) }) {
+	x := 0
+	s.M(&x)
+}
+
+func swap(p, q **int) {
+	*p, *q = *q, *p
+}
+
+func swapCall() {
+	x, y := 0, 0
+	p, q := &x, &y
+	swap(&p, &q)
+}
+
+func recursive(p *int, n int) *int {
+	if n == 0 {
+		return p
+	}
+	return recursive(p, n-1)
+}
@@ -0,0 +1,40 @@
+// Copyright 2024 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package ranges
+
+import (
+	"reflect"
+
+	"golang.org/x/tools/go/analysis"
+	"golang.org/x/tools/go/analysis/passes/buildssa"
+	"golang.org/x/tools/go/ssa"
+)
+
+// Analyzer computes the Ranges of the values of each source function of
+// a package. It reports no diagnostics; its result, of type *Result,
+// is for use by other analyzers.
+var Analyzer = &analysis.Analyzer{
+	Name:       "ranges",
+	Doc:        "compute the ranges of SSA values",
+	URL:        "https://pkg.go.dev/golang.org/x/tools/go/ssa/dataflow/ranges",
+	Requires:   []*analysis.Analyzer{buildssa.Analyzer},
+	ResultType: reflect.TypeOf(new(Result)),
+	Run:        run,
+}
+
+// A Result holds the Info for each source function of a package,
+// including anonymous functions.
+type Result struct {
+	Funcs map[*ssa.Function]*Info
+}
+
+func run(pass *analysis.Pass) (any, error) {
+	ssainput := pass.ResultOf[buildssa.Analyzer].(*buildssa.SSA)
+	res := &Result{Funcs: make(map[*ssa.Function]*Info)}
+	for _, fn := range ssainput.SrcFuncs {
+		res.Funcs[fn] = Analyze(fn, pass.TypesSizes)
+	}
+	return res, nil
+}
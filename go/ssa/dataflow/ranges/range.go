@@ -0,0 +1,541 @@
+// Copyright 2024 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package ranges
+
+import (
+	"fmt"
+	"go/constant"
+	"go/token"
+	"go/types"
+)
+
+// A Range is an abstract value: a set of values that an SSA value may
+// take. It is one of:
+//
+//   - bottom, the empty set, for a value not (yet) known to be computed
+//     at all, such as one defined in an unreachable block;
+//   - for a value of integer type, an interval [lo, hi] of the values
+//     of that type, which may be a single constant;
+//   - for a value of another basic type, a single constant;
+//   - top, any value of the type.
+//
+// The zero Range is bottom.
+type Range struct {
+	kind   kind
+	c      constant.Value // kind == constKind
+	lo, hi constant.Value // kind == intervalKind; exact
+}
+
+type kind uint8
+
+const (
+	bottomKind kind = iota
+	constKind
+	intervalKind
+	topKind
+)
+
+var (
+	bottom = Range{}
+	top    = Range{kind: topKind}
+)
+
+// interval returns the integer interval [lo, hi], or bottom if it is
+// empty.
+func interval(lo, hi constant.Value) Range {
+	if less(hi, lo) {
+		return bottom
+	}
+	return Range{kind: intervalKind, lo: lo, hi: hi}
+}
+
+// constRange returns the Range of the single constant c.
+func constRange(c constant.Value) Range {
+	if c.Kind() == constant.Int {
+		return interval(c, c)
+	}
+	return Range{kind: constKind, c: c}
+}
+
+// IsBottom reports whether r is empty, as is the Range of a value never
+// computed.
+func (r Range) IsBottom() bool { return r.kind == bottomKind }
+
+// IsTop reports whether r is the Range of any value of a non-integer
+// type. (The Range of any integer is the full interval of its type.)
+func (r Range) IsTop() bool { return r.kind == topKind }
+
+// Const returns the constant of a Range consisting of a single value,
+// or nil.
+func (r Range) Const() constant.Value {
+	switch r.kind {
+	case constKind:
+		return r.c
+	case intervalKind:
+		if constant.Compare(r.lo, token.EQL, r.hi) {
+			return r.lo
+		}
+	}
+	return nil
+}
+
+// Bounds returns the bounds of an integer interval: lo <= x <= hi for
+// every x in r. It reports false if r is not an interval.
+func (r Range) Bounds() (lo, hi constant.Value, ok bool) {
+	if r.kind != intervalKind {
+		return nil, nil, false
+	}
+	return r.lo, r.hi, true
+}
+
+// Contains reports whether r may include the constant x.
+func (r Range) Contains(x constant.Value) bool {
+	switch r.kind {
+	case constKind:
+		return constant.Compare(r.c, token.EQL, x)
+	case intervalKind:
+		return !less(x, r.lo) && !less(r.hi, x)
+	case topKind:
+		return true
+	}
+	return false
+}
+
+func (r Range) String() string {
+	switch r.kind {
+	case constKind:
+		return r.c.ExactString()
+	case intervalKind:
+		if c := r.Const(); c != nil {
+			return c.ExactString()
+		}
+		return fmt.Sprintf("[%s, %s]", r.lo.ExactString(), r.hi.ExactString())
+	case topKind:
+		return "⊤"
+	}
+	return "⊥"
+}
+
+// equal reports whether x and y are the same Range.
+func equal(x, y Range) bool {
+	if x.kind != y.kind {
+		return false
+	}
+	switch x.kind {
+	case constKind:
+		return constant.Compare(x.c, token.EQL, y.c)
+	case intervalKind:
+		return constant.Compare(x.lo, token.EQL, y.lo) && constant.Compare(x.hi, token.EQL, y.hi)
+	}
+	return true
+}
+
+// join returns the least Range containing x and y.
+func join(x, y Range) Range {
+	switch {
+	case x.kind == bottomKind:
+		return y
+	case y.kind == bottomKind:
+		return x
+	case x.kind == intervalKind && y.kind == intervalKind:
+		return interval(least(x.lo, y.lo), greatest(x.hi, y.hi))
+	case x.kind == constKind && y.kind == constKind && equal(x, y):
+		return x
+	}
+	return top
+}
+
+// meet returns the intersection of x and y.
+func meet(x, y Range) Range {
+	switch {
+	case x.kind == topKind:
+		return y
+	case y.kind == topKind:
+		return x
+	case x.kind == intervalKind && y.kind == intervalKind:
+		return interval(greatest(x.lo, y.lo), least(x.hi, y.hi))
+	case x.kind == constKind && y.kind == constKind && equal(x, y):
+		return x
+	}
+	return bottom
+}
+
+// widen returns the widening of old by new (which contains old) for
+// values of type t: each bound of old that new exceeds jumps to the
+// limit of the type.
+func (a *solver) widen(t types.Type, old, new Range) Range {
+	if old.kind != intervalKind || new.kind != intervalKind {
+		return new
+	}
+	full := a.full(t)
+	lo, hi := old.lo, old.hi
+	if less(new.lo, lo) {
+		lo = full.lo
+	}
+	if less(hi, new.hi) {
+		hi = full.hi
+	}
+	return interval(lo, hi)
+}
+
+func less(x, y constant.Value) bool { return constant.Compare(x, token.LSS, y) }
+
+func least(x, y constant.Value) constant.Value {
+	if less(y, x) {
+		return y
+	}
+	return x
+}
+
+func greatest(x, y constant.Value) constant.Value {
+	if less(x, y) {
+		return y
+	}
+	return x
+}
+
+var (
+	zero = constant.MakeInt64(0)
+	one  = constant.MakeInt64(1)
+)
+
+// isInteger reports whether t is an integer type.
+func isInteger(t types.Type) bool {
+	b, ok := t.Underlying().(*types.Basic)
+	return ok && b.Info()&types.IsInteger != 0
+}
+
+// bits returns the size in bits of integer type t.
+func (a *solver) bits(t types.Type) uint {
+	return uint(8 * a.sizes.Sizeof(t))
+}
+
+// full returns the interval of all values of integer type t.
+func (a *solver) full(t types.Type) Range {
+	bits := a.bits(t)
+	if t.Underlying().(*types.Basic).Info()&types.IsUnsigned != 0 {
+		hi := constant.Shift(one, token.SHL, bits)
+		return interval(zero, constant.BinaryOp(hi, token.SUB, one))
+	}
+	hi := constant.Shift(one, token.SHL, bits-1)
+	return interval(constant.UnaryOp(token.SUB, hi, 0), constant.BinaryOp(hi, token.SUB, one))
+}
+
+// any returns the Range of any value of type t.
+func (a *solver) any(t types.Type) Range {
+	if isInteger(t) {
+		return a.full(t)
+	}
+	return top
+}
+
+// fit returns the interval [lo, hi] if it is within the range of
+// integer type t, or the full range of t if the computation that
+// produced it may have overflowed and wrapped around.
+func (a *solver) fit(t types.Type, lo, hi constant.Value) Range {
+	full := a.full(t)
+	if less(lo, full.lo) || less(full.hi, hi) {
+		return full
+	}
+	return interval(lo, hi)
+}
+
+// hull returns the interval spanning the specified values, fitted to
+// type t.
+func (a *solver) hull(t types.Type, xs ...constant.Value) Range {
+	lo, hi := xs[0], xs[0]
+	for _, x := range xs[1:] {
+		lo, hi = least(lo, x), greatest(hi, x)
+	}
+	return a.fit(t, lo, hi)
+}
+
+// binop returns the Range of x op y, for operands of type t.
+func (a *solver) binop(op token.Token, t types.Type, x, y Range) Range {
+	if x.kind == bottomKind || y.kind == bottomKind {
+		return bottom
+	}
+	switch op {
+	case token.EQL, token.NEQ, token.LSS, token.LEQ, token.GTR, token.GEQ:
+		return compare(op, x, y)
+	}
+	if !isInteger(t) {
+		// Fold constants.
+		if x.kind != constKind || y.kind != constKind {
+			return top
+		}
+		if op == token.QUO && constant.Sign(y.c) == 0 {
+			return top // division by zero: Inf, NaN, or a panic
+		}
+		if op == token.SHL || op == token.SHR {
+			return top
+		}
+		return constRange(constant.BinaryOp(x.c, op, y.c))
+	}
+	if op == token.SHL || op == token.SHR {
+		return a.shift(op, t, x, y)
+	}
+
+	xlo, xhi := x.lo, x.hi
+	ylo, yhi := y.lo, y.hi
+	if x.kind != intervalKind || y.kind != intervalKind {
+		return a.full(t)
+	}
+	switch op {
+	case token.ADD:
+		return a.fit(t, constant.BinaryOp(xlo, token.ADD, ylo), constant.BinaryOp(xhi, token.ADD, yhi))
+
+	case token.SUB:
+		return a.fit(t, constant.BinaryOp(xlo, token.SUB, yhi), constant.BinaryOp(xhi, token.SUB, ylo))
+
+	case token.MUL:
+		return a.hull(t,
+			constant.BinaryOp(xlo, token.MUL, ylo),
+			constant.BinaryOp(xlo, token.MUL, yhi),
+			constant.BinaryOp(xhi, token.MUL, ylo),
+			constant.BinaryOp(xhi, token.MUL, yhi))
+
+	case token.QUO:
+		// Division by zero panics, so only nonzero divisors
+		// contribute. Truncated division is monotonic in each
+		// operand when the divisor's sign is fixed, so the
+		// extremes are at the corners.
+		r := bottom
+		for _, d := range nonzero(y) {
+			r = join(r, a.hull(t,
+				constant.BinaryOp(xlo, token.QUO_ASSIGN, d.lo),
+				constant.BinaryOp(xlo, token.QUO_ASSIGN, d.hi),
+				constant.BinaryOp(xhi, token.QUO_ASSIGN, d.lo),
+				constant.BinaryOp(xhi, token.QUO_ASSIGN, d.hi)))
+		}
+		return r
+
+	case token.REM:
+		// |x % y| < |y|, and x % y has the sign of x.
+		var m constant.Value // max |y| - 1
+		for _, d := range nonzero(y) {
+			for _, v := range []constant.Value{d.lo, d.hi} {
+				v = constant.BinaryOp(abs(v), token.SUB, one)
+				if m == nil || less(m, v) {
+					m = v
+				}
+			}
+		}
+		if m == nil {
+			return bottom
+		}
+		lo, hi := xlo, xhi
+		if less(xlo, zero) {
+			lo = greatest(xlo, constant.UnaryOp(token.SUB, m, 0))
+		} else {
+			lo = zero
+		}
+		if less(zero, xhi) {
+			hi = least(xhi, m)
+		} else {
+			hi = zero
+		}
+		return interval(lo, hi)
+
+	case token.AND:
+		switch {
+		case !less(xlo, zero) && !less(ylo, zero):
+			return interval(zero, least(xhi, yhi))
+		case !less(xlo, zero):
+			return interval(zero, xhi)
+		case !less(ylo, zero):
+			return interval(zero, yhi)
+		}
+
+	case token.AND_NOT:
+		if !less(xlo, zero) {
+			return interval(zero, xhi)
+		}
+
+	case token.OR, token.XOR:
+		if !less(xlo, zero) && !less(ylo, zero) {
+			// The result has no more bits than the larger operand.
+			n := constant.BitLen(greatest(xhi, yhi))
+			hi := constant.BinaryOp(constant.Shift(one, token.SHL, uint(n)), token.SUB, one)
+			lo := zero
+			if op == token.OR {
+				lo = greatest(xlo, ylo)
+			}
+			return a.fit(t, lo, hi)
+		}
+	}
+	return a.full(t)
+}
+
+// nonzero returns the nonnegative and nonpositive parts of the integer
+// interval y, excluding zero.
+func nonzero(y Range) []Range {
+	var parts []Range
+	if less(y.lo, zero) {
+		parts = append(parts, interval(y.lo, least(y.hi, constant.MakeInt64(-1))))
+	}
+	if less(zero, y.hi) {
+		parts = append(parts, interval(greatest(y.lo, one), y.hi))
+	}
+	return parts
+}
+
+func abs(x constant.Value) constant.Value {
+	if constant.Sign(x) < 0 {
+		return constant.UnaryOp(token.SUB, x, 0)
+	}
+	return x
+}
+
+// shift returns the Range of x op y, where op is SHL or SHR and x is
+// of integer type t.
+func (a *solver) shift(op token.Token, t types.Type, x, y Range) Range {
+	if x.kind != intervalKind || y.kind != intervalKind {
+		return a.full(t)
+	}
+	// A negative count panics; a count of at least the size of x
+	// shifts out every bit.
+	bits := a.bits(t)
+	ylo, yhi := greatest(y.lo, zero), y.hi
+	if less(yhi, ylo) {
+		return bottom
+	}
+	if op == token.SHL && !less(yhi, constant.MakeUint64(uint64(bits))) {
+		return a.full(t)
+	}
+	count := func(c constant.Value) uint {
+		n, _ := constant.Uint64Val(least(c, constant.MakeUint64(uint64(bits))))
+		return uint(n)
+	}
+	lo, hi := count(ylo), count(yhi)
+	return a.hull(t,
+		constant.Shift(x.lo, op, lo),
+		constant.Shift(x.lo, op, hi),
+		constant.Shift(x.hi, op, lo),
+		constant.Shift(x.hi, op, hi))
+}
+
+// compare returns the boolean Range of x op y.
+func compare(op token.Token, x, y Range) Range {
+	switch {
+	case x.kind == constKind && y.kind == constKind:
+		return constRange(constant.MakeBool(constant.Compare(x.c, op, y.c)))
+	case x.kind != intervalKind || y.kind != intervalKind:
+		return top
+	}
+	var always, never bool
+	switch op {
+	case token.LSS:
+		always, never = less(x.hi, y.lo), !less(x.lo, y.hi)
+	case token.LEQ:
+		always, never = !less(y.lo, x.hi), less(y.hi, x.lo)
+	case token.GTR:
+		always, never = less(y.hi, x.lo), !less(y.lo, x.hi)
+	case token.GEQ:
+		always, never = !less(x.lo, y.hi), less(x.hi, y.lo)
+	case token.EQL, token.NEQ:
+		disjoint := less(x.hi, y.lo) || less(y.hi, x.lo)
+		same := x.Const() != nil && equal(x, y)
+		always, never = same, disjoint
+		if op == token.NEQ {
+			always, never = never, always
+		}
+	}
+	switch {
+	case always:
+		return constRange(constant.MakeBool(true))
+	case never:
+		return constRange(constant.MakeBool(false))
+	}
+	return top
+}
+
+// unop returns the Range of op x, for an operand of type t.
+func (a *solver) unop(op token.Token, t types.Type, x Range) Range {
+	if x.kind == bottomKind {
+		return bottom
+	}
+	switch {
+	case isInteger(t) && x.kind == intervalKind:
+		switch op {
+		case token.SUB:
+			return a.fit(t, constant.UnaryOp(token.SUB, x.hi, 0), constant.UnaryOp(token.SUB, x.lo, 0))
+		case token.XOR:
+			// ^x is -x-1 for signed integers, max-x for unsigned.
+			if t.Underlying().(*types.Basic).Info()&types.IsUnsigned != 0 {
+				max := a.full(t).hi
+				return interval(constant.BinaryOp(max, token.SUB, x.hi), constant.BinaryOp(max, token.SUB, x.lo))
+			}
+			return interval(
+				constant.BinaryOp(constant.UnaryOp(token.SUB, x.hi, 0), token.SUB, one),
+				constant.BinaryOp(constant.UnaryOp(token.SUB, x.lo, 0), token.SUB, one))
+		}
+	case x.kind == constKind:
+		switch op {
+		case token.NOT, token.SUB:
+			return constRange(constant.UnaryOp(op, x.c, 0))
+		}
+	}
+	return a.any(t)
+}
+
+// convert returns the Range of the conversion of x, of type from, to
+// type to.
+func (a *solver) convert(to, from types.Type, x Range) Range {
+	if x.kind == bottomKind {
+		return bottom
+	}
+	switch {
+	case isInteger(to) && isInteger(from) && x.kind == intervalKind:
+		return a.fit(to, x.lo, x.hi)
+	case isInteger(to):
+		return a.full(to)
+	}
+	if c := x.Const(); c != nil {
+		if b, ok := to.Underlying().(*types.Basic); ok && b.Info()&types.IsFloat != 0 {
+			if c := constant.ToFloat(c); c.Kind() == constant.Float {
+				return constRange(c)
+			}
+		}
+	}
+	return top
+}
+
+// refine returns the subset of x, of type t, for which x op y holds.
+func (a *solver) refine(t types.Type, x Range, op token.Token, y Range) Range {
+	if x.kind != intervalKind || y.kind != intervalKind {
+		return x
+	}
+	full := a.full(t)
+	minus1 := func(c constant.Value) constant.Value { return constant.BinaryOp(c, token.SUB, one) }
+	plus1 := func(c constant.Value) constant.Value { return constant.BinaryOp(c, token.ADD, one) }
+	var r Range
+	switch op {
+	case token.LSS:
+		r = interval(full.lo, minus1(y.hi))
+	case token.LEQ:
+		r = interval(full.lo, y.hi)
+	case token.GTR:
+		r = interval(plus1(y.lo), full.hi)
+	case token.GEQ:
+		r = interval(y.lo, full.hi)
+	case token.EQL:
+		r = y
+	case token.NEQ:
+		// Only a constant at either end of x can be excluded.
+		if c := y.Const(); c != nil {
+			switch {
+			case constant.Compare(c, token.EQL, x.lo):
+				return interval(plus1(x.lo), x.hi)
+			case constant.Compare(c, token.EQL, x.hi):
+				return interval(x.lo, minus1(x.hi))
+			}
+		}
+		return x
+	default:
+		return x
+	}
+	return meet(x, r)
+}
@@ -0,0 +1,339 @@
+// Copyright 2024 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package ranges computes the possible values of the SSA values of a
+// function by abstract interpretation: sparse conditional constant
+// propagation combined with integer interval analysis.
+//
+// The abstract value of each SSA value is a [Range]: for an integer,
+// an interval of the values of its type; for another basic type, a
+// constant if the value is known to be one. As in Wegman and Zadeck's
+// sparse conditional constant propagation, the analysis follows only
+// the edges of the control-flow graph that may be taken, given the
+// abstract values of the branch conditions, so values computed only in
+// unreachable blocks remain empty (bottom), and φ-nodes ignore
+// incoming edges that are never taken.
+//
+// The comparisons of integers that control branches refine the
+// intervals of their operands: in the body of
+//
+//	for i := 0; i < 10; i++ { ... }
+//
+// the Range of i is [0, 9]. The analysis uses no other relational
+// information: if the bound were len(s), the Range of i would be only
+// [0, maxint-1]. Nor can an interval exclude a value from its middle,
+// so after a test x != 0, zero remains in the Range of a parameter x.
+//
+// Loops may require many iterations to converge, so when a φ-node of a
+// loop header grows, it is widened: each bound that moved jumps to the
+// limit of the type. Once a fixed point is reached, a few descending
+// (narrowing) iterations recover bounds lost to widening.
+//
+// Values loaded from memory, the results of calls (other than to len
+// and cap), parameters, and so on may hold any value of their type;
+// the analysis is intraprocedural.
+//
+// [Analyzer] makes the results available to go/analysis passes, for
+// checks such as division by a possibly zero value, or indexing an
+// array with a possibly out-of-range index.
+package ranges // import "golang.org/x/tools/go/ssa/dataflow/ranges"
+
+import (
+	"go/constant"
+	"go/token"
+	"go/types"
+
+	"golang.org/x/tools/go/ssa"
+)
+
+// Info holds the Ranges computed for the values of a function.
+type Info struct {
+	a *solver
+}
+
+// Range returns the Range of value v of the analyzed function at its
+// definition, or of a constant, parameter, or other operand.
+func (info *Info) Range(v ssa.Value) Range {
+	return info.a.fact(v)
+}
+
+// RangeAt returns the Range of value v as an operand of instruction
+// instr, refined by the conditions of the branches that lead to it.
+func (info *Info) RangeAt(v ssa.Value, instr ssa.Instruction) Range {
+	b := instr.Block()
+	if !info.a.reachable[b.Index] {
+		return bottom
+	}
+	return info.a.at(v, b, nil)
+}
+
+// Reachable reports whether block b may be executed.
+func (info *Info) Reachable(b *ssa.BasicBlock) bool {
+	return info.a.reachable[b.Index]
+}
+
+// EdgeReachable reports whether control may flow from block from to
+// its successor to.
+func (info *Info) EdgeReachable(from, to *ssa.BasicBlock) bool {
+	return info.a.edges[edge{from, to}]
+}
+
+// Analyze computes the Ranges of the values of fn, which must be
+// built, using sizes for the sizes of int, uint and uintptr.
+func Analyze(fn *ssa.Function, sizes types.Sizes) *Info {
+	a := &solver{
+		sizes:     sizes,
+		fn:        fn,
+		values:    make(map[ssa.Value]Range),
+		reachable: make([]bool, len(fn.Blocks)),
+		edges:     make(map[edge]bool),
+		changes:   make(map[*ssa.Phi]int),
+		header:    make([]bool, len(fn.Blocks)),
+	}
+	if len(fn.Blocks) > 0 {
+		a.solve()
+	}
+	return &Info{a}
+}
+
+// widenDelay is the number of times a φ-node that is not a loop header
+// may grow before it is widened. (An irreducible loop has no header.)
+const widenDelay = 8
+
+// narrowings is the number of descending iterations.
+const narrowings = 2
+
+type edge struct{ from, to *ssa.BasicBlock }
+
+type solver struct {
+	sizes     types.Sizes
+	fn        *ssa.Function
+	values    map[ssa.Value]Range // Ranges of the value-defining instructions
+	reachable []bool              // reachability of each block, by Index
+	edges     map[edge]bool       // set of CFG edges that may be taken
+	changes   map[*ssa.Phi]int    // number of times each φ-node grew
+	header    []bool              // whether each block is a loop header
+	changed   bool                // whether the current iteration changed anything
+}
+
+func (a *solver) solve() {
+	for _, l := range a.fn.Loops().All() {
+		a.header[l.Header.Index] = true
+	}
+	a.reachable[0] = true
+	if a.fn.Recover != nil {
+		a.reachable[a.fn.Recover.Index] = true
+	}
+
+	// Visiting blocks in dominator preorder ensures that every
+	// operand other than that of a φ-node is computed before use.
+	blocks := a.fn.DomPreorder()
+
+	// Ascend to a fixed point, widening at loop headers.
+	for a.changed = true; a.changed; {
+		a.changed = false
+		for _, b := range blocks {
+			if !a.reachable[b.Index] {
+				continue
+			}
+			for _, instr := range b.Instrs {
+				if v, ok := instr.(ssa.Value); ok {
+					a.update(v)
+				}
+			}
+			a.branch(b)
+		}
+	}
+
+	// Descend, recovering precision lost to widening.
+	for i := 0; i < narrowings; i++ {
+		for _, b := range blocks {
+			if !a.reachable[b.Index] {
+				continue
+			}
+			for _, instr := range b.Instrs {
+				if v, ok := instr.(ssa.Value); ok {
+					a.values[v] = meet(a.values[v], a.eval(v))
+				}
+			}
+		}
+	}
+}
+
+// update recomputes the Range of v during the ascending iterations.
+func (a *solver) update(v ssa.Value) {
+	old := a.values[v]
+	new := join(old, a.eval(v))
+	if equal(old, new) {
+		return
+	}
+	if phi, ok := v.(*ssa.Phi); ok {
+		a.changes[phi]++
+		if !old.IsBottom() && (a.header[phi.Block().Index] || a.changes[phi] > widenDelay) {
+			new = a.widen(v.Type(), old, new)
+		}
+	}
+	a.values[v] = new
+	a.changed = true
+}
+
+// branch marks the outgoing edges of b that may be taken.
+func (a *solver) branch(b *ssa.BasicBlock) {
+	switch instr := b.Instrs[len(b.Instrs)-1].(type) {
+	case *ssa.Jump:
+		a.mark(b, b.Succs[0])
+	case *ssa.If:
+		cond := a.at(instr.Cond, b, nil)
+		if c := cond.Const(); c != nil && c.Kind() == constant.Bool {
+			if constant.BoolVal(c) {
+				a.mark(b, b.Succs[0])
+			} else {
+				a.mark(b, b.Succs[1])
+			}
+		} else if !cond.IsBottom() {
+			a.mark(b, b.Succs[0])
+			a.mark(b, b.Succs[1])
+		}
+	}
+}
+
+func (a *solver) mark(from, to *ssa.BasicBlock) {
+	if e := (edge{from, to}); !a.edges[e] {
+		a.edges[e] = true
+		a.reachable[to.Index] = true
+		a.changed = true
+	}
+}
+
+// fact returns the current Range of value v, unrefined.
+func (a *solver) fact(v ssa.Value) Range {
+	switch x := v.(type) {
+	case *ssa.Const:
+		if x.Value == nil {
+			return a.any(x.Type()) // zero value of a non-basic type
+		}
+		c := x.Value
+		if b, ok := x.Type().Underlying().(*types.Basic); ok {
+			switch {
+			case b.Info()&types.IsInteger != 0:
+				c = constant.ToInt(c)
+			case b.Info()&types.IsFloat != 0:
+				c = constant.ToFloat(c)
+			}
+		}
+		return constRange(c)
+	case ssa.Instruction:
+		if x.Parent() == a.fn {
+			return a.values[v]
+		}
+	}
+	return a.any(v.Type())
+}
+
+// eval computes the Range of the value-defining instruction v from the
+// current Ranges of its operands.
+func (a *solver) eval(v ssa.Value) Range {
+	b := v.(ssa.Instruction).Block()
+	switch v := v.(type) {
+	case *ssa.Phi:
+		r := bottom
+		for i, pred := range b.Preds {
+			if a.edges[edge{pred, b}] {
+				r = join(r, a.at(v.Edges[i], pred, b))
+			}
+		}
+		return r
+
+	case *ssa.BinOp:
+		return a.binop(v.Op, v.X.Type(), a.at(v.X, b, nil), a.at(v.Y, b, nil))
+
+	case *ssa.UnOp:
+		if v.Op == token.MUL || v.Op == token.ARROW {
+			break // load, receive
+		}
+		return a.unop(v.Op, v.Type(), a.at(v.X, b, nil))
+
+	case *ssa.Convert:
+		return a.convert(v.Type(), v.X.Type(), a.at(v.X, b, nil))
+
+	case *ssa.ChangeType:
+		return a.convert(v.Type(), v.X.Type(), a.at(v.X, b, nil))
+
+	case *ssa.Call:
+		if builtin, ok := v.Call.Value.(*ssa.Builtin); ok {
+			switch builtin.Name() {
+			case "len", "cap":
+				return interval(zero, a.full(v.Type()).hi)
+			}
+		}
+	}
+	return a.any(v.Type())
+}
+
+// at returns the Range of x at the start of block b, refined by the
+// branch conditions that dominate b; or, if succ is non-nil, on the
+// edge from the end of b to its successor succ.
+func (a *solver) at(x ssa.Value, b, succ *ssa.BasicBlock) Range {
+	r := a.fact(x)
+	if !isInteger(x.Type()) || r.kind != intervalKind {
+		return r
+	}
+	// check refines r by the branch condition of block p, given
+	// that control flows from p to its successor s.
+	check := func(p, s *ssa.BasicBlock) {
+		cond, ok := p.Instrs[len(p.Instrs)-1].(*ssa.If)
+		if !ok || p.Succs[0] == p.Succs[1] {
+			return
+		}
+		cmp, ok := cond.Cond.(*ssa.BinOp)
+		if !ok {
+			return
+		}
+		op := cmp.Op
+		if s == p.Succs[1] {
+			op = negate[op]
+		}
+		switch x {
+		case cmp.X:
+			r = a.refine(x.Type(), r, op, a.fact(cmp.Y))
+		case cmp.Y:
+			r = a.refine(x.Type(), r, swap[op], a.fact(cmp.X))
+		}
+	}
+	// Apply the outermost conditions first, as excluding a value
+	// from an interval works only at its ends.
+	var edges []edge
+	if succ != nil {
+		edges = append(edges, edge{b, succ})
+	}
+	for s := b; s != nil; s = s.Idom() {
+		if len(s.Preds) == 1 {
+			edges = append(edges, edge{s.Preds[0], s})
+		}
+	}
+	for i := len(edges) - 1; i >= 0; i-- {
+		check(edges[i].from, edges[i].to)
+	}
+	return r
+}
+
+// negate maps each comparison to its negation: !(x op y) is x negate[op] y.
+var negate = map[token.Token]token.Token{
+	token.EQL: token.NEQ,
+	token.NEQ: token.EQL,
+	token.LSS: token.GEQ,
+	token.LEQ: token.GTR,
+	token.GTR: token.LEQ,
+	token.GEQ: token.LSS,
+}
+
+// swap maps each comparison to its converse: x op y is y swap[op] x.
+var swap = map[token.Token]token.Token{
+	token.EQL: token.EQL,
+	token.NEQ: token.NEQ,
+	token.LSS: token.GTR,
+	token.LEQ: token.GEQ,
+	token.GTR: token.LSS,
+	token.GEQ: token.LEQ,
+}
@@ -0,0 +1,199 @@
+// Copyright 2024 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package ranges_test
+
+import (
+	"go/ast"
+	"go/constant"
+	"go/parser"
+	"go/token"
+	"go/types"
+	"testing"
+
+	"golang.org/x/tools/go/analysis"
+	"golang.org/x/tools/go/analysis/analysistest"
+	"golang.org/x/tools/go/ssa"
+	"golang.org/x/tools/go/ssa/dataflow/ranges"
+	"golang.org/x/tools/go/ssa/ssautil"
+)
+
+// Each call print(want, x) asserts that the Range of x at the call is want.
+const src = `package p
+
+func constants() {
+	x := 3
+	y := x * 4
+	print("12", y)
+	s := "a" + "b"
+	print("\"ab\"", s)
+	if y > 10 {
+		print("true", y > 10)
+	} else {
+		print("⊥", y)
+	}
+}
+
+func params(x int8, u uint16, f float64) {
+	print("[-128, 127]", x)
+	print("[0, 65535]", u)
+	print("⊤", f)
+	print("[-127, 128]", -int(x))
+	print("[0, 255]", uint8(x)&0xff)
+	print("[0, 15]", u%16)
+	print("[-12, 12]", int(x)%13)
+	print("[0, 1023]", u>>6)
+	print("[-32, 31]", x>>2)
+	if x > 0 {
+		print("[1, 127]", x)
+		print("[0, 252]", int(x)*2-2)
+		print("[-128, 127]", x*2) // may wrap around
+		if x <= 10 {
+			print("[1, 10]", x)
+			print("[0, 9]", 10/x-1)
+		}
+	} else if x == 0 {
+		print("0", x)
+	} else {
+		print("[-128, -1]", x)
+		print("[-128, 127]", x-1) // may wrap around
+	}
+}
+
+func loops(n int) {
+	for i := 0; i < 10; i++ {
+		print("[0, 9]", i)
+	}
+	for i := 0; i < n; i++ {
+		print("[0, 9223372036854775806]", i)
+	}
+	for i := 0; i < n; i += 2 { // i+2 may wrap around
+		print("[-9223372036854775808, 9223372036854775806]", i)
+	}
+	j := 100
+	for j > 0 {
+		j--
+	}
+	print("0", j)
+}
+
+func unreachable() {
+	x := 1
+	for x < 10 {
+		x += 3
+	}
+	print("[10, 12]", x)
+	if x == 0 {
+		print("⊥", x)
+	}
+	b := x > 5
+	if !b {
+		print("⊥", x)
+	}
+}
+
+func builtins(s []int) {
+	print("[0, 9223372036854775807]", len(s))
+}
+`
+
+func TestRanges(t *testing.T) {
+	fset := token.NewFileSet()
+	f, err := parser.ParseFile(fset, "p.go", src, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pkg, _, err := ssautil.BuildPackage(&types.Config{}, fset, types.NewPackage("p", ""), []*ast.File{f}, ssa.SanityCheckFunctions)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sizes := types.SizesFor("gc", "amd64")
+	for _, mem := range pkg.Members {
+		fn, ok := mem.(*ssa.Function)
+		if !ok || fn.Synthetic != "" {
+			continue
+		}
+		info := ranges.Analyze(fn, sizes)
+		for _, b := range fn.Blocks {
+			for _, instr := range b.Instrs {
+				call, ok := instr.(*ssa.Call)
+				if !ok || len(call.Call.Args) != 2 {
+					continue
+				}
+				if builtin, ok := call.Call.Value.(*ssa.Builtin); !ok || builtin.Name() != "print" {
+					continue
+				}
+				want := constant.StringVal(call.Call.Args[0].(*ssa.Const).Value)
+				x := call.Call.Args[1]
+				if got := info.RangeAt(x, call).String(); got != want {
+					t.Errorf("%s: range of %s is %s, want %s", fset.Position(call.Pos()), x, got, want)
+				}
+			}
+		}
+	}
+}
+
+// checker reports divisions by possibly zero values, array indices
+// possibly out of range, and variable shifts whose count is always at
+// least the size of the shifted value, as a client of ranges.Analyzer.
+var checker = &analysis.Analyzer{
+	Name:     "checker",
+	Doc:      "report divisions by zero, array indices out of range, and shift counts too large",
+	Requires: []*analysis.Analyzer{ranges.Analyzer},
+	Run: func(pass *analysis.Pass) (any, error) {
+		res := pass.ResultOf[ranges.Analyzer].(*ranges.Result)
+		for fn, info := range res.Funcs {
+			for _, b := range fn.Blocks {
+				if !info.Reachable(b) {
+					continue
+				}
+				for _, instr := range b.Instrs {
+					switch instr := instr.(type) {
+					case *ssa.BinOp:
+						if (instr.Op == token.QUO || instr.Op == token.REM) && isInteger(instr.Y.Type()) {
+							if info.RangeAt(instr.Y, instr).Contains(constant.MakeInt64(0)) {
+								pass.Reportf(instr.Pos(), "division by possibly zero value")
+							}
+						}
+						if (instr.Op == token.SHL || instr.Op == token.SHR) && !is[*ssa.Const](instr.Y) {
+							// Constant shifts are checked by the shift pass.
+							bits := 8 * pass.TypesSizes.Sizeof(instr.X.Type())
+							lo, _, ok := info.RangeAt(instr.Y, instr).Bounds()
+							if ok && !constant.Compare(lo, token.LSS, constant.MakeInt64(bits)) {
+								pass.Reportf(instr.Pos(), "shift count too large for %d-bit value", bits)
+							}
+						}
+					case *ssa.IndexAddr:
+						var n int64
+						switch t := instr.X.Type().Underlying().(type) {
+						case *types.Pointer:
+							n = t.Elem().Underlying().(*types.Array).Len()
+						default:
+							continue // slice
+						}
+						lo, hi, ok := info.RangeAt(instr.Index, instr).Bounds()
+						if !ok || constant.Sign(lo) < 0 || constant.Compare(hi, token.GEQ, constant.MakeInt64(n)) {
+							pass.Reportf(instr.Pos(), "index possibly out of range")
+						}
+					}
+				}
+			}
+		}
+		return nil, nil
+	},
+}
+
+func is[T any](x any) bool {
+	_, ok := x.(T)
+	return ok
+}
+
+func isInteger(t types.Type) bool {
+	b, ok := t.Underlying().(*types.Basic)
+	return ok && b.Info()&types.IsInteger != 0
+}
+
+func TestAnalyzer(t *testing.T) {
+	analysistest.Run(t, analysistest.TestData(), checker, "a")
+}
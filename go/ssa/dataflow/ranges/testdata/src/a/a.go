@@ -0,0 +1,48 @@
+package a
+
+func div(x, y int) int {
+	if y > 0 {
+		return x / y
+	}
+	if x > 0 {
+		return 100 % x
+	}
+	return x / y // want "division by possibly zero value"
+}
+
+func loop(d int) int {
+	sum := 0
+	for i := 1; i < 10; i++ {
+		sum += 100 / i
+	}
+	for d >= 0 {
+		sum += sum / d // want "division by possibly zero value"
+		d--
+	}
+	return sum
+}
+
+func index(i int) int {
+	var a [8]int
+	for j := 0; j < len(a); j++ {
+		a[j] = j
+	}
+	for j := 0; j <= len(a); j++ {
+		a[j] = j // want "index possibly out of range"
+	}
+	if i >= 0 && i < 8 {
+		return a[i]
+	}
+	return a[i&7]
+}
+
+func shift(x uint32, n uint) uint32 {
+	if n < 32 {
+		return x<<n | x>>(32-n)
+	}
+	y := x >> (n - 32)
+	for s := 0; s < 40; s += 8 {
+		y |= x << s
+	}
+	return y | x>>n // want "shift count too large for 32-bit value"
+}
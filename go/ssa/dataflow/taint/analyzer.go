@@ -0,0 +1,74 @@
+// Copyright 2024 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package taint
+
+import (
+	"go/types"
+
+	"golang.org/x/tools/go/analysis"
+	"golang.org/x/tools/go/analysis/passes/buildssa"
+	"golang.org/x/tools/go/ssa"
+)
+
+// NewAnalyzer returns an Analyzer that reports flows of tainted values
+// to sinks, as specified by config. It exports the [Summary] of each
+// function as a fact, so that flows through functions of other packages
+// are reported too.
+func NewAnalyzer(config *Config) *analysis.Analyzer {
+	return &analysis.Analyzer{
+		Name:      "taint",
+		Doc:       "report flows of tainted values from sources to sinks",
+		URL:       "https://pkg.go.dev/golang.org/x/tools/go/ssa/dataflow/taint",
+		Requires:  []*analysis.Analyzer{buildssa.Analyzer},
+		FactTypes: []analysis.Fact{new(Summary)},
+		Run: func(pass *analysis.Pass) (any, error) {
+			return run(pass, config)
+		},
+	}
+}
+
+func run(pass *analysis.Pass, config *Config) (any, error) {
+	ssainput := pass.ResultOf[buildssa.Analyzer].(*buildssa.SSA)
+
+	imported := func(fn *ssa.Function) *Summary {
+		obj, ok := fn.Object().(*types.Func)
+		if !ok || obj.Pkg() == pass.Pkg {
+			return nil
+		}
+		s := new(Summary)
+		if !pass.ImportObjectFact(obj, s) {
+			return nil
+		}
+		return s
+	}
+	res := Analyze(config, ssainput.SrcFuncs, imported)
+
+	// Functions without a fact are assumed to propagate taint from
+	// each argument to the results, so only summaries that differ
+	// from that need be exported.
+	for fn, s := range res.Summaries {
+		if obj := fn.Object(); obj != nil && !isDefault(fn.Signature, s) {
+			pass.ExportObjectFact(obj, s)
+		}
+	}
+	for _, f := range res.Findings {
+		pass.Reportf(f.Pos(), "%s", f)
+	}
+	return nil, nil
+}
+
+// isDefault reports whether s is the summary assumed for a function
+// of the specified signature that has no fact.
+func isDefault(sig *types.Signature, s *Summary) bool {
+	n := sig.Params().Len()
+	if sig.Recv() != nil {
+		n++
+	}
+	var all Taint
+	for i := 0; i < n; i++ {
+		all |= Param(i)
+	}
+	return s.Sinks == 0 && (s.Results == all || sig.Results().Len() == 0)
+}
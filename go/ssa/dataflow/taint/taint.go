@@ -0,0 +1,435 @@
+// Copyright 2024 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package taint implements an interprocedural taint tracker for
+// programs in SSA form, and an [analysis.Analyzer] based on it.
+//
+// Values returned by calls to source functions are tainted, and taint
+// propagates through the operations of the program, through memory, and
+// through calls, until it is either removed by a call to a sanitizer
+// function or reaches an argument of a call to a sink function, which
+// is reported as a [Finding]. Sources, sinks and sanitizers are
+// specified by name in a [Config].
+//
+// Each function is described by a [Summary] of its effect on taint,
+// which is computed by [Analyze] for the functions of a package and
+// used at each call to the function, so that each function is
+// analyzed once regardless of the number of calls to it. The Analyzer
+// returned by [NewAnalyzer] exports summaries as facts, so that flows
+// across packages are tracked too.
+//
+// The analysis is flow-insensitive with respect to memory: a value
+// stored anywhere within a variable, such as in a field of a local
+// struct or an element of a slice, taints the entire variable. The
+// following flows are not tracked: stores through pointers received
+// as parameters, stores to global variables in other packages, and
+// flows of parameters into closures (although flows of tainted
+// values into closures are). The results of calls to functions with
+// unknown summaries, including dynamic calls, are tainted if any of
+// their arguments is.
+package taint // import "golang.org/x/tools/go/ssa/dataflow/taint"
+
+import (
+	"fmt"
+	"go/token"
+	"strings"
+
+	"golang.org/x/tools/go/ssa"
+	"golang.org/x/tools/go/ssa/dataflow"
+)
+
+// A Config specifies the sources, sinks and sanitizers of a taint analysis.
+//
+// Functions are specified by their name as reported by
+// [ssa.Function.String], for example "os.Getenv",
+// "(*net/http.Request).FormValue" or "(*database/sql.DB).Query".
+// Instances of a generic function have the name of the generic function.
+type Config struct {
+	Sources    []string // functions whose results are tainted
+	Sinks      []string // functions whose arguments must not be tainted
+	Sanitizers []string // functions whose results are never tainted
+}
+
+// A Taint is a set of origins of taint: [Source], for values tainted
+// regardless of the calling context, and [Param], for values tainted
+// if a parameter of the enclosing function is.
+type Taint uint64
+
+// Source is the origin of values tainted by a source function.
+const Source Taint = 1 << 63
+
+// maxParam is the number of the last parameter with its own Taint bit.
+// Parameters beyond it share its bit.
+const maxParam = 62
+
+// Param returns the origin of values tainted by the ith parameter of
+// the enclosing function, counting the receiver, if any, as parameter 0.
+func Param(i int) Taint {
+	return 1 << min(i, maxParam)
+}
+
+func (t Taint) String() string {
+	if t == 0 {
+		return "none"
+	}
+	var origins []string
+	if t&Source != 0 {
+		origins = append(origins, "source")
+	}
+	for i := 0; i <= maxParam; i++ {
+		if t&Param(i) != 0 {
+			origins = append(origins, fmt.Sprintf("p%d", i))
+		}
+	}
+	return strings.Join(origins, "|")
+}
+
+// A Summary describes the effect of a function on taint.
+// The Analyzer returned by [NewAnalyzer] exports it as a fact for
+// each function whose summary differs from the one assumed for
+// unknown functions, in which each parameter flows to the results.
+type Summary struct {
+	// Results is the taint of the results of the function:
+	// Source if they may be tainted by a source called within
+	// the function, and Param(i) if they may be tainted by
+	// parameter i.
+	Results Taint
+
+	// Sinks contains Param(i) if parameter i may flow to a sink.
+	Sinks Taint
+}
+
+func (*Summary) AFact() {}
+
+func (s *Summary) String() string {
+	return fmt.Sprintf("taint(results=%v, sinks=%v)", s.Results, s.Sinks)
+}
+
+// A Finding reports a call through which a tainted value flows to a sink.
+type Finding struct {
+	Call ssa.CallInstruction // call of a sink, or of a function that calls one
+	Arg  int                 // index of the tainted argument, counting the receiver of an invoke-mode call
+	Via  *ssa.Function       // the function called, if it is not itself a sink
+}
+
+// Pos returns the position of the call.
+func (f *Finding) Pos() token.Pos { return f.Call.Pos() }
+
+func (f *Finding) String() string {
+	if f.Via != nil {
+		return fmt.Sprintf("tainted value flows to a sink via call to %s", name(f.Via))
+	}
+	return fmt.Sprintf("tainted value flows to sink %s", name(f.Call.Common().StaticCallee()))
+}
+
+// A Result holds the results of [Analyze].
+type Result struct {
+	Summaries map[*ssa.Function]*Summary // summary of each analyzed function
+	Findings  []*Finding                 // calls through which tainted values reach sinks, in order
+}
+
+// Analyze tracks taint through the specified functions, which are
+// typically those of one package. Functions are identified with their
+// generic origin, if any; instances need not be included.
+//
+// The imported function, if non-nil, returns the summary of a function
+// that is not among funcs, or nil if it is unknown.
+func Analyze(config *Config, funcs []*ssa.Function, imported func(*ssa.Function) *Summary) *Result {
+	a := &analyzer{
+		sources:    makeSet(config.Sources),
+		sinks:      makeSet(config.Sinks),
+		sanitizers: makeSet(config.Sanitizers),
+		funcs:      make(map[*ssa.Function]bool),
+		imported:   imported,
+		writes:     make(map[ssa.Value][]ssa.Value),
+		closures:   make(map[*ssa.Function][]*ssa.MakeClosure),
+		returns:    make(map[*ssa.Function][]*ssa.Return),
+		sinkParams: make(map[*ssa.Function]Taint),
+	}
+	var (
+		origins []*ssa.Function
+		roots   []ssa.Value
+	)
+	for _, fn := range funcs {
+		fn = origin(fn)
+		if a.funcs[fn] {
+			continue
+		}
+		a.funcs[fn] = true
+		origins = append(origins, fn)
+		a.scan(fn)
+		roots = append(roots, dataflow.Values(fn)...)
+	}
+	a.facts = dataflow.SolveValues(roots, &dataflow.ValueProblem[Taint]{
+		Lattice:  lattice{},
+		Transfer: a.transfer,
+	})
+	a.solveSinks()
+
+	res := &Result{Summaries: make(map[*ssa.Function]*Summary)}
+	for _, fn := range origins {
+		res.Summaries[fn] = &Summary{
+			Results: a.results(fn, a.facts.Fact),
+			Sinks:   a.sinkParams[fn],
+		}
+		forEachCall(fn, func(call ssa.CallInstruction) {
+			sinks, via := a.sinkArgs(call)
+			for i, arg := range args(call.Common()) {
+				if sinks&Param(i) != 0 && a.facts.Fact(arg)&Source != 0 {
+					res.Findings = append(res.Findings, &Finding{Call: call, Arg: i, Via: via})
+					break
+				}
+			}
+		})
+	}
+	return res
+}
+
+type analyzer struct {
+	sources, sinks, sanitizers map[string]bool
+
+	funcs    map[*ssa.Function]bool // analyzed functions
+	imported func(*ssa.Function) *Summary
+
+	writes   map[ssa.Value][]ssa.Value            // values stored in the variable rooted at each value
+	closures map[*ssa.Function][]*ssa.MakeClosure // closures of each anonymous function
+	returns  map[*ssa.Function][]*ssa.Return      // return statements of each function
+
+	facts      *dataflow.ValueResult[Taint]
+	sinkParams map[*ssa.Function]Taint // parameters that flow to sinks
+}
+
+// scan records the writes, closures and returns of function fn.
+func (a *analyzer) scan(fn *ssa.Function) {
+	for _, b := range fn.Blocks {
+		for _, instr := range b.Instrs {
+			switch instr := instr.(type) {
+			case *ssa.Store:
+				a.write(instr.Addr, instr.Val)
+			case *ssa.MapUpdate:
+				a.write(instr.Map, instr.Key)
+				a.write(instr.Map, instr.Value)
+			case *ssa.Send:
+				a.write(instr.Chan, instr.X)
+			case *ssa.MakeClosure:
+				fn := instr.Fn.(*ssa.Function)
+				a.closures[fn] = append(a.closures[fn], instr)
+			case *ssa.Return:
+				a.returns[fn] = append(a.returns[fn], instr)
+			}
+		}
+	}
+}
+
+// write records a flow from value v into the variable rooted at addr.
+func (a *analyzer) write(addr, v ssa.Value) {
+	root := root(addr)
+	a.writes[root] = append(a.writes[root], v)
+}
+
+// root returns the value from which the address or reference v is
+// derived by selecting fields and elements.
+func root(v ssa.Value) ssa.Value {
+	for {
+		switch x := v.(type) {
+		case *ssa.FieldAddr:
+			v = x.X
+		case *ssa.IndexAddr:
+			v = x.X
+		case *ssa.Slice:
+			v = x.X
+		default:
+			return v
+		}
+	}
+}
+
+// transfer computes the taint of value v.
+func (a *analyzer) transfer(v ssa.Value, fact func(ssa.Value) Taint) Taint {
+	var t Taint
+	switch v := v.(type) {
+	case *ssa.Parameter:
+		for i, p := range v.Parent().Params {
+			if p == v {
+				t = Param(i)
+			}
+		}
+
+	case *ssa.FreeVar:
+		// Only taint from sources flows into closures.
+		fn := v.Parent()
+		for i, fv := range fn.FreeVars {
+			if fv == v {
+				for _, mc := range a.closures[fn] {
+					t |= fact(mc.Bindings[i]) & Source
+				}
+			}
+		}
+
+	case *ssa.Const, *ssa.Function, *ssa.Builtin, *ssa.Global:
+		// untainted, apart from stored values
+
+	case *ssa.Call:
+		t = a.call(v.Common(), fact)
+
+	default:
+		var rands [10]*ssa.Value
+		for _, op := range v.(ssa.Instruction).Operands(rands[:0]) {
+			if *op != nil {
+				t |= fact(*op)
+			}
+		}
+	}
+	for _, w := range a.writes[v] {
+		t |= fact(w)
+	}
+	return t
+}
+
+// call computes the taint of the result of a call.
+func (a *analyzer) call(call *ssa.CallCommon, fact func(ssa.Value) Taint) Taint {
+	args := args(call)
+	if callee := call.StaticCallee(); callee != nil {
+		switch name := name(callee); {
+		case a.sources[name]:
+			return Source
+		case a.sanitizers[name]:
+			return 0
+		}
+		if fn := origin(callee); a.funcs[fn] {
+			return apply(a.results(fn, fact), args, fact)
+		}
+		if s := a.summary(callee); s != nil {
+			return apply(s.Results, args, fact)
+		}
+	}
+
+	// Unknown callee: assume the result depends on all arguments.
+	var t Taint
+	if !call.IsInvoke() {
+		t = fact(call.Value)
+	}
+	for _, arg := range args {
+		t |= fact(arg)
+	}
+	return t
+}
+
+// results returns the taint of the results of analyzed function fn,
+// in terms of its parameters.
+func (a *analyzer) results(fn *ssa.Function, fact func(ssa.Value) Taint) Taint {
+	var t Taint
+	for _, ret := range a.returns[fn] {
+		for _, res := range ret.Results {
+			t |= fact(res)
+		}
+	}
+	return t
+}
+
+// solveSinks computes the parameters of each function that flow to sinks.
+func (a *analyzer) solveSinks() {
+	for changed := true; changed; {
+		changed = false
+		for fn := range a.funcs {
+			forEachCall(fn, func(call ssa.CallInstruction) {
+				sinks, _ := a.sinkArgs(call)
+				for i, arg := range args(call.Common()) {
+					if sinks&Param(i) != 0 {
+						old := a.sinkParams[fn]
+						a.sinkParams[fn] |= a.facts.Fact(arg) &^ Source
+						changed = changed || a.sinkParams[fn] != old
+					}
+				}
+			})
+		}
+	}
+}
+
+// sinkArgs returns the arguments of a call that flow to sinks, and
+// the callee, if it is not itself a sink.
+func (a *analyzer) sinkArgs(call ssa.CallInstruction) (Taint, *ssa.Function) {
+	callee := call.Common().StaticCallee()
+	if callee == nil {
+		return 0, nil
+	}
+	if a.sinks[name(callee)] {
+		return ^Taint(0), nil
+	}
+	if fn := origin(callee); a.funcs[fn] {
+		return a.sinkParams[fn], callee
+	}
+	if s := a.summary(callee); s != nil {
+		return s.Sinks, callee
+	}
+	return 0, nil
+}
+
+// summary returns the summary of a function that is not analyzed, or nil.
+func (a *analyzer) summary(fn *ssa.Function) *Summary {
+	if a.imported == nil {
+		return nil
+	}
+	return a.imported(origin(fn))
+}
+
+// apply returns the taint of the results of a call to a function whose
+// results have the specified taint.
+func apply(results Taint, args []ssa.Value, fact func(ssa.Value) Taint) Taint {
+	t := results & Source
+	for i, arg := range args {
+		if results&Param(i) != 0 {
+			t |= fact(arg)
+		}
+	}
+	return t
+}
+
+// args returns the arguments of a call, including the receiver of an
+// invoke-mode call, so that they correspond to the parameters of the
+// callee.
+func args(call *ssa.CallCommon) []ssa.Value {
+	if call.IsInvoke() {
+		return append([]ssa.Value{call.Value}, call.Args...)
+	}
+	return call.Args
+}
+
+func forEachCall(fn *ssa.Function, f func(ssa.CallInstruction)) {
+	for _, b := range fn.Blocks {
+		for _, instr := range b.Instrs {
+			if call, ok := instr.(ssa.CallInstruction); ok {
+				f(call)
+			}
+		}
+	}
+}
+
+// origin returns the generic function of which fn is an instance, or fn.
+func origin(fn *ssa.Function) *ssa.Function {
+	if orig := fn.Origin(); orig != nil {
+		return orig
+	}
+	return fn
+}
+
+// name returns the name of fn, as it appears in a Config.
+func name(fn *ssa.Function) string {
+	return origin(fn).String()
+}
+
+func makeSet(names []string) map[string]bool {
+	set := make(map[string]bool)
+	for _, name := range names {
+		set[name] = true
+	}
+	return set
+}
+
+// lattice is the lattice of Taint values, ordered by inclusion.
+type lattice struct{}
+
+func (lattice) Bottom() Taint         { return 0 }
+func (lattice) Join(x, y Taint) Taint { return x | y }
+func (lattice) Equal(x, y Taint) bool { return x == y }
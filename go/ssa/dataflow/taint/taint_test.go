@@ -0,0 +1,22 @@
+// Copyright 2024 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package taint_test
+
+import (
+	"testing"
+
+	"golang.org/x/tools/go/analysis/analysistest"
+	"golang.org/x/tools/go/ssa/dataflow/taint"
+)
+
+func Test(t *testing.T) {
+	analyzer := taint.NewAnalyzer(&taint.Config{
+		Sources:    []string{"b.Source", "(*b.Request).Param"},
+		Sinks:      []string{"b.Sink"},
+		Sanitizers: []string{"b.Sanitize"},
+	})
+	testdata := analysistest.TestData()
+	analysistest.Run(t, testdata, analyzer, "a", "b")
+}
@@ -0,0 +1,83 @@
+package a
+
+import "b"
+
+func direct() {
+	b.Sink(b.Source()) // want "tainted value flows to sink b.Sink"
+	b.Sink("constant")
+	b.Sink(b.Sanitize(b.Source()))
+}
+
+func method(r *b.Request) {
+	b.Sink("id=" + r.Param("id")) // want "tainted value flows to sink b.Sink"
+}
+
+func run(s string) { // want run:`taint\(results=none, sinks=p0\)`
+	b.Sink(s) // ok: only tainted if the parameter is
+}
+
+func wrap(s string) string {
+	return "[" + s + "]"
+}
+
+func local() {
+	run(b.Source())       // want "tainted value flows to a sink via call to a.run"
+	run(wrap(b.Source())) // want "tainted value flows to a sink via call to a.run"
+	run(wrap("ok"))
+
+	f := wrap             // dynamic calls propagate taint from arguments to results
+	b.Sink(f(b.Source())) // want "tainted value flows to sink b.Sink"
+}
+
+func imported() {
+	b.Exec(b.Input())          // want "tainted value flows to a sink via call to b.Exec"
+	b.Sink(b.Trim(b.Source())) // want "tainted value flows to sink b.Sink"
+	b.Sink(b.Trim("ok"))
+	b.Sink(b.Clean(b.Source()))
+}
+
+type T struct {
+	name string
+	args []string
+}
+
+func memory() {
+	var t T
+	t.name = b.Source()
+	b.Sink(t.name) // want "tainted value flows to sink b.Sink"
+
+	args := []string{"ok"}
+	args = append(args, b.Source())
+	b.Sink(args[1]) // want "tainted value flows to sink b.Sink"
+
+	m := make(map[string]string)
+	m["k"] = b.Source()
+	b.Sink(m["k"]) // want "tainted value flows to sink b.Sink"
+}
+
+func closure() {
+	s := b.Source()
+	func() {
+		b.Sink(s) // want "tainted value flows to sink b.Sink"
+	}()
+}
+
+func id[T any](x T) T {
+	return x
+}
+
+func generic() {
+	b.Sink(id(b.Source())) // want "tainted value flows to sink b.Sink"
+	b.Sink(id("ok"))
+}
+
+func recursive(s string, n int) string { // want recursive:`taint\(results=p0, sinks=none\)`
+	if n == 0 {
+		return s
+	}
+	return recursive(s, n-1)
+}
+
+func recursion() {
+	b.Sink(recursive(b.Source(), 3)) // want "tainted value flows to sink b.Sink"
+}
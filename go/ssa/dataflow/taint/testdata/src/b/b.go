@@ -0,0 +1,27 @@
+package b
+
+func Source() string { return "" }
+
+func Sink(string) {}
+
+func Sanitize(s string) string { return s }
+
+type Request struct{}
+
+func (*Request) Param(name string) string { return name } // want Param:`taint\(results=p1, sinks=none\)`
+
+func Exec(cmd string) { // want Exec:`taint\(results=none, sinks=p0\)`
+	Sink("sh -c " + cmd)
+}
+
+func Input() string { // want Input:`taint\(results=source, sinks=none\)`
+	return Source()
+}
+
+func Trim(s string) string {
+	return s[1:]
+}
+
+func Clean(s string) string { // want Clean:`taint\(results=none, sinks=none\)`
+	return Sanitize(s)
+}
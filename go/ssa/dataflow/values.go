@@ -0,0 +1,126 @@
+// Copyright 2024 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package dataflow
+
+import "golang.org/x/tools/go/ssa"
+
+// A ValueProblem describes a sparse dataflow problem over SSA values.
+type ValueProblem[F any] struct {
+	Lattice Lattice[F]
+
+	// Transfer computes the fact for value v, given a function that
+	// returns the current fact for any value.
+	//
+	// Each fact read through the fact function is a dependency of v:
+	// whenever it changes, the fact for v is recomputed. A forward
+	// problem typically reads the facts of the operands of v; a
+	// backward problem, those of its referrers; an interprocedural
+	// problem, values of other functions, such as the results of a
+	// callee. There is no need to declare dependencies in advance.
+	//
+	// Transfer must be monotone in the facts it reads.
+	Transfer func(v ssa.Value, fact func(ssa.Value) F) F
+}
+
+// A ValueResult holds the solution of a [ValueProblem].
+type ValueResult[F any] struct {
+	facts  map[ssa.Value]F
+	bottom F
+}
+
+// Fact returns the fact computed for value v, or the bottom element
+// if v was neither a root of the problem nor a dependency of one.
+func (r *ValueResult[F]) Fact(v ssa.Value) F {
+	if f, ok := r.facts[v]; ok {
+		return f
+	}
+	return r.bottom
+}
+
+// SolveValues solves the dataflow problem p for the specified root
+// values, and for all the values on which they depend, transitively.
+// [Values] returns the values of a function, for use as roots.
+func SolveValues[F any](roots []ssa.Value, p *ValueProblem[F]) *ValueResult[F] {
+	s := &valueSolver[F]{
+		p:      p,
+		res:    &ValueResult[F]{facts: make(map[ssa.Value]F), bottom: p.Lattice.Bottom()},
+		queued: make(map[ssa.Value]bool),
+		deps:   make(map[ssa.Value][]ssa.Value),
+		seen:   make(map[[2]ssa.Value]bool),
+	}
+	for _, v := range roots {
+		s.enqueue(v)
+	}
+	for len(s.work) > 0 {
+		v := s.work[0]
+		s.work = s.work[1:]
+		s.queued[v] = false
+
+		fact := p.Transfer(v, func(w ssa.Value) F {
+			// Record the dependency v -> w.
+			if k := [2]ssa.Value{w, v}; !s.seen[k] {
+				s.seen[k] = true
+				s.deps[w] = append(s.deps[w], v)
+			}
+			f, ok := s.res.facts[w]
+			if !ok {
+				// First encounter with w: compute its fact.
+				s.enqueue(w)
+				return s.res.bottom
+			}
+			return f
+		})
+		old, ok := s.res.facts[v]
+		if !ok {
+			old = s.res.bottom
+		}
+		fact = p.Lattice.Join(old, fact)
+		if ok && p.Lattice.Equal(fact, old) {
+			continue
+		}
+		s.res.facts[v] = fact
+		for _, dep := range s.deps[v] {
+			s.enqueue(dep)
+		}
+	}
+	return s.res
+}
+
+type valueSolver[F any] struct {
+	p      *ValueProblem[F]
+	res    *ValueResult[F]
+	work   []ssa.Value
+	queued map[ssa.Value]bool
+	deps   map[ssa.Value][]ssa.Value // deps[w] lists the values whose fact depends on w
+	seen   map[[2]ssa.Value]bool     // set of (w, v) pairs in deps
+}
+
+func (s *valueSolver[F]) enqueue(v ssa.Value) {
+	if !s.queued[v] {
+		s.queued[v] = true
+		s.work = append(s.work, v)
+	}
+}
+
+// Values returns the values defined by function fn: its parameters,
+// free variables, and the value-defining instructions of its body,
+// in order.
+func Values(fn *ssa.Function) []ssa.Value {
+	var values []ssa.Value
+	for _, p := range fn.Params {
+		values = append(values, p)
+	}
+	for _, fv := range fn.FreeVars {
+		values = append(values, fv)
+	}
+	for _, b := range fn.Blocks {
+		for _, instr := range b.Instrs {
+			if v, ok := instr.(ssa.Value); ok {
+				values = append(values, v)
+			}
+		}
+	}
+	return values
+}
@@ -15,24 +15,21 @@ import (
 	"sort"
 	"strconv"
 	"strings"
-	"time"
 	"unicode/utf8"
 )
 
 type externalFn func(fr *frame, args []value) value
 
-// TODO(adonovan): fix: reflect.Value abstracts an lvalue or an
-// rvalue; Set() causes mutations that can be observed via aliases.
-// We have not captured that correctly here.
-
 // Key strings are from Function.String().
 var externals = make(map[string]externalFn)
 
 func init() {
 	// That little dot ۰ is an Arabic zero numeral (U+06F0), categories [Nd].
 	for k, v := range map[string]externalFn{
+		"(reflect.Value).Addr":            ext۰reflect۰Value۰Addr,
 		"(reflect.Value).Bool":            ext۰reflect۰Value۰Bool,
 		"(reflect.Value).CanAddr":         ext۰reflect۰Value۰CanAddr,
+		"(reflect.Value).CanSet":          ext۰reflect۰Value۰CanSet,
 		"(reflect.Value).CanInterface":    ext۰reflect۰Value۰CanInterface,
 		"(reflect.Value).Elem":            ext۰reflect۰Value۰Elem,
 		"(reflect.Value).Field":           ext۰reflect۰Value۰Field,
@@ -50,6 +47,11 @@ func init() {
 		"(reflect.Value).NumMethod":       ext۰reflect۰Value۰NumMethod,
 		"(reflect.Value).Pointer":         ext۰reflect۰Value۰Pointer,
 		"(reflect.Value).Set":             ext۰reflect۰Value۰Set,
+		"(reflect.Value).SetBool":         ext۰reflect۰Value۰SetBool,
+		"(reflect.Value).SetFloat":        ext۰reflect۰Value۰SetFloat,
+		"(reflect.Value).SetInt":          ext۰reflect۰Value۰SetInt,
+		"(reflect.Value).SetString":       ext۰reflect۰Value۰SetString,
+		"(reflect.Value).SetUint":         ext۰reflect۰Value۰SetUint,
 		"(reflect.Value).String":          ext۰reflect۰Value۰String,
 		"(reflect.Value).Type":            ext۰reflect۰Value۰Type,
 		"(reflect.Value).Uint":            ext۰reflect۰Value۰Uint,
@@ -69,22 +71,14 @@ func init() {
 		"bytes.Equal":                     ext۰bytes۰Equal,
 		"bytes.IndexByte":                 ext۰bytes۰IndexByte,
 		"fmt.Sprint":                      ext۰fmt۰Sprint,
-		"math.Abs":                        ext۰math۰Abs,
-		"math.Copysign":                   ext۰math۰Copysign,
 		"math.Exp":                        ext۰math۰Exp,
-		"math.Float32bits":                ext۰math۰Float32bits,
-		"math.Float32frombits":            ext۰math۰Float32frombits,
-		"math.Float64bits":                ext۰math۰Float64bits,
-		"math.Float64frombits":            ext۰math۰Float64frombits,
-		"math.Inf":                        ext۰math۰Inf,
-		"math.IsNaN":                      ext۰math۰IsNaN,
 		"math.Ldexp":                      ext۰math۰Ldexp,
 		"math.Log":                        ext۰math۰Log,
 		"math.Min":                        ext۰math۰Min,
-		"math.NaN":                        ext۰math۰NaN,
 		"math.Sqrt":                       ext۰math۰Sqrt,
 		"os.Exit":                         ext۰os۰Exit,
 		"os.Getenv":                       ext۰os۰Getenv,
+		"reflect.Append":                  ext۰reflect۰Append,
 		"reflect.New":                     ext۰reflect۰New,
 		"reflect.SliceOf":                 ext۰reflect۰SliceOf,
 		"reflect.TypeOf":                  ext۰reflect۰TypeOf,
@@ -97,6 +91,7 @@ func init() {
 		"runtime.Goexit":                  ext۰runtime۰Goexit,
 		"runtime.Gosched":                 ext۰runtime۰Gosched,
 		"runtime.NumCPU":                  ext۰runtime۰NumCPU,
+		"runtime.NumGoroutine":            ext۰runtime۰NumGoroutine,
 		"sort.Float64s":                   ext۰sort۰Float64s,
 		"sort.Ints":                       ext۰sort۰Ints,
 		"sort.Strings":                    ext۰sort۰Strings,
@@ -143,50 +138,14 @@ func ext۰bytes۰IndexByte(fr *frame, args []value) value {
 	return -1
 }
 
-func ext۰math۰Float64frombits(fr *frame, args []value) value {
-	return math.Float64frombits(args[0].(uint64))
-}
-
-func ext۰math۰Float64bits(fr *frame, args []value) value {
-	return math.Float64bits(args[0].(float64))
-}
-
-func ext۰math۰Float32frombits(fr *frame, args []value) value {
-	return math.Float32frombits(args[0].(uint32))
-}
-
-func ext۰math۰Abs(fr *frame, args []value) value {
-	return math.Abs(args[0].(float64))
-}
-
-func ext۰math۰Copysign(fr *frame, args []value) value {
-	return math.Copysign(args[0].(float64), args[1].(float64))
-}
-
 func ext۰math۰Exp(fr *frame, args []value) value {
 	return math.Exp(args[0].(float64))
 }
 
-func ext۰math۰Float32bits(fr *frame, args []value) value {
-	return math.Float32bits(args[0].(float32))
-}
-
 func ext۰math۰Min(fr *frame, args []value) value {
 	return math.Min(args[0].(float64), args[1].(float64))
 }
 
-func ext۰math۰NaN(fr *frame, args []value) value {
-	return math.NaN()
-}
-
-func ext۰math۰IsNaN(fr *frame, args []value) value {
-	return math.IsNaN(args[0].(float64))
-}
-
-func ext۰math۰Inf(fr *frame, args []value) value {
-	return math.Inf(args[0].(int))
-}
-
 func ext۰math۰Ldexp(fr *frame, args []value) value {
 	return math.Ldexp(args[0].(float64), args[1].(int))
 }
@@ -275,9 +234,7 @@ func ext۰runtime۰GOMAXPROCS(fr *frame, args []value) value {
 }
 
 func ext۰runtime۰Goexit(fr *frame, args []value) value {
-	// TODO(adonovan): don't kill the interpreter's main goroutine.
-	runtime.Goexit()
-	return nil
+	panic(goexitPanic{}) // unwinds the goroutine, running deferred calls
 }
 
 func ext۰runtime۰GOROOT(fr *frame, args []value) value {
@@ -290,7 +247,7 @@ func ext۰runtime۰GC(fr *frame, args []value) value {
 }
 
 func ext۰runtime۰Gosched(fr *frame, args []value) value {
-	runtime.Gosched()
+	fr.i.sched.yield(fr)
 	return nil
 }
 
@@ -298,8 +255,12 @@ func ext۰runtime۰NumCPU(fr *frame, args []value) value {
 	return runtime.NumCPU()
 }
 
+func ext۰runtime۰NumGoroutine(fr *frame, args []value) value {
+	return len(fr.i.sched.live)
+}
+
 func ext۰time۰Sleep(fr *frame, args []value) value {
-	time.Sleep(time.Duration(args[0].(int64)))
+	fr.i.sched.sleep(fr, args[0].(int64))
 	return nil
 }
 
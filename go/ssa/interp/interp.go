@@ -14,19 +14,29 @@
 // The following is a partial list of Go features that are currently
 // unsupported or incomplete in the interpreter.
 //
-// * Unsafe operations, including all uses of unsafe.Pointer, are
-// impossible to support given the "boxed" value representation we
-// have chosen.
+// * Unsafe operations are supported only as far as the "boxed" value
+// representation allows (see memory.go): an unsafe.Pointer may be
+// converted to a pointer to a variable, field or array element of
+// the same layout at the same address, or to a view of the bytes of
+// a region of booleans and numbers, but not, for example, to a view
+// of the bytes of a string.
 //
-// * The reflect package is only partially implemented.
+// * The reflect package is only partially implemented, by functions
+// of the interpreter (see reflect.go) rather than by interpreting its
+// source. Other packages that depend on the runtime's representation
+// of values, such as sync.Pool, are tested only against the
+// simplified standard library in testdata/src, whose sync.Pool,
+// strings.Builder and reflection-based encoding/json are written in
+// ordinary Go.
 //
 // * The "testing" package is no longer supported because it
 // depends on low-level details that change too often.
 //
-// * "sync/atomic" operations are not atomic due to the "boxed" value
-// representation: it is not possible to read, modify and write an
-// interface value atomically. As a consequence, Mutexes are currently
-// broken.
+// * Goroutines are interpreted one at a time by a deterministic
+// cooperative scheduler (see sched.go), so the interpreter cannot
+// exhibit all the behaviors of a parallel program. Channels, and the
+// Mutex, RWMutex, WaitGroup and Cond types of package sync, are
+// implemented by the scheduler, which reports deadlocks.
 //
 // * recover is only partially implemented.  Also, the interpreter
 // makes no attempt to distinguish target panics from interpreter
@@ -49,9 +59,7 @@ import (
 	"go/token"
 	"go/types"
 	"os"
-	"reflect"
 	"runtime"
-	"sync/atomic"
 	_ "unsafe"
 
 	"golang.org/x/tools/go/ssa"
@@ -72,6 +80,7 @@ type Mode uint
 const (
 	DisableRecover Mode = 1 << iota // Disable recover() in target programs; show interpreter crash instead.
 	EnableTracing                   // Print a trace of all instructions as they are interpreted.
+	DetectRaces                     // Report data races between goroutines; exit with code 66 if any.
 )
 
 type methodSet map[string]*ssa.Function
@@ -87,7 +96,8 @@ type interpreter struct {
 	rtypeMethods       methodSet              // the method set of rtype, which implements the reflect.Type interface.
 	runtimeErrorString types.Type             // the runtime.errorString type
 	sizes              types.Sizes            // the effective type-sizing function
-	goroutines         int32                  // atomically updated
+	sched              *scheduler             // the goroutines of the program
+	mem                memory                 // addresses of variables for unsafe.Pointer
 }
 
 type deferred struct {
@@ -108,6 +118,7 @@ type frame struct {
 	result           value
 	panicking        bool
 	panic            interface{}
+	instr            ssa.Instruction // the current instruction
 }
 
 func (fr *frame) get(key ssa.Value) value {
@@ -142,8 +153,12 @@ func (fr *frame) runDefer(d *deferred) {
 	defer func() {
 		if !ok {
 			// Deferred call created a new state of panic.
+			p := recover()
+			if _, ok := p.(*fatalError); ok {
+				panic(p) // not recoverable
+			}
 			fr.panicking = true
-			fr.panic = recover()
+			fr.panic = p
 		}
 	}()
 	call(fr.i, fr, d.instr.Pos(), d.fn, d.args)
@@ -192,7 +207,21 @@ func visitInstr(fr *frame, instr ssa.Instruction) continuation {
 		// no-op
 
 	case *ssa.UnOp:
-		fr.env[instr] = unop(instr, fr.get(instr.X))
+		if instr.Op == token.ARROW {
+			v, ok := fr.i.sched.recv(fr, fr.get(instr.X).(*channel))
+			if !ok {
+				v = zero(instr.X.Type().Underlying().(*types.Chan).Elem())
+			}
+			if instr.CommaOk {
+				v = tuple{v, ok}
+			}
+			fr.env[instr] = v
+		} else {
+			if instr.Op == token.MUL && fr.i.sched.races != nil {
+				fr.i.sched.access(fr, fr.get(instr.X).(*value), false)
+			}
+			fr.env[instr] = unop(instr, fr.get(instr.X))
+		}
 
 	case *ssa.BinOp:
 		fr.env[instr] = binop(instr.Op, instr.X.Type(), fr.get(instr.X), fr.get(instr.Y))
@@ -208,7 +237,12 @@ func visitInstr(fr *frame, instr ssa.Instruction) continuation {
 		fr.env[instr] = fr.get(instr.X) // (can't fail)
 
 	case *ssa.Convert:
-		fr.env[instr] = conv(instr.Type(), instr.X.Type(), fr.get(instr.X))
+		x := fr.get(instr.X)
+		if v, ok := fr.i.convUnsafe(instr.Type(), instr.X.Type(), x); ok {
+			fr.env[instr] = v
+		} else {
+			fr.env[instr] = conv(instr.Type(), instr.X.Type(), x)
+		}
 
 	case *ssa.SliceToArrayPointer:
 		fr.env[instr] = sliceToArrayPointer(instr.Type(), instr.X.Type(), fr.get(instr.X))
@@ -244,9 +278,12 @@ func visitInstr(fr *frame, instr ssa.Instruction) continuation {
 		panic(targetPanic{fr.get(instr.X)})
 
 	case *ssa.Send:
-		fr.get(instr.Chan).(chan value) <- fr.get(instr.X)
+		fr.i.sched.send(fr, fr.get(instr.Chan).(*channel), fr.get(instr.X))
 
 	case *ssa.Store:
+		if fr.i.sched.races != nil {
+			fr.i.sched.access(fr, fr.get(instr.Addr).(*value), true)
+		}
 		store(typeparams.MustDeref(instr.Addr.Type()), fr.get(instr.Addr).(*value), fr.get(instr.Val))
 
 	case *ssa.If:
@@ -276,14 +313,16 @@ func visitInstr(fr *frame, instr ssa.Instruction) continuation {
 
 	case *ssa.Go:
 		fn, args := prepareCall(fr, &instr.Call)
-		atomic.AddInt32(&fr.i.goroutines, 1)
-		go func() {
+		fr.i.sched.spawn(func() {
 			call(fr.i, nil, instr.Pos(), fn, args)
-			atomic.AddInt32(&fr.i.goroutines, -1)
-		}()
+		})
 
 	case *ssa.MakeChan:
-		fr.env[instr] = make(chan value, asInt64(fr.get(instr.Size)))
+		size := asInt64(fr.get(instr.Size))
+		if size < 0 {
+			panic(runtimeError("makechan: size out of range"))
+		}
+		fr.env[instr] = &channel{cap: int(size)}
 
 	case *ssa.Alloc:
 		var addr *value
@@ -387,40 +426,22 @@ func visitInstr(fr *frame, instr ssa.Instruction) continuation {
 		}
 
 	case *ssa.Select:
-		var cases []reflect.SelectCase
-		if !instr.Blocking {
-			cases = append(cases, reflect.SelectCase{
-				Dir: reflect.SelectDefault,
-			})
-		}
+		var cases []selectCase
 		for _, state := range instr.States {
-			var dir reflect.SelectDir
-			if state.Dir == types.RecvOnly {
-				dir = reflect.SelectRecv
-			} else {
-				dir = reflect.SelectSend
-			}
-			var send reflect.Value
+			c := selectCase{dir: state.Dir, ch: fr.get(state.Chan).(*channel)}
 			if state.Send != nil {
-				send = reflect.ValueOf(fr.get(state.Send))
+				c.send = fr.get(state.Send)
 			}
-			cases = append(cases, reflect.SelectCase{
-				Dir:  dir,
-				Chan: reflect.ValueOf(fr.get(state.Chan)),
-				Send: send,
-			})
-		}
-		chosen, recv, recvOk := reflect.Select(cases)
-		if !instr.Blocking {
-			chosen-- // default case should have index -1.
+			cases = append(cases, c)
 		}
+		chosen, recv, recvOk := fr.i.sched.doSelect(fr, cases, instr.Blocking)
 		r := tuple{chosen, recvOk}
 		for i, st := range instr.States {
 			if st.Dir == types.RecvOnly {
 				var v value
 				if i == chosen && recvOk {
 					// No need to copy since send makes an unaliased copy.
-					v = recv.Interface().(value)
+					v = recv
 				} else {
 					v = zero(st.Chan.Type().Underlying().(*types.Chan).Elem())
 				}
@@ -576,8 +597,15 @@ func runFrame(fr *frame) {
 		if fr.i.mode&DisableRecover != 0 {
 			return // let interpreter crash
 		}
+		p := recover()
+		if _, ok := p.(*fatalError); ok {
+			panic(p) // not recoverable; don't run defers
+		}
+		if g := fr.i.sched.cur; g.frame == nil {
+			g.frame = fr // the innermost frame, for the panic's stack
+		}
 		fr.panicking = true
-		fr.panic = recover()
+		fr.panic = p
 		if fr.i.mode&EnableTracing != 0 {
 			fmt.Fprintf(os.Stderr, "Panicking: %T %v.\n", fr.panic, fr.panic)
 		}
@@ -598,6 +626,8 @@ func runFrame(fr *frame) {
 					fmt.Fprintln(os.Stderr, "\t", instr)
 				}
 			}
+			fr.instr = instr
+			fr.i.sched.tick(fr)
 			switch visitInstr(fr, instr) {
 			case kReturn:
 				return
@@ -619,11 +649,14 @@ func doRecover(caller *frame) value {
 	if caller.i.mode&DisableRecover == 0 &&
 		caller != nil && !caller.panicking &&
 		caller.caller != nil && caller.caller.panicking {
-		caller.caller.panicking = false
 		p := caller.caller.panic
+		if _, ok := p.(goexitPanic); ok {
+			return iface{} // runtime.Goexit is not a panic
+		}
+		caller.caller.panicking = false
 		caller.caller.panic = nil
+		caller.i.sched.cur.frame = nil
 
-		// TODO(adonovan): support runtime.Goexit.
 		switch p := p.(type) {
 		case targetPanic:
 			// The target program explicitly called panic().
@@ -654,12 +687,20 @@ func doRecover(caller *frame) value {
 // Type parameterized functions must have been built with
 // InstantiateGenerics in the ssa.BuilderMode to be interpreted.
 func Interpret(mainpkg *ssa.Package, mode Mode, sizes types.Sizes, filename string, args []string) (exitCode int) {
+	return InterpretWithSeed(mainpkg, mode, sizes, filename, args, 0)
+}
+
+// InterpretWithSeed is like Interpret, but if seed is nonzero it runs
+// the goroutines of the program under a randomized schedule determined
+// by seed. Running a program with the same seed reproduces the same
+// schedule, so a failure found by trying many seeds can be replayed.
+func InterpretWithSeed(mainpkg *ssa.Package, mode Mode, sizes types.Sizes, filename string, args []string, seed int64) (exitCode int) {
 	i := &interpreter{
-		prog:       mainpkg.Prog,
-		globals:    make(map[*ssa.Global]*value),
-		mode:       mode,
-		sizes:      sizes,
-		goroutines: 1,
+		prog:    mainpkg.Prog,
+		globals: make(map[*ssa.Global]*value),
+		mode:    mode,
+		sizes:   sizes,
+		sched:   newScheduler(seed, mode&DetectRaces != 0),
 	}
 	runtimePkg := i.prog.ImportedPackage("runtime")
 	if runtimePkg == nil {
@@ -685,31 +726,38 @@ func Interpret(mainpkg *ssa.Package, mode Mode, sizes types.Sizes, filename stri
 		}
 	}
 
+	// Like the Go race detector, exit with code 66 after a data race.
+	defer func() {
+		if r := i.sched.races; r != nil && r.count > 0 {
+			fmt.Fprintf(os.Stderr, "Found %d data race(s)\n", r.count)
+			if exitCode == 0 {
+				exitCode = 66
+			}
+		}
+	}()
+
 	// Top-level error handler.
 	exitCode = 2
 	defer func() {
 		if exitCode != 2 || i.mode&DisableRecover != 0 {
 			return
 		}
-		switch p := recover().(type) {
+		p := recover()
+		if _, ok := p.(goexitPanic); ok {
+			// The main goroutine called runtime.Goexit:
+			// the program continues until the others exit.
+			p = i.sched.goexitMain()
+		}
+		switch p := p.(type) {
 		case exitPanic:
 			exitCode = int(p)
-			return
-		case targetPanic:
-			fmt.Fprintln(os.Stderr, "panic:", toString(p.v))
-		case runtime.Error:
-			fmt.Fprintln(os.Stderr, "panic:", p.Error())
-		case string:
-			fmt.Fprintln(os.Stderr, "panic:", p)
+		case *fatalError:
+			fmt.Fprintln(os.Stderr, p.msg)
+			fmt.Fprint(os.Stderr, p.stacks)
 		default:
-			fmt.Fprintf(os.Stderr, "panic: unexpected type: %T: %v\n", p, p)
+			fmt.Fprintln(os.Stderr, "panic:", panicString(p))
+			fmt.Fprint(os.Stderr, i.sched.stacks(i.sched.main))
 		}
-
-		// TODO(adonovan): dump panicking interpreter goroutine?
-		// buf := make([]byte, 0x10000)
-		// runtime.Stack(buf, false)
-		// fmt.Fprintln(os.Stderr, string(buf))
-		// (Or dump panicking target goroutine?)
 	}()
 
 	// Run!
@@ -723,3 +771,17 @@ func Interpret(mainpkg *ssa.Package, mode Mode, sizes types.Sizes, filename stri
 	}
 	return
 }
+
+// panicString returns the message of a panic with value p.
+func panicString(p any) string {
+	switch p := p.(type) {
+	case targetPanic:
+		return toString(p.v)
+	case runtime.Error:
+		return p.Error()
+	case string:
+		return p
+	default:
+		return fmt.Sprintf("unexpected type: %T: %v", p, p)
+	}
+}
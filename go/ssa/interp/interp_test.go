@@ -139,8 +139,12 @@ var testdataTests = []string{
 	"fixedbugs/issue66783.go",
 	"typeassert.go",
 	"zeros.go",
+	"concurrency.go",
+	"unsafe.go",
 	"slice2array.go",
 	"minmax.go",
+	"pool.go",
+	"json.go",
 	"rangevarlifetime_go122.go",
 	"forvarlifetime_go122.go",
 }
@@ -153,6 +157,24 @@ func init() {
 }
 
 func run(t *testing.T, input string, goroot string) {
+	exitCode, output := interpret(t, input, goroot, 0, 0)
+	if exitCode != 0 {
+		t.Fatalf("interpreting %s: exit code was %d\n%s", input, exitCode, traceHint(input))
+	}
+	// $GOROOT/test tests use this convention:
+	if strings.Contains(output, "BUG") {
+		t.Fatalf("interpreting %s: exited zero but output contained 'BUG'\n%s", input, traceHint(input))
+	}
+}
+
+func traceHint(input string) string {
+	return fmt.Sprintf("To trace execution, run:\n%% go build golang.org/x/tools/cmd/ssadump && ./ssadump -build=C -test -run --interp=T %s\n", input)
+}
+
+// interpret interprets the program in file input in the given mode,
+// under the schedule with the given seed, and returns its exit code
+// and its output.
+func interpret(t *testing.T, input string, goroot string, imode interp.Mode, seed int64) (exitCode int, output string) {
 	testenv.NeedsExec(t) // really we just need os.Pipe, but os/exec uses pipes
 
 	t.Logf("Input: %s\n", input)
@@ -215,7 +237,7 @@ func run(t *testing.T, input string, goroot string) {
 	if sizes.Sizeof(types.Typ[types.Int]) < 4 {
 		panic("bogus SizesFor")
 	}
-	hint = fmt.Sprintf("To trace execution, run:\n%% go build golang.org/x/tools/cmd/ssadump && ./ssadump -build=C -test -run --interp=T %s\n", input)
+	hint = traceHint(input)
 
 	// Capture anything written by the interpreter to os.Std{out,err}
 	// by temporarily redirecting them to a buffer via a pipe.
@@ -226,7 +248,8 @@ func run(t *testing.T, input string, goroot string) {
 	// Suppress capturing if we are the child process of TestRangeFunc.
 	// TODO(adonovan): simplify that test using this mechanism.
 	// Also eliminate the redundant interp.CapturedOutput mechanism.
-	restore := func() {} // restore files and log the mixed out/err.
+	// restore restores the files, and logs and returns the mixed out/err.
+	restore := func() string { return interp.CapturedOutput.String() }
 	if os.Getenv("INTERPTEST_CHILD") == "" {
 		// Connect std{out,err} to pipe.
 		r, w, err := os.Pipe()
@@ -249,33 +272,27 @@ func run(t *testing.T, input string, goroot string) {
 		}()
 
 		// Finally, restore the files and log what was captured.
-		restore = func() {
+		restore = func() string {
 			os.Stdout = savedStdout
 			os.Stderr = savedStderr
 			w.Close()
 			<-done
 			t.Logf("Interpreter's stdout+stderr:\n%s", &buf)
+			return buf.String()
 		}
 	}
 
-	var imode interp.Mode // default mode
 	// imode |= interp.DisableRecover // enable for debugging
 	// imode |= interp.EnableTracing // enable for debugging
-	exitCode := interp.Interpret(mainPkg, imode, sizes, input, []string{})
-	restore()
-	if exitCode != 0 {
-		t.Fatalf("interpreting %s: exit code was %d", input, exitCode)
-	}
-	// $GOROOT/test tests use this convention:
-	if strings.Contains(interp.CapturedOutput.String(), "BUG") {
-		t.Fatalf("interpreting %s: exited zero but output contained 'BUG'", input)
-	}
+	exitCode = interp.InterpretWithSeed(mainPkg, imode, sizes, input, []string{}, seed)
+	output = restore()
 
 	hint = "" // call off the hounds
 
 	if false {
 		t.Log(input, time.Since(start)) // test profiling
 	}
+	return exitCode, output
 }
 
 // makeGoroot copies testdata/src into the "src" directory of a temporary
@@ -368,14 +385,12 @@ func TestTypeparamTest(t *testing.T) {
 	skip := map[string]string{
 		"chans.go":      "interp tests do not support runtime.SetFinalizer",
 		"issue23536.go": "unknown reason",
-		"issue48042.go": "interp tests do not handle reflect.Value.SetInt",
 		"issue47716.go": "interp tests do not handle unsafe.Sizeof",
 		"issue50419.go": "interp tests do not handle dispatch to String() correctly",
 		"issue51733.go": "interp does not handle unsafe casts",
 		"ordered.go":    "math.NaN() comparisons not being handled correctly",
 		"orderedmap.go": "interp tests do not support runtime.SetFinalizer",
 		"stringer.go":   "unknown reason",
-		"issue48317.go": "interp tests do not support encoding/json",
 		"issue48318.go": "interp tests do not support encoding/json",
 		"issue58513.go": "interp tests do not support runtime.Caller",
 	}
@@ -410,3 +425,100 @@ func TestTypeparamTest(t *testing.T) {
 		})
 	}
 }
+
+// TestFatal checks the fatal errors reported by the scheduler
+// for the programs in testdata/fatal.
+func TestFatal(t *testing.T) {
+	goroot := makeGoroot(t)
+	for _, test := range []struct {
+		input string
+		want  []string
+	}{
+		{"deadlock.go", []string{
+			"fatal error: all goroutines are asleep - deadlock!",
+			"goroutine 1 [sync.Mutex.Lock]:\n(*sync.Mutex).Lock(...)\nmain.main(...)\n\t",
+			"deadlock.go:14:9",
+			"goroutine 2 [chan send]:\nmain.main$1(...)\n\t",
+			"deadlock.go:11:6",
+		}},
+		{"gopanic.go", []string{
+			"panic: (string, boom)",
+			"goroutine 2 [running]:\nmain.main$1(...)",
+		}},
+		{"goexit.go", []string{
+			"fatal error: no goroutines (main called runtime.Goexit) - deadlock!",
+		}},
+		{"unlock.go", []string{
+			"fatal error: sync: unlock of unlocked mutex",
+			"goroutine 1 [running]:\n(*sync.Mutex).Unlock(...)\nmain.main(...)",
+		}},
+		{"waitgroup.go", []string{
+			"fatal error: all goroutines are asleep - deadlock!",
+			"goroutine 1 [sync.WaitGroup.Wait]:",
+			"goroutine 2 [select (no cases)]:",
+		}},
+	} {
+		t.Run(test.input, func(t *testing.T) {
+			exitCode, output := interpret(t, filepath.Join("testdata", "fatal", test.input), goroot, 0, 0)
+			if exitCode != 2 {
+				t.Errorf("exit code was %d, want 2", exitCode)
+			}
+			for _, want := range test.want {
+				if !strings.Contains(output, want) {
+					t.Errorf("output does not contain %q:\n%s", want, output)
+				}
+			}
+		})
+	}
+}
+
+// TestRaces checks that data races are reported, and only for racy
+// programs.
+func TestRaces(t *testing.T) {
+	goroot := makeGoroot(t)
+	exitCode, output := interpret(t, filepath.Join("testdata", "fatal", "race.go"), goroot, interp.DetectRaces, 0)
+	if exitCode != 66 {
+		t.Errorf("race.go: exit code was %d, want 66", exitCode)
+	}
+	for _, want := range []string{
+		"WARNING: DATA RACE\nWrite by goroutine 2:\n  main.main$1(...)\n",
+		"race.go:10:3",
+		"Previous read by goroutine 1:\n  main.main(...)\n",
+		"race.go:13:8",
+		"Found 1 data race(s)",
+	} {
+		if !strings.Contains(output, want) {
+			t.Errorf("race.go: output does not contain %q:\n%s", want, output)
+		}
+	}
+
+	input := filepath.Join("testdata", "concurrency.go")
+	if exitCode, output := interpret(t, input, goroot, interp.DetectRaces, 0); exitCode != 0 {
+		t.Errorf("concurrency.go: exit code was %d, want 0:\n%s", exitCode, output)
+	}
+}
+
+// TestSchedules checks that randomized schedules find a failure that
+// the default schedule does not, and that they are reproducible.
+func TestSchedules(t *testing.T) {
+	goroot := makeGoroot(t)
+	input := filepath.Join("testdata", "fatal", "schedule.go")
+	if exitCode, output := interpret(t, input, goroot, 0, 0); exitCode != 0 {
+		t.Fatalf("default schedule: exit code was %d, want 0:\n%s", exitCode, output)
+	}
+	for seed := int64(1); seed <= 100; seed++ {
+		exitCode, output := interpret(t, input, goroot, 0, seed)
+		if exitCode == 0 {
+			continue
+		}
+		if !strings.Contains(output, "panic: (string, preempted)") {
+			t.Errorf("seed %d: unexpected failure:\n%s", seed, output)
+		}
+		exitCode2, output2 := interpret(t, input, goroot, 0, seed)
+		if exitCode2 != exitCode || output2 != output {
+			t.Errorf("seed %d: replay differs:\n%s\n----\n%s", seed, output, output2)
+		}
+		return
+	}
+	t.Errorf("no schedule failed")
+}
@@ -0,0 +1,428 @@
+// Copyright 2024 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package interp
+
+// Unsafe pointers.
+//
+// In the interpreter's "boxed" representation, a variable is a cell
+// (a *value) holding a value whose shape follows its type: a struct is
+// a structure of cells, an array an array of cells. There are no
+// bytes, so an unsafe.Pointer cannot simply be an address.
+//
+// Instead, an unsafe.Pointer is an unsafePointer: a cell, its type,
+// and a byte offset within it, computed from the layout given by
+// types.Sizes. Converting it to a *T finds the cell at that offset
+// whose type has the layout of T: a field of a struct, an element of an
+// array, or the variable itself, so that the resulting pointer aliases
+// the original variable.
+//
+// When no such cell exists but T is a scalar type (a boolean or
+// number) that lies within a region of scalar cells, such as the bits
+// of a float64 viewed as a uint64, or a byte of an integer, the result
+// is a pointer to a view: loads and stores through it encode the
+// region into bytes, in the host's byte order, and read or write the
+// bytes of T. Regions containing pointers, strings, and other
+// reference values cannot be viewed as bytes.
+//
+// Converting an unsafe.Pointer to uintptr yields a synthetic address
+// from which the pointer can be recovered, even after arithmetic, as
+// long as the result stays within the same variable.
+//
+// Pointers are compared by cell, so the addresses of a struct and of
+// its first field, though both at offset zero, compare unequal.
+
+import (
+	"encoding/binary"
+	"fmt"
+	"go/types"
+	"math"
+	"unsafe"
+)
+
+// An unsafePointer is the interpreter's representation of an
+// unsafe.Pointer: the address of byte off of the variable cell of type t.
+// The zero value is nil.
+type unsafePointer struct {
+	cell *value
+	t    types.Type
+	off  int64
+}
+
+// A view is the content of the cell to which a pointer to a scalar
+// type T points when T is not the type of a cell at that address:
+// the bytes [off, off+size(T)) of the variable cell of type t.
+type view struct {
+	sizes types.Sizes
+	cell  *value
+	t     types.Type
+	off   int64
+	T     types.Type
+}
+
+// memory holds the synthetic addresses of variables, and the arrays of
+// slices whose data pointers were taken.
+type memory struct {
+	ids    map[*value]uintptr // ids of variables, from 1
+	cells  []unsafePointer    // variables (with off=0), by id-1
+	arrays map[*value][]value // arrays, by address of first element
+}
+
+// addrStride is the distance between synthetic addresses of variables,
+// and thus the limit on the size of a variable whose interior
+// addresses are valid.
+const addrStride = 1 << (4 * unsafe.Sizeof(uintptr(0)))
+
+// uintptr returns the synthetic address of p.
+func (m *memory) uintptr(p unsafePointer) uintptr {
+	if p.cell == nil {
+		return uintptr(p.off)
+	}
+	id, ok := m.ids[p.cell]
+	if !ok {
+		if m.ids == nil {
+			m.ids = make(map[*value]uintptr)
+		}
+		m.cells = append(m.cells, unsafePointer{cell: p.cell, t: p.t})
+		id = uintptr(len(m.cells))
+		m.ids[p.cell] = id
+	}
+	return id*addrStride + uintptr(p.off)
+}
+
+// pointer returns the pointer whose synthetic address is addr.
+func (m *memory) pointer(addr uintptr) unsafePointer {
+	id := addr / addrStride
+	if id == 0 {
+		return unsafePointer{off: int64(addr)} // not a pointer, e.g. nil
+	}
+	if id > uintptr(len(m.cells)) {
+		panic(fmt.Sprintf("unsafe.Pointer(%#x): not the address of a variable", addr))
+	}
+	p := m.cells[id-1]
+	p.off = int64(addr % addrStride)
+	return p
+}
+
+// convUnsafe converts the value x of type t_src to type t_dst if either
+// is unsafe.Pointer, and reports whether it did so.
+func (i *interpreter) convUnsafe(t_dst, t_src types.Type, x value) (value, bool) {
+	dst, dstIsPtr := t_dst.Underlying().(*types.Pointer)
+	src, srcIsPtr := t_src.Underlying().(*types.Pointer)
+	switch {
+	case isUnsafePointer(t_dst) && srcIsPtr:
+		// *T -> unsafe.Pointer
+		if x.(*value) == nil {
+			return unsafePointer{}, true
+		}
+		return unsafePointer{cell: x.(*value), t: src.Elem()}, true
+
+	case isUnsafePointer(t_src) && dstIsPtr:
+		// unsafe.Pointer -> *T
+		return i.derefUnsafe(x.(unsafePointer), dst.Elem()), true
+
+	case isUnsafePointer(t_src) && isUnsafePointer(t_dst):
+		return x, true
+
+	case isUnsafePointer(t_src):
+		// unsafe.Pointer -> uintptr
+		return i.mem.uintptr(x.(unsafePointer)), true
+
+	case isUnsafePointer(t_dst):
+		// uintptr -> unsafe.Pointer
+		return i.mem.pointer(x.(uintptr)), true
+	}
+	return nil, false
+}
+
+func isUnsafePointer(t types.Type) bool {
+	b, ok := t.Underlying().(*types.Basic)
+	return ok && b.Kind() == types.UnsafePointer
+}
+
+// derefUnsafe returns the pointer of type *T to which p points.
+func (i *interpreter) derefUnsafe(p unsafePointer, T types.Type) *value {
+	if p.cell == nil {
+		if p.off != 0 {
+			panic(fmt.Sprintf("unsafe.Pointer(%#x): not the address of a variable", p.off))
+		}
+		return nil
+	}
+	size := i.sizes.Sizeof(T)
+
+	// Descend to the innermost cell that contains the bytes of T.
+	cell, t, off := p.cell, p.t, p.off
+	for !(off == 0 && sameLayout(t, T)) {
+		switch u := t.Underlying().(type) {
+		case *types.Struct:
+			fields := make([]*types.Var, u.NumFields())
+			for j := range fields {
+				fields[j] = u.Field(j)
+			}
+			offsets := i.sizes.Offsetsof(fields)
+			found := false
+			for j, f := range fields {
+				if offsets[j] <= off && off+size <= offsets[j]+i.sizes.Sizeof(f.Type()) {
+					cell = &(*cell).(structure)[j]
+					t = f.Type()
+					off -= offsets[j]
+					found = true
+					break
+				}
+			}
+			if found {
+				continue
+			}
+
+		case *types.Array:
+			if es := i.sizes.Sizeof(u.Elem()); es > 0 {
+				k := off / es
+				if k < u.Len() && off+size <= (k+1)*es {
+					cell = &(*cell).(array)[k]
+					t = u.Elem()
+					off -= k * es
+					continue
+				}
+			}
+		}
+
+		// No cell has the layout of T: view the bytes.
+		if !isScalar(T) || off < 0 || off+size > i.sizes.Sizeof(t) || !isScalarRegion(t) {
+			panic(fmt.Sprintf("unsafe.Pointer to byte %d of %s cannot be converted to *%s", p.off, p.t, T))
+		}
+		var v value = &view{sizes: i.sizes, cell: cell, t: t, off: off, T: T}
+		return &v
+	}
+	return cell
+}
+
+// sameLayout reports whether variables of types x and y have the same
+// representation as cells.
+func sameLayout(x, y types.Type) bool {
+	if types.Identical(x.Underlying(), y.Underlying()) {
+		return true
+	}
+	switch x := x.Underlying().(type) {
+	case *types.Struct:
+		y, ok := y.Underlying().(*types.Struct)
+		if !ok || x.NumFields() != y.NumFields() {
+			return false
+		}
+		for i := 0; i < x.NumFields(); i++ {
+			if !sameLayout(x.Field(i).Type(), y.Field(i).Type()) {
+				return false
+			}
+		}
+		return true
+	case *types.Array:
+		y, ok := y.Underlying().(*types.Array)
+		return ok && x.Len() == y.Len() && sameLayout(x.Elem(), y.Elem())
+	case *types.Pointer:
+		_, ok := y.Underlying().(*types.Pointer)
+		return ok // all pointers are *value
+	}
+	return false
+}
+
+// isScalar reports whether t is a boolean or numeric type.
+func isScalar(t types.Type) bool {
+	b, ok := t.Underlying().(*types.Basic)
+	return ok && b.Info()&(types.IsBoolean|types.IsNumeric) != 0
+}
+
+// isScalarRegion reports whether variables of type t consist only of
+// scalars.
+func isScalarRegion(t types.Type) bool {
+	switch u := t.Underlying().(type) {
+	case *types.Struct:
+		for i := 0; i < u.NumFields(); i++ {
+			if !isScalarRegion(u.Field(i).Type()) {
+				return false
+			}
+		}
+		return true
+	case *types.Array:
+		return isScalarRegion(u.Elem())
+	}
+	return isScalar(t)
+}
+
+func (v *view) load() value {
+	buf := make([]byte, v.sizes.Sizeof(v.t))
+	encode(v.sizes, v.t, *v.cell, buf)
+	return decode(v.sizes, v.T, buf[v.off:])
+}
+
+func (v *view) store(x value) {
+	buf := make([]byte, v.sizes.Sizeof(v.t))
+	encode(v.sizes, v.t, *v.cell, buf)
+	encode(v.sizes, v.T, x, buf[v.off:])
+	patch(v.sizes, v.t, v.cell, buf)
+}
+
+// encode writes the bytes of the scalar region x of type t to buf.
+func encode(sizes types.Sizes, t types.Type, x value, buf []byte) {
+	switch u := t.Underlying().(type) {
+	case *types.Struct:
+		fields, offsets := structLayout(sizes, u)
+		for i := range fields {
+			encode(sizes, fields[i].Type(), x.(structure)[i], buf[offsets[i]:])
+		}
+	case *types.Array:
+		es := sizes.Sizeof(u.Elem())
+		for i, e := range x.(array) {
+			encode(sizes, u.Elem(), e, buf[int64(i)*es:])
+		}
+	default:
+		var bits uint64
+		switch x := x.(type) {
+		case bool:
+			if x {
+				bits = 1
+			}
+		case float32:
+			bits = uint64(math.Float32bits(x))
+		case float64:
+			bits = math.Float64bits(x)
+		case complex64:
+			encode(sizes, types.Typ[types.Float32], real(x), buf)
+			encode(sizes, types.Typ[types.Float32], imag(x), buf[4:])
+			return
+		case complex128:
+			encode(sizes, types.Typ[types.Float64], real(x), buf)
+			encode(sizes, types.Typ[types.Float64], imag(x), buf[8:])
+			return
+		default:
+			bits = widen(x).(uint64)
+		}
+		switch sizes.Sizeof(t) {
+		case 1:
+			buf[0] = byte(bits)
+		case 2:
+			binary.NativeEndian.PutUint16(buf, uint16(bits))
+		case 4:
+			binary.NativeEndian.PutUint32(buf, uint32(bits))
+		case 8:
+			binary.NativeEndian.PutUint64(buf, bits)
+		}
+	}
+}
+
+// decode returns the scalar region of type t encoded in buf.
+func decode(sizes types.Sizes, t types.Type, buf []byte) value {
+	switch u := t.Underlying().(type) {
+	case *types.Struct:
+		fields, offsets := structLayout(sizes, u)
+		s := make(structure, len(fields))
+		for i := range fields {
+			s[i] = decode(sizes, fields[i].Type(), buf[offsets[i]:])
+		}
+		return s
+	case *types.Array:
+		es := sizes.Sizeof(u.Elem())
+		a := make(array, u.Len())
+		for i := range a {
+			a[i] = decode(sizes, u.Elem(), buf[int64(i)*es:])
+		}
+		return a
+	}
+	var bits uint64
+	switch sizes.Sizeof(t) {
+	case 1:
+		bits = uint64(buf[0])
+	case 2:
+		bits = uint64(binary.NativeEndian.Uint16(buf))
+	case 4:
+		bits = uint64(binary.NativeEndian.Uint32(buf))
+	case 8:
+		bits = binary.NativeEndian.Uint64(buf)
+	}
+	switch t.Underlying().(*types.Basic).Kind() {
+	case types.Bool:
+		return bits != 0
+	case types.Float32:
+		return math.Float32frombits(uint32(bits))
+	case types.Float64:
+		return math.Float64frombits(bits)
+	case types.Complex64:
+		f := types.Typ[types.Float32]
+		return complex(decode(sizes, f, buf).(float32), decode(sizes, f, buf[4:]).(float32))
+	case types.Complex128:
+		f := types.Typ[types.Float64]
+		return complex(decode(sizes, f, buf).(float64), decode(sizes, f, buf[8:]).(float64))
+	}
+	return conv(t, types.Typ[types.Uint64], bits)
+}
+
+// patch updates, in place, the cells of the scalar region *cell of
+// type t from the bytes of buf.
+func patch(sizes types.Sizes, t types.Type, cell *value, buf []byte) {
+	switch u := t.Underlying().(type) {
+	case *types.Struct:
+		fields, offsets := structLayout(sizes, u)
+		for i := range fields {
+			patch(sizes, fields[i].Type(), &(*cell).(structure)[i], buf[offsets[i]:])
+		}
+	case *types.Array:
+		es := sizes.Sizeof(u.Elem())
+		a := (*cell).(array)
+		for i := range a {
+			patch(sizes, u.Elem(), &a[i], buf[int64(i)*es:])
+		}
+	default:
+		*cell = decode(sizes, t, buf)
+	}
+}
+
+func structLayout(sizes types.Sizes, t *types.Struct) ([]*types.Var, []int64) {
+	fields := make([]*types.Var, t.NumFields())
+	for i := range fields {
+		fields[i] = t.Field(i)
+	}
+	return fields, sizes.Offsetsof(fields)
+}
+
+// -- unsafe built-ins --
+
+// sliceData returns a pointer to the first element of the backing
+// array of slice s, recording the array so that unsafe.Slice and
+// unsafe.String can recover it.
+func (m *memory) sliceData(s []value) *value {
+	if cap(s) == 0 {
+		if s == nil {
+			return nil
+		}
+		var v value = bad{} // unusable pointer to a zero-length array
+		return &v
+	}
+	p := &s[:1][0]
+	if m.arrays == nil {
+		m.arrays = make(map[*value][]value)
+	}
+	m.arrays[p] = s[:cap(s)]
+	return p
+}
+
+// slice returns the slice of length n whose first element is *p.
+func (m *memory) slice(p *value, n int) []value {
+	if p == nil {
+		if n != 0 {
+			panic(runtimeError("unsafe.Slice: ptr is nil and len is not zero"))
+		}
+		return nil
+	}
+	if n == 0 {
+		return []value{}
+	}
+	a, ok := m.arrays[p]
+	if !ok {
+		// A slice cannot alias a variable that is not an element of
+		// a slice's array, as cells are not contiguous.
+		panic("unsafe.Slice of a pointer not obtained from unsafe.SliceData or unsafe.StringData")
+	}
+	if n > len(a) {
+		panic(runtimeError("unsafe.Slice: len out of range"))
+	}
+	return a[:n:n]
+}
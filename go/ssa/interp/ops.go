@@ -221,7 +221,7 @@ func zero(t types.Type) value {
 		case types.String:
 			return ""
 		case types.UnsafePointer:
-			return unsafe.Pointer(nil)
+			return unsafePointer{}
 		default:
 			panic(fmt.Sprint("zero for unexpected type:", t))
 		}
@@ -257,7 +257,7 @@ func zero(t types.Type) value {
 		}
 		return s
 	case *types.Chan:
-		return chan value(nil)
+		return (*channel)(nil)
 	case *types.Map:
 		if usesBuiltinMap(t.Key()) {
 			return map[value]value(nil)
@@ -841,15 +841,6 @@ func eqnil(t types.Type, x, y value) bool {
 
 func unop(instr *ssa.UnOp, x value) value {
 	switch instr.Op {
-	case token.ARROW: // receive
-		v, ok := <-x.(chan value)
-		if !ok {
-			v = zero(instr.X.Type().Underlying().(*types.Chan).Elem())
-		}
-		if instr.CommaOk {
-			v = tuple{v, ok}
-		}
-		return v
 	case token.SUB:
 		switch x := x.(type) {
 		case int:
@@ -1000,7 +991,7 @@ func callBuiltin(caller *frame, callpos token.Pos, fn *ssa.Builtin, args []value
 		return copy(args[0].([]value), src.([]value))
 
 	case "close": // close(chan T)
-		close(args[0].(chan value))
+		caller.i.sched.close(args[0].(*channel))
 		return nil
 
 	case "delete": // delete(map[K]value, K)
@@ -1043,8 +1034,8 @@ func callBuiltin(caller *frame, callpos token.Pos, fn *ssa.Builtin, args []value
 			return len(x)
 		case *hashmap:
 			return x.len()
-		case chan value:
-			return len(x)
+		case *channel:
+			return x.len()
 		default:
 			panic(fmt.Sprintf("len: illegal operand: %T", x))
 		}
@@ -1057,8 +1048,8 @@ func callBuiltin(caller *frame, callpos token.Pos, fn *ssa.Builtin, args []value
 			return cap((*x).(array))
 		case []value:
 			return cap(x)
-		case chan value:
-			return cap(x)
+		case *channel:
+			return x.capacity()
 		default:
 			panic(fmt.Sprintf("cap: illegal operand: %T", x))
 		}
@@ -1106,6 +1097,36 @@ func callBuiltin(caller *frame, callpos token.Pos, fn *ssa.Builtin, args []value
 	case "recover":
 		return doRecover(caller)
 
+	case "Add": // unsafe.Add(unsafe.Pointer, int) unsafe.Pointer
+		p := args[0].(unsafePointer)
+		p.off += asInt64(args[1])
+		return p
+
+	case "Slice": // unsafe.Slice(*T, int) []T
+		return caller.i.mem.slice(args[0].(*value), int(asInt64(args[1])))
+
+	case "SliceData": // unsafe.SliceData([]T) *T
+		return caller.i.mem.sliceData(args[0].([]value))
+
+	case "String": // unsafe.String(*byte, int) string
+		s := caller.i.mem.slice(args[0].(*value), int(asInt64(args[1])))
+		b := make([]byte, len(s))
+		for i, v := range s {
+			b[i] = v.(byte)
+		}
+		return string(b)
+
+	case "StringData": // unsafe.StringData(string) *byte
+		s := args[0].(string)
+		if s == "" {
+			return (*value)(nil)
+		}
+		b := make([]value, len(s))
+		for i := range b {
+			b[i] = s[i]
+		}
+		return caller.i.mem.sliceData(b)
+
 	case "ssa:wrapnilchk":
 		recv := args[0]
 		if recv.(*value) == nil {
@@ -1144,7 +1165,7 @@ func rangeIter(x value, t types.Type) iter {
 // cases we have to consider.
 func widen(x value) value {
 	switch y := x.(type) {
-	case bool, int64, uint64, float64, complex128, string, unsafe.Pointer:
+	case bool, int64, uint64, float64, complex128, string:
 		return x
 	case int:
 		return int64(y)
@@ -1206,15 +1227,6 @@ func conv(t_dst, t_src types.Type, x value) value {
 	// or string), then we convert it to the desired type.
 
 	switch ut_src := ut_src.(type) {
-	case *types.Pointer:
-		switch ut_dst := ut_dst.(type) {
-		case *types.Basic:
-			// *value to unsafe.Pointer?
-			if ut_dst.Kind() == types.UnsafePointer {
-				return unsafe.Pointer(x.(*value))
-			}
-		}
-
 	case *types.Slice:
 		// []byte or []rune -> string
 		switch ut_src.Elem().Underlying().(*types.Basic).Kind() {
@@ -1270,27 +1282,6 @@ func conv(t_dst, t_src types.Type, x value) value {
 			break // fail: no other conversions for string
 		}
 
-		// unsafe.Pointer -> *value
-		if ut_src.Kind() == types.UnsafePointer {
-			// TODO(adonovan): this is wrong and cannot
-			// really be fixed with the current design.
-			//
-			// return (*value)(x.(unsafe.Pointer))
-			// creates a new pointer of a different
-			// type but the underlying interface value
-			// knows its "true" type and so cannot be
-			// meaningfully used through the new pointer.
-			//
-			// To make this work, the interpreter needs to
-			// simulate the memory layout of a real
-			// compiled implementation.
-			//
-			// To at least preserve type-safety, we'll
-			// just return the zero value of the
-			// destination type.
-			return zero(t_dst)
-		}
-
 		// Conversions between complex numeric types?
 		if ut_src.Info()&types.IsComplex != 0 {
 			switch ut_dst.(*types.Basic).Kind() {
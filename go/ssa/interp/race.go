@@ -0,0 +1,189 @@
+// Copyright 2024 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package interp
+
+// Data race detection.
+//
+// The detector records, for each variable, the last write and the
+// reads since, and reports accesses by different goroutines that are
+// not ordered by happens-before. Variables are the cells of memory
+// loaded and stored by SSA Load (*x) and Store instructions: each
+// global, each heap-allocated variable, and each field and element of
+// one. (A load of a whole struct does not conflict with a store to one
+// of its fields.)
+//
+// Happens-before is tracked by vector clocks. Every operation on a
+// channel, a sync object or an atomic variable both acquires and
+// releases the clock of that object. This orders more events than the
+// Go memory model does, so some races go unreported, but those that are
+// reported are real.
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+
+	"golang.org/x/tools/go/ssa"
+)
+
+// A vclock is a vector clock, indexed by goroutine id.
+type vclock []int
+
+func (c vclock) get(id int) int {
+	if id < len(c) {
+		return c[id]
+	}
+	return 0
+}
+
+// tick advances the component id of *c.
+func (c *vclock) tick(id int) {
+	for len(*c) <= id {
+		*c = append(*c, 0)
+	}
+	(*c)[id]++
+}
+
+// join sets *c to the component-wise maximum of *c and d.
+func (c *vclock) join(d vclock) {
+	for len(*c) < len(d) {
+		*c = append(*c, 0)
+	}
+	for i, t := range d {
+		if t > (*c)[i] {
+			(*c)[i] = t
+		}
+	}
+}
+
+func (c vclock) copy() vclock {
+	return append(vclock(nil), c...)
+}
+
+// A raceDetector holds the state of the data race detector.
+type raceDetector struct {
+	clocks   map[any]vclock     // clocks of synchronizing objects
+	vars     map[*value]*shadow // access histories of variables
+	reported map[[2]ssa.Instruction]bool
+	count    int // number of races reported
+}
+
+// A shadow is the access history of a variable.
+type shadow struct {
+	write access   // last write, if write.g != 0
+	reads []access // reads since the last write, one per goroutine
+}
+
+// An access is a load or store of a variable by goroutine g at time
+// clock of g.
+type access struct {
+	g     int
+	clock int
+	fn    *ssa.Function
+	instr ssa.Instruction
+}
+
+func newRaceDetector() *raceDetector {
+	return &raceDetector{
+		clocks:   make(map[any]vclock),
+		vars:     make(map[*value]*shadow),
+		reported: make(map[[2]ssa.Instruction]bool),
+	}
+}
+
+// syncWith records a synchronizing operation of the running goroutine
+// on the object identified by key: a channel, or the address of a sync
+// object or atomic variable.
+func (s *scheduler) syncWith(key any) {
+	if s.races == nil {
+		return
+	}
+	g := s.cur
+	c := s.races.clocks[key]
+	c.join(g.clock)
+	s.races.clocks[key] = c
+	g.clock.join(c)
+	g.clock.tick(g.id)
+}
+
+// access records a load (or store, if write) of the variable at addr
+// by the running goroutine, reporting any race.
+func (s *scheduler) access(fr *frame, addr *value, write bool) {
+	if addr == nil {
+		return // the load or store will panic
+	}
+	r := s.races
+	g := s.cur
+	v := r.vars[addr]
+	if v == nil {
+		v = new(shadow)
+		r.vars[addr] = v
+	}
+	cur := access{g: g.id, clock: g.clock.get(g.id), fn: fr.fn, instr: fr.instr}
+
+	// racy reports whether prev is concurrent with cur.
+	racy := func(prev access) bool {
+		return prev.g != 0 && prev.g != g.id && prev.clock > g.clock.get(prev.g)
+	}
+	if racy(v.write) {
+		s.reportRace(fr, write, v.write, true)
+	}
+	if write {
+		for _, prev := range v.reads {
+			if racy(prev) {
+				s.reportRace(fr, write, prev, false)
+			}
+		}
+		v.write = cur
+		v.reads = v.reads[:0]
+		return
+	}
+	for i, prev := range v.reads {
+		if prev.g == g.id {
+			v.reads[i] = cur
+			return
+		}
+	}
+	v.reads = append(v.reads, cur)
+}
+
+// reportRace reports a race between the current instruction of fr and
+// the earlier access prev, in the style of the Go race detector.
+func (s *scheduler) reportRace(fr *frame, write bool, prev access, prevWrite bool) {
+	r := s.races
+	key := [2]ssa.Instruction{fr.instr, prev.instr}
+	if r.reported[key] {
+		return
+	}
+	r.reported[key] = true
+	r.count++
+
+	op, prevOp := "Read", "read"
+	if write {
+		op = "Write"
+	}
+	if prevWrite {
+		prevOp = "write"
+	}
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "==================\nWARNING: DATA RACE\n")
+	fmt.Fprintf(&buf, "%s by goroutine %d:\n", op, s.cur.id)
+	for f := fr; f != nil; f = f.caller {
+		writeFrame(&buf, f.fn, f.instr)
+	}
+	fmt.Fprintf(&buf, "\nPrevious %s by goroutine %d:\n", prevOp, prev.g)
+	writeFrame(&buf, prev.fn, prev.instr)
+	fmt.Fprintf(&buf, "==================\n")
+	os.Stderr.Write(buf.Bytes())
+}
+
+// writeFrame writes a line of a stack for a call to fn that is
+// executing instr, which may be nil.
+func writeFrame(buf *bytes.Buffer, fn *ssa.Function, instr ssa.Instruction) {
+	fmt.Fprintf(buf, "  %s(...)\n", fn)
+	if instr != nil {
+		fmt.Fprintf(buf, "      %s\n", fn.Prog.Fset.Position(instr.Pos()))
+	}
+}
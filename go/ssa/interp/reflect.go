@@ -49,20 +49,54 @@ func makeNamedType(name string, underlying types.Type) *types.Named {
 	return types.NewNamed(obj, underlying, nil)
 }
 
+// A reflect.Value is a structure{rtype, v, addr, ro}: its type, and
+// either its value v, or, if it is addressable, the address addr of
+// the variable holding it. ro is set if it was obtained through an
+// unexported field, and so cannot be set.
+
 func makeReflectValue(t types.Type, v value) value {
-	return structure{rtype{t}, v}
+	return structure{rtype{t}, v, (*value)(nil), false}
+}
+
+// makeAddrReflectValue returns the addressable reflect.Value of type t
+// for the variable at addr.
+func makeAddrReflectValue(t types.Type, addr *value, ro bool) value {
+	return structure{rtype{t}, nil, addr, ro}
 }
 
 // Given a reflect.Value, returns its rtype.
 func rV2T(v value) rtype {
-	return v.(structure)[0].(rtype)
+	rt, _ := v.(structure)[0].(rtype) // zero for the zero Value
+	return rt
 }
 
 // Given a reflect.Value, returns the underlying interpreter value.
 func rV2V(v value) value {
+	if addr := rV2A(v); addr != nil {
+		if view, ok := (*addr).(*view); ok {
+			return view.load()
+		}
+		return *addr
+	}
+	if rV2T(v).t == nil {
+		return nil // the zero Value
+	}
 	return v.(structure)[1]
 }
 
+// Given a reflect.Value, returns the address of its variable,
+// or nil if it is not addressable.
+func rV2A(v value) *value {
+	addr, _ := v.(structure)[2].(*value) // nil for the zero Value
+	return addr
+}
+
+// Given a reflect.Value, reports whether it was obtained through an
+// unexported field.
+func rV2RO(v value) bool {
+	return v.(structure)[3].(bool)
+}
+
 // makeReflectType boxes up an rtype in a reflect.Type interface.
 func makeReflectType(rt rtype) value {
 	return iface{rtypeType, rt}
@@ -90,13 +124,17 @@ func ext۰reflect۰rtype۰Field(fr *frame, args []value) value {
 	st := args[0].(rtype).t.Underlying().(*types.Struct)
 	i := args[1].(int)
 	f := st.Field(i)
+	var pkgPath string
+	if !f.Exported() {
+		pkgPath = f.Pkg().Path()
+	}
 	return structure{
 		f.Name(),
-		f.Pkg().Path(),
+		pkgPath,
 		makeReflectType(rtype{f.Type()}),
 		st.Tag(i),
-		0,         // TODO(adonovan): offset
-		[]value{}, // TODO(adonovan): indices
+		uintptr(0), // TODO(adonovan): offset
+		[]value{i},
 		f.Anonymous(),
 	}
 }
@@ -148,6 +186,27 @@ func ext۰reflect۰rtype۰String(fr *frame, args []value) value {
 	return args[0].(rtype).t.String()
 }
 
+func ext۰reflect۰Append(fr *frame, args []value) value {
+	// Signature: func (s reflect.Value, x ...reflect.Value) reflect.Value
+	s := args[0]
+	t := rV2T(s).t
+	elemT := t.Underlying().(*types.Slice).Elem()
+	elems := rV2V(s).([]value)
+	for _, x := range args[1].([]value) {
+		if !types.AssignableTo(rV2T(x).t, elemT) {
+			panic(targetString(fmt.Sprintf("reflect.Append: value of type %s is not assignable to type %s", rV2T(x).t, elemT)))
+		}
+		v := rV2V(x)
+		if types.IsInterface(elemT) && !types.IsInterface(rV2T(x).t) {
+			v = iface{rV2T(x).t, v}
+		}
+		elem := zero(elemT)
+		store(elemT, &elem, v)
+		elems = append(elems, elem)
+	}
+	return makeReflectValue(t, elems)
+}
+
 func ext۰reflect۰New(fr *frame, args []value) value {
 	// Signature: func (t reflect.Type) reflect.Value
 	t := args[0].(iface).v.(rtype).t
@@ -179,6 +238,8 @@ func ext۰reflect۰Zero(fr *frame, args []value) value {
 
 func reflectKind(t types.Type) reflect.Kind {
 	switch t := t.(type) {
+	case nil:
+		return reflect.Invalid
 	case *types.Named, *types.Alias:
 		return reflectKind(t.Underlying())
 	case *types.Basic:
@@ -281,8 +342,8 @@ func ext۰reflect۰Value۰Len(fr *frame, args []value) value {
 		return len(v)
 	case array:
 		return len(v)
-	case chan value:
-		return cap(v)
+	case *channel:
+		return v.len()
 	case []value:
 		return len(v)
 	case *hashmap:
@@ -296,7 +357,7 @@ func ext۰reflect۰Value۰Len(fr *frame, args []value) value {
 
 func ext۰reflect۰Value۰MapIndex(fr *frame, args []value) value {
 	// Signature: func (reflect.Value) Value
-	tValue := rV2T(args[0]).t.Underlying().(*types.Map).Key()
+	tValue := rV2T(args[0]).t.Underlying().(*types.Map).Elem()
 	k := rV2V(args[1])
 	switch m := rV2V(args[0]).(type) {
 	case map[value]value:
@@ -353,8 +414,10 @@ func ext۰reflect۰Value۰Pointer(fr *frame, args []value) value {
 	switch v := rV2V(args[0]).(type) {
 	case *value:
 		return uintptr(unsafe.Pointer(v))
-	case chan value:
-		return reflect.ValueOf(v).Pointer()
+	case unsafePointer:
+		return fr.i.mem.uintptr(v)
+	case *channel:
+		return uintptr(unsafe.Pointer(v))
 	case []value:
 		return reflect.ValueOf(v).Pointer()
 	case *hashmap:
@@ -376,9 +439,13 @@ func ext۰reflect۰Value۰Index(fr *frame, args []value) value {
 	t := rV2T(args[0]).t.Underlying()
 	switch v := rV2V(args[0]).(type) {
 	case array:
+		if addr := rV2A(args[0]); addr != nil {
+			return makeAddrReflectValue(t.(*types.Array).Elem(), &v[i], rV2RO(args[0]))
+		}
 		return makeReflectValue(t.(*types.Array).Elem(), v[i])
 	case []value:
-		return makeReflectValue(t.(*types.Slice).Elem(), v[i])
+		// Slice elements are always addressable.
+		return makeAddrReflectValue(t.(*types.Slice).Elem(), &v[i], rV2RO(args[0]))
 	default:
 		panic(fmt.Sprintf("reflect.(Value).Index(%T)", v))
 	}
@@ -391,8 +458,21 @@ func ext۰reflect۰Value۰Bool(fr *frame, args []value) value {
 
 func ext۰reflect۰Value۰CanAddr(fr *frame, args []value) value {
 	// Signature: func (v reflect.Value) bool
-	// Always false for our representation.
-	return false
+	return rV2A(args[0]) != nil
+}
+
+func ext۰reflect۰Value۰CanSet(fr *frame, args []value) value {
+	// Signature: func (v reflect.Value) bool
+	return rV2A(args[0]) != nil && !rV2RO(args[0])
+}
+
+func ext۰reflect۰Value۰Addr(fr *frame, args []value) value {
+	// Signature: func (v reflect.Value) reflect.Value
+	addr := rV2A(args[0])
+	if addr == nil {
+		panic(targetString("reflect.Value.Addr of unaddressable value"))
+	}
+	return makeReflectValue(types.NewPointer(rV2T(args[0]).t), addr)
 }
 
 func ext۰reflect۰Value۰CanInterface(fr *frame, args []value) value {
@@ -407,11 +487,10 @@ func ext۰reflect۰Value۰Elem(fr *frame, args []value) value {
 	case iface:
 		return makeReflectValue(x.t, x.v)
 	case *value:
-		var v value
-		if x != nil {
-			v = *x
+		if x == nil {
+			return makeReflectValue(rV2T(args[0]).t.Underlying().(*types.Pointer).Elem(), nil)
 		}
-		return makeReflectValue(rV2T(args[0]).t.Underlying().(*types.Pointer).Elem(), v)
+		return makeAddrReflectValue(rV2T(args[0]).t.Underlying().(*types.Pointer).Elem(), x, rV2RO(args[0]))
 	default:
 		panic(fmt.Sprintf("reflect.(Value).Elem(%T)", x))
 	}
@@ -421,7 +500,11 @@ func ext۰reflect۰Value۰Field(fr *frame, args []value) value {
 	// Signature: func (v reflect.Value, i int) reflect.Value
 	v := args[0]
 	i := args[1].(int)
-	return makeReflectValue(rV2T(v).t.Underlying().(*types.Struct).Field(i).Type(), rV2V(v).(structure)[i])
+	f := rV2T(v).t.Underlying().(*types.Struct).Field(i)
+	if addr := rV2A(v); addr != nil {
+		return makeAddrReflectValue(f.Type(), &(*addr).(structure)[i], rV2RO(v) || !f.Exported())
+	}
+	return makeReflectValue(f.Type(), rV2V(v).(structure)[i])
 }
 
 func ext۰reflect۰Value۰Float(fr *frame, args []value) value {
@@ -463,8 +546,10 @@ func ext۰reflect۰Value۰IsNil(fr *frame, args []value) value {
 	switch x := rV2V(args[0]).(type) {
 	case *value:
 		return x == nil
-	case chan value:
+	case *channel:
 		return x == nil
+	case unsafePointer:
+		return x.cell == nil && x.off == 0
 	case map[value]value:
 		return x == nil
 	case *hashmap:
@@ -490,14 +575,81 @@ func ext۰reflect۰Value۰IsValid(fr *frame, args []value) value {
 }
 
 func ext۰reflect۰Value۰Set(fr *frame, args []value) value {
-	// TODO(adonovan): implement.
+	// Signature: func (v reflect.Value, x reflect.Value)
+	setReflectValue(args[0], "Set", func(t types.Type) value {
+		x := args[1]
+		if !types.AssignableTo(rV2T(x).t, t) {
+			panic(targetString(fmt.Sprintf("reflect.Set: value of type %s is not assignable to type %s", rV2T(x).t, t)))
+		}
+		if _, ok := t.Underlying().(*types.Interface); ok {
+			if _, ok := rV2T(x).t.Underlying().(*types.Interface); !ok {
+				return iface{rV2T(x).t, rV2V(x)}
+			}
+		}
+		return rV2V(x)
+	})
 	return nil
 }
 
+func ext۰reflect۰Value۰SetBool(fr *frame, args []value) value {
+	// Signature: func (v reflect.Value, x bool)
+	setReflectValue(args[0], "SetBool", func(t types.Type) value { return args[1] })
+	return nil
+}
+
+func ext۰reflect۰Value۰SetFloat(fr *frame, args []value) value {
+	// Signature: func (v reflect.Value, x float64)
+	setReflectValue(args[0], "SetFloat", func(t types.Type) value {
+		return conv(t, types.Typ[types.Float64], args[1])
+	})
+	return nil
+}
+
+func ext۰reflect۰Value۰SetInt(fr *frame, args []value) value {
+	// Signature: func (v reflect.Value, x int64)
+	setReflectValue(args[0], "SetInt", func(t types.Type) value {
+		return conv(t, types.Typ[types.Int64], args[1])
+	})
+	return nil
+}
+
+func ext۰reflect۰Value۰SetString(fr *frame, args []value) value {
+	// Signature: func (v reflect.Value, x string)
+	setReflectValue(args[0], "SetString", func(t types.Type) value { return args[1] })
+	return nil
+}
+
+func ext۰reflect۰Value۰SetUint(fr *frame, args []value) value {
+	// Signature: func (v reflect.Value, x uint64)
+	setReflectValue(args[0], "SetUint", func(t types.Type) value {
+		return conv(t, types.Typ[types.Uint64], args[1])
+	})
+	return nil
+}
+
+// setReflectValue stores into the variable of the reflect.Value v
+// the value returned by x for its type, or panics if v is not
+// settable.
+func setReflectValue(v value, method string, x func(t types.Type) value) {
+	addr := rV2A(v)
+	if addr == nil {
+		panic(targetString("reflect: reflect.Value." + method + " using unaddressable value"))
+	}
+	if rV2RO(v) {
+		panic(targetString("reflect: reflect.Value." + method + " using value obtained using unexported field"))
+	}
+	t := rV2T(v).t
+	store(t, addr, x(t))
+}
+
 func ext۰reflect۰valueInterface(fr *frame, args []value) value {
 	// Signature: func (v reflect.Value, safe bool) interface{}
 	v := args[0].(structure)
-	return iface{rV2T(v).t, rV2V(v)}
+	t := rV2T(v).t
+	if addr := rV2A(v); addr != nil {
+		return iface{t, load(t, addr)} // a copy
+	}
+	return iface{t, rV2V(v)}
 }
 
 func ext۰reflect۰error۰Error(fr *frame, args []value) value {
@@ -550,6 +702,8 @@ func initReflect(i *interpreter) {
 		rV.SetUnderlying(types.NewStruct([]*types.Var{
 			types.NewField(token.NoPos, r.Pkg, "t", tEface, false), // a lie
 			types.NewField(token.NoPos, r.Pkg, "v", tEface, false),
+			types.NewField(token.NoPos, r.Pkg, "addr", tEface, false), // a lie
+			types.NewField(token.NoPos, r.Pkg, "ro", types.Typ[types.Bool], false),
 		}, nil))
 	}
 
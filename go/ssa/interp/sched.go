@@ -0,0 +1,612 @@
+// Copyright 2024 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package interp
+
+// Goroutines and channels.
+//
+// Each goroutine of the target program runs on its own goroutine of
+// the interpreter, but only one of them runs at a time: the one that
+// holds the baton, s.cur. A goroutine gives up the baton when it
+// blocks (on a channel, a select, a mutex, etc), when it exits, when
+// it calls runtime.Gosched or time.Sleep, and after executing a
+// fixed quantum of instructions. The scheduler then passes the baton
+// to the goroutine at the head of its run queue. So, given the same
+// inputs, a program always follows the same schedule, and operations
+// that are not atomic in the interpreter (such as sync/atomic) are
+// atomic in the target program.
+//
+// Channels are implemented by the interpreter, not by Go channels,
+// so that the scheduler knows which goroutines are blocked. When all
+// goroutines are blocked, the program has deadlocked: the interpreter
+// reports the stacks of all goroutines and exits.
+//
+// A nonzero seed selects a randomized schedule instead: the
+// scheduler preempts goroutines after a random, and much smaller,
+// number of instructions, and passes the baton to a runnable goroutine
+// chosen at random. The schedule is reproducible for a given seed, so
+// that running a program under many seeds explores its interleavings,
+// and any failure found can be replayed.
+//
+// Time is virtual: time.Sleep suspends a goroutine until all others
+// are blocked or asleep, at which point the clock advances to the
+// earliest wake-up time.
+
+import (
+	"bytes"
+	"fmt"
+	"go/types"
+	"math/rand"
+	"sort"
+)
+
+// quantum is the number of instructions a goroutine executes before
+// it yields to other runnable goroutines.
+const quantum = 1000
+
+// randomQuantum is the mean quantum of a randomized schedule.
+const randomQuantum = 10
+
+// A goroutine is a goroutine of the target program.
+type goroutine struct {
+	id     int
+	status string        // "running", "runnable", or the reason it is blocked
+	wake   chan struct{} // receives the baton
+	frame  *frame        // innermost frame, while blocked
+	until  int64         // wake-up time, while asleep
+	clock  vclock        // vector clock, for race detection
+}
+
+// A scheduler holds the state of the goroutines of an interpreter.
+type scheduler struct {
+	main     *goroutine
+	cur      *goroutine            // the running goroutine
+	live     map[*goroutine]bool   // goroutines that have not exited
+	runq     []*goroutine          // runnable goroutines, in order
+	sleeping []*goroutine          // goroutines in time.Sleep
+	nextID   int                   // id of the next goroutine
+	budget   int                   // instructions until the next yield
+	now      int64                 // virtual time, in nanoseconds
+	rand     *rand.Rand            // source of random choices
+	random   bool                  // randomize the schedule
+	races    *raceDetector         // race detector, if enabled
+	fatal    *fatalError           // pending fatal error, if any
+	syncs    map[*value]syncObject // states of sync objects, by address
+}
+
+// A fatalError is an unrecoverable error of the target program, such
+// as a deadlock or a panic that was not recovered by a goroutine
+// other than the main one. The main goroutine panics with it.
+type fatalError struct {
+	msg    string // e.g. "fatal error: all goroutines are asleep - deadlock!"
+	stacks string // stacks of the goroutines
+}
+
+// goexitPanic is the panic value by which runtime.Goexit unwinds a goroutine.
+type goexitPanic struct{}
+
+// A plainError is a runtime error without the "runtime error: " prefix,
+// such as the panic of a send on a closed channel.
+type plainError string
+
+func (e plainError) Error() string { return string(e) }
+func (e plainError) RuntimeError() {}
+
+// A runtimeError is a run-time panic detected by the interpreter.
+type runtimeError string
+
+func (e runtimeError) Error() string { return "runtime error: " + string(e) }
+func (e runtimeError) RuntimeError() {}
+
+// newScheduler returns a scheduler whose schedule is randomized by
+// seed, if nonzero. If detectRaces is set, it reports data races.
+func newScheduler(seed int64, detectRaces bool) *scheduler {
+	main := &goroutine{id: 1, status: "running", wake: make(chan struct{}, 1)}
+	s := &scheduler{
+		main:   main,
+		cur:    main,
+		live:   map[*goroutine]bool{main: true},
+		nextID: 2,
+		rand:   rand.New(rand.NewSource(1)),
+		syncs:  make(map[*value]syncObject),
+	}
+	if seed != 0 {
+		s.rand = rand.New(rand.NewSource(seed))
+		s.random = true
+	}
+	if detectRaces {
+		s.races = newRaceDetector()
+		main.clock = vclock{1: 1}
+	}
+	s.budget = s.quantum()
+	return s
+}
+
+// quantum returns the number of instructions that the running
+// goroutine may execute before it yields.
+func (s *scheduler) quantum() int {
+	if s.random {
+		return 1 + s.rand.Intn(2*randomQuantum)
+	}
+	return quantum
+}
+
+// spawn creates a goroutine that calls f, and makes it runnable.
+func (s *scheduler) spawn(f func()) {
+	g := &goroutine{id: s.nextID, status: "runnable", wake: make(chan struct{}, 1)}
+	s.nextID++
+	if s.races != nil {
+		// The go statement happens before the goroutine starts.
+		g.clock = s.cur.clock.copy()
+		g.clock.tick(g.id)
+		s.cur.clock.tick(s.cur.id)
+	}
+	s.live[g] = true
+	s.runq = append(s.runq, g)
+	go func() {
+		s.wait(g)
+		func() {
+			defer func() {
+				if p := recover(); p != nil {
+					s.crash(g, p)
+				}
+			}()
+			f()
+		}()
+		s.exit(g)
+	}()
+}
+
+// crash records the panic p of goroutine g as a fatal error.
+func (s *scheduler) crash(g *goroutine, p any) {
+	switch p := p.(type) {
+	case goexitPanic:
+		return
+	case *fatalError:
+		s.fatal = p
+		return
+	}
+	s.fatal = &fatalError{
+		msg:    "panic: " + panicString(p),
+		stacks: s.stacks(g),
+	}
+}
+
+// exit is called when the running goroutine g exits.
+func (s *scheduler) exit(g *goroutine) {
+	delete(s.live, g)
+	g.status = "exited"
+	if s.fatal == nil {
+		if next := s.next(); next != nil {
+			s.run(next)
+			return
+		}
+		s.deadlock()
+	}
+	s.run(s.main)
+}
+
+// tick is called before each instruction, and yields once the
+// running goroutine has used up its quantum.
+func (s *scheduler) tick(fr *frame) {
+	if s.budget--; s.budget <= 0 {
+		s.yield(fr)
+	}
+}
+
+// yield lets other runnable goroutines run.
+func (s *scheduler) yield(fr *frame) {
+	s.budget = s.quantum()
+	if len(s.runq) == 0 && len(s.sleeping) > 0 {
+		s.wakeSleepers()
+	}
+	if len(s.runq) == 0 {
+		return
+	}
+	g := s.cur
+	g.status = "runnable"
+	g.frame = fr
+	s.runq = append(s.runq, g)
+	s.switchTo(g, s.next())
+}
+
+// park blocks the running goroutine for the given reason, until
+// another goroutine calls ready.
+func (s *scheduler) park(fr *frame, reason string) {
+	g := s.cur
+	g.status = reason
+	g.frame = fr
+	next := s.next()
+	if next == nil {
+		s.deadlock()
+		if g == s.main {
+			panic(s.fatal)
+		}
+		next = s.main
+	}
+	s.switchTo(g, next)
+}
+
+// ready makes a blocked goroutine runnable.
+func (s *scheduler) ready(g *goroutine) {
+	g.status = "runnable"
+	s.runq = append(s.runq, g)
+}
+
+// sleep suspends the running goroutine for d nanoseconds of virtual time.
+func (s *scheduler) sleep(fr *frame, d int64) {
+	if d <= 0 {
+		s.yield(fr)
+		return
+	}
+	g := s.cur
+	g.until = s.now + d
+	s.sleeping = append(s.sleeping, g)
+	s.park(fr, "sleep")
+}
+
+// next removes and returns the next runnable goroutine,
+// advancing the clock if all goroutines are blocked or asleep.
+// It returns nil if there is none.
+func (s *scheduler) next() *goroutine {
+	if len(s.runq) == 0 {
+		s.wakeSleepers()
+	}
+	if len(s.runq) == 0 {
+		return nil
+	}
+	i := 0
+	if s.random {
+		i = s.rand.Intn(len(s.runq))
+	}
+	g := s.runq[i]
+	s.runq = append(s.runq[:i], s.runq[i+1:]...)
+	return g
+}
+
+// wakeSleepers advances the clock to the earliest wake-up time and
+// makes the goroutines that wake up then runnable.
+func (s *scheduler) wakeSleepers() {
+	if len(s.sleeping) == 0 {
+		return
+	}
+	sort.SliceStable(s.sleeping, func(i, j int) bool {
+		return s.sleeping[i].until < s.sleeping[j].until
+	})
+	s.now = s.sleeping[0].until
+	for len(s.sleeping) > 0 && s.sleeping[0].until == s.now {
+		s.ready(s.sleeping[0])
+		s.sleeping = s.sleeping[1:]
+	}
+}
+
+// switchTo passes the baton from g to next, and waits for its return.
+func (s *scheduler) switchTo(g, next *goroutine) {
+	if next == g {
+		g.status = "running"
+		g.frame = nil
+		return
+	}
+	s.run(next)
+	s.wait(g)
+}
+
+// run passes the baton to g.
+func (s *scheduler) run(g *goroutine) {
+	s.cur = g
+	g.status = "running"
+	g.frame = nil
+	s.budget = s.quantum()
+	g.wake <- struct{}{}
+}
+
+// wait waits for g to receive the baton. The main goroutine then
+// panics with any pending fatal error.
+func (s *scheduler) wait(g *goroutine) {
+	<-g.wake
+	if g == s.main && s.fatal != nil {
+		panic(s.fatal)
+	}
+}
+
+// goexitMain is called when the main goroutine has called
+// runtime.Goexit. It runs the other goroutines until they have all
+// exited or blocked, and returns the resulting fatal error.
+func (s *scheduler) goexitMain() (p any) {
+	defer func() { p = recover() }()
+	s.park(nil, "goexit")
+	panic("unreachable")
+}
+
+// deadlock records that all goroutines are blocked.
+func (s *scheduler) deadlock() {
+	msg := "fatal error: all goroutines are asleep - deadlock!"
+	if s.main.status == "goexit" {
+		msg = "fatal error: no goroutines (main called runtime.Goexit) - deadlock!"
+	}
+	s.fatal = &fatalError{msg: msg, stacks: s.stacks(s.blocked()...)}
+}
+
+// stacks returns the stacks of goroutines gs, in the style of the Go
+// runtime.
+func (s *scheduler) stacks(gs ...*goroutine) string {
+	var buf bytes.Buffer
+	for _, g := range gs {
+		fmt.Fprintf(&buf, "\ngoroutine %d [%s]:\n", g.id, g.status)
+		for fr := g.frame; fr != nil; fr = fr.caller {
+			fmt.Fprintf(&buf, "%s(...)\n", fr.fn)
+			if fr.instr != nil {
+				fmt.Fprintf(&buf, "\t%s\n", fr.i.prog.Fset.Position(fr.instr.Pos()))
+			}
+		}
+	}
+	return buf.String()
+}
+
+// blocked returns the live goroutines, other than a main goroutine
+// that called runtime.Goexit, in order.
+func (s *scheduler) blocked() []*goroutine {
+	var gs []*goroutine
+	for g := range s.live {
+		if g.status != "goexit" {
+			gs = append(gs, g)
+		}
+	}
+	sort.Slice(gs, func(i, j int) bool { return gs[i].id < gs[j].id })
+	return gs
+}
+
+// -- channels --
+
+// A channel is the interpreter's representation of a channel.
+type channel struct {
+	cap    int
+	buf    []value
+	closed bool
+	recvq  []*waiter // blocked receivers
+	sendq  []*waiter // blocked senders
+}
+
+// len returns the number of buffered values of ch, which may be nil.
+func (ch *channel) len() int {
+	if ch == nil {
+		return 0
+	}
+	return len(ch.buf)
+}
+
+// capacity returns the capacity of ch, which may be nil.
+func (ch *channel) capacity() int {
+	if ch == nil {
+		return 0
+	}
+	return ch.cap
+}
+
+// A waiter is a goroutine blocked on a channel operation, perhaps as
+// one of the cases of a select.
+type waiter struct {
+	g      *goroutine
+	val    value      // value sent or received
+	ok     bool       // a value was received (not the close)
+	closed bool       // the channel was closed (send only)
+	sel    *selection // the select of which this is a case, if any
+	index  int        // index of the case in the select
+}
+
+// A selection records the outcome of a blocked select.
+type selection struct {
+	done   bool
+	chosen int
+}
+
+// fire reports whether w can complete, marking its select as done.
+func (w *waiter) fire() bool {
+	if w.sel != nil {
+		if w.sel.done {
+			return false // another case of the select completed
+		}
+		w.sel.done = true
+		w.sel.chosen = w.index
+	}
+	return true
+}
+
+// dequeue removes and returns the first waiter of q that can complete.
+func dequeue(q *[]*waiter) *waiter {
+	for len(*q) > 0 {
+		w := (*q)[0]
+		*q = (*q)[1:]
+		if w.fire() {
+			return w
+		}
+	}
+	return nil
+}
+
+// send sends v on ch, blocking if necessary.
+func (s *scheduler) send(fr *frame, ch *channel, v value) {
+	if ch == nil {
+		s.park(fr, "chan send (nil chan)")
+		panic("unreachable")
+	}
+	s.syncWith(ch)
+	if !s.trySend(ch, v) {
+		w := &waiter{g: s.cur, val: v}
+		ch.sendq = append(ch.sendq, w)
+		s.park(fr, "chan send")
+		s.syncWith(ch)
+		if w.closed {
+			panic(plainError("send on closed channel"))
+		}
+	}
+}
+
+// trySend sends v on ch if it can do so without blocking.
+func (s *scheduler) trySend(ch *channel, v value) bool {
+	if ch.closed {
+		panic(plainError("send on closed channel"))
+	}
+	if w := dequeue(&ch.recvq); w != nil {
+		w.val, w.ok = v, true
+		s.ready(w.g)
+		return true
+	}
+	if len(ch.buf) < ch.cap {
+		ch.buf = append(ch.buf, v)
+		return true
+	}
+	return false
+}
+
+// recv receives a value from ch, blocking if necessary.
+// ok is false if the channel is closed; v is then nil.
+func (s *scheduler) recv(fr *frame, ch *channel) (v value, ok bool) {
+	if ch == nil {
+		s.park(fr, "chan receive (nil chan)")
+		panic("unreachable")
+	}
+	s.syncWith(ch)
+	if v, ok, done := s.tryRecv(ch); done {
+		return v, ok
+	}
+	w := &waiter{g: s.cur}
+	ch.recvq = append(ch.recvq, w)
+	s.park(fr, "chan receive")
+	s.syncWith(ch)
+	return w.val, w.ok
+}
+
+// tryRecv receives a value from ch if it can do so without blocking.
+func (s *scheduler) tryRecv(ch *channel) (v value, ok, done bool) {
+	if len(ch.buf) > 0 {
+		v, ch.buf = ch.buf[0], ch.buf[1:]
+		if w := dequeue(&ch.sendq); w != nil {
+			ch.buf = append(ch.buf, w.val)
+			s.ready(w.g)
+		}
+		return v, true, true
+	}
+	if w := dequeue(&ch.sendq); w != nil {
+		s.ready(w.g)
+		return w.val, true, true
+	}
+	if ch.closed {
+		return nil, false, true
+	}
+	return nil, false, false
+}
+
+// close closes ch, waking all goroutines blocked on it.
+func (s *scheduler) close(ch *channel) {
+	if ch == nil {
+		panic(plainError("close of nil channel"))
+	}
+	if ch.closed {
+		panic(plainError("close of closed channel"))
+	}
+	s.syncWith(ch)
+	ch.closed = true
+	for w := dequeue(&ch.recvq); w != nil; w = dequeue(&ch.recvq) {
+		w.val, w.ok = nil, false
+		s.ready(w.g)
+	}
+	for w := dequeue(&ch.sendq); w != nil; w = dequeue(&ch.sendq) {
+		w.closed = true
+		s.ready(w.g)
+	}
+}
+
+// selectCase is a case of a select statement.
+type selectCase struct {
+	dir  types.ChanDir
+	ch   *channel
+	send value
+}
+
+// doSelect executes a select statement. It returns the index of the
+// chosen case, or -1 for the default case of a non-blocking select,
+// and the value received, if any.
+//
+// When several cases are ready, one is chosen uniformly at random.
+func (s *scheduler) doSelect(fr *frame, cases []selectCase, blocking bool) (chosen int, recv value, recvOk bool) {
+	var ready []int
+	for i, c := range cases {
+		if c.ch != nil {
+			s.syncWith(c.ch)
+		}
+		switch {
+		case c.ch == nil:
+		case c.dir == types.SendOnly:
+			if c.ch.closed || len(c.ch.recvq) > 0 || len(c.ch.buf) < c.ch.cap {
+				ready = append(ready, i)
+			}
+		default:
+			if c.ch.closed || len(c.ch.sendq) > 0 || len(c.ch.buf) > 0 {
+				ready = append(ready, i)
+			}
+		}
+	}
+	// A queued waiter may be a stale case of another select,
+	// so a case that appears ready may not be.
+	for len(ready) > 0 {
+		k := s.rand.Intn(len(ready))
+		i := ready[k]
+		ready = append(ready[:k], ready[k+1:]...)
+		c := cases[i]
+		if c.dir == types.SendOnly {
+			if s.trySend(c.ch, c.send) {
+				return i, nil, false
+			}
+		} else if v, ok, done := s.tryRecv(c.ch); done {
+			return i, v, ok
+		}
+	}
+	if !blocking {
+		return -1, nil, false
+	}
+	if len(cases) == 0 {
+		s.park(fr, "select (no cases)")
+		panic("unreachable")
+	}
+
+	sel := new(selection)
+	waiters := make([]*waiter, len(cases))
+	for i, c := range cases {
+		w := &waiter{g: s.cur, sel: sel, index: i}
+		waiters[i] = w
+		switch {
+		case c.ch == nil:
+		case c.dir == types.SendOnly:
+			w.val = c.send
+			c.ch.sendq = append(c.ch.sendq, w)
+		default:
+			c.ch.recvq = append(c.ch.recvq, w)
+		}
+	}
+	s.park(fr, "select")
+	s.syncWith(cases[sel.chosen].ch)
+
+	// Remove the other cases from the queues.
+	for i, c := range cases {
+		if c.ch != nil && i != sel.chosen {
+			c.ch.sendq = removeWaiter(c.ch.sendq, waiters[i])
+			c.ch.recvq = removeWaiter(c.ch.recvq, waiters[i])
+		}
+	}
+	w := waiters[sel.chosen]
+	if w.closed {
+		panic(plainError("send on closed channel"))
+	}
+	return sel.chosen, w.val, w.ok
+}
+
+func removeWaiter(q []*waiter, w *waiter) []*waiter {
+	for i, x := range q {
+		if x == w {
+			return append(q[:i:i], q[i+1:]...)
+		}
+	}
+	return q
+}
@@ -0,0 +1,452 @@
+// Copyright 2024 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package interp
+
+// Intrinsics for packages sync and sync/atomic.
+//
+// As the scheduler runs one goroutine at a time, the operations of
+// sync/atomic are implemented by ordinary loads and stores.
+//
+// The states of Mutex, RWMutex, WaitGroup and Cond objects are held by
+// the scheduler, keyed by the address of the object, not in the
+// objects themselves; goroutines that block on them are parked.
+
+import (
+	"go/types"
+)
+
+// A syncObject is the state of a sync object: a *mutex, *rwmutex,
+// *waitGroup or *cond.
+type syncObject any
+
+type mutex struct {
+	locked  bool
+	waiters []*goroutine
+}
+
+type rwmutex struct {
+	writer  bool // held by a writer
+	readers int  // number of readers holding the lock
+	wwait   []*goroutine
+	rwait   []*goroutine
+}
+
+type waitGroup struct {
+	n       int64
+	waiters []*goroutine
+}
+
+type cond struct {
+	waiters []*goroutine
+}
+
+func init() {
+	for k, v := range map[string]externalFn{
+		"(*sync.Cond).Broadcast":              ext۰sync۰Cond۰Broadcast,
+		"(*sync.Cond).Signal":                 ext۰sync۰Cond۰Signal,
+		"(*sync.Cond).Wait":                   ext۰sync۰Cond۰Wait,
+		"(*sync.Mutex).Lock":                  ext۰sync۰Mutex۰Lock,
+		"(*sync.Mutex).TryLock":               ext۰sync۰Mutex۰TryLock,
+		"(*sync.Mutex).Unlock":                ext۰sync۰Mutex۰Unlock,
+		"(*sync.RWMutex).Lock":                ext۰sync۰RWMutex۰Lock,
+		"(*sync.RWMutex).RLock":               ext۰sync۰RWMutex۰RLock,
+		"(*sync.RWMutex).RUnlock":             ext۰sync۰RWMutex۰RUnlock,
+		"(*sync.RWMutex).TryLock":             ext۰sync۰RWMutex۰TryLock,
+		"(*sync.RWMutex).TryRLock":            ext۰sync۰RWMutex۰TryRLock,
+		"(*sync.RWMutex).Unlock":              ext۰sync۰RWMutex۰Unlock,
+		"(*sync.WaitGroup).Add":               ext۰sync۰WaitGroup۰Add,
+		"(*sync.WaitGroup).Done":              ext۰sync۰WaitGroup۰Done,
+		"(*sync.WaitGroup).Wait":              ext۰sync۰WaitGroup۰Wait,
+		"(*sync/atomic.Value).CompareAndSwap": ext۰atomic۰Value۰CompareAndSwap,
+		"(*sync/atomic.Value).Load":           ext۰atomic۰Value۰Load,
+		"(*sync/atomic.Value).Store":          ext۰atomic۰Value۰Store,
+		"(*sync/atomic.Value).Swap":           ext۰atomic۰Value۰Swap,
+		"sync/atomic.AddInt32":                atomicAdd[int32],
+		"sync/atomic.AddInt64":                atomicAdd[int64],
+		"sync/atomic.AddUint32":               atomicAdd[uint32],
+		"sync/atomic.AddUint64":               atomicAdd[uint64],
+		"sync/atomic.AddUintptr":              atomicAdd[uintptr],
+		"sync/atomic.AndInt32":                atomicAnd[int32],
+		"sync/atomic.AndInt64":                atomicAnd[int64],
+		"sync/atomic.AndUint32":               atomicAnd[uint32],
+		"sync/atomic.AndUint64":               atomicAnd[uint64],
+		"sync/atomic.AndUintptr":              atomicAnd[uintptr],
+		"sync/atomic.CompareAndSwapInt32":     atomicCompareAndSwap,
+		"sync/atomic.CompareAndSwapInt64":     atomicCompareAndSwap,
+		"sync/atomic.CompareAndSwapPointer":   atomicCompareAndSwap,
+		"sync/atomic.CompareAndSwapUint32":    atomicCompareAndSwap,
+		"sync/atomic.CompareAndSwapUint64":    atomicCompareAndSwap,
+		"sync/atomic.CompareAndSwapUintptr":   atomicCompareAndSwap,
+		"sync/atomic.LoadInt32":               atomicLoad,
+		"sync/atomic.LoadInt64":               atomicLoad,
+		"sync/atomic.LoadPointer":             atomicLoad,
+		"sync/atomic.LoadUint32":              atomicLoad,
+		"sync/atomic.LoadUint64":              atomicLoad,
+		"sync/atomic.LoadUintptr":             atomicLoad,
+		"sync/atomic.OrInt32":                 atomicOr[int32],
+		"sync/atomic.OrInt64":                 atomicOr[int64],
+		"sync/atomic.OrUint32":                atomicOr[uint32],
+		"sync/atomic.OrUint64":                atomicOr[uint64],
+		"sync/atomic.OrUintptr":               atomicOr[uintptr],
+		"sync/atomic.StoreInt32":              atomicStore,
+		"sync/atomic.StoreInt64":              atomicStore,
+		"sync/atomic.StorePointer":            atomicStore,
+		"sync/atomic.StoreUint32":             atomicStore,
+		"sync/atomic.StoreUint64":             atomicStore,
+		"sync/atomic.StoreUintptr":            atomicStore,
+		"sync/atomic.SwapInt32":               atomicSwap,
+		"sync/atomic.SwapInt64":               atomicSwap,
+		"sync/atomic.SwapPointer":             atomicSwap,
+		"sync/atomic.SwapUint32":              atomicSwap,
+		"sync/atomic.SwapUint64":              atomicSwap,
+		"sync/atomic.SwapUintptr":             atomicSwap,
+	} {
+		externals[k] = v
+	}
+}
+
+// syncState returns the state of the sync object at address addr.
+// Every operation on the object calls it first, as the operation
+// synchronizes with earlier ones.
+func syncState[T any](fr *frame, addr value) *T {
+	p := addr.(*value)
+	_ = *p // nil check
+	s := fr.i.sched
+	s.syncWith(p)
+	st, ok := s.syncs[p].(*T)
+	if !ok {
+		st = new(T)
+		s.syncs[p] = st
+	}
+	return st
+}
+
+// throw reports an unrecoverable error of the running goroutine.
+func throw(fr *frame, msg string) {
+	s := fr.i.sched
+	s.cur.frame = fr
+	panic(&fatalError{msg: "fatal error: " + msg, stacks: s.stacks(s.cur)})
+}
+
+// targetString returns a panic value of the target program for a string.
+func targetString(msg string) targetPanic {
+	return targetPanic{iface{types.Typ[types.String], msg}}
+}
+
+// -- Mutex --
+
+func ext۰sync۰Mutex۰Lock(fr *frame, args []value) value {
+	m := syncState[mutex](fr, args[0])
+	if !m.locked {
+		m.locked = true
+		return nil
+	}
+	s := fr.i.sched
+	m.waiters = append(m.waiters, s.cur)
+	s.park(fr, "sync.Mutex.Lock") // Unlock hands over the lock
+	s.syncWith(args[0].(*value))
+	return nil
+}
+
+func ext۰sync۰Mutex۰TryLock(fr *frame, args []value) value {
+	m := syncState[mutex](fr, args[0])
+	if m.locked {
+		return false
+	}
+	m.locked = true
+	return true
+}
+
+func ext۰sync۰Mutex۰Unlock(fr *frame, args []value) value {
+	m := syncState[mutex](fr, args[0])
+	if !m.locked {
+		throw(fr, "sync: unlock of unlocked mutex")
+	}
+	if len(m.waiters) > 0 {
+		g := m.waiters[0]
+		m.waiters = m.waiters[1:]
+		fr.i.sched.ready(g)
+	} else {
+		m.locked = false
+	}
+	return nil
+}
+
+// -- RWMutex --
+
+func ext۰sync۰RWMutex۰Lock(fr *frame, args []value) value {
+	rw := syncState[rwmutex](fr, args[0])
+	if !rw.writer && rw.readers == 0 {
+		rw.writer = true
+		return nil
+	}
+	s := fr.i.sched
+	rw.wwait = append(rw.wwait, s.cur)
+	s.park(fr, "sync.RWMutex.Lock")
+	s.syncWith(args[0].(*value))
+	return nil
+}
+
+func ext۰sync۰RWMutex۰TryLock(fr *frame, args []value) value {
+	rw := syncState[rwmutex](fr, args[0])
+	if rw.writer || rw.readers > 0 {
+		return false
+	}
+	rw.writer = true
+	return true
+}
+
+func ext۰sync۰RWMutex۰Unlock(fr *frame, args []value) value {
+	rw := syncState[rwmutex](fr, args[0])
+	if !rw.writer {
+		throw(fr, "sync: Unlock of unlocked RWMutex")
+	}
+	rw.writer = false
+	s := fr.i.sched
+	if len(rw.rwait) > 0 {
+		// Admit the waiting readers.
+		for _, g := range rw.rwait {
+			rw.readers++
+			s.ready(g)
+		}
+		rw.rwait = nil
+	} else if len(rw.wwait) > 0 {
+		rw.writer = true
+		s.ready(rw.wwait[0])
+		rw.wwait = rw.wwait[1:]
+	}
+	return nil
+}
+
+func ext۰sync۰RWMutex۰RLock(fr *frame, args []value) value {
+	rw := syncState[rwmutex](fr, args[0])
+	// A waiting writer excludes new readers.
+	if !rw.writer && len(rw.wwait) == 0 {
+		rw.readers++
+		return nil
+	}
+	s := fr.i.sched
+	rw.rwait = append(rw.rwait, s.cur)
+	s.park(fr, "sync.RWMutex.RLock")
+	s.syncWith(args[0].(*value))
+	return nil
+}
+
+func ext۰sync۰RWMutex۰TryRLock(fr *frame, args []value) value {
+	rw := syncState[rwmutex](fr, args[0])
+	if rw.writer || len(rw.wwait) > 0 {
+		return false
+	}
+	rw.readers++
+	return true
+}
+
+func ext۰sync۰RWMutex۰RUnlock(fr *frame, args []value) value {
+	rw := syncState[rwmutex](fr, args[0])
+	if rw.readers == 0 {
+		throw(fr, "sync: RUnlock of unlocked RWMutex")
+	}
+	rw.readers--
+	if rw.readers == 0 && len(rw.wwait) > 0 {
+		rw.writer = true
+		fr.i.sched.ready(rw.wwait[0])
+		rw.wwait = rw.wwait[1:]
+	}
+	return nil
+}
+
+// -- WaitGroup --
+
+func ext۰sync۰WaitGroup۰Add(fr *frame, args []value) value {
+	waitGroupAdd(fr, args[0], int64(args[1].(int)))
+	return nil
+}
+
+func ext۰sync۰WaitGroup۰Done(fr *frame, args []value) value {
+	waitGroupAdd(fr, args[0], -1)
+	return nil
+}
+
+func waitGroupAdd(fr *frame, addr value, delta int64) {
+	wg := syncState[waitGroup](fr, addr)
+	wg.n += delta
+	if wg.n < 0 {
+		panic(targetString("sync: negative WaitGroup counter"))
+	}
+	if wg.n == 0 {
+		for _, g := range wg.waiters {
+			fr.i.sched.ready(g)
+		}
+		wg.waiters = nil
+	}
+}
+
+func ext۰sync۰WaitGroup۰Wait(fr *frame, args []value) value {
+	wg := syncState[waitGroup](fr, args[0])
+	if wg.n > 0 {
+		s := fr.i.sched
+		wg.waiters = append(wg.waiters, s.cur)
+		s.park(fr, "sync.WaitGroup.Wait")
+		s.syncWith(args[0].(*value))
+	}
+	return nil
+}
+
+// -- Cond --
+
+func ext۰sync۰Cond۰Wait(fr *frame, args []value) value {
+	c := syncState[cond](fr, args[0])
+	s := fr.i.sched
+	c.waiters = append(c.waiters, s.cur)
+
+	// Find the field c.L of the receiver.
+	l := iface{}
+	st := fr.fn.Signature.Recv().Type().(*types.Pointer).Elem().Underlying().(*types.Struct)
+	for i := 0; i < st.NumFields(); i++ {
+		if st.Field(i).Name() == "L" {
+			l = (*args[0].(*value)).(structure)[i].(iface)
+		}
+	}
+	if l.t == nil {
+		panic(runtimeError("invalid memory address or nil pointer dereference"))
+	}
+	callLocker(fr, l, "Unlock")
+	s.park(fr, "sync.Cond.Wait")
+	s.syncWith(args[0].(*value))
+	callLocker(fr, l, "Lock")
+	return nil
+}
+
+// callLocker calls method name of the sync.Locker l.
+func callLocker(fr *frame, l iface, name string) {
+	fn := fr.i.prog.LookupMethod(l.t, nil, name)
+	call(fr.i, fr, fr.fn.Pos(), fn, []value{l.v})
+}
+
+func ext۰sync۰Cond۰Signal(fr *frame, args []value) value {
+	c := syncState[cond](fr, args[0])
+	if len(c.waiters) > 0 {
+		fr.i.sched.ready(c.waiters[0])
+		c.waiters = c.waiters[1:]
+	}
+	return nil
+}
+
+func ext۰sync۰Cond۰Broadcast(fr *frame, args []value) value {
+	c := syncState[cond](fr, args[0])
+	for _, g := range c.waiters {
+		fr.i.sched.ready(g)
+	}
+	c.waiters = nil
+	return nil
+}
+
+// -- sync/atomic --
+
+type atomicInt interface {
+	int32 | int64 | uint32 | uint64 | uintptr
+}
+
+func atomicAdd[T atomicInt](fr *frame, args []value) value {
+	p := atomicAddr(fr, args[0])
+	v := (*p).(T) + args[1].(T)
+	*p = v
+	return v
+}
+
+func atomicAnd[T atomicInt](fr *frame, args []value) value {
+	p := atomicAddr(fr, args[0])
+	old := (*p).(T)
+	*p = old & args[1].(T)
+	return old
+}
+
+func atomicOr[T atomicInt](fr *frame, args []value) value {
+	p := atomicAddr(fr, args[0])
+	old := (*p).(T)
+	*p = old | args[1].(T)
+	return old
+}
+
+func atomicLoad(fr *frame, args []value) value {
+	return *atomicAddr(fr, args[0])
+}
+
+func atomicStore(fr *frame, args []value) value {
+	*atomicAddr(fr, args[0]) = args[1]
+	return nil
+}
+
+// atomicAddr returns the address of the variable of an atomic
+// operation, which synchronizes with earlier ones.
+func atomicAddr(fr *frame, addr value) *value {
+	p := addr.(*value)
+	fr.i.sched.syncWith(p)
+	return p
+}
+
+func atomicSwap(fr *frame, args []value) value {
+	p := atomicAddr(fr, args[0])
+	old := *p
+	*p = args[1]
+	return old
+}
+
+func atomicCompareAndSwap(fr *frame, args []value) value {
+	p := atomicAddr(fr, args[0])
+	if *p != args[1] {
+		return false
+	}
+	*p = args[2]
+	return true
+}
+
+// atomicValue returns the address of the field v of an atomic.Value.
+func atomicValue(fr *frame, addr value) *value {
+	return atomicAddr(fr, &(*addr.(*value)).(structure)[0])
+}
+
+func ext۰atomic۰Value۰Load(fr *frame, args []value) value {
+	return *atomicValue(fr, args[0])
+}
+
+func ext۰atomic۰Value۰Store(fr *frame, args []value) value {
+	p := atomicValue(fr, args[0])
+	checkAtomicValue(*p, args[1], "store")
+	*p = args[1]
+	return nil
+}
+
+func ext۰atomic۰Value۰Swap(fr *frame, args []value) value {
+	p := atomicValue(fr, args[0])
+	checkAtomicValue(*p, args[1], "swap")
+	old := *p
+	*p = args[1]
+	return old
+}
+
+func ext۰atomic۰Value۰CompareAndSwap(fr *frame, args []value) value {
+	p := atomicValue(fr, args[0])
+	checkAtomicValue(*p, args[2], "compare and swap")
+	if old := args[1].(iface); old.t != nil && (*p).(iface).t != nil && !types.Identical(old.t, (*p).(iface).t) {
+		panic(targetString("sync/atomic: compare and swap of inconsistently typed values"))
+	}
+	if !equals(types.NewInterfaceType(nil, nil), *p, args[1]) {
+		return false
+	}
+	*p = args[2]
+	return true
+}
+
+// checkAtomicValue checks that the value v may replace the value old
+// of an atomic.Value.
+func checkAtomicValue(old, v value, op string) {
+	if v.(iface).t == nil {
+		panic(targetString("sync/atomic: " + op + " of nil value into Value"))
+	}
+	if t := old.(iface).t; t != nil && !types.Identical(t, v.(iface).t) {
+		panic(targetString("sync/atomic: " + op + " of inconsistently typed value into Value"))
+	}
+}
@@ -0,0 +1,280 @@
+package main
+
+// Tests of goroutines, channels, select, and the sync and sync/atomic
+// intrinsics under the interpreter's scheduler.
+
+import (
+	"fmt"
+	"runtime"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Unbuffered channels synchronize sender and receiver.
+func init() {
+	ch := make(chan int)
+	done := make(chan bool)
+	go func() {
+		sum := 0
+		for x := range ch {
+			sum += x
+		}
+		if sum != 45 {
+			panic(sum)
+		}
+		done <- true
+	}()
+	for i := 0; i < 10; i++ {
+		ch <- i
+	}
+	close(ch)
+	<-done
+
+	x, ok := <-ch
+	if x != 0 || ok {
+		panic(fmt.Sprint(x, ok))
+	}
+}
+
+// Buffered channels.
+func init() {
+	ch := make(chan string, 2)
+	ch <- "a"
+	ch <- "b"
+	if len(ch) != 2 || cap(ch) != 2 {
+		panic(fmt.Sprint(len(ch), cap(ch)))
+	}
+	go func() { ch <- "c" }()
+	if s := <-ch + <-ch + <-ch; s != "abc" {
+		panic(s)
+	}
+	var nilch chan int
+	if len(nilch) != 0 || cap(nilch) != 0 {
+		panic("nil chan")
+	}
+}
+
+// A mutex makes a read-modify-write atomic, even across yields.
+func init() {
+	var (
+		mu sync.Mutex
+		wg sync.WaitGroup
+		n  int
+	)
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 100; j++ {
+				mu.Lock()
+				v := n
+				runtime.Gosched()
+				n = v + 1
+				mu.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+	if n != 1000 {
+		panic(n)
+	}
+	if !mu.TryLock() || mu.TryLock() {
+		panic("TryLock")
+	}
+	mu.Unlock()
+}
+
+// RWMutex admits many readers or one writer.
+func init() {
+	var (
+		rw      sync.RWMutex
+		wg      sync.WaitGroup
+		readers int32
+		max     atomic.Int32
+	)
+	for i := 0; i < 5; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			rw.RLock()
+			if n := atomic.AddInt32(&readers, 1); n > max.Load() {
+				max.Store(n)
+			}
+			runtime.Gosched()
+			atomic.AddInt32(&readers, -1)
+			rw.RUnlock()
+		}()
+	}
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		rw.Lock()
+		if atomic.LoadInt32(&readers) != 0 {
+			panic("writer with readers")
+		}
+		rw.Unlock()
+	}()
+	wg.Wait()
+	if max.Load() < 2 {
+		panic(fmt.Sprint("max readers: ", max.Load()))
+	}
+}
+
+// Atomics.
+func init() {
+	var i64 int64
+	if atomic.AddInt64(&i64, 5) != 5 || atomic.SwapInt64(&i64, 7) != 5 {
+		panic("AddInt64/SwapInt64")
+	}
+	if atomic.CompareAndSwapInt64(&i64, 5, 9) || !atomic.CompareAndSwapInt64(&i64, 7, 9) || i64 != 9 {
+		panic(i64)
+	}
+	var u32 uint32 = 6
+	if atomic.AndUint32(&u32, 3) != 6 || u32 != 2 || atomic.OrUint32(&u32, 1) != 2 || u32 != 3 {
+		panic(u32)
+	}
+	var c atomic.Int32
+	c.Add(2)
+	c.Store(c.Load() * 10)
+	if c.Load() != 20 {
+		panic(c.Load())
+	}
+	var b atomic.Bool
+	if b.Swap(true) || !b.Load() {
+		panic("Bool")
+	}
+
+	var v atomic.Value
+	if v.Load() != nil {
+		panic("Value.Load")
+	}
+	v.Store("hello")
+	if !v.CompareAndSwap("hello", "world") || v.Load() != "world" {
+		panic(v.Load())
+	}
+	defer func() {
+		if r := recover(); r != "sync/atomic: store of inconsistently typed value into Value" {
+			panic(r)
+		}
+	}()
+	v.Store(1)
+}
+
+// Cond.
+func init() {
+	var (
+		mu    sync.Mutex
+		cond  = sync.NewCond(&mu)
+		ready bool
+		woken int
+	)
+	var wg sync.WaitGroup
+	for i := 0; i < 3; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			mu.Lock()
+			for !ready {
+				cond.Wait()
+			}
+			woken++
+			mu.Unlock()
+		}()
+	}
+	runtime.Gosched()
+	mu.Lock()
+	ready = true
+	cond.Broadcast()
+	mu.Unlock()
+	wg.Wait()
+	if woken != 3 {
+		panic(woken)
+	}
+}
+
+// Select chooses among ready cases fairly.
+func init() {
+	a := make(chan int, 100)
+	b := make(chan int, 100)
+	for i := 0; i < 100; i++ {
+		a <- i
+		b <- i
+	}
+	var na, nb int
+	for i := 0; i < 100; i++ {
+		select {
+		case <-a:
+			na++
+		case <-b:
+			nb++
+		}
+	}
+	if na < 25 || nb < 25 {
+		panic(fmt.Sprint("unfair select: ", na, nb))
+	}
+
+	// A blocking select is woken by a sender.
+	c := make(chan int)
+	go func() { c <- 42 }()
+	select {
+	case x := <-c:
+		if x != 42 {
+			panic(x)
+		}
+	case <-make(chan int):
+		panic("nil case")
+	}
+
+	// Non-blocking select.
+	select {
+	case x := <-c:
+		panic(x)
+	default:
+	}
+}
+
+// Sleep uses virtual time.
+func init() {
+	var (
+		order []int
+		mu    sync.Mutex
+		wg    sync.WaitGroup
+	)
+	for _, d := range []int{3, 1, 2} {
+		wg.Add(1)
+		go func(d int) {
+			defer wg.Done()
+			time.Sleep(time.Duration(d) * time.Hour)
+			mu.Lock()
+			order = append(order, d)
+			mu.Unlock()
+		}(d)
+	}
+	wg.Wait()
+	if fmt.Sprint(order) != "[1 2 3]" {
+		panic(fmt.Sprint(order))
+	}
+}
+
+// Goexit runs deferred calls.
+func init() {
+	done := make(chan bool)
+	go func() {
+		defer func() { done <- true }()
+		defer func() {
+			if r := recover(); r != nil {
+				panic(r)
+			}
+		}()
+		runtime.Goexit()
+		panic("unreachable")
+	}()
+	<-done
+}
+
+func main() {
+	if n := runtime.NumGoroutine(); n < 1 {
+		panic(n)
+	}
+}
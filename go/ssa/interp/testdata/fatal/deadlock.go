@@ -0,0 +1,15 @@
+package main
+
+import "sync"
+
+func main() {
+	var mu sync.Mutex
+	ch := make(chan int)
+	go func() {
+		mu.Lock()
+		ch <- 1
+		ch <- 2
+	}()
+	<-ch
+	mu.Lock()
+}
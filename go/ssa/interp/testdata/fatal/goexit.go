@@ -0,0 +1,12 @@
+package main
+
+import "runtime"
+
+func main() {
+	go func() {
+		for i := 0; i < 10; i++ {
+			runtime.Gosched()
+		}
+	}()
+	runtime.Goexit()
+}
@@ -0,0 +1,11 @@
+package main
+
+func main() {
+	done := make(chan bool)
+	go func() {
+		defer func() { done <- true }()
+		panic("boom")
+	}()
+	<-done
+	<-done
+}
@@ -0,0 +1,15 @@
+package main
+
+import "sync"
+
+func main() {
+	var wg sync.WaitGroup
+	n := 0
+	wg.Add(1)
+	go func() {
+		n++
+		wg.Done()
+	}()
+	print(n)
+	wg.Wait()
+}
@@ -0,0 +1,20 @@
+package main
+
+import "sync/atomic"
+
+// The goroutine runs before main checks the flag only if main is
+// preempted.
+func main() {
+	var flag atomic.Bool
+	done := make(chan bool)
+	go func() {
+		flag.Store(true)
+		done <- true
+	}()
+	for i := 0; i < 10; i++ {
+		if flag.Load() {
+			panic("preempted")
+		}
+	}
+	<-done
+}
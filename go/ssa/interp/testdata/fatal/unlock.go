@@ -0,0 +1,11 @@
+package main
+
+import "sync"
+
+func main() {
+	defer func() {
+		recover() // fatal errors cannot be recovered
+	}()
+	var mu sync.Mutex
+	mu.Unlock()
+}
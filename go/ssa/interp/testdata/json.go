@@ -0,0 +1,96 @@
+package main
+
+// Tests of the reflection-based encoding/json package.
+
+import "encoding/json"
+
+type Point struct {
+	X, Y int
+}
+
+type Record struct {
+	Name    string         `json:"name"`
+	Count   uint8          `json:"count,omitempty"`
+	Ratio   float64        `json:"ratio"`
+	Ok      bool           `json:"ok"`
+	Tags    []string       `json:"tags"`
+	Origin  *Point         `json:"origin,omitempty"`
+	Path    []Point        `json:"path"`
+	Attrs   map[string]int `json:"attrs,omitempty"`
+	Extra   any            `json:"extra,omitempty"`
+	Skipped string         `json:"-"`
+	hidden  int
+}
+
+func main() {
+	r := Record{
+		Name:    "a \"quoted\"\nname",
+		Ratio:   2.5,
+		Ok:      true,
+		Tags:    []string{"x", "y"},
+		Origin:  &Point{1, -2},
+		Path:    []Point{{3, 4}, {5, 6}},
+		Attrs:   map[string]int{"b": 2, "a": 1},
+		Extra:   []any{"s", 7, nil},
+		Skipped: "skipped",
+		hidden:  1,
+	}
+	data, err := json.Marshal(r)
+	if err != nil {
+		panic(err)
+	}
+	const want = `{"name":"a \"quoted\"\nname","ratio":2.5,"ok":true,"tags":["x","y"],` +
+		`"origin":{"X":1,"Y":-2},"path":[{"X":3,"Y":4},{"X":5,"Y":6}],` +
+		`"attrs":{"a":1,"b":2},"extra":["s",7,null]}`
+	if string(data) != want {
+		panic(string(data))
+	}
+
+	// Unmarshal sets fields through reflect.Value.Set, allocating
+	// pointers and growing slices as needed.
+	in := `{"NAME": "né", "count": 3, "ratio": -0.25, "ok": true,
+		"tags": ["p", "q", "r"], "origin": {"X": 7, "Y": 8},
+		"path": [{"X": 1}], "unknown": {"a": [1, {"b": null}]}, "Skipped": "no"}`
+	var got Record
+	got.Skipped = "kept"
+	if err := json.Unmarshal([]byte(in), &got); err != nil {
+		panic(err)
+	}
+	if got.Name != "né" || got.Count != 3 || got.Ratio != -0.25 || !got.Ok {
+		panic(got.Name)
+	}
+	if len(got.Tags) != 3 || got.Tags[0] != "p" || got.Tags[2] != "r" {
+		panic(len(got.Tags))
+	}
+	if got.Origin == nil || *got.Origin != (Point{7, 8}) {
+		panic("origin")
+	}
+	if len(got.Path) != 1 || got.Path[0] != (Point{1, 0}) {
+		panic("path")
+	}
+	if got.Skipped != "kept" {
+		panic(got.Skipped)
+	}
+
+	// Round trip.
+	var p Point
+	if err := json.Unmarshal([]byte(`{"X":1,"Y":2}`), &p); err != nil || p != (Point{1, 2}) {
+		panic(err)
+	}
+	if data, _ := json.Marshal(&p); string(data) != `{"X":1,"Y":2}` {
+		panic(string(data))
+	}
+
+	// Errors.
+	for _, bad := range []string{`{"X":"1"}`, `{"X":1`, `{"X":1}x`, `[1]`} {
+		if err := json.Unmarshal([]byte(bad), &p); err == nil {
+			panic(bad)
+		}
+	}
+	if err := json.Unmarshal([]byte(`1`), p); err == nil {
+		panic("non-pointer")
+	}
+	if _, err := json.Marshal(make(chan int)); err == nil {
+		panic("chan")
+	}
+}
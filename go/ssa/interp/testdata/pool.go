@@ -0,0 +1,104 @@
+package main
+
+// Tests of sync.Pool and strings.Builder, whose real implementations
+// depend on unsafe.
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+type buffer struct {
+	b []byte
+}
+
+var allocs int
+
+var pool = sync.Pool{
+	New: func() any {
+		allocs++
+		return new(buffer)
+	},
+}
+
+func format(i int) string {
+	buf := pool.Get().(*buffer)
+	defer pool.Put(buf)
+	buf.b = append(buf.b[:0], fmt.Sprint("item ", i)...)
+	return string(buf.b)
+}
+
+func init() {
+	// Sequential use reuses a single buffer.
+	for i := 0; i < 5; i++ {
+		if got, want := format(i), fmt.Sprint("item ", i); got != want {
+			panic(got)
+		}
+	}
+	if allocs != 1 {
+		panic(allocs)
+	}
+
+	// Concurrent use allocates at most one buffer per goroutine.
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 3; j++ {
+				format(j)
+			}
+		}()
+	}
+	wg.Wait()
+	if allocs < 1 || allocs > 5 {
+		panic(allocs)
+	}
+
+	// Without New, Get of an empty pool returns nil; Put(nil) is ignored.
+	var p sync.Pool
+	p.Put(nil)
+	if x := p.Get(); x != nil {
+		panic(x)
+	}
+	p.Put("x")
+	if x := p.Get(); x != "x" {
+		panic(x)
+	}
+}
+
+func init() {
+	var b strings.Builder
+	b.Grow(8)
+	for i := 0; i < 3; i++ {
+		b.WriteString(strconv.Itoa(i))
+		b.WriteByte(',')
+	}
+	b.WriteByte('x')
+	b.WriteRune('é')
+	if got := b.String(); got != "0,1,2,xé" {
+		panic(got)
+	}
+	if b.Len() != len("0,1,2,xé") {
+		panic(b.Len())
+	}
+	s := b.String()
+	b.Reset()
+	b.WriteString("new")
+	if s != "0,1,2,xé" || b.String() != "new" {
+		panic(s + b.String())
+	}
+
+	// Copying a non-zero Builder and writing to the copy panics.
+	defer func() {
+		if r := recover(); r != "strings: illegal use of non-zero Builder copied by value" {
+			panic(r)
+		}
+	}()
+	c := b
+	c.WriteString("bad")
+}
+
+func main() {}
@@ -2,10 +2,59 @@ package main
 
 import "reflect"
 
+type T struct {
+	A int
+	b string
+	C []float64
+}
+
 func main() {
 	// Regression test for issue 9462.
 	got := reflect.SliceOf(reflect.TypeOf(byte(0))).String()
 	if got != "[]uint8" && got != "[]byte" { // result varies by toolchain
 		println("BUG: " + got)
 	}
+
+	// Addressability and Set.
+	t := T{A: 1, b: "x", C: []float64{1}}
+	if reflect.ValueOf(t).Field(0).CanAddr() {
+		println("BUG: field of unaddressable struct is addressable")
+	}
+	v := reflect.ValueOf(&t).Elem()
+	if !v.CanAddr() || !v.Field(0).CanSet() || v.Field(1).CanSet() {
+		println("BUG: addressability")
+	}
+	v.Field(0).SetInt(42)
+	v.Field(2).Index(0).SetFloat(2.5)
+	if t.A != 42 || t.C[0] != 2.5 {
+		println("BUG: Set did not update the variable")
+	}
+	v.Set(reflect.ValueOf(T{A: 7}))
+	if t.A != 7 || t.C != nil {
+		println("BUG: Set of struct")
+	}
+	if p := v.Field(0).Addr().Interface().(*int); p != &t.A {
+		println("BUG: Addr")
+	}
+	func() {
+		defer func() {
+			if recover() == nil {
+				println("BUG: SetString of unexported field did not panic")
+			}
+		}()
+		v.Field(1).SetString("y")
+	}()
+
+	// An interface value copies the variable.
+	x := v.Interface().(T)
+	t.A = 8
+	if x.A != 7 {
+		println("BUG: Interface aliases the variable")
+	}
+
+	n := reflect.New(reflect.TypeOf(""))
+	n.Elem().SetString("hi")
+	if *n.Interface().(*string) != "hi" {
+		println("BUG: New")
+	}
 }
@@ -0,0 +1,501 @@
+// Package json is a reduced encoding/json for the interpreter tests.
+//
+// The real package cannot be interpreted: it depends on packages, such
+// as bytes and encoding/json/v2, that the simplified standard library
+// in testdata/src lacks. Like the real package, this one is written
+// in terms of reflection, so that programs that use encoding/json,
+// such as $GOROOT/test/typeparam/issue48317.go and testdata/json.go,
+// exercise the interpreter's implementation of reflect: struct fields
+// and tags, Value.Set and its typed variants, New, Append, and
+// MapIndex. It handles booleans, integers, decimal floats, strings,
+// pointers, slices, arrays, and structs, and (for Marshal only) maps
+// with string keys and interfaces, which covers the values of those
+// programs.
+package json
+
+import (
+	"errors"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Marshal returns the JSON encoding of v.
+func Marshal(v any) ([]byte, error) {
+	var b strings.Builder
+	if err := encode(&b, reflect.ValueOf(v)); err != nil {
+		return nil, err
+	}
+	return []byte(b.String()), nil
+}
+
+func encode(b *strings.Builder, v reflect.Value) error {
+	switch v.Kind() {
+	case reflect.Invalid:
+		b.WriteString("null")
+	case reflect.Bool:
+		if v.Bool() {
+			b.WriteString("true")
+		} else {
+			b.WriteString("false")
+		}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		b.WriteString(strconv.Itoa(int(v.Int())))
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		b.WriteString(strconv.Itoa(int(v.Uint())))
+	case reflect.Float32, reflect.Float64:
+		b.WriteString(strconv.FormatFloat(v.Float(), 'g', -1, 64))
+	case reflect.String:
+		quote(b, v.String())
+	case reflect.Pointer, reflect.Interface:
+		if v.IsNil() {
+			b.WriteString("null")
+			return nil
+		}
+		return encode(b, v.Elem())
+	case reflect.Slice, reflect.Array:
+		if v.Kind() == reflect.Slice && v.IsNil() {
+			b.WriteString("null")
+			return nil
+		}
+		b.WriteByte('[')
+		for i := 0; i < v.Len(); i++ {
+			if i > 0 {
+				b.WriteByte(',')
+			}
+			if err := encode(b, v.Index(i)); err != nil {
+				return err
+			}
+		}
+		b.WriteByte(']')
+	case reflect.Map:
+		if v.IsNil() {
+			b.WriteString("null")
+			return nil
+		}
+		keys := make(map[string]reflect.Value)
+		var names []string
+		for _, k := range v.MapKeys() {
+			if k.Kind() != reflect.String {
+				return errors.New("json: unsupported type: " + v.Type().String())
+			}
+			keys[k.String()] = k
+			names = append(names, k.String())
+		}
+		sort.Strings(names)
+		b.WriteByte('{')
+		for i, name := range names {
+			if i > 0 {
+				b.WriteByte(',')
+			}
+			quote(b, name)
+			b.WriteByte(':')
+			if err := encode(b, v.MapIndex(keys[name])); err != nil {
+				return err
+			}
+		}
+		b.WriteByte('}')
+	case reflect.Struct:
+		b.WriteByte('{')
+		first := true
+		for _, f := range fields(v.Type()) {
+			fv := v.Field(f.index)
+			if f.omitEmpty && isEmpty(fv) {
+				continue
+			}
+			if !first {
+				b.WriteByte(',')
+			}
+			first = false
+			quote(b, f.name)
+			b.WriteByte(':')
+			if err := encode(b, fv); err != nil {
+				return err
+			}
+		}
+		b.WriteByte('}')
+	default:
+		return errors.New("json: unsupported type: " + v.Type().String())
+	}
+	return nil
+}
+
+func quote(b *strings.Builder, s string) {
+	const hex = "0123456789abcdef"
+	b.WriteByte('"')
+	for i := 0; i < len(s); i++ {
+		switch c := s[i]; c {
+		case '"', '\\':
+			b.WriteByte('\\')
+			b.WriteByte(c)
+		case '\n':
+			b.WriteString(`\n`)
+		case '\r':
+			b.WriteString(`\r`)
+		case '\t':
+			b.WriteString(`\t`)
+		default:
+			if c < ' ' {
+				b.WriteString(`\u00`)
+				b.WriteByte(hex[c>>4])
+				b.WriteByte(hex[c&0xF])
+			} else {
+				b.WriteByte(c)
+			}
+		}
+	}
+	b.WriteByte('"')
+}
+
+func isEmpty(v reflect.Value) bool {
+	switch v.Kind() {
+	case reflect.Array, reflect.Map, reflect.Slice, reflect.String:
+		return v.Len() == 0
+	case reflect.Bool:
+		return !v.Bool()
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return v.Int() == 0
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return v.Uint() == 0
+	case reflect.Float32, reflect.Float64:
+		return v.Float() == 0
+	case reflect.Interface, reflect.Pointer:
+		return v.IsNil()
+	}
+	return false
+}
+
+// A field is an exported struct field as seen by the encoder.
+type field struct {
+	name      string
+	index     int
+	omitEmpty bool
+}
+
+func fields(t reflect.Type) []field {
+	var fs []field
+	for i := 0; i < t.NumField(); i++ {
+		sf := t.Field(i)
+		if !sf.IsExported() {
+			continue
+		}
+		tag := sf.Tag.Get("json")
+		if tag == "-" {
+			continue
+		}
+		name, opts, _ := strings.Cut(tag, ",")
+		if name == "" {
+			name = sf.Name
+		}
+		fs = append(fs, field{name: name, index: i, omitEmpty: opts == "omitempty"})
+	}
+	return fs
+}
+
+// Unmarshal parses the JSON-encoded data and stores the result in
+// the value pointed to by v.
+func Unmarshal(data []byte, v any) error {
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Pointer || rv.IsNil() {
+		return errors.New("json: Unmarshal(non-pointer)")
+	}
+	d := &decoder{data: string(data)}
+	if err := d.value(rv.Elem()); err != nil {
+		return err
+	}
+	d.skipSpace()
+	if d.off < len(d.data) {
+		return d.syntaxError("after top-level value")
+	}
+	return nil
+}
+
+type decoder struct {
+	data string
+	off  int
+}
+
+func (d *decoder) syntaxError(context string) error {
+	if d.off >= len(d.data) {
+		return errors.New("json: unexpected end of JSON input")
+	}
+	return errors.New("json: invalid character '" + d.data[d.off:d.off+1] + "' " + context)
+}
+
+func (d *decoder) skipSpace() {
+	for d.off < len(d.data) {
+		switch d.data[d.off] {
+		case ' ', '\t', '\n', '\r':
+			d.off++
+		default:
+			return
+		}
+	}
+}
+
+// consume reports whether the next non-space token is c, and skips it if so.
+func (d *decoder) consume(c byte) bool {
+	d.skipSpace()
+	if d.off < len(d.data) && d.data[d.off] == c {
+		d.off++
+		return true
+	}
+	return false
+}
+
+// value decodes the next value into v. If v is the zero Value, the
+// input value is parsed and discarded.
+func (d *decoder) value(v reflect.Value) error {
+	d.skipSpace()
+	if d.off >= len(d.data) {
+		return d.syntaxError("")
+	}
+	if strings.HasPrefix(d.data[d.off:], "null") {
+		d.off += len("null")
+		if v.IsValid() {
+			switch v.Kind() {
+			case reflect.Pointer, reflect.Slice, reflect.Map, reflect.Interface:
+				v.Set(reflect.Zero(v.Type()))
+			}
+		}
+		return nil
+	}
+	if v.IsValid() && v.Kind() == reflect.Pointer {
+		if v.IsNil() {
+			v.Set(reflect.New(v.Type().Elem()))
+		}
+		return d.value(v.Elem())
+	}
+	switch c := d.data[d.off]; {
+	case c == '{':
+		return d.object(v)
+	case c == '[':
+		return d.array(v)
+	case c == '"':
+		s, err := d.str()
+		if err != nil {
+			return err
+		}
+		if v.IsValid() {
+			if v.Kind() != reflect.String {
+				return typeError("string", v)
+			}
+			v.SetString(s)
+		}
+	case strings.HasPrefix(d.data[d.off:], "true"), strings.HasPrefix(d.data[d.off:], "false"):
+		lit := d.data[d.off] == 't'
+		if lit {
+			d.off += len("true")
+		} else {
+			d.off += len("false")
+		}
+		if v.IsValid() {
+			if v.Kind() != reflect.Bool {
+				return typeError("bool", v)
+			}
+			v.SetBool(lit)
+		}
+	case c == '-' || '0' <= c && c <= '9':
+		return d.number(v)
+	default:
+		return d.syntaxError("looking for beginning of value")
+	}
+	return nil
+}
+
+func typeError(what string, v reflect.Value) error {
+	return errors.New("json: cannot unmarshal " + what + " into Go value of type " + v.Type().String())
+}
+
+func (d *decoder) object(v reflect.Value) error {
+	if v.IsValid() && v.Kind() != reflect.Struct {
+		return typeError("object", v)
+	}
+	var fs []field
+	if v.IsValid() {
+		fs = fields(v.Type())
+	}
+	d.off++ // '{'
+	if d.consume('}') {
+		return nil
+	}
+	for {
+		d.skipSpace()
+		key, err := d.str()
+		if err != nil {
+			return err
+		}
+		if !d.consume(':') {
+			return d.syntaxError("after object key")
+		}
+		var fv reflect.Value
+		for _, f := range fs {
+			if f.name == key || strings.EqualFold(f.name, key) {
+				fv = v.Field(f.index)
+				break
+			}
+		}
+		if err := d.value(fv); err != nil {
+			return err
+		}
+		if d.consume('}') {
+			return nil
+		}
+		if !d.consume(',') {
+			return d.syntaxError("after object key:value pair")
+		}
+	}
+}
+
+func (d *decoder) array(v reflect.Value) error {
+	if v.IsValid() && v.Kind() != reflect.Slice {
+		return typeError("array", v)
+	}
+	d.off++ // '['
+	if v.IsValid() {
+		v.Set(reflect.Zero(v.Type()))
+	}
+	if d.consume(']') {
+		return nil
+	}
+	for i := 0; ; i++ {
+		var elem reflect.Value
+		if v.IsValid() {
+			v.Set(reflect.Append(v, reflect.Zero(v.Type().Elem())))
+			elem = v.Index(i)
+		}
+		if err := d.value(elem); err != nil {
+			return err
+		}
+		if d.consume(']') {
+			return nil
+		}
+		if !d.consume(',') {
+			return d.syntaxError("after array element")
+		}
+	}
+}
+
+func (d *decoder) str() (string, error) {
+	if d.off >= len(d.data) || d.data[d.off] != '"' {
+		return "", d.syntaxError("looking for beginning of string")
+	}
+	d.off++
+	var b strings.Builder
+	for d.off < len(d.data) {
+		c := d.data[d.off]
+		d.off++
+		switch c {
+		case '"':
+			return b.String(), nil
+		case '\\':
+			if d.off >= len(d.data) {
+				return "", d.syntaxError("in string escape code")
+			}
+			c = d.data[d.off]
+			d.off++
+			switch c {
+			case '"', '\\', '/':
+				b.WriteByte(c)
+			case 'n':
+				b.WriteByte('\n')
+			case 'r':
+				b.WriteByte('\r')
+			case 't':
+				b.WriteByte('\t')
+			case 'u':
+				if d.off+4 > len(d.data) {
+					return "", d.syntaxError("in \\u hexadecimal character escape")
+				}
+				var r rune
+				for _, h := range d.data[d.off : d.off+4] {
+					switch {
+					case '0' <= h && h <= '9':
+						r = r<<4 | (h - '0')
+					case 'a' <= h && h <= 'f':
+						r = r<<4 | (h - 'a' + 10)
+					case 'A' <= h && h <= 'F':
+						r = r<<4 | (h - 'A' + 10)
+					default:
+						return "", d.syntaxError("in \\u hexadecimal character escape")
+					}
+				}
+				d.off += 4
+				b.WriteRune(r)
+			default:
+				d.off--
+				return "", d.syntaxError("in string escape code")
+			}
+		default:
+			b.WriteByte(c)
+		}
+	}
+	return "", d.syntaxError("in string literal")
+}
+
+func (d *decoder) number(v reflect.Value) error {
+	start := d.off
+	for d.off < len(d.data) && strings.IndexByte("+-0123456789.eE", d.data[d.off]) >= 0 {
+		d.off++
+	}
+	lit := d.data[start:d.off]
+	if !v.IsValid() {
+		return nil
+	}
+	switch v.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		n, err := strconv.Atoi(lit)
+		if err != nil {
+			return typeError("number "+lit, v)
+		}
+		v.SetInt(int64(n))
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		n, err := strconv.Atoi(lit)
+		if err != nil || n < 0 {
+			return typeError("number "+lit, v)
+		}
+		v.SetUint(uint64(n))
+	case reflect.Float32, reflect.Float64:
+		f, ok := parseFloat(lit)
+		if !ok {
+			return typeError("number "+lit, v)
+		}
+		v.SetFloat(f)
+	default:
+		return typeError("number", v)
+	}
+	return nil
+}
+
+// parseFloat parses a decimal literal without an exponent; the test
+// strconv package has no ParseFloat.
+func parseFloat(s string) (float64, bool) {
+	neg := strings.HasPrefix(s, "-")
+	if neg {
+		s = s[1:]
+	}
+	ip, fp, _ := strings.Cut(s, ".")
+	if ip == "" {
+		return 0, false
+	}
+	var f float64
+	for _, c := range ip {
+		if c < '0' || c > '9' {
+			return 0, false
+		}
+		f = f*10 + float64(c-'0')
+	}
+	scale := 1.0
+	for _, c := range fp {
+		if c < '0' || c > '9' {
+			return 0, false
+		}
+		scale /= 10
+		f += float64(c-'0') * scale
+	}
+	if neg {
+		f = -f
+	}
+	return f, true
+}
@@ -1,17 +1,46 @@
 package math
 
-func Copysign(float64, float64) float64
+import "unsafe"
 
-func NaN() float64
+const (
+	uvnan    = 0x7FF8000000000001
+	uvinf    = 0x7FF0000000000000
+	uvneginf = 0xFFF0000000000000
+	signMask = 1 << 63
+)
 
-func Inf(int) float64
+func Abs(x float64) float64 {
+	return Float64frombits(Float64bits(x) &^ signMask)
+}
+
+func Copysign(f, sign float64) float64 {
+	return Float64frombits(Float64bits(f)&^signMask | Float64bits(sign)&signMask)
+}
+
+func NaN() float64 { return Float64frombits(uvnan) }
 
-func IsNaN(float64) bool
+func Inf(sign int) float64 {
+	var v uint64
+	if sign >= 0 {
+		v = uvinf
+	} else {
+		v = uvneginf
+	}
+	return Float64frombits(v)
+}
+
+func IsNaN(f float64) (is bool) {
+	// IEEE 754 says that only NaNs satisfy f != f.
+	return f != f
+}
 
-func Float64bits(float64) uint64
+func Float32bits(f float32) uint32     { return *(*uint32)(unsafe.Pointer(&f)) }
+func Float32frombits(b uint32) float32 { return *(*float32)(unsafe.Pointer(&b)) }
+func Float64bits(f float64) uint64     { return *(*uint64)(unsafe.Pointer(&f)) }
+func Float64frombits(b uint64) float64 { return *(*float64)(unsafe.Pointer(&b)) }
 
 func Signbit(x float64) bool {
-	return Float64bits(x)&(1<<63) != 0
+	return Float64bits(x)&signMask != 0
 }
 
 func Sqrt(x float64) float64
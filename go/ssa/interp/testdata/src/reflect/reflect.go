@@ -4,6 +4,67 @@ type Type interface {
 	String() string
 	Kind() Kind
 	Elem() Type
+	NumField() int
+	Field(int) StructField
+}
+
+type StructField struct {
+	Name      string
+	PkgPath   string
+	Type      Type
+	Tag       StructTag
+	Offset    uintptr
+	Index     []int
+	Anonymous bool
+}
+
+func (f StructField) IsExported() bool { return f.PkgPath == "" }
+
+type StructTag string
+
+func (tag StructTag) Get(key string) string {
+	v, _ := tag.Lookup(key)
+	return v
+}
+
+// Lookup is like the real StructTag.Lookup, except that it does not
+// interpret escape sequences in quoted values.
+func (tag StructTag) Lookup(key string) (value string, ok bool) {
+	for tag != "" {
+		i := 0
+		for i < len(tag) && tag[i] == ' ' {
+			i++
+		}
+		tag = tag[i:]
+		if tag == "" {
+			break
+		}
+		i = 0
+		for i < len(tag) && tag[i] > ' ' && tag[i] != ':' && tag[i] != '"' && tag[i] != 0x7f {
+			i++
+		}
+		if i == 0 || i+1 >= len(tag) || tag[i] != ':' || tag[i+1] != '"' {
+			break
+		}
+		name := string(tag[:i])
+		tag = tag[i+1:]
+		i = 1
+		for i < len(tag) && tag[i] != '"' {
+			if tag[i] == '\\' {
+				i++
+			}
+			i++
+		}
+		if i >= len(tag) {
+			break
+		}
+		qvalue := string(tag[1:i])
+		tag = tag[i+1:]
+		if key == name {
+			return qvalue, true
+		}
+	}
+	return "", false
 }
 
 type Value struct {
@@ -25,6 +86,18 @@ func (Value) MapIndex(Value) Value
 func (Value) MapKeys() []Value
 func (Value) NumField() int
 func (Value) Interface() interface{}
+func (Value) CanAddr() bool
+func (Value) CanSet() bool
+func (Value) Addr() Value
+func (Value) Set(Value)
+func (Value) SetBool(bool)
+func (Value) SetFloat(float64)
+func (Value) SetInt(int64)
+func (Value) SetString(string)
+func (Value) SetUint(uint64)
+func (Value) Bool() bool
+func (Value) Float() float64
+func (Value) Uint() uint64
 
 func SliceOf(Type) Type
 
@@ -32,6 +105,12 @@ func TypeOf(interface{}) Type
 
 func ValueOf(interface{}) Value
 
+func New(Type) Value
+
+func Zero(Type) Value
+
+func Append(s Value, x ...Value) Value
+
 type Kind uint
 
 // Constants need to be kept in sync with the actual definitions for comparisons in tests.
@@ -17,3 +17,9 @@ type Error interface {
 }
 
 func GC()
+
+func Gosched()
+
+func Goexit()
+
+func NumGoroutine() int
@@ -1,5 +1,7 @@
 package strings
 
+import "unsafe"
+
 func Replace(s, old, new string, n int) string
 
 func Index(haystack, needle string) int
@@ -12,15 +14,85 @@ func HasPrefix(s, prefix string) bool {
 	return len(s) >= len(prefix) && s[0:len(prefix)] == prefix
 }
 
+func IndexByte(s string, c byte) int {
+	for i := 0; i < len(s); i++ {
+		if s[i] == c {
+			return i
+		}
+	}
+	return -1
+}
+
+func Cut(s, sep string) (before, after string, found bool) {
+	if i := Index(s, sep); i >= 0 {
+		return s[:i], s[i+len(sep):], true
+	}
+	return s, "", false
+}
+
 func EqualFold(s, t string) bool
 func ToLower(s string) string
 
+// Builder mirrors the real strings.Builder, including its use of
+// unsafe to detect copies and to avoid copying in String.
 type Builder struct {
-	s string
+	addr *Builder
+	buf  []byte
+}
+
+func (b *Builder) copyCheck() {
+	if b.addr == nil {
+		b.addr = (*Builder)(unsafe.Pointer(b))
+	} else if b.addr != b {
+		panic("strings: illegal use of non-zero Builder copied by value")
+	}
+}
+
+func (b *Builder) String() string {
+	return unsafe.String(unsafe.SliceData(b.buf), len(b.buf))
+}
+
+func (b *Builder) Len() int { return len(b.buf) }
+func (b *Builder) Cap() int { return cap(b.buf) }
+
+func (b *Builder) Reset() {
+	b.addr = nil
+	b.buf = nil
+}
+
+func (b *Builder) Grow(n int) {
+	b.copyCheck()
+	if n < 0 {
+		panic("strings.Builder.Grow: negative count")
+	}
+	if cap(b.buf)-len(b.buf) < n {
+		buf := make([]byte, len(b.buf), 2*cap(b.buf)+n)
+		copy(buf, b.buf)
+		b.buf = buf
+	}
+}
+
+func (b *Builder) Write(p []byte) (int, error) {
+	b.copyCheck()
+	b.buf = append(b.buf, p...)
+	return len(p), nil
+}
+
+func (b *Builder) WriteByte(c byte) error {
+	b.copyCheck()
+	b.buf = append(b.buf, c)
+	return nil
+}
+
+func (b *Builder) WriteRune(r rune) (int, error) {
+	b.copyCheck()
+	n := len(b.buf)
+	b.buf = append(b.buf, string(r)...)
+	return len(b.buf) - n, nil
 }
 
 func (b *Builder) WriteString(s string) (int, error) {
-	b.s += s
+	b.copyCheck()
+	b.buf = append(b.buf, s...)
 	return len(s), nil
 }
-func (b *Builder) String() string { return b.s }
@@ -1,36 +1,94 @@
 package sync
 
-// Rudimentary implementation of a mutex for interp tests.
+// The methods of Mutex, RWMutex, WaitGroup and Cond are intrinsics
+// of the interpreter's scheduler.
+
+type Locker interface {
+	Lock()
+	Unlock()
+}
+
 type Mutex struct {
-	c chan int // Mutex is held when held c!=nil and is empty. Access is guarded by g.
+	_ int32
+}
+
+func (m *Mutex) Lock()
+func (m *Mutex) TryLock() bool
+func (m *Mutex) Unlock()
+
+type RWMutex struct {
+	_ int32
 }
 
-func (m *Mutex) Lock() {
-	c := ch(m)
-	<-c
+func (rw *RWMutex) Lock()
+func (rw *RWMutex) RLock()
+func (rw *RWMutex) RUnlock()
+func (rw *RWMutex) TryLock() bool
+func (rw *RWMutex) TryRLock() bool
+func (rw *RWMutex) Unlock()
+
+type WaitGroup struct {
+	_ int32
 }
 
-func (m *Mutex) Unlock() {
-	c := ch(m)
-	c <- 1
+func (wg *WaitGroup) Add(delta int)
+func (wg *WaitGroup) Done()
+func (wg *WaitGroup) Wait()
+
+type Cond struct {
+	L Locker
 }
 
-// sequentializes Mutex.c access.
-var g = make(chan int, 1)
+func NewCond(l Locker) *Cond { return &Cond{L: l} }
 
-func init() {
-	g <- 1
+func (c *Cond) Broadcast()
+func (c *Cond) Signal()
+func (c *Cond) Wait()
+
+type Once struct {
+	m    Mutex
+	done bool
 }
 
-// ch initializes the m.c field if needed and returns it.
-func ch(m *Mutex) chan int {
-	<-g
-	defer func() {
-		g <- 1
-	}()
-	if m.c == nil {
-		m.c = make(chan int, 1)
-		m.c <- 1
+func (o *Once) Do(f func()) {
+	o.m.Lock()
+	defer o.m.Unlock()
+	if !o.done {
+		defer func() { o.done = true }()
+		f()
+	}
+}
+
+// A Pool is a set of temporary objects. Unlike the real Pool, it
+// never drops items, so programs that depend on reuse are
+// deterministic under the interpreter.
+type Pool struct {
+	m     Mutex
+	items []any
+
+	New func() any
+}
+
+func (p *Pool) Put(x any) {
+	if x == nil {
+		return
+	}
+	p.m.Lock()
+	p.items = append(p.items, x)
+	p.m.Unlock()
+}
+
+func (p *Pool) Get() any {
+	p.m.Lock()
+	if n := len(p.items); n > 0 {
+		x := p.items[n-1]
+		p.items = p.items[:n-1]
+		p.m.Unlock()
+		return x
+	}
+	p.m.Unlock()
+	if p.New != nil {
+		return p.New()
 	}
-	return m.c
+	return nil
 }
@@ -0,0 +1,99 @@
+package main
+
+// Tests of unsafe.Pointer and the unsafe built-ins.
+
+import (
+	"fmt"
+	"math"
+	"strings"
+	"unsafe"
+)
+
+type T struct {
+	a int8
+	b int32
+	c [4]uint16
+	d float64
+}
+
+// U has the layout of T.
+type U struct {
+	w int8
+	x int32
+	y [4]uint16
+	z float64
+}
+
+func main() {
+	// Conversion to a pointer of the same layout aliases the variable.
+	t := T{a: 1, b: 2, c: [4]uint16{3, 4, 5, 6}, d: 7}
+	u := (*U)(unsafe.Pointer(&t))
+	u.x = 20
+	if t.b != 20 || u.y[2] != 5 {
+		panic(fmt.Sprint(t, *u))
+	}
+
+	// Field and element addresses by offset.
+	pb := (*int32)(unsafe.Add(unsafe.Pointer(&t), unsafe.Offsetof(t.b)))
+	*pb = 21
+	if t.b != 21 {
+		panic(t.b)
+	}
+	pc := (*uint16)(unsafe.Pointer(uintptr(unsafe.Pointer(&t.c)) + 2*unsafe.Sizeof(t.c[0])))
+	*pc = 50
+	if t.c[2] != 50 {
+		panic(t.c)
+	}
+
+	// Bit views of scalars.
+	if math.Float64bits(1.5) != 0x3ff8000000000000 || math.Float64frombits(0x4000000000000000) != 2 {
+		panic("Float64bits")
+	}
+	if math.Float32bits(-1) != 0xbf800000 {
+		panic("Float32bits")
+	}
+	x := uint32(0x01020304)
+	lo := (*byte)(unsafe.Pointer(&x))
+	if *lo != 4 && *lo != 1 { // little or big endian
+		panic(*lo)
+	}
+	*lo = 0xff
+	if x != 0x010203ff && x != 0xff020304 {
+		panic(x)
+	}
+	pair := [2]uint32{1, 2}
+	if v := *(*uint64)(unsafe.Pointer(&pair)); v != 1<<32|2 && v != 2<<32|1 {
+		panic(v)
+	}
+	*(*uint64)(unsafe.Pointer(&pair)) = 0
+	if pair != [2]uint32{} {
+		panic(pair)
+	}
+
+	// unsafe.Slice, SliceData, String and StringData.
+	s := []int{1, 2, 3, 4}
+	s2 := unsafe.Slice(unsafe.SliceData(s), 3)
+	s2[1] = 20
+	if s[1] != 20 || len(s2) != 3 || cap(s2) != 3 {
+		panic(fmt.Sprint(s, s2))
+	}
+	str := "hello"
+	if unsafe.String(unsafe.StringData(str), 4) != "hell" {
+		panic("unsafe.String")
+	}
+	if unsafe.Slice((*int)(nil), 0) != nil {
+		panic("unsafe.Slice(nil, 0)")
+	}
+
+	var sb strings.Builder
+	sb.WriteString("a")
+	sb.WriteByte('b')
+	if sb.String() != "ab" || sb.Len() != 2 {
+		panic(sb.String())
+	}
+
+	var p unsafe.Pointer
+	if p != nil || uintptr(p) != 0 {
+		panic("nil unsafe.Pointer")
+	}
+}
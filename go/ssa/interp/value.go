@@ -14,7 +14,8 @@ package interp
 // - string
 // - map[value]value --- maps for which  usesBuiltinMap(keyType)
 //   *hashmap        --- maps for which !usesBuiltinMap(keyType)
-// - chan value
+// - *channel --- channels, managed by the scheduler.
+// - unsafePointer --- unsafe.Pointer values (see memory.go).
 // - []value --- slices
 // - iface --- interfaces.
 // - structure --- structs.  Fields are ordered and accessed by numeric indices.
@@ -237,8 +238,11 @@ func equals(t types.Type, x, y value) bool {
 		return x == y.(string)
 	case *value:
 		return x == y.(*value)
-	case chan value:
-		return x == y.(chan value)
+	case *channel:
+		return x == y.(*channel)
+	case unsafePointer:
+		y := y.(unsafePointer)
+		return x.cell == y.cell && x.off == y.off
 	case structure:
 		return x.eq(t, y)
 	case array:
@@ -298,8 +302,10 @@ func hash(outer, t types.Type, x value) int {
 		return hashString(x)
 	case *value:
 		return int(uintptr(unsafe.Pointer(x)))
-	case chan value:
-		return int(uintptr(reflect.ValueOf(x).Pointer()))
+	case *channel:
+		return int(uintptr(unsafe.Pointer(x)))
+	case unsafePointer:
+		return int(uintptr(unsafe.Pointer(x.cell))) + int(x.off)
 	case structure:
 		return x.hash(t)
 	case array:
@@ -337,6 +343,9 @@ func load(T types.Type, addr *value) value {
 		}
 		return a
 	default:
+		if v, ok := (*addr).(*view); ok {
+			return v.load()
+		}
 		return *addr
 	}
 }
@@ -357,6 +366,10 @@ func store(T types.Type, addr *value, v value) {
 			store(T.Elem(), &lhs[i], rhs[i])
 		}
 	default:
+		if view, ok := (*addr).(*view); ok {
+			view.store(v)
+			return
+		}
 		*addr = v
 	}
 }
@@ -396,8 +409,8 @@ func writeValue(buf *bytes.Buffer, v value) {
 		}
 		buf.WriteString("]")
 
-	case chan value:
-		fmt.Fprintf(buf, "%v", v) // (an address)
+	case *channel:
+		fmt.Fprintf(buf, "%p", v) // (an address)
 
 	case *value:
 		if v == nil {
@@ -444,6 +457,13 @@ func writeValue(buf *bytes.Buffer, v value) {
 	case *ssa.Function, *ssa.Builtin, *closure:
 		fmt.Fprintf(buf, "%p", v) // (an address)
 
+	case unsafePointer:
+		if v.cell == nil {
+			fmt.Fprintf(buf, "%#x", v.off)
+		} else {
+			fmt.Fprintf(buf, "%p+%d", v.cell, v.off) // (an address)
+		}
+
 	case rtype:
 		buf.WriteString(v.t.String())
 
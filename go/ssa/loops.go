@@ -0,0 +1,262 @@
+// Copyright 2024 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package ssa
+
+// This file defines the loop forest of a function.
+//
+// A back edge is an edge b->h of the CFG such that h dominates b;
+// h is the header of a loop and b one of its latches. The natural
+// loop of a header is the set of blocks from which a latch can be
+// reached without passing through the header, plus the header itself.
+// Natural loops with distinct headers are either disjoint or nested,
+// so they form a forest.
+//
+// An irreducible cycle (one entered at more than one block, such as
+// may be created by goto) has no back edge and thus no loop.
+
+import (
+	"go/token"
+	"go/types"
+	"sort"
+)
+
+// A Loop is a natural loop of a function.
+type Loop struct {
+	Header   *BasicBlock   // the unique entry block, which dominates the loop
+	Blocks   []*BasicBlock // blocks of the loop, header first, then in Index order
+	Latches  []*BasicBlock // blocks with a back edge to the header, in Index order
+	Exits    []*BasicBlock // blocks outside the loop with a predecessor inside, in Index order
+	Parent   *Loop         // innermost enclosing loop, or nil
+	Children []*Loop       // loops immediately nested within this one, by header Index
+	Depth    int           // nesting depth: 1 for an outermost loop
+
+	in []bool // in[b.Index] reports whether b is in the loop
+}
+
+// Contains reports whether block b belongs to the loop (or one of the
+// loops nested within it).
+func (l *Loop) Contains(b *BasicBlock) bool {
+	return b.Index < len(l.in) && l.in[b.Index] && l.Header.parent == b.parent
+}
+
+// Invariant reports whether v is trivially invariant in the loop,
+// that is, it is not computed by an instruction within the loop.
+func (l *Loop) Invariant(v Value) bool {
+	if instr, ok := v.(Instruction); ok && instr.Block() != nil {
+		return !l.Contains(instr.Block())
+	}
+	return true
+}
+
+func (l *Loop) String() string {
+	return "loop@" + l.Header.String()
+}
+
+// A LoopForest holds the natural loops of a function.
+type LoopForest struct {
+	Loops []*Loop // outermost loops, by header Index
+
+	innermost []*Loop // innermost loop containing each block, by Index
+}
+
+// All returns a new slice containing all the loops of the forest,
+// outer loops before the loops they contain.
+func (lf *LoopForest) All() []*Loop {
+	var all []*Loop
+	var visit func(loops []*Loop)
+	visit = func(loops []*Loop) {
+		for _, l := range loops {
+			all = append(all, l)
+			visit(l.Children)
+		}
+	}
+	visit(lf.Loops)
+	return all
+}
+
+// LoopOf returns the innermost loop containing block b, or nil if b is
+// not in a loop.
+func (lf *LoopForest) LoopOf(b *BasicBlock) *Loop {
+	if b.Index < len(lf.innermost) {
+		return lf.innermost[b.Index]
+	}
+	return nil
+}
+
+// Depth returns the loop nesting depth of block b: zero if b is not
+// in a loop.
+func (lf *LoopForest) Depth(b *BasicBlock) int {
+	if l := lf.LoopOf(b); l != nil {
+		return l.Depth
+	}
+	return 0
+}
+
+// Loops returns a new loop forest for the natural loops of f, which
+// must be built.
+func (f *Function) Loops() *LoopForest {
+	lf := &LoopForest{innermost: make([]*Loop, len(f.Blocks))}
+
+	// Find the back edges, and the loop of each header.
+	var loops []*Loop
+	for _, h := range f.Blocks {
+		var l *Loop
+		for _, b := range h.Preds {
+			if h.Dominates(b) {
+				if l == nil {
+					l = &Loop{Header: h, in: make([]bool, len(f.Blocks))}
+					loops = append(loops, l)
+				}
+				l.Latches = append(l.Latches, b)
+			}
+		}
+		if l == nil {
+			continue
+		}
+
+		// Walk backwards from the latches to the header.
+		l.in[h.Index] = true
+		stack := append([]*BasicBlock(nil), l.Latches...)
+		for len(stack) > 0 {
+			b := stack[len(stack)-1]
+			stack = stack[:len(stack)-1]
+			if !l.in[b.Index] {
+				l.in[b.Index] = true
+				stack = append(stack, b.Preds...)
+			}
+		}
+
+		l.Blocks = append(l.Blocks, h)
+		for _, b := range f.Blocks {
+			if l.in[b.Index] && b != h {
+				l.Blocks = append(l.Blocks, b)
+			}
+		}
+		seen := make(map[*BasicBlock]bool)
+		for _, b := range l.Blocks {
+			for _, s := range b.Succs {
+				if !l.in[s.Index] && !seen[s] {
+					seen[s] = true
+					l.Exits = append(l.Exits, s)
+				}
+			}
+		}
+		sortBlocks(l.Latches)
+		sortBlocks(l.Exits)
+	}
+
+	// Nest the loops. A loop is strictly larger than the loops
+	// nested within it, so visiting loops in decreasing order of
+	// size finds each loop's parent before the loop itself.
+	sorted := append([]*Loop(nil), loops...)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return len(sorted[i].Blocks) > len(sorted[j].Blocks)
+	})
+	for _, l := range sorted {
+		l.Parent = lf.innermost[l.Header.Index]
+		for _, b := range l.Blocks {
+			lf.innermost[b.Index] = l
+		}
+	}
+	for _, l := range loops { // in header order
+		if l.Parent == nil {
+			lf.Loops = append(lf.Loops, l)
+		} else {
+			l.Parent.Children = append(l.Parent.Children, l)
+		}
+	}
+	for _, l := range lf.All() {
+		l.Depth = 1
+		if l.Parent != nil {
+			l.Depth = l.Parent.Depth + 1
+		}
+	}
+	return lf
+}
+
+func sortBlocks(blocks []*BasicBlock) {
+	sort.Slice(blocks, func(i, j int) bool { return blocks[i].Index < blocks[j].Index })
+}
+
+// An InductionVar is a basic induction variable of a loop: an integer
+// φ-node of the loop header whose value is Init on entry to the loop
+// and is incremented (or decremented) by the loop-invariant Step on
+// each iteration.
+//
+// For example, in
+//
+//	for i := 0; i < n; i += 2 { ... }
+//
+// the φ-node for i has Init 0, Op token.ADD and Step 2.
+type InductionVar struct {
+	Phi    *Phi        // φ-node of the loop header
+	Init   Value       // value on entry to the loop
+	Op     token.Token // ADD or SUB
+	Step   Value       // loop-invariant increment
+	Update *BinOp      // Phi Op Step, the value on each back edge
+}
+
+// InductionVars returns the basic induction variables of loop l, in
+// the order of their φ-nodes.
+func (l *Loop) InductionVars() []*InductionVar {
+	var ivs []*InductionVar
+	for _, instr := range l.Header.Instrs {
+		phi, ok := instr.(*Phi)
+		if !ok {
+			break // φ-nodes come first
+		}
+		if iv := l.inductionVar(phi); iv != nil {
+			ivs = append(ivs, iv)
+		}
+	}
+	return ivs
+}
+
+// inductionVar returns the induction variable defined by phi, or nil.
+func (l *Loop) inductionVar(phi *Phi) *InductionVar {
+	if b, ok := phi.Type().Underlying().(*types.Basic); !ok || b.Info()&types.IsInteger == 0 {
+		return nil
+	}
+	iv := &InductionVar{Phi: phi}
+	for i, pred := range l.Header.Preds {
+		v := phi.Edges[i]
+		if !l.Contains(pred) {
+			// Entry edge: all must agree on the initial value.
+			if iv.Init != nil && iv.Init != v {
+				return nil
+			}
+			iv.Init = v
+			continue
+		}
+
+		// Back edge: all must be the same update of phi.
+		if iv.Update != nil {
+			if v != iv.Update {
+				return nil
+			}
+			continue
+		}
+		update, ok := v.(*BinOp)
+		if !ok || !l.Contains(update.Block()) {
+			return nil
+		}
+		switch {
+		case update.Op == token.ADD && update.X == phi && l.Invariant(update.Y):
+			iv.Step = update.Y
+		case update.Op == token.ADD && update.Y == phi && l.Invariant(update.X):
+			iv.Step = update.X
+		case update.Op == token.SUB && update.X == phi && l.Invariant(update.Y):
+			iv.Step = update.Y
+		default:
+			return nil
+		}
+		iv.Op = update.Op
+		iv.Update = update
+	}
+	if iv.Init == nil || iv.Update == nil {
+		return nil
+	}
+	return iv
+}
@@ -0,0 +1,116 @@
+// Copyright 2024 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package ssa_test
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"testing"
+
+	"golang.org/x/tools/go/ssa"
+)
+
+func TestLoops(t *testing.T) {
+	const input = `
+package p
+
+func nested(n int) (sum int) {
+	for i := 0; i < n; i++ {
+		for j := n; j > 0; j -= 2 {
+			sum += i * j
+		}
+	}
+	return
+}
+
+func exits(xs []int) int {
+	for i := 1; ; i = i + 1 {
+		if xs[i] == 0 {
+			break
+		}
+		if xs[i] < 0 {
+			return i
+		}
+	}
+	return -1
+}
+
+func goto_(n int) {
+loop:
+	n--
+	if n > 0 {
+		goto loop
+	}
+}
+
+func none(x int) int {
+	if x > 0 {
+		return 1
+	}
+	return 0
+}
+
+func notInduction(n int) {
+	for i := 1; i < n; i *= 2 {
+	}
+}
+`
+	pkg, _ := buildPackage(t, input, ssa.BuilderMode(0))
+
+	// describe summarizes the loop forest of a function.
+	describe := func(f *ssa.Function) string {
+		lf := f.Loops()
+		var lines []string
+		for _, l := range lf.All() {
+			line := fmt.Sprintf("%s depth=%d blocks=%v latches=%v exits=%v",
+				l, l.Depth, l.Blocks, l.Latches, l.Exits)
+			for _, b := range l.Blocks {
+				if lf.LoopOf(b) == nil || !l.Contains(b) {
+					t.Errorf("%s: inconsistent membership of block %s", f, b)
+				}
+			}
+			for _, iv := range l.InductionVars() {
+				line += fmt.Sprintf(" iv(%s %s %s)", iv.Init.Name(), iv.Op, iv.Step.Name())
+			}
+			lines = append(lines, line)
+		}
+		return strings.Join(lines, "\n")
+	}
+
+	for _, test := range []struct {
+		fn, want string
+	}{
+		{"nested", `loop@1 depth=1 blocks=[1 2 4 5 6] latches=[6] exits=[3]` +
+			` iv(0:int + 1:int)` + "\n" +
+			`loop@4 depth=2 blocks=[4 5] latches=[5] exits=[6]` +
+			` iv(n - 2:int)`},
+		{"exits", `loop@1 depth=1 blocks=[1 3 5] latches=[5] exits=[2 4]` +
+			` iv(1:int + 1:int)`},
+		{"goto_", `loop@1 depth=1 blocks=[1] latches=[1] exits=[2] iv(n - 1:int)`},
+		{"none", ``},
+		{"notInduction", `loop@1 depth=1 blocks=[1 2] latches=[2] exits=[3]`},
+	} {
+		f := pkg.Func(test.fn)
+		if got := describe(f); got != test.want {
+			var buf bytes.Buffer
+			ssa.WriteFunction(&buf, f)
+			t.Errorf("%s: got loops\n%s\nwant\n%s\n%s", test.fn, got, test.want, &buf)
+		}
+	}
+
+	// The nesting depth of a block is that of its innermost loop.
+	f := pkg.Func("nested")
+	lf := f.Loops()
+	for _, b := range f.Blocks {
+		want := 0
+		for l := lf.LoopOf(b); l != nil; l = l.Parent {
+			want++
+		}
+		if got := lf.Depth(b); got != want {
+			t.Errorf("Depth(%s) = %d, want %d", b, got, want)
+		}
+	}
+}
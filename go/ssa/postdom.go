@@ -0,0 +1,272 @@
+// Copyright 2024 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package ssa
+
+// This file defines post-dominance and control dependence.
+//
+// Block c post-dominates block b if every path from b to an exit of
+// the function passes through c. The exits are the blocks with no
+// successors, that is, those ending in Return or Panic. To give every
+// block a path to an exit, as the tree requires, one block of each
+// cycle from which no exit can be reached (an infinite loop) is
+// treated as an exit too.
+//
+// The tree is rooted at a virtual exit node that post-dominates every
+// block; the blocks it immediately post-dominates are the roots.
+//
+// The Recover block is entered only by a recovered panic, not by an
+// edge of the CFG. Like the dominator tree, post-dominance ignores the
+// implicit edges from calls to the Recover block, and the Recover
+// block is control dependent on no block.
+//
+// We use the algorithm of Cooper, Harvey and Kennedy, A Simple, Fast
+// Dominance Algorithm, 2001, on the reverse CFG.
+
+// A PostDomTree is the post-dominator tree of a function.
+type PostDomTree struct {
+	// Each slice is indexed by b.Index.
+	ipdom     []*BasicBlock   // immediate post-dominator; nil for a root
+	children  [][]*BasicBlock // blocks immediately post-dominated
+	pre, post []int32         // pre- and post-order numbering within tree
+
+	roots []*BasicBlock // blocks immediately post-dominated by the exit
+}
+
+// Ipdom returns the block that immediately post-dominates b: its
+// parent in the post-dominator tree, or nil if b is a root.
+func (t *PostDomTree) Ipdom(b *BasicBlock) *BasicBlock { return t.ipdom[b.Index] }
+
+// PostDominees returns the list of blocks that b immediately
+// post-dominates: its children in the post-dominator tree.
+func (t *PostDomTree) PostDominees(b *BasicBlock) []*BasicBlock { return t.children[b.Index] }
+
+// Roots returns the blocks immediately post-dominated by the virtual
+// exit node, in Index order. They include every exit.
+func (t *PostDomTree) Roots() []*BasicBlock { return t.roots }
+
+// PostDominates reports whether b post-dominates c.
+func (t *PostDomTree) PostDominates(b, c *BasicBlock) bool {
+	return t.pre[b.Index] <= t.pre[c.Index] && t.post[c.Index] <= t.post[b.Index]
+}
+
+// PostDomTree returns a new post-dominator tree for f, which must be
+// built.
+func (f *Function) PostDomTree() *PostDomTree {
+	n := len(f.Blocks)
+	exit := n // index of the virtual exit node
+
+	// The exits, real and pseudo.
+	isExit := make([]bool, n)
+	var exits []int
+	for _, b := range f.Blocks {
+		if len(b.Succs) == 0 {
+			isExit[b.Index] = true
+			exits = append(exits, b.Index)
+		}
+	}
+
+	// Number the nodes in postorder of a DFS of the reverse CFG
+	// from the exit, adding a pseudo-exit for each part of the
+	// graph not yet reached.
+	order := make([]int, n+1) // postorder number + 1, or 0 if unvisited
+	var post []int            // nodes in postorder
+	var visit func(x int)
+	visit = func(x int) {
+		order[x] = -1 // on stack
+		if x == exit {
+			for _, e := range exits {
+				if order[e] == 0 {
+					visit(e)
+				}
+			}
+		} else {
+			for _, p := range f.Blocks[x].Preds {
+				if order[p.Index] == 0 {
+					visit(p.Index)
+				}
+			}
+		}
+		post = append(post, x)
+		order[x] = len(post)
+	}
+	for {
+		post = post[:0]
+		for i := range order {
+			order[i] = 0
+		}
+		visit(exit)
+		if len(post) == n+1 {
+			break
+		}
+		e := inCycle(f, order)
+		isExit[e] = true
+		exits = append(exits, e)
+	}
+
+	// Compute immediate post-dominators.
+	idom := make([]int, n+1)
+	for i := range idom {
+		idom[i] = -1
+	}
+	idom[exit] = exit
+	intersect := func(x, y int) int {
+		for x != y {
+			for order[x] < order[y] {
+				x = idom[x]
+			}
+			for order[y] < order[x] {
+				y = idom[y]
+			}
+		}
+		return x
+	}
+	for changed := true; changed; {
+		changed = false
+		for i := len(post) - 2; i >= 0; i-- { // reverse postorder, after exit
+			x := post[i]
+			b := f.Blocks[x]
+			d := -1
+			meet := func(s int) {
+				if idom[s] < 0 {
+					return // not yet processed
+				}
+				if d < 0 {
+					d = s
+				} else {
+					d = intersect(s, d)
+				}
+			}
+			for _, s := range b.Succs {
+				meet(s.Index)
+			}
+			if isExit[x] {
+				meet(exit)
+			}
+			if idom[x] != d {
+				idom[x] = d
+				changed = true
+			}
+		}
+	}
+
+	t := &PostDomTree{
+		ipdom:    make([]*BasicBlock, n),
+		children: make([][]*BasicBlock, n),
+		pre:      make([]int32, n),
+		post:     make([]int32, n),
+	}
+	for _, b := range f.Blocks {
+		if d := idom[b.Index]; d == exit {
+			t.roots = append(t.roots, b)
+		} else {
+			t.ipdom[b.Index] = f.Blocks[d]
+			t.children[d] = append(t.children[d], b)
+		}
+	}
+	var pre, postn int32
+	var number func(b *BasicBlock)
+	number = func(b *BasicBlock) {
+		t.pre[b.Index] = pre
+		pre++
+		for _, child := range t.children[b.Index] {
+			number(child)
+		}
+		t.post[b.Index] = postn
+		postn++
+	}
+	for _, root := range t.roots {
+		number(root)
+	}
+	return t
+}
+
+// inCycle returns the index of a block, not yet visited according to
+// order, that lies on a cycle from which no exit is reachable.
+//
+// The first block to finish in a forward DFS of the unvisited blocks
+// has no successor that reaches an exit (or it would have been
+// visited) and no successor that has finished, so one of its
+// successors must be on the DFS stack.
+func inCycle(f *Function, order []int) int {
+	state := make([]int, len(f.Blocks)) // 0=new 1=on stack 2=done
+	found := -1
+	var dfs func(b *BasicBlock)
+	dfs = func(b *BasicBlock) {
+		state[b.Index] = 1
+		for _, s := range b.Succs {
+			if found < 0 && state[s.Index] == 0 && order[s.Index] == 0 {
+				dfs(s)
+			}
+		}
+		if found < 0 {
+			found = b.Index
+		}
+		state[b.Index] = 2
+	}
+	for _, b := range f.Blocks {
+		if order[b.Index] == 0 {
+			dfs(b)
+			break
+		}
+	}
+	return found
+}
+
+// ControlDependence holds the control dependences of a function's
+// blocks.
+//
+// Block b is control dependent on block a if a has a successor from
+// which every path to an exit passes through b, and another from
+// which some path avoids b: that is, the branch at the end of a
+// decides whether b is executed. A loop header is control dependent on
+// the branches that decide whether the loop repeats, possibly
+// including itself.
+type ControlDependence struct {
+	// Each slice is indexed by b.Index.
+	deps       [][]*BasicBlock // blocks on which b is control dependent
+	dependents [][]*BasicBlock // blocks control dependent on b
+}
+
+// Deps returns the blocks on which b is control dependent, in Index
+// order. It is empty for blocks executed on every path through the
+// function, such as its entry block.
+func (cd *ControlDependence) Deps(b *BasicBlock) []*BasicBlock { return cd.deps[b.Index] }
+
+// Dependents returns the blocks control dependent on a, in Index
+// order. It is empty unless a ends in an If or a Jump from a pseudo-exit.
+func (cd *ControlDependence) Dependents(a *BasicBlock) []*BasicBlock {
+	return cd.dependents[a.Index]
+}
+
+// ControlDependence returns the control dependences of the blocks of
+// f, which must be built.
+func (f *Function) ControlDependence() *ControlDependence {
+	t := f.PostDomTree()
+	n := len(f.Blocks)
+	cd := &ControlDependence{
+		deps:       make([][]*BasicBlock, n),
+		dependents: make([][]*BasicBlock, n),
+	}
+	seen := make([]*BasicBlock, n) // seen[b.Index] == a if b already depends on a
+	for _, a := range f.Blocks {
+		// For each edge a->s, the blocks from s up to but
+		// excluding ipdom(a) are control dependent on a.
+		stop := t.Ipdom(a)
+		for _, s := range a.Succs {
+			for b := s; b != nil && b != stop; b = t.Ipdom(b) {
+				if seen[b.Index] != a {
+					seen[b.Index] = a
+					cd.deps[b.Index] = append(cd.deps[b.Index], a)
+					cd.dependents[a.Index] = append(cd.dependents[a.Index], b)
+				}
+			}
+		}
+	}
+	for i := range cd.deps {
+		sortBlocks(cd.deps[i])
+		sortBlocks(cd.dependents[i])
+	}
+	return cd
+}
@@ -0,0 +1,194 @@
+// Copyright 2024 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package ssa_test
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"testing"
+
+	"golang.org/x/tools/go/ssa"
+)
+
+func TestPostDominators(t *testing.T) {
+	const input = `
+package p
+
+func ifelse(x int) int {
+	y := 0
+	if x > 0 {
+		y = 1
+	} else {
+		y = 2
+	}
+	return y
+}
+
+func exits(x int) int {
+	if x > 0 {
+		return 1
+	}
+	if x < 0 {
+		panic(x)
+	}
+	return 0
+}
+
+func loop(n int) (sum int) {
+	for i := 0; i < n; i++ {
+		if i%2 == 0 {
+			continue
+		}
+		sum += i
+	}
+	return
+}
+
+func forever(x int) {
+	if x > 0 {
+		for {
+			print(x)
+		}
+	}
+	print(0)
+}
+
+func recovers(x int) (err error) {
+	defer func() {
+		if recover() != nil {
+			err = nil
+		}
+	}()
+	if x > 0 {
+		panic(x)
+	}
+	return nil
+}
+`
+	pkg, _ := buildPackage(t, input, ssa.BuilderMode(0))
+
+	// describe prints the post-dominator tree and the control
+	// dependences of each block.
+	describe := func(f *ssa.Function) string {
+		pdt := f.PostDomTree()
+		cd := f.ControlDependence()
+		var lines []string
+		lines = append(lines, fmt.Sprintf("roots %v", pdt.Roots()))
+		for _, b := range f.Blocks {
+			line := fmt.Sprintf("%d: ipdom=%v deps=%v", b.Index, pdt.Ipdom(b), cd.Deps(b))
+			lines = append(lines, line)
+			for _, a := range cd.Deps(b) {
+				if !contains(cd.Dependents(a), b) {
+					t.Errorf("%s: block %s depends on %s but is not among its dependents", f, b, a)
+				}
+			}
+		}
+		return strings.Join(lines, "\n")
+	}
+
+	for _, test := range []struct {
+		fn, want string
+	}{
+		{"ifelse", `
+roots [2]
+0: ipdom=2 deps=[]
+1: ipdom=2 deps=[0]
+2: ipdom=<nil> deps=[]
+3: ipdom=2 deps=[0]`},
+		{"exits", `
+roots [0 1 2 3 4]
+0: ipdom=<nil> deps=[]
+1: ipdom=<nil> deps=[0]
+2: ipdom=<nil> deps=[0]
+3: ipdom=<nil> deps=[2]
+4: ipdom=<nil> deps=[2]`},
+		// The loop header, block 1, decides whether the loop repeats.
+		{"loop", `
+roots [4]
+0: ipdom=1 deps=[]
+1: ipdom=4 deps=[1]
+2: ipdom=3 deps=[1]
+3: ipdom=1 deps=[1]
+4: ipdom=<nil> deps=[]
+5: ipdom=3 deps=[2]`},
+		// The infinite loop, block 2, is a pseudo-exit.
+		{"forever", `
+roots [0 1 2]
+0: ipdom=<nil> deps=[]
+1: ipdom=<nil> deps=[0]
+2: ipdom=<nil> deps=[0 2]`},
+		// Block 1 is the Recover block.
+		{"recovers", `
+roots [0 1 2 3]
+0: ipdom=<nil> deps=[]
+1: ipdom=<nil> deps=[]
+2: ipdom=<nil> deps=[0]
+3: ipdom=<nil> deps=[0]`},
+	} {
+		f := pkg.Func(test.fn)
+		if got := describe(f); got != strings.TrimSpace(test.want) {
+			var buf bytes.Buffer
+			ssa.WriteFunction(&buf, f)
+			t.Errorf("%s: got\n%s\nwant\n%s\n%s", test.fn, got, strings.TrimSpace(test.want), &buf)
+		}
+		if test.fn != "forever" {
+			checkPostDominance(t, f)
+		}
+	}
+}
+
+// checkPostDominance checks the PostDominates relation of f, which
+// must have no infinite loops, against the relation computed by a
+// naive backward dataflow analysis.
+func checkPostDominance(t *testing.T, f *ssa.Function) {
+	n := len(f.Blocks)
+	// PD[i][j] reports whether block j post-dominates block i.
+	PD := make([][]bool, n)
+	for i, b := range f.Blocks {
+		PD[i] = make([]bool, n)
+		for j := range PD[i] {
+			PD[i][j] = len(b.Succs) > 0 || i == j
+		}
+	}
+	for changed := true; changed; {
+		changed = false
+		for i, b := range f.Blocks {
+			if len(b.Succs) == 0 {
+				continue
+			}
+			for j := range PD[i] {
+				x := i == j
+				if !x {
+					x = true
+					for _, s := range b.Succs {
+						x = x && PD[s.Index][j]
+					}
+				}
+				if PD[i][j] != x {
+					PD[i][j] = x
+					changed = true
+				}
+			}
+		}
+	}
+	pdt := f.PostDomTree()
+	for i, b := range f.Blocks {
+		for j, c := range f.Blocks {
+			if got := pdt.PostDominates(c, b); got != PD[i][j] {
+				t.Errorf("%s: PostDominates(%s, %s) = %t, want %t", f, c, b, got, PD[i][j])
+			}
+		}
+	}
+}
+
+func contains(blocks []*ssa.BasicBlock, b *ssa.BasicBlock) bool {
+	for _, x := range blocks {
+		if x == b {
+			return true
+		}
+	}
+	return false
+}
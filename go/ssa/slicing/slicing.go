@@ -0,0 +1,530 @@
+// Copyright 2024 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package slicing computes program slices over SSA form.
+//
+// The backward slice of a set of criteria (values and instructions) is
+// the set of values and instructions that may affect them: those on
+// which they depend, transitively. The forward slice is the set that
+// they may affect.
+//
+// An instruction depends on:
+//   - the values of its operands (data dependence);
+//   - the stores to memory that a load may read, approximated by the
+//     variable (Alloc or Global) from which the address of each was
+//     derived by field and element selections, or, if that variable is
+//     unknown, by the type of the address;
+//   - the If instructions whose branches decide whether it executes,
+//     or, for a φ-node, which of its edges is taken (control
+//     dependence; see [ssa.Function.ControlDependence]).
+//
+// The free variables of a closure depend on the values bound to them
+// by MakeClosure, and loads of captured variables on the stores to them
+// in the enclosing functions. If a call graph is provided, the slice
+// extends across calls: parameters depend on the arguments of each
+// call site, the results of a call depend on the Return instructions
+// of its callees, and loads of a global depend on the stores to it in
+// every function of the graph. Otherwise the slice stays within the
+// functions of the criteria and the closures they create. In both
+// cases, the effects of calls on memory are ignored.
+//
+// The slices are context-insensitive: a forward slice that enters a
+// function through one call site leaves it through the results of all
+// of them.
+package slicing // import "golang.org/x/tools/go/ssa/slicing"
+
+import (
+	"go/token"
+	"go/types"
+	"sort"
+
+	"golang.org/x/tools/go/callgraph"
+	"golang.org/x/tools/go/ssa"
+)
+
+// A Slice is a set of values and instructions.
+//
+// Its elements are instructions, Parameters, FreeVars and Globals;
+// constants and functions, which depend on nothing, are omitted.
+type Slice map[ssa.Node]bool
+
+// Nodes returns the elements of the slice, ordered by function and
+// position.
+func (s Slice) Nodes() []ssa.Node {
+	nodes := make([]ssa.Node, 0, len(s))
+	for n := range s {
+		nodes = append(nodes, n)
+	}
+	sort.Slice(nodes, func(i, j int) bool {
+		x, y := nodes[i], nodes[j]
+		if fx, fy := funcName(x), funcName(y); fx != fy {
+			return fx < fy
+		}
+		if px, py := x.Pos(), y.Pos(); px != py {
+			return px < py
+		}
+		return order(x) < order(y)
+	})
+	return nodes
+}
+
+func funcName(n ssa.Node) string {
+	if fn := parent(n); fn != nil {
+		return fn.String()
+	}
+	return ""
+}
+
+// order breaks ties between nodes at the same position: values
+// before instructions, and instructions in block order.
+func order(n ssa.Node) int {
+	if instr, ok := n.(ssa.Instruction); ok && instr.Block() != nil {
+		b := instr.Block()
+		for i, x := range b.Instrs {
+			if x == instr {
+				return (b.Index+1)<<16 + i
+			}
+		}
+	}
+	return 0
+}
+
+// parent returns the function of a node, or nil for a global.
+func parent(n ssa.Node) *ssa.Function {
+	switch n := n.(type) {
+	case ssa.Instruction:
+		return n.Parent()
+	case *ssa.Parameter:
+		return n.Parent()
+	case *ssa.FreeVar:
+		return n.Parent()
+	}
+	return nil
+}
+
+// Backward returns the backward slice of the criteria: the values and
+// instructions that may affect them. If cg is non-nil, the slice is
+// interprocedural.
+func Backward(criteria []ssa.Node, cg *callgraph.Graph) Slice {
+	s := newSlicer(cg)
+	return s.slice(criteria, s.deps)
+}
+
+// Forward returns the forward slice of the criteria: the values and
+// instructions that they may affect. If cg is non-nil, the slice is
+// interprocedural.
+func Forward(criteria []ssa.Node, cg *callgraph.Graph) Slice {
+	s := newSlicer(cg)
+	return s.slice(criteria, s.dependents)
+}
+
+// At returns the criteria within the source interval [start, end) of
+// the specified functions: the instructions whose position lies within
+// it, and, for the DebugRefs among them, the values they refer to.
+// The DebugRefs themselves are omitted.
+func At(funcs []*ssa.Function, start, end token.Pos) []ssa.Node {
+	var nodes []ssa.Node
+	within := func(pos token.Pos) bool { return pos.IsValid() && start <= pos && pos < end }
+	for _, fn := range funcs {
+		for _, p := range fn.Params {
+			if within(p.Pos()) {
+				nodes = append(nodes, p)
+			}
+		}
+		for _, b := range fn.Blocks {
+			for _, instr := range b.Instrs {
+				if ref, ok := instr.(*ssa.DebugRef); ok {
+					if within(ref.Pos()) {
+						if n := node(ref.X); n != nil {
+							nodes = append(nodes, n)
+						}
+					}
+				} else if within(instr.Pos()) {
+					nodes = append(nodes, instr.(ssa.Node))
+				}
+			}
+		}
+	}
+	return nodes
+}
+
+// node returns the slice element for value v, or nil if v depends on
+// nothing.
+func node(v ssa.Value) ssa.Node {
+	switch v.(type) {
+	case *ssa.Const, *ssa.Function, *ssa.Builtin:
+		return nil
+	}
+	return v.(ssa.Node)
+}
+
+type slicer struct {
+	cg    *callgraph.Graph
+	funcs map[*ssa.Function]*funcInfo
+
+	// Stores to and loads from each global, in all functions of cg.
+	globalStores map[*ssa.Global][]*ssa.Store
+	globalLoads  map[*ssa.Global][]*ssa.UnOp
+}
+
+// funcInfo holds the dependence information of one function.
+type funcInfo struct {
+	cd     *ssa.ControlDependence
+	stores []*ssa.Store
+	loads  []*ssa.UnOp
+}
+
+func newSlicer(cg *callgraph.Graph) *slicer {
+	s := &slicer{cg: cg, funcs: make(map[*ssa.Function]*funcInfo)}
+	if cg != nil {
+		s.globalStores = make(map[*ssa.Global][]*ssa.Store)
+		s.globalLoads = make(map[*ssa.Global][]*ssa.UnOp)
+		for fn := range cg.Nodes {
+			if fn == nil {
+				continue
+			}
+			info := s.info(fn)
+			for _, store := range info.stores {
+				if g, ok := root(store.Addr).(*ssa.Global); ok {
+					s.globalStores[g] = append(s.globalStores[g], store)
+				}
+			}
+			for _, load := range info.loads {
+				if g, ok := root(load.X).(*ssa.Global); ok {
+					s.globalLoads[g] = append(s.globalLoads[g], load)
+				}
+			}
+		}
+	}
+	return s
+}
+
+// slice returns the transitive closure of the criteria under edges.
+func (s *slicer) slice(criteria []ssa.Node, edges func(ssa.Node, func(ssa.Node))) Slice {
+	res := make(Slice)
+	var work []ssa.Node
+	add := func(n ssa.Node) {
+		if n != nil && !res[n] {
+			res[n] = true
+			work = append(work, n)
+		}
+	}
+	for _, n := range criteria {
+		add(n)
+	}
+	for len(work) > 0 {
+		n := work[len(work)-1]
+		work = work[:len(work)-1]
+		edges(n, add)
+	}
+	return res
+}
+
+// info returns the dependence information of fn, computing it on first use.
+func (s *slicer) info(fn *ssa.Function) *funcInfo {
+	info, ok := s.funcs[fn]
+	if !ok {
+		info = new(funcInfo)
+		if len(fn.Blocks) > 0 {
+			info.cd = fn.ControlDependence()
+		}
+		for _, b := range fn.Blocks {
+			for _, instr := range b.Instrs {
+				switch instr := instr.(type) {
+				case *ssa.Store:
+					info.stores = append(info.stores, instr)
+				case *ssa.UnOp:
+					if instr.Op == token.MUL {
+						info.loads = append(info.loads, instr)
+					}
+				}
+			}
+		}
+		s.funcs[fn] = info
+	}
+	return info
+}
+
+// deps calls add for each node on which n depends.
+func (s *slicer) deps(n ssa.Node, add func(ssa.Node)) {
+	switch n := n.(type) {
+	case *ssa.Parameter:
+		if s.cg == nil {
+			break
+		}
+		fn := n.Parent()
+		i := paramIndex(n)
+		if cgn := s.cg.Nodes[fn]; cgn != nil {
+			for _, e := range cgn.In {
+				if arg := argument(e.Site.Common(), i); arg != nil {
+					add(node(arg))
+				}
+			}
+		}
+
+	case *ssa.FreeVar:
+		fn := n.Parent()
+		i := freeVarIndex(n)
+		forEachClosure(fn, func(mc *ssa.MakeClosure) {
+			add(node(mc.Bindings[i]))
+		})
+
+	case *ssa.Global:
+		for _, store := range s.globalStores[n] {
+			add(store)
+		}
+
+	case ssa.Instruction:
+		fn := n.Parent()
+		info := s.info(fn)
+		for _, op := range n.Operands(nil) {
+			if *op != nil {
+				add(node(*op))
+			}
+		}
+
+		// Memory.
+		if load, ok := n.(*ssa.UnOp); ok && load.Op == token.MUL {
+			for _, store := range info.stores {
+				if mayAlias(store.Addr, load.X) {
+					add(store)
+				}
+			}
+			if fv, ok := root(load.X).(*ssa.FreeVar); ok {
+				s.capturedStores(fv, add)
+			}
+		}
+
+		// Control.
+		b := n.Block()
+		s.controlDeps(info, b, add)
+		if _, ok := n.(*ssa.Phi); ok {
+			for _, pred := range b.Preds {
+				s.controlDeps(info, pred, add)
+				if cond, ok := pred.Instrs[len(pred.Instrs)-1].(*ssa.If); ok {
+					add(cond)
+				}
+			}
+		}
+
+		// Results of calls.
+		if call, ok := n.(*ssa.Call); ok && s.cg != nil {
+			if cgn := s.cg.Nodes[fn]; cgn != nil {
+				for _, e := range cgn.Out {
+					if e.Site == call {
+						forEachReturn(e.Callee.Func, func(ret *ssa.Return) { add(ret) })
+					}
+				}
+			}
+		}
+	}
+}
+
+// capturedStores adds the stores to the variable captured by free
+// variable fv in the enclosing functions.
+func (s *slicer) capturedStores(fv *ssa.FreeVar, add func(ssa.Node)) {
+	i := freeVarIndex(fv)
+	forEachClosure(fv.Parent(), func(mc *ssa.MakeClosure) {
+		v := root(mc.Bindings[i])
+		for _, store := range s.info(mc.Parent()).stores {
+			if root(store.Addr) == v {
+				add(store)
+			}
+		}
+		if fv, ok := v.(*ssa.FreeVar); ok {
+			s.capturedStores(fv, add)
+		}
+	})
+}
+
+// controlDeps adds the If instructions on which block b is control dependent.
+func (s *slicer) controlDeps(info *funcInfo, b *ssa.BasicBlock, add func(ssa.Node)) {
+	for _, a := range info.cd.Deps(b) {
+		add(a.Instrs[len(a.Instrs)-1].(ssa.Node))
+	}
+}
+
+// dependents calls add for each node that depends on n.
+func (s *slicer) dependents(n ssa.Node, add func(ssa.Node)) {
+	if v, ok := n.(ssa.Value); ok {
+		if refs := v.Referrers(); refs != nil {
+			for _, ref := range *refs {
+				switch ref := ref.(type) {
+				case *ssa.DebugRef:
+					continue
+				case *ssa.MakeClosure:
+					// The free variables bound to v.
+					for i, b := range ref.Bindings {
+						if b == v {
+							add(ref.Fn.(*ssa.Function).FreeVars[i])
+						}
+					}
+				case ssa.CallInstruction:
+					// The parameters bound to v.
+					if cgn := s.cgNode(ref.Parent()); cgn != nil {
+						for _, e := range cgn.Out {
+							if e.Site != ref {
+								continue
+							}
+							for i, p := range e.Callee.Func.Params {
+								if argument(ref.Common(), i) == v {
+									add(p)
+								}
+							}
+						}
+					}
+				}
+				add(ref.(ssa.Node))
+			}
+		}
+	}
+
+	instr, ok := n.(ssa.Instruction)
+	if !ok {
+		return
+	}
+	fn := instr.Parent()
+	info := s.info(fn)
+	switch instr := instr.(type) {
+	case *ssa.Store:
+		for _, load := range info.loads {
+			if mayAlias(instr.Addr, load.X) {
+				add(load)
+			}
+		}
+		if g, ok := root(instr.Addr).(*ssa.Global); ok {
+			for _, load := range s.globalLoads[g] {
+				add(load)
+			}
+		}
+
+	case *ssa.If:
+		a := instr.Block()
+		for _, b := range info.cd.Dependents(a) {
+			for _, x := range b.Instrs {
+				add(x.(ssa.Node))
+			}
+		}
+		for _, succ := range a.Succs {
+			for _, x := range succ.Instrs {
+				phi, ok := x.(*ssa.Phi)
+				if !ok {
+					break
+				}
+				add(phi)
+			}
+		}
+
+	case *ssa.Return:
+		if cgn := s.cgNode(fn); cgn != nil {
+			for _, e := range cgn.In {
+				if call, ok := e.Site.(*ssa.Call); ok {
+					add(call)
+				}
+			}
+		}
+	}
+}
+
+func (s *slicer) cgNode(fn *ssa.Function) *callgraph.Node {
+	if s.cg == nil {
+		return nil
+	}
+	return s.cg.Nodes[fn]
+}
+
+// argument returns the argument of call that is bound to parameter i
+// of the callee, or nil.
+func argument(call *ssa.CallCommon, i int) ssa.Value {
+	args := call.Args
+	if call.IsInvoke() {
+		// The receiver is call.Value.
+		if i == 0 {
+			return call.Value
+		}
+		i--
+	}
+	if i < len(args) {
+		return args[i]
+	}
+	return nil
+}
+
+func paramIndex(p *ssa.Parameter) int {
+	for i, x := range p.Parent().Params {
+		if x == p {
+			return i
+		}
+	}
+	return -1
+}
+
+func freeVarIndex(fv *ssa.FreeVar) int {
+	for i, x := range fv.Parent().FreeVars {
+		if x == fv {
+			return i
+		}
+	}
+	return -1
+}
+
+// forEachClosure calls f for each MakeClosure of the anonymous
+// function fn in its enclosing function.
+func forEachClosure(fn *ssa.Function, f func(*ssa.MakeClosure)) {
+	if fn.Parent() == nil {
+		return
+	}
+	for _, b := range fn.Parent().Blocks {
+		for _, instr := range b.Instrs {
+			if mc, ok := instr.(*ssa.MakeClosure); ok && mc.Fn == fn {
+				f(mc)
+			}
+		}
+	}
+}
+
+func forEachReturn(fn *ssa.Function, f func(*ssa.Return)) {
+	for _, b := range fn.Blocks {
+		if ret, ok := b.Instrs[len(b.Instrs)-1].(*ssa.Return); ok {
+			f(ret)
+		}
+	}
+}
+
+// root returns the value from which address addr was derived by field
+// and element selections.
+func root(addr ssa.Value) ssa.Value {
+	for {
+		switch x := addr.(type) {
+		case *ssa.FieldAddr:
+			addr = x.X
+		case *ssa.IndexAddr:
+			if _, ok := x.X.Type().Underlying().(*types.Slice); ok {
+				return addr // elements of a slice are not part of a variable
+			}
+			addr = x.X
+		default:
+			return addr
+		}
+	}
+}
+
+// mayAlias reports whether addresses x and y may refer to the same
+// memory.
+func mayAlias(x, y ssa.Value) bool {
+	rx, ry := root(x), root(y)
+	if isVariable(rx) && isVariable(ry) {
+		return rx == ry
+	}
+	return types.Identical(x.Type(), y.Type())
+}
+
+// isVariable reports whether v is the address of a variable.
+func isVariable(v ssa.Value) bool {
+	switch v.(type) {
+	case *ssa.Alloc, *ssa.Global:
+		return true
+	}
+	return false
+}
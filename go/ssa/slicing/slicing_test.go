@@ -0,0 +1,139 @@
+// Copyright 2024 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package slicing_test
+
+import (
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"go/types"
+	"sort"
+	"testing"
+
+	"golang.org/x/tools/go/callgraph"
+	"golang.org/x/tools/go/callgraph/static"
+	"golang.org/x/tools/go/ssa"
+	"golang.org/x/tools/go/ssa/slicing"
+	"golang.org/x/tools/go/ssa/ssautil"
+)
+
+const src = `package p
+
+var g int
+
+func f(a, b int) int {
+	x := a + 1
+	y := b * 2
+	if x > 10 {
+		y = 0
+	}
+	z := x + y
+	g = y
+	return z
+}
+
+func h() int {
+	return f(1, 2)
+}
+
+func k(n int) {
+	m := f(n, 3)
+	print(m)
+}
+
+func l() {
+	print(g)
+}
+
+func c(a int) func() int {
+	b := a * 2
+	return func() int { return b + 1 }
+}
+`
+
+func TestSlice(t *testing.T) {
+	fset := token.NewFileSet()
+	f, err := parser.ParseFile(fset, "p.go", src, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pkg, _, err := ssautil.BuildPackage(&types.Config{}, fset, types.NewPackage("p", ""), []*ast.File{f}, ssa.GlobalDebug|ssa.SanityCheckFunctions)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var funcs []*ssa.Function
+	for fn := range ssautil.AllFunctions(pkg.Prog) {
+		if fn.Pkg == pkg {
+			funcs = append(funcs, fn)
+		}
+	}
+	cg := static.CallGraph(pkg.Prog)
+	file := fset.File(f.Pos())
+
+	for _, test := range []struct {
+		line    int  // line of the criteria
+		forward bool // compute a forward slice
+		cg      *callgraph.Graph
+		want    string // lines of the slice
+	}{
+		// Within f: z depends on a and b and on the condition, but
+		// not on the store to g. (The constant assigned at line 9
+		// depends on nothing.)
+		{13, false, nil, "[5 6 7 8 11 13]"},
+		// The criteria of a line include the variables it refers to.
+		{7, true, nil, "[5 7 11 12 13]"},
+		// The result of a call depends on the callee only if a
+		// call graph is provided, and then only on the arguments
+		// of this call site.
+		{22, false, nil, "[20 21 22]"},
+		{22, false, cg, "[5 6 7 8 11 13 20 21 22]"},
+		// Forward slices are context-insensitive: the result of
+		// f reaches its call in h too.
+		{21, true, nil, "[20 21 22]"},
+		{21, true, cg, "[5 6 7 8 9 11 12 13 17 20 21 22 26]"},
+		// Loads of a global depend on the stores in other functions.
+		{26, false, nil, "[3 26]"},
+		{26, false, cg, "[3 5 6 7 8 12 20 26]"},
+		// Free variables depend on the values bound to them, and
+		// loads of captured variables on the stores to them.
+		{31, false, nil, "[29 30 31]"},
+		{30, true, nil, "[29 30 31]"},
+	} {
+		start := file.LineStart(test.line)
+		end := file.LineStart(test.line + 1)
+		criteria := slicing.At(funcs, start, end)
+		if len(criteria) == 0 {
+			t.Errorf("no criteria at line %d", test.line)
+			continue
+		}
+		var s slicing.Slice
+		if test.forward {
+			s = slicing.Forward(criteria, test.cg)
+		} else {
+			s = slicing.Backward(criteria, test.cg)
+		}
+		if got := lines(fset, s); got != test.want {
+			t.Errorf("slice (line %d, forward=%t, cg=%t) = %s, want %s",
+				test.line, test.forward, test.cg != nil, got, test.want)
+		}
+	}
+}
+
+// lines returns the set of lines of the nodes of s that have a position.
+func lines(fset *token.FileSet, s slicing.Slice) string {
+	set := make(map[int]bool)
+	for _, n := range s.Nodes() {
+		if pos := n.Pos(); pos.IsValid() {
+			set[fset.Position(pos).Line] = true
+		}
+	}
+	var lines []int
+	for line := range set {
+		lines = append(lines, line)
+	}
+	sort.Ints(lines)
+	return fmt.Sprint(lines)
+}
@@ -63,6 +63,7 @@ type Package struct {
 	init    *Function               // Func("init"); the package's init function
 	debug   bool                    // include full debug info in this package
 	syntax  bool                    // package was loaded from syntax
+	text    bool                    // package was read by Program.ReadText
 
 	// The following fields are set transiently, then cleared
 	// after building.
@@ -0,0 +1,695 @@
+// Copyright 2024 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package ssa
+
+// This file defines the textual form of a package's SSA code, written
+// by Package.WriteText and read by Program.ReadText (see textread.go).
+//
+// Unlike the output of WriteFunction, which is meant for people, the
+// textual form is meant to be read back: it records every value and
+// instruction, and refers to types, functions and globals
+// unambiguously, so that the reader can reconstruct an equivalent
+// package in another Program created from the same type information
+// (for example, from export data).
+//
+// The text is a sequence of lines, each a sequence of tokens separated
+// by spaces; a token is a word or a Go quoted string. It consists of:
+//
+//   - a header:
+//
+//	ssa 1
+//	package "example.com/p"
+//
+//   - a table of the types used by the package, in which each type
+//     refers only to types defined before it, except that the
+//     underlying types of local named types are defined last:
+//
+//	type T0 basic "int"
+//	type T1 pointer T0
+//	type T2 named "example.com/p" "S"     (an object path)
+//	type T3 func false 1 "x" T1 1 "" T0   (variadic, params, results)
+//	underlying T4 T5
+//
+//   - a table of the global variables used by the package:
+//
+//	global g0 "example.com/p" "counter"
+//
+//   - a table of the functions used by the package, such as
+//     declared functions, anonymous functions, generic instances,
+//     and synthetic wrappers:
+//
+//	func f0 decl "example.com/p" "T.M0"   (an object path)
+//	func f1 anon f0 0 "M$1" T3 "Synthetic" "p.go:3:9"
+//	func f2 instance f0 1 T0
+//
+//   - the bodies of the functions of the package and the generic
+//     instances it uses:
+//
+//	body f0
+//	params "x" "y"
+//	block 0 "entry" preds succs 1 2
+//	t0 = binop T0 "+" p0 p1 ""
+//	if t0 ""
+//	...
+//	end
+//
+// Operands are written as tN (a register), pN (a parameter), vN (a
+// free variable), gN (a global), fN (a function), "const T ..." (a
+// constant), "builtin name T" (a built-in) or _ (no value). The last
+// token of each instruction is its position, as "file:line:col", or ""
+// if it has none.
+//
+// DebugRef instructions are not recorded.
+
+import (
+	"bytes"
+	"fmt"
+	"go/constant"
+	"go/token"
+	"go/types"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+
+	"golang.org/x/tools/go/types/objectpath"
+	"golang.org/x/tools/go/types/typeutil"
+)
+
+// textVersion is the version of the textual form.
+const textVersion = 1
+
+// WriteText writes the textual form of the SSA code of package p to w.
+// The package must have been built. The text can be read back by
+// [Program.ReadText].
+//
+// The text contains the bodies of all functions of the package,
+// including anonymous functions, the package initializer, and the
+// instances of generic functions used by the package that were built
+// from syntax. Synthetic wrappers and functions of other packages are
+// recorded only by reference.
+//
+// WriteText returns an error if the package refers to a function or
+// type that cannot be referenced by the textual form, such as a
+// function created by [Program.NewFunction].
+func (p *Package) WriteText(w io.Writer) (err error) {
+	tw := &textWriter{
+		pkg:     p,
+		funcIDs: make(map[*Function]int),
+		globals: make(map[*Global]int),
+		locals:  make(map[*types.TypeName]int),
+		opaques: make(map[string]string),
+		owned:   make(map[*Function]bool),
+	}
+	defer func() {
+		if x := recover(); x != nil {
+			if e, ok := x.(textWriteError); ok {
+				err = e
+				return
+			}
+			panic(x)
+		}
+	}()
+
+	// Enumerate the functions of the package: its members, and the
+	// methods of its named types, in a deterministic order.
+	var fns []*Function
+	names := make([]string, 0, len(p.Members))
+	for name := range p.Members {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		switch mem := p.Members[name].(type) {
+		case *Function:
+			fns = append(fns, mem)
+		case *Type:
+			if named, ok := types.Unalias(mem.Type()).(*types.Named); ok {
+				for i := 0; i < named.NumMethods(); i++ {
+					if fn := p.Prog.FuncValue(named.Method(i)); fn != nil {
+						fns = append(fns, fn)
+					}
+				}
+			}
+		}
+	}
+	for _, fn := range fns {
+		tw.own(fn)
+	}
+	// Writing bodies may discover more owned functions (instances).
+	var bodies bytes.Buffer
+	for i := 0; i < len(tw.ownedList); i++ {
+		tw.body(&bodies, tw.ownedList[i])
+	}
+
+	var out bytes.Buffer
+	fmt.Fprintf(&out, "ssa %d\n", textVersion)
+	fmt.Fprintf(&out, "package %s\n", strconv.Quote(p.Pkg.Path()))
+	out.Write(tw.typeText.Bytes())
+	for _, line := range tw.underlying {
+		out.WriteString(line)
+	}
+	out.Write(tw.globalText.Bytes())
+	out.Write(tw.funcText.Bytes())
+	out.Write(bodies.Bytes())
+	_, err = w.Write(out.Bytes())
+	return err
+}
+
+type textWriteError struct{ error }
+
+type textWriter struct {
+	pkg *Package
+	enc objectpath.Encoder
+
+	typeIDs    typeutil.Map            // type -> index in type table
+	ntypes     int                     // number of types in type table
+	locals     map[*types.TypeName]int // local named types -> index
+	opaques    map[string]string       // opaque types -> name
+	typeText   bytes.Buffer            // type table
+	underlying []string                // underlying types of local named types
+
+	globals    map[*Global]int
+	globalText bytes.Buffer
+
+	funcIDs  map[*Function]int
+	funcText bytes.Buffer
+
+	owned     map[*Function]bool // functions whose bodies are written
+	ownedList []*Function
+}
+
+func (tw *textWriter) errorf(format string, args ...any) {
+	panic(textWriteError{fmt.Errorf("ssa.WriteText: "+format, args...)})
+}
+
+// own records that fn's body (and those of its anonymous functions)
+// are to be written.
+func (tw *textWriter) own(fn *Function) {
+	if tw.owned[fn] {
+		return
+	}
+	tw.owned[fn] = true
+	tw.ownedList = append(tw.ownedList, fn)
+	tw.function(fn)
+	for _, anon := range fn.AnonFuncs {
+		tw.own(anon)
+	}
+}
+
+// pos returns the token for a position.
+func (tw *textWriter) pos(pos token.Pos) string {
+	if !pos.IsValid() {
+		return `""`
+	}
+	posn := tw.pkg.Prog.Fset.Position(pos)
+	return strconv.Quote(fmt.Sprintf("%s:%d:%d", posn.Filename, posn.Line, posn.Column))
+}
+
+// -- types --
+
+// typ returns the name of type t in the type table, adding it if necessary.
+func (tw *textWriter) typ(t types.Type) string {
+	// Opaque types are not supported by typeutil.Map.
+	switch t {
+	case tRangeIter:
+		return tw.opaque("iter")
+	case tDeferStack:
+		return tw.opaque("deferstack")
+	}
+	t = types.Unalias(t)
+	if named, ok := t.(*types.Named); ok && isLocalType(named.Obj()) {
+		return tw.localType(named)
+	}
+	if id, ok := tw.typeIDs.At(t).(int); ok {
+		return fmt.Sprintf("T%d", id)
+	}
+
+	var def string
+	switch t := t.(type) {
+	case *types.Basic:
+		def = "basic " + strconv.Quote(t.Name())
+		if t.Kind() == types.UnsafePointer {
+			def = `basic "unsafe.Pointer"`
+		}
+	case *types.Pointer:
+		def = "pointer " + tw.typ(t.Elem())
+	case *types.Slice:
+		def = "slice " + tw.typ(t.Elem())
+	case *types.Array:
+		def = fmt.Sprintf("array %d %s", t.Len(), tw.typ(t.Elem()))
+	case *types.Map:
+		def = fmt.Sprintf("map %s %s", tw.typ(t.Key()), tw.typ(t.Elem()))
+	case *types.Chan:
+		def = fmt.Sprintf("chan %d %s", t.Dir(), tw.typ(t.Elem()))
+	case *types.Named:
+		if targs := t.TypeArgs(); targs.Len() > 0 {
+			var buf strings.Builder
+			fmt.Fprintf(&buf, "inst %s %d", tw.typ(t.Origin()), targs.Len())
+			for i := 0; i < targs.Len(); i++ {
+				buf.WriteString(" " + tw.typ(targs.At(i)))
+			}
+			def = buf.String()
+		} else if t.Obj().Pkg() == nil {
+			def = "universe " + strconv.Quote(t.Obj().Name())
+		} else {
+			def = "named " + tw.object(t.Obj())
+		}
+	case *types.TypeParam:
+		def = "typeparam " + tw.typeParam(t)
+	case *types.Struct:
+		var buf strings.Builder
+		fmt.Fprintf(&buf, "struct %d", t.NumFields())
+		for i := 0; i < t.NumFields(); i++ {
+			f := t.Field(i)
+			fmt.Fprintf(&buf, " %s %s %s %t %s",
+				strconv.Quote(f.Name()), tw.pkgpath(f), tw.typ(f.Type()), f.Embedded(), strconv.Quote(t.Tag(i)))
+		}
+		def = buf.String()
+	case *types.Tuple:
+		var buf strings.Builder
+		fmt.Fprintf(&buf, "tuple %d", t.Len())
+		for i := 0; i < t.Len(); i++ {
+			v := t.At(i)
+			fmt.Fprintf(&buf, " %s %s", strconv.Quote(v.Name()), tw.typ(v.Type()))
+		}
+		def = buf.String()
+	case *types.Signature:
+		if t.Recv() != nil || t.TypeParams().Len() > 0 {
+			tw.errorf("cannot encode type %s", t)
+		}
+		var buf strings.Builder
+		fmt.Fprintf(&buf, "func %t", t.Variadic())
+		for _, tuple := range []*types.Tuple{t.Params(), t.Results()} {
+			fmt.Fprintf(&buf, " %d", tuple.Len())
+			for i := 0; i < tuple.Len(); i++ {
+				v := tuple.At(i)
+				fmt.Fprintf(&buf, " %s %s", strconv.Quote(v.Name()), tw.typ(v.Type()))
+			}
+		}
+		def = buf.String()
+	case *types.Interface:
+		var buf strings.Builder
+		fmt.Fprintf(&buf, "interface %d", t.NumExplicitMethods())
+		for i := 0; i < t.NumExplicitMethods(); i++ {
+			m := t.ExplicitMethod(i)
+			sig := m.Type().(*types.Signature)
+			sig = types.NewSignatureType(nil, nil, nil, sig.Params(), sig.Results(), sig.Variadic())
+			fmt.Fprintf(&buf, " %s %s %s", strconv.Quote(m.Name()), tw.pkgpath(m), tw.typ(sig))
+		}
+		fmt.Fprintf(&buf, " %d", t.NumEmbeddeds())
+		for i := 0; i < t.NumEmbeddeds(); i++ {
+			buf.WriteString(" " + tw.typ(t.EmbeddedType(i)))
+		}
+		def = buf.String()
+	default:
+		tw.errorf("cannot encode type %s (%T)", t, t)
+	}
+	return tw.define(t, def)
+}
+
+// define adds a type definition to the table.
+func (tw *textWriter) define(t types.Type, def string) string {
+	id := tw.ntypes
+	tw.ntypes++
+	if t != nil {
+		tw.typeIDs.Set(t, id)
+	}
+	fmt.Fprintf(&tw.typeText, "type T%d %s\n", id, def)
+	return fmt.Sprintf("T%d", id)
+}
+
+func (tw *textWriter) opaque(name string) string {
+	if id, ok := tw.opaques[name]; ok {
+		return id
+	}
+	id := tw.define(nil, "opaque "+name)
+	tw.opaques[name] = id
+	return id
+}
+
+// isLocalType reports whether obj is a type declared within a function.
+// (A type declared within a function of a package read by ReadText has
+// no parent scope.)
+func isLocalType(obj *types.TypeName) bool {
+	return obj.Pkg() != nil && obj.Pkg().Scope().Lookup(obj.Name()) != obj
+}
+
+// localType defines a named type declared within a function.
+// Its underlying type is defined at the end of the table,
+// as it may refer to the named type itself.
+func (tw *textWriter) localType(t *types.Named) string {
+	obj := t.Obj()
+	if id, ok := tw.locals[obj]; ok {
+		return fmt.Sprintf("T%d", id)
+	}
+	if t.TypeArgs().Len() > 0 || t.NumMethods() > 0 {
+		tw.errorf("cannot encode local type %s", t)
+	}
+	id := tw.ntypes
+	tw.locals[obj] = id
+	tw.define(nil, fmt.Sprintf("local %s %s", strconv.Quote(obj.Pkg().Path()), strconv.Quote(obj.Name())))
+	tw.underlying = append(tw.underlying, fmt.Sprintf("underlying T%d %s\n", id, tw.typ(t.Underlying())))
+	return fmt.Sprintf("T%d", id)
+}
+
+// object returns the tokens for a package-level object or an object
+// reachable from one: its package path and object path. (The path of
+// a package-level object is its name, even if it is not exported.)
+func (tw *textWriter) object(obj types.Object) string {
+	path := objectpath.Path(obj.Name())
+	if obj.Pkg().Scope().Lookup(obj.Name()) != obj {
+		var err error
+		path, err = tw.enc.For(obj)
+		if err != nil {
+			tw.errorf("cannot encode %s: %v", obj, err)
+		}
+	}
+	return strconv.Quote(obj.Pkg().Path()) + " " + strconv.Quote(string(path))
+}
+
+// typeParam returns the tokens for a type parameter: an object path,
+// or, for a type parameter of a non-exported function, which has no
+// object path, the function's name and the parameter's index, as in
+// "fn" "p" "f" 0.
+func (tw *textWriter) typeParam(t *types.TypeParam) string {
+	obj := t.Obj()
+	if path, err := tw.enc.For(obj); err == nil {
+		return strconv.Quote(obj.Pkg().Path()) + " " + strconv.Quote(string(path))
+	}
+	scope := obj.Pkg().Scope()
+	for _, name := range scope.Names() {
+		if fn, ok := scope.Lookup(name).(*types.Func); ok {
+			tparams := fn.Type().(*types.Signature).TypeParams()
+			for i := 0; i < tparams.Len(); i++ {
+				if tparams.At(i) == t {
+					return fmt.Sprintf(`"fn" %s %s %d`, strconv.Quote(obj.Pkg().Path()), strconv.Quote(name), i)
+				}
+			}
+		}
+	}
+	tw.errorf("cannot encode type parameter %s", t)
+	return ""
+}
+
+// pkgpath returns the quoted package path of an unexported field or method, or "".
+func (tw *textWriter) pkgpath(obj types.Object) string {
+	if obj.Exported() || obj.Pkg() == nil {
+		return `""`
+	}
+	return strconv.Quote(obj.Pkg().Path())
+}
+
+// -- functions and globals --
+
+// global returns the name of g in the globals table.
+func (tw *textWriter) global(g *Global) string {
+	id, ok := tw.globals[g]
+	if !ok {
+		id = len(tw.globals)
+		tw.globals[g] = id
+		fmt.Fprintf(&tw.globalText, "global g%d %s %s\n", id, strconv.Quote(g.Pkg.Pkg.Path()), strconv.Quote(g.Name()))
+	}
+	return fmt.Sprintf("g%d", id)
+}
+
+// function returns the name of fn in the functions table.
+func (tw *textWriter) function(fn *Function) string {
+	if id, ok := tw.funcIDs[fn]; ok {
+		return fmt.Sprintf("f%d", id)
+	}
+
+	var def string
+	switch {
+	case fn.parent != nil:
+		parent := tw.function(fn.parent)
+		tw.own(fn.parent)
+		def = fmt.Sprintf("anon %s %d %s %s %s %s", parent, fn.anonIdx,
+			strconv.Quote(fn.name), tw.typ(fn.Signature), strconv.Quote(fn.Synthetic), tw.pos(fn.pos))
+
+	case fn.topLevelOrigin != nil:
+		var buf strings.Builder
+		fmt.Fprintf(&buf, "instance %s %d", tw.function(fn.topLevelOrigin), len(fn.typeargs))
+		for _, targ := range fn.typeargs {
+			buf.WriteString(" " + tw.typ(targ))
+		}
+		fmt.Fprintf(&buf, " %s", strconv.Quote(fn.Synthetic))
+		def = buf.String()
+
+	case fn.method != nil:
+		kind := "wrapper"
+		if fn.method.kind == types.MethodExpr {
+			kind = "thunk"
+		}
+		def = fmt.Sprintf("%s %s %s %s", kind, tw.typ(fn.method.recv), tw.pkgpath(fn.method.obj), strconv.Quote(fn.method.obj.Name()))
+
+	case strings.HasPrefix(fn.Synthetic, "bound method wrapper"):
+		def = fmt.Sprintf("bound %s %s %s", tw.typ(recvType(fn.object)), tw.pkgpath(fn.object), strconv.Quote(fn.object.Name()))
+
+	case fn.Synthetic == "package initializer":
+		def = "pkginit " + strconv.Quote(fn.Pkg.Pkg.Path())
+
+	case strings.HasPrefix(fn.name, "init#") && fn.Pkg != nil:
+		def = fmt.Sprintf("init %s %s %s", strconv.Quote(fn.Pkg.Pkg.Path()), strconv.Quote(fn.name), tw.pos(fn.pos))
+
+	case fn.object != nil:
+		def = "decl " + tw.object(fn.object)
+
+	default:
+		tw.errorf("cannot encode function %s (%s)", fn, fn.Synthetic)
+	}
+
+	id := len(tw.funcIDs)
+	tw.funcIDs[fn] = id
+	fmt.Fprintf(&tw.funcText, "func f%d %s\n", id, def)
+
+	// Instances built from syntax are written too.
+	if fn.topLevelOrigin != nil && strings.HasPrefix(fn.Synthetic, "instance of") && fn.Blocks != nil {
+		tw.own(fn)
+	}
+	return fmt.Sprintf("f%d", id)
+}
+
+// -- bodies --
+
+// body writes the body of function fn.
+func (tw *textWriter) body(buf *bytes.Buffer, fn *Function) {
+	if fn.build != nil {
+		tw.errorf("function %s is not built", fn)
+	}
+	fmt.Fprintf(buf, "body %s\n", tw.function(fn))
+	if fn.Blocks == nil {
+		fmt.Fprintf(buf, "end\n")
+		return
+	}
+
+	params := make(map[*Parameter]int)
+	buf.WriteString("params")
+	for i, p := range fn.Params {
+		params[p] = i
+		buf.WriteString(" " + strconv.Quote(p.name))
+	}
+	buf.WriteString("\n")
+	freevars := make(map[*FreeVar]int)
+	for i, fv := range fn.FreeVars {
+		freevars[fv] = i
+		fmt.Fprintf(buf, "freevar %s %s %s\n", strconv.Quote(fv.name), tw.typ(fv.typ), tw.pos(fv.pos))
+	}
+	if len(fn.Locals) > 0 {
+		buf.WriteString("locals")
+		for _, l := range fn.Locals {
+			buf.WriteString(" " + l.Name())
+		}
+		buf.WriteString("\n")
+	}
+	if fn.Recover != nil {
+		fmt.Fprintf(buf, "recover %d\n", fn.Recover.Index)
+	}
+
+	operand := func(v Value) string {
+		switch v := v.(type) {
+		case nil:
+			return "_"
+		case *Parameter:
+			if i, ok := params[v]; ok {
+				return fmt.Sprintf("p%d", i)
+			}
+		case *FreeVar:
+			if i, ok := freevars[v]; ok {
+				return fmt.Sprintf("v%d", i)
+			}
+		case *Global:
+			return tw.global(v)
+		case *Function:
+			return tw.function(v)
+		case *Const:
+			return tw.constant(v)
+		case *Builtin:
+			return fmt.Sprintf("builtin %s %s", strconv.Quote(v.name), tw.typ(v.sig))
+		case Instruction:
+			if v.Parent() == fn {
+				return v.(Value).Name()
+			}
+		}
+		tw.errorf("in %s: invalid operand %s", fn, v)
+		return ""
+	}
+	operands := func(vs []Value) string {
+		var buf strings.Builder
+		fmt.Fprintf(&buf, "%d", len(vs))
+		for _, v := range vs {
+			buf.WriteString(" " + operand(v))
+		}
+		return buf.String()
+	}
+	call := func(c *CallCommon) string {
+		if c.IsInvoke() {
+			return fmt.Sprintf("invoke %s %s %s %s %s", operand(c.Value), tw.pkgpath(c.Method), strconv.Quote(c.Method.Name()), operands(c.Args), tw.pos(c.pos))
+		}
+		return fmt.Sprintf("call %s %s %s", operand(c.Value), operands(c.Args), tw.pos(c.pos))
+	}
+
+	for _, b := range fn.Blocks {
+		fmt.Fprintf(buf, "block %d %s preds", b.Index, strconv.Quote(b.Comment))
+		for _, pred := range b.Preds {
+			fmt.Fprintf(buf, " %d", pred.Index)
+		}
+		buf.WriteString(" succs")
+		for _, succ := range b.Succs {
+			fmt.Fprintf(buf, " %d", succ.Index)
+		}
+		buf.WriteString("\n")
+
+		for _, instr := range b.Instrs {
+			var args string
+			switch instr := instr.(type) {
+			case *DebugRef:
+				continue
+			case *Alloc:
+				kind := "local"
+				if instr.Heap {
+					kind = "heap"
+				}
+				args = fmt.Sprintf("alloc %s %s", kind, strconv.Quote(instr.Comment))
+			case *Phi:
+				args = fmt.Sprintf("phi %s %s", strconv.Quote(instr.Comment), operands(instr.Edges))
+			case *Call:
+				args = "call " + call(&instr.Call)
+			case *BinOp:
+				args = fmt.Sprintf("binop %s %s %s", strconv.Quote(instr.Op.String()), operand(instr.X), operand(instr.Y))
+			case *UnOp:
+				args = fmt.Sprintf("unop %s %s %t", strconv.Quote(instr.Op.String()), operand(instr.X), instr.CommaOk)
+			case *ChangeType:
+				args = "changetype " + operand(instr.X)
+			case *Convert:
+				args = "convert " + operand(instr.X)
+			case *MultiConvert:
+				args = "multiconvert " + operand(instr.X)
+			case *ChangeInterface:
+				args = "changeinterface " + operand(instr.X)
+			case *SliceToArrayPointer:
+				args = "slicetoarrayptr " + operand(instr.X)
+			case *MakeInterface:
+				args = "makeinterface " + operand(instr.X)
+			case *MakeClosure:
+				args = fmt.Sprintf("makeclosure %s %s", operand(instr.Fn), operands(instr.Bindings))
+			case *MakeMap:
+				args = "makemap " + operand(instr.Reserve)
+			case *MakeChan:
+				args = "makechan " + operand(instr.Size)
+			case *MakeSlice:
+				args = fmt.Sprintf("makeslice %s %s", operand(instr.Len), operand(instr.Cap))
+			case *Slice:
+				args = fmt.Sprintf("slice %s %s %s %s", operand(instr.X), operand(instr.Low), operand(instr.High), operand(instr.Max))
+			case *FieldAddr:
+				args = fmt.Sprintf("fieldaddr %s %d", operand(instr.X), instr.Field)
+			case *Field:
+				args = fmt.Sprintf("field %s %d", operand(instr.X), instr.Field)
+			case *IndexAddr:
+				args = fmt.Sprintf("indexaddr %s %s", operand(instr.X), operand(instr.Index))
+			case *Index:
+				args = fmt.Sprintf("index %s %s", operand(instr.X), operand(instr.Index))
+			case *Lookup:
+				args = fmt.Sprintf("lookup %s %s %t", operand(instr.X), operand(instr.Index), instr.CommaOk)
+			case *Select:
+				var sb strings.Builder
+				fmt.Fprintf(&sb, "select %t %d", instr.Blocking, len(instr.States))
+				for _, st := range instr.States {
+					if st.Dir == types.SendOnly {
+						fmt.Fprintf(&sb, " send %s %s %s", operand(st.Chan), operand(st.Send), tw.pos(st.Pos))
+					} else {
+						fmt.Fprintf(&sb, " recv %s %s", operand(st.Chan), tw.pos(st.Pos))
+					}
+				}
+				args = sb.String()
+			case *Range:
+				args = "range " + operand(instr.X)
+			case *Next:
+				args = fmt.Sprintf("next %s %t", operand(instr.Iter), instr.IsString)
+			case *TypeAssert:
+				args = fmt.Sprintf("typeassert %s %s %t", operand(instr.X), tw.typ(instr.AssertedType), instr.CommaOk)
+			case *Extract:
+				args = fmt.Sprintf("extract %s %d", operand(instr.Tuple), instr.Index)
+			case *Jump:
+				args = "jump"
+			case *If:
+				args = "if " + operand(instr.Cond)
+			case *Return:
+				args = "return " + operands(instr.Results)
+			case *RunDefers:
+				args = "rundefers"
+			case *Panic:
+				args = "panic " + operand(instr.X)
+			case *Go:
+				args = "go " + call(&instr.Call)
+			case *Defer:
+				args = fmt.Sprintf("defer %s %s", call(&instr.Call), operand(instr.DeferStack))
+			case *Send:
+				args = fmt.Sprintf("send %s %s", operand(instr.Chan), operand(instr.X))
+			case *Store:
+				args = fmt.Sprintf("store %s %s", operand(instr.Addr), operand(instr.Val))
+			case *MapUpdate:
+				args = fmt.Sprintf("mapupdate %s %s %s", operand(instr.Map), operand(instr.Key), operand(instr.Value))
+			default:
+				tw.errorf("in %s: unexpected instruction %T", fn, instr)
+			}
+
+			// Insert the register name and type after the opcode.
+			if v, ok := instr.(Value); ok {
+				op, rest, _ := strings.Cut(args, " ")
+				args = fmt.Sprintf("%s = %s %s %s", v.Name(), op, tw.typ(v.Type()), rest)
+			}
+			fmt.Fprintf(buf, "%s %s\n", strings.TrimSpace(args), tw.pos(instr.Pos()))
+		}
+	}
+	buf.WriteString("end\n")
+}
+
+// constant returns the tokens for a constant.
+func (tw *textWriter) constant(c *Const) string {
+	typ := tw.typ(c.typ)
+	if c.Value == nil {
+		return fmt.Sprintf("const %s nil", typ)
+	}
+	rat := func(x constant.Value) string {
+		return fmt.Sprintf("%s %s",
+			strconv.Quote(constant.Num(x).ExactString()),
+			strconv.Quote(constant.Denom(x).ExactString()))
+	}
+	switch v := c.Value; v.Kind() {
+	case constant.Bool:
+		return fmt.Sprintf("const %s bool %t", typ, constant.BoolVal(v))
+	case constant.String:
+		return fmt.Sprintf("const %s string %s", typ, strconv.Quote(constant.StringVal(v)))
+	case constant.Int:
+		return fmt.Sprintf("const %s int %s", typ, strconv.Quote(v.ExactString()))
+	case constant.Float:
+		return fmt.Sprintf("const %s float %s", typ, rat(v))
+	case constant.Complex:
+		return fmt.Sprintf("const %s complex %s %s", typ, rat(constant.Real(v)), rat(constant.Imag(v)))
+	}
+	tw.errorf("cannot encode constant %s", c)
+	return ""
+}
@@ -0,0 +1,350 @@
+// Copyright 2024 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package ssa_test
+
+import (
+	"bytes"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"go/types"
+	"sort"
+	"strings"
+	"testing"
+
+	"golang.org/x/tools/go/gcexportdata"
+	"golang.org/x/tools/go/ssa"
+	"golang.org/x/tools/go/ssa/ssautil"
+)
+
+const textSrc = `package p
+
+type I interface{ M() int }
+
+type T struct {
+	x int
+	s []string
+}
+
+func (t T) M() int   { return t.x }
+func (t *T) Inc()    { t.x++ }
+func (t T) Len() int { return len(t.s) }
+
+type Pair[K comparable, V any] struct {
+	k K
+	v V
+}
+
+func (p *Pair[K, V]) Key() K { return p.k }
+
+func Map[S ~[]E, E, R any](s S, f func(E) R) []R {
+	var r []R
+	for _, e := range s {
+		r = append(r, f(e))
+	}
+	return r
+}
+
+var counter int
+
+const big = 1 << 70
+
+func init() { counter = 1 }
+
+func closures(n int) func() int {
+	return func() int {
+		counter += n
+		return counter
+	}
+}
+
+func methods(t T, i I) (int, int) {
+	f := t.M
+	g := (*T).Inc
+	h := I.M
+	g(&t)
+	return f() + h(i), i.M()
+}
+
+func control(m map[string]int, ch chan int, s string) (res int) {
+	defer func() {
+		if recover() != nil {
+			res = -1
+		}
+	}()
+	for k, v := range m {
+		res += len(k) + v
+	}
+	for i, r := range s {
+		res += i + int(r)
+	}
+	select {
+	case x := <-ch:
+		res += x
+	case ch <- 1:
+	default:
+	}
+	if v, ok := m["x"]; ok {
+		res *= v
+	}
+	go func() { ch <- 2 }()
+	return
+}
+
+func conversions(x any, f float64, c complex128) (int, bool) {
+	type local struct{ a, b int }
+	l := local{1, 2}
+	s, ok := x.(fmtStringer)
+	_ = s
+	arr := [3]int{1, 2, 3}
+	p := (*[2]int)([]int{1, 2})
+	return l.a + arr[1] + p[0] + int(f*2.5) + int(real(c+1i)), ok
+}
+
+type fmtStringer interface{ String() string }
+
+func generic() int {
+	p := &Pair[string, int]{"a", 1}
+	r := Map([]int{1, 2}, func(i int) string { return p.Key() })
+	return len(r) + big/(1<<68)
+}
+`
+
+// TestTextRoundTrip checks that a package read from its textual form
+// is equivalent to the original.
+func TestTextRoundTrip(t *testing.T) {
+	for _, mode := range []ssa.BuilderMode{0, ssa.InstantiateGenerics} {
+		fset := token.NewFileSet()
+		f, err := parser.ParseFile(fset, "p.go", textSrc, 0)
+		if err != nil {
+			t.Fatal(err)
+		}
+		pkg := types.NewPackage("p", "")
+		mode |= ssa.SanityCheckFunctions
+		orig, _, err := ssautil.BuildPackage(&types.Config{}, fset, pkg, []*ast.File{f}, mode)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var text bytes.Buffer
+		if err := orig.WriteText(&text); err != nil {
+			t.Fatal(err)
+		}
+
+		prog := ssa.NewProgram(fset, mode)
+		read, err := prog.ReadText(bytes.NewReader(text.Bytes()), pkg)
+		if err != nil {
+			t.Fatalf("ReadText: %v\n%s", err, text.String())
+		}
+		read.Build() // no effect
+
+		// The text of the package read must be the same.
+		var text2 bytes.Buffer
+		if err := read.WriteText(&text2); err != nil {
+			t.Fatal(err)
+		}
+		if text.String() != text2.String() {
+			t.Errorf("mode %v: text differs after round trip:\n%s\n----\n%s", mode, text.String(), text2.String())
+		}
+
+		// So must the printed functions.
+		if got, want := printFuncs(read), printFuncs(orig); got != want {
+			t.Errorf("mode %v: functions differ after round trip:\n%s\n----\n%s", mode, got, want)
+		}
+
+		// The package cannot be read twice.
+		if _, err := prog.ReadText(bytes.NewReader(text.Bytes()), pkg); err == nil {
+			t.Errorf("second ReadText succeeded")
+		}
+	}
+}
+
+// textExportSrc is a package all of whose declarations are exported,
+// so that they are all present in its export data.
+const textExportSrc = `package p
+
+type Shape interface{ Area() float64 }
+
+type Rect struct{ W, H float64 }
+
+func (r Rect) Area() float64 { return r.W * r.H }
+
+type List[T any] struct {
+	Elems []T
+}
+
+func (l *List[T]) Push(x T) { l.Elems = append(l.Elems, x) }
+
+func Sum(shapes []Shape) (total float64) {
+	defer func() {
+		if recover() != nil {
+			total = -1
+		}
+	}()
+	for _, s := range shapes {
+		total += s.Area()
+	}
+	return
+}
+
+func Counter() func() int {
+	type state struct{ n int }
+	s := &state{}
+	return func() int { s.n++; return s.n }
+}
+
+func Rects(n int) *List[Shape] {
+	l := new(List[Shape])
+	for i := 0; i < n; i++ {
+		l.Push(Rect{float64(i), 2})
+	}
+	return l
+}
+`
+
+// TestTextExportData checks that the textual form of a package can be
+// read into a separate program, against the package loaded from its
+// export data.
+func TestTextExportData(t *testing.T) {
+	for _, mode := range []ssa.BuilderMode{0, ssa.InstantiateGenerics} {
+		fset := token.NewFileSet()
+		f, err := parser.ParseFile(fset, "p.go", textExportSrc, 0)
+		if err != nil {
+			t.Fatal(err)
+		}
+		pkg := types.NewPackage("p", "")
+		orig, _, err := ssautil.BuildPackage(&types.Config{}, fset, pkg, []*ast.File{f}, mode)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var text, export bytes.Buffer
+		if err := orig.WriteText(&text); err != nil {
+			t.Fatal(err)
+		}
+		if err := gcexportdata.Write(&export, fset, pkg); err != nil {
+			t.Fatal(err)
+		}
+
+		// Read the text into a program with its own file set,
+		// against the types of the package from its export data.
+		fset2 := token.NewFileSet()
+		pkg2, err := gcexportdata.Read(&export, fset2, make(map[string]*types.Package), "p")
+		if err != nil {
+			t.Fatal(err)
+		}
+		prog := ssa.NewProgram(fset2, mode|ssa.SanityCheckFunctions)
+		read, err := prog.ReadText(bytes.NewReader(text.Bytes()), pkg2)
+		if err != nil {
+			t.Fatalf("ReadText: %v\n%s", err, text.String())
+		}
+		if got, want := printFuncs(read), printFuncs(orig); got != want {
+			t.Errorf("mode %v: functions differ after reading against export data:\n%s\n----\n%s", mode, got, want)
+		}
+
+		// The functions read refer to the objects of pkg2.
+		for name, mem := range read.Members {
+			if fn, ok := mem.(*ssa.Function); ok && fn.Object() != nil {
+				if obj := pkg2.Scope().Lookup(name); fn.Object() != obj {
+					t.Errorf("mode %v: function %s has object %v, want %v", mode, name, fn.Object(), obj)
+				}
+			}
+		}
+	}
+}
+
+// printFuncs returns the printed functions of the members of p and
+// their anonymous functions.
+func printFuncs(p *ssa.Package) string {
+	var names []string
+	for name := range p.Members {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	var buf bytes.Buffer
+	var print func(fn *ssa.Function)
+	print = func(fn *ssa.Function) {
+		// Omit the provenance of functions, which is not recorded.
+		var fbuf bytes.Buffer
+		ssa.WriteFunction(&fbuf, fn)
+		for _, line := range strings.Split(fbuf.String(), "\n") {
+			if !strings.HasPrefix(line, "# Synthetic") && !strings.HasPrefix(line, "# Location") {
+				buf.WriteString(line + "\n")
+			}
+		}
+		for _, anon := range fn.AnonFuncs {
+			print(anon)
+		}
+	}
+	for _, name := range names {
+		if fn, ok := p.Members[name].(*ssa.Function); ok {
+			print(fn)
+		}
+	}
+	return buf.String()
+}
+
+func TestReadTextErrors(t *testing.T) {
+	for _, test := range []struct{ text, want string }{
+		{"ssa 2\npackage \"p\"\n", "line 1: unsupported version 2"},
+		{"ssa 1\npackage \"q\"\n", `line 2: text is for package "q", not "p"`},
+		{"ssa 1\npackage \"p\"\ntype T0 basic \"integer\"\n", "line 3: unknown basic type integer"},
+		{"ssa 1\npackage \"p\"\ntype T1 basic \"int\"\n", "line 3: got T1, want T0"},
+		{"ssa 1\npackage \"p\"\nfunc f0 decl \"p\" \"F\" extra\n", `line 3: unexpected "extra"`},
+		{"ssa 1\npackage \"p\"\nglobal g0 \"p\" \"x\n", "line 3: unterminated string"},
+	} {
+		pkg := types.NewPackage("p", "p")
+		pkg.Scope().Insert(types.NewFunc(token.NoPos, pkg, "F", types.NewSignatureType(nil, nil, nil, nil, nil, false)))
+		prog := ssa.NewProgram(token.NewFileSet(), 0)
+		_, err := prog.ReadText(strings.NewReader(test.text), pkg)
+		if err == nil || !strings.Contains(err.Error(), test.want) {
+			t.Errorf("ReadText(%q) = %v, want error containing %q", test.text, err, test.want)
+		}
+	}
+}
+
+// TestReadTextMalformed checks that ReadText reports an error, rather
+// than panicking, when lines of a valid text are missing.
+func TestReadTextMalformed(t *testing.T) {
+	fset := token.NewFileSet()
+	f, err := parser.ParseFile(fset, "p.go", textSrc, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pkg := types.NewPackage("p", "")
+	orig, _, err := ssautil.BuildPackage(&types.Config{}, fset, pkg, []*ast.File{f}, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var text bytes.Buffer
+	if err := orig.WriteText(&text); err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(text.String(), "\n")
+
+	read := func(lines []string) (err error) {
+		defer func() {
+			if x := recover(); x != nil {
+				t.Errorf("ReadText panicked: %v\n%s", x, strings.Join(lines, "\n"))
+			}
+		}()
+		prog := ssa.NewProgram(fset, ssa.SanityCheckFunctions)
+		_, err = prog.ReadText(strings.NewReader(strings.Join(lines, "\n")), pkg)
+		return err
+	}
+
+	// Deleting a recover line leaves a block without predecessors.
+	for i, line := range lines {
+		if strings.HasPrefix(line, "recover ") {
+			err := read(append(lines[:i:i], lines[i+1:]...))
+			if err == nil || !strings.Contains(err.Error(), "no predecessors") {
+				t.Errorf("ReadText without %q: got %v, want error about a block with no predecessors", line, err)
+			}
+		}
+	}
+
+	// Deleting any other line must not cause a panic.
+	for i := range lines {
+		read(append(lines[:i:i], lines[i+1:]...))
+	}
+}
@@ -0,0 +1,1263 @@
+// Copyright 2024 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package ssa
+
+// This file defines Program.ReadText, the reader for the textual form
+// written by Package.WriteText (see text.go).
+
+import (
+	"bytes"
+	"fmt"
+	"go/constant"
+	"go/token"
+	"go/types"
+	"io"
+	"math/big"
+	"strconv"
+	"strings"
+
+	"golang.org/x/tools/go/types/objectpath"
+)
+
+// ReadText reads the textual form of the SSA code of a package, as
+// written by [Package.WriteText], and creates the package in prog.
+// pkg is the type-checked package, which must be the package (or
+// have been loaded from the export data of the package) from which
+// the text was written. Export data must include every package-level
+// declaration to which the text refers: export data written by
+// gcexportdata.Write omits unexported declarations not reachable from
+// exported ones. prog must have been created with the same mode as
+// the program that wrote the text.
+//
+// Packages on which pkg depends that do not yet exist in prog are
+// created from their type information, as if by
+// prog.CreatePackage(dep, nil, nil, true), so packages should be read
+// in dependency order.
+//
+// The resulting package is built: its functions have bodies, and
+// Package.Build has no effect. Its functions have no syntax.
+//
+// ReadText returns an error if the text is malformed, including if
+// the functions it describes do not satisfy the invariants of
+// functions built from syntax.
+func (prog *Program) ReadText(r io.Reader, pkg *types.Package) (_ *Package, err error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	if prog.packages[pkg] != nil {
+		return nil, fmt.Errorf("ssa.ReadText: package %s already exists", pkg.Path())
+	}
+
+	tr := &textReader{
+		prog:   prog,
+		pkgs:   make(map[string]*types.Package),
+		files:  make(map[string]*token.File),
+		bodied: make(map[string]bool),
+		skip:   make(map[*Function]bool),
+	}
+	defer func() {
+		if x := recover(); x != nil {
+			if e, ok := x.(textReadError); ok {
+				err = e
+				return
+			}
+			panic(x)
+		}
+	}()
+	for i, line := range bytes.Split(data, []byte("\n")) {
+		if len(bytes.TrimSpace(line)) == 0 {
+			continue
+		}
+		tr.lines = append(tr.lines, textLine{num: i + 1, toks: tr.split(i+1, string(line))})
+	}
+	tr.read(pkg)
+	return tr.pkg, nil
+}
+
+type textReadError struct{ error }
+
+// A textLine is a line of the text, split into tokens.
+type textLine struct {
+	num  int // line number
+	toks []string
+}
+
+type textReader struct {
+	prog  *Program
+	pkg   *Package                  // package being read
+	pkgs  map[string]*types.Package // pkg and its dependencies, by path
+	files map[string]*token.File    // files of prog.Fset, by name
+	b     builder                   // builds synthetic functions
+
+	lines []textLine
+	next  int      // index of the next line
+	line  int      // number of the current line
+	toks  []string // remaining tokens of the current line
+
+	types   []types.Type
+	globals []*Global
+	funcs   []*Function
+	bodied  map[string]bool    // names of functions that have bodies
+	skip    map[*Function]bool // functions whose bodies are ignored
+	built   []*Function        // functions whose bodies were read
+}
+
+func (r *textReader) errorf(format string, args ...any) {
+	panic(textReadError{fmt.Errorf("ssa.ReadText: line %d: "+format, append([]any{r.line}, args...)...)})
+}
+
+// split splits a line into words and quoted strings.
+func (r *textReader) split(num int, line string) []string {
+	var toks []string
+	for i := 0; i < len(line); {
+		if line[i] == ' ' {
+			i++
+			continue
+		}
+		j := i
+		if line[i] == '"' {
+			for j++; j < len(line) && line[j] != '"'; j++ {
+				if line[j] == '\\' {
+					j++
+				}
+			}
+			if j >= len(line) {
+				r.line = num
+				r.errorf("unterminated string")
+			}
+			j++
+		} else {
+			for j < len(line) && line[j] != ' ' {
+				j++
+			}
+		}
+		toks = append(toks, line[i:j])
+		i = j
+	}
+	return toks
+}
+
+// nextLine advances to the next line, reporting whether there is one.
+func (r *textReader) nextLine() bool {
+	if r.next >= len(r.lines) {
+		return false
+	}
+	l := r.lines[r.next]
+	r.next++
+	r.line, r.toks = l.num, l.toks
+	return true
+}
+
+// word returns the next token of the current line.
+func (r *textReader) word() string {
+	if len(r.toks) == 0 {
+		r.errorf("unexpected end of line")
+	}
+	tok := r.toks[0]
+	r.toks = r.toks[1:]
+	return tok
+}
+
+// expect consumes the next token, which must be want.
+func (r *textReader) expect(want string) {
+	if tok := r.word(); tok != want {
+		r.errorf("got %q, want %q", tok, want)
+	}
+}
+
+// end checks that the current line has no more tokens.
+func (r *textReader) end() {
+	if len(r.toks) > 0 {
+		r.errorf("unexpected %q", r.toks[0])
+	}
+}
+
+func (r *textReader) str() string {
+	tok := r.word()
+	s, err := strconv.Unquote(tok)
+	if err != nil || !strings.HasPrefix(tok, `"`) {
+		r.errorf("invalid string %s", tok)
+	}
+	return s
+}
+
+func (r *textReader) int() int {
+	tok := r.word()
+	i, err := strconv.Atoi(tok)
+	if err != nil {
+		r.errorf("invalid integer %s", tok)
+	}
+	return i
+}
+
+func (r *textReader) bool() bool {
+	tok := r.word()
+	b, err := strconv.ParseBool(tok)
+	if err != nil {
+		r.errorf("invalid boolean %s", tok)
+	}
+	return b
+}
+
+// entry parses the name of the next entry, of the form prefix%d,
+// of a table of length n.
+func (r *textReader) entry(prefix string, n int) {
+	if tok := r.word(); tok != fmt.Sprintf("%s%d", prefix, n) {
+		r.errorf("got %s, want %s%d", tok, prefix, n)
+	}
+}
+
+// ref parses a reference of the form prefix%d to an element of a
+// table of length n.
+func (r *textReader) ref(prefix string, n int) int {
+	tok := r.word()
+	i, err := strconv.Atoi(strings.TrimPrefix(tok, prefix))
+	if err != nil || !strings.HasPrefix(tok, prefix) || i < 0 || i >= n {
+		r.errorf("invalid reference %s", tok)
+	}
+	return i
+}
+
+func (r *textReader) typ() types.Type {
+	return r.types[r.ref("T", len(r.types))]
+}
+
+func (r *textReader) function() *Function {
+	return r.funcs[r.ref("f", len(r.funcs))]
+}
+
+// pos parses a position token. Positions in files unknown to
+// prog.Fset are discarded.
+func (r *textReader) pos() token.Pos {
+	s := r.str()
+	if s == "" {
+		return token.NoPos
+	}
+	i := strings.LastIndexByte(s, ':')
+	j := strings.LastIndexByte(s[:max(i, 0)], ':')
+	if j < 0 {
+		r.errorf("invalid position %q", s)
+	}
+	line, err1 := strconv.Atoi(s[j+1 : i])
+	col, err2 := strconv.Atoi(s[i+1:])
+	if err1 != nil || err2 != nil {
+		r.errorf("invalid position %q", s)
+	}
+	f := r.files[s[:j]]
+	if f == nil || line < 1 || line > f.LineCount() {
+		return token.NoPos
+	}
+	pos := f.LineStart(line) + token.Pos(col-1)
+	if int(pos)-f.Base() > f.Size() {
+		return token.NoPos
+	}
+	return pos
+}
+
+// typesPackage returns the package with the given path, or, for the
+// empty path, the package being read.
+func (r *textReader) typesPackage(path string) *types.Package {
+	if path == "" {
+		return r.pkg.Pkg
+	}
+	p := r.pkgs[path]
+	if p == nil {
+		r.errorf("unknown package %q", path)
+	}
+	return p
+}
+
+func (r *textReader) object() types.Object {
+	p := r.typesPackage(r.str())
+	path := r.str()
+	obj, err := objectpath.Object(p, objectpath.Path(path))
+	if err != nil {
+		r.errorf("%v", err)
+	}
+	return obj
+}
+
+// read reads the text, creating the package for pkg.
+func (r *textReader) read(pkg *types.Package) {
+	prog := r.prog
+
+	r.nextLine()
+	r.expect("ssa")
+	if v := r.int(); v != textVersion {
+		r.errorf("unsupported version %d", v)
+	}
+	r.nextLine()
+	r.expect("package")
+	if path := r.str(); path != pkg.Path() {
+		r.errorf("text is for package %q, not %q", path, pkg.Path())
+	}
+
+	// Create the packages on which pkg depends.
+	var visit func(p *types.Package)
+	visit = func(p *types.Package) {
+		if r.pkgs[p.Path()] == nil {
+			r.pkgs[p.Path()] = p
+			for _, imp := range p.Imports() {
+				visit(imp)
+			}
+			if p != pkg && prog.packages[p] == nil {
+				prog.CreatePackage(p, nil, nil, true)
+			}
+		}
+	}
+	visit(pkg)
+	r.pkg = prog.CreatePackage(pkg, nil, nil, true)
+	r.pkg.text = true
+
+	prog.Fset.Iterate(func(f *token.File) bool {
+		r.files[f.Name()] = f
+		return true
+	})
+	for _, l := range r.lines {
+		if len(l.toks) == 2 && l.toks[0] == "body" {
+			r.bodied[l.toks[1]] = true
+		}
+	}
+
+	for r.nextLine() {
+		switch op := r.word(); op {
+		case "type":
+			r.readType()
+		case "underlying":
+			named, ok := r.typ().(*types.Named)
+			if !ok || named.Underlying() != nil {
+				r.errorf("invalid local type")
+			}
+			named.SetUnderlying(r.typ().Underlying())
+		case "global":
+			r.entry("g", len(r.globals))
+			p := prog.packages[r.typesPackage(r.str())]
+			name := r.str()
+			g, ok := p.Members[name].(*Global)
+			if !ok {
+				r.errorf("no global %s.%s", p.Pkg.Path(), name)
+			}
+			r.globals = append(r.globals, g)
+		case "func":
+			r.readFunc()
+		case "body":
+			r.readBody()
+		default:
+			r.errorf("unexpected %q", op)
+		}
+		r.end()
+	}
+
+	// Complete the functions whose bodies were read.
+	for _, fn := range r.built {
+		buildReferrers(fn)
+		buildDomTree(fn)
+	}
+	for _, fn := range r.built {
+		var report strings.Builder
+		if !sanityCheck(fn, &report) {
+			panic(textReadError{fmt.Errorf("ssa.ReadText: invalid function %s:\n%s", fn, report.String())})
+		}
+	}
+	for _, fn := range r.built {
+		if fn.parent == nil {
+			fn.done()
+		}
+	}
+
+	// Build the remaining functions of the package.
+	for _, fn := range r.pkg.created {
+		r.b.enqueue(fn)
+	}
+	r.b.iterate()
+
+	r.pkg.created = nil
+	r.pkg.initVersion = nil
+	if prog.mode&SanityCheckFunctions != 0 {
+		sanityCheckPackage(r.pkg)
+	}
+}
+
+// basicTypes maps the names of basic types to the types.
+var basicTypes = func() map[string]types.Type {
+	m := map[string]types.Type{
+		"byte":           types.Universe.Lookup("byte").Type(),
+		"rune":           types.Universe.Lookup("rune").Type(),
+		"unsafe.Pointer": types.Typ[types.UnsafePointer],
+	}
+	for _, t := range types.Typ {
+		if _, ok := m[t.Name()]; !ok {
+			m[t.Name()] = t
+		}
+	}
+	return m
+}()
+
+// readType reads an entry of the type table.
+func (r *textReader) readType() {
+	r.entry("T", len(r.types))
+	var t types.Type
+	switch kind := r.word(); kind {
+	case "basic":
+		name := r.str()
+		t = basicTypes[name]
+		if t == nil {
+			r.errorf("unknown basic type %s", name)
+		}
+	case "pointer":
+		t = types.NewPointer(r.typ())
+	case "slice":
+		t = types.NewSlice(r.typ())
+	case "array":
+		n := r.int()
+		t = types.NewArray(r.typ(), int64(n))
+	case "map":
+		k := r.typ()
+		t = types.NewMap(k, r.typ())
+	case "chan":
+		dir := types.ChanDir(r.int())
+		t = types.NewChan(dir, r.typ())
+	case "inst":
+		orig := r.typ()
+		targs := make([]types.Type, r.int())
+		for i := range targs {
+			targs[i] = r.typ()
+		}
+		inst, err := types.Instantiate(r.prog.ctxt, orig, targs, false)
+		if err != nil {
+			r.errorf("%v", err)
+		}
+		t = inst
+	case "universe":
+		obj := types.Universe.Lookup(r.str())
+		if obj == nil {
+			r.errorf("unknown universe type")
+		}
+		t = obj.Type()
+	case "typeparam":
+		if len(r.toks) > 0 && r.toks[0] == `"fn"` {
+			r.word()
+			p := r.typesPackage(r.str())
+			fn, ok := p.Scope().Lookup(r.str()).(*types.Func)
+			if !ok {
+				r.errorf("invalid type parameter")
+			}
+			tparams := fn.Type().(*types.Signature).TypeParams()
+			i := r.int()
+			if i < 0 || i >= tparams.Len() {
+				r.errorf("invalid type parameter")
+			}
+			t = tparams.At(i)
+			break
+		}
+		fallthrough
+	case "named":
+		obj, ok := r.object().(*types.TypeName)
+		if !ok {
+			r.errorf("not a type: %s", obj)
+		}
+		t = obj.Type()
+	case "local":
+		p := r.typesPackage(r.str())
+		tn := types.NewTypeName(token.NoPos, p, r.str(), nil)
+		t = types.NewNamed(tn, nil, nil)
+	case "struct":
+		fields := make([]*types.Var, r.int())
+		tags := make([]string, len(fields))
+		for i := range fields {
+			name := r.str()
+			p := r.typesPackage(r.str())
+			typ := r.typ()
+			embedded := r.bool()
+			tags[i] = r.str()
+			fields[i] = types.NewField(token.NoPos, p, name, typ, embedded)
+		}
+		t = types.NewStruct(fields, tags)
+	case "tuple":
+		vars := make([]*types.Var, r.int())
+		for i := range vars {
+			name := r.str()
+			vars[i] = newVar(name, r.typ())
+		}
+		t = types.NewTuple(vars...)
+	case "func":
+		variadic := r.bool()
+		var tuples [2]*types.Tuple
+		for i := range tuples {
+			vars := make([]*types.Var, r.int())
+			for j := range vars {
+				name := r.str()
+				vars[j] = newVar(name, r.typ())
+			}
+			tuples[i] = types.NewTuple(vars...)
+		}
+		t = types.NewSignatureType(nil, nil, nil, tuples[0], tuples[1], variadic)
+	case "interface":
+		methods := make([]*types.Func, r.int())
+		for i := range methods {
+			name := r.str()
+			p := r.typesPackage(r.str())
+			sig, ok := r.typ().(*types.Signature)
+			if !ok {
+				r.errorf("invalid method signature")
+			}
+			methods[i] = types.NewFunc(token.NoPos, p, name, sig)
+		}
+		embeddeds := make([]types.Type, r.int())
+		for i := range embeddeds {
+			embeddeds[i] = r.typ()
+		}
+		t = types.NewInterfaceType(methods, embeddeds).Complete()
+	case "opaque":
+		switch name := r.word(); name {
+		case "iter":
+			t = tRangeIter
+		case "deferstack":
+			t = tDeferStack
+		default:
+			r.errorf("unknown opaque type %s", name)
+		}
+	default:
+		r.errorf("unknown type kind %s", kind)
+	}
+	r.types = append(r.types, t)
+}
+
+// readFunc reads an entry of the function table.
+func (r *textReader) readFunc() {
+	prog := r.prog
+	name := r.toks[0]
+	r.entry("f", len(r.funcs))
+
+	var fn *Function
+	switch kind := r.word(); kind {
+	case "decl":
+		obj, ok := r.object().(*types.Func)
+		if !ok {
+			r.errorf("not a function: %s", obj)
+		}
+		fn = prog.FuncValue(obj)
+		if fn == nil {
+			r.errorf("no function for %s", obj)
+		}
+
+	case "pkginit":
+		fn = prog.packages[r.typesPackage(r.str())].init
+
+	case "init":
+		if r.typesPackage(r.str()) != r.pkg.Pkg {
+			r.errorf("init function of another package")
+		}
+		name := r.str()
+		fn = &Function{
+			name:      name,
+			Signature: new(types.Signature),
+			pos:       r.pos(),
+			Pkg:       r.pkg,
+			Prog:      prog,
+		}
+		r.pkg.ninit++
+		r.pkg.Members[name] = fn
+
+	case "anon":
+		parent := r.function()
+		if idx := r.int(); idx != len(parent.AnonFuncs) {
+			r.errorf("anonymous function out of order")
+		}
+		name := r.str()
+		sig, ok := r.typ().(*types.Signature)
+		if !ok {
+			r.errorf("invalid signature")
+		}
+		fn = &Function{
+			name:       name,
+			Signature:  sig,
+			Synthetic:  r.str(),
+			pos:        r.pos(),
+			parent:     parent,
+			anonIdx:    int32(len(parent.AnonFuncs)),
+			Pkg:        parent.Pkg,
+			Prog:       prog,
+			typeparams: parent.typeparams,
+			typeargs:   parent.typeargs,
+		}
+		parent.AnonFuncs = append(parent.AnonFuncs, fn)
+
+	case "instance":
+		origin := r.function()
+		targs := make([]types.Type, r.int())
+		for i := range targs {
+			targs[i] = r.typ()
+		}
+		synthetic := r.str()
+		if origin.generic == nil {
+			r.errorf("%s is not generic", origin)
+		}
+		if r.bodied[name] && origin.syntax == nil {
+			fn = r.instance(origin, targs, synthetic)
+		} else {
+			fn = origin.instance(targs, &r.b)
+			r.skip[fn] = true
+		}
+
+	case "thunk":
+		recv := r.typ()
+		p := r.typesPackage(r.str())
+		obj, index, indirect := types.LookupFieldOrMethod(recv, false, p, r.str())
+		m, ok := obj.(*types.Func)
+		if !ok {
+			r.errorf("no method for thunk")
+		}
+		sig := m.Type().(*types.Signature)
+		sel := &selection{
+			kind:     types.MethodExpr,
+			recv:     recv,
+			typ:      recvAsFirstArg(changeRecv(sig, newVar(sig.Recv().Name(), recv))),
+			obj:      m,
+			index:    index,
+			indirect: indirect,
+		}
+		fn = createThunk(prog, sel)
+		r.b.enqueue(fn)
+
+	case "wrapper":
+		recv := r.typ()
+		p := r.typesPackage(r.str())
+		sel := prog.MethodSets.MethodSet(recv).Lookup(p, r.str())
+		if sel == nil {
+			r.errorf("no method for wrapper")
+		}
+		fn = prog.MethodValue(sel)
+		if fn == nil {
+			r.errorf("no wrapper for %s", sel)
+		}
+
+	case "bound":
+		recv := r.typ()
+		p := r.typesPackage(r.str())
+		obj, _, _ := types.LookupFieldOrMethod(recv, true, p, r.str())
+		m, ok := obj.(*types.Func)
+		if !ok {
+			r.errorf("no method for bound")
+		}
+		fn = createBound(prog, m)
+		r.b.enqueue(fn)
+
+	default:
+		r.errorf("unknown function kind %s", kind)
+	}
+	r.funcs = append(r.funcs, fn)
+}
+
+// instance returns the instance of origin whose body is to be read,
+// or an existing one, whose body is then ignored.
+func (r *textReader) instance(origin *Function, targs []types.Type, synthetic string) *Function {
+	key := r.prog.canon.List(targs)
+	gen := origin.generic
+	gen.instancesMu.Lock()
+	defer gen.instancesMu.Unlock()
+	if inst, ok := gen.instances[key]; ok {
+		r.skip[inst] = true
+		return inst
+	}
+	inst := createInstance(origin, targs)
+	inst.Synthetic = synthetic
+	if gen.instances == nil {
+		gen.instances = make(map[*typeList]*Function)
+	}
+	gen.instances[key] = inst
+	return inst
+}
+
+// A textInstr is an instruction whose operands are yet to be read.
+type textInstr struct {
+	instr Instruction
+	line  textLine // tokens after the register, type and opcode
+}
+
+// readBody reads the body of a function.
+func (r *textReader) readBody() {
+	fn := r.function()
+	r.end()
+	if r.skip[fn] {
+		for r.nextLine() && !(len(r.toks) == 1 && r.toks[0] == "end") {
+		}
+		r.toks = nil
+		return
+	}
+	if fn.build == nil && fn.Blocks != nil {
+		r.errorf("function %s has two bodies", fn)
+	}
+	fn.build = nil
+	fn.buildshared = nil
+	if fn.object != nil && fn.topLevelOrigin == nil && fn.Pkg == r.pkg {
+		fn.Synthetic = "" // declared function
+	}
+
+	var (
+		locals []string
+		instrs []textInstr
+		regs   = make(map[string]Value)
+		preds  [][]int
+		succs  [][]int
+		params bool
+	)
+	for {
+		if !r.nextLine() {
+			r.errorf("missing end of body")
+		}
+		op := r.word()
+		if op == "end" {
+			break
+		}
+		switch op {
+		case "params":
+			var vars []*types.Var
+			if recv := fn.Signature.Recv(); recv != nil {
+				vars = append(vars, recv)
+			}
+			for i := 0; i < fn.Signature.Params().Len(); i++ {
+				vars = append(vars, fn.Signature.Params().At(i))
+			}
+			if len(r.toks) != len(vars) {
+				r.errorf("got %d parameters, want %d", len(r.toks), len(vars))
+			}
+			for _, v := range vars {
+				fn.Params = append(fn.Params, &Parameter{
+					name:   r.str(),
+					object: v,
+					typ:    v.Type(),
+					parent: fn,
+				})
+			}
+			params = true
+		case "freevar":
+			name := r.str()
+			typ := r.typ()
+			fn.FreeVars = append(fn.FreeVars, &FreeVar{name: name, typ: typ, pos: r.pos(), parent: fn})
+		case "locals":
+			locals, r.toks = r.toks, nil
+		case "recover":
+			fn.Recover = &BasicBlock{Index: r.int()} // resolved below
+		case "block":
+			if r.int() != len(fn.Blocks) {
+				r.errorf("block out of order")
+			}
+			b := &BasicBlock{Index: len(fn.Blocks), Comment: r.str(), parent: fn}
+			var p, s []int
+			r.expect("preds")
+			for len(r.toks) > 0 && r.toks[0] != "succs" {
+				p = append(p, r.int())
+			}
+			r.expect("succs")
+			for len(r.toks) > 0 {
+				s = append(s, r.int())
+			}
+			fn.Blocks = append(fn.Blocks, b)
+			preds, succs = append(preds, p), append(succs, s)
+		default:
+			if len(fn.Blocks) == 0 {
+				r.errorf("instruction outside block")
+			}
+			var reg string
+			if len(r.toks) > 0 && r.toks[0] == "=" {
+				reg = op
+				r.word()
+				op = r.word()
+			}
+			instr := r.newInstr(op, reg != "")
+			if v, ok := instr.(interface {
+				Value
+				setNum(int)
+				setType(types.Type)
+			}); ok {
+				if reg == "" {
+					r.errorf("%s lacks a register", op)
+				}
+				num, err := strconv.Atoi(strings.TrimPrefix(reg, "t"))
+				if err != nil || !strings.HasPrefix(reg, "t") || regs[reg] != nil {
+					r.errorf("invalid register %s", reg)
+				}
+				v.setNum(num)
+				v.setType(r.typ())
+				regs[reg] = v
+			} else if reg != "" {
+				r.errorf("%s has no register", op)
+			}
+			b := fn.Blocks[len(fn.Blocks)-1]
+			instr.setBlock(b)
+			b.Instrs = append(b.Instrs, instr)
+			instrs = append(instrs, textInstr{instr, textLine{r.line, r.toks}})
+			r.toks = nil
+		}
+		r.end()
+	}
+	if fn.Blocks == nil {
+		if params || len(instrs) > 0 {
+			r.errorf("function %s has no blocks", fn)
+		}
+		r.b.buildParamsOnly(fn) // external function
+		return
+	}
+
+	for i, b := range fn.Blocks {
+		for _, p := range preds[i] {
+			b.Preds = append(b.Preds, r.block(fn, p))
+		}
+		b.Succs = b.succs2[:0]
+		for _, s := range succs[i] {
+			b.Succs = append(b.Succs, r.block(fn, s))
+		}
+	}
+	if fn.Recover != nil {
+		fn.Recover = r.block(fn, fn.Recover.Index)
+	}
+	for _, name := range locals {
+		alloc, ok := regs[name].(*Alloc)
+		if !ok {
+			r.errorf("invalid local %s", name)
+		}
+		fn.Locals = append(fn.Locals, alloc)
+	}
+	for _, ti := range instrs {
+		r.line, r.toks = ti.line.num, ti.line.toks
+		r.readOperands(fn, regs, ti.instr)
+		r.end()
+	}
+	r.checkBlocks(fn)
+	r.built = append(r.built, fn)
+}
+
+// checkBlocks reports an error if the control-flow graph of fn is
+// malformed. It ensures that the dominator tree can be built.
+func (r *textReader) checkBlocks(fn *Function) {
+	count := func(blocks []*BasicBlock, b *BasicBlock) int {
+		n := 0
+		for _, x := range blocks {
+			if x == b {
+				n++
+			}
+		}
+		return n
+	}
+	for _, b := range fn.Blocks {
+		if len(b.Instrs) == 0 {
+			r.errorf("%s: block %d is empty", fn, b.Index)
+		}
+		for i, instr := range b.Instrs {
+			switch instr.(type) {
+			case *If, *Jump, *Return, *Panic:
+				if i < len(b.Instrs)-1 {
+					r.errorf("%s: block %d: control transfer before end of block", fn, b.Index)
+				}
+			default:
+				if i == len(b.Instrs)-1 {
+					r.errorf("%s: block %d does not end in a control transfer", fn, b.Index)
+				}
+			}
+		}
+		nsuccs := 0
+		switch b.Instrs[len(b.Instrs)-1].(type) {
+		case *If:
+			nsuccs = 2
+		case *Jump:
+			nsuccs = 1
+		}
+		if len(b.Succs) != nsuccs {
+			r.errorf("%s: block %d has %d successors, want %d", fn, b.Index, len(b.Succs), nsuccs)
+		}
+		for _, s := range b.Succs {
+			if count(s.Preds, b) != count(b.Succs, s) {
+				r.errorf("%s: edge from block %d to %d is not among the predecessors of %d", fn, b.Index, s.Index, s.Index)
+			}
+		}
+		for _, p := range b.Preds {
+			if count(p.Succs, b) != count(b.Preds, p) {
+				r.errorf("%s: edge from block %d to %d is not among the successors of %d", fn, p.Index, b.Index, p.Index)
+			}
+		}
+		if b.Index > 0 && b != fn.Recover && len(b.Preds) == 0 {
+			r.errorf("%s: block %d has no predecessors", fn, b.Index)
+		}
+	}
+	if fn.Recover != nil && len(fn.Recover.Preds) > 0 {
+		r.errorf("%s: recover block %d has predecessors", fn, fn.Recover.Index)
+	}
+
+	// All blocks must be reachable from the entry or recover block.
+	reached := make([]bool, len(fn.Blocks))
+	var visit func(b *BasicBlock)
+	visit = func(b *BasicBlock) {
+		if !reached[b.Index] {
+			reached[b.Index] = true
+			for _, s := range b.Succs {
+				visit(s)
+			}
+		}
+	}
+	visit(fn.Blocks[0])
+	if fn.Recover != nil {
+		visit(fn.Recover)
+	}
+	for i, ok := range reached {
+		if !ok {
+			r.errorf("%s: block %d is unreachable", fn, i)
+		}
+	}
+}
+
+func (r *textReader) block(fn *Function, i int) *BasicBlock {
+	if i < 0 || i >= len(fn.Blocks) {
+		r.errorf("invalid block %d", i)
+	}
+	return fn.Blocks[i]
+}
+
+// newInstr returns a new instruction for the opcode op.
+func (r *textReader) newInstr(op string, value bool) Instruction {
+	switch op {
+	case "alloc":
+		return new(Alloc)
+	case "phi":
+		return new(Phi)
+	case "call":
+		if value {
+			return new(Call)
+		}
+	case "binop":
+		return new(BinOp)
+	case "unop":
+		return new(UnOp)
+	case "changetype":
+		return new(ChangeType)
+	case "convert":
+		return new(Convert)
+	case "multiconvert":
+		return new(MultiConvert)
+	case "changeinterface":
+		return new(ChangeInterface)
+	case "slicetoarrayptr":
+		return new(SliceToArrayPointer)
+	case "makeinterface":
+		return new(MakeInterface)
+	case "makeclosure":
+		return new(MakeClosure)
+	case "makemap":
+		return new(MakeMap)
+	case "makechan":
+		return new(MakeChan)
+	case "makeslice":
+		return new(MakeSlice)
+	case "slice":
+		return new(Slice)
+	case "fieldaddr":
+		return new(FieldAddr)
+	case "field":
+		return new(Field)
+	case "indexaddr":
+		return new(IndexAddr)
+	case "index":
+		return new(Index)
+	case "lookup":
+		return new(Lookup)
+	case "select":
+		return new(Select)
+	case "range":
+		return new(Range)
+	case "next":
+		return new(Next)
+	case "typeassert":
+		return new(TypeAssert)
+	case "extract":
+		return new(Extract)
+	case "jump":
+		return new(Jump)
+	case "if":
+		return new(If)
+	case "return":
+		return new(Return)
+	case "rundefers":
+		return new(RunDefers)
+	case "panic":
+		return new(Panic)
+	case "go":
+		return new(Go)
+	case "defer":
+		return new(Defer)
+	case "send":
+		return new(Send)
+	case "store":
+		return new(Store)
+	case "mapupdate":
+		return new(MapUpdate)
+	}
+	r.errorf("unknown instruction %s", op)
+	return nil
+}
+
+// operators maps the names of operators to tokens.
+var operators = func() map[string]token.Token {
+	m := make(map[string]token.Token)
+	for tok := token.ILLEGAL; tok <= token.TILDE; tok++ {
+		if tok.IsOperator() {
+			m[tok.String()] = tok
+		}
+	}
+	return m
+}()
+
+// readOperands reads the operands and position of an instruction.
+func (r *textReader) readOperands(fn *Function, regs map[string]Value, instr Instruction) {
+	value := func() Value {
+		tok := r.word()
+		switch {
+		case tok == "_":
+			return nil
+		case tok == "const":
+			return r.constant()
+		case tok == "builtin":
+			name := r.str()
+			sig, ok := r.typ().(*types.Signature)
+			if !ok {
+				r.errorf("invalid builtin signature")
+			}
+			return &Builtin{name: name, sig: sig}
+		case tok[0] == 't':
+			if v := regs[tok]; v != nil {
+				return v
+			}
+		}
+		r.toks = append([]string{tok}, r.toks...)
+		switch tok[0] {
+		case 'p':
+			return fn.Params[r.ref("p", len(fn.Params))]
+		case 'v':
+			return fn.FreeVars[r.ref("v", len(fn.FreeVars))]
+		case 'g':
+			return r.globals[r.ref("g", len(r.globals))]
+		case 'f':
+			return r.function()
+		}
+		r.errorf("invalid operand %s", tok)
+		return nil
+	}
+	values := func() []Value {
+		vs := make([]Value, r.int())
+		for i := range vs {
+			vs[i] = value()
+		}
+		return vs
+	}
+	call := func(c *CallCommon) {
+		switch kind := r.word(); kind {
+		case "call":
+			c.Value = value()
+		case "invoke":
+			c.Value = value()
+			p := r.typesPackage(r.str())
+			obj, _, _ := types.LookupFieldOrMethod(c.Value.Type(), false, p, r.str())
+			m, ok := obj.(*types.Func)
+			if !ok {
+				r.errorf("no method for invoke")
+			}
+			c.Method = m
+		default:
+			r.errorf("invalid call %s", kind)
+		}
+		c.Args = values()
+		c.pos = r.pos()
+	}
+
+	switch instr := instr.(type) {
+	case *Alloc:
+		instr.Heap = r.word() == "heap"
+		instr.Comment = r.str()
+	case *Phi:
+		instr.Comment = r.str()
+		instr.Edges = values()
+	case *Call:
+		call(&instr.Call)
+	case *BinOp:
+		instr.Op = r.operator()
+		instr.X = value()
+		instr.Y = value()
+	case *UnOp:
+		instr.Op = r.operator()
+		instr.X = value()
+		instr.CommaOk = r.bool()
+	case *ChangeType:
+		instr.X = value()
+	case *Convert:
+		instr.X = value()
+	case *MultiConvert:
+		instr.X = value()
+		instr.from = typeSetOf(instr.X.Type().Underlying())
+		instr.to = typeSetOf(instr.Type().Underlying())
+	case *ChangeInterface:
+		instr.X = value()
+	case *SliceToArrayPointer:
+		instr.X = value()
+	case *MakeInterface:
+		instr.X = value()
+		if t := instr.X.Type(); fn.typeparams.Len() == 0 || !r.prog.isParameterized(t) {
+			addMakeInterfaceType(r.prog, t)
+		}
+	case *MakeClosure:
+		instr.Fn = value()
+		instr.Bindings = values()
+	case *MakeMap:
+		instr.Reserve = value()
+	case *MakeChan:
+		instr.Size = value()
+	case *MakeSlice:
+		instr.Len = value()
+		instr.Cap = value()
+	case *Slice:
+		instr.X = value()
+		instr.Low = value()
+		instr.High = value()
+		instr.Max = value()
+	case *FieldAddr:
+		instr.X = value()
+		instr.Field = r.int()
+	case *Field:
+		instr.X = value()
+		instr.Field = r.int()
+	case *IndexAddr:
+		instr.X = value()
+		instr.Index = value()
+	case *Index:
+		instr.X = value()
+		instr.Index = value()
+	case *Lookup:
+		instr.X = value()
+		instr.Index = value()
+		instr.CommaOk = r.bool()
+	case *Select:
+		instr.Blocking = r.bool()
+		instr.States = make([]*SelectState, r.int())
+		for i := range instr.States {
+			st := new(SelectState)
+			switch dir := r.word(); dir {
+			case "send":
+				st.Dir = types.SendOnly
+				st.Chan = value()
+				st.Send = value()
+			case "recv":
+				st.Dir = types.RecvOnly
+				st.Chan = value()
+			default:
+				r.errorf("invalid select state %s", dir)
+			}
+			st.Pos = r.pos()
+			instr.States[i] = st
+		}
+	case *Range:
+		instr.X = value()
+	case *Next:
+		instr.Iter = value()
+		instr.IsString = r.bool()
+	case *TypeAssert:
+		instr.X = value()
+		instr.AssertedType = r.typ()
+		instr.CommaOk = r.bool()
+	case *Extract:
+		instr.Tuple = value()
+		instr.Index = r.int()
+	case *Jump, *RunDefers:
+	case *If:
+		instr.Cond = value()
+	case *Return:
+		instr.Results = values()
+		instr.pos = r.pos()
+		return
+	case *Panic:
+		instr.X = value()
+		instr.pos = r.pos()
+		return
+	case *Go:
+		call(&instr.Call)
+		instr.pos = r.pos()
+		return
+	case *Defer:
+		call(&instr.Call)
+		instr.DeferStack = value()
+		instr.pos = r.pos()
+		return
+	case *Send:
+		instr.Chan = value()
+		instr.X = value()
+		instr.pos = r.pos()
+		return
+	case *Store:
+		instr.Addr = value()
+		instr.Val = value()
+		instr.pos = r.pos()
+		return
+	case *MapUpdate:
+		instr.Map = value()
+		instr.Key = value()
+		instr.Value = value()
+		instr.pos = r.pos()
+		return
+	}
+
+	pos := r.pos()
+	if v, ok := instr.(interface{ setPos(token.Pos) }); ok {
+		if _, isCall := instr.(*Call); !isCall {
+			v.setPos(pos)
+		}
+	}
+}
+
+func (r *textReader) operator() token.Token {
+	s := r.str()
+	tok, ok := operators[s]
+	if !ok {
+		r.errorf("invalid operator %q", s)
+	}
+	return tok
+}
+
+// constant reads a constant, after the "const" token.
+func (r *textReader) constant() *Const {
+	typ := r.typ()
+	integer := func() *big.Int {
+		s := r.str()
+		x, ok := new(big.Int).SetString(s, 10)
+		if !ok {
+			r.errorf("invalid integer %q", s)
+		}
+		return x
+	}
+	rat := func() constant.Value {
+		num := integer()
+		den := integer()
+		if den.Sign() == 0 {
+			r.errorf("zero denominator")
+		}
+		return constant.Make(new(big.Rat).SetFrac(num, den))
+	}
+	var val constant.Value
+	switch kind := r.word(); kind {
+	case "nil":
+	case "bool":
+		val = constant.MakeBool(r.bool())
+	case "string":
+		val = constant.MakeString(r.str())
+	case "int":
+		val = constant.Make(integer())
+	case "float":
+		val = constant.ToFloat(rat())
+	case "complex":
+		re := rat()
+		im := rat()
+		val = constant.BinaryOp(constant.ToComplex(re), token.ADD, constant.MakeImag(im))
+	default:
+		r.errorf("invalid constant kind %s", kind)
+	}
+	return NewConst(val, typ)
+}
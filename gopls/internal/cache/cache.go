@@ -78,8 +78,9 @@ func New(store *memoize.Store) *Cache {
 		store:      store,
 		memoizedFS: newMemoizedFS(),
 		modCache: &sharedModCache{
-			caches: make(map[string]*imports.DirInfoCache),
-			timers: make(map[string]*refreshTimer),
+			caches:  make(map[string]*imports.DirInfoCache),
+			indexes: make(map[string]*imports.ModIndex),
+			timers:  make(map[string]*refreshTimer),
 		},
 	}
 	return c
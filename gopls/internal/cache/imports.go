@@ -85,8 +85,9 @@ func (t *refreshTimer) schedule() {
 //
 // This state is refreshed independently of view-specific imports state.
 type sharedModCache struct {
-	mu     sync.Mutex
-	caches map[string]*imports.DirInfoCache // GOMODCACHE -> cache content; never invalidated
+	mu      sync.Mutex
+	caches  map[string]*imports.DirInfoCache // GOMODCACHE -> cache content; never invalidated
+	indexes map[string]*imports.ModIndex     // GOMODCACHE -> symbol index, if used
 	// TODO(rfindley): consider stopping these timers when the session shuts down.
 	timers map[string]*refreshTimer // GOMODCACHE -> timer
 }
@@ -103,6 +104,19 @@ func (c *sharedModCache) dirCache(dir string) *imports.DirInfoCache {
 	return cache
 }
 
+// modIndex returns the shared index of the given module cache.
+func (c *sharedModCache) modIndex(dir string) *imports.ModIndex {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	ix, ok := c.indexes[dir]
+	if !ok {
+		ix = imports.NewModIndex(dir)
+		c.indexes[dir] = ix
+	}
+	return ix
+}
+
 // refreshDir schedules a refresh of the given directory, which must be a
 // module cache.
 func (c *sharedModCache) refreshDir(ctx context.Context, dir string, logf func(string, ...any)) {
@@ -115,7 +129,15 @@ func (c *sharedModCache) refreshDir(ctx context.Context, dir string, logf func(s
 		timer = newRefreshTimer(func() {
 			_, done := event.Start(ctx, "cache.sharedModCache.refreshDir", label.Directory.Of(dir))
 			defer done()
-			imports.ScanModuleCache(dir, cache, logf)
+			c.mu.Lock()
+			ix := c.indexes[dir]
+			c.mu.Unlock()
+			if ix == nil {
+				imports.ScanModuleCache(dir, cache, logf)
+			} else if err := ix.Update(); err != nil && logf != nil {
+				// The index replaces the scan of the module cache.
+				logf("updating module cache index: %v", err)
+			}
 		})
 		c.timers[dir] = timer
 	}
@@ -199,6 +221,7 @@ func (s *importsState) runProcessEnvFunc(ctx context.Context, snapshot *Snapshot
 		TabWidth:    8,
 		Env:         s.processEnv,
 		LocalPrefix: snapshot.Options().Local,
+		Grouping:    snapshot.Options().Grouping(),
 	}
 
 	if err := fn(ctx, opts); err != nil {
@@ -217,6 +217,10 @@ func (s *Session) createView(ctx context.Context, def *viewDefinition) (*View, *
 			WorkingDir:     def.root.Path(),
 			ModCache:       s.cache.modCache.dirCache(def.folder.Env.GOMODCACHE),
 		}
+		if def.folder.Options.UseModIndex {
+			pe.UseModIndex = true
+			pe.ModIndex = s.cache.modCache.modIndex(def.folder.Env.GOMODCACHE)
+		}
 		if def.folder.Options.VerboseOutput {
 			pe.Logf = func(format string, args ...interface{}) {
 				event.Log(ctx, fmt.Sprintf(format, args...))
@@ -296,6 +296,7 @@ func (app *Application) featureCommands() []tool.Application {
 		newRemote(app, ""),
 		newRemote(app, "inspect"),
 		&links{app: app},
+		&modsearch{app: app, Args: -1},
 		&prepareRename{app: app},
 		&references{app: app},
 		&rename{app: app},
@@ -51,10 +51,14 @@ Valid kinds include:
 	quickfix
 	refactor
 	refactor.extract
+	refactor.extract.constant
+	refactor.extract.constant-all
 	refactor.extract.function
 	refactor.extract.method
 	refactor.extract.toNewFile
+	refactor.extract.type
 	refactor.extract.variable
+	refactor.extract.variable-all
 	refactor.inline
 	refactor.inline.call
 	refactor.rewrite
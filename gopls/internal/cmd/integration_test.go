@@ -605,6 +605,61 @@ func f() {}
 	}
 }
 
+// TestModsearch tests the 'modsearch' subcommand (modsearch.go).
+func TestModsearch(t *testing.T) {
+	t.Parallel()
+
+	tree := writeTree(t, `
+-- go.mod --
+module example.com
+go 1.18
+
+-- modcache/example.com/m@v1.0.0/go.mod --
+module example.com/m
+
+-- modcache/example.com/m@v1.0.0/m.go --
+package m
+
+func Fixed(a int, b string) {}
+func Variadic(format string, args ...any) {}
+`)
+	env := []string{
+		"GOMODCACHE=" + filepath.Join(tree, "modcache"),
+		// The index is kept in the user's cache directory.
+		"XDG_CACHE_HOME=" + filepath.Join(tree, "cache"),
+		"HOME=" + tree,
+	}
+	for _, test := range []struct {
+		name string
+		args int
+		want bool
+	}{
+		{"Fixed", 1, false},
+		{"Fixed", 2, true},
+		{"Fixed", 3, false},
+		{"Variadic", 0, false},
+		{"Variadic", 1, true},
+		{"Variadic", 2, true},
+		{"Variadic", 4, true},
+	} {
+		res := goplsWithEnv(t, tree, env, "modsearch", "-json", fmt.Sprintf("-args=%d", test.args), "m."+test.name)
+		res.checkExit(true)
+		var results []struct{ ImportPath, Name string }
+		if !res.toJSON(&results) {
+			continue
+		}
+		got := len(results) == 1 && results[0].ImportPath == "example.com/m" && results[0].Name == test.name
+		if got != test.want || len(results) > 1 {
+			t.Errorf("modsearch -args=%d m.%s = %v, want match: %t", test.args, test.name, results, test.want)
+		}
+	}
+
+	// Without -json, the declaration of the function is printed.
+	res := goplsWithEnv(t, tree, env, "modsearch", "-kind=func", "Variadic")
+	res.checkExit(true)
+	res.checkStdout(`example.com/m@v1.0.0\tfunc m.Variadic\(format string, args \.\.\.any\)`)
+}
+
 // TestReferences tests the 'references' subcommand (references.go).
 func TestReferences(t *testing.T) {
 	t.Parallel()
@@ -0,0 +1,287 @@
+// Copyright 2024 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"golang.org/x/tools/gopls/internal/fuzzy"
+	"golang.org/x/tools/internal/modindex"
+	"golang.org/x/tools/internal/tool"
+)
+
+// modsearch implements the modsearch verb for gopls.
+type modsearch struct {
+	app *Application
+
+	JSON     bool   `flag:"json" help:"emit results in JSON format"`
+	Fuzzy    bool   `flag:"fuzzy" help:"match symbol names fuzzily instead of exactly"`
+	Kind     string `flag:"kind" help:"match only symbols of this kind: func, type, var or const"`
+	Args     int    `flag:"args" help:"match only functions that accept this number of arguments (-1 for any)"`
+	Versions bool   `flag:"versions" help:"search all cached versions of the module given as first argument"`
+	Limit    int    `flag:"limit" help:"maximum number of results (0 for no limit)"`
+}
+
+func (m *modsearch) Name() string   { return "modsearch" }
+func (m *modsearch) Parent() string { return m.app.Name() }
+func (m *modsearch) Usage() string {
+	return "[modsearch-flags] [pkg.]symbol | -versions [modsearch-flags] module [pkg.]symbol"
+}
+func (m *modsearch) ShortHelp() string { return "search the module cache for exported symbols" }
+func (m *modsearch) DetailedHelp(f *flag.FlagSet) {
+	fmt.Fprint(f.Output(), `
+Search the index of the module cache (GOMODCACHE) for the exported
+symbols of the latest cached version of each package, updating the index
+first if necessary. The query is a symbol name, optionally qualified by
+a package name. With -fuzzy, the query is matched fuzzily against the
+qualified names of the symbols, and the results are ordered by score.
+
+With -versions, the first argument is a module path, and all the cached
+versions of that module that define a matching symbol are listed, latest
+first. These are not in the index, so the module is read from the cache.
+
+Example: find the functions named Marshal that accept two arguments:
+
+	$ gopls modsearch -kind=func -args=2 Marshal
+
+Example: find the versions of gopkg.in/yaml.v3 that define yaml.NewEncoder:
+
+	$ gopls modsearch -versions gopkg.in/yaml.v3 yaml.NewEncoder
+
+modsearch-flags:
+`)
+	printFlagDefaults(f)
+}
+
+// modsearchResult is the JSON form of a result of modsearch.
+type modsearchResult struct {
+	ImportPath string
+	Version    string
+	Dir        string // absolute
+	PkgName    string
+	Name       string
+	Kind       string           // func, type, var or const
+	Params     []modindex.Field `json:",omitempty"`
+	Results    int              `json:",omitempty"`
+	Score      float32          `json:",omitempty"` // with -fuzzy
+}
+
+func (m *modsearch) Run(ctx context.Context, args ...string) error {
+	var modpath string
+	if m.Versions {
+		if len(args) != 2 {
+			return tool.CommandLineErrorf("modsearch -versions expects a module path and a symbol")
+		}
+		modpath, args = args[0], args[1:]
+	}
+	if len(args) != 1 {
+		return tool.CommandLineErrorf("modsearch expects one symbol")
+	}
+	filter, match, err := m.matcher(args[0])
+	if err != nil {
+		return err
+	}
+
+	out, err := exec.CommandContext(ctx, "go", "env", "GOMODCACHE").Output()
+	if err != nil {
+		return fmt.Errorf("go env GOMODCACHE: %v", err)
+	}
+	cachedir := string(bytes.TrimSpace(out))
+	if cachedir == "" {
+		return fmt.Errorf("GOMODCACHE is not set")
+	}
+
+	var cands []modindex.Candidate
+	if m.Versions {
+		versions, err := modindex.Versions(cachedir, modpath)
+		if err != nil {
+			return err
+		}
+		if len(versions) == 0 {
+			return fmt.Errorf("module %s is not in the module cache", modpath)
+		}
+		for _, v := range versions {
+			syms, err := modindex.ModuleSymbols(cachedir, modpath, v)
+			if err != nil {
+				return err
+			}
+			for _, c := range syms {
+				if filter(c.PkgName, c.Name) {
+					cands = append(cands, c)
+				}
+			}
+		}
+	} else {
+		if _, err := modindex.Update(cachedir); err != nil {
+			return err
+		}
+		ix, err := modindex.ReadIndex(cachedir)
+		if err != nil {
+			return err
+		}
+		if ix == nil {
+			return fmt.Errorf("no index of %s", cachedir)
+		}
+		cands = ix.Search(filter)
+	}
+
+	var results []modsearchResult
+	for _, c := range cands {
+		score := match(c)
+		if score <= 0 {
+			continue
+		}
+		r := modsearchResult{
+			ImportPath: c.ImportPath,
+			Version:    c.Version,
+			Dir:        filepath.Join(cachedir, filepath.FromSlash(c.Dir)),
+			PkgName:    c.PkgName,
+			Name:       c.Name,
+			Kind:       lexTypeName(c.Type),
+			Params:     c.Sig,
+			Results:    int(c.Results),
+		}
+		if m.Fuzzy {
+			r.Score = score
+		}
+		results = append(results, r)
+	}
+	if !m.Versions {
+		// With -versions, keep the results in order of version.
+		sort.SliceStable(results, func(i, j int) bool {
+			x, y := results[i], results[j]
+			if x.Score != y.Score {
+				return x.Score > y.Score
+			}
+			if x.ImportPath != y.ImportPath {
+				return x.ImportPath < y.ImportPath
+			}
+			return x.Name < y.Name
+		})
+	}
+	if m.Limit > 0 && len(results) > m.Limit {
+		results = results[:m.Limit]
+	}
+
+	if m.JSON {
+		if results == nil {
+			results = []modsearchResult{} // print [], not null
+		}
+		data, err := json.MarshalIndent(results, "", "\t")
+		if err != nil {
+			return err
+		}
+		fmt.Printf("%s\n", data)
+		return nil
+	}
+	for _, r := range results {
+		fmt.Printf("%s@%s\t%s\n", r.ImportPath, r.Version, r.describe())
+	}
+	return nil
+}
+
+// matcher returns functions that match a candidate against the query:
+// filter is a quick test of the names of the candidate, and match
+// scores it, returning 0 if it does not match.
+func (m *modsearch) matcher(query string) (filter func(pkg, name string) bool, match func(modindex.Candidate) float32, err error) {
+	kind := modindex.LexType(-1)
+	switch m.Kind {
+	case "":
+	case "func":
+		kind = modindex.Func
+	case "type":
+		kind = modindex.Type
+	case "var":
+		kind = modindex.Var
+	case "const":
+		kind = modindex.Const
+	default:
+		return nil, nil, tool.CommandLineErrorf("unknown kind %q", m.Kind)
+	}
+	pkg, name, qualified := strings.Cut(query, ".")
+	if !qualified {
+		pkg, name = "", query
+	}
+	if name == "" {
+		return nil, nil, tool.CommandLineErrorf("empty symbol name in %q", query)
+	}
+
+	var score func(c modindex.Candidate) float32
+	if m.Fuzzy {
+		matcher := fuzzy.NewMatcher(query)
+		filter = func(string, string) bool { return true }
+		score = func(c modindex.Candidate) float32 {
+			if qualified {
+				return matcher.Score(c.PkgName + "." + c.Name)
+			}
+			return matcher.Score(c.Name)
+		}
+	} else {
+		filter = func(p, n string) bool {
+			return n == name && (pkg == "" || p == pkg)
+		}
+		score = func(c modindex.Candidate) float32 { return 1 }
+	}
+	match = func(c modindex.Candidate) float32 {
+		if kind >= 0 && c.Type != kind {
+			return 0
+		}
+		if m.Args >= 0 && !c.AcceptsArgs(m.Args) {
+			return 0
+		}
+		if !filter(c.PkgName, c.Name) {
+			return 0
+		}
+		return score(c)
+	}
+	return filter, match, nil
+}
+
+// describe returns the declaration of the symbol, without types for
+// results, which the index does not record.
+func (r *modsearchResult) describe() string {
+	var buf strings.Builder
+	fmt.Fprintf(&buf, "%s %s.%s", r.Kind, r.PkgName, r.Name)
+	if r.Kind == "func" {
+		buf.WriteString("(")
+		for i, p := range r.Params {
+			if i > 0 {
+				buf.WriteString(", ")
+			}
+			fmt.Fprintf(&buf, "%s %s", p.Arg, p.Type)
+		}
+		buf.WriteString(")")
+		switch r.Results {
+		case 0:
+		case 1:
+			buf.WriteString(" (1 result)")
+		default:
+			fmt.Fprintf(&buf, " (%d results)", r.Results)
+		}
+	}
+	return buf.String()
+}
+
+func lexTypeName(t modindex.LexType) string {
+	switch t {
+	case modindex.Func:
+		return "func"
+	case modindex.Type:
+		return "type"
+	case modindex.Var:
+		return "var"
+	case modindex.Const:
+		return "const"
+	}
+	return "unknown"
+}
@@ -6,6 +6,7 @@ package golang
 
 import (
 	"bytes"
+	"cmp"
 	"context"
 	"fmt"
 	"go/ast"
@@ -14,12 +15,16 @@ import (
 	"go/token"
 	"go/types"
 	"regexp"
+	"slices"
+	"unicode"
+	"unicode/utf8"
 
 	"golang.org/x/tools/go/ast/astutil"
 	"golang.org/x/tools/gopls/internal/cache"
 	"golang.org/x/tools/gopls/internal/cache/parsego"
 	"golang.org/x/tools/gopls/internal/file"
 	"golang.org/x/tools/gopls/internal/protocol"
+	"golang.org/x/tools/gopls/internal/protocol/command"
 	"golang.org/x/tools/gopls/internal/util/bug"
 	"golang.org/x/tools/gopls/internal/util/safetoken"
 	"golang.org/x/tools/imports"
@@ -47,14 +52,8 @@ func RemoveUnusedParameter(ctx context.Context, fh file.Handle, rng protocol.Ran
 
 	// Changes to our heuristics for whether we can remove a parameter must also
 	// be reflected in the canRemoveParameter helper.
-	if perrors, terrors := pkg.ParseErrors(), pkg.TypeErrors(); len(perrors) > 0 || len(terrors) > 0 {
-		var sample string
-		if len(perrors) > 0 {
-			sample = perrors[0].Error()
-		} else {
-			sample = terrors[0].Error()
-		}
-		return nil, fmt.Errorf("can't change signatures for packages with parse or type errors: (e.g. %s)", sample)
+	if err := checkPackageErrors(pkg); err != nil {
+		return nil, err
 	}
 
 	info, err := findParam(pgf, rng)
@@ -149,14 +148,34 @@ func RemoveUnusedParameter(ctx context.Context, fh file.Handle, rng protocol.Ran
 			src = pgf.Src
 		}
 		fset := tokeninternal.FileSetFor(pgf.Tok)
-		src, err := rewriteSignature(fset, idx, src, newDecl)
+		src, err := rewriteSignature(fset, idx, src, newDecl, nil, "")
 		if err != nil {
 			return nil, err
 		}
 		newContent[pgf.URI] = src
 	}
 
-	// Translate the resulting state into document changes.
+	return documentChanges(ctx, snapshot, newContent)
+}
+
+// checkPackageErrors returns an error if pkg has parse or type errors, as
+// signature rewriting relies on complete type information.
+func checkPackageErrors(pkg *cache.Package) error {
+	if perrors, terrors := pkg.ParseErrors(), pkg.TypeErrors(); len(perrors) > 0 || len(terrors) > 0 {
+		var sample string
+		if len(perrors) > 0 {
+			sample = perrors[0].Error()
+		} else {
+			sample = terrors[0].Error()
+		}
+		return fmt.Errorf("can't change signatures for packages with parse or type errors: (e.g. %s)", sample)
+	}
+	return nil
+}
+
+// documentChanges translates the new content of each modified file into
+// document changes.
+func documentChanges(ctx context.Context, snapshot *cache.Snapshot, newContent map[protocol.DocumentURI][]byte) ([]protocol.DocumentChange, error) {
 	var changes []protocol.DocumentChange
 	for uri, after := range newContent {
 		fh, err := snapshot.ReadFile(ctx, uri)
@@ -179,9 +198,536 @@ func RemoveUnusedParameter(ctx context.Context, fh file.Handle, rng protocol.Ran
 	return changes, nil
 }
 
+// ChangeSignature computes a refactoring to change the parameters of the
+// function declared at the given range to those described by newParams,
+// rewriting all calls accordingly. Parameters may be added (in which case
+// each call passes the parameter's default argument), removed (if unused),
+// reordered, renamed, or changed to a type to which the old type is trivially
+// convertible. If paramsStruct is set, the new parameters instead become the
+// fields of a new struct type of that name, which is the type of the sole
+// parameter of the function.
+//
+// As with RemoveUnusedParameter, calls are rewritten by inlining a wrapper
+// with the original signature that delegates to the new declaration.
+func ChangeSignature(ctx context.Context, fh file.Handle, rng protocol.Range, snapshot *cache.Snapshot, newParams []command.ChangeSignatureParam, paramsStruct string) ([]protocol.DocumentChange, error) {
+	pkg, pgf, err := NarrowestPackageForFile(ctx, snapshot, fh.URI())
+	if err != nil {
+		return nil, err
+	}
+	if err := checkPackageErrors(pkg); err != nil {
+		return nil, err
+	}
+	pinfo, err := findParam(pgf, rng)
+	if err != nil {
+		return nil, err // e.g. invalid range
+	}
+	decl := pinfo.decl
+	if decl.Body == nil {
+		return nil, fmt.Errorf("cannot change signature of %s: it has no body", decl.Name.Name)
+	}
+	var (
+		fset = pkg.FileSet()
+		info = pkg.TypesInfo()
+	)
+	fn, ok := info.Defs[decl.Name].(*types.Func)
+	if !ok {
+		return nil, bug.Errorf("no func for %s", decl.Name.Name)
+	}
+	sig := fn.Type().(*types.Signature)
+
+	// Enumerate the original parameters, one per name, with the fields
+	// that declare them.
+	var (
+		vars   []*types.Var
+		fields []*ast.Field
+	)
+	for _, fld := range decl.Type.Params.List {
+		for range max(1, len(fld.Names)) {
+			vars = append(vars, sig.Params().At(len(vars)))
+			fields = append(fields, fld)
+		}
+	}
+	isParam := func(obj types.Object) bool {
+		v, ok := obj.(*types.Var)
+		return ok && slices.Contains(vars, v)
+	}
+
+	// Find references to the parameters in the body, and record the other
+	// names used by the function, which new or renamed parameters must not
+	// shadow.
+	var (
+		refs      = make(map[*types.Var][]*ast.Ident)
+		inUse     = make(map[string]bool)
+		recursive = false
+	)
+	var visit func(n ast.Node) bool
+	visit = func(n ast.Node) bool {
+		switch n := n.(type) {
+		case *ast.SelectorExpr:
+			ast.Inspect(n.X, visit) // n.Sel cannot be shadowed
+			return false
+		case *ast.Ident:
+			switch obj := info.ObjectOf(n).(type) {
+			case *types.Var:
+				if isParam(obj) {
+					refs[obj] = append(refs[obj], n)
+				} else if !obj.IsField() {
+					inUse[n.Name] = true
+				}
+			case *types.Label:
+				// Labels have their own namespace.
+			case nil:
+			default:
+				if obj == fn {
+					recursive = true
+				}
+				inUse[n.Name] = true
+			}
+		}
+		return true
+	}
+	ast.Inspect(decl.Body, visit)
+	if decl.Type.Results != nil {
+		ast.Inspect(decl.Type.Results, visit)
+	}
+
+	// The wrapper that delegates to the new declaration has the original
+	// parameters, named so that it may refer to each of them.
+	var (
+		params   = internalastutil.CloneNode(decl.Type.Params)
+		pnames   []string                // name of each parameter of the wrapper
+		allNames = make(map[string]bool) // names of the receiver and parameters of the wrapper
+	)
+	{
+		for _, fld := range params.List {
+			for _, n := range fld.Names {
+				allNames[n.Name] = true
+			}
+		}
+		if decl.Recv != nil {
+			for _, n := range decl.Recv.List[0].Names {
+				allNames[n.Name] = true
+			}
+		}
+		blanks := 0
+		newName := func() *ast.Ident {
+			for {
+				name := fmt.Sprintf("blank%d", blanks)
+				blanks++
+				if !allNames[name] {
+					return &ast.Ident{Name: name}
+				}
+			}
+		}
+		for _, fld := range params.List {
+			if len(fld.Names) == 0 {
+				fld.Names = []*ast.Ident{newName()}
+			}
+			for i, n := range fld.Names {
+				if n.Name == "_" {
+					fld.Names[i] = newName()
+				}
+				pnames = append(pnames, fld.Names[i].Name)
+				allNames[fld.Names[i].Name] = true
+			}
+		}
+	}
+
+	// check parses and type-checks an expression in the file scope
+	// of the declaration.
+	check := func(src string) (ast.Expr, types.TypeAndValue, error) {
+		expr, err := parser.ParseExpr(src)
+		if err != nil {
+			return nil, types.TypeAndValue{}, err
+		}
+		tinfo := &types.Info{Types: make(map[ast.Expr]types.TypeAndValue)}
+		if err := types.CheckExpr(fset, pkg.Types(), pgf.File.Package, expr, tinfo); err != nil {
+			return nil, types.TypeAndValue{}, err
+		}
+		return expr, tinfo.Types[expr], nil
+	}
+
+	// Compute the new parameters, and the arguments of the delegating call.
+	type newParam struct {
+		name  string
+		field string   // name of struct field, if paramsStruct != ""
+		typ   ast.Expr // type syntax
+		arg   ast.Expr // argument in delegating call
+	}
+	var (
+		nparams  []newParam
+		kept     = make(map[int]bool)          // indices of retained parameters
+		renames  = make(map[*types.Var]string) // new names of retained parameters
+		variadic = false                       // whether the delegating call is variadic
+	)
+	for i, p := range newParams {
+		var np newParam
+		if p.Default != "" {
+			// A new parameter.
+			if p.Name == "" || p.Type == "" {
+				return nil, fmt.Errorf("new parameter %d must have a name and type", i)
+			}
+			typ, tv, err := check(p.Type)
+			if err != nil {
+				return nil, fmt.Errorf("invalid type for parameter %s: %v", p.Name, err)
+			} else if !tv.IsType() {
+				return nil, fmt.Errorf("invalid type for parameter %s: %s is not a type", p.Name, p.Type)
+			}
+			arg, atv, err := check(p.Default)
+			if err != nil {
+				return nil, fmt.Errorf("invalid default for parameter %s: %v", p.Name, err)
+			} else if !atv.IsValue() || !types.AssignableTo(atv.Type, tv.Type) {
+				return nil, fmt.Errorf("invalid default for parameter %s: %s is not assignable to %s", p.Name, p.Default, p.Type)
+			}
+			// The wrapper's parameters must not shadow names used by the default.
+			var shadowed string
+			ast.Inspect(arg, func(n ast.Node) bool {
+				switch n := n.(type) {
+				case *ast.SelectorExpr:
+					ast.Inspect(n.X, func(n ast.Node) bool {
+						if id, ok := n.(*ast.Ident); ok && allNames[id.Name] {
+							shadowed = id.Name
+						}
+						return true
+					})
+					return false
+				case *ast.Ident:
+					if allNames[n.Name] {
+						shadowed = n.Name
+					}
+				}
+				return true
+			})
+			if shadowed != "" {
+				return nil, fmt.Errorf("default for parameter %s refers to %s, which is shadowed by a parameter of %s", p.Name, shadowed, decl.Name.Name)
+			}
+			np = newParam{name: p.Name, typ: typ, arg: arg}
+		} else {
+			// An existing parameter.
+			if p.OldIndex < 0 || p.OldIndex >= len(vars) {
+				return nil, fmt.Errorf("parameter index %d out of range", p.OldIndex)
+			}
+			if kept[p.OldIndex] {
+				return nil, fmt.Errorf("parameter %d appears more than once", p.OldIndex)
+			}
+			kept[p.OldIndex] = true
+			v := vars[p.OldIndex]
+			np = newParam{
+				name: v.Name(),
+				typ:  internalastutil.CloneNode(fields[p.OldIndex].Type),
+				arg:  &ast.Ident{Name: pnames[p.OldIndex]},
+			}
+			if p.Name != "" && p.Name != v.Name() {
+				if p.Name == "_" && len(refs[v]) > 0 {
+					return nil, fmt.Errorf("cannot rename parameter %s to _: it is used by %s", v.Name(), decl.Name.Name)
+				}
+				np.name = p.Name
+				renames[v] = p.Name
+			}
+			isVariadic := sig.Variadic() && p.OldIndex == len(vars)-1
+			if p.Type != "" {
+				if isVariadic {
+					return nil, fmt.Errorf("cannot change the type of variadic parameter %s", v.Name())
+				}
+				typ, tv, err := check(p.Type)
+				if err != nil {
+					return nil, fmt.Errorf("invalid type for parameter %s: %v", v.Name(), err)
+				} else if !tv.IsType() {
+					return nil, fmt.Errorf("invalid type for parameter %s: %s is not a type", v.Name(), p.Type)
+				}
+				explicit, ok := paramConversion(v.Type(), tv.Type)
+				if !ok {
+					return nil, fmt.Errorf("cannot change the type of parameter %s from %s to %s: conversion is not trivial",
+						v.Name(), types.TypeString(v.Type(), types.RelativeTo(pkg.Types())), p.Type)
+				}
+				np.typ = typ
+				if explicit {
+					var fun ast.Expr = internalastutil.CloneNode(typ)
+					if !is[*ast.Ident](fun) && !is[*ast.SelectorExpr](fun) {
+						fun = &ast.ParenExpr{X: fun}
+					}
+					np.arg = &ast.CallExpr{Fun: fun, Args: []ast.Expr{np.arg}}
+				}
+			}
+			if isVariadic {
+				if paramsStruct != "" {
+					np.typ = &ast.ArrayType{Elt: np.typ.(*ast.Ellipsis).Elt}
+				} else if i != len(newParams)-1 {
+					return nil, fmt.Errorf("variadic parameter %s must remain last", v.Name())
+				} else {
+					variadic = true
+				}
+			}
+		}
+		if np.name != "" && np.name != "_" && !token.IsIdentifier(np.name) {
+			return nil, fmt.Errorf("invalid parameter name %q", np.name)
+		}
+		nparams = append(nparams, np)
+	}
+
+	// Removed parameters must be unused.
+	for i, v := range vars {
+		if !kept[i] && len(refs[v]) > 0 {
+			return nil, fmt.Errorf("cannot remove parameter %s: it is used by %s", v.Name(), decl.Name.Name)
+		}
+	}
+
+	// Check that the new names are distinct, and that new or renamed
+	// parameters do not shadow other names used by the function.
+	{
+		seen := make(map[string]bool)
+		for i, np := range nparams {
+			if np.name == "" || np.name == "_" {
+				continue
+			}
+			if seen[np.name] {
+				return nil, fmt.Errorf("duplicate parameter %s", np.name)
+			}
+			seen[np.name] = true
+			p := newParams[i]
+			changed := p.Default != "" || np.name != vars[p.OldIndex].Name()
+			if paramsStruct == "" && changed && inUse[np.name] {
+				return nil, fmt.Errorf("parameter %s would shadow a name used by %s", np.name, decl.Name.Name)
+			}
+		}
+	}
+
+	// Create the new declaration, and the edits to the references to
+	// parameters within its body.
+	var (
+		newDecl   = internalastutil.CloneNode(decl)
+		repl      = make(map[token.Pos]func() ast.Expr) // replacements for references in newDecl
+		bodyEdits []diff.Edit
+		decls     []ast.Decl // additional declarations
+		after     string     // text of additional declarations
+		args      []ast.Expr // delegating call arguments
+	)
+	lbrace, err := safetoken.Offset(pgf.Tok, decl.Body.Lbrace)
+	if err != nil {
+		return nil, err
+	}
+	replace := func(id *ast.Ident, new func() ast.Expr) error {
+		start, end, err := safetoken.Offsets(pgf.Tok, id.Pos(), id.End())
+		if err != nil {
+			return err
+		}
+		repl[id.Pos()] = new
+		bodyEdits = append(bodyEdits, diff.Edit{Start: start - lbrace, End: end - lbrace, New: FormatNode(fset, new())})
+		return nil
+	}
+	if paramsStruct == "" {
+		// The new parameters are a list of fields, combined where possible.
+		named := slices.ContainsFunc(nparams, func(np newParam) bool { return np.name != "" })
+		var list []*ast.Field
+		for _, np := range nparams {
+			args = append(args, np.arg)
+			var name *ast.Ident
+			if named {
+				name = &ast.Ident{Name: cmp.Or(np.name, "_")}
+			}
+			if n := len(list); n > 0 && name != nil && !is[*ast.Ellipsis](np.typ) &&
+				FormatNode(fset, list[n-1].Type) == FormatNode(fset, np.typ) {
+				list[n-1].Names = append(list[n-1].Names, name)
+				continue
+			}
+			fld := &ast.Field{Type: np.typ}
+			if name != nil {
+				fld.Names = []*ast.Ident{name}
+			}
+			list = append(list, fld)
+		}
+		newDecl.Type.Params = &ast.FieldList{List: list}
+		for v, name := range renames {
+			for _, id := range refs[v] {
+				if err := replace(id, func() ast.Expr { return &ast.Ident{Name: name, NamePos: id.Pos()} }); err != nil {
+					return nil, err
+				}
+			}
+		}
+	} else {
+		// The new parameters are the fields of a new struct type.
+		if !token.IsIdentifier(paramsStruct) {
+			return nil, fmt.Errorf("invalid type name %q", paramsStruct)
+		}
+		if pkg.Types().Scope().Lookup(paramsStruct) != nil {
+			return nil, fmt.Errorf("%s is already declared in package %s", paramsStruct, pkg.Types().Name())
+		}
+		var (
+			fieldList []*ast.Field
+			elts      []ast.Expr
+			seen      = make(map[string]bool)
+		)
+		for i := range nparams {
+			np := &nparams[i]
+			if np.name == "" || np.name == "_" {
+				return nil, fmt.Errorf("parameter %d must be named to become a field of %s", i, paramsStruct)
+			}
+			r, size := utf8.DecodeRuneInString(np.name)
+			np.field = string(unicode.ToUpper(r)) + np.name[size:]
+			if seen[np.field] {
+				return nil, fmt.Errorf("duplicate field %s", np.field)
+			}
+			seen[np.field] = true
+			fieldList = append(fieldList, &ast.Field{Names: []*ast.Ident{{Name: np.field}}, Type: np.typ})
+			elts = append(elts, &ast.KeyValueExpr{Key: &ast.Ident{Name: np.field}, Value: np.arg})
+		}
+		typeDecl := &ast.GenDecl{
+			Tok: token.TYPE,
+			Specs: []ast.Spec{&ast.TypeSpec{
+				Name: &ast.Ident{Name: paramsStruct},
+				Type: &ast.StructType{Fields: &ast.FieldList{List: fieldList}},
+			}},
+		}
+		decls = append(decls, typeDecl)
+		// Unlike FormatNode, format.Node aligns the field types.
+		var typeSrc bytes.Buffer
+		if err := format.Node(&typeSrc, fset, typeDecl); err != nil {
+			return nil, bug.Errorf("formatting %s: %v", paramsStruct, err)
+		}
+		after = fmt.Sprintf("// %s holds the parameters of %s.\n%s", paramsStruct, decl.Name.Name, &typeSrc)
+		args = []ast.Expr{&ast.CompositeLit{Type: &ast.Ident{Name: paramsStruct}, Elts: elts}}
+
+		// Choose a name for the parameter, and replace each reference
+		// to a retained parameter by a reference to its field.
+		opts := "opts"
+		for i := 0; inUse[opts]; i++ {
+			opts = fmt.Sprintf("opts%d", i)
+		}
+		newDecl.Type.Params = &ast.FieldList{List: []*ast.Field{{
+			Names: []*ast.Ident{{Name: opts}},
+			Type:  &ast.Ident{Name: paramsStruct},
+		}}}
+		for i, p := range newParams {
+			if p.Default != "" {
+				continue
+			}
+			field := nparams[i].field
+			for _, id := range refs[vars[p.OldIndex]] {
+				err := replace(id, func() ast.Expr {
+					return &ast.SelectorExpr{X: &ast.Ident{Name: opts, NamePos: id.Pos()}, Sel: &ast.Ident{Name: field}}
+				})
+				if err != nil {
+					return nil, err
+				}
+			}
+		}
+	}
+	if len(bodyEdits) > 0 {
+		// Inlining would modify recursive calls within the body,
+		// invalidating the edits.
+		if recursive {
+			return nil, fmt.Errorf("cannot change the parameter names of recursive function %s", decl.Name.Name)
+		}
+		newDecl.Body = astutil.Apply(newDecl.Body, func(c *astutil.Cursor) bool {
+			if id, ok := c.Node().(*ast.Ident); ok {
+				if new, ok := repl[id.Pos()]; ok {
+					c.Replace(new())
+				}
+			}
+			return true
+		}, nil).(*ast.BlockStmt)
+	}
+
+	// Rewrite all referring calls.
+	newContent, err := rewriteCalls(ctx, signatureRewrite{
+		snapshot: snapshot,
+		pkg:      pkg,
+		pgf:      pgf,
+		origDecl: decl,
+		newDecl:  newDecl,
+		params:   params,
+		callArgs: args,
+		variadic: variadic,
+		decls:    decls,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// Finally, rewrite the original declaration.
+	{
+		idx := findDecl(pgf.File, decl)
+		if idx < 0 {
+			return nil, bug.Errorf("didn't find original decl")
+		}
+		src, ok := newContent[pgf.URI]
+		if !ok {
+			src = pgf.Src
+		}
+		src, err := rewriteSignature(tokeninternal.FileSetFor(pgf.Tok), idx, src, newDecl, bodyEdits, after)
+		if err != nil {
+			return nil, err
+		}
+		newContent[pgf.URI] = src
+	}
+
+	return documentChanges(ctx, snapshot, newContent)
+}
+
+// paramConversion reports whether a parameter of type from may be changed
+// to type to without loss of information, and if so, whether each argument
+// then requires an explicit conversion, as when widening a numeric type
+// or converting between named types with identical underlying types.
+func paramConversion(from, to types.Type) (explicit, ok bool) {
+	if types.AssignableTo(from, to) {
+		return false, true
+	}
+	if !is[*types.TypeParam](from) && !is[*types.TypeParam](to) &&
+		types.Identical(from.Underlying(), to.Underlying()) {
+		return true, true
+	}
+	fb, ok1 := from.Underlying().(*types.Basic)
+	tb, ok2 := to.Underlying().(*types.Basic)
+	if !ok1 || !ok2 {
+		return false, false
+	}
+	// bits returns the size of an integer type. The size of int, uint,
+	// and uintptr is platform dependent, so we assume the larger size of
+	// a source type and the smaller size of a target type.
+	bits := func(t *types.Basic, target bool) int {
+		switch t.Kind() {
+		case types.Int8, types.Uint8:
+			return 8
+		case types.Int16, types.Uint16:
+			return 16
+		case types.Int32, types.Uint32:
+			return 32
+		case types.Int64, types.Uint64:
+			return 64
+		case types.Int, types.Uint, types.Uintptr:
+			if target {
+				return 32
+			}
+			return 64
+		}
+		return 0
+	}
+	switch {
+	case fb.Info()&types.IsInteger != 0 && tb.Info()&types.IsInteger != 0:
+		fbits, tbits := bits(fb, false), bits(tb, true)
+		fsigned := fb.Info()&types.IsUnsigned == 0
+		tsigned := tb.Info()&types.IsUnsigned == 0
+		switch {
+		case fsigned == tsigned:
+			return true, tbits >= fbits
+		case tsigned:
+			return true, tbits > fbits
+		}
+	case fb.Kind() == types.Float32 && tb.Kind() == types.Float64,
+		fb.Kind() == types.Complex64 && tb.Kind() == types.Complex128:
+		return true, true
+	}
+	return false, false
+}
+
 // rewriteSignature rewrites the signature of the declIdx'th declaration in src
 // to use the signature of newDecl (described by fset).
 //
+// If bodyEdits is non-empty, the edits, whose offsets are relative to the
+// opening brace of the function body, are applied to the body too; the
+// caller must ensure that the body was not changed by inlining. If after is
+// non-empty, it is inserted as a new declaration following the function.
+//
 // TODO(rfindley): I think this operation could be generalized, for example by
 // using a concept of a 'nodepath' to correlate nodes between two related
 // files.
@@ -189,7 +735,7 @@ func RemoveUnusedParameter(ctx context.Context, fh file.Handle, rng protocol.Ran
 // Note that with its current application, rewriteSignature is expected to
 // succeed. Separate bug.Errorf calls are used below (rather than one call at
 // the callsite) in order to have greater precision.
-func rewriteSignature(fset *token.FileSet, declIdx int, src0 []byte, newDecl *ast.FuncDecl) ([]byte, error) {
+func rewriteSignature(fset *token.FileSet, declIdx int, src0 []byte, newDecl *ast.FuncDecl, bodyEdits []diff.Edit, after string) ([]byte, error) {
 	// Parse the new file0 content, to locate the original params.
 	file0, err := parser.ParseFile(fset, "", src0, parser.ParseComments|parser.SkipObjectResolution)
 	if err != nil {
@@ -221,11 +767,32 @@ func rewriteSignature(fset *token.FileSet, declIdx int, src0 []byte, newDecl *as
 	newParams := formattedType[opening1 : closing1+1]
 
 	// Splice.
-	var buf bytes.Buffer
-	buf.Write(src0[:opening0])
-	buf.WriteString(newParams)
-	buf.Write(src0[closing0+1:])
-	newSrc := buf.Bytes()
+	edits := []diff.Edit{{Start: opening0, End: closing0 + 1, New: newParams}}
+	if len(bodyEdits) > 0 {
+		lbrace0, err := safetoken.Offset(fset.File(decl0.Pos()), decl0.Body.Lbrace)
+		if err != nil {
+			return nil, bug.Errorf("can't find body: %v", err)
+		}
+		for _, edit := range bodyEdits {
+			edit.Start += lbrace0
+			edit.End += lbrace0
+			if edit.End > len(src0) || !token.IsIdentifier(string(src0[edit.Start:edit.End])) {
+				return nil, bug.Errorf("inlining affected the body of func %s", newDecl.Name.Name)
+			}
+			edits = append(edits, edit)
+		}
+	}
+	if after != "" {
+		end0, err := safetoken.Offset(fset.File(decl0.Pos()), decl0.End())
+		if err != nil {
+			return nil, bug.Errorf("can't find end of declaration: %v", err)
+		}
+		edits = append(edits, diff.Edit{Start: end0, End: end0, New: "\n\n" + after})
+	}
+	newSrc, err := diff.ApplyBytes(src0, edits)
+	if err != nil {
+		return nil, bug.Errorf("applying signature edits: %v", err)
+	}
 	if len(file0.Imports) > 0 {
 		formatted, err := imports.Process("output", newSrc, nil)
 		if err != nil {
@@ -311,6 +878,7 @@ type signatureRewrite struct {
 	params            *ast.FieldList
 	callArgs          []ast.Expr
 	variadic          bool
+	decls             []ast.Decl // additional declarations needed by newDecl
 }
 
 // rewriteCalls returns the document changes required to rewrite the
@@ -446,6 +1014,9 @@ func rewriteCalls(ctx context.Context, rw signatureRewrite) (map[protocol.Docume
 		// TODO(rfindley): we can probably get away with one fewer parse operations
 		// by returning the modified AST from replaceDecl. Investigate if that is
 		// accurate.
+		for _, decl := range rw.decls {
+			modifiedSrc = append(modifiedSrc, []byte("\n\n"+FormatNode(fset, decl))...)
+		}
 		modifiedSrc = append(modifiedSrc, []byte("\n\n"+FormatNode(fset, wrapper))...)
 		modifiedFile, err = parser.ParseFile(rw.pkg.FileSet(), rw.pgf.URI.Path(), modifiedSrc, parser.ParseComments|parser.SkipObjectResolution)
 		if err != nil {
@@ -234,10 +234,14 @@ var codeActionProducers = [...]codeActionProducer{
 	{kind: settings.GoFreeSymbols, fn: goFreeSymbols},
 	{kind: settings.GoTest, fn: goTest},
 	{kind: settings.GoplsDocFeatures, fn: goplsDocFeatures},
+	{kind: settings.RefactorExtractConstant, fn: refactorExtractConstant, needPkg: true},
+	{kind: settings.RefactorExtractConstantAll, fn: refactorExtractConstantAll, needPkg: true},
 	{kind: settings.RefactorExtractFunction, fn: refactorExtractFunction},
 	{kind: settings.RefactorExtractMethod, fn: refactorExtractMethod},
 	{kind: settings.RefactorExtractToNewFile, fn: refactorExtractToNewFile},
+	{kind: settings.RefactorExtractType, fn: refactorExtractType},
 	{kind: settings.RefactorExtractVariable, fn: refactorExtractVariable},
+	{kind: settings.RefactorExtractVariableAll, fn: refactorExtractVariableAll, needPkg: true},
 	{kind: settings.RefactorInlineCall, fn: refactorInlineCall, needPkg: true},
 	{kind: settings.RefactorRewriteChangeQuote, fn: refactorRewriteChangeQuote},
 	{kind: settings.RefactorRewriteFillStruct, fn: refactorRewriteFillStruct, needPkg: true},
@@ -423,7 +427,14 @@ type allImportsFixesResult struct {
 }
 
 func (res *allImportsFixesResult) init(ctx context.Context, req *codeActionsRequest) {
-	res.allFixEdits, res.editsPerFix, res.err = allImportsFixes(ctx, req.snapshot, req.pgf)
+	// Type information makes the fixes more precise. It is usually
+	// already available, as the package of an open file is checked
+	// for diagnostics; if not, fall back to syntactic heuristics.
+	pkg := req.pkg
+	if pkg == nil {
+		pkg, _, _ = NarrowestPackageForFile(ctx, req.snapshot, req.loc.URI)
+	}
+	res.allFixEdits, res.editsPerFix, res.err = allImportsFixes(ctx, req.snapshot, pkg, req.pgf)
 	if res.err != nil {
 		event.Error(ctx, "imports fixes", res.err, label.File.Of(req.loc.URI.Path()))
 	}
@@ -537,6 +548,42 @@ func refactorExtractVariable(ctx context.Context, req *codeActionsRequest) error
 	return nil
 }
 
+// refactorExtractVariableAll produces "Extract N occurrences to variable" code actions.
+// See [extractVariableAll] for command implementation.
+func refactorExtractVariableAll(ctx context.Context, req *codeActionsRequest) error {
+	if n, ok := canExtractAll(req.pkg.FileSet(), req.start, req.end, req.pgf.File, req.pkg.TypesInfo(), false); ok {
+		req.addApplyFixAction(fmt.Sprintf("Extract %d occurrences to variable", n+1), fixExtractVariableAll, req.loc)
+	}
+	return nil
+}
+
+// refactorExtractConstant produces "Extract constant" code actions.
+// See [extractConstant] for command implementation.
+func refactorExtractConstant(ctx context.Context, req *codeActionsRequest) error {
+	if expr, _, ok, _ := canExtractVariable(req.start, req.end, req.pgf.File); ok && req.pkg.TypesInfo().Types[expr].Value != nil {
+		req.addApplyFixAction("Extract constant", fixExtractConstant, req.loc)
+	}
+	return nil
+}
+
+// refactorExtractConstantAll produces "Extract N occurrences to constant" code actions.
+// See [extractConstantAll] for command implementation.
+func refactorExtractConstantAll(ctx context.Context, req *codeActionsRequest) error {
+	if n, ok := canExtractAll(req.pkg.FileSet(), req.start, req.end, req.pgf.File, req.pkg.TypesInfo(), true); ok {
+		req.addApplyFixAction(fmt.Sprintf("Extract %d occurrences to constant", n+1), fixExtractConstantAll, req.loc)
+	}
+	return nil
+}
+
+// refactorExtractType produces "Extract type" code actions.
+// See [extractType] for command implementation.
+func refactorExtractType(ctx context.Context, req *codeActionsRequest) error {
+	if _, _, err := canExtractType(req.start, req.end, req.pgf.File); err == nil {
+		req.addApplyFixAction("Extract type", fixExtractType, req.loc)
+	}
+	return nil
+}
+
 // refactorExtractToNewFile produces "Extract declarations to new file" code actions.
 // See [server.commandHandler.ExtractToNewFile] for command implementation.
 func refactorExtractToNewFile(ctx context.Context, req *codeActionsRequest) error {
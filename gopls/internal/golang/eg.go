@@ -0,0 +1,183 @@
+// Copyright 2024 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package golang
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"go/ast"
+	"go/format"
+	"go/parser"
+	"go/token"
+	"go/types"
+
+	"golang.org/x/tools/gopls/internal/cache"
+	"golang.org/x/tools/gopls/internal/file"
+	"golang.org/x/tools/gopls/internal/protocol"
+	"golang.org/x/tools/internal/diff"
+	"golang.org/x/tools/internal/event"
+	"golang.org/x/tools/refactor/eg"
+)
+
+// EgRewrite applies the example-based refactoring template in the
+// file tmpl (see golang.org/x/tools/refactor/eg) to the workspace
+// packages, and returns the resulting changes. Packages with errors
+// are not changed.
+//
+// The transformer mutates the syntax trees to which it is applied, so
+// each package is parsed and type-checked again, against the types of
+// its dependencies in the snapshot, with which the template is also
+// type-checked. Consequently, references within a package to its own
+// objects never match those of the template.
+//
+// TODO: fix that, if the need arises.
+func EgRewrite(ctx context.Context, snapshot *cache.Snapshot, tmpl file.Handle) ([]protocol.DocumentChange, error) {
+	ctx, done := event.Start(ctx, "golang.EgRewrite")
+	defer done()
+
+	mps, err := snapshot.WorkspaceMetadata(ctx)
+	if err != nil {
+		return nil, err
+	}
+	var ids []PackageID
+	for _, mp := range mps {
+		if !mp.IsIntermediateTestVariant() {
+			ids = append(ids, mp.ID)
+		}
+	}
+	pkgs, err := snapshot.TypeCheck(ctx, ids...)
+	if err != nil {
+		return nil, err
+	}
+
+	// All the packages were type-checked together, so they
+	// share the types of their dependencies.
+	importsByPath := make(map[string]*types.Package)
+	var addImports func(pkg *types.Package)
+	addImports = func(pkg *types.Package) {
+		if _, ok := importsByPath[pkg.Path()]; !ok {
+			importsByPath[pkg.Path()] = pkg
+			for _, imp := range pkg.Imports() {
+				addImports(imp)
+			}
+		}
+	}
+	for _, pkg := range pkgs {
+		addImports(pkg.Types())
+	}
+	importer := func(path string) (*types.Package, error) {
+		if pkg, ok := importsByPath[path]; ok {
+			return pkg, nil
+		}
+		return nil, fmt.Errorf("package %q is not a dependency of the workspace", path)
+	}
+
+	// Parse and type-check the template.
+	fset := token.NewFileSet()
+	content, err := tmpl.Content()
+	if err != nil {
+		return nil, err
+	}
+	tmplFile, err := parser.ParseFile(fset, tmpl.URI().Path(), content, parser.ParseComments)
+	if err != nil {
+		return nil, fmt.Errorf("parsing template: %v", err)
+	}
+	tmplInfo := newEgInfo()
+	cfg := &types.Config{Importer: ImporterFunc(importer)}
+	tmplPkg, err := cfg.Check("egtemplate", fset, []*ast.File{tmplFile}, tmplInfo)
+	if err != nil {
+		return nil, fmt.Errorf("type-checking template: %v", err)
+	}
+	xform, err := eg.NewTransformer(fset, tmplPkg, tmplFile, tmplInfo, false)
+	if err != nil {
+		return nil, err
+	}
+
+	var changes []protocol.DocumentChange
+	seen := make(map[protocol.DocumentURI]bool) // a file may belong to a package and its test variant
+	for _, pkg := range pkgs {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		if len(pkg.ParseErrors()) > 0 || len(pkg.TypeErrors()) > 0 {
+			continue
+		}
+		mp := pkg.Metadata()
+
+		// Parse the files of the package again.
+		var (
+			files []*ast.File
+			fhs   []file.Handle
+		)
+		for _, pgf := range pkg.CompiledGoFiles() {
+			fh, err := snapshot.ReadFile(ctx, pgf.URI)
+			if err != nil {
+				return nil, err
+			}
+			src, err := fh.Content()
+			if err != nil {
+				return nil, err
+			}
+			f, err := parser.ParseFile(fset, pgf.URI.Path(), src, parser.ParseComments)
+			if err != nil {
+				return nil, err // can't happen: the package has no parse errors
+			}
+			files = append(files, f)
+			fhs = append(fhs, fh)
+		}
+
+		info := newEgInfo()
+		cfg := &types.Config{
+			Importer: ImporterFunc(importer),
+			Sizes:    pkg.TypesSizes(),
+			Error:    func(error) {},
+		}
+		if mp.Module != nil && mp.Module.GoVersion != "" {
+			cfg.GoVersion = "go" + mp.Module.GoVersion
+		}
+		tpkg := types.NewPackage(string(mp.PkgPath), string(mp.Name))
+		if err := types.NewChecker(cfg, fset, tpkg, info).Files(files); err != nil {
+			continue // e.g. a missing dependency; ignore the package
+		}
+
+		for i, f := range files {
+			fh := fhs[i]
+			if fh.URI() == tmpl.URI() || seen[fh.URI()] {
+				continue
+			}
+			seen[fh.URI()] = true
+			if xform.Transform(info, tpkg, f) == 0 {
+				continue
+			}
+
+			var buf bytes.Buffer
+			if err := format.Node(&buf, fset, f); err != nil {
+				return nil, fmt.Errorf("formatting %s: %v", fh.URI().Path(), err)
+			}
+			before, _ := fh.Content()
+			edits := diff.Bytes(before, buf.Bytes())
+			textedits, err := protocol.EditsFromDiffEdits(protocol.NewMapper(fh.URI(), before), edits)
+			if err != nil {
+				return nil, fmt.Errorf("computing edits for %s: %v", fh.URI(), err)
+			}
+			changes = append(changes, protocol.DocumentChangeEdit(fh, textedits))
+		}
+	}
+	return changes, nil
+}
+
+// newEgInfo returns a types.Info that records everything needed by
+// the eg transformer.
+func newEgInfo() *types.Info {
+	return &types.Info{
+		Types:      make(map[ast.Expr]types.TypeAndValue),
+		Defs:       make(map[*ast.Ident]types.Object),
+		Uses:       make(map[*ast.Ident]types.Object),
+		Implicits:  make(map[ast.Node]types.Object),
+		Selections: make(map[*ast.SelectorExpr]*types.Selection),
+		Scopes:     make(map[ast.Node]*types.Scope),
+	}
+}
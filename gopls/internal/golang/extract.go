@@ -12,6 +12,8 @@ import (
 	"go/parser"
 	"go/token"
 	"go/types"
+	"reflect"
+	"slices"
 	"sort"
 	"strings"
 	"text/scanner"
@@ -21,29 +23,80 @@ import (
 	"golang.org/x/tools/gopls/internal/util/bug"
 	"golang.org/x/tools/gopls/internal/util/safetoken"
 	"golang.org/x/tools/internal/analysisinternal"
+	"golang.org/x/tools/internal/typeparams"
 )
 
+// extractVariable implements the refactor.extract.variable CodeAction command.
 func extractVariable(fset *token.FileSet, start, end token.Pos, src []byte, file *ast.File, pkg *types.Package, info *types.Info) (*token.FileSet, *analysis.SuggestedFix, error) {
+	return extractExprs(fset, start, end, src, file, pkg, info, false, false)
+}
+
+// extractVariableAll implements the refactor.extract.variable-all CodeAction command.
+func extractVariableAll(fset *token.FileSet, start, end token.Pos, src []byte, file *ast.File, pkg *types.Package, info *types.Info) (*token.FileSet, *analysis.SuggestedFix, error) {
+	return extractExprs(fset, start, end, src, file, pkg, info, true, false)
+}
+
+// extractConstant implements the refactor.extract.constant CodeAction command.
+func extractConstant(fset *token.FileSet, start, end token.Pos, src []byte, file *ast.File, pkg *types.Package, info *types.Info) (*token.FileSet, *analysis.SuggestedFix, error) {
+	return extractExprs(fset, start, end, src, file, pkg, info, false, true)
+}
+
+// extractConstantAll implements the refactor.extract.constant-all CodeAction command.
+func extractConstantAll(fset *token.FileSet, start, end token.Pos, src []byte, file *ast.File, pkg *types.Package, info *types.Info) (*token.FileSet, *analysis.SuggestedFix, error) {
+	return extractExprs(fset, start, end, src, file, pkg, info, true, true)
+}
+
+// extractExprs replaces the selected expression by a reference to a new
+// local variable or, if constant is set, a new constant, which is
+// declared at package level unless the expression refers to local
+// constants. If all is set, every identical occurrence of the
+// expression in the enclosing function is replaced too.
+func extractExprs(fset *token.FileSet, start, end token.Pos, src []byte, file *ast.File, pkg *types.Package, info *types.Info, all, constant bool) (*token.FileSet, *analysis.SuggestedFix, error) {
 	tokFile := fset.File(file.FileStart)
 	expr, path, ok, err := canExtractVariable(start, end, file)
 	if !ok {
 		return nil, nil, fmt.Errorf("extractVariable: cannot extract %s: %v", safetoken.StartPosition(fset, start), err)
 	}
+	if constant {
+		if info.Types[expr].Value == nil {
+			return nil, nil, fmt.Errorf("cannot extract non-constant expression %s to a constant", FormatNode(fset, expr))
+		}
+		if usesIota(info, expr) {
+			return nil, nil, fmt.Errorf("cannot extract %s, as it refers to iota", FormatNode(fset, expr))
+		}
+	}
+
+	// Find the occurrences to replace.
+	exprs, paths := []ast.Expr{expr}, [][]ast.Node{path}
+	if all {
+		exprs, paths, err = findOccurrences(fset, file, info, expr, path, !constant)
+		if err != nil {
+			return nil, nil, err
+		}
+	}
+
+	// A constant that refers only to package-level names is
+	// declared at package level.
+	pkgLevel := constant && !refersToLocals(info, pkg, expr)
 
 	// Create new AST node for extracted code.
+	prefix := "x"
+	if constant {
+		prefix = "k"
+	}
 	var lhsNames []string
 	switch expr := expr.(type) {
 	// TODO: stricter rules for selectorExpr.
 	case *ast.BasicLit, *ast.CompositeLit, *ast.IndexExpr, *ast.SliceExpr,
 		*ast.UnaryExpr, *ast.BinaryExpr, *ast.SelectorExpr:
-		lhsName, _ := generateAvailableIdentifier(expr.Pos(), path, pkg, info, "x", 0)
+		lhsName, _ := freshName(info, pkg, paths, pkgLevel, prefix, 0)
 		lhsNames = append(lhsNames, lhsName)
 	case *ast.CallExpr:
 		tup, ok := info.TypeOf(expr).(*types.Tuple)
 		if !ok {
 			// If the call expression only has one return value, we can treat it the
 			// same as our standard extract variable case.
-			lhsName, _ := generateAvailableIdentifier(expr.Pos(), path, pkg, info, "x", 0)
+			lhsName, _ := freshName(info, pkg, paths, pkgLevel, prefix, 0)
 			lhsNames = append(lhsNames, lhsName)
 			break
 		}
@@ -51,58 +104,95 @@ func extractVariable(fset *token.FileSet, start, end token.Pos, src []byte, file
 		for i := 0; i < tup.Len(); i++ {
 			// Generate a unique variable for each return value.
 			var lhsName string
-			lhsName, idx = generateAvailableIdentifier(expr.Pos(), path, pkg, info, "x", idx)
+			lhsName, idx = freshName(info, pkg, paths, pkgLevel, prefix, idx)
 			lhsNames = append(lhsNames, lhsName)
 		}
 	default:
 		return nil, nil, fmt.Errorf("cannot extract %T", expr)
 	}
 
-	// TODO: There is a bug here: for a variable declared in a labeled
-	// switch/for statement it returns the for/switch statement itself
-	// which produces the below code which is a compiler error e.g.
-	// label:
-	// switch r1 := r() { ... break label ... }
-	// On extracting "r()" to a variable
-	// label:
-	// x := r()
-	// switch r1 := x { ... break label ... } // compiler error
-	insertBeforeStmt := analysisinternal.StmtToInsertVarBefore(path)
-	if insertBeforeStmt == nil {
-		return nil, nil, fmt.Errorf("cannot find location to insert extraction")
-	}
-	indent, err := calculateIndentation(src, tokFile, insertBeforeStmt)
-	if err != nil {
-		return nil, nil, err
+	// Find the position of the new declaration.
+	var (
+		insertPos token.Pos // position before which to insert the declaration
+		indent    string    // indentation of the declaration
+	)
+	if pkgLevel {
+		insertPos = declStart(path[len(path)-2].(ast.Decl))
+	} else {
+		// TODO: There is a bug here: for a variable declared in a labeled
+		// switch/for statement it returns the for/switch statement itself
+		// which produces the below code which is a compiler error e.g.
+		// label:
+		// switch r1 := r() { ... break label ... }
+		// On extracting "r()" to a variable
+		// label:
+		// x := r()
+		// switch r1 := x { ... break label ... } // compiler error
+		insertBeforeStmt := insertionStmt(paths)
+		if insertBeforeStmt == nil {
+			return nil, nil, fmt.Errorf("cannot find location to insert extraction")
+		}
+		insertPos = insertBeforeStmt.Pos()
+		if err := checkVisible(info, file, expr, insertPos); err != nil {
+			return nil, nil, err
+		}
+		if all && !constant {
+			if err := checkUnmodified(fset, info, file, expr, exprs, paths, insertPos); err != nil {
+				return nil, nil, err
+			}
+		}
+		indent, err = calculateIndentation(src, tokFile, insertBeforeStmt)
+		if err != nil {
+			return nil, nil, err
+		}
 	}
-	newLineIndent := "\n" + indent
 
 	lhs := strings.Join(lhsNames, ", ")
-	assignStmt := &ast.AssignStmt{
-		Lhs: []ast.Expr{ast.NewIdent(lhs)},
-		Tok: token.DEFINE,
-		Rhs: []ast.Expr{expr},
+	var decl ast.Node
+	if constant {
+		decl = &ast.GenDecl{
+			Tok: token.CONST,
+			Specs: []ast.Spec{&ast.ValueSpec{
+				Names:  []*ast.Ident{ast.NewIdent(lhs)},
+				Values: []ast.Expr{expr},
+			}},
+		}
+	} else {
+		decl = &ast.AssignStmt{
+			Lhs: []ast.Expr{ast.NewIdent(lhs)},
+			Tok: token.DEFINE,
+			Rhs: []ast.Expr{expr},
+		}
 	}
 	var buf bytes.Buffer
-	if err := format.Node(&buf, fset, assignStmt); err != nil {
+	if err := format.Node(&buf, fset, decl); err != nil {
 		return nil, nil, err
 	}
-	assignment := strings.ReplaceAll(buf.String(), "\n", newLineIndent) + newLineIndent
-
-	return fset, &analysis.SuggestedFix{
-		TextEdits: []analysis.TextEdit{
-			{
-				Pos:     insertBeforeStmt.Pos(),
-				End:     insertBeforeStmt.Pos(),
-				NewText: []byte(assignment),
-			},
-			{
-				Pos:     start,
-				End:     end,
-				NewText: []byte(lhs),
-			},
-		},
-	}, nil
+	var declText string
+	if pkgLevel {
+		declText = buf.String() + "\n\n"
+	} else {
+		newLineIndent := "\n" + indent
+		declText = strings.ReplaceAll(buf.String(), "\n", newLineIndent) + newLineIndent
+	}
+
+	edits := []analysis.TextEdit{{
+		Pos:     insertPos,
+		End:     insertPos,
+		NewText: []byte(declText),
+	}}
+	for i, expr := range exprs {
+		var old ast.Node = expr
+		if all && len(paths[i]) > 1 && is[*ast.ParenExpr](paths[i][1]) {
+			old = paths[i][1] // replace (expr) by name
+		}
+		edits = append(edits, analysis.TextEdit{
+			Pos:     old.Pos(),
+			End:     old.End(),
+			NewText: []byte(lhs),
+		})
+	}
+	return fset, &analysis.SuggestedFix{TextEdits: edits}, nil
 }
 
 // canExtractVariable reports whether the code in the given range can be
@@ -136,6 +226,552 @@ func canExtractVariable(start, end token.Pos, file *ast.File) (ast.Expr, []ast.N
 	return nil, nil, false, fmt.Errorf("cannot extract an %T to a variable", expr)
 }
 
+// canExtractAll reports whether the expression in the given range can be
+// extracted, along with the n other occurrences of it in the enclosing
+// function, to a variable, or, if constant is set, to a constant.
+func canExtractAll(fset *token.FileSet, start, end token.Pos, file *ast.File, info *types.Info, constant bool) (n int, ok bool) {
+	expr, path, ok, _ := canExtractVariable(start, end, file)
+	if !ok || constant && info.Types[expr].Value == nil {
+		return 0, false
+	}
+	exprs, _, err := findOccurrences(fset, file, info, expr, path, !constant)
+	if err != nil || len(exprs) < 2 {
+		return 0, false
+	}
+	return len(exprs) - 1, true
+}
+
+// findOccurrences returns the expressions, and their paths, within the
+// function enclosing expr (whose path is given) that are identical to
+// expr, including expr itself, in order.
+//
+// If variable is set, the occurrences are to be replaced by a variable
+// whose value is that of expr, so expr must have no effects.
+func findOccurrences(fset *token.FileSet, file *ast.File, info *types.Info, expr ast.Expr, path []ast.Node, variable bool) ([]ast.Expr, [][]ast.Node, error) {
+	var body *ast.BlockStmt
+	for _, n := range path {
+		if decl, ok := n.(*ast.FuncDecl); ok {
+			body = decl.Body
+		}
+	}
+	if body == nil {
+		return nil, nil, fmt.Errorf("cannot extract all occurrences of an expression outside a function")
+	}
+	text := FormatNode(fset, expr)
+	if variable {
+		var err error
+		ast.Inspect(expr, func(n ast.Node) bool {
+			switch n := n.(type) {
+			case *ast.CallExpr:
+				if tv := info.Types[n.Fun]; tv.IsType() {
+					break // conversion
+				} else if id, ok := ast.Unparen(n.Fun).(*ast.Ident); ok && tv.IsBuiltin() {
+					switch id.Name {
+					case "len", "cap", "min", "max", "real", "imag", "complex":
+						return true
+					}
+				}
+				err = fmt.Errorf("cannot extract all occurrences of %s, as it contains a call", text)
+			case *ast.UnaryExpr:
+				if n.Op == token.ARROW {
+					err = fmt.Errorf("cannot extract all occurrences of %s, as it contains a receive", text)
+				}
+			}
+			return err == nil
+		})
+		if err != nil {
+			return nil, nil, err
+		}
+	}
+
+	var (
+		objs  = identObjs(info, expr)
+		typ   = info.TypeOf(expr)
+		exprs []ast.Expr
+		paths [][]ast.Node
+	)
+	ast.Inspect(body, func(n ast.Node) bool {
+		e, ok := n.(ast.Expr)
+		if !ok || reflect.TypeOf(e) != reflect.TypeOf(expr) ||
+			FormatNode(fset, e) != text ||
+			!slices.Equal(identObjs(info, e), objs) {
+			return true
+		}
+		// A variable has a single type, whereas the (untyped)
+		// constant operands of an expression may differ in type.
+		if variable && !types.Identical(info.TypeOf(e), typ) {
+			return true
+		}
+		path, _ := astutil.PathEnclosingInterval(file, e.Pos(), e.End())
+		for len(path) > 0 && path[0] != e {
+			path = path[1:]
+		}
+		if len(path) > 0 {
+			exprs = append(exprs, e)
+			paths = append(paths, path)
+		}
+		return false
+	})
+	if !slices.Contains(exprs, expr) {
+		return nil, nil, bug.Errorf("selected expression is not among its occurrences")
+	}
+	return exprs, paths, nil
+}
+
+// identObjs returns the objects denoted by the identifiers of expr, in order.
+func identObjs(info *types.Info, expr ast.Expr) []types.Object {
+	var objs []types.Object
+	ast.Inspect(expr, func(n ast.Node) bool {
+		if id, ok := n.(*ast.Ident); ok {
+			objs = append(objs, info.ObjectOf(id))
+		}
+		return true
+	})
+	return objs
+}
+
+// checkUnmodified returns an error if, between the declaration of a
+// variable at pos and the last occurrence of expr (one of exprs, at the
+// given paths), an occurrence may be updated, a variable to which expr
+// refers may be assigned, or, if expr reads memory that a function might
+// write, a function may be called, as then the value of the variable
+// would differ from that of the later occurrences. A loop that encloses
+// an occurrence but not pos is checked in full, as its later iterations
+// follow its end.
+func checkUnmodified(fset *token.FileSet, info *types.Info, file *ast.File, expr ast.Expr, exprs []ast.Expr, paths [][]ast.Node, pos token.Pos) error {
+	from, to := pos, exprs[len(exprs)-1].End()
+	for _, path := range paths {
+		for _, n := range path {
+			switch n.(type) {
+			case *ast.ForStmt, *ast.RangeStmt:
+				if !(n.Pos() <= from && from < n.End()) {
+					to = max(to, n.End())
+				}
+			}
+		}
+	}
+
+	vars := make(map[types.Object]bool)
+	for _, obj := range identObjs(info, expr) {
+		if v, ok := obj.(*types.Var); ok && !v.IsField() {
+			vars[v] = true
+		}
+	}
+	updated := func(e ast.Expr) bool {
+		for {
+			e = ast.Unparen(e)
+			if slices.Contains(exprs, e) {
+				return true
+			}
+			switch x := e.(type) {
+			case *ast.Ident:
+				return vars[info.ObjectOf(x)]
+			case *ast.SelectorExpr:
+				e = x.X
+			case *ast.IndexExpr:
+				e = x.X
+			case *ast.StarExpr:
+				e = x.X
+			default:
+				return false
+			}
+		}
+	}
+	shared := readsShared(info, expr)
+	found := false
+	ast.Inspect(file, func(n ast.Node) bool {
+		if n == nil || found || n.End() <= from || n.Pos() >= to {
+			return false
+		}
+		switch n := n.(type) {
+		case ast.Expr:
+			if slices.Contains(exprs, n) {
+				return false // calls within an occurrence are evaluated once anyway
+			}
+			if call, ok := n.(*ast.CallExpr); ok && shared {
+				tv := info.Types[call.Fun]
+				found = !tv.IsType() && !tv.IsBuiltin()
+			}
+			if u, ok := n.(*ast.UnaryExpr); ok {
+				found = found || u.Op == token.AND && updated(u.X)
+			}
+		case *ast.AssignStmt:
+			found = slices.ContainsFunc(n.Lhs, updated)
+		case *ast.IncDecStmt:
+			found = updated(n.X)
+		case *ast.RangeStmt:
+			found = n.Tok == token.ASSIGN &&
+				(n.Key != nil && updated(n.Key) || n.Value != nil && updated(n.Value))
+		}
+		return !found
+	})
+	if found {
+		return fmt.Errorf("cannot extract all occurrences of %s, as its value may change between them", FormatNode(fset, expr))
+	}
+	return nil
+}
+
+// readsShared reports whether expr reads a package-level variable,
+// dereferences a pointer, or indexes a map or slice: memory that a
+// called function might write.
+func readsShared(info *types.Info, expr ast.Expr) bool {
+	shared := false
+	ast.Inspect(expr, func(n ast.Node) bool {
+		switch n := n.(type) {
+		case *ast.Ident:
+			if v, ok := info.Uses[n].(*types.Var); ok && !v.IsField() && v.Pkg() != nil && v.Parent() == v.Pkg().Scope() {
+				shared = true
+			}
+		case *ast.StarExpr:
+			shared = true
+		case *ast.SelectorExpr:
+			if sel, ok := info.Selections[n]; ok && sel.Kind() == types.FieldVal && sel.Indirect() {
+				shared = true
+			}
+		case *ast.IndexExpr:
+			switch typeparams.CoreType(info.TypeOf(n.X)).(type) {
+			case *types.Map, *types.Slice, *types.Pointer:
+				shared = true
+			}
+		}
+		return !shared
+	})
+	return shared
+}
+
+// insertionStmt returns the statement before which to declare a variable
+// to replace the expressions at the given paths: the statement, within the
+// innermost block enclosing all of the expressions, that encloses the
+// first of them.
+func insertionStmt(paths [][]ast.Node) ast.Stmt {
+	path := paths[0]
+	i := 0 // index in path of the innermost node that encloses all expressions
+	for _, p := range paths[1:] {
+		n := 0 // length of the common suffix of path and p
+		for n < len(path) && n < len(p) && path[len(path)-1-n] == p[len(p)-1-n] {
+			n++
+		}
+		i = max(i, len(path)-n)
+	}
+	switch path[i].(type) {
+	case *ast.BlockStmt, *ast.CaseClause, *ast.CommClause:
+		if i > 0 {
+			i-- // the enclosed statement (or case expression)
+		}
+	}
+	return analysisinternal.StmtToInsertVarBefore(path[i:])
+}
+
+// checkVisible returns an error if any identifier in expr would denote a
+// different object (or none) if expr were moved to pos.
+func checkVisible(info *types.Info, file *ast.File, expr ast.Expr, pos token.Pos) error {
+	path, _ := astutil.PathEnclosingInterval(file, pos, pos)
+	var scope *types.Scope // innermost scope at pos
+	for _, s := range CollectScopes(info, path, pos) {
+		if s != nil {
+			scope = s
+			break
+		}
+	}
+	if scope == nil {
+		return bug.Errorf("no scope at insertion point")
+	}
+	var err error
+	ast.Inspect(expr, func(n ast.Node) bool {
+		switch n := n.(type) {
+		case *ast.SelectorExpr:
+			ast.Inspect(n.X, func(n ast.Node) bool { return err == nil && checkVisibleIdent(info, scope, n, pos, &err) })
+			return false
+		default:
+			return err == nil && checkVisibleIdent(info, scope, n, pos, &err)
+		}
+	})
+	return err
+}
+
+// checkVisibleIdent is a helper for checkVisible that sets *err if n is an
+// identifier that is not visible at pos in scope.
+func checkVisibleIdent(info *types.Info, scope *types.Scope, n ast.Node, pos token.Pos, err *error) bool {
+	id, ok := n.(*ast.Ident)
+	if !ok {
+		return true
+	}
+	obj := info.Uses[id]
+	if v, ok := obj.(*types.Var); obj == nil || ok && v.IsField() {
+		return true // e.g. field name in composite literal
+	}
+	if _, found := scope.LookupParent(id.Name, pos); found != obj {
+		*err = fmt.Errorf("cannot extract expression: %s is not in scope at the new declaration", id.Name)
+	}
+	return true
+}
+
+// refersToLocals reports whether expr refers to any object of pkg that
+// is not declared at package level, in an import, or in the universe.
+func refersToLocals(info *types.Info, pkg *types.Package, expr ast.Expr) bool {
+	local := false
+	ast.Inspect(expr, func(n ast.Node) bool {
+		if id, ok := n.(*ast.Ident); ok {
+			if obj := info.Uses[id]; obj != nil && (obj.Pkg() == nil || obj.Pkg() == pkg) {
+				if parent := obj.Parent(); parent != nil &&
+					parent != types.Universe &&
+					parent != pkg.Scope() &&
+					parent.Parent() != pkg.Scope() { // file scope
+					local = true
+				}
+			}
+		}
+		return !local
+	})
+	return local
+}
+
+// usesIota reports whether expr refers to iota.
+func usesIota(info *types.Info, expr ast.Expr) bool {
+	iota := types.Universe.Lookup("iota")
+	found := false
+	ast.Inspect(expr, func(n ast.Node) bool {
+		if id, ok := n.(*ast.Ident); ok && info.Uses[id] == iota {
+			found = true
+		}
+		return !found
+	})
+	return found
+}
+
+// freshName returns a name with the given prefix (and the next index to
+// try) that is not in scope at any of the given paths, nor at package
+// level. If pkgLevel is set, the name must also not conflict with the
+// imports of any file of the package, nor with the universe.
+func freshName(info *types.Info, pkg *types.Package, paths [][]ast.Node, pkgLevel bool, prefix string, idx int) (string, int) {
+	var scopes []*types.Scope
+	for _, path := range paths {
+		scopes = append(scopes, CollectScopes(info, path, path[0].Pos())...)
+	}
+	scopes = append(scopes, pkg.Scope())
+	if pkgLevel {
+		for i := 0; i < pkg.Scope().NumChildren(); i++ {
+			scopes = append(scopes, pkg.Scope().Child(i)) // file scopes
+		}
+		scopes = append(scopes, types.Universe)
+	}
+	return generateIdentifier(idx, prefix, func(name string) bool {
+		for _, scope := range scopes {
+			if scope != nil && scope.Lookup(name) != nil {
+				return true
+			}
+		}
+		return false
+	})
+}
+
+// declStart returns the start of a package-level declaration,
+// including its doc comment.
+func declStart(decl ast.Decl) token.Pos {
+	switch decl := decl.(type) {
+	case *ast.FuncDecl:
+		if decl.Doc != nil {
+			return decl.Doc.Pos()
+		}
+	case *ast.GenDecl:
+		if decl.Doc != nil {
+			return decl.Doc.Pos()
+		}
+	}
+	return decl.Pos()
+}
+
+// extractType implements the refactor.extract.type CodeAction command.
+// It replaces the selected struct or func type literal by a reference
+// to a new named type of that underlying type, declared at package
+// level, or, if the type refers to local types, before the enclosing
+// statement.
+//
+// Values of other named types with the same underlying type are
+// assignable to and from a variable of the unnamed type, but not of the
+// new named one. So the literal must be the type of a local variable,
+// whose uses are all checked; in other positions, such as parameters
+// and fields, the uses may lie beyond the file or package.
+func extractType(fset *token.FileSet, start, end token.Pos, src []byte, file *ast.File, pkg *types.Package, info *types.Info) (*token.FileSet, *analysis.SuggestedFix, error) {
+	tokFile := fset.File(file.FileStart)
+	expr, path, err := canExtractType(start, end, file)
+	if err != nil {
+		return nil, nil, fmt.Errorf("extractType: cannot extract %s: %v", safetoken.StartPosition(fset, start), err)
+	}
+	var tparam *types.TypeName
+	ast.Inspect(expr, func(n ast.Node) bool {
+		if id, ok := n.(*ast.Ident); ok {
+			if tname, ok := info.Uses[id].(*types.TypeName); ok && is[*types.TypeParam](tname.Type()) {
+				tparam = tname
+			}
+		}
+		return tparam == nil
+	})
+	if tparam != nil {
+		return nil, nil, fmt.Errorf("cannot extract a type that refers to type parameter %s", tparam.Name())
+	}
+	if err := checkTypeUses(fset, info, path, expr); err != nil {
+		return nil, nil, err
+	}
+	local := refersToLocals(info, pkg, expr)
+
+	name, _ := freshName(info, pkg, [][]ast.Node{path}, !local, "T", 0)
+	decl := &ast.GenDecl{
+		Tok: token.TYPE,
+		Specs: []ast.Spec{&ast.TypeSpec{
+			Name: ast.NewIdent(name),
+			Type: expr,
+		}},
+	}
+	var buf bytes.Buffer
+	if err := format.Node(&buf, fset, decl); err != nil {
+		return nil, nil, err
+	}
+
+	var (
+		insertPos token.Pos
+		declText  string
+	)
+	if !local {
+		insertPos = declStart(path[len(path)-2].(ast.Decl))
+		declText = buf.String() + "\n\n"
+	} else {
+		for _, n := range path {
+			if fn, ok := n.(*ast.FuncDecl); ok && fn.Type.TypeParams != nil {
+				return nil, nil, fmt.Errorf("cannot declare a local type in generic function %s", fn.Name.Name)
+			}
+		}
+		stmt := analysisinternal.StmtToInsertVarBefore(path)
+		if stmt == nil {
+			return nil, nil, fmt.Errorf("cannot find location to insert extraction")
+		}
+		insertPos = stmt.Pos()
+		if err := checkVisible(info, file, expr, insertPos); err != nil {
+			return nil, nil, err
+		}
+		indent, err := calculateIndentation(src, tokFile, stmt)
+		if err != nil {
+			return nil, nil, err
+		}
+		newLineIndent := "\n" + indent
+		declText = strings.ReplaceAll(buf.String(), "\n", newLineIndent) + newLineIndent
+	}
+
+	return fset, &analysis.SuggestedFix{
+		TextEdits: []analysis.TextEdit{
+			{
+				Pos:     insertPos,
+				End:     insertPos,
+				NewText: []byte(declText),
+			},
+			{
+				Pos:     start,
+				End:     end,
+				NewText: []byte(name),
+			},
+		},
+	}, nil
+}
+
+// canExtractType reports whether the code in the given range is a struct
+// or func type literal that can be extracted to a named type.
+func canExtractType(start, end token.Pos, file *ast.File) (ast.Expr, []ast.Node, error) {
+	if start == end {
+		return nil, nil, fmt.Errorf("start and end are equal")
+	}
+	path, _ := astutil.PathEnclosingInterval(file, start, end)
+	if len(path) < 2 {
+		return nil, nil, fmt.Errorf("no path enclosing interval")
+	}
+	node := path[0]
+	if start != node.Pos() || end != node.End() {
+		return nil, nil, fmt.Errorf("range does not map to an AST node")
+	}
+	switch node.(type) {
+	case *ast.StructType, *ast.FuncType:
+	default:
+		return nil, nil, fmt.Errorf("cannot extract an %T to a type", node)
+	}
+	if spec, ok := path[1].(*ast.ValueSpec); !ok || spec.Type != node || len(path) < 4 || !is[*ast.DeclStmt](path[3]) {
+		return nil, nil, fmt.Errorf("can only extract the type of a local variable")
+	}
+	return node.(ast.Expr), path, nil
+}
+
+// checkTypeUses returns an error if the variables declared with the type
+// literal expr, at the given path, are initialized or used in a way that
+// depends on their type being unnamed: in an assignment from or to a
+// value of another type, or in any use other than a field or method
+// selection or a call.
+func checkTypeUses(fset *token.FileSet, info *types.Info, path []ast.Node, expr ast.Expr) error {
+	spec := path[1].(*ast.ValueSpec)
+	t := info.TypeOf(expr)
+	// sameType reports whether the ith value of rhs has type t.
+	sameType := func(rhs []ast.Expr, i int) bool {
+		if len(rhs) == 1 {
+			if tup, ok := info.TypeOf(rhs[0]).(*types.Tuple); ok {
+				return i < tup.Len() && types.Identical(tup.At(i).Type(), t)
+			}
+		}
+		return i < len(rhs) && (info.Types[rhs[i]].IsNil() || types.Identical(info.TypeOf(rhs[i]), t))
+	}
+	for i, id := range spec.Names {
+		if len(spec.Values) > 0 && !sameType(spec.Values, i) {
+			return fmt.Errorf("cannot extract type: the initial value of %s has a different type", id.Name)
+		}
+	}
+	vars := make(map[types.Object]bool)
+	for _, id := range spec.Names {
+		vars[info.Defs[id]] = true
+	}
+
+	// safe reports whether the use id, whose parent is parent, does not
+	// depend on its type being unnamed.
+	safe := func(id *ast.Ident, parent ast.Node) bool {
+		switch parent := parent.(type) {
+		case *ast.SelectorExpr:
+			return parent.X == id
+		case *ast.CallExpr:
+			return parent.Fun == id
+		case *ast.AssignStmt:
+			if parent.Tok != token.ASSIGN {
+				return false
+			}
+			if i := slices.Index(parent.Lhs, ast.Expr(id)); i >= 0 {
+				return sameType(parent.Rhs, i)
+			}
+			if i := slices.Index(parent.Rhs, ast.Expr(id)); i >= 0 && len(parent.Lhs) == len(parent.Rhs) {
+				lhs := parent.Lhs[i]
+				if id, ok := lhs.(*ast.Ident); ok && id.Name == "_" {
+					return true
+				}
+				return types.Identical(info.TypeOf(lhs), t)
+			}
+		}
+		return false
+	}
+	var (
+		stack []ast.Node
+		err   error
+	)
+	ast.Inspect(path[len(path)-1], func(n ast.Node) bool {
+		if n == nil {
+			stack = stack[:len(stack)-1]
+			return true
+		}
+		if err != nil {
+			return false
+		}
+		if id, ok := n.(*ast.Ident); ok && vars[info.Uses[id]] && !safe(id, stack[len(stack)-1]) {
+			err = fmt.Errorf("cannot extract type: the use of %s at %s depends on its type being unnamed",
+				id.Name, safetoken.StartPosition(fset, id.Pos()))
+		}
+		stack = append(stack, n)
+		return true
+	})
+	return err
+}
+
 // Calculate indentation for insertion.
 // When inserting lines of code, we must ensure that the lines have consistent
 // formatting (i.e. the proper indentation). To do so, we observe the indentation on the
@@ -59,6 +59,10 @@ func singleFile(fixer1 singleFileFixer) fixer {
 // Names of ApplyFix.Fix created directly by the CodeAction handler.
 const (
 	fixExtractVariable         = "extract_variable"
+	fixExtractVariableAll      = "extract_variable_all"
+	fixExtractConstant         = "extract_constant"
+	fixExtractConstantAll      = "extract_constant_all"
+	fixExtractType             = "extract_type"
 	fixExtractFunction         = "extract_function"
 	fixExtractMethod           = "extract_method"
 	fixInlineCall              = "inline_call"
@@ -107,6 +111,10 @@ func ApplyFix(ctx context.Context, fix string, snapshot *cache.Snapshot, fh file
 		fixExtractFunction:         singleFile(extractFunction),
 		fixExtractMethod:           singleFile(extractMethod),
 		fixExtractVariable:         singleFile(extractVariable),
+		fixExtractVariableAll:      singleFile(extractVariableAll),
+		fixExtractConstant:         singleFile(extractConstant),
+		fixExtractConstantAll:      singleFile(extractConstantAll),
+		fixExtractType:             singleFile(extractType),
 		fixInlineCall:              inlineCall,
 		fixInvertIfCondition:       singleFile(invertIfCondition),
 		fixSplitLines:              singleFile(splitLines),
@@ -115,12 +115,22 @@ type importFix struct {
 // In addition to returning the result of applying all edits,
 // it returns a list of fixes that could be applied to the file, with the
 // corresponding TextEdits that would be needed to apply that fix.
-func allImportsFixes(ctx context.Context, snapshot *cache.Snapshot, pgf *parsego.File) (allFixEdits []protocol.TextEdit, editsPerFix []*importFix, err error) {
+//
+// If pkg is non-nil, its type information is used to decide which
+// imports are unused and the names of the imported packages.
+func allImportsFixes(ctx context.Context, snapshot *cache.Snapshot, pkg *cache.Package, pgf *parsego.File) (allFixEdits []protocol.TextEdit, editsPerFix []*importFix, err error) {
 	ctx, done := event.Start(ctx, "golang.allImportsFixes")
 	defer done()
 
+	var tinfo *imports.TypeInfo
+	if pkg != nil {
+		if typed, err := pkg.File(pgf.URI); err == nil {
+			tinfo = imports.NewTypeInfo(typed.File, pkg.TypesInfo())
+		}
+	}
+
 	if err := snapshot.RunProcessEnvFunc(ctx, func(ctx context.Context, opts *imports.Options) error {
-		allFixEdits, editsPerFix, err = computeImportEdits(ctx, pgf, snapshot.View().Folder().Env.GOROOT, opts)
+		allFixEdits, editsPerFix, err = computeImportEdits(ctx, pgf, snapshot.View().Folder().Env.GOROOT, opts, tinfo)
 		return err
 	}); err != nil {
 		return nil, nil, fmt.Errorf("allImportsFixes: %v", err)
@@ -130,12 +140,12 @@ func allImportsFixes(ctx context.Context, snapshot *cache.Snapshot, pgf *parsego
 
 // computeImportEdits computes a set of edits that perform one or all of the
 // necessary import fixes.
-func computeImportEdits(ctx context.Context, pgf *parsego.File, goroot string, options *imports.Options) (allFixEdits []protocol.TextEdit, editsPerFix []*importFix, err error) {
+func computeImportEdits(ctx context.Context, pgf *parsego.File, goroot string, options *imports.Options, tinfo *imports.TypeInfo) (allFixEdits []protocol.TextEdit, editsPerFix []*importFix, err error) {
 	filename := pgf.URI.Path()
 
 	// Build up basic information about the original file.
 	isource, err := imports.NewProcessEnvSource(options.Env, filename, pgf.File.Name.Name)
-	allFixes, err := imports.FixImports(ctx, filename, pgf.Src, goroot, options.Env.Logf, isource)
+	allFixes, err := imports.FixImports(ctx, filename, pgf.Src, goroot, options.Env.Logf, isource, tinfo)
 	if err != nil {
 		return nil, nil, err
 	}
@@ -164,6 +174,7 @@ func computeImportEdits(ctx context.Context, pgf *parsego.File, goroot string, o
 func ComputeOneImportFixEdits(snapshot *cache.Snapshot, pgf *parsego.File, fix *imports.ImportFix) ([]protocol.TextEdit, error) {
 	options := &imports.Options{
 		LocalPrefix: snapshot.Options().Local,
+		Grouping:    snapshot.Options().Grouping(),
 		// Defaults.
 		AllErrors:  true,
 		Comments:   true,
@@ -182,6 +193,9 @@ func computeFixEdits(pgf *parsego.File, options *imports.Options, fixes []*impor
 		return nil, err
 	}
 	extra := !strings.Contains(left, "\n") // one line may have more than imports
+	if options.Grouping != nil && len(options.Grouping.Aliases) > 0 {
+		extra = true // renaming an import requires renaming its references
+	}
 	if extra {
 		left = string(pgf.Src)
 	}
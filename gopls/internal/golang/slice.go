@@ -0,0 +1,147 @@
+// Copyright 2024 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package golang
+
+import (
+	"context"
+	"fmt"
+	"go/ast"
+	"go/types"
+	"slices"
+
+	"golang.org/x/tools/go/ast/astutil"
+	"golang.org/x/tools/go/callgraph/static"
+	"golang.org/x/tools/go/ssa"
+	"golang.org/x/tools/go/ssa/slicing"
+	"golang.org/x/tools/go/ssa/ssautil"
+	"golang.org/x/tools/gopls/internal/cache"
+	"golang.org/x/tools/gopls/internal/cache/parsego"
+	"golang.org/x/tools/gopls/internal/file"
+	"golang.org/x/tools/gopls/internal/protocol"
+	"golang.org/x/tools/internal/event"
+)
+
+// Slice returns the program slice of the selected range of fh, as
+// highlights within the same file: the statements and expressions
+// that may affect the values computed by the selection or, if forward,
+// that may be affected by them.
+//
+// The selection itself is highlighted as a write, the rest of the
+// slice as reads. An empty selection denotes the enclosing statement.
+func Slice(ctx context.Context, snapshot *cache.Snapshot, fh file.Handle, rng protocol.Range, forward bool) ([]protocol.DocumentHighlight, error) {
+	ctx, done := event.Start(ctx, "golang.Slice")
+	defer done()
+
+	pkg, pgf, err := NarrowestPackageForFile(ctx, snapshot, fh.URI())
+	if err != nil {
+		return nil, err
+	}
+	if len(pkg.ParseErrors()) > 0 || len(pkg.TypeErrors()) > 0 {
+		return nil, fmt.Errorf("package %s has errors", pkg.Types().Path())
+	}
+	start, end, err := pgf.RangePos(rng)
+	if err != nil {
+		return nil, err
+	}
+	if start == end {
+		path, _ := astutil.PathEnclosingInterval(pgf.File, start, end)
+		if n := sliceElement(path); n != nil {
+			start, end = n.Pos(), n.End()
+		}
+	}
+
+	// Build the SSA form of the package, with the source-level
+	// variables needed to find the criteria.
+	prog := ssa.NewProgram(pkg.FileSet(), ssa.GlobalDebug)
+	created := make(map[*types.Package]bool)
+	var createAll func(pkgs []*types.Package)
+	createAll = func(pkgs []*types.Package) {
+		for _, p := range pkgs {
+			if !created[p] {
+				created[p] = true
+				prog.CreatePackage(p, nil, nil, true)
+				createAll(p.Imports())
+			}
+		}
+	}
+	createAll(pkg.Types().Imports())
+	spkg := prog.CreatePackage(pkg.Types(), pkg.Syntax(), pkg.TypesInfo(), false)
+	spkg.Build()
+
+	var funcs []*ssa.Function
+	for fn := range ssautil.AllFunctions(prog) {
+		if fn.Pkg == spkg && fn.Pos().IsValid() && pkg.FileSet().File(fn.Pos()) == pgf.Tok {
+			funcs = append(funcs, fn)
+		}
+	}
+	criteria := slicing.At(funcs, start, end)
+	if len(criteria) == 0 {
+		return nil, fmt.Errorf("no code selected")
+	}
+
+	// Only calls to functions of this package are followed,
+	// as the others have no bodies.
+	cg := static.CallGraph(prog)
+	var s slicing.Slice
+	if forward {
+		s = slicing.Forward(criteria, cg)
+	} else {
+		s = slicing.Backward(criteria, cg)
+	}
+
+	kinds := make(map[posRange]protocol.DocumentHighlightKind)
+	for _, n := range s.Nodes() {
+		pos := n.Pos()
+		if !pos.IsValid() || pkg.FileSet().File(pos) != pgf.Tok {
+			continue
+		}
+		path, _ := astutil.PathEnclosingInterval(pgf.File, pos, pos)
+		if e := sliceElement(path); e != nil {
+			r := posRange{e.Pos(), e.End()}
+			if start <= r.start && r.end <= end {
+				kinds[r] = protocol.Write
+			} else if kinds[r] == 0 {
+				kinds[r] = protocol.Read
+			}
+		}
+	}
+	return highlights(pgf, kinds)
+}
+
+// sliceElement returns the innermost simple statement enclosing the
+// innermost node of path, or, if there is none within the enclosing
+// compound statement or declaration, the node just below it, such as
+// the condition of an if statement or the name of a parameter.
+func sliceElement(path []ast.Node) ast.Node {
+	for i, n := range path {
+		switch n.(type) {
+		case *ast.AssignStmt, *ast.ExprStmt, *ast.IncDecStmt, *ast.SendStmt,
+			*ast.ReturnStmt, *ast.DeclStmt, *ast.GoStmt, *ast.DeferStmt:
+			return n
+		case ast.Stmt, *ast.Field, *ast.FuncType, *ast.FuncDecl, *ast.FuncLit, *ast.GenDecl, *ast.File:
+			if i > 0 {
+				return path[i-1]
+			}
+			return nil
+		}
+	}
+	return nil
+}
+
+// highlights converts a set of ranges of pgf to highlights, in order.
+func highlights(pgf *parsego.File, kinds map[posRange]protocol.DocumentHighlightKind) ([]protocol.DocumentHighlight, error) {
+	var result []protocol.DocumentHighlight
+	for r, kind := range kinds {
+		rng, err := pgf.PosRange(r.start, r.end)
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, protocol.DocumentHighlight{Range: rng, Kind: kind})
+	}
+	slices.SortFunc(result, func(x, y protocol.DocumentHighlight) int {
+		return protocol.CompareRange(x.Range, y.Range)
+	})
+	return result, nil
+}
@@ -36,6 +36,7 @@ const (
 	DiagnoseFiles           Command = "gopls.diagnose_files"
 	Doc                     Command = "gopls.doc"
 	EditGoDirective         Command = "gopls.edit_go_directive"
+	EgRewrite               Command = "gopls.eg_rewrite"
 	ExtractToNewFile        Command = "gopls.extract_to_new_file"
 	FetchVulncheckResult    Command = "gopls.fetch_vulncheck_result"
 	FreeSymbols             Command = "gopls.free_symbols"
@@ -55,6 +56,7 @@ const (
 	RunGovulncheck          Command = "gopls.run_govulncheck"
 	RunTests                Command = "gopls.run_tests"
 	ScanImports             Command = "gopls.scan_imports"
+	Slice                   Command = "gopls.slice"
 	StartDebugging          Command = "gopls.start_debugging"
 	StartProfile            Command = "gopls.start_profile"
 	StopProfile             Command = "gopls.stop_profile"
@@ -81,6 +83,7 @@ var Commands = []Command{
 	DiagnoseFiles,
 	Doc,
 	EditGoDirective,
+	EgRewrite,
 	ExtractToNewFile,
 	FetchVulncheckResult,
 	FreeSymbols,
@@ -100,6 +103,7 @@ var Commands = []Command{
 	RunGovulncheck,
 	RunTests,
 	ScanImports,
+	Slice,
 	StartDebugging,
 	StartProfile,
 	StopProfile,
@@ -189,6 +193,12 @@ func Dispatch(ctx context.Context, params *protocol.ExecuteCommandParams, s Inte
 			return nil, err
 		}
 		return nil, s.EditGoDirective(ctx, a0)
+	case EgRewrite:
+		var a0 EgRewriteArgs
+		if err := UnmarshalArgs(params.Arguments, &a0); err != nil {
+			return nil, err
+		}
+		return s.EgRewrite(ctx, a0)
 	case ExtractToNewFile:
 		var a0 protocol.Location
 		if err := UnmarshalArgs(params.Arguments, &a0); err != nil {
@@ -292,6 +302,12 @@ func Dispatch(ctx context.Context, params *protocol.ExecuteCommandParams, s Inte
 		return nil, s.RunTests(ctx, a0)
 	case ScanImports:
 		return nil, s.ScanImports(ctx)
+	case Slice:
+		var a0 SliceArgs
+		if err := UnmarshalArgs(params.Arguments, &a0); err != nil {
+			return nil, err
+		}
+		return s.Slice(ctx, a0)
 	case StartDebugging:
 		var a0 DebuggingArgs
 		if err := UnmarshalArgs(params.Arguments, &a0); err != nil {
@@ -452,6 +468,14 @@ func NewEditGoDirectiveCommand(title string, a0 EditGoDirectiveArgs) *protocol.C
 	}
 }
 
+func NewEgRewriteCommand(title string, a0 EgRewriteArgs) *protocol.Command {
+	return &protocol.Command{
+		Title:     title,
+		Command:   EgRewrite.String(),
+		Arguments: MustMarshalArgs(a0),
+	}
+}
+
 func NewExtractToNewFileCommand(title string, a0 protocol.Location) *protocol.Command {
 	return &protocol.Command{
 		Title:     title,
@@ -604,6 +628,14 @@ func NewScanImportsCommand(title string) *protocol.Command {
 	}
 }
 
+func NewSliceCommand(title string, a0 SliceArgs) *protocol.Command {
+	return &protocol.Command{
+		Title:     title,
+		Command:   Slice.String(),
+		Arguments: MustMarshalArgs(a0),
+	}
+}
+
 func NewStartDebuggingCommand(title string, a0 DebuggingArgs) *protocol.Command {
 	return &protocol.Command{
 		Title:     title,
@@ -240,6 +240,15 @@ type Interface interface {
 	// Its signature will certainly change in the future (pun intended).
 	ChangeSignature(context.Context, ChangeSignatureArgs) (*protocol.WorkspaceEdit, error)
 
+	// EgRewrite: Apply an example-based refactoring template
+	//
+	// This command applies the example-based refactoring template
+	// in the given Go file, which defines pairs of "before" and
+	// "after" functions as described by the documentation of
+	// golang.org/x/tools/refactor/eg, to all the workspace
+	// packages. The template file itself is not changed.
+	EgRewrite(context.Context, EgRewriteArgs) (*protocol.WorkspaceEdit, error)
+
 	// DiagnoseFiles: Cause server to publish diagnostics for the specified files.
 	//
 	// This command is needed by the 'gopls {check,fix}' CLI subcommands.
@@ -292,6 +301,18 @@ type Interface interface {
 	// language server client), there should never be a case where Modules is
 	// called on a path that has not already been loaded.
 	Modules(context.Context, ModulesArgs) (ModulesResult, error)
+
+	// Slice: Highlight the program slice of the selection
+	//
+	// This command is a query over a selected range of Go source
+	// code. It reports the statements and expressions of the
+	// selection's package that may affect the values computed by
+	// the selection (its backward slice) or, if Forward is set,
+	// that may be affected by them (its forward slice), in the
+	// form of document highlights. Ranges are computed using the
+	// SSA form of the package; calls to functions of other
+	// packages are not followed.
+	Slice(context.Context, SliceArgs) ([]protocol.DocumentHighlight, error)
 }
 
 type RunTestsArgs struct {
@@ -569,8 +590,45 @@ type AddTelemetryCountersArgs struct {
 }
 
 // ChangeSignatureArgs specifies a "change signature" refactoring to perform.
+//
+// If RemoveParameter is set, the unused parameter at that location is
+// removed. Otherwise, the parameters of the function declared at
+// Location become those described by NewParams.
 type ChangeSignatureArgs struct {
 	RemoveParameter protocol.Location
+	// The location of the function declaration whose signature is changed.
+	Location protocol.Location
+	// The parameters of the new signature, in order. Parameters of the
+	// original signature that are not mentioned are removed, and must be
+	// unused.
+	NewParams []ChangeSignatureParam
+	// If set, the name of a new struct type, declared after the function,
+	// whose fields are the new parameters; the function then has a
+	// single parameter of this type.
+	ParamsStruct string
+	// Whether to resolve and return the edits.
+	ResolveEdits bool
+}
+
+// ChangeSignatureParam describes a parameter of a changed signature.
+type ChangeSignatureParam struct {
+	// The index of the original parameter, counting each name of a
+	// parameter group separately. Ignored for new parameters.
+	OldIndex int
+	// The parameter name; empty keeps the original name.
+	Name string
+	// The parameter type; empty keeps the original type. Changing the
+	// type of an existing parameter is allowed only if the old type is
+	// assignable or losslessly convertible to the new type.
+	Type string
+	// For a new parameter, the argument expression added to each call.
+	// It is evaluated in the file scope of the declaration.
+	Default string
+}
+
+type EgRewriteArgs struct {
+	// The template file.
+	Template protocol.DocumentURI
 	// Whether to resolve and return the edits.
 	ResolveEdits bool
 }
@@ -732,3 +790,10 @@ type ModulesArgs struct {
 type ModulesResult struct {
 	Modules []Module
 }
+
+type SliceArgs struct {
+	// The selection whose slice is requested.
+	Location protocol.Location
+	// Forward requests the forward slice instead of the backward one.
+	Forward bool
+}
@@ -1510,13 +1510,47 @@ func showDocumentImpl(ctx context.Context, cli protocol.Client, url protocol.URI
 	}
 }
 
+func (c *commandHandler) EgRewrite(ctx context.Context, args command.EgRewriteArgs) (*protocol.WorkspaceEdit, error) {
+	var result *protocol.WorkspaceEdit
+	err := c.run(ctx, commandConfig{
+		progress: "Applying eg template",
+		forURI:   args.Template,
+	}, func(ctx context.Context, deps commandDeps) error {
+		if deps.snapshot.FileKind(deps.fh) != file.Go {
+			return fmt.Errorf("eg template %s is not a Go file", args.Template)
+		}
+		docedits, err := golang.EgRewrite(ctx, deps.snapshot, deps.fh)
+		if err != nil {
+			return err
+		}
+		wsedit := protocol.NewWorkspaceEdit(docedits...)
+		if args.ResolveEdits {
+			result = wsedit
+			return nil
+		}
+		return applyChanges(ctx, c.s.client, docedits)
+	})
+	return result, err
+}
+
 func (c *commandHandler) ChangeSignature(ctx context.Context, args command.ChangeSignatureArgs) (*protocol.WorkspaceEdit, error) {
 	var result *protocol.WorkspaceEdit
+	forURI := args.RemoveParameter.URI
+	if forURI == "" {
+		forURI = args.Location.URI
+	}
 	err := c.run(ctx, commandConfig{
-		forURI: args.RemoveParameter.URI,
+		forURI: forURI,
 	}, func(ctx context.Context, deps commandDeps) error {
-		// For now, gopls only supports removing unused parameters.
-		docedits, err := golang.RemoveUnusedParameter(ctx, deps.fh, args.RemoveParameter.Range, deps.snapshot)
+		var (
+			docedits []protocol.DocumentChange
+			err      error
+		)
+		if args.RemoveParameter.URI != "" {
+			docedits, err = golang.RemoveUnusedParameter(ctx, deps.fh, args.RemoveParameter.Range, deps.snapshot)
+		} else {
+			docedits, err = golang.ChangeSignature(ctx, deps.fh, args.Location.Range, deps.snapshot, args.NewParams, args.ParamsStruct)
+		}
 		if err != nil {
 			return err
 		}
@@ -1620,3 +1654,18 @@ func (c *commandHandler) ScanImports(ctx context.Context) error {
 	}
 	return nil
 }
+
+func (c *commandHandler) Slice(ctx context.Context, args command.SliceArgs) ([]protocol.DocumentHighlight, error) {
+	var result []protocol.DocumentHighlight
+	err := c.run(ctx, commandConfig{
+		forURI: args.Location.URI,
+	}, func(ctx context.Context, deps commandDeps) error {
+		if deps.snapshot.FileKind(deps.fh) != file.Go {
+			return fmt.Errorf("can't compute slice of non-Go file")
+		}
+		highlights, err := golang.Slice(ctx, deps.snapshot, deps.fh, args.Location.Range, args.Forward)
+		result = highlights
+		return err
+	})
+	return result, err
+}
@@ -61,9 +61,13 @@ func DefaultOptions(overrides ...func(*Options)) *Options {
 						RefactorRewriteRemoveUnusedParam: true,
 						RefactorRewriteSplitLines:        true,
 						RefactorInlineCall:               true,
+						RefactorExtractConstant:          true,
+						RefactorExtractConstantAll:       true,
 						RefactorExtractFunction:          true,
 						RefactorExtractMethod:            true,
+						RefactorExtractType:              true,
 						RefactorExtractVariable:          true,
+						RefactorExtractVariableAll:       true,
 						RefactorExtractToNewFile:         true,
 						// Not GoTest: it must be explicit in CodeActionParams.Context.Only
 					},
@@ -14,6 +14,7 @@ import (
 	"golang.org/x/tools/gopls/internal/file"
 	"golang.org/x/tools/gopls/internal/protocol"
 	"golang.org/x/tools/gopls/internal/util/frob"
+	"golang.org/x/tools/internal/imports"
 )
 
 type Annotation string
@@ -403,10 +404,38 @@ type FormattingOptions struct {
 	// existing imports.
 	Local string
 
+	// ImportGrouping specifies how imports are grouped, and the names
+	// required for some of them, overriding Local. Each element is a
+	// rule in the syntax of the file named by the `goimports -grouping`
+	// flag, such as "std", "default", "prefix example.com/", "blank",
+	// or "alias example.com/gen/v1 genpb". Groups appear in the order
+	// of their rules.
+	//
+	// Example Usage:
+	//
+	// ```json5
+	// "gopls": {
+	// ...
+	//   "importGrouping": ["std", "default", "prefix example.com/", "blank"]
+	// ...
+	// }
+	// ```
+	ImportGrouping []string
+
 	// Gofumpt indicates if we should run gofumpt formatting.
 	Gofumpt bool
 }
 
+// Grouping returns the import grouping specified by ImportGrouping,
+// or nil if it is unset.
+func (o *FormattingOptions) Grouping() *imports.Grouping {
+	if len(o.ImportGrouping) == 0 {
+		return nil
+	}
+	g, _ := imports.ParseGrouping(o.ImportGrouping) // validated by set
+	return g
+}
+
 // Note: DiagnosticOptions must be comparable with reflect.DeepEqual.
 type DiagnosticOptions struct {
 	// Analyses specify analyses that the user would like to enable or disable.
@@ -613,6 +642,11 @@ type InternalOptions struct {
 	// currently import.
 	CompleteUnimported bool
 
+	// UseModIndex causes unimported packages to be found in the module
+	// cache using its index of exported symbols, instead of by scanning
+	// the module cache.
+	UseModIndex bool
+
 	// DeepCompletion enables the ability to return completions from deep
 	// inside relevant entities, rather than just the locally accessible ones.
 	//
@@ -985,6 +1019,8 @@ func (o *Options) setOne(name string, value any) error {
 		return setBool(&o.DeepCompletion, value)
 	case "completeUnimported":
 		return setBool(&o.CompleteUnimported, value)
+	case "useModIndex":
+		return setBool(&o.UseModIndex, value)
 	case "addTestSourceCodeAction":
 		return setBool(&o.AddTestSourceCodeAction, value)
 	case "completionBudget":
@@ -1085,6 +1121,16 @@ func (o *Options) setOne(name string, value any) error {
 	case "local":
 		return setString(&o.Local, value)
 
+	case "importGrouping":
+		rules, err := asStringSlice(value)
+		if err != nil {
+			return err
+		}
+		if _, err := imports.ParseGrouping(rules); err != nil {
+			return err
+		}
+		o.ImportGrouping = rules
+
 	case "verboseOutput":
 		return setBool(&o.VerboseOutput, value)
 
@@ -52,6 +52,17 @@ func TestOptions_Set(t *testing.T) {
 			value: "caseInsensitive",
 			check: func(o Options) bool { return o.SymbolMatcher == SymbolCaseInsensitive },
 		},
+		{
+			name:  "importGrouping",
+			value: []any{"std", "default", "prefix example.com/"},
+			check: func(o Options) bool { return len(o.Grouping().Groups) == 3 },
+		},
+		{
+			name:      "importGrouping",
+			value:     []any{"std", "local"},
+			wantError: true,
+			check:     func(o Options) bool { return o.Grouping() == nil },
+		},
 		{
 			name:  "completionBudget",
 			value: "2s",
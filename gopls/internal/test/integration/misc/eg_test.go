@@ -0,0 +1,155 @@
+// Copyright 2024 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package misc
+
+import (
+	"testing"
+
+	"golang.org/x/tools/gopls/internal/protocol"
+	"golang.org/x/tools/gopls/internal/protocol/command"
+	"golang.org/x/tools/gopls/internal/test/compare"
+	. "golang.org/x/tools/gopls/internal/test/integration"
+)
+
+// TestEgRewrite tests the gopls.eg_rewrite command, which applies an
+// example-based refactoring template to the workspace packages.
+func TestEgRewrite(t *testing.T) {
+	const files = `
+-- go.mod --
+module example.com
+
+go 1.18
+-- a/a.go --
+package a
+
+import "strings"
+
+func F(s string) bool {
+	return strings.Index(s, "x") >= 0
+}
+-- b/b.go --
+package b
+
+import "strings"
+
+func G(s, sub string) bool {
+	return strings.Index(s+sub, sub) >= 0 || strings.Index(s, "y") != -1
+}
+-- c/c.go --
+package c
+
+import "strings"
+
+func H(s string) bool {
+	return strings.Index(s, "z") >= 0 && undefined
+}
+-- template/template.go --
+//go:build ignore
+
+package template
+
+import "strings"
+
+func before(s, sub string) bool { return strings.Index(s, sub) >= 0 }
+func after(s, sub string) bool  { return strings.Contains(s, sub) }
+`
+	const (
+		wantA = `package a
+
+import "strings"
+
+func F(s string) bool {
+	return strings.Contains(s, "x")
+}
+`
+		wantB = `package b
+
+import "strings"
+
+func G(s, sub string) bool {
+	return strings.Contains(s+sub, sub) || strings.Index(s, "y") != -1
+}
+`
+	)
+	Run(t, files, func(t *testing.T, env *Env) {
+		for _, name := range []string{"a/a.go", "b/b.go", "c/c.go", "template/template.go"} {
+			env.OpenFile(name)
+		}
+		before := env.BufferText("c/c.go")
+		tmpl := env.BufferText("template/template.go")
+
+		cmd := command.NewEgRewriteCommand("eg", command.EgRewriteArgs{
+			Template: env.Sandbox.Workdir.URI("template/template.go"),
+		})
+		env.ExecuteCommand(&protocol.ExecuteCommandParams{
+			Command:   command.EgRewrite.String(),
+			Arguments: cmd.Arguments,
+		}, nil)
+
+		for name, want := range map[string]string{
+			"a/a.go":               wantA,
+			"b/b.go":               wantB,
+			"c/c.go":               before, // has errors
+			"template/template.go": tmpl,
+		} {
+			if got := env.BufferText(name); got != want {
+				t.Errorf("gopls.eg_rewrite: unexpected %s:\n%s", name, compare.Text(want, got))
+			}
+		}
+	})
+}
+
+// TestEgRewriteResolveEdits tests that gopls.eg_rewrite returns the
+// edits without applying them if asked to.
+func TestEgRewriteResolveEdits(t *testing.T) {
+	const files = `
+-- go.mod --
+module example.com
+
+go 1.18
+-- a/a.go --
+package a
+
+import "strings"
+
+func F(s string) bool {
+	return strings.Index(s, "x") >= 0
+}
+-- template/template.go --
+//go:build ignore
+
+package template
+
+import "strings"
+
+func before(s, sub string) bool { return strings.Index(s, sub) >= 0 }
+func after(s, sub string) bool  { return strings.Contains(s, sub) }
+`
+	Run(t, files, func(t *testing.T, env *Env) {
+		env.OpenFile("a/a.go")
+		before := env.BufferText("a/a.go")
+
+		cmd := command.NewEgRewriteCommand("eg", command.EgRewriteArgs{
+			Template:     env.Sandbox.Workdir.URI("template/template.go"),
+			ResolveEdits: true,
+		})
+		var result protocol.WorkspaceEdit
+		env.ExecuteCommand(&protocol.ExecuteCommandParams{
+			Command:   command.EgRewrite.String(),
+			Arguments: cmd.Arguments,
+		}, &result)
+
+		if got := env.BufferText("a/a.go"); got != before {
+			t.Errorf("gopls.eg_rewrite with ResolveEdits changed a/a.go:\n%s", compare.Text(before, got))
+		}
+		if len(result.DocumentChanges) != 1 {
+			t.Fatalf("gopls.eg_rewrite returned %d document changes, want 1", len(result.DocumentChanges))
+		}
+		edit := result.DocumentChanges[0].TextDocumentEdit
+		if edit == nil || edit.TextDocument.URI != env.Sandbox.Workdir.URI("a/a.go") {
+			t.Fatalf("gopls.eg_rewrite returned unexpected change %+v", result.DocumentChanges[0])
+		}
+	})
+}
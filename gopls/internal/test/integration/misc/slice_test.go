@@ -0,0 +1,98 @@
+// Copyright 2024 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package misc
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"golang.org/x/tools/gopls/internal/protocol"
+	"golang.org/x/tools/gopls/internal/protocol/command"
+	. "golang.org/x/tools/gopls/internal/test/integration"
+)
+
+// TestSlice tests the gopls.slice command, which reports the backward
+// or forward program slice of a selection as document highlights, in
+// order.
+func TestSlice(t *testing.T) {
+	const files = `
+-- go.mod --
+module example.com
+
+go 1.18
+-- a/a.go --
+package a
+
+func f(x, y int) int {
+	a := x + 1
+	b := y * 2
+	println(b)
+	c := double(a)
+	return c
+}
+
+func double(v int) int {
+	return v * 2
+}
+`
+	tests := []struct {
+		selection string
+		forward   bool
+		want      []string
+	}{
+		{
+			"return c", false,
+			[]string{
+				`read "x"`,
+				`read "a := x + 1"`,
+				`read "c := double(a)"`,
+				`write "return c"`,
+				`read "v"`,
+				`read "return v * 2"`,
+			},
+		},
+		{
+			"b := y \\* 2", true,
+			[]string{
+				`read "y"`,
+				`write "b := y * 2"`,
+				`read "println(b)"`,
+			},
+		},
+	}
+	Run(t, files, func(t *testing.T, env *Env) {
+		env.OpenFile("a/a.go")
+		content := env.BufferText("a/a.go")
+		mapper := protocol.NewMapper(env.Sandbox.Workdir.URI("a/a.go"), []byte(content))
+		for _, test := range tests {
+			cmd := command.NewSliceCommand("slice", command.SliceArgs{
+				Location: env.RegexpSearch("a/a.go", test.selection),
+				Forward:  test.forward,
+			})
+			var highlights []protocol.DocumentHighlight
+			env.ExecuteCommand(&protocol.ExecuteCommandParams{
+				Command:   command.Slice.String(),
+				Arguments: cmd.Arguments,
+			}, &highlights)
+
+			var got []string
+			for _, h := range highlights {
+				start, end, err := mapper.RangeOffsets(h.Range)
+				if err != nil {
+					t.Fatal(err)
+				}
+				kind := "read"
+				if h.Kind == protocol.Write {
+					kind = "write"
+				}
+				got = append(got, fmt.Sprintf("%s %q", kind, content[start:end]))
+			}
+			if diff := cmp.Diff(test.want, got); diff != "" {
+				t.Errorf("gopls.slice(%q, forward=%t): unexpected highlights (-want +got):\n%s", test.selection, test.forward, diff)
+			}
+		}
+	})
+}
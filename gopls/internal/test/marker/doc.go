@@ -100,6 +100,16 @@ The following markers are supported within marker tests:
     completion candidate produced at the given location with provided label
     results in the given golden state.
 
+  - changesignature(location, args, golden): executes the
+    gopls.change_signature command for the function declaration at the
+    given location. The args string is a JSON object holding the other
+    fields of command.ChangeSignatureArgs, such as NewParams. The golden
+    directory contains changed file content after the command is applied.
+
+  - changesignatureerr(location, args, wantError): specifies a
+    gopls.change_signature command that fails with an error that matches
+    the expectation.
+
   - codeaction(start, end, kind, golden): specifies a code action
     to request for the given range. To support multi-line ranges, the range
     is defined to be between start.Start and end.End. The golden directory
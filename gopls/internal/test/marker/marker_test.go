@@ -35,6 +35,7 @@ import (
 	"golang.org/x/tools/gopls/internal/debug"
 	"golang.org/x/tools/gopls/internal/lsprpc"
 	"golang.org/x/tools/gopls/internal/protocol"
+	"golang.org/x/tools/gopls/internal/protocol/command"
 	"golang.org/x/tools/gopls/internal/test/compare"
 	"golang.org/x/tools/gopls/internal/test/integration"
 	"golang.org/x/tools/gopls/internal/test/integration/fake"
@@ -485,39 +486,41 @@ var valueMarkerFuncs = map[string]func(marker){
 
 // Supported action marker functions. See [actionMarkerFunc] for more details.
 var actionMarkerFuncs = map[string]func(marker){
-	"acceptcompletion": actionMarkerFunc(acceptCompletionMarker),
-	"codeaction":       actionMarkerFunc(codeActionMarker),
-	"codeactionedit":   actionMarkerFunc(codeActionEditMarker),
-	"codeactionerr":    actionMarkerFunc(codeActionErrMarker),
-	"codelenses":       actionMarkerFunc(codeLensesMarker),
-	"complete":         actionMarkerFunc(completeMarker),
-	"def":              actionMarkerFunc(defMarker),
-	"diag":             actionMarkerFunc(diagMarker),
-	"documentlink":     actionMarkerFunc(documentLinkMarker),
-	"foldingrange":     actionMarkerFunc(foldingRangeMarker),
-	"format":           actionMarkerFunc(formatMarker),
-	"highlight":        actionMarkerFunc(highlightMarker),
-	"highlightall":     actionMarkerFunc(highlightAllMarker),
-	"hover":            actionMarkerFunc(hoverMarker),
-	"hovererr":         actionMarkerFunc(hoverErrMarker),
-	"implementation":   actionMarkerFunc(implementationMarker),
-	"incomingcalls":    actionMarkerFunc(incomingCallsMarker),
-	"inlayhints":       actionMarkerFunc(inlayhintsMarker),
-	"outgoingcalls":    actionMarkerFunc(outgoingCallsMarker),
-	"preparerename":    actionMarkerFunc(prepareRenameMarker),
-	"rank":             actionMarkerFunc(rankMarker),
-	"refs":             actionMarkerFunc(refsMarker),
-	"rename":           actionMarkerFunc(renameMarker),
-	"renameerr":        actionMarkerFunc(renameErrMarker),
-	"selectionrange":   actionMarkerFunc(selectionRangeMarker),
-	"signature":        actionMarkerFunc(signatureMarker),
-	"snippet":          actionMarkerFunc(snippetMarker),
-	"quickfix":         actionMarkerFunc(quickfixMarker),
-	"quickfixerr":      actionMarkerFunc(quickfixErrMarker),
-	"symbol":           actionMarkerFunc(symbolMarker),
-	"token":            actionMarkerFunc(tokenMarker),
-	"typedef":          actionMarkerFunc(typedefMarker),
-	"workspacesymbol":  actionMarkerFunc(workspaceSymbolMarker),
+	"acceptcompletion":   actionMarkerFunc(acceptCompletionMarker),
+	"changesignature":    actionMarkerFunc(changeSignatureMarker),
+	"changesignatureerr": actionMarkerFunc(changeSignatureErrMarker),
+	"codeaction":         actionMarkerFunc(codeActionMarker),
+	"codeactionedit":     actionMarkerFunc(codeActionEditMarker),
+	"codeactionerr":      actionMarkerFunc(codeActionErrMarker),
+	"codelenses":         actionMarkerFunc(codeLensesMarker),
+	"complete":           actionMarkerFunc(completeMarker),
+	"def":                actionMarkerFunc(defMarker),
+	"diag":               actionMarkerFunc(diagMarker),
+	"documentlink":       actionMarkerFunc(documentLinkMarker),
+	"foldingrange":       actionMarkerFunc(foldingRangeMarker),
+	"format":             actionMarkerFunc(formatMarker),
+	"highlight":          actionMarkerFunc(highlightMarker),
+	"highlightall":       actionMarkerFunc(highlightAllMarker),
+	"hover":              actionMarkerFunc(hoverMarker),
+	"hovererr":           actionMarkerFunc(hoverErrMarker),
+	"implementation":     actionMarkerFunc(implementationMarker),
+	"incomingcalls":      actionMarkerFunc(incomingCallsMarker),
+	"inlayhints":         actionMarkerFunc(inlayhintsMarker),
+	"outgoingcalls":      actionMarkerFunc(outgoingCallsMarker),
+	"preparerename":      actionMarkerFunc(prepareRenameMarker),
+	"rank":               actionMarkerFunc(rankMarker),
+	"refs":               actionMarkerFunc(refsMarker),
+	"rename":             actionMarkerFunc(renameMarker),
+	"renameerr":          actionMarkerFunc(renameErrMarker),
+	"selectionrange":     actionMarkerFunc(selectionRangeMarker),
+	"signature":          actionMarkerFunc(signatureMarker),
+	"snippet":            actionMarkerFunc(snippetMarker),
+	"quickfix":           actionMarkerFunc(quickfixMarker),
+	"quickfixerr":        actionMarkerFunc(quickfixErrMarker),
+	"symbol":             actionMarkerFunc(symbolMarker),
+	"token":              actionMarkerFunc(tokenMarker),
+	"typedef":            actionMarkerFunc(typedefMarker),
+	"workspacesymbol":    actionMarkerFunc(workspaceSymbolMarker),
 }
 
 // markerTest holds all the test data extracted from a test txtar archive.
@@ -1934,6 +1937,43 @@ func codeActionErrMarker(mark marker, start, end protocol.Location, actionKind s
 	wantErr.checkErr(mark, err)
 }
 
+// changeSignatureMarker implements the @changesignature(location, args,
+// golden) marker. It executes the gopls.change_signature command for the
+// function declaration at the given location, with the remaining command
+// arguments decoded from the JSON object args, and compares the resulting
+// file contents with the golden directory.
+func changeSignatureMarker(mark marker, loc protocol.Location, args string, g *Golden) {
+	changed, err := changeSignature(mark.run.env, loc, args)
+	if err != nil {
+		mark.errorf("changeSignature failed: %v", err)
+		return
+	}
+
+	checkChangedFiles(mark, changed, g)
+}
+
+func changeSignatureErrMarker(mark marker, loc protocol.Location, args string, wantErr stringMatcher) {
+	_, err := changeSignature(mark.run.env, loc, args)
+	wantErr.checkErr(mark, err)
+}
+
+// changeSignature executes the gopls.change_signature command for the
+// function declaration at loc, with the remaining arguments decoded from
+// the JSON object args. The resulting map contains the changed file
+// contents.
+func changeSignature(env *integration.Env, loc protocol.Location, args string) (map[string][]byte, error) {
+	var csargs command.ChangeSignatureArgs
+	if err := json.Unmarshal([]byte(args), &csargs); err != nil {
+		env.T.Fatalf("invalid change signature arguments %s: %v", args, err)
+	}
+	csargs.Location = loc
+	changes, err := commandChanges(env, command.NewChangeSignatureCommand("", csargs))
+	if err != nil {
+		return nil, err
+	}
+	return changedFiles(env, changes)
+}
+
 // codeLensesMarker runs the @codelenses() marker, collecting @codelens marks
 // in the current file and comparing with the result of the
 // textDocument/codeLens RPC.
@@ -2144,26 +2184,32 @@ func codeActionChanges(env *integration.Env, uri protocol.DocumentURI, rng proto
 		// whose WorkspaceEditFunc hook temporarily gathers the edits
 		// instead of applying them.
 
-		var changes []protocol.DocumentChange
-		cli := env.Editor.Client()
-		restore := cli.SetApplyEditHandler(func(ctx context.Context, wsedit *protocol.WorkspaceEdit) error {
-			changes = append(changes, wsedit.DocumentChanges...)
-			return nil
-		})
-		defer restore()
-
-		if _, err := env.Editor.Server.ExecuteCommand(env.Ctx, &protocol.ExecuteCommandParams{
-			Command:   action.Command.Command,
-			Arguments: action.Command.Arguments,
-		}); err != nil {
-			return nil, err
-		}
-		return changes, nil // populated as a side effect of ExecuteCommand
+		return commandChanges(env, action.Command)
 	}
 
 	return nil, nil
 }
 
+// commandChanges executes the given command, and captures the document
+// changes that the server asks the client to apply.
+func commandChanges(env *integration.Env, cmd *protocol.Command) ([]protocol.DocumentChange, error) {
+	var changes []protocol.DocumentChange
+	cli := env.Editor.Client()
+	restore := cli.SetApplyEditHandler(func(ctx context.Context, wsedit *protocol.WorkspaceEdit) error {
+		changes = append(changes, wsedit.DocumentChanges...)
+		return nil
+	})
+	defer restore()
+
+	if _, err := env.Editor.Server.ExecuteCommand(env.Ctx, &protocol.ExecuteCommandParams{
+		Command:   cmd.Command,
+		Arguments: cmd.Arguments,
+	}); err != nil {
+		return nil, err
+	}
+	return changes, nil // populated as a side effect of ExecuteCommand
+}
+
 // refsMarker implements the @refs marker.
 func refsMarker(mark marker, src protocol.Location, want ...protocol.Location) {
 	refs := func(includeDeclaration bool, want []protocol.Location) error {
@@ -185,6 +185,42 @@ func collectReferences(f *ast.File) References {
 	return refs
 }
 
+// collectCalls returns the number of arguments of the calls through
+// the package references of f, as recorded by collectReferences.
+func collectCalls(f *ast.File) Calls {
+	calls := Calls{}
+	ast.Inspect(f, func(n ast.Node) bool {
+		call, ok := n.(*ast.CallExpr)
+		if !ok {
+			return true
+		}
+		sel, ok := call.Fun.(*ast.SelectorExpr)
+		if !ok {
+			return true
+		}
+		xident, ok := sel.X.(*ast.Ident)
+		if !ok || xident.Obj != nil || !ast.IsExported(sel.Sel.Name) {
+			return true
+		}
+		nargs := len(call.Args)
+		if call.Ellipsis.IsValid() {
+			nargs = -1
+		} else if nargs == 1 {
+			if _, ok := call.Args[0].(*ast.CallExpr); ok {
+				nargs = -1 // may be a multi-valued call
+			}
+		}
+		c := calls[xident.Name]
+		if c == nil {
+			c = make(map[string][]int)
+			calls[xident.Name] = c
+		}
+		c[sel.Sel.Name] = append(c[sel.Sel.Name], nargs)
+		return true
+	})
+	return calls
+}
+
 // collectImports returns all the imports in f.
 // Unnamed imports (., _) and "C" are ignored.
 func collectImports(f *ast.File) []*ImportInfo {
@@ -245,6 +281,7 @@ type pass struct {
 	loadRealPackageNames bool        // if true, load package names from disk rather than guessing them.
 	otherFiles           []*ast.File // sibling files.
 	goroot               string
+	typeInfo             *TypeInfo // type information for f, or nil
 
 	// Intermediate state, generated by load.
 	existingImports map[string][]*ImportInfo
@@ -342,8 +379,17 @@ func (p *pass) load(ctx context.Context) ([]*ImportFix, bool) {
 	}
 
 	// Resolve all the import paths we've seen to package names, and store
-	// f's imports by the identifier they introduce.
+	// f's imports by the identifier they introduce. The type checker, if
+	// available, knows the names of the packages f imports.
 	imports := collectImports(p.f)
+	for _, imp := range imports {
+		if t, ok := p.typeInfo.lookup(imp); ok {
+			p.knownPackages[imp.ImportPath] = &PackageInfo{
+				Name:    t.name,
+				Exports: map[string]bool{},
+			}
+		}
+	}
 	if p.loadRealPackageNames {
 		err := p.loadPackageNames(ctx, append(imports, p.candidates...))
 		if err != nil {
@@ -395,12 +441,7 @@ func (p *pass) fix() ([]*ImportFix, bool) {
 	var fixes []*ImportFix
 	for _, identifierImports := range p.existingImports {
 		for _, imp := range identifierImports {
-			// We deliberately ignore globals here, because we can't be sure
-			// they're in the same package. People do things like put multiple
-			// main packages in the same directory, and we don't want to
-			// remove imports if they happen to have the same name as a var in
-			// a different package.
-			if _, ok := p.allRefs[p.importIdentifier(imp)]; !ok {
+			if p.isUnused(imp) {
 				fixes = append(fixes, &ImportFix{
 					StmtInfo:  *imp,
 					IdentName: p.importIdentifier(imp),
@@ -445,6 +486,26 @@ func (p *pass) fix() ([]*ImportFix, bool) {
 	return append(fixes, selectedFixes...), true
 }
 
+// isUnused reports whether the existing import imp of p.f is unused:
+// it must be unreferenced syntactically and, if the type checker knows
+// imp, unused according to it too. No import of a file that does not
+// parse is unused.
+func (p *pass) isUnused(imp *ImportInfo) bool {
+	if p.typeInfo != nil && p.typeInfo.parseErrors {
+		return false
+	}
+	if t, ok := p.typeInfo.lookup(imp); ok && t.used {
+		return false
+	}
+	// We deliberately ignore globals here, because we can't be sure
+	// they're in the same package. People do things like put multiple
+	// main packages in the same directory, and we don't want to
+	// remove imports if they happen to have the same name as a var in
+	// a different package.
+	_, referenced := p.allRefs[p.importIdentifier(imp)]
+	return !referenced
+}
+
 func sortFixes(fixes []*ImportFix) {
 	sort.Slice(fixes, func(i, j int) bool {
 		fi, fj := fixes[i], fixes[j]
@@ -554,7 +615,7 @@ func (p *pass) addCandidate(imp *ImportInfo, pkg *PackageInfo) {
 var fixImports = fixImportsDefault
 
 func fixImportsDefault(fset *token.FileSet, f *ast.File, filename string, env *ProcessEnv) error {
-	fixes, err := getFixes(context.Background(), fset, f, filename, env)
+	fixes, err := getFixes(context.Background(), fset, f, filename, env, nil)
 	if err != nil {
 		return err
 	}
@@ -563,8 +624,8 @@ func fixImportsDefault(fset *token.FileSet, f *ast.File, filename string, env *P
 }
 
 // getFixes gets the import fixes that need to be made to f in order to fix the imports.
-// It does not modify the ast.
-func getFixes(ctx context.Context, fset *token.FileSet, f *ast.File, filename string, env *ProcessEnv) ([]*ImportFix, error) {
+// It does not modify the ast. If tinfo is non-nil, it describes the imports of f.
+func getFixes(ctx context.Context, fset *token.FileSet, f *ast.File, filename string, env *ProcessEnv, tinfo *TypeInfo) ([]*ImportFix, error) {
 	source, err := NewProcessEnvSource(env, filename, f.Name.Name)
 	if err != nil {
 		return nil, err
@@ -573,10 +634,10 @@ func getFixes(ctx context.Context, fset *token.FileSet, f *ast.File, filename st
 	if err != nil {
 		return nil, err
 	}
-	return getFixesWithSource(ctx, fset, f, filename, goEnv["GOROOT"], env.logf, source)
+	return getFixesWithSource(ctx, fset, f, filename, goEnv["GOROOT"], env.logf, source, tinfo)
 }
 
-func getFixesWithSource(ctx context.Context, fset *token.FileSet, f *ast.File, filename string, goroot string, logf func(string, ...any), source Source) ([]*ImportFix, error) {
+func getFixesWithSource(ctx context.Context, fset *token.FileSet, f *ast.File, filename string, goroot string, logf func(string, ...any), source Source, tinfo *TypeInfo) ([]*ImportFix, error) {
 	// This logic is defensively duplicated from getFixes.
 	abs, err := filepath.Abs(filename)
 	if err != nil {
@@ -593,12 +654,13 @@ func getFixesWithSource(ctx context.Context, fset *token.FileSet, f *ast.File, f
 	// complete. We can't add any imports yet, because we don't know
 	// if missing references are actually package vars.
 	p := &pass{
-		fset:   fset,
-		f:      f,
-		srcDir: srcDir,
-		logf:   logf,
-		goroot: goroot,
-		source: source,
+		fset:     fset,
+		f:        f,
+		srcDir:   srcDir,
+		logf:     logf,
+		goroot:   goroot,
+		source:   source,
+		typeInfo: tinfo,
 	}
 	if fixes, done := p.load(ctx); done {
 		return fixes, nil
@@ -626,12 +688,13 @@ func getFixesWithSource(ctx context.Context, fset *token.FileSet, f *ast.File, f
 	// Third pass: get real package names where we had previously used
 	// the naive algorithm.
 	p = &pass{
-		fset:   fset,
-		f:      f,
-		srcDir: srcDir,
-		logf:   logf,
-		goroot: goroot,
-		source: p.source, // safe to reuse, as it's just a wrapper around env
+		fset:     fset,
+		f:        f,
+		srcDir:   srcDir,
+		logf:     logf,
+		goroot:   goroot,
+		source:   p.source, // safe to reuse, as it's just a wrapper around env
+		typeInfo: tinfo,
 	}
 	p.loadRealPackageNames = true
 	p.otherFiles = otherFiles
@@ -933,6 +996,15 @@ type ProcessEnv struct {
 	// multiple ProcessEnvs.
 	ModCache *DirInfoCache
 
+	// If UseModIndex is set, packages in the module cache other than
+	// the dependencies of the main module are found using the index
+	// maintained by package modindex rather than by scanning the
+	// module cache. ModIndex, if set, holds the index to use, which
+	// may be shared across multiple ProcessEnvs; otherwise the index
+	// of GOMODCACHE is used, and updated on first use.
+	UseModIndex bool
+	ModIndex    *ModIndex
+
 	initialized bool // see TODO above
 
 	// resolver and resolverErr are lazily evaluated (see GetResolver).
@@ -965,6 +1037,8 @@ func (e *ProcessEnv) CopyConfig() *ProcessEnv {
 		BuildFlags:  e.BuildFlags,
 		Logf:        e.Logf,
 		WorkingDir:  e.WorkingDir,
+		UseModIndex: e.UseModIndex,
+		ModIndex:    e.ModIndex,
 		resolver:    nil,
 		Env:         map[string]string{},
 	}
@@ -1189,7 +1263,15 @@ func addExternalCandidates(ctx context.Context, pass *pass, refs References, fil
 	ctx, done := event.Start(ctx, "imports.addExternalCandidates")
 	defer done()
 
-	results, err := pass.source.ResolveReferences(ctx, filename, refs)
+	var (
+		results map[PackageName]*Result
+		err     error
+	)
+	if source, ok := pass.source.(CallsSource); ok {
+		results, err = source.ResolveCalls(ctx, filename, refs, collectCalls(pass.f))
+	} else {
+		results, err = pass.source.ResolveReferences(ctx, filename, refs)
+	}
 	if err != nil {
 		return err
 	}
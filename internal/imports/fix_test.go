@@ -8,7 +8,11 @@ import (
 	"context"
 	"flag"
 	"fmt"
+	"go/ast"
 	"go/build"
+	"go/parser"
+	"go/token"
+	"go/types"
 	"log"
 	"os"
 	"path"
@@ -2058,6 +2062,93 @@ const _ = runtime.GOOS
 	}
 }
 
+// Tests that the Grouping option sorts imports into the configured
+// groups and applies the required names.
+func TestGrouping(t *testing.T) {
+	grouping, err := ParseGrouping(strings.Split(`
+std
+default
+prefix example.com/ example.com/org/ # the longest prefix wins
+regexp ^example\.com/org/.*/gen/
+blank
+alias example.com/org/api/gen/foo foopb
+`, "\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	const src = `package main
+
+import (
+	"example.com/org/api/gen/foo"
+	_ "embed"
+	"example.com/org/x"
+	"example.com/y"
+	"fmt"
+	"github.com/z"
+)
+
+func f() {
+	fmt.Println(foo.X, x.X, y.Y, z.Z)
+}
+
+func g(foo struct{ X int }) {
+	_ = foo.X // not a reference to the import
+}
+`
+	const want = `package main
+
+import (
+	"fmt"
+
+	"github.com/z"
+
+	"example.com/org/x"
+	"example.com/y"
+
+	foopb "example.com/org/api/gen/foo"
+
+	_ "embed"
+)
+
+func f() {
+	fmt.Println(foopb.X, x.X, y.Y, z.Z)
+}
+
+func g(foo struct{ X int }) {
+	_ = foo.X // not a reference to the import
+}
+`
+	options := &Options{
+		Grouping:   grouping,
+		FormatOnly: true,
+		TabWidth:   8,
+		TabIndent:  true,
+		Comments:   true,
+	}
+	got, err := Process("main.go", []byte(src), options)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(got) != want {
+		t.Errorf("got:\n%s\nwant:\n%s", got, want)
+	}
+}
+
+func TestParseGroupingErrors(t *testing.T) {
+	for _, test := range []struct{ line, want string }{
+		{"std x", "line 1: std takes 0 arguments"},
+		{"prefix", "line 1: prefix requires at least one prefix"},
+		{"regexp (", "line 1: error parsing regexp: missing closing ): `(`"},
+		{"alias p", "line 1: alias takes 2 arguments"},
+		{"local x", `line 1: unknown directive "local"`},
+	} {
+		if _, err := ParseGrouping([]string{test.line}); err == nil || err.Error() != test.want {
+			t.Errorf("ParseGrouping(%q) = %v, want %q", test.line, err, test.want)
+		}
+	}
+}
+
 // Tests that "package documentation" files are ignored.
 func TestIgnoreDocumentationPackage(t *testing.T) {
 	const input = `package x
@@ -3085,3 +3176,122 @@ func BenchmarkMatchesPath(b *testing.B) {
 		})
 	}
 }
+
+// importerFunc implements types.Importer.
+type importerFunc func(path string) (*types.Package, error)
+
+func (f importerFunc) Import(path string) (*types.Package, error) { return f(path) }
+
+// TestTypeInfo tests that type information decides the names of
+// imported packages and which imports are unused. The type information
+// may come from a different version of the file, as when the file
+// given to the type checker was repaired after a parse error.
+func TestTypeInfo(t *testing.T) {
+	const (
+		usesRealname = "package main\n\nimport \"foo.com/pkgx\"\n\nvar _ = realname.X\n"
+		usesFmt      = "package main\n\nimport \"fmt\"\n\nvar _ = fmt.Sprint\n"
+		importsFmt   = "package main\n\nimport \"fmt\"\n\nvar _ = 1\n"
+		noImports    = "package main\n\nvar _ = 1\n"
+		// The reference to fmt is lost in a parse error.
+		parseError = "package main\n\nimport \"fmt\"\n\nfunc f() {\n\tdefer fmt.Sprint\n}\n"
+		fixedError = "package main\n\nimport \"fmt\"\n\nfunc f() {\n\tdefer fmt.Sprint()\n}\n"
+	)
+	tests := []struct {
+		name         string
+		checked, src string // the file as type-checked, and processed
+		want         string
+	}{
+		{"package name", usesRealname, usesRealname, usesRealname},
+		{"used by types", usesFmt, importsFmt, importsFmt},
+		{"unused by types", importsFmt, importsFmt, noImports},
+		{"unused by types, referenced", importsFmt, usesFmt, usesFmt},
+		{"parse error, referenced", parseError, fixedError, fixedError},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			opts := &Options{
+				Comments:  true,
+				TabIndent: true,
+				TabWidth:  8,
+				TypeInfo:  checkTypeInfo(t, test.checked),
+			}
+			testConfig{
+				module: packagestest.Module{
+					Name:  "foo.com",
+					Files: fm{"test/t.go": test.src},
+				},
+			}.processTest(t, "foo.com", "test/t.go", nil, opts, test.want)
+		})
+	}
+}
+
+// TestTypeInfoParseError tests that, given type information, FixImports
+// fixes a file that does not parse without removing any of its imports.
+func TestTypeInfoParseError(t *testing.T) {
+	const src = `package main
+
+import (
+	"fmt"
+	"os"
+)
+
+func f() {
+	defer fmt.Sprint
+	_ = strings.ToUpper("")
+}
+`
+	testConfig{
+		module: packagestest.Module{
+			Name:  "foo.com",
+			Files: fm{"test/t.go": src},
+		},
+	}.test(t, func(t *goimportTest) {
+		filename := t.exported.File("foo.com", "test/t.go")
+		source, err := NewProcessEnvSource(t.env, filename, "main")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := FixImports(context.Background(), filename, []byte(src), t.goroot, nil, source, nil); err == nil {
+			t.Fatal("FixImports without type information succeeded despite the parse error")
+		}
+		fixes, err := FixImports(context.Background(), filename, []byte(src), t.goroot, nil, source, checkTypeInfo(t.T, src))
+		if err != nil {
+			t.Fatal(err)
+		}
+		var got []string
+		for _, fix := range fixes {
+			got = append(got, fmt.Sprintf("%v %s", fix.FixType, fix.StmtInfo.ImportPath))
+		}
+		if want := []string{fmt.Sprintf("%v strings", AddImport)}; !reflect.DeepEqual(got, want) {
+			t.Errorf("FixImports = %q, want %q", got, want)
+		}
+	})
+}
+
+// checkTypeInfo type checks src, which may have parse and type errors,
+// and returns the TypeInfo of the file.
+func checkTypeInfo(t *testing.T, src string) *TypeInfo {
+	// Packages known only to the type checker.
+	names := map[string]string{"foo.com/pkgx": "realname", "fmt": "fmt", "os": "os"}
+	imp := importerFunc(func(path string) (*types.Package, error) {
+		pkg := types.NewPackage(path, names[path])
+		for _, name := range []string{"X", "Sprint"} {
+			pkg.Scope().Insert(types.NewVar(token.NoPos, pkg, name, types.Typ[types.Int]))
+		}
+		pkg.MarkComplete()
+		return pkg, nil
+	})
+	fset := token.NewFileSet()
+	f, _ := parser.ParseFile(fset, "t.go", src, parser.AllErrors)
+	if f == nil {
+		t.Fatalf("no syntax tree for %q", src)
+	}
+	info := &types.Info{
+		Defs:      make(map[*ast.Ident]types.Object),
+		Uses:      make(map[*ast.Ident]types.Object),
+		Implicits: make(map[ast.Node]types.Object),
+	}
+	conf := types.Config{Importer: imp, Error: func(error) {}}
+	conf.Check("main", fset, []*ast.File{f}, info)
+	return NewTypeInfo(f, info)
+}
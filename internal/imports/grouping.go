@@ -0,0 +1,226 @@
+// Copyright 2024 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package imports
+
+import (
+	"fmt"
+	"go/ast"
+	"regexp"
+	"strings"
+)
+
+// A Grouping specifies how imports are sorted into groups, separated
+// by blank lines, and which names are required for some imports.
+// It replaces the default grouping of standard library, third-party
+// and local (see Options.LocalPrefix) imports.
+type Grouping struct {
+	Groups  []Group           // groups, in order of appearance
+	Aliases map[string]string // maps an import path to its required name
+}
+
+// A Group describes one group of imports.
+//
+// An import belongs to the most specific group that matches it: a
+// blank or dot import belongs to the BlankGroup or DotGroup, if any;
+// otherwise to the first RegexpGroup whose Regexp matches its path;
+// otherwise to the PrefixGroup with the longest prefix of its path;
+// otherwise to the StdGroup if it is in the standard library;
+// otherwise to the DefaultGroup. An import that belongs to no group
+// is placed after all groups.
+type Group struct {
+	Kind     GroupKind
+	Prefixes []string       // for PrefixGroup
+	Regexp   *regexp.Regexp // for RegexpGroup
+}
+
+// A GroupKind is the kind of a Group.
+type GroupKind int
+
+const (
+	StdGroup     GroupKind = iota // standard library imports
+	DefaultGroup                  // imports that belong to no other group
+	PrefixGroup                   // imports whose path begins with one of Prefixes
+	RegexpGroup                   // imports whose path matches Regexp
+	BlankGroup                    // blank imports (import _ "path")
+	DotGroup                      // dot imports (import . "path")
+)
+
+// ParseGrouping parses a Grouping from lines of the form:
+//
+//	std                    # standard library imports
+//	default                # imports that belong to no other group
+//	prefix PREFIX...       # imports beginning with one of the prefixes
+//	regexp REGEXP          # imports matching the regular expression
+//	blank                  # blank imports
+//	dot                    # dot imports
+//	alias PATH NAME        # import PATH must be named NAME
+//
+// Group lines appear in the order of the groups. Blank lines and
+// comments beginning with '#' are ignored.
+func ParseGrouping(lines []string) (*Grouping, error) {
+	g := new(Grouping)
+	for i, line := range lines {
+		if j := strings.IndexByte(line, '#'); j >= 0 {
+			line = line[:j]
+		}
+		fields := strings.Fields(line)
+		if len(fields) == 0 {
+			continue
+		}
+		errorf := func(format string, args ...any) error {
+			return fmt.Errorf("line %d: %s", i+1, fmt.Sprintf(format, args...))
+		}
+		nargs := map[string]int{"std": 0, "default": 0, "blank": 0, "dot": 0, "regexp": 1, "alias": 2}
+		if n, ok := nargs[fields[0]]; ok && len(fields)-1 != n {
+			return nil, errorf("%s takes %d arguments", fields[0], n)
+		}
+		switch fields[0] {
+		case "std":
+			g.Groups = append(g.Groups, Group{Kind: StdGroup})
+		case "default":
+			g.Groups = append(g.Groups, Group{Kind: DefaultGroup})
+		case "blank":
+			g.Groups = append(g.Groups, Group{Kind: BlankGroup})
+		case "dot":
+			g.Groups = append(g.Groups, Group{Kind: DotGroup})
+		case "prefix":
+			if len(fields) < 2 {
+				return nil, errorf("prefix requires at least one prefix")
+			}
+			g.Groups = append(g.Groups, Group{Kind: PrefixGroup, Prefixes: fields[1:]})
+		case "regexp":
+			re, err := regexp.Compile(fields[1])
+			if err != nil {
+				return nil, errorf("%v", err)
+			}
+			g.Groups = append(g.Groups, Group{Kind: RegexpGroup, Regexp: re})
+		case "alias":
+			if g.Aliases == nil {
+				g.Aliases = make(map[string]string)
+			}
+			g.Aliases[fields[1]] = fields[2]
+		default:
+			return nil, errorf("unknown directive %q", fields[0])
+		}
+	}
+	return g, nil
+}
+
+// group returns the index of the group of the import with the given
+// name and path.
+func (g *Grouping) group(name, importPath string) int {
+	find := func(kind GroupKind) int {
+		for i, grp := range g.Groups {
+			if grp.Kind == kind {
+				return i
+			}
+		}
+		return -1
+	}
+	if name == "_" || name == "." {
+		kind := BlankGroup
+		if name == "." {
+			kind = DotGroup
+		}
+		if i := find(kind); i >= 0 {
+			return i
+		}
+	}
+	for i, grp := range g.Groups {
+		if grp.Kind == RegexpGroup && grp.Regexp.MatchString(importPath) {
+			return i
+		}
+	}
+	best, longest := -1, -1
+	for i, grp := range g.Groups {
+		if grp.Kind != PrefixGroup {
+			continue
+		}
+		for _, p := range grp.Prefixes {
+			if (strings.HasPrefix(importPath, p) || strings.TrimSuffix(p, "/") == importPath) && len(p) > longest {
+				best, longest = i, len(p)
+			}
+		}
+	}
+	if best >= 0 {
+		return best
+	}
+	if !strings.Contains(strings.Split(importPath, "/")[0], ".") {
+		if i := find(StdGroup); i >= 0 {
+			return i
+		}
+	}
+	if i := find(DefaultGroup); i >= 0 {
+		return i
+	}
+	return len(g.Groups)
+}
+
+// A groupFunc returns the group number of the import with the
+// given name and path.
+type groupFunc func(name, importPath string) int
+
+// groupFunc returns the grouping function specified by opt.
+func (opt *Options) groupFunc() groupFunc {
+	if opt.Grouping != nil {
+		return opt.Grouping.group
+	}
+	return func(_, importPath string) int {
+		return importGroup(opt.LocalPrefix, importPath)
+	}
+}
+
+// applyAliases gives the imports of f that have a required name in
+// aliases that name, and renames their references accordingly.
+// References are identified by their lack of a resolved object, so f
+// must have been parsed with object resolution.
+func applyAliases(f *ast.File, aliases map[string]string) {
+	if len(aliases) == 0 {
+		return
+	}
+	used := make(map[string]bool) // names of imports
+	for _, imp := range f.Imports {
+		if imp.Name != nil {
+			used[imp.Name.Name] = true
+		} else {
+			used[ImportPathToAssumedName(importPath(imp))] = true
+		}
+	}
+	renames := make(map[string]string)
+	for _, imp := range f.Imports {
+		path := importPath(imp)
+		name, ok := aliases[path]
+		if !ok {
+			continue
+		}
+		old := ImportPathToAssumedName(path)
+		if imp.Name != nil {
+			old = imp.Name.Name
+		}
+		if old == name || old == "_" || old == "." || used[name] || f.Scope != nil && f.Scope.Lookup(name) != nil {
+			continue // nothing to do, or unsafe
+		}
+		if imp.Name != nil {
+			imp.Name.Name = name
+		} else {
+			imp.Name = &ast.Ident{NamePos: imp.Path.Pos(), Name: name}
+		}
+		used[name] = true
+		renames[old] = name
+	}
+	if len(renames) == 0 {
+		return
+	}
+	ast.Inspect(f, func(n ast.Node) bool {
+		if sel, ok := n.(*ast.SelectorExpr); ok {
+			if id, ok := sel.X.(*ast.Ident); ok && id.Obj == nil {
+				if name, ok := renames[id.Name]; ok {
+					id.Name = name
+				}
+			}
+		}
+		return true
+	})
+}
@@ -34,6 +34,10 @@ type Options struct {
 	// into another group after 3rd-party packages.
 	LocalPrefix string
 
+	// Grouping, if non-nil, specifies the grouping of imports and the
+	// names required for some of them. It overrides LocalPrefix.
+	Grouping *Grouping
+
 	Fragment  bool // Accept fragment of a source file (no package statement)
 	AllErrors bool // Report all errors (not just the first 10 on different lines)
 
@@ -42,6 +46,11 @@ type Options struct {
 	TabWidth  int  // Tab width (8 if nil *Options provided)
 
 	FormatOnly bool // Disable the insertion and deletion of imports
+
+	// TypeInfo, if non-nil, describes the imports of the file as
+	// determined by the type checker. It is used to decide precisely
+	// which imports are unused and the names of imported packages.
+	TypeInfo *TypeInfo
 }
 
 // Process implements golang.org/x/tools/imports.Process with explicit context in opt.Env.
@@ -60,10 +69,19 @@ func Process(filename string, src []byte, opt *Options) (formatted []byte, err e
 	}
 
 	if !opt.FormatOnly {
-		if err := fixImports(fileSet, file, filename, opt.Env); err != nil {
+		if opt.TypeInfo != nil {
+			fixes, err := getFixes(context.Background(), fileSet, file, filename, opt.Env, opt.TypeInfo)
+			if err != nil {
+				return nil, err
+			}
+			apply(fileSet, file, fixes)
+		} else if err := fixImports(fileSet, file, filename, opt.Env); err != nil {
 			return nil, err
 		}
 	}
+	if opt.Grouping != nil {
+		applyAliases(file, opt.Grouping.Aliases)
+	}
 	return formatFile(fileSet, file, src, adjust, opt)
 }
 
@@ -73,19 +91,34 @@ func Process(filename string, src []byte, opt *Options) (formatted []byte, err e
 //
 // Note that filename's directory influences which imports can be chosen,
 // so it is important that filename be accurate.
-func FixImports(ctx context.Context, filename string, src []byte, goroot string, logf func(string, ...any), source Source) (fixes []*ImportFix, err error) {
+//
+// If tinfo is non-nil, it describes the imports of src; see [TypeInfo].
+// In that case src need not parse: the fixes then add missing imports
+// and correct import names, but remove no imports.
+func FixImports(ctx context.Context, filename string, src []byte, goroot string, logf func(string, ...any), source Source, tinfo *TypeInfo) (fixes []*ImportFix, err error) {
 	ctx, done := event.Start(ctx, "imports.FixImports")
 	defer done()
 
 	fileSet := token.NewFileSet()
 	// TODO(rfindley): these default values for ParseComments and AllErrors were
 	// extracted from gopls, but are they even needed?
-	file, _, err := parse(fileSet, filename, src, parser.ParseComments|parser.AllErrors, true)
+	const mode = parser.ParseComments | parser.AllErrors
+	file, _, err := parse(fileSet, filename, src, mode, true)
 	if err != nil {
-		return nil, err
+		if tinfo == nil {
+			return nil, err
+		}
+		// Fix what we can of the partial syntax tree, which the
+		// parser returns unless the package clause is missing.
+		fileSet = token.NewFileSet()
+		file, _ = parser.ParseFile(fileSet, filename, src, mode)
+		if file == nil || !file.Package.IsValid() {
+			return nil, err
+		}
+		tinfo = tinfo.withParseErrors()
 	}
 
-	return getFixesWithSource(ctx, fileSet, file, filename, goroot, logf, source)
+	return getFixesWithSource(ctx, fileSet, file, filename, goroot, logf, source, tinfo)
 }
 
 // ApplyFixes applies all of the fixes to the file and formats it. extraMode
@@ -95,7 +128,13 @@ func ApplyFixes(fixes []*ImportFix, filename string, src []byte, opt *Options, e
 	// Don't use parse() -- we don't care about fragments or statement lists
 	// here, and we need to work with unparseable files.
 	fileSet := token.NewFileSet()
-	parserMode := parser.SkipObjectResolution
+	// Required import names can be applied only if the whole file,
+	// with resolved objects, is available to rename references.
+	applyNames := opt.Grouping != nil && len(opt.Grouping.Aliases) > 0 && extraMode&parser.ImportsOnly == 0
+	var parserMode parser.Mode
+	if !applyNames {
+		parserMode |= parser.SkipObjectResolution
+	}
 	if opt.Comments {
 		parserMode |= parser.ParseComments
 	}
@@ -111,6 +150,9 @@ func ApplyFixes(fixes []*ImportFix, filename string, src []byte, opt *Options, e
 
 	// Apply the fixes to the file.
 	apply(fileSet, file, fixes)
+	if applyNames {
+		applyAliases(file, opt.Grouping.Aliases)
+	}
 
 	return formatFile(fileSet, file, src, nil, opt)
 }
@@ -123,7 +165,8 @@ func ApplyFixes(fixes []*ImportFix, filename string, src []byte, opt *Options, e
 // formatted file, and returns the postpocessed result.
 func formatFile(fset *token.FileSet, file *ast.File, src []byte, adjust func(orig []byte, src []byte) []byte, opt *Options) ([]byte, error) {
 	mergeImports(file)
-	sortImports(opt.LocalPrefix, fset.File(file.FileStart), file)
+	group := opt.groupFunc()
+	sortImports(group, fset.File(file.FileStart), file)
 	var spacesBefore []string // import paths we need spaces before
 	for _, impSection := range astutil.Imports(fset, file) {
 		// Within each block of contiguous imports, see if any
@@ -133,7 +176,7 @@ func formatFile(fset *token.FileSet, file *ast.File, src []byte, adjust func(ori
 		lastGroup := -1
 		for _, importSpec := range impSection {
 			importPath, _ := strconv.Unquote(importSpec.Path.Value)
-			groupNum := importGroup(opt.LocalPrefix, importPath)
+			groupNum := group(importName(importSpec), importPath)
 			if groupNum != lastGroup && lastGroup != -1 {
 				spacesBefore = append(spacesBefore, importPath)
 			}
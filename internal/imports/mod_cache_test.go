@@ -6,12 +6,18 @@ package imports
 
 import (
 	"fmt"
+	"os"
 	"os/exec"
+	"path/filepath"
 	"reflect"
 	"sort"
 	"strings"
 	"testing"
 	"time"
+
+	"golang.org/x/tools/internal/gocommand"
+	"golang.org/x/tools/internal/modindex"
+	"golang.org/x/tools/internal/testenv"
 )
 
 func TestDirectoryPackageInfoReachedStatus(t *testing.T) {
@@ -142,3 +148,114 @@ func BenchmarkScanModuleCache(b *testing.B) {
 		ScanModuleCache(gomodcache, cache, nil)
 	}
 }
+
+func TestSearchModIndex(t *testing.T) {
+	cachedir := t.TempDir()
+	indexdir := t.TempDir()
+	defer func(dir func() (string, error)) { modindex.IndexDir = dir }(modindex.IndexDir)
+	modindex.IndexDir = func() (string, error) { return indexdir, nil }
+
+	// Two packages named log with the same exported symbols, which
+	// differ only in the number of parameters of Print.
+	files := map[string]string{
+		"a.com/log@v1.0.0/log.go":            "package log\nfunc Print(s string) {}\nvar Level int\n",
+		"b.com/log@v1.0.0/log.go":            "package log\nfunc Print(format string, args ...any) {}\nvar Level int\n",
+		"c.com/x@v1.0.0/internal/log/log.go": "package log\nfunc Print() {}\nvar Level int\n",
+	}
+	for name, content := range files {
+		filename := filepath.Join(cachedir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(filename), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(filename, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	mi := NewModIndex(cachedir)
+	ix, err := mi.index()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	symbols := map[string]bool{"Print": true, "Level": true}
+	tests := []struct {
+		calls []int
+		want  string
+	}{
+		{nil, "a.com/log"},          // no calls: shortest, then first path
+		{[]int{1}, "a.com/log"},     // both accept one argument
+		{[]int{2}, "b.com/log"},     // only the variadic Print accepts two
+		{[]int{1, 3}, "b.com/log"},  // ... and three
+		{[]int{0}, ""},              // the internal package is not importable
+		{[]int{-1, 2}, "b.com/log"}, // unknown counts are ignored
+	}
+	for _, test := range tests {
+		got := searchModIndex(ix, "log", symbols, map[string][]int{"Print": test.calls})
+		if got != test.want {
+			t.Errorf("searchModIndex(calls=%v) = %q, want %q", test.calls, got, test.want)
+		}
+	}
+	if got := searchModIndex(ix, "log", map[string]bool{"Missing": true}, nil); got != "" {
+		t.Errorf("searchModIndex(Missing) = %q, want none", got)
+	}
+}
+
+// TestProcessModIndex checks that Process resolves a missing import from
+// the module cache index when ProcessEnv.UseModIndex is set, choosing
+// among the candidates by the number of arguments of the calls.
+func TestProcessModIndex(t *testing.T) {
+	testenv.NeedsTool(t, "go")
+
+	dir := t.TempDir()
+	cachedir := filepath.Join(dir, "modcache")
+	indexdir := filepath.Join(dir, "index")
+	defer func(dir func() (string, error)) { modindex.IndexDir = dir }(modindex.IndexDir)
+	modindex.IndexDir = func() (string, error) { return indexdir, nil }
+
+	files := map[string]string{
+		"modcache/a.com/strutil@v1.0.0/strutil.go": "package strutil\nfunc Reverse(s string) string { return s }\n",
+		"modcache/b.com/strutil@v1.0.0/strutil.go": "package strutil\nfunc Reverse(s string, n int) string { return s }\n",
+		"main/go.mod": "module example.com/main\n\ngo 1.18\n",
+	}
+	for name, content := range files {
+		filename := filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(filename), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(filename, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tests := []struct {
+		call, want string
+	}{
+		{`strutil.Reverse("x")`, "a.com/strutil"},
+		{`strutil.Reverse("x", 2)`, "b.com/strutil"},
+	}
+	for _, test := range tests {
+		env := &ProcessEnv{
+			Env: map[string]string{
+				"GOPATH":      filepath.Join(dir, "gopath"),
+				"GOMODCACHE":  cachedir,
+				"GO111MODULE": "on",
+				"GOFLAGS":     "-mod=mod",
+				"GOPROXY":     "off",
+			},
+			WorkingDir:  filepath.Join(dir, "main"),
+			GocmdRunner: &gocommand.Runner{},
+			UseModIndex: true,
+		}
+		src := "package main\n\nvar _ = " + test.call + "\n"
+		want := "package main\n\nimport \"" + test.want + "\"\n\nvar _ = " + test.call + "\n"
+		filename := filepath.Join(dir, "main", "main.go")
+		got, err := Process(filename, []byte(src), &Options{Env: env, Comments: true, TabIndent: true, TabWidth: 8})
+		if err != nil {
+			t.Fatalf("Process(%s): %v", test.call, err)
+		}
+		if string(got) != want {
+			t.Errorf("Process(%s) = %q, want %q", test.call, got, want)
+		}
+	}
+}
@@ -18,6 +18,12 @@ type (
 	// left hand side of a selector expression, the second key is the right hand
 	// side, and the value should always be true.
 	References = map[PackageName]map[Symbol]bool
+
+	// Calls records the number of arguments of each call through a
+	// missing reference, keyed like References. A count of -1 stands
+	// for a call whose number of arguments is unknown, such as f(g())
+	// or f(x...).
+	Calls = map[PackageName]map[Symbol][]int
 )
 
 // A Result satisfies a missing import.
@@ -61,3 +67,13 @@ type Source interface {
 	// missing map.
 	ResolveReferences(ctx context.Context, filename string, missing References) (map[PackageName]*Result, error)
 }
+
+// A CallsSource is a Source that can use the calls through missing
+// references to choose among candidate packages.
+type CallsSource interface {
+	Source
+
+	// ResolveCalls is like ResolveReferences, but it may reject
+	// candidates whose functions cannot accept the given calls.
+	ResolveCalls(ctx context.Context, filename string, missing References, calls Calls) (map[PackageName]*Result, error)
+}
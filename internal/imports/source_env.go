@@ -12,6 +12,7 @@ import (
 
 	"golang.org/x/sync/errgroup"
 	"golang.org/x/tools/internal/gopathwalk"
+	"golang.org/x/tools/internal/modindex"
 )
 
 // ProcessEnvSource implements the [Source] interface using the legacy
@@ -49,10 +50,39 @@ func (s *ProcessEnvSource) LoadPackageNames(ctx context.Context, srcDir string,
 }
 
 func (s *ProcessEnvSource) ResolveReferences(ctx context.Context, filename string, refs map[string]map[string]bool) (map[string]*Result, error) {
+	return s.ResolveCalls(ctx, filename, refs, nil)
+}
+
+// ResolveCalls implements [CallsSource]. If the env uses the module
+// cache index, the number of arguments of the calls is used to choose
+// among the candidates found in the index.
+func (s *ProcessEnvSource) ResolveCalls(ctx context.Context, filename string, refs map[string]map[string]bool, calls Calls) (map[string]*Result, error) {
+	resolver, err := s.env.GetResolver()
+	if err != nil {
+		return nil, err
+	}
+
+	// In module mode, the index replaces the scan of the module cache,
+	// but not of the dependencies of the main module.
+	var index *modindex.Index
+	if _, ok := resolver.(*ModuleResolver); ok {
+		mi, err := s.env.modIndex()
+		if err == nil && mi != nil {
+			index, err = mi.index()
+		}
+		if err != nil {
+			s.env.logf("using module cache index: %v", err)
+			index = nil
+		}
+	}
+
 	var mu sync.Mutex
 	found := make(map[string][]pkgDistance)
 	callback := &scanCallback{
-		rootFound: func(gopathwalk.Root) bool {
+		rootFound: func(root gopathwalk.Root) bool {
+			if index != nil && root.Type == gopathwalk.RootModuleCache && filepath.Clean(root.Path) == filepath.Clean(string(index.Cachedir)) {
+				return false // use the index instead
+			}
 			return true // We want everything.
 		},
 		dirFound: func(pkg *pkg) bool {
@@ -76,10 +106,6 @@ func (s *ProcessEnvSource) ResolveReferences(ctx context.Context, filename strin
 			return false // We'll do our own loading after we sort.
 		},
 	}
-	resolver, err := s.env.GetResolver()
-	if err != nil {
-		return nil, err
-	}
 	if err := resolver.scan(ctx, callback); err != nil {
 		return nil, err
 	}
@@ -101,12 +127,18 @@ func (s *ProcessEnvSource) ResolveReferences(ctx context.Context, filename strin
 			if err != nil {
 				return err
 			}
-			if found == nil {
+			var importPath string
+			if found != nil {
+				importPath = found.importPathShort
+			} else if index != nil {
+				importPath = searchModIndex(index, pkgName, symbols, calls[pkgName])
+			}
+			if importPath == "" {
 				return nil // No matching package.
 			}
 
 			imp := &ImportInfo{
-				ImportPath: found.importPathShort,
+				ImportPath: importPath,
 			}
 			pkg := &PackageInfo{
 				Name:    pkgName,
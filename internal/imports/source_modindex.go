@@ -0,0 +1,142 @@
+// Copyright 2024 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package imports
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+
+	"golang.org/x/tools/internal/modindex"
+)
+
+// A ModIndex provides access to the index of the exported symbols of a
+// module cache maintained by package modindex. It may be shared by
+// several ProcessEnvs.
+type ModIndex struct {
+	dir string // GOMODCACHE
+
+	mu sync.Mutex
+	ix *modindex.Index // nil until first use
+}
+
+// NewModIndex returns a ModIndex for the module cache dir.
+func NewModIndex(dir string) *ModIndex {
+	return &ModIndex{dir: dir}
+}
+
+// Update brings the index up to date with the module cache, creating
+// it if necessary. Only the directories of the module cache that were
+// added since the last update are scanned.
+func (m *ModIndex) Update() error {
+	changed, err := modindex.Update(m.dir)
+	if err != nil {
+		return err
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if changed || m.ix == nil {
+		ix, err := modindex.ReadIndex(m.dir)
+		if err != nil {
+			return err
+		}
+		if ix == nil {
+			return fmt.Errorf("no index of %s", m.dir)
+		}
+		m.ix = ix
+	}
+	return nil
+}
+
+// index returns the current index, updating it on first use.
+func (m *ModIndex) index() (*modindex.Index, error) {
+	m.mu.Lock()
+	ix := m.ix
+	m.mu.Unlock()
+	if ix != nil {
+		return ix, nil
+	}
+	if err := m.Update(); err != nil {
+		return nil, err
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.ix, nil
+}
+
+// modIndex returns the ModIndex of the env, if it is in use.
+func (e *ProcessEnv) modIndex() (*ModIndex, error) {
+	if !e.UseModIndex {
+		return nil, nil
+	}
+	if e.ModIndex == nil {
+		env, err := e.goEnv()
+		if err != nil {
+			return nil, err
+		}
+		if env["GOMODCACHE"] == "" {
+			return nil, fmt.Errorf("GOMODCACHE is not set")
+		}
+		e.ModIndex = NewModIndex(env["GOMODCACHE"])
+	}
+	return e.ModIndex, nil
+}
+
+// searchModIndex returns the import path of the best package in ix
+// named pkgName that exports all of symbols, and whose functions
+// accept the given calls, or "" if there is none.
+//
+// Packages with fewer path segments are preferred, as they are more
+// likely to be the primary API of their module.
+func searchModIndex(ix *modindex.Index, pkgName string, symbols map[string]bool, calls map[string][]int) string {
+	count := make(map[string]int) // import path -> number of symbols satisfied
+	for sym := range symbols {
+		seen := make(map[string]bool)
+		for _, c := range ix.Lookup(pkgName, sym, false) {
+			if c.Type == modindex.Func && !acceptsCalls(c, calls[sym]) {
+				continue
+			}
+			if !seen[c.ImportPath] {
+				seen[c.ImportPath] = true
+				count[c.ImportPath]++
+			}
+		}
+	}
+	var best string
+	for path, n := range count {
+		if n < len(symbols) || isInternalPath(path) {
+			continue
+		}
+		if best == "" || betterIndexPath(path, best) {
+			best = path
+		}
+	}
+	return best
+}
+
+// acceptsCalls reports whether the function c may be called with each
+// of the given numbers of arguments (-1 for unknown).
+func acceptsCalls(c modindex.Candidate, calls []int) bool {
+	for _, n := range calls {
+		if n >= 0 && !c.AcceptsArgs(n) {
+			return false
+		}
+	}
+	return true
+}
+
+// isInternalPath reports whether the package path has an "internal"
+// segment, and so cannot be imported from outside its module.
+func isInternalPath(path string) bool {
+	return strings.HasSuffix(path, "/internal") || strings.Contains(path, "/internal/")
+}
+
+// betterIndexPath reports whether the import path x is preferable to y.
+func betterIndexPath(x, y string) bool {
+	if nx, ny := strings.Count(x, "/"), strings.Count(y, "/"); nx != ny {
+		return nx < ny
+	}
+	return x < y
+}
@@ -0,0 +1,92 @@
+// Copyright 2024 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package imports
+
+import (
+	"go/ast"
+	"go/types"
+	"strconv"
+)
+
+// A TypeInfo records what the type checker determined about the
+// imports of a file: the names of the imported packages, and which
+// imports are used. It allows imports to be fixed precisely even when
+// the packages cannot be found, or when some references to them are
+// not visible to a purely syntactic analysis.
+//
+// The type checker's view of a file may be incomplete, for example
+// because the package has errors or because the file has changed
+// since it was checked, so an import is removed only if it appears
+// unused both to the type checker and syntactically. With a TypeInfo,
+// [FixImports] also accepts a file that does not parse, and then
+// removes no imports at all, as any of them may be referenced from
+// the code that failed to parse.
+//
+// A TypeInfo is independent of the syntax tree from which it was
+// built, so it may be used for a file that is parsed again, provided
+// its imports are unchanged.
+type TypeInfo struct {
+	imports map[ImportInfo]typedImport
+
+	// parseErrors reports that the file being fixed does not parse.
+	parseErrors bool
+}
+
+type typedImport struct {
+	name string // name of the imported package
+	used bool
+}
+
+// NewTypeInfo returns the TypeInfo for file f, whose package was type
+// checked with the given info, which must record Defs, Implicits and
+// Uses.
+func NewTypeInfo(f *ast.File, info *types.Info) *TypeInfo {
+	used := make(map[*types.PkgName]bool)
+	for _, obj := range info.Uses {
+		if pkgname, ok := obj.(*types.PkgName); ok {
+			used[pkgname] = true
+		}
+	}
+	ti := &TypeInfo{imports: make(map[ImportInfo]typedImport)}
+	for _, spec := range f.Imports {
+		path, err := strconv.Unquote(spec.Path.Value)
+		if err != nil {
+			continue
+		}
+		var obj types.Object
+		key := ImportInfo{ImportPath: path}
+		if spec.Name != nil {
+			key.Name = spec.Name.Name
+			obj = info.Defs[spec.Name]
+		} else {
+			obj = info.Implicits[spec]
+		}
+		pkgname, ok := obj.(*types.PkgName)
+		if !ok {
+			continue // blank or dot import, or not type checked
+		}
+		ti.imports[key] = typedImport{
+			name: pkgname.Imported().Name(),
+			used: used[pkgname] || ti.imports[key].used,
+		}
+	}
+	return ti
+}
+
+// withParseErrors returns a copy of ti for a file that does not parse.
+func (ti *TypeInfo) withParseErrors() *TypeInfo {
+	copy := *ti
+	copy.parseErrors = true
+	return &copy
+}
+
+// lookup returns the information about the import imp, if known.
+func (ti *TypeInfo) lookup(imp *ImportInfo) (typedImport, bool) {
+	if ti == nil {
+		return typedImport{}, false
+	}
+	t, ok := ti.imports[*imp]
+	return t, ok
+}
@@ -186,6 +186,9 @@ func writeIndex(cachedir Abspath, ix *Index) error {
 	if err != nil {
 		return err
 	}
+	if err := os.MkdirAll(dir, 0777); err != nil {
+		return err
+	}
 	ipat := fmt.Sprintf("index-%d-*", CurrentVersion)
 	fd, err := os.CreateTemp(dir, ipat)
 	if err != nil {
@@ -15,6 +15,7 @@ type Candidate struct {
 	Name       string
 	Dir        string
 	ImportPath string
+	Version    string // semantic version of the module
 	Type       LexType
 	// information for Funcs
 	Results int16   // how many results
@@ -25,6 +26,19 @@ type Field struct {
 	Arg, Type string
 }
 
+// AcceptsArgs reports whether c is a function that may be called with
+// n arguments: exactly one for each parameter or, if the function is
+// variadic, at least one for each parameter but the last.
+func (c Candidate) AcceptsArgs(n int) bool {
+	if c.Type != Func {
+		return false
+	}
+	if len(c.Sig) > 0 && strings.HasPrefix(c.Sig[len(c.Sig)-1].Type, "...") {
+		return n >= len(c.Sig)-1
+	}
+	return n == len(c.Sig)
+}
+
 type LexType int8
 
 const (
@@ -70,38 +84,50 @@ func (ix *Index) Lookup(pkg, name string, prefix bool) []Candidate {
 				// past range of matching Names
 				break
 			}
-			if len(flds) < 2 {
-				continue // should never happen
+			if px, ok := newCandidate(&e, flds); ok {
+				ans = append(ans, px)
 			}
-			px := Candidate{
-				PkgName:    pkg,
-				Name:       flds[0],
-				Dir:        string(e.Dir),
-				ImportPath: e.ImportPath,
-				Type:       asLexType(flds[1][0]),
-			}
-			if flds[1] == "F" {
-				n, err := strconv.Atoi(flds[2])
-				if err != nil {
-					continue // should never happen
-				}
-				px.Results = int16(n)
-				if len(flds) >= 4 {
-					sig := strings.Split(flds[3], " ")
-					for i := 0; i < len(sig); i++ {
-						// $ cannot otherwise occur. removing the spaces
-						// almost works, but for chan struct{}, e.g.
-						sig[i] = strings.Replace(sig[i], "$", " ", -1)
-					}
-					px.Sig = toFields(sig)
-				}
-			}
-			ans = append(ans, px)
 		}
 	}
 	return ans
 }
 
+// newCandidate returns the Candidate for the fields of a name of entry
+// e, or false if they are malformed.
+func newCandidate(e *Entry, flds []string) (Candidate, bool) {
+	if len(flds) < 2 {
+		return Candidate{}, false // should never happen
+	}
+	px := Candidate{
+		PkgName:    e.PkgName,
+		Name:       flds[0],
+		Dir:        string(e.Dir),
+		ImportPath: e.ImportPath,
+		Version:    e.Version,
+		Type:       asLexType(flds[1][0]),
+	}
+	if flds[1] == "F" {
+		if len(flds) < 3 {
+			return Candidate{}, false // should never happen
+		}
+		n, err := strconv.Atoi(flds[2])
+		if err != nil {
+			return Candidate{}, false // should never happen
+		}
+		px.Results = int16(n)
+		if len(flds) >= 4 {
+			sig := strings.Split(flds[3], " ")
+			for i := 0; i < len(sig); i++ {
+				// $ cannot otherwise occur. removing the spaces
+				// almost works, but for chan struct{}, e.g.
+				sig[i] = strings.Replace(sig[i], "$", " ", -1)
+			}
+			px.Sig = toFields(sig)
+		}
+	}
+	return px, true
+}
+
 func toFields(sig []string) []Field {
 	ans := make([]Field, len(sig)/2)
 	for i := 0; i < len(ans); i++ {
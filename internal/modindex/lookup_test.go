@@ -128,3 +128,91 @@ func wrtData(t *testing.T, dir string, data tdata) {
 		fd.WriteString(item.code + "\n")
 	}
 }
+
+func TestSearch(t *testing.T) {
+	dir := testModCache(t)
+	wrtData(t, dir, thedata)
+	if _, err := indexModCache(dir, true); err != nil {
+		t.Fatal(err)
+	}
+	ix, err := ReadIndex(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	p := ix.Search(func(pkg, name string) bool { return strings.HasSuffix(name, "F") })
+	if len(p) != 1 || !okresult(thedata.items[2].result, p[0]) {
+		t.Fatalf("got %#v, expected FooF", p)
+	}
+	if p[0].ImportPath != "cloud.google.com/go/longrunning" || p[0].Version != "v0.4.1" {
+		t.Errorf("got %s@%s, expected cloud.google.com/go/longrunning@v0.4.1", p[0].ImportPath, p[0].Version)
+	}
+}
+
+func TestModuleSymbols(t *testing.T) {
+	dir := testModCache(t)
+	for _, d := range []tdata{
+		{"example.com/!m@v1.0.0/m.go", "m", []titem{{code: "func Old() {}"}}},
+		{"example.com/!m@v1.2.0/m.go", "m", []titem{{code: "func New(x int) {}"}}},
+		{"example.com/!m@v1.2.0/sub/sub.go", "sub", []titem{{code: "func Old() {}"}}},
+		{"example.com/!m@v1.2.0/internal/i/i.go", "i", []titem{{code: "func Old() {}"}}},
+		{"example.com/!m@v1.10.0/m.go", "m", []titem{{code: "func Old(x, y int) {}"}}},
+	} {
+		wrtData(t, dir, d)
+	}
+	versions, err := Versions(dir, "example.com/M")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := fmt.Sprint(versions), "[v1.10.0 v1.2.0 v1.0.0]"; got != want {
+		t.Errorf("Versions = %s, want %s", got, want)
+	}
+
+	var got []string
+	for _, v := range versions {
+		cands, err := ModuleSymbols(dir, "example.com/M", v)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, c := range cands {
+			if c.Name == "Old" {
+				got = append(got, fmt.Sprintf("%s@%s %s %d", c.ImportPath, c.Version, c.Dir, len(c.Sig)))
+			}
+		}
+	}
+	want := []string{
+		"example.com/M@v1.10.0 example.com/!m@v1.10.0 2",
+		"example.com/M/sub@v1.2.0 example.com/!m@v1.2.0/sub 0",
+		"example.com/M@v1.0.0 example.com/!m@v1.0.0 0",
+	}
+	if fmt.Sprint(got) != fmt.Sprint(want) {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
+
+func TestAcceptsArgs(t *testing.T) {
+	fixed := Candidate{Type: Func, Sig: []Field{{"a", "int"}, {"b", "string"}}}
+	variadic := Candidate{Type: Func, Sig: []Field{{"a", "int"}, {"b", "...string"}}}
+	onlyVariadic := Candidate{Type: Func, Sig: []Field{{"b", "...any"}}}
+	for _, test := range []struct {
+		name string
+		c    Candidate
+		n    int
+		want bool
+	}{
+		{"fixed", fixed, 1, false},
+		{"fixed", fixed, 2, true},
+		{"fixed", fixed, 3, false},
+		{"variadic", variadic, 0, false},
+		{"variadic", variadic, 1, true},
+		{"variadic", variadic, 2, true},
+		{"variadic", variadic, 5, true},
+		{"only variadic", onlyVariadic, 0, true},
+		{"no params", Candidate{Type: Func}, 0, true},
+		{"no params", Candidate{Type: Func}, 1, false},
+		{"var", Candidate{Type: Var}, 0, false},
+	} {
+		if got := test.c.AcceptsArgs(test.n); got != test.want {
+			t.Errorf("%s: AcceptsArgs(%d) = %v, want %v", test.name, test.n, got, test.want)
+		}
+	}
+}
@@ -0,0 +1,131 @@
+// Copyright 2024 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package modindex
+
+import (
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"golang.org/x/mod/module"
+	"golang.org/x/mod/semver"
+)
+
+// Search returns the symbols in the index for which match, called with
+// the package name and the name of each symbol, returns true.
+// Unlike Lookup, it examines every entry of the index.
+func (ix *Index) Search(match func(pkg, name string) bool) []Candidate {
+	var ans []Candidate
+	for i := range ix.Entries {
+		e := &ix.Entries[i]
+		for _, nstr := range e.Names {
+			flds := fastSplit(nstr)
+			if !match(e.PkgName, flds[0]) {
+				continue
+			}
+			if px, ok := newCandidate(e, flds); ok {
+				ans = append(ans, px)
+			}
+		}
+	}
+	return ans
+}
+
+// Versions returns the versions of the module with path modpath that
+// are present in the module cache cachedir, latest first.
+func Versions(cachedir, modpath string) ([]string, error) {
+	escaped, err := module.EscapePath(modpath)
+	if err != nil {
+		return nil, err
+	}
+	parent, base := path.Split(escaped)
+	des, err := os.ReadDir(filepath.Join(cachedir, filepath.FromSlash(parent)))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	var versions []string
+	for _, de := range des {
+		v, ok := strings.CutPrefix(de.Name(), base+"@")
+		if !ok || !de.IsDir() {
+			continue
+		}
+		v, err := module.UnescapeVersion(v)
+		if err != nil || !semver.IsValid(v) {
+			continue
+		}
+		versions = append(versions, v)
+	}
+	slices.SortFunc(versions, func(x, y string) int {
+		return -semver.Compare(x, y) // latest first
+	})
+	return versions, nil
+}
+
+// ModuleSymbols returns the exported symbols of the packages of the
+// given version of the module modpath in the module cache cachedir,
+// which need not be in the index. As for the index, internal packages
+// are ignored, as are nested modules.
+func ModuleSymbols(cachedir, modpath, version string) ([]Candidate, error) {
+	escaped, err := module.EscapePath(modpath)
+	if err != nil {
+		return nil, err
+	}
+	escver, err := module.EscapeVersion(version)
+	if err != nil {
+		return nil, err
+	}
+	reldir := escaped + "@" + escver
+	root := filepath.Join(cachedir, filepath.FromSlash(reldir))
+	var ans []Candidate
+	err = filepath.WalkDir(root, func(dir string, de fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if !de.IsDir() {
+			return nil
+		}
+		rel, err := filepath.Rel(root, dir)
+		if err != nil {
+			return err
+		}
+		rel = filepath.ToSlash(rel)
+		if dir != root {
+			name := de.Name()
+			if name == "internal" || name == "testdata" || name == "vendor" ||
+				strings.HasPrefix(name, ".") || strings.HasPrefix(name, "_") {
+				return filepath.SkipDir
+			}
+			if _, err := os.Stat(filepath.Join(dir, "go.mod")); err == nil {
+				return filepath.SkipDir // a nested module
+			}
+		}
+		pkg, names := processSyms(dirSymbols(dir))
+		if pkg == "" {
+			return nil
+		}
+		e := &Entry{
+			Dir:        Relpath(path.Join(reldir, rel)),
+			ImportPath: path.Join(modpath, rel),
+			PkgName:    pkg,
+			Version:    version,
+		}
+		for _, nstr := range names {
+			if px, ok := newCandidate(e, fastSplit(nstr)); ok {
+				ans = append(ans, px)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return ans, nil
+}
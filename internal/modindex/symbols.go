@@ -40,30 +40,37 @@ func getSymbols(cd Abspath, dirs map[string][]*directory) {
 		// throttling some day?
 		d := vv[0]
 		g.Go(func() error {
-			thedir := filepath.Join(string(cd), string(d.path))
-			mode := parser.SkipObjectResolution
-
-			fi, err := os.ReadDir(thedir)
-			if err != nil {
-				return nil // log this someday?
-			}
-			for _, fx := range fi {
-				if !strings.HasSuffix(fx.Name(), ".go") || strings.HasSuffix(fx.Name(), "_test.go") {
-					continue
-				}
-				fname := filepath.Join(thedir, fx.Name())
-				tr, err := parser.ParseFile(token.NewFileSet(), fname, nil, mode)
-				if err != nil {
-					continue // ignore errors, someday log them?
-				}
-				d.syms = append(d.syms, getFileExports(tr)...)
-			}
+			d.syms = dirSymbols(filepath.Join(string(cd), string(d.path)))
 			return nil
 		})
 	}
 	g.Wait()
 }
 
+// dirSymbols returns the exported symbols of the non-test Go files
+// in the directory.
+func dirSymbols(thedir string) []symbol {
+	mode := parser.SkipObjectResolution
+
+	fi, err := os.ReadDir(thedir)
+	if err != nil {
+		return nil // log this someday?
+	}
+	var syms []symbol
+	for _, fx := range fi {
+		if !strings.HasSuffix(fx.Name(), ".go") || strings.HasSuffix(fx.Name(), "_test.go") {
+			continue
+		}
+		fname := filepath.Join(thedir, fx.Name())
+		tr, err := parser.ParseFile(token.NewFileSet(), fname, nil, mode)
+		if err != nil {
+			continue // ignore errors, someday log them?
+		}
+		syms = append(syms, getFileExports(tr)...)
+	}
+	return syms
+}
+
 func getFileExports(f *ast.File) []symbol {
 	pkg := f.Name.Name
 	if pkg == "main" {
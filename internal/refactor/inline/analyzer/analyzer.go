@@ -15,19 +15,26 @@ import (
 	"golang.org/x/tools/go/analysis"
 	"golang.org/x/tools/go/analysis/passes/inspect"
 	"golang.org/x/tools/go/ast/inspector"
-	"golang.org/x/tools/go/types/typeutil"
 	"golang.org/x/tools/internal/diff"
 	"golang.org/x/tools/internal/refactor/inline"
 )
 
-const Doc = `inline calls to functions with "inlineme" doc comment`
+const Doc = `inline calls to functions with "inlineme" doc comment
+
+The analyzer also forwards references to constants and type aliases
+with an "inlineme" doc comment, such as
+
+	// inlineme
+	const Old = newpkg.New
+
+to the constant or type they denote.`
 
 var Analyzer = &analysis.Analyzer{
 	Name:      "inline",
 	Doc:       Doc,
 	URL:       "https://pkg.go.dev/golang.org/x/tools/internal/refactor/inline/analyzer",
 	Run:       run,
-	FactTypes: []analysis.Fact{new(inlineMeFact)},
+	FactTypes: []analysis.Fact{new(inlineMeFact), new(forwardFact)},
 	Requires:  []*analysis.Analyzer{inspect.Analyzer},
 }
 
@@ -50,12 +57,53 @@ func run(pass *analysis.Pass) (interface{}, error) {
 		return content, nil
 	}
 
-	// Pass 1: find functions annotated with an "inlineme"
-	// comment, and export a fact for each one.
-	inlinable := make(map[*types.Func]*inline.Callee) // memoization of fact import (nil => no fact)
+	// Pass 1: find functions, constants, and type aliases annotated
+	// with an "inlineme" comment, and export a fact for each one.
+	inlinable := make(map[*types.Func]*inline.Callee)     // memoization of fact import (nil => no fact)
+	forwardable := make(map[types.Object]*inline.Forward) // memoization of fact import (nil => no fact)
 	for _, file := range pass.Files {
 		for _, decl := range file.Decls {
-			if decl, ok := decl.(*ast.FuncDecl); ok {
+			switch decl := decl.(type) {
+			case *ast.GenDecl:
+				if decl.Tok != token.CONST && decl.Tok != token.TYPE {
+					continue
+				}
+				for _, spec := range decl.Specs {
+					// The annotation may be on the spec, or
+					// on the declaration if it has only one spec.
+					doc := decl.Doc
+					if decl.Lparen.IsValid() {
+						doc = nil
+					}
+					var names []*ast.Ident
+					switch spec := spec.(type) {
+					case *ast.ValueSpec:
+						if spec.Doc != nil {
+							doc = spec.Doc
+						}
+						names = spec.Names
+					case *ast.TypeSpec:
+						if spec.Doc != nil {
+							doc = spec.Doc
+						}
+						names = []*ast.Ident{spec.Name}
+					}
+					if !strings.Contains(doc.Text(), "inlineme") {
+						continue
+					}
+					for _, id := range names {
+						fwd, err := inline.AnalyzeForward(pass.Pkg, pass.TypesInfo, spec, id)
+						if err != nil {
+							pass.Reportf(doc.Pos(), "invalid forwarding candidate: %v", err)
+							continue
+						}
+						obj := pass.TypesInfo.Defs[id]
+						pass.ExportObjectFact(obj, &forwardFact{fwd})
+						forwardable[obj] = fwd
+					}
+				}
+
+			case *ast.FuncDecl:
 				// TODO(adonovan): this is just a placeholder.
 				// Use the precise go:fix syntax in the proposal.
 				// Beware that //go: comments are treated specially
@@ -83,11 +131,13 @@ func run(pass *analysis.Pass) (interface{}, error) {
 		}
 	}
 
-	// Pass 2. Inline each static call to an inlinable function.
+	// Pass 2. Inline each static call to an inlinable function,
+	// and forward each reference to a forwardable constant or type.
 	inspect := pass.ResultOf[inspect.Analyzer].(*inspector.Inspector)
 	nodeFilter := []ast.Node{
 		(*ast.File)(nil),
 		(*ast.CallExpr)(nil),
+		(*ast.Ident)(nil),
 	}
 	var currentFile *ast.File
 	inspect.Preorder(nodeFilter, func(n ast.Node) {
@@ -95,8 +145,49 @@ func run(pass *analysis.Pass) (interface{}, error) {
 			currentFile = file
 			return
 		}
+		if id, ok := n.(*ast.Ident); ok {
+			obj := pass.TypesInfo.Uses[id]
+			if !is[*types.Const](obj) && !is[*types.TypeName](obj) {
+				return
+			}
+
+			// Forwardable?
+			fwd, ok := forwardable[obj]
+			if !ok {
+				var fact forwardFact
+				if pass.ImportObjectFact(obj, &fact) {
+					fwd = fact.Forward
+				}
+				forwardable[obj] = fwd
+			}
+			if fwd == nil {
+				return // nope
+			}
+
+			// Forward the reference.
+			content, err := readFile(id)
+			if err != nil {
+				pass.Reportf(id.Pos(), "invalid forwarding candidate: cannot read source file: %v", err)
+				return
+			}
+			ref := &inline.Reference{
+				Fset:    pass.Fset,
+				Types:   pass.Pkg,
+				Info:    pass.TypesInfo,
+				File:    currentFile,
+				Ident:   id,
+				Content: content,
+			}
+			res, err := inline.InlineForward(ref, fwd, &inline.Options{Logf: discard})
+			if err != nil {
+				pass.Reportf(id.Pos(), "%v", err)
+				return
+			}
+			report(pass, currentFile, id, content, res.Content, fmt.Sprintf("inline reference to %v", fwd))
+			return
+		}
 		call := n.(*ast.CallExpr)
-		if fn := typeutil.StaticCallee(pass.TypesInfo, call); fn != nil {
+		if fn := inline.ConcreteCallee(pass.TypesInfo, call); fn != nil {
 			// Inlinable?
 			callee, ok := inlinable[fn]
 			if !ok {
@@ -129,33 +220,45 @@ func run(pass *analysis.Pass) (interface{}, error) {
 				pass.Reportf(call.Lparen, "%v", err)
 				return
 			}
-			got := res.Content
-
-			// Suggest the "fix".
-			var textEdits []analysis.TextEdit
-			for _, edit := range diff.Bytes(content, got) {
-				textEdits = append(textEdits, analysis.TextEdit{
-					Pos:     currentFile.FileStart + token.Pos(edit.Start),
-					End:     currentFile.FileStart + token.Pos(edit.End),
-					NewText: []byte(edit.New),
-				})
-			}
-			msg := fmt.Sprintf("inline call of %v", callee)
-			pass.Report(analysis.Diagnostic{
-				Pos:     call.Pos(),
-				End:     call.End(),
-				Message: msg,
-				SuggestedFixes: []analysis.SuggestedFix{{
-					Message:   msg,
-					TextEdits: textEdits,
-				}},
-			})
+			report(pass, currentFile, call, content, res.Content, fmt.Sprintf("inline call of %v", callee))
 		}
 	})
 
 	return nil, nil
 }
 
+// report reports a diagnostic for node n in file, whose suggested
+// fix changes the file content from old to new.
+func report(pass *analysis.Pass, file *ast.File, n ast.Node, old, new []byte, msg string) {
+	var textEdits []analysis.TextEdit
+	for _, edit := range diff.Bytes(old, new) {
+		textEdits = append(textEdits, analysis.TextEdit{
+			Pos:     file.FileStart + token.Pos(edit.Start),
+			End:     file.FileStart + token.Pos(edit.End),
+			NewText: []byte(edit.New),
+		})
+	}
+	pass.Report(analysis.Diagnostic{
+		Pos:     n.Pos(),
+		End:     n.End(),
+		Message: msg,
+		SuggestedFixes: []analysis.SuggestedFix{{
+			Message:   msg,
+			TextEdits: textEdits,
+		}},
+	})
+}
+
+type forwardFact struct{ Forward *inline.Forward }
+
+func (f *forwardFact) String() string { return "inlineme " + f.Forward.String() }
+func (*forwardFact) AFact()           {}
+
+func is[T any](x any) bool {
+	_, ok := x.(T)
+	return ok
+}
+
 type inlineMeFact struct{ Callee *inline.Callee }
 
 func (f *inlineMeFact) String() string { return "inlineme " + f.Callee.String() }
@@ -4,6 +4,12 @@ func f() {
 	One() // want `inline call of a.One`
 
 	new(T).Two() // want `inline call of \(a.T\).Two`
+
+	I(T{}).Three() // want `inline call of \(a.T\).Three`
+
+	_ = Four // want `inline reference to a.Four`
+
+	var _ U // want `inline reference to a.U`
 }
 
 type T struct{}
@@ -15,3 +21,16 @@ const one = 1
 
 // inlineme
 func (T) Two() int { return 2 } // want Two:`inlineme \(a.T\).Two`
+
+type I interface{ Three() int }
+
+// inlineme
+func (T) Three() int { return 3 } // want Three:`inlineme \(a.T\).Three`
+
+// inlineme
+const Four = four // want Four:`inlineme a.Four`
+
+const four = 4
+
+// inlineme
+type U = T // want U:`inlineme a.U`
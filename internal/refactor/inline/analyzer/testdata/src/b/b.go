@@ -6,4 +6,8 @@ func f() {
 	a.One() // want `cannot inline call to a.One because body refers to non-exported one`
 
 	new(a.T).Two() // want `inline call of \(a.T\).Two`
+
+	_ = a.Four // want `cannot forward a.Four to non-exported four`
+
+	var _ a.U // want `inline reference to a.U`
 }
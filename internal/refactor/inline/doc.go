@@ -234,6 +234,17 @@ which they can be addressed.
 More complex callee functions are inlinable with more elaborate and
 invasive changes to the statements surrounding the call expression.
 
+A call through an interface is not generally inlinable, but a call
+I(x).f() whose receiver's dynamic type is statically known, because
+it is a conversion of a value x of concrete type, is treated as a
+call x.f() to the concrete method (see ConcreteCallee).
+
+The package also supports the simpler operation of "forwarding" a
+constant or type alias whose declaration denotes another constant or
+named type, such as "const Old = newpkg.New", by replacing each
+reference to it with a reference to the target, for use in API
+migrations (see AnalyzeForward and InlineForward).
+
 TODO(adonovan): future work:
 
   - Handle more of the above special cases by careful analysis,
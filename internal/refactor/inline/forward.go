@@ -0,0 +1,338 @@
+// Copyright 2024 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package inline
+
+// This file defines the forwarding of constants and type aliases.
+
+import (
+	"bytes"
+	"encoding/gob"
+	"fmt"
+	"go/ast"
+	"go/format"
+	"go/parser"
+	"go/token"
+	"go/types"
+	pathpkg "path"
+	"slices"
+	"strconv"
+
+	"golang.org/x/tools/go/ast/astutil"
+)
+
+// A Forward holds information about a constant, or a type alias,
+// whose references may be replaced by ("forwarded to") references to
+// the constant or type it denotes, such as OldConst and OldType in:
+//
+//	const OldConst = newpkg.NewConst
+//	type OldType = newpkg.NewType
+//
+// Gob-serializable.
+type Forward struct {
+	impl gobForward
+}
+
+func (fwd *Forward) String() string { return fwd.impl.Name }
+
+type gobForward struct {
+	PkgPath string // package path of declaring package
+	Name    string // user-friendly name for error messages
+	Target  object // the constant or type denoted by the declaration
+}
+
+// A Reference describes a reference to a forwarded constant or type,
+// and its enclosing context.
+//
+// The client is responsible for populating this struct and passing
+// it to InlineForward.
+type Reference struct {
+	Fset    *token.FileSet
+	Types   *types.Package
+	Info    *types.Info
+	File    *ast.File
+	Ident   *ast.Ident // the reference (or the Sel part of a qualified reference)
+	Content []byte     // source of file containing
+}
+
+// AnalyzeForward analyzes the declaration of a constant or type alias,
+// identified by its spec and declaring identifier, that is a candidate
+// for forwarding, and returns a Forward that describes it. The value
+// of the constant must be a reference to another constant of identical
+// type, and the alias must denote a named type (or a basic type), so
+// that every reference to the declared name may be replaced by a
+// reference to the target without change of meaning.
+//
+// Like a Callee, the Forward is serializable, so that it can be
+// recorded as an analysis fact.
+func AnalyzeForward(pkg *types.Package, info *types.Info, spec ast.Spec, id *ast.Ident) (*Forward, error) {
+	obj := info.Defs[id]
+	if obj == nil {
+		return nil, fmt.Errorf("internal error: no object for %s", id.Name)
+	}
+	name := fmt.Sprintf("%s.%s", pkg.Name(), id.Name)
+	if obj.Parent() != pkg.Scope() {
+		return nil, fmt.Errorf("cannot forward %s as it is not declared at package level", id.Name)
+	}
+
+	var rhs ast.Expr
+	switch spec := spec.(type) {
+	case *ast.ValueSpec:
+		if !is[*types.Const](obj) {
+			return nil, fmt.Errorf("cannot forward %s as it is not a constant", name)
+		}
+		i := slices.Index(spec.Names, id)
+		if i < 0 || i >= len(spec.Values) {
+			return nil, fmt.Errorf("cannot forward constant %s as it has no explicit value", name)
+		}
+		rhs = spec.Values[i]
+
+	case *ast.TypeSpec:
+		if !spec.Assign.IsValid() {
+			return nil, fmt.Errorf("cannot forward type %s as it is not an alias", name)
+		}
+		if spec.TypeParams != nil {
+			return nil, fmt.Errorf("cannot forward generic alias %s", name)
+		}
+		rhs = spec.Type
+
+	default:
+		return nil, fmt.Errorf("cannot forward %s as it is not a constant or type", name)
+	}
+
+	// The right-hand side must be a reference x or p.x
+	// to a package-level or built-in constant or type.
+	var target types.Object
+	switch e := ast.Unparen(rhs).(type) {
+	case *ast.Ident:
+		target = info.Uses[e]
+	case *ast.SelectorExpr:
+		if x, ok := e.X.(*ast.Ident); ok && is[*types.PkgName](info.Uses[x]) {
+			target = info.Uses[e.Sel]
+		}
+	}
+	switch target := target.(type) {
+	case *types.Const:
+		if target.Pkg() == nil && target.Name() == "iota" {
+			return nil, fmt.Errorf("cannot forward constant %s to iota", name)
+		}
+		if !types.Identical(obj.Type(), target.Type()) {
+			return nil, fmt.Errorf("cannot forward constant %s as its type %s differs from that of %s",
+				name, obj.Type(), target.Name())
+		}
+	case *types.TypeName:
+		// ok
+	default:
+		if is[*types.Const](obj) {
+			return nil, fmt.Errorf("cannot forward constant %s as its value is not a reference to another constant", name)
+		}
+		return nil, fmt.Errorf("cannot forward type %s as it is not an alias for a named type", name)
+	}
+	if target.Pkg() != nil && target.Parent() != target.Pkg().Scope() {
+		return nil, fmt.Errorf("cannot forward %s to %s as it is not declared at package level", name, target.Name())
+	}
+
+	var pkgpath, pkgname string
+	if target.Pkg() != nil {
+		pkgpath = target.Pkg().Path()
+		pkgname = target.Pkg().Name()
+	}
+	return &Forward{gobForward{
+		PkgPath: pkg.Path(),
+		Name:    name,
+		Target: object{
+			Name:     target.Name(),
+			Kind:     objectKind(target),
+			PkgPath:  pkgpath,
+			PkgName:  pkgname,
+			ValidPos: target.Pos().IsValid(),
+		},
+	}}, nil
+}
+
+// InlineForward replaces the reference to a forwarded constant or
+// type by a reference to its target, and returns the updated,
+// formatted content of the file containing the reference. Imports are
+// added and removed as needed.
+//
+// InlineForward does not mutate any public fields of Reference or
+// Forward.
+func InlineForward(ref *Reference, fwd *Forward, opts *Options) (*Result, error) {
+	logf := opts.Logf
+	if logf == nil {
+		logf = func(string, ...any) {}
+	}
+	target := &fwd.impl.Target
+	logf("forward %s @ %v to %s", fwd, ref.Fset.PositionFor(ref.Ident.Pos(), false), target.Name)
+
+	if ref.Info.Uses[ref.Ident] == nil {
+		return nil, fmt.Errorf("internal error: %s is not a reference", ref.Ident.Name)
+	}
+
+	// Find the reference, and its qualifier, if any.
+	path, _ := astutil.PathEnclosingInterval(ref.File, ref.Ident.Pos(), ref.Ident.End())
+	var (
+		old       ast.Expr        = ref.Ident
+		oldPkg    *types.PkgName  // package name of qualified reference
+		oldImport *ast.ImportSpec // import referenced only by old
+		pos       = ref.Ident.Pos()
+		lookup    = func(name string) types.Object { return lookupAt(ref.Info, path, pos, name) }
+	)
+	if sel, ok := path[1].(*ast.SelectorExpr); ok && sel.Sel == ref.Ident {
+		if x, ok := sel.X.(*ast.Ident); ok {
+			if pkgName, ok := ref.Info.Uses[x].(*types.PkgName); ok {
+				old, oldPkg = sel, pkgName
+				path = path[1:]
+				if soleUse(ref.Info, pkgName) == x {
+					for _, spec := range ref.File.Imports {
+						if pkgName2, ok := importedPkgName(ref.Info, spec); ok && pkgName2 == pkgName {
+							oldImport = spec
+						}
+					}
+				}
+			}
+		}
+	}
+
+	// Changing the type of an embedded field would change its name.
+	{
+		i := 1
+		if star, ok := path[i].(*ast.StarExpr); ok && star.X == old {
+			i++
+		}
+		if field, ok := path[i].(*ast.Field); ok && field.Names == nil && is[*ast.StructType](path[i+2]) {
+			return nil, fmt.Errorf("cannot forward %s as it is the type of an embedded field", fwd)
+		}
+	}
+
+	// Form the new reference.
+	var (
+		new       ast.Expr
+		newImport *ast.ImportSpec
+	)
+	switch {
+	case !target.ValidPos:
+		// Built-in constant or type: check not shadowed.
+		if found := lookup(target.Name); found == nil || found.Pos().IsValid() {
+			return nil, fmt.Errorf("cannot forward %s to built-in %s, which is shadowed at the reference", fwd, target.Name)
+		}
+		new = makeIdent(target.Name)
+
+	case target.PkgPath == ref.Types.Path():
+		// Same package: check not shadowed.
+		if found := lookup(target.Name); found == nil || !isPkgLevel(found) {
+			return nil, fmt.Errorf("cannot forward %s to %s %q, which is shadowed at the reference", fwd, target.Kind, target.Name)
+		}
+		new = makeIdent(target.Name)
+
+	default:
+		// Another package: form a qualified identifier.
+		if !token.IsExported(target.Name) {
+			return nil, fmt.Errorf("cannot forward %s to non-exported %s", fwd, target.Name)
+		}
+		if !canImport(ref.Types.Path(), target.PkgPath) {
+			return nil, fmt.Errorf("cannot forward %s to %s in inaccessible package %q", fwd, target.Name, target.PkgPath)
+		}
+
+		// Use an existing import, if any.
+		var name string
+		for _, spec := range ref.File.Imports {
+			if pkgName, ok := importedPkgName(ref.Info, spec); ok &&
+				pkgName.Imported().Path() == target.PkgPath &&
+				pkgName.Name() != "." && pkgName.Name() != "_" &&
+				lookup(pkgName.Name()) == pkgName {
+				name = pkgName.Name()
+				break
+			}
+		}
+		if name == "" {
+			// Add an import, choosing a name that is not in use
+			// at the reference, other than by an import that
+			// will be deleted. ("init" is not a legal name.)
+			base := target.PkgName
+			name = base
+			for n := 0; ; n++ {
+				found := lookup(name)
+				if name != "init" && (found == nil || oldImport != nil && found == oldPkg) {
+					break
+				}
+				name = fmt.Sprintf("%s%d", base, n)
+			}
+			logf("adding import %s %q", name, target.PkgPath)
+			newImport = &ast.ImportSpec{
+				Path: &ast.BasicLit{
+					Kind:  token.STRING,
+					Value: strconv.Quote(target.PkgPath),
+				},
+			}
+			if name != target.PkgName || name != pathpkg.Base(target.PkgPath) {
+				newImport.Name = makeIdent(name)
+			}
+		}
+		new = &ast.SelectorExpr{X: makeIdent(name), Sel: makeIdent(target.Name)}
+	}
+
+	// Splice the new reference in place of the old, re-parse,
+	// and update the imports.
+	fset := token.NewFileSet()
+	var f *ast.File
+	{
+		start := offsetOf(ref.Fset, old.Pos())
+		end := offsetOf(ref.Fset, old.End())
+		var out bytes.Buffer
+		out.Write(ref.Content[:start])
+		if err := format.Node(&out, fset, new); err != nil {
+			return nil, err
+		}
+		out.Write(ref.Content[end:])
+		const mode = parser.ParseComments | parser.SkipObjectResolution | parser.AllErrors
+		var err error
+		f, err = parser.ParseFile(fset, "caller.go", &out, mode)
+		if err != nil {
+			logf("failed to parse <<%s>>", &out) // debugging
+			return nil, err
+		}
+	}
+	if oldImport != nil {
+		path, _ := strconv.Unquote(oldImport.Path.Value)
+		var name string
+		if oldImport.Name != nil {
+			name = oldImport.Name.Name
+		}
+		astutil.DeleteNamedImport(fset, f, name, path)
+	}
+	if newImport != nil {
+		path, _ := strconv.Unquote(newImport.Path.Value)
+		var name string
+		if newImport.Name != nil {
+			name = newImport.Name.Name
+		}
+		astutil.AddNamedImport(fset, f, name, path)
+	}
+
+	var out bytes.Buffer
+	if err := format.Node(&out, fset, f); err != nil {
+		return nil, err
+	}
+	return &Result{Content: out.Bytes()}, nil
+}
+
+// -- serialization --
+
+var (
+	_ gob.GobEncoder = (*Forward)(nil)
+	_ gob.GobDecoder = (*Forward)(nil)
+)
+
+func (fwd *Forward) GobEncode() ([]byte, error) {
+	var out bytes.Buffer
+	if err := gob.NewEncoder(&out).Encode(fwd.impl); err != nil {
+		return nil, err
+	}
+	return out.Bytes(), nil
+}
+
+func (fwd *Forward) GobDecode(data []byte) error {
+	return gob.NewDecoder(bytes.NewReader(data)).Decode(&fwd.impl)
+}
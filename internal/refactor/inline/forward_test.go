@@ -0,0 +1,275 @@
+// Copyright 2024 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package inline_test
+
+import (
+	"bytes"
+	"encoding/gob"
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"go/types"
+	"regexp"
+	"strings"
+	"testing"
+
+	"golang.org/x/tools/internal/refactor/inline"
+)
+
+// TestForward tests the forwarding of references to constants and
+// type aliases. Each test declares Old in package oldpkg, which may
+// import newpkg. The first reference to Old, in the caller file of
+// package p or else in oldpkg itself, is forwarded.
+func TestForward(t *testing.T) {
+	const newpkg = `package newpkg
+
+const C = 1
+
+type T int
+`
+	tests := []struct {
+		descr          string
+		oldpkg, caller string // Go source files (sans package decl)
+		want           string // expected caller file, or "error: regexp"
+	}{
+		{
+			"Constant in another package.",
+			`import "example.com/newpkg"; const Old = newpkg.C`,
+			`import "example.com/oldpkg"; var x = oldpkg.Old`,
+			`package p
+
+import "example.com/newpkg"
+
+var x = newpkg.C`,
+		},
+		{
+			"Type alias; the old import is still needed.",
+			`import "example.com/newpkg"; type Old = newpkg.T; var V int`,
+			`import "example.com/oldpkg"; var x oldpkg.Old; var y = oldpkg.V`,
+			`package p
+
+import (
+	"example.com/newpkg"
+	"example.com/oldpkg"
+)
+
+var x newpkg.T
+var y = oldpkg.V`,
+		},
+		{
+			"Existing import of the target package.",
+			`import "example.com/newpkg"; type Old = newpkg.T`,
+			`import ( np "example.com/newpkg"; "example.com/oldpkg" ); var _ = np.C; var x oldpkg.Old`,
+			`package p
+
+import (
+	np "example.com/newpkg"
+)
+
+var _ = np.C
+var x np.T`,
+		},
+		{
+			"New import renamed to avoid a conflict.",
+			`import "example.com/newpkg"; type Old = newpkg.T`,
+			`import "example.com/oldpkg"; var newpkg = 1; var x oldpkg.Old`,
+			`package p
+
+import newpkg0 "example.com/newpkg"
+
+var newpkg = 1
+var x newpkg0.T`,
+		},
+		{
+			"Built-in type.",
+			`type Old = int`,
+			`import "example.com/oldpkg"; var x oldpkg.Old`,
+			`package p
+
+var x int`,
+		},
+		{
+			"Same package.",
+			`type New int; type Old = New; var x Old`,
+			``,
+			`package oldpkg
+
+type New int
+type Old = New
+
+var x New`,
+		},
+		{
+			"Same package, shadowed target.",
+			`const New = 1; const Old = New; func _() { const New = 2; _ = Old }`,
+			``,
+			`error: cannot forward oldpkg.Old to const "New", which is shadowed`,
+		},
+		{
+			"Non-exported target in another package.",
+			`const old = 1; const Old = old`,
+			`import "example.com/oldpkg"; var x = oldpkg.Old`,
+			`error: cannot forward oldpkg.Old to non-exported old`,
+		},
+		{
+			"Embedded field.",
+			`import "example.com/newpkg"; type Old = newpkg.T`,
+			`import "example.com/oldpkg"; var x struct{ *oldpkg.Old }`,
+			`error: type of an embedded field`,
+		},
+		{
+			"Not a reference to a constant.",
+			`const Old = 1`,
+			`import "example.com/oldpkg"; var x = oldpkg.Old`,
+			`error: its value is not a reference to another constant`,
+		},
+		{
+			"Constant of a different type.",
+			`import "example.com/newpkg"; const Old int = newpkg.C`,
+			`import "example.com/oldpkg"; var x = oldpkg.Old`,
+			`error: its type int differs from that of C`,
+		},
+		{
+			"Not an alias.",
+			`import "example.com/newpkg"; type Old newpkg.T`,
+			`import "example.com/oldpkg"; var x oldpkg.Old`,
+			`error: it is not an alias`,
+		},
+		{
+			"Alias for an unnamed type.",
+			`import "example.com/newpkg"; type Old = []newpkg.T`,
+			`import "example.com/oldpkg"; var x oldpkg.Old`,
+			`error: it is not an alias for a named type`,
+		},
+	}
+	for _, test := range tests {
+		test := test
+		t.Run(test.descr, func(t *testing.T) {
+			got, err := forward(t, newpkg, "package oldpkg\n"+test.oldpkg, "package p\n"+test.caller)
+
+			// Want error?
+			if rest := strings.TrimPrefix(test.want, "error: "); rest != test.want {
+				if err == nil {
+					t.Fatalf("unexpected success: want error matching %q", rest)
+				}
+				if ok, _ := regexp.MatchString(rest, err.Error()); !ok {
+					t.Fatalf("wrong error: %s (want match for %q)", err, rest)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if strings.TrimSpace(got) != strings.TrimSpace(test.want) {
+				t.Errorf("got:\n%s\nwant:\n%s", got, test.want)
+			}
+		})
+	}
+}
+
+// forward type-checks the three packages, and forwards the first
+// reference to oldpkg.Old in the caller, or, if the caller is empty,
+// in oldpkg.
+func forward(t *testing.T, newSrc, oldSrc, callerSrc string) (string, error) {
+	fset := token.NewFileSet()
+	pkgs := make(map[string]*types.Package)
+	check := func(path, src string) (*types.Package, *types.Info, *ast.File) {
+		f, err := parser.ParseFile(fset, path+".go", src, parser.ParseComments|parser.SkipObjectResolution)
+		if err != nil {
+			t.Fatalf("ParseFile: %v", err)
+		}
+		info := &types.Info{
+			Defs:       make(map[*ast.Ident]types.Object),
+			Uses:       make(map[*ast.Ident]types.Object),
+			Types:      make(map[ast.Expr]types.TypeAndValue),
+			Implicits:  make(map[ast.Node]types.Object),
+			Selections: make(map[*ast.SelectorExpr]*types.Selection),
+			Scopes:     make(map[ast.Node]*types.Scope),
+		}
+		conf := &types.Config{
+			Importer: importerFunc(func(path string) (*types.Package, error) {
+				if pkg, ok := pkgs[path]; ok {
+					return pkg, nil
+				}
+				return nil, fmt.Errorf("no package %q", path)
+			}),
+		}
+		pkg, err := conf.Check(path, fset, []*ast.File{f}, info)
+		if err != nil {
+			t.Fatalf("Check: %v", err)
+		}
+		pkgs[path] = pkg
+		return pkg, info, f
+	}
+	check("example.com/newpkg", newSrc)
+	oldPkg, oldInfo, oldFile := check("example.com/oldpkg", oldSrc)
+
+	// Analyze the declaration of Old.
+	var fwd *inline.Forward
+	for _, decl := range oldFile.Decls {
+		if decl, ok := decl.(*ast.GenDecl); ok {
+			for _, spec := range decl.Specs {
+				var names []*ast.Ident
+				switch spec := spec.(type) {
+				case *ast.ValueSpec:
+					names = spec.Names
+				case *ast.TypeSpec:
+					names = []*ast.Ident{spec.Name}
+				}
+				for _, id := range names {
+					if id.Name == "Old" {
+						var err error
+						fwd, err = inline.AnalyzeForward(oldPkg, oldInfo, spec, id)
+						if err != nil {
+							return "", err
+						}
+					}
+				}
+			}
+		}
+	}
+	if fwd == nil {
+		t.Fatalf("no declaration of Old")
+	}
+
+	// Check that gob transcoding is lossless.
+	var enc bytes.Buffer
+	if err := gob.NewEncoder(&enc).Encode(fwd); err != nil {
+		t.Fatal(err)
+	}
+	fwd = new(inline.Forward)
+	if err := gob.NewDecoder(&enc).Decode(fwd); err != nil {
+		t.Fatal(err)
+	}
+
+	pkg, info, file, content := oldPkg, oldInfo, oldFile, oldSrc
+	if callerSrc != "package p\n" {
+		pkg, info, file = check("p", callerSrc)
+		content = callerSrc
+	}
+	var ref *ast.Ident
+	ast.Inspect(file, func(n ast.Node) bool {
+		if id, ok := n.(*ast.Ident); ok && id.Name == "Old" && info.Uses[id] != nil {
+			ref = id
+		}
+		return ref == nil
+	})
+	if ref == nil {
+		t.Fatalf("no reference to Old")
+	}
+	res, err := inline.InlineForward(&inline.Reference{
+		Fset:    fset,
+		Types:   pkg,
+		Info:    info,
+		File:    file,
+		Ident:   ref,
+		Content: []byte(content),
+	}, fwd, &inline.Options{Logf: t.Logf})
+	if err != nil {
+		return "", err
+	}
+	return string(res.Content), nil
+}
@@ -79,6 +79,52 @@ func Inline(caller *Caller, callee *Callee, opts *Options) (*Result, error) {
 	return st.inline()
 }
 
+// ConcreteCallee returns the function or concrete method called by
+// call, if it can be determined statically, or nil otherwise.
+//
+// In addition to the calls whose callee is reported by
+// typeutil.StaticCallee, it handles calls to interface methods whose
+// receiver's dynamic type is statically known, namely conversions
+// I(x).f() of a value x of concrete type to an interface type I.
+// Inline accepts all such calls.
+func ConcreteCallee(info *types.Info, call *ast.CallExpr) *types.Func {
+	if fn := typeutil.StaticCallee(info, call); fn != nil {
+		return fn
+	}
+	_, method := devirtualize(info, call)
+	return method
+}
+
+// devirtualize returns the operand x and the concrete method of the
+// dynamic call I(x).f(), where x is not an interface, or nil if the
+// call is not of this form.
+func devirtualize(info *types.Info, call *ast.CallExpr) (x ast.Expr, method *types.Func) {
+	sel, ok := ast.Unparen(call.Fun).(*ast.SelectorExpr)
+	if !ok {
+		return nil, nil
+	}
+	seln, ok := info.Selections[sel]
+	if !ok || seln.Kind() != types.MethodVal || !types.IsInterface(seln.Recv()) {
+		return nil, nil
+	}
+	conv, ok := ast.Unparen(sel.X).(*ast.CallExpr)
+	if !ok || len(conv.Args) != 1 || !info.Types[conv.Fun].IsType() {
+		return nil, nil
+	}
+	x = conv.Args[0]
+	t := info.TypeOf(x)
+	if t == nil || types.IsInterface(t) {
+		return nil, nil // e.g. nil, or a conversion between interfaces
+	}
+	fn := seln.Obj().(*types.Func)
+	obj, _, _ := types.LookupFieldOrMethod(t, false, fn.Pkg(), fn.Name())
+	method, ok = obj.(*types.Func)
+	if !ok || types.IsInterface(method.Type().(*types.Signature).Recv().Type()) {
+		return nil, nil // e.g. a method promoted from an embedded interface
+	}
+	return x, method
+}
+
 // state holds the working state of the inliner.
 type state struct {
 	caller *Caller
@@ -428,7 +474,9 @@ func (st *state) inlineCall() (*inlineCallResult, error) {
 
 	// Inlining of dynamic calls is not currently supported,
 	// even for local closure calls. (This would be a lot of work.)
-	calleeSymbol := typeutil.StaticCallee(caller.Info, caller.Call)
+	// The exception is calls I(x).f() of interface methods,
+	// whose receiver's dynamic type is that of x.
+	calleeSymbol := ConcreteCallee(caller.Info, caller.Call)
 	if calleeSymbol == nil {
 		// e.g. interface method
 		return nil, fmt.Errorf("cannot inline: not a static function call")
@@ -1266,10 +1314,21 @@ func (st *state) arguments(caller *Caller, calleeDecl *ast.FuncDecl, assign1 fun
 	if calleeDecl.Recv != nil {
 		sel := ast.Unparen(caller.Call.Fun).(*ast.SelectorExpr)
 		seln := caller.Info.Selections[sel]
-		var recvArg ast.Expr
+		var (
+			recvArg ast.Expr
+			indices = seln.Index()
+			method  = seln.Obj()
+		)
 		switch seln.Kind() {
 		case types.MethodVal: // recv.f(callArgs)
 			recvArg = sel.X
+			if x, concrete := devirtualize(caller.Info, caller.Call); concrete != nil {
+				// I(x).f(callArgs): the receiver is x,
+				// and the selection is that of x.f.
+				recvArg = x
+				_, indices, _ = types.LookupFieldOrMethod(caller.Info.TypeOf(x), false, concrete.Pkg(), concrete.Name())
+				method = concrete
+			}
 		case types.MethodExpr: // T.f(recv, callArgs)
 			recvArg = callArgs[0]
 			callArgs = callArgs[1:]
@@ -1294,7 +1353,6 @@ func (st *state) arguments(caller *Caller, calleeDecl *ast.FuncDecl, assign1 fun
 
 			// Make field selections explicit (recv.f -> recv.y.f),
 			// updating arg.{expr,typ}.
-			indices := seln.Index()
 			for _, index := range indices[:len(indices)-1] {
 				fld := typeparams.CoreType(typeparams.Deref(arg.typ)).(*types.Struct).Field(index)
 				if fld.Pkg() != caller.Types && !fld.Exported() {
@@ -1315,7 +1373,7 @@ func (st *state) arguments(caller *Caller, calleeDecl *ast.FuncDecl, assign1 fun
 
 			// Make * or & explicit.
 			argIsPtr := isPointer(arg.typ)
-			paramIsPtr := isPointer(seln.Obj().Type().Underlying().(*types.Signature).Recv().Type())
+			paramIsPtr := isPointer(method.Type().Underlying().(*types.Signature).Recv().Type())
 			if !argIsPtr && paramIsPtr {
 				// &recv
 				arg.expr = &ast.UnaryExpr{Op: token.AND, X: arg.expr}
@@ -1963,9 +2021,14 @@ func createBindingDecl(logf func(string, ...any), caller *Caller, args []*argume
 
 // lookup does a symbol lookup in the lexical environment of the caller.
 func (caller *Caller) lookup(name string) types.Object {
-	pos := caller.Call.Pos()
-	for _, n := range caller.path {
-		if scope := scopeFor(caller.Info, n); scope != nil {
+	return lookupAt(caller.Info, caller.path, caller.Call.Pos(), name)
+}
+
+// lookupAt does a symbol lookup in the lexical environment at pos,
+// whose enclosing syntax is given by path, innermost first.
+func lookupAt(info *types.Info, path []ast.Node, pos token.Pos, name string) types.Object {
+	for _, n := range path {
+		if scope := scopeFor(info, n); scope != nil {
 			if _, obj := scope.LookupParent(name, pos); obj != nil {
 				return obj
 			}
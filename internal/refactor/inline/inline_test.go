@@ -25,7 +25,6 @@ import (
 	"golang.org/x/tools/go/ast/astutil"
 	"golang.org/x/tools/go/expect"
 	"golang.org/x/tools/go/packages"
-	"golang.org/x/tools/go/types/typeutil"
 	"golang.org/x/tools/internal/diff"
 	"golang.org/x/tools/internal/refactor/inline"
 	"golang.org/x/tools/internal/testenv"
@@ -202,8 +201,9 @@ func doInlineNote(logf func(string, ...any), pkg *packages.Package, file *ast.Fi
 		}
 	}
 
-	// Is it a static function call?
-	fn := typeutil.StaticCallee(caller.Info, caller.Call)
+	// Is it a static function call,
+	// or one with a statically known receiver type?
+	fn := inline.ConcreteCallee(caller.Info, caller.Call)
 	if fn == nil {
 		return fmt.Errorf("cannot inline: not a static call")
 	}
@@ -1494,6 +1494,29 @@ func TestRedundantConversions(t *testing.T) {
 	})
 }
 
+func TestDevirtualizedCalls(t *testing.T) {
+	runTests(t, []testcase{
+		{
+			"Dynamic call through a conversion of a concrete value.",
+			`type I interface{ f() int }; type T int; func (t T) f() int { return int(t) }`,
+			`func _(x T) int { return I(x).f() }`,
+			`func _(x T) int { return int(x) }`,
+		},
+		{
+			"Dynamic call through a conversion of a pointer to a value receiver.",
+			`type I interface{ f() int }; type T int; func (t T) f() int { return int(t) }`,
+			`func _(x *T) int { return I(x).f() }`,
+			`func _(x *T) int { return int(*x) }`,
+		},
+		{
+			"Dynamic call through a conversion of an interface.",
+			`type I interface{ f() int }; type T int; func (t T) f() int { return int(t) }`,
+			`func _(x I) int { return I(x).f() }`,
+			`error: not a static function call`,
+		},
+	})
+}
+
 func runTests(t *testing.T, tests []testcase) {
 	for _, test := range tests {
 		test := test
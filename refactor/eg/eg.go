@@ -15,10 +15,12 @@ import (
 	"go/token"
 	"go/types"
 	"os"
+	"strings"
 )
 
 const Help = `
-This tool implements example-based refactoring of expressions.
+This tool implements example-based refactoring of expressions and
+statements.
 
 The transformation is specified as a Go file defining two functions,
 'before' and 'after', of identical types.  Each function body consists
@@ -63,6 +65,56 @@ Matching of type syntax is semantic, not syntactic: type syntax in the
 pattern matches type syntax in the input if the types are identical.
 Thus, func(x int) matches func(y int).
 
+A wildcard whose type is a type parameter of a generic 'before'
+function matches any expression whose type satisfies the constraint of
+the type parameter.  All wildcards of the same type parameter must
+match expressions of identical types.  For example, this rule applies
+to operands of every integer type:
+
+	type integer interface{ ~int | ~int8 | ~int16 | ~int32 | ~int64 }
+	func before[T integer](x T) T { return x + x }
+	func after[T integer](x T) T  { return 2 * x }
+
+Type parameters may not be used in the bodies of the functions.
+
+A template may define several rules, each a pair of functions named
+before_N and after_N for some suffix N, in addition to or instead of
+the 'before' and 'after' pair.  The rules are tried in the order of
+their 'before' functions in the template, and the first that matches
+an expression is applied.
+
+A 'before' function whose body is not a single return or expression
+statement defines a statement pattern: its body matches any sequence
+of consecutive statements of a block, which is replaced by the body
+of the 'after' function, possibly empty.  A variable declared by the
+pattern matches any variable of identical type, and must consistently
+match the same variable; the replacement may refer to it by the same
+name.  Trailing statements that only satisfy the type checker, namely
+assignments to the blank identifier and a final call to panic, are not
+part of the pattern or its replacement.  For example, this rule adds
+context to the errors of strconv.Atoi:
+
+	func before(s string) (int, error) {
+		n, err := strconv.Atoi(s)
+		if err != nil {
+			return 0, err
+		}
+		_ = n
+		panic(0)
+	}
+	func after(s string) (int, error) {
+		n, err := strconv.Atoi(s)
+		if err != nil {
+			return 0, fmt.Errorf("parsing %q: %v", s, err)
+		}
+		_ = n
+		panic(0)
+	}
+
+The results of the functions serve only to make the return statements
+of the pattern well typed; they match returns of the same expressions
+in functions of any type.
+
 This tool was inspired by other example-based refactoring tools,
 'gofmt -r' for Go and Refaster for Java.
 
@@ -72,16 +124,16 @@ LIMITATIONS
 
 EXPRESSIVENESS
 
-Only refactorings that replace one expression with another, regardless
-of the expression's context, may be expressed.  Refactoring arbitrary
-statements (or sequences of statements) is a less well-defined problem
-and is less amenable to this approach.
+Only refactorings that replace one expression with another, or one
+sequence of statements with another, regardless of their context, may
+be expressed.  Statement patterns match only simple statements, blocks
+and if statements; loops, switch statements and labeled statements in
+a pattern never match.
 
-A pattern that contains a function literal (and hence statements)
-never matches.
+A pattern that contains a function literal never matches.
 
-There is no way to generalize over related types, e.g. to express that
-a wildcard may have any integer type, for example.
+A type parameter generalizes only over the type of a wildcard: a
+wildcard of type []T, for example, never matches.
 
 It is not possible to replace an expression by one of a different
 type, even in contexts where this is legal, such as x in fmt.Print(x).
@@ -141,37 +193,59 @@ type Transformer struct {
 	verbose        bool
 	info           *types.Info // combined type info for template/input/output ASTs
 	seenInfos      map[*types.Info]bool
-	wildcards      map[*types.Var]bool                // set of parameters in func before()
-	env            map[string]ast.Expr                // maps parameter name to wildcard binding
-	importedObjs   map[types.Object]*ast.SelectorExpr // objects imported by after().
-	before, after  ast.Expr
-	afterStmts     []ast.Stmt
+	rules          []*rule                         // in order of appearance in the template
+	env            map[string]ast.Expr             // maps parameter name to wildcard binding
+	targs          map[*types.TypeParam]types.Type // maps type parameter to wildcard type
 	allowWildcards bool
 
 	// Working state of Transform():
+	*rule                     // current rule
 	nsubsts    int            // number of substitutions made
+	applied    map[*rule]bool // rules that made substitutions
+	matches    []Match        // substitutions made
 	currentPkg *types.Package // package of current call
 }
 
+// A rule is a pair of before and after functions of a template.
+//
+// An expression rule replaces expressions that match before by after,
+// inserting afterStmts before the enclosing statement. A statement
+// rule replaces sequences of statements that match beforeStmts by
+// afterStmts.
+type rule struct {
+	name          string                             // name of the before function
+	wildcards     map[*types.Var]bool                // set of parameters in func before()
+	locals        map[*types.Var]bool                // set of variables declared by beforeStmts
+	importedObjs  map[types.Object]*ast.SelectorExpr // objects imported by after().
+	before, after ast.Expr                           // nil for a statement rule
+	beforeStmts   []ast.Stmt                         // nil for an expression rule
+	afterStmts    []ast.Stmt
+}
+
 // NewTransformer returns a transformer based on the specified template,
-// a single-file package containing "before" and "after" functions as
-// described in the package documentation.
+// a single-file package containing "before" and "after" functions, or
+// pairs of "before_N" and "after_N" functions, as described in the
+// package documentation.
 // tmplInfo is the type information for tmplFile.
 func NewTransformer(fset *token.FileSet, tmplPkg *types.Package, tmplFile *ast.File, tmplInfo *types.Info, verbose bool) (*Transformer, error) {
 	// Check the template.
-	beforeSig := funcSig(tmplPkg, "before")
-	if beforeSig == nil {
-		return nil, fmt.Errorf("no 'before' func found in template")
+	funcs := make(map[string]*ast.FuncDecl)
+	var befores []*ast.FuncDecl
+	for _, decl := range tmplFile.Decls {
+		if decl, ok := decl.(*ast.FuncDecl); ok && decl.Recv == nil {
+			funcs[decl.Name.Name] = decl
+			if _, ok := ruleSuffix(decl.Name.Name, "before"); ok {
+				befores = append(befores, decl)
+			}
+		}
 	}
-	afterSig := funcSig(tmplPkg, "after")
-	if afterSig == nil {
-		return nil, fmt.Errorf("no 'after' func found in template")
+	if len(befores) == 0 {
+		return nil, fmt.Errorf("no 'before' func found in template")
 	}
-
-	// TODO(adonovan): should we also check the names of the params match?
-	if !types.Identical(afterSig, beforeSig) {
-		return nil, fmt.Errorf("before %s and after %s functions have different signatures",
-			beforeSig, afterSig)
+	for name := range funcs {
+		if suffix, ok := ruleSuffix(name, "after"); ok && funcs["before"+suffix] == nil {
+			return nil, fmt.Errorf("no 'before%s' func found in template", suffix)
+		}
 	}
 
 	for _, imp := range tmplFile.Imports {
@@ -182,67 +256,12 @@ func NewTransformer(fset *token.FileSet, tmplPkg *types.Package, tmplFile *ast.F
 			return nil, fmt.Errorf("dot-import (of %s) in template", imp.Path.Value)
 		}
 	}
-	var beforeDecl, afterDecl *ast.FuncDecl
-	for _, decl := range tmplFile.Decls {
-		if decl, ok := decl.(*ast.FuncDecl); ok {
-			switch decl.Name.Name {
-			case "before":
-				beforeDecl = decl
-			case "after":
-				afterDecl = decl
-			}
-		}
-	}
-
-	before, err := soleExpr(beforeDecl)
-	if err != nil {
-		return nil, fmt.Errorf("before: %s", err)
-	}
-	afterStmts, after, err := stmtAndExpr(afterDecl)
-	if err != nil {
-		return nil, fmt.Errorf("after: %s", err)
-	}
-
-	wildcards := make(map[*types.Var]bool)
-	for i := 0; i < beforeSig.Params().Len(); i++ {
-		wildcards[beforeSig.Params().At(i)] = true
-	}
-
-	// checkExprTypes returns an error if Tb (type of before()) is not
-	// safe to replace with Ta (type of after()).
-	//
-	// Only superficial checks are performed, and they may result in both
-	// false positives and negatives.
-	//
-	// Ideally, we would only require that the replacement be assignable
-	// to the context of a specific pattern occurrence, but the type
-	// checker doesn't record that information and it's complex to deduce.
-	// A Go type cannot capture all the constraints of a given expression
-	// context, which may include the size, constness, signedness,
-	// namedness or constructor of its type, and even the specific value
-	// of the replacement.  (Consider the rule that array literal keys
-	// must be unique.)  So we cannot hope to prove the safety of a
-	// transformation in general.
-	Tb := tmplInfo.TypeOf(before)
-	Ta := tmplInfo.TypeOf(after)
-	if types.AssignableTo(Tb, Ta) {
-		// safe: replacement is assignable to pattern.
-	} else if tuple, ok := Tb.(*types.Tuple); ok && tuple.Len() == 0 {
-		// safe: pattern has void type (must appear in an ExprStmt).
-	} else {
-		return nil, fmt.Errorf("%s is not a safe replacement for %s", Ta, Tb)
-	}
 
 	tr := &Transformer{
 		fset:           fset,
 		verbose:        verbose,
-		wildcards:      wildcards,
 		allowWildcards: true,
 		seenInfos:      make(map[*types.Info]bool),
-		importedObjs:   make(map[types.Object]*ast.SelectorExpr),
-		before:         before,
-		after:          after,
-		afterStmts:     afterStmts,
 	}
 
 	// Combine type info from the template and input packages, and
@@ -257,21 +276,125 @@ func NewTransformer(fset *token.FileSet, tmplPkg *types.Package, tmplFile *ast.F
 	}
 	mergeTypeInfo(tr.info, tmplInfo)
 
+	for _, beforeDecl := range befores {
+		suffix, _ := ruleSuffix(beforeDecl.Name.Name, "before")
+		afterDecl := funcs["after"+suffix]
+		if afterDecl == nil {
+			return nil, fmt.Errorf("no 'after%s' func found in template", suffix)
+		}
+		r, err := newRule(tmplInfo, beforeDecl, afterDecl)
+		if err != nil {
+			return nil, err
+		}
+		tr.rules = append(tr.rules, r)
+	}
+	return tr, nil
+}
+
+// newRule returns the rule defined by the before and after functions
+// of a template with type information tmplInfo.
+func newRule(tmplInfo *types.Info, beforeDecl, afterDecl *ast.FuncDecl) (*rule, error) {
+	beforeName, afterName := beforeDecl.Name.Name, afterDecl.Name.Name
+	beforeSig := tmplInfo.Defs[beforeDecl.Name].Type().(*types.Signature)
+	afterSig := tmplInfo.Defs[afterDecl.Name].Type().(*types.Signature)
+
+	// TODO(adonovan): should we also check the names of the params match?
+	if !types.Identical(afterSig, beforeSig) {
+		return nil, fmt.Errorf("%s %s and %s %s functions have different signatures",
+			beforeName, beforeSig, afterName, afterSig)
+	}
+	for _, decl := range []*ast.FuncDecl{beforeDecl, afterDecl} {
+		if err := checkNoTypeParams(tmplInfo, decl); err != nil {
+			return nil, fmt.Errorf("%s: %s", decl.Name.Name, err)
+		}
+	}
+
+	r := &rule{
+		name:         beforeName,
+		wildcards:    make(map[*types.Var]bool),
+		importedObjs: make(map[types.Object]*ast.SelectorExpr),
+	}
+	for i := 0; i < beforeSig.Params().Len(); i++ {
+		r.wildcards[beforeSig.Params().At(i)] = true
+	}
+
+	if before, err := soleExpr(beforeDecl); err == nil {
+		// An expression rule.
+		afterStmts, after, err := stmtAndExpr(afterDecl)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %s", afterName, err)
+		}
+
+		// checkExprTypes returns an error if Tb (type of before()) is not
+		// safe to replace with Ta (type of after()).
+		//
+		// Only superficial checks are performed, and they may result in both
+		// false positives and negatives.
+		//
+		// Ideally, we would only require that the replacement be assignable
+		// to the context of a specific pattern occurrence, but the type
+		// checker doesn't record that information and it's complex to deduce.
+		// A Go type cannot capture all the constraints of a given expression
+		// context, which may include the size, constness, signedness,
+		// namedness or constructor of its type, and even the specific value
+		// of the replacement.  (Consider the rule that array literal keys
+		// must be unique.)  So we cannot hope to prove the safety of a
+		// transformation in general.
+		Tb := tmplInfo.TypeOf(before)
+		Ta := tmplInfo.TypeOf(after)
+		if types.AssignableTo(Tb, Ta) {
+			// safe: replacement is assignable to pattern.
+		} else if sameTypeParam(Tb, Ta) {
+			// safe: both have the same type parameter of a generic rule.
+		} else if tuple, ok := Tb.(*types.Tuple); ok && tuple.Len() == 0 {
+			// safe: pattern has void type (must appear in an ExprStmt).
+		} else {
+			return nil, fmt.Errorf("%s is not a safe replacement for %s", Ta, Tb)
+		}
+		r.before, r.after, r.afterStmts = before, after, afterStmts
+	} else {
+		// A statement rule.
+		if beforeDecl.Body == nil {
+			return nil, fmt.Errorf("%s: no body", beforeName)
+		}
+		if afterDecl.Body == nil {
+			return nil, fmt.Errorf("%s: no body", afterName)
+		}
+		r.beforeStmts = trimStmts(beforeDecl.Body.List)
+		if len(r.beforeStmts) == 0 {
+			return nil, fmt.Errorf("%s: must contain at least one statement", beforeName)
+		}
+		r.afterStmts = trimStmts(afterDecl.Body.List)
+
+		r.locals = make(map[*types.Var]bool)
+		for _, stmt := range r.beforeStmts {
+			ast.Inspect(stmt, func(n ast.Node) bool {
+				if id, ok := n.(*ast.Ident); ok {
+					if v, ok := tmplInfo.Defs[id].(*types.Var); ok && !v.IsField() {
+						r.locals[v] = true
+					}
+				}
+				return true
+			})
+		}
+	}
+
 	// Compute set of imported objects required by after().
 	// TODO(adonovan): reject dot-imports in pattern
-	ast.Inspect(after, func(n ast.Node) bool {
+	ast.Inspect(afterDecl.Body, func(n ast.Node) bool {
 		if n, ok := n.(*ast.SelectorExpr); ok {
-			if _, ok := tr.info.Selections[n]; !ok {
+			if _, ok := tmplInfo.Selections[n]; !ok {
 				// qualified ident
-				obj := tr.info.Uses[n.Sel]
-				tr.importedObjs[obj] = n
+				if obj, ok := tmplInfo.Uses[n.Sel]; ok && obj.Pkg() != nil {
+					r.importedObjs[obj] = n
+				}
 				return false // prune
 			}
 		}
 		return true // recur
 	})
 
-	return tr, nil
+	return r, nil
 }
 
 // WriteAST is a convenience function that writes AST f to the specified file.
@@ -291,12 +414,74 @@ func WriteAST(fset *token.FileSet, filename string, f *ast.File) (err error) {
 
 // -- utilities --------------------------------------------------------
 
-// funcSig returns the signature of the specified package-level function.
-func funcSig(pkg *types.Package, name string) *types.Signature {
-	if f, ok := pkg.Scope().Lookup(name).(*types.Func); ok {
-		return f.Type().(*types.Signature)
+// ruleSuffix reports whether name is that of a template function of
+// the given kind ("before" or "after"), and if so returns the suffix
+// that identifies its rule: "" or "_N".
+func ruleSuffix(name, kind string) (string, bool) {
+	suffix, ok := strings.CutPrefix(name, kind)
+	if !ok || suffix != "" && (len(suffix) < 2 || suffix[0] != '_') {
+		return "", false
 	}
-	return nil
+	return suffix, true
+}
+
+// sameTypeParam reports whether x and y are corresponding type
+// parameters of the before and after functions of a generic rule.
+func sameTypeParam(x, y types.Type) bool {
+	xp, ok := x.(*types.TypeParam)
+	if !ok {
+		return false
+	}
+	yp, ok := y.(*types.TypeParam)
+	return ok && xp.Index() == yp.Index()
+}
+
+// checkNoTypeParams returns an error if the body of fn refers to a
+// type parameter.
+func checkNoTypeParams(info *types.Info, fn *ast.FuncDecl) error {
+	var err error
+	ast.Inspect(fn.Body, func(n ast.Node) bool {
+		if id, ok := n.(*ast.Ident); ok && err == nil {
+			if tname, ok := info.Uses[id].(*types.TypeName); ok {
+				if _, ok := tname.Type().(*types.TypeParam); ok {
+					err = fmt.Errorf("type parameter %s used in body", id.Name)
+				}
+			}
+		}
+		return err == nil
+	})
+	return err
+}
+
+// trimStmts returns stmts without its trailing assignments to the
+// blank identifier and final call to panic, which serve only to
+// satisfy the type checker in a statement rule.
+func trimStmts(stmts []ast.Stmt) []ast.Stmt {
+	for len(stmts) > 0 {
+		switch stmt := stmts[len(stmts)-1].(type) {
+		case *ast.AssignStmt:
+			if stmt.Tok != token.ASSIGN {
+				return stmts
+			}
+			for _, lhs := range stmt.Lhs {
+				if id, ok := lhs.(*ast.Ident); !ok || id.Name != "_" {
+					return stmts
+				}
+			}
+		case *ast.ExprStmt:
+			call, ok := stmt.X.(*ast.CallExpr)
+			if !ok {
+				return stmts
+			}
+			if id, ok := call.Fun.(*ast.Ident); !ok || id.Name != "panic" {
+				return stmts
+			}
+		default:
+			return stmts
+		}
+		stmts = stmts[:len(stmts)-1]
+	}
+	return stmts
 }
 
 // soleExpr returns the sole expression in the before/after template function.
@@ -364,7 +549,16 @@ func mergeTypeInfo(dst, src *types.Info) {
 	}
 }
 
-// (debugging only)
+// stmtsString returns the formatted syntax of stmts, one per line.
+func stmtsString(fset *token.FileSet, stmts []ast.Stmt) string {
+	var lines []string
+	for _, stmt := range stmts {
+		lines = append(lines, astString(fset, stmt))
+	}
+	return strings.Join(lines, "\n")
+}
+
+// astString returns the formatted syntax of n.
 func astString(fset *token.FileSet, n ast.Node) string {
 	var buf bytes.Buffer
 	printer.Fprint(&buf, fset, n)
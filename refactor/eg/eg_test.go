@@ -64,8 +64,12 @@ func Test(t *testing.T) {
 		"testdata/h.txtar",
 		"testdata/i.txtar",
 		"testdata/j.txtar",
+		"testdata/multi.txtar",
+		"testdata/stmts.txtar",
+		"testdata/typed.txtar",
 		"testdata/bad_type.txtar",
 		"testdata/no_before.txtar",
+		"testdata/no_after_n.txtar",
 		"testdata/no_after_return.txtar",
 		"testdata/type_mismatch.txtar",
 		"testdata/expr_type_mismatch.txtar",
@@ -125,6 +129,9 @@ func Test(t *testing.T) {
 					if n == 0 {
 						t.Fatalf("%s: no replacements", filename)
 					}
+					if got := len(xform.Matches()); got != n {
+						t.Errorf("%s: got %d matches, want %d", filename, got, n)
+					}
 					var got []byte
 					{
 						var out bytes.Buffer
@@ -42,6 +42,11 @@ func (tr *Transformer) matchExpr(x, y ast.Expr) bool {
 		return tr.matchWildcard(xobj, y)
 	}
 
+	// Is x a variable declared by a statement pattern?
+	if xobj, ok := tr.localObj(x); ok {
+		return tr.matchLocal(xobj, y)
+	}
+
 	// Object identifiers (including pkg-qualified ones)
 	// are handled semantically, not syntactically.
 	xobj := isRef(x, tr.info)
@@ -62,6 +67,9 @@ func (tr *Transformer) matchExpr(x, y ast.Expr) bool {
 	}
 	switch x := x.(type) {
 	case *ast.Ident:
+		if x.Name == "_" {
+			return y.(*ast.Ident).Name == "_"
+		}
 		log.Fatalf("unexpected Ident: %s", astString(tr.fset, x))
 
 	case *ast.BasicLit:
@@ -171,6 +179,13 @@ func (tr *Transformer) matchSelectorExpr(x, y *ast.SelectorExpr) bool {
 		yt := tr.info.TypeOf(y.X)
 		o, _, _ := types.LookupFieldOrMethod(yt, true, tr.currentPkg, field)
 		if o != nil {
+			if old, ok := tr.env[xobj.Name()]; ok {
+				// found existing binding
+				tr.allowWildcards = false
+				r := tr.matchExpr(old, y.X)
+				tr.allowWildcards = true
+				return r
+			}
 			tr.env[xobj.Name()] = y.X // record binding
 			return true
 		}
@@ -197,7 +212,14 @@ func (tr *Transformer) matchWildcard(xobj *types.Var, y ast.Expr) bool {
 		// the difference between T{v} and T{k:v} for structs.
 		return false
 	}
-	if !types.AssignableTo(yt, xobj.Type()) {
+	if tparam, ok := xobj.Type().(*types.TypeParam); ok {
+		if !tr.matchTypeParam(tparam, yt) {
+			if tr.verbose {
+				fmt.Fprintf(os.Stderr, "%s does not match %s\n", yt, tparam)
+			}
+			return false
+		}
+	} else if !types.AssignableTo(yt, xobj.Type()) {
 		if tr.verbose {
 			fmt.Fprintf(os.Stderr, "%s not assignable to %s\n", yt, xobj.Type())
 		}
@@ -227,6 +249,166 @@ func (tr *Transformer) matchWildcard(xobj *types.Var, y ast.Expr) bool {
 	return true
 }
 
+// matchTypeParam reports whether the type t of an expression matched
+// by a wildcard whose type is the type parameter tparam satisfies its
+// constraint, and binds tparam to t.  A type parameter must be bound
+// to identical types by all the wildcards of the pattern.
+func (tr *Transformer) matchTypeParam(tparam *types.TypeParam, t types.Type) bool {
+	t = types.Default(t)
+	if old, ok := tr.targs[tparam]; ok {
+		return types.Identical(old, t)
+	}
+	iface, ok := tparam.Constraint().Underlying().(*types.Interface)
+	if !ok || !types.Satisfies(t, iface) {
+		return false
+	}
+	tr.targs[tparam] = t // record binding
+	return true
+}
+
+func (tr *Transformer) localObj(x ast.Expr) (*types.Var, bool) {
+	if x, ok := x.(*ast.Ident); ok && tr.allowWildcards && tr.locals != nil {
+		if xobj, ok := objectOf(x, tr.info).(*types.Var); ok && tr.locals[xobj] {
+			return xobj, true
+		}
+	}
+	return nil, false
+}
+
+// matchLocal reports whether y is an identifier for a variable of the
+// same type as the variable xobj declared by a statement pattern.
+// A variable appearing more than once in the pattern must
+// consistently match the same variable.
+func (tr *Transformer) matchLocal(xobj *types.Var, y ast.Expr) bool {
+	id, ok := y.(*ast.Ident)
+	if !ok {
+		return false
+	}
+	yobj, ok := objectOf(id, tr.info).(*types.Var)
+	if !ok || !types.Identical(xobj.Type(), yobj.Type()) {
+		return false
+	}
+	if old, ok := tr.env[xobj.Name()]; ok {
+		old, ok := old.(*ast.Ident)
+		return ok && objectOf(old, tr.info) == yobj
+	}
+	tr.env[xobj.Name()] = id // record binding
+	return true
+}
+
+// matchStmt reports whether statement pattern x matches y.
+// Only simple statements, blocks and if statements are matched.
+func (tr *Transformer) matchStmt(x, y ast.Stmt) bool {
+	if x == nil && y == nil {
+		return true
+	}
+	if x == nil || y == nil {
+		return false
+	}
+	if reflect.TypeOf(x) != reflect.TypeOf(y) {
+		return false
+	}
+	switch x := x.(type) {
+	case *ast.ExprStmt:
+		y := y.(*ast.ExprStmt)
+		return tr.matchExpr(x.X, y.X)
+
+	case *ast.AssignStmt:
+		y := y.(*ast.AssignStmt)
+		return x.Tok == y.Tok &&
+			tr.matchExprs(x.Lhs, y.Lhs) &&
+			tr.matchExprs(x.Rhs, y.Rhs)
+
+	case *ast.IncDecStmt:
+		y := y.(*ast.IncDecStmt)
+		return x.Tok == y.Tok &&
+			tr.matchExpr(x.X, y.X)
+
+	case *ast.SendStmt:
+		y := y.(*ast.SendStmt)
+		return tr.matchExpr(x.Chan, y.Chan) &&
+			tr.matchExpr(x.Value, y.Value)
+
+	case *ast.DeclStmt:
+		y := y.(*ast.DeclStmt)
+		return tr.matchVarDecl(x.Decl, y.Decl)
+
+	case *ast.DeferStmt:
+		y := y.(*ast.DeferStmt)
+		return tr.matchExpr(x.Call, y.Call)
+
+	case *ast.GoStmt:
+		y := y.(*ast.GoStmt)
+		return tr.matchExpr(x.Call, y.Call)
+
+	case *ast.ReturnStmt:
+		y := y.(*ast.ReturnStmt)
+		return tr.matchExprs(x.Results, y.Results)
+
+	case *ast.BranchStmt:
+		y := y.(*ast.BranchStmt)
+		return x.Tok == y.Tok && x.Label == nil && y.Label == nil
+
+	case *ast.BlockStmt:
+		y := y.(*ast.BlockStmt)
+		return tr.matchStmts(x.List, y.List)
+
+	case *ast.IfStmt:
+		y := y.(*ast.IfStmt)
+		return tr.matchStmt(x.Init, y.Init) &&
+			tr.matchExpr(x.Cond, y.Cond) &&
+			tr.matchStmt(x.Body, y.Body) &&
+			tr.matchStmt(x.Else, y.Else)
+
+	case *ast.EmptyStmt:
+		return true
+	}
+
+	// Loops, switches, selects and labeled statements never match.
+	return false
+}
+
+func (tr *Transformer) matchStmts(xx, yy []ast.Stmt) bool {
+	if len(xx) != len(yy) {
+		return false
+	}
+	for i := range xx {
+		if !tr.matchStmt(xx[i], yy[i]) {
+			return false
+		}
+	}
+	return true
+}
+
+// matchVarDecl reports whether the var declaration x matches y.
+// Other declarations never match.
+func (tr *Transformer) matchVarDecl(x, y ast.Decl) bool {
+	xd, ok := x.(*ast.GenDecl)
+	if !ok || xd.Tok != token.VAR {
+		return false
+	}
+	yd, ok := y.(*ast.GenDecl)
+	if !ok || yd.Tok != token.VAR || len(xd.Specs) != len(yd.Specs) {
+		return false
+	}
+	for i := range xd.Specs {
+		xs := xd.Specs[i].(*ast.ValueSpec)
+		ys := yd.Specs[i].(*ast.ValueSpec)
+		if len(xs.Names) != len(ys.Names) ||
+			(xs.Type == nil) != (ys.Type == nil) ||
+			xs.Type != nil && !tr.matchType(xs.Type, ys.Type) ||
+			!tr.matchExprs(xs.Values, ys.Values) {
+			return false
+		}
+		for j := range xs.Names {
+			if !tr.matchExpr(xs.Names[j], ys.Names[j]) {
+				return false
+			}
+		}
+	}
+	return true
+}
+
 // -- utilities --------------------------------------------------------
 
 func unparen(e ast.Expr) ast.Expr { return astutil.Unparen(e) }
@@ -246,3 +428,11 @@ func isRef(n ast.Node, info *types.Info) types.Object {
 	}
 	return nil
 }
+
+// objectOf returns the object defined or referred to by id.
+func objectOf(id *ast.Ident, info *types.Info) types.Object {
+	if obj, ok := info.Defs[id]; ok {
+		return obj
+	}
+	return info.Uses[id]
+}
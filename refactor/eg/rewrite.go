@@ -22,50 +22,192 @@ import (
 	"golang.org/x/tools/go/ast/astutil"
 )
 
+// A substitution records a replacement made by an expression rule, so
+// that the statements preceding its after expression can be inserted
+// before the enclosing statement.
+type substitution struct {
+	rule *rule
+	env  map[string]ast.Expr // wildcard bindings
+}
+
 // transformItem takes a reflect.Value representing a variable of type ast.Node
 // transforms its child elements recursively with apply, and then transforms the
 // actual element if it contains an expression.
-func (tr *Transformer) transformItem(rv reflect.Value) (reflect.Value, bool, map[string]ast.Expr) {
+func (tr *Transformer) transformItem(rv reflect.Value) (reflect.Value, bool, *substitution) {
 	// don't bother if val is invalid to start with
 	if !rv.IsValid() {
 		return reflect.Value{}, false, nil
 	}
 
-	rv, changed, newEnv := tr.apply(tr.transformItem, rv)
+	rv, changed, newSubst := tr.apply(tr.transformItem, rv)
 
 	e := rvToExpr(rv)
 	if e == nil {
-		return rv, changed, newEnv
+		return rv, changed, newSubst
 	}
 
-	savedEnv := tr.env
-	tr.env = make(map[string]ast.Expr) // inefficient!  Use a slice of k/v pairs
-
-	if tr.matchExpr(tr.before, e) {
-		if tr.verbose {
-			fmt.Fprintf(os.Stderr, "%s matches %s",
-				astString(tr.fset, tr.before), astString(tr.fset, e))
-			if len(tr.env) > 0 {
-				fmt.Fprintf(os.Stderr, " with:")
-				for name, ast := range tr.env {
-					fmt.Fprintf(os.Stderr, " %s->%s",
-						name, astString(tr.fset, ast))
+	savedEnv, savedRule := tr.env, tr.rule
+	for _, r := range tr.rules {
+		if r.before == nil {
+			continue // statement rule
+		}
+		tr.rule = r
+		tr.env = make(map[string]ast.Expr) // inefficient!  Use a slice of k/v pairs
+		tr.targs = make(map[*types.TypeParam]types.Type)
+
+		if tr.matchExpr(tr.before, e) {
+			if tr.verbose {
+				fmt.Fprintf(os.Stderr, "%s matches %s",
+					astString(tr.fset, tr.before), astString(tr.fset, e))
+				tr.printEnv()
+			}
+			tr.nsubsts++
+			tr.applied[r] = true
+
+			// Clone the replacement tree, performing parameter substitution.
+			// We update all positions to n.Pos() to aid comment placement.
+			rv = tr.subst(tr.env, reflect.ValueOf(tr.after),
+				reflect.ValueOf(e.Pos()))
+			changed = true
+			newSubst = &substitution{rule: r, env: tr.env}
+			tr.matches = append(tr.matches, Match{
+				Rule: r.name,
+				Pos:  e.Pos(),
+				End:  e.End(),
+				Old:  astString(tr.fset, e),
+				New:  astString(tr.fset, rv.Interface().(ast.Node)),
+			})
+			break
+		}
+	}
+	tr.env, tr.rule = savedEnv, savedRule
+
+	return rv, changed, newSubst
+}
+
+// transformStmts returns stmts, in which each sequence of statements
+// that matches the pattern of a statement rule has been replaced by the
+// rule's replacement.
+func (tr *Transformer) transformStmts(stmts []ast.Stmt) []ast.Stmt {
+	savedEnv, savedRule := tr.env, tr.rule
+	defer func() { tr.env, tr.rule = savedEnv, savedRule }()
+
+	var out []ast.Stmt
+	for i := 0; i < len(stmts); {
+		n := 0 // number of statements replaced
+		for _, r := range tr.rules {
+			if r.beforeStmts == nil || len(r.beforeStmts) > len(stmts)-i {
+				continue // expression rule, or too few statements
+			}
+			tr.rule = r
+			tr.env = make(map[string]ast.Expr)
+			tr.targs = make(map[*types.TypeParam]types.Type)
+
+			if tr.matchStmts(tr.beforeStmts, stmts[i:i+len(tr.beforeStmts)]) {
+				if tr.verbose {
+					fmt.Fprintf(os.Stderr, "%s matches statements at %s",
+						r.name, tr.fset.Position(stmts[i].Pos()))
+					tr.printEnv()
 				}
+				tr.nsubsts++
+				tr.applied[r] = true
+
+				n = len(tr.beforeStmts)
+				start := len(out)
+				for _, s := range tr.afterStmts {
+					t := tr.subst(tr.env, reflect.ValueOf(s), reflect.Value{})
+					tr.relocate(t, stmts[i:i+n])
+					out = append(out, t.Interface().(ast.Stmt))
+				}
+				tr.matches = append(tr.matches, Match{
+					Rule: r.name,
+					Pos:  stmts[i].Pos(),
+					End:  stmts[i+n-1].End(),
+					Old:  stmtsString(tr.fset, stmts[i:i+n]),
+					New:  stmtsString(tr.fset, out[start:]),
+				})
+				break
 			}
-			fmt.Fprintf(os.Stderr, "\n")
 		}
-		tr.nsubsts++
-
-		// Clone the replacement tree, performing parameter substitution.
-		// We update all positions to n.Pos() to aid comment placement.
-		rv = tr.subst(tr.env, reflect.ValueOf(tr.after),
-			reflect.ValueOf(e.Pos()))
-		changed = true
-		newEnv = tr.env
+		if n == 0 {
+			out = append(out, stmts[i])
+			n = 1
+		}
+		i += n
 	}
-	tr.env = savedEnv
+	return out
+}
 
-	return rv, changed, newEnv
+// relocate moves the positions of the template tokens in v, a copy of
+// a statement of the replacement of the matched statements stmts, onto
+// the lines of stmts, preserving their relative lines as far as
+// possible, so that the printer lays out the replacement naturally.
+// The tokens of copies of wildcard bindings, whose positions are in
+// the input, are moved to the line of the preceding template token.
+func (tr *Transformer) relocate(v reflect.Value, stmts []ast.Stmt) {
+	file := tr.fset.File(stmts[0].Pos())
+	first := file.Line(stmts[0].Pos())
+	last := file.Line(stmts[len(stmts)-1].End())
+	base := tr.fset.Position(tr.afterStmts[0].Pos()).Line
+	cur := file.LineStart(first) // position of the preceding template token
+
+	var visit func(v reflect.Value)
+	visit = func(v reflect.Value) {
+		switch v.Kind() {
+		case reflect.Ptr, reflect.Interface:
+			if !v.IsNil() {
+				visit(v.Elem())
+			}
+		case reflect.Slice:
+			for i := 0; i < v.Len(); i++ {
+				visit(v.Index(i))
+			}
+		case reflect.Struct:
+			for i := 0; i < v.NumField(); i++ {
+				visit(v.Field(i))
+			}
+		case reflect.Int:
+			if v.Type() == positionType && v.CanSet() {
+				pos := token.Pos(v.Int())
+				if !pos.IsValid() {
+					return
+				}
+				if tr.fset.File(pos) != file {
+					line := min(first+tr.fset.Position(pos).Line-base, last)
+					cur = file.LineStart(line)
+				}
+				v.SetInt(int64(cur))
+			}
+		}
+	}
+	visit(v)
+}
+
+// printEnv prints the wildcard bindings of a match (verbose only).
+func (tr *Transformer) printEnv() {
+	if len(tr.env) > 0 {
+		fmt.Fprintf(os.Stderr, " with:")
+		for name, ast := range tr.env {
+			fmt.Fprintf(os.Stderr, " %s->%s",
+				name, astString(tr.fset, ast))
+		}
+	}
+	fmt.Fprintf(os.Stderr, "\n")
+}
+
+// A Match describes a replacement made by Transform.
+type Match struct {
+	Rule     string    // name of the before function of the rule
+	Pos, End token.Pos // extent of the replaced syntax in the input
+	Old, New string    // replaced and replacement syntax, formatted
+}
+
+// Matches returns the replacements made by the most recent call to
+// Transform, in the order in which they were made. A replacement
+// within a subexpression precedes any replacement of the enclosing
+// expression, whose Old syntax includes it.
+func (tr *Transformer) Matches() []Match {
+	return tr.matches
 }
 
 // Transform applies the transformation to the specified parsed file,
@@ -84,11 +226,19 @@ func (tr *Transformer) Transform(info *types.Info, pkg *types.Package, file *ast
 	}
 	tr.currentPkg = pkg
 	tr.nsubsts = 0
+	tr.applied = make(map[*rule]bool)
+	tr.matches = nil
 
 	if tr.verbose {
-		fmt.Fprintf(os.Stderr, "before: %s\n", astString(tr.fset, tr.before))
-		fmt.Fprintf(os.Stderr, "after: %s\n", astString(tr.fset, tr.after))
-		fmt.Fprintf(os.Stderr, "afterStmts: %s\n", tr.afterStmts)
+		for _, r := range tr.rules {
+			if r.before != nil {
+				fmt.Fprintf(os.Stderr, "%s: %s\n", r.name, astString(tr.fset, r.before))
+				fmt.Fprintf(os.Stderr, "after: %s\n", astString(tr.fset, r.after))
+			} else {
+				fmt.Fprintf(os.Stderr, "%s: %s\n", r.name, r.beforeStmts)
+			}
+			fmt.Fprintf(os.Stderr, "afterStmts: %s\n", r.afterStmts)
+		}
 	}
 
 	o, changed, _ := tr.apply(tr.transformItem, reflect.ValueOf(file))
@@ -106,8 +256,10 @@ func (tr *Transformer) Transform(info *types.Info, pkg *types.Package, file *ast
 	// TODO(adonovan): remove no-longer needed imports too.
 	if tr.nsubsts > 0 {
 		pkgs := make(map[string]*types.Package)
-		for obj := range tr.importedObjs {
-			pkgs[obj.Pkg().Path()] = obj.Pkg()
+		for r := range tr.applied {
+			for obj := range r.importedObjs {
+				pkgs[obj.Pkg().Path()] = obj.Pkg()
+			}
 		}
 
 		for _, imp := range file.Imports {
@@ -129,6 +281,7 @@ func (tr *Transformer) Transform(info *types.Info, pkg *types.Package, file *ast
 	}
 
 	tr.currentPkg = nil
+	tr.applied = nil
 
 	return tr.nsubsts
 }
@@ -170,9 +323,10 @@ var (
 // To avoid extra conversions, f operates on the reflect.Value form.
 // f takes a reflect.Value representing the variable to modify of type ast.Node.
 // It returns a reflect.Value containing the transformed value of type ast.Node,
-// whether any change was made, and a map of identifiers to ast.Expr (so we can
+// whether any change was made, and the substitution made (so we can
 // do contextually correct substitutions in the parent statements).
-func (tr *Transformer) apply(f func(reflect.Value) (reflect.Value, bool, map[string]ast.Expr), val reflect.Value) (reflect.Value, bool, map[string]ast.Expr) {
+// Sequences of statements are transformed by statement rules.
+func (tr *Transformer) apply(f func(reflect.Value) (reflect.Value, bool, *substitution), val reflect.Value) (reflect.Value, bool, *substitution) {
 	if !val.IsValid() {
 		return reflect.Value{}, false, nil
 	}
@@ -194,56 +348,59 @@ func (tr *Transformer) apply(f func(reflect.Value) (reflect.Value, bool, map[str
 		// no possible rewriting of statements.
 		if v.Type().Elem() != statementType {
 			changed := false
-			var envp map[string]ast.Expr
+			var substp *substitution
 			for i := 0; i < v.Len(); i++ {
 				e := v.Index(i)
-				o, localchanged, env := f(e)
+				o, localchanged, sub := f(e)
 				if localchanged {
 					changed = true
-					// we clobber envp here,
+					// we clobber substp here,
 					// which means if we have two successive
 					// replacements inside the same statement
 					// we will only generate the setup for one of them.
-					envp = env
+					substp = sub
 				}
 				setValue(e, o)
 			}
-			return val, changed, envp
+			return val, changed, substp
 		}
 
 		// statements are rewritten.
 		var out []ast.Stmt
 		for i := 0; i < v.Len(); i++ {
 			e := v.Index(i)
-			o, changed, env := f(e)
-			if changed {
+			o, changed, sub := f(e)
+			if changed && sub != nil {
+				saved := tr.rule
+				tr.rule = sub.rule
 				for _, s := range tr.afterStmts {
-					t := tr.subst(env, reflect.ValueOf(s), reflect.Value{}).Interface()
+					t := tr.subst(sub.env, reflect.ValueOf(s), reflect.Value{}).Interface()
 					out = append(out, t.(ast.Stmt))
 				}
+				tr.rule = saved
 			}
 			setValue(e, o)
 			out = append(out, e.Interface().(ast.Stmt))
 		}
-		return reflect.ValueOf(out), false, nil
+		return reflect.ValueOf(tr.transformStmts(out)), false, nil
 	case reflect.Struct:
 		changed := false
-		var envp map[string]ast.Expr
+		var substp *substitution
 		for i := 0; i < v.NumField(); i++ {
 			e := v.Field(i)
-			o, localchanged, env := f(e)
+			o, localchanged, sub := f(e)
 			if localchanged {
 				changed = true
-				envp = env
+				substp = sub
 			}
 			setValue(e, o)
 		}
-		return val, changed, envp
+		return val, changed, substp
 	case reflect.Interface:
 		e := v.Elem()
-		o, changed, env := f(e)
+		o, changed, sub := f(e)
 		setValue(v, o)
-		return val, changed, env
+		return val, changed, sub
 	}
 	return val, false, nil
 }